@@ -0,0 +1,551 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+
+	"url-shortener/internal/backup"
+	"url-shortener/internal/blob"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domains"
+	"url-shortener/internal/http-server/handlers/redirect"
+	"url-shortener/internal/lib/aliascheck"
+	"url-shortener/internal/lib/loopcheck"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/lib/urlcheck"
+	"url-shortener/internal/screening"
+	"url-shortener/internal/shortener"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/factory"
+	"url-shortener/internal/transfer"
+)
+
+// pageSize is the number of links read per storage call by list.
+const pageSize = 100
+
+// openStorage loads config with the command flags registered in fs and opens the storage
+// directly, without cache, metrics and tracing of the server.
+func openStorage(fs *flag.FlagSet, args []string) (*config.Config, storage.Storage, error) {
+	cfg, err := config.LoadFlags(fs, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	aliascheck.Reserve(cfg.Aliases.Reserved...)
+
+	s, err := factory.New(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to init storage: %w", err)
+	}
+
+	return cfg, s, nil
+}
+
+func runCreate(args []string) error {
+	fs := flag.NewFlagSet("url-shortener create", flag.ContinueOnError)
+	alias := fs.String("alias", "", "alias of the link, generated if empty")
+	ttl := fs.Duration("ttl", 0, "lifetime of the link, the link never expires if zero")
+	code := fs.Int("redirect-code", 0, "redirect status: 301, 302, 307 or 308; the configured one if zero")
+
+	cfg, s, err := openStorage(fs, args)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if fs.NArg() != 1 {
+		return errors.New("exactly one url is required")
+	}
+
+	u := storage.URL{URL: fs.Arg(0), Alias: *alias, RedirectCode: *code}
+
+	if err := validator.New().Var(u.URL, "required,url"); err != nil {
+		return fmt.Errorf("invalid url %q", u.URL)
+	}
+	checker, err := newURLChecker(cfg)
+	if err != nil {
+		return err
+	}
+	u.URL, err = checker.Normalize(u.URL)
+	if err != nil {
+		return err
+	}
+	if u.RedirectCode != 0 && !redirect.ValidCode(u.RedirectCode) {
+		return fmt.Errorf("invalid redirect code %d", u.RedirectCode)
+	}
+	if *ttl < 0 {
+		return errors.New("ttl must be positive")
+	}
+	if *ttl > 0 {
+		u.ExpiresAt = time.Now().Add(*ttl)
+	}
+	aliasOpts, err := newAliasOptions(cfg.Aliases)
+	if err != nil {
+		return err
+	}
+
+	if u.Alias != "" {
+		if err := aliascheck.Validate(u.Alias); err != nil {
+			return err
+		}
+
+		_, err = s.SaveURL(context.Background(), u)
+	} else {
+		u.Alias, _, err = shortener.SaveWithGeneratedAlias(context.Background(), s, u, aliasOpts, tenant.Default)
+	}
+	if errors.Is(err, storage.ErrURLExists) {
+		return fmt.Errorf("alias %q is taken", u.Alias)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(u.Alias)
+
+	return nil
+}
+
+func runDelete(args []string) error {
+	fs := flag.NewFlagSet("url-shortener delete", flag.ContinueOnError)
+
+	_, s, err := openStorage(fs, args)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if fs.NArg() == 0 {
+		return errors.New("at least one alias is required")
+	}
+
+	// Удаляем все, что можем, и сообщаем об остальном в конце
+	var failed int
+	for _, alias := range fs.Args() {
+		if err := s.DeleteURL(context.Background(), alias); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", alias, err)
+			failed++
+
+			continue
+		}
+
+		fmt.Println("deleted", alias)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d of %d links", failed, fs.NArg())
+	}
+
+	return nil
+}
+
+func runList(args []string) error {
+	fs := flag.NewFlagSet("url-shortener list", flag.ContinueOnError)
+	prefix := fs.String("prefix", "", "list only aliases starting with prefix")
+
+	_, s, err := openStorage(fs, args)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ALIAS\tURL\tCREATED\tEXPIRES")
+
+	err = eachURL(s, storage.ListFilter{AliasPrefix: *prefix}, func(u storage.URL) error {
+		_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", u.Alias, u.URL, formatTime(u.CreatedAt), formatTime(u.ExpiresAt))
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("url-shortener export", flag.ContinueOnError)
+	output := fs.String("o", "", "output file, s3:name for an object of the S3 bucket, stdout if empty")
+	format := fs.String("format", "", "ndjson or csv; taken from the extension of -o if empty")
+
+	cfg, s, err := openStorage(fs, args)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	key, toS3 := strings.CutPrefix(*output, s3PathPrefix)
+
+	var out io.Writer = os.Stdout
+	switch {
+	case toS3:
+		// В S3 файл загружается после экспорта, когда известна его длина
+		f, err := os.CreateTemp("", "url-shortener-export")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+
+		out = f
+	case *output != "":
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		out = f
+	}
+
+	w, err := transfer.NewWriter(out, fileFormat(*format, *output))
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	n, err := transfer.Export(ctx, s, w, storage.ListFilter{}, tenant.Default)
+	if err != nil {
+		return err
+	}
+
+	if toS3 {
+		bucket, err := openS3(cfg)
+		if err != nil {
+			return err
+		}
+
+		f := out.(*os.File)
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := bucket.Put(ctx, key, f); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d links\n", n)
+
+	return nil
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("url-shortener import", flag.ContinueOnError)
+	input := fs.String("i", "", "input file written by export, s3:name for an object of the S3 bucket, stdin if empty")
+	format := fs.String("format", "", "ndjson or csv; taken from the extension of -i if empty")
+	conflict := fs.String("conflict", transfer.ConflictSkip, "what to do with taken aliases: skip, overwrite or rename")
+
+	cfg, s, err := openStorage(fs, args)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	var in io.Reader = os.Stdin
+	if key, ok := strings.CutPrefix(*input, s3PathPrefix); ok {
+		bucket, err := openS3(cfg)
+		if err != nil {
+			return err
+		}
+
+		r, err := bucket.Get(context.Background(), key)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		in = r
+	} else if *input != "" {
+		f, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		in = f
+	}
+
+	r, err := transfer.NewReader(in, fileFormat(*format, *input))
+	if err != nil {
+		return err
+	}
+
+	checker, err := newURLChecker(cfg)
+	if err != nil {
+		return err
+	}
+	screener, err := newScreener(cfg, s)
+	if err != nil {
+		return err
+	}
+
+	res, err := transfer.Import(context.Background(), s, checker, screener, newLoopChecker(cfg, s), r, transfer.ImportOptions{Conflict: *conflict})
+	for _, rec := range res.Invalid {
+		fmt.Fprintf(os.Stderr, "record %d (%s): %s\n", rec.Line, rec.Alias, rec.Reason)
+	}
+	for old, alias := range res.Renamed {
+		fmt.Fprintf(os.Stderr, "renamed %s to %s\n", old, alias)
+	}
+	for _, alias := range res.Skipped {
+		fmt.Fprintf(os.Stderr, "skipped %s: alias is taken\n", alias)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "imported %d links, overwritten %d, renamed %d, skipped %d, invalid %d\n",
+		res.Imported, len(res.Overwritten), len(res.Renamed), len(res.Skipped), len(res.Invalid))
+
+	return nil
+}
+
+// runMigrate applies pending schema migrations. Storages migrate on open, so the
+// command lets operators do it before rolling out a new server version.
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("url-shortener migrate", flag.ContinueOnError)
+
+	cfg, s, err := openStorage(fs, args)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	// У redis нет схемы, мигрировать нечего
+	versioned, ok := s.(interface{ SchemaVersion() (int, error) })
+	if !ok {
+		fmt.Printf("%s storage has no schema to migrate\n", cfg.Storage.Driver)
+
+		return nil
+	}
+
+	version, err := versioned.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s schema is at version %d\n", cfg.Storage.Driver, version)
+
+	return nil
+}
+
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("url-shortener backup", flag.ContinueOnError)
+
+	cfg, s, err := openStorage(fs, args)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	db, ok := s.(backup.Backuper)
+	if !ok {
+		return fmt.Errorf("backups are supported by sqlite storage only, not %s", cfg.Storage.Driver)
+	}
+
+	store, err := backupStore(cfg)
+	if err != nil {
+		return err
+	}
+
+	name, err := backup.Create(context.Background(), db, store, cfg.Backup.Keep)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(name)
+
+	return nil
+}
+
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("url-shortener restore", flag.ContinueOnError)
+	list := fs.Bool("list", false, "print the backups, oldest first, instead of restoring")
+
+	// База не открывается: ее файл будет заменен
+	cfg, err := config.LoadFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Storage.Driver != factory.DriverSQLite && cfg.Storage.Driver != "" {
+		return fmt.Errorf("backups are supported by sqlite storage only, not %s", cfg.Storage.Driver)
+	}
+	if fs.NArg() > 1 {
+		return errors.New("at most one backup name is allowed")
+	}
+
+	store, err := backupStore(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	if *list {
+		backups, err := backup.List(ctx, store)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range backups {
+			fmt.Println(name)
+		}
+
+		return nil
+	}
+
+	name, err := backup.Restore(ctx, store, fs.Arg(0), cfg.StoragePath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "restored %s from %s\n", cfg.StoragePath, name)
+
+	return nil
+}
+
+// newURLChecker returns the checker of destinations with the rules of the server,
+// including its blocked and allowed domains.
+func newURLChecker(cfg *config.Config) (*urlcheck.Checker, error) {
+	domainPolicy, err := urlcheck.NewDomainPolicy(cfg.URLCheck.BlockDomains, cfg.URLCheck.AllowDomains)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination domains: %w", err)
+	}
+
+	return urlcheck.New(urlcheck.Options{
+		Schemes:       cfg.URLCheck.Schemes,
+		MaxLength:     cfg.URLCheck.MaxLength,
+		BlockPrivate:  cfg.URLCheck.BlockPrivate,
+		StripFragment: cfg.URLCheck.StripFragment,
+		Domains:       domainPolicy,
+	}), nil
+}
+
+// newScreener returns the configured sources of malicious urls and the hosts banned
+// by administrators in store, like the server uses.
+func newScreener(cfg *config.Config, store screening.BannedHostLister) (screening.Chain, error) {
+	var screener screening.Chain
+	if cfg.Screening.BlocklistPath != "" {
+		blocklist, err := screening.LoadBlocklist(cfg.Screening.BlocklistPath)
+		if err != nil {
+			return nil, err
+		}
+
+		screener = append(screener, blocklist)
+	}
+	if cfg.Screening.SafeBrowsingKey != "" {
+		screener = append(screener, screening.NewSafeBrowsing(cfg.Screening.SafeBrowsingKey, "", cfg.Screening.Timeout))
+	}
+	// Импорт не должен возвращать ссылки на хосты, забаненные по жалобам
+	screener = append(screener, screening.NewBanned(store))
+
+	return screener, nil
+}
+
+// newLoopChecker returns the checker of links to the hosts and short domains of the
+// server, which would make redirects loop.
+func newLoopChecker(cfg *config.Config, store domains.DomainGetter) *loopcheck.Checker {
+	selfHosts := append(append([]string{}, cfg.URLCheck.SelfHosts...), cfg.HTTPServer.TLS.Hosts...)
+	for _, t := range cfg.Tenants {
+		selfHosts = append(selfHosts, t.Hosts...)
+	}
+
+	return loopcheck.New(domains.NewRegistry(cfg.Domains, store), loopcheck.Options{
+		Hosts:   selfHosts,
+		MaxHops: cfg.URLCheck.ResolveHops,
+		Timeout: cfg.URLCheck.ResolveTimeout,
+	})
+}
+
+// s3PathPrefix marks export and import paths which are objects of the S3 bucket.
+const s3PathPrefix = "s3:"
+
+// openS3 returns the client of the configured S3 bucket.
+func openS3(cfg *config.Config) (*blob.S3, error) {
+	if cfg.S3.Bucket == "" {
+		return nil, errors.New("s3 bucket is not configured")
+	}
+
+	return blob.NewS3(blob.S3Options{
+		Endpoint:        cfg.S3.Endpoint,
+		Region:          cfg.S3.Region,
+		Bucket:          cfg.S3.Bucket,
+		PathStyle:       cfg.S3.PathStyle,
+		AccessKeyID:     cfg.S3.AccessKeyID,
+		SecretAccessKey: cfg.S3.SecretAccessKey,
+		Prefix:          cfg.S3.Prefix,
+		Encryption:      cfg.S3.Encryption,
+		KMSKeyID:        cfg.S3.KMSKeyID,
+		CustomerKey:     cfg.S3.CustomerKey,
+		Timeout:         cfg.S3.Timeout,
+	})
+}
+
+// backupStore returns where backups are kept, see config.Backup.Target.
+func backupStore(cfg *config.Config) (backup.Store, error) {
+	switch cfg.Backup.Target {
+	case "dir", "":
+		return blob.NewDir(cfg.Backup.Dir)
+	case "s3":
+		bucket, err := openS3(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return bucket.Sub("backups/"), nil
+	default:
+		return nil, fmt.Errorf("unknown backup target %q", cfg.Backup.Target)
+	}
+}
+
+// eachURL calls fn for every link matching filter, page by page.
+func eachURL(s storage.Storage, filter storage.ListFilter, fn func(u storage.URL) error) error {
+	filter.Limit = pageSize
+
+	for {
+		urls, next, err := s.ListURLs(context.Background(), filter)
+		if err != nil {
+			return err
+		}
+
+		for _, u := range urls {
+			if err := fn(u); err != nil {
+				return err
+			}
+		}
+
+		if next == "" {
+			return nil
+		}
+
+		filter.Cursor = next
+	}
+}
+
+// fileFormat returns format, or the one of the file extension if format is empty.
+func fileFormat(format, path string) string {
+	if format == "" && strings.EqualFold(filepath.Ext(path), ".csv") {
+		return transfer.FormatCSV
+	}
+
+	return format
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Format(time.RFC3339)
+}
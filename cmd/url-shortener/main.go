@@ -1,24 +1,16 @@
 package main
 
 import (
-	"context"
-	"net/http"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
+	"strings"
 
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 	"golang.org/x/exp/slog"
 
-	"url-shortener/internal/config"
-	"url-shortener/internal/http-server/handlers/redirect"
-	"url-shortener/internal/http-server/handlers/url/save"
-	mwLogger "url-shortener/internal/http-server/middleware/logger"
 	"url-shortener/internal/lib/logger/handlers/slogpretty"
-	"url-shortener/internal/lib/logger/sl"
-	"url-shortener/internal/storage/sqlite"
 )
 
 const (
@@ -28,140 +20,115 @@ const (
 	envProd  = "prod"
 )
 
-func main() {
-	cfg := config.MustLoad()
+// commands are the subcommands of the binary. Without a command the server is started,
+// as it was before subcommands appeared.
+var commands = map[string]func(args []string) error{
+	"serve":   runServe,
+	"create":  runCreate,
+	"delete":  runDelete,
+	"list":    runList,
+	"export":  runExport,
+	"import":  runImport,
+	"migrate": runMigrate,
+	"backup":  runBackup,
+	"restore": runRestore,
+	"help": func([]string) error {
+		fmt.Print(usage)
+
+		return nil
+	},
+}
 
-	log := setupLogger(cfg.Env)
+func main() {
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
 
-	log.Info(
-		"starting url-shortener",
-		slog.String("env", cfg.Env),
-		slog.String("version", "123"),
-	)
-	log.Debug("debug messages are enabled")
+	run, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
+	}
 
-	storage, err := sqlite.New(cfg.StoragePath)
+	err := run(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
-		log.Error("failed to init storage", sl.Err(err))
+		fmt.Fprintln(os.Stderr, "error:", err)
 		os.Exit(1)
 	}
+}
 
-	router := chi.NewRouter()
-
-	router.Use(middleware.RequestID)
-	router.Use(middleware.Logger)
-	router.Use(mwLogger.New(log))
-	router.Use(middleware.Recoverer)
-	router.Use(middleware.URLFormat)
-
-	router.Route("/url", func(r chi.Router) {
-		r.Use(middleware.BasicAuth("url-shortener", map[string]string{
-			cfg.HTTPServer.User: cfg.HTTPServer.Password,
-		}))
-
-		r.Post("/", save.New(log, storage))
-		// TODO: add DELETE /url/{id}
-	})
-
-	router.Get("/{alias}", redirect.New(log, storage))
-
-	log.Info("starting server", slog.String("address", cfg.Address))
-
-	// ❗Graceful shutdown
-
-	// Анализ  от google:
-	// 1️⃣ Инициализация канала сигналов (done)
-	// done: Это наш "стоп-кран".
-	// Это буферизованный канал, который будет ожидать системные сигналы.
-	done := make(chan os.Signal, 1)
-	// signal.Notify: Регистрирует канал done для получения уведомлений,
-	// когда операционная система отправляет сигналы прерывания (Ctrl+C),
-	// SIGINT или SIGTERM (используется в Docker, Kubernetes, systemd для завершения процессов).
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	// 2️⃣ Конфигурация и запуск сервера
-	// http.Server: Сервер корректно сконфигурирован с таймаутами для чтения/записи,
-	// что очень важно для продакшена.
-	srv := &http.Server{
-		Addr:         cfg.Address,
-		Handler:      router,
-		ReadTimeout:  cfg.HTTPServer.Timeout,
-		WriteTimeout: cfg.HTTPServer.Timeout,
-		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
-	}
-
-	// Отдельная горутина: Сервер запускается в своей собственной горутине.
-	// Это необходимо, так как ListenAndServe() является блокирующим вызовом.
-	go func() {
-		if err := srv.ListenAndServe(); err != nil {
-			log.Error("failed to start server")
-		}
-	}()
-
-	log.Info("server started")
-
-	// 3️⃣ Ожидание сигнала остановки
-	// <-done: Это критическая точка синхронизации. Основная горутина main блокируется здесь.
-	// Она будет ждать, пока в канал done не придет системный сигнал.
-	// Как только пользователь нажимает Ctrl+C, канал разблокируется, и выполнение продолжается.
-	<-done
-	log.Info("stopping server")
-
-	// 4️⃣ Корректное завершение с таймаутом (context.WithTimeout и Shutdown)
-	// context.WithTimeout: Создает контекст, который автоматически отменится через 10 секунд.
-	// Это наша "страховка" от зависания сервера.
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	// TODO: move timeout to config
-
-	// Всегда нужно отменять контекст, чтобы освободить его ресурсы
-	defer cancel()
-
-	// srv.Shutdown(ctx): Вызывает изящное (graceful) завершение работы.
-	// Он перестает принимать новые запросы, но дает активным запросам время завершиться.
-	// Он использует канал <-ctx.Done() (который находится внутри ctx), чтобы узнать, когда истечет 10-секундный лимит.
-	if err := srv.Shutdown(ctx); err != nil {
-		// Обработка ошибок: Если Shutdown возвращает ошибку
-		// (обычно context deadline exceeded), это логируется.
-		log.Error("failed to stop server", sl.Err(err))
-		return
+const usage = `Usage: url-shortener [command] [flags]
+
+Commands:
+  serve    start the HTTP server (default)
+  create   shorten a URL: create [-alias a] [-ttl 24h] [-redirect-code 301] <url>
+  delete   delete links: delete <alias>...
+  list     print links: list [-prefix p]
+  export   write all links as ND-JSON or CSV: export [-format csv] [-o file|s3:name]
+  import   save links written by export: import [-format csv] [-conflict skip|overwrite|rename] [-i file|s3:name]
+  migrate  create or upgrade the storage schema
+  backup   copy the sqlite database to the backup target now
+  restore  replace the sqlite database with a backup, the newest if no name is given;
+           stop the server first: restore [-list] [name]
+
+Every command accepts config flags, see serve -h.
+`
+
+// setupLogger returns the logger of env writing to out. Its level is kept in level,
+// so it can be changed while the server is running.
+func setupLogger(env string, level *slog.LevelVar, out io.Writer) *slog.Logger {
+	level.Set(envLevel(env))
+
+	if env == envLocal {
+		return setupPrettySlog(level, out)
 	}
 
-	// TODO: close storage
+	// If env config is invalid, set prod settings by default due to security
+	return slog.New(
+		slog.NewJSONHandler(out, &slog.HandlerOptions{Level: level}),
+	)
+}
 
-	log.Info("server stopped")
+// envLevel is the log level of env when log_level is not set.
+func envLevel(env string) slog.Level {
+	switch env {
+	case envLocal, envDev:
+		return slog.LevelDebug
+	default:
+		return slog.LevelInfo
+	}
 }
 
-func setupLogger(env string) *slog.Logger {
-	var log *slog.Logger
+// setLogLevel sets level to the named one or, for the empty name, to the level of env.
+func setLogLevel(level *slog.LevelVar, env, name string) error {
+	if name == "" {
+		level.Set(envLevel(env))
 
-	switch env {
-	case envLocal:
-		log = setupPrettySlog()
-	case envDev:
-		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-		)
-	case envProd:
-		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
-		)
-	default: // If env config is invalid, set prod settings by default due to security
-		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
-		)
+		return nil
+	}
+
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return err
 	}
 
-	return log
+	level.Set(l)
+
+	return nil
 }
 
-func setupPrettySlog() *slog.Logger {
+func setupPrettySlog(level slog.Leveler, out io.Writer) *slog.Logger {
 	opts := slogpretty.PrettyHandlerOptions{
 		SlogOpts: &slog.HandlerOptions{
-			Level: slog.LevelDebug,
+			Level: level,
 		},
 	}
 
-	handler := opts.NewPrettyHandler(os.Stdout)
+	handler := opts.NewPrettyHandler(out)
 
 	return slog.New(handler)
 }
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/config"
+	mwRateLimit "url-shortener/internal/http-server/middleware/ratelimit"
+	"url-shortener/internal/lib/aliascheck"
+	"url-shortener/internal/lib/urlcheck"
+	"url-shortener/internal/screening"
+)
+
+// reloader applies settings which can change without a restart: the log level,
+// rate limits, reserved aliases, destination domain lists and the blocklist file. Rate limiting and the
+// blocklist can be tuned this way, but turning them on or off needs a restart.
+type reloader struct {
+	args     []string
+	logLevel *slog.LevelVar
+	// Limiters and blocklist are nil when they are disabled.
+	saveLimiter     *mwRateLimit.Limiter
+	redirectLimiter *mwRateLimit.Limiter
+	reportLimiter   *mwRateLimit.Limiter
+	blocklist       *screening.Blocklist
+	domainPolicy    *urlcheck.DomainPolicy
+	// tenantPrefixes stay reserved along with aliases of the config.
+	tenantPrefixes []string
+}
+
+// reload reads the config again, from the same file, environment and flags as on start.
+// Nothing is applied if the config or the blocklist can't be read.
+func (r *reloader) reload() error {
+	const op = "main.reload"
+
+	cfg, err := config.LoadFlags(flag.NewFlagSet("url-shortener serve", flag.ContinueOnError), r.args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var blocklist *screening.Blocklist
+	if r.blocklist != nil && cfg.Screening.BlocklistPath != "" {
+		blocklist, err = screening.LoadBlocklist(cfg.Screening.BlocklistPath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	domainPolicy, err := urlcheck.NewDomainPolicy(cfg.URLCheck.BlockDomains, cfg.URLCheck.AllowDomains)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := setLogLevel(r.logLevel, cfg.Env, cfg.LogLevel); err != nil {
+		return fmt.Errorf("%s: invalid log level: %w", op, err)
+	}
+
+	if r.saveLimiter != nil {
+		r.saveLimiter.SetLimit(cfg.RateLimit.SaveRPS, cfg.RateLimit.SaveBurst)
+		r.redirectLimiter.SetLimit(cfg.RateLimit.RedirectRPS, cfg.RateLimit.RedirectBurst)
+		r.reportLimiter.SetLimit(cfg.RateLimit.ReportRPS, cfg.RateLimit.ReportBurst)
+	}
+
+	aliascheck.SetReserved(append(cfg.Aliases.Reserved, r.tenantPrefixes...)...)
+
+	r.domainPolicy.Replace(domainPolicy)
+
+	if blocklist != nil {
+		r.blocklist.Replace(blocklist)
+	}
+
+	return nil
+}
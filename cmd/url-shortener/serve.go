@@ -0,0 +1,1322 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/exp/slog"
+	"golang.org/x/net/http2"
+
+	"url-shortener/internal/acl"
+	"url-shortener/internal/analytics"
+	"url-shortener/internal/backup"
+	"url-shortener/internal/config"
+	"url-shortener/internal/deadlink"
+	"url-shortener/internal/domains"
+	"url-shortener/internal/events"
+	"url-shortener/internal/hitcounter"
+	"url-shortener/internal/http-server/handlers/admin"
+	adminStats "url-shortener/internal/http-server/handlers/admin/stats"
+	"url-shortener/internal/http-server/handlers/apikey/create"
+	"url-shortener/internal/http-server/handlers/apikey/revoke"
+	"url-shortener/internal/http-server/handlers/auth/forgot"
+	"url-shortener/internal/http-server/handlers/auth/login"
+	"url-shortener/internal/http-server/handlers/auth/register"
+	"url-shortener/internal/http-server/handlers/auth/reset"
+	"url-shortener/internal/http-server/handlers/auth/sso"
+	"url-shortener/internal/http-server/handlers/auth/verify"
+	banDelete "url-shortener/internal/http-server/handlers/ban/delete"
+	banList "url-shortener/internal/http-server/handlers/ban/list"
+	"url-shortener/internal/http-server/handlers/docs"
+	domainCreate "url-shortener/internal/http-server/handlers/domain/create"
+	domainDelete "url-shortener/internal/http-server/handlers/domain/delete"
+	domainList "url-shortener/internal/http-server/handlers/domain/list"
+	"url-shortener/internal/http-server/handlers/health"
+	"url-shortener/internal/http-server/handlers/loglevel"
+	"url-shortener/internal/http-server/handlers/preview"
+	"url-shortener/internal/http-server/handlers/redirect"
+	reportCreate "url-shortener/internal/http-server/handlers/report/create"
+	reportList "url-shortener/internal/http-server/handlers/report/list"
+	reportResolve "url-shortener/internal/http-server/handlers/report/resolve"
+	"url-shortener/internal/http-server/handlers/slack"
+	"url-shortener/internal/http-server/handlers/static"
+	"url-shortener/internal/http-server/handlers/unfurl"
+	del "url-shortener/internal/http-server/handlers/url/delete"
+	"url-shortener/internal/http-server/handlers/url/export"
+	"url-shortener/internal/http-server/handlers/url/info"
+	"url-shortener/internal/http-server/handlers/url/list"
+	"url-shortener/internal/http-server/handlers/url/quick"
+	"url-shortener/internal/http-server/handlers/url/restore"
+	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/http-server/handlers/url/stats"
+	"url-shortener/internal/http-server/handlers/url/timeseries"
+	"url-shortener/internal/http-server/handlers/url/transfer"
+	"url-shortener/internal/http-server/handlers/url/update"
+	userRole "url-shortener/internal/http-server/handlers/user/role"
+	webhookCreate "url-shortener/internal/http-server/handlers/webhook/create"
+	webhookDelete "url-shortener/internal/http-server/handlers/webhook/delete"
+	webhookDeliveries "url-shortener/internal/http-server/handlers/webhook/deliveries"
+	webhookList "url-shortener/internal/http-server/handlers/webhook/list"
+	webhookUpdate "url-shortener/internal/http-server/handlers/webhook/update"
+	mwAPIKey "url-shortener/internal/http-server/middleware/apikey"
+	mwAuth "url-shortener/internal/http-server/middleware/auth"
+	mwBodyLimit "url-shortener/internal/http-server/middleware/bodylimit"
+	mwCORS "url-shortener/internal/http-server/middleware/cors"
+	mwIPFilter "url-shortener/internal/http-server/middleware/ipfilter"
+	mwLogger "url-shortener/internal/http-server/middleware/logger"
+	mwMetrics "url-shortener/internal/http-server/middleware/metrics"
+	mwRateLimit "url-shortener/internal/http-server/middleware/ratelimit"
+	mwRealIP "url-shortener/internal/http-server/middleware/realip"
+	mwRole "url-shortener/internal/http-server/middleware/role"
+	mwSentry "url-shortener/internal/http-server/middleware/sentry"
+	mwSignature "url-shortener/internal/http-server/middleware/signature"
+	mwTenant "url-shortener/internal/http-server/middleware/tenant"
+	mwTimeout "url-shortener/internal/http-server/middleware/timeout"
+	mwTracing "url-shortener/internal/http-server/middleware/tracing"
+	"url-shortener/internal/janitor"
+	"url-shortener/internal/lib/aliascheck"
+	"url-shortener/internal/lib/captcha"
+	"url-shortener/internal/lib/clientip"
+	"url-shortener/internal/lib/cron"
+	"url-shortener/internal/lib/errpage"
+	"url-shortener/internal/lib/geoip"
+	"url-shortener/internal/lib/listener"
+	"url-shortener/internal/lib/logger/handlers/slogsentry"
+	"url-shortener/internal/lib/logger/logfile"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/loopcheck"
+	"url-shortener/internal/lib/pagetitle"
+	"url-shortener/internal/lib/snowflake"
+	"url-shortener/internal/lib/urlcheck"
+	"url-shortener/internal/mailer"
+	"url-shortener/internal/oidc"
+	"url-shortener/internal/pagemeta"
+	"url-shortener/internal/rollup"
+	"url-shortener/internal/screening"
+	"url-shortener/internal/shortener"
+	storageBloom "url-shortener/internal/storage/bloom"
+	storageCache "url-shortener/internal/storage/cache"
+	storageEvents "url-shortener/internal/storage/events"
+	"url-shortener/internal/storage/factory"
+	storageMetrics "url-shortener/internal/storage/metrics"
+	"url-shortener/internal/storage/rediscache"
+	storageTracing "url-shortener/internal/storage/tracing"
+	"url-shortener/internal/telegram"
+	"url-shortener/internal/tracing"
+	"url-shortener/internal/usermail"
+	"url-shortener/internal/webhook"
+)
+
+// quickCreatePath is the route of links created by bookmarklets and browser extensions.
+const quickCreatePath = "/api/shorten"
+
+// runServe starts the HTTP server and blocks until it is stopped by a signal.
+// Startup failures are fatal, they are logged and the process exits.
+func runServe(args []string) error {
+	cfg := config.MustLoad(flag.NewFlagSet("url-shortener serve", flag.ContinueOnError), args)
+
+	logLevel := new(slog.LevelVar)
+	// Пока файлы логов не открыты, ошибки пишутся в stdout
+	log := setupLogger(cfg.Env, logLevel, os.Stdout)
+
+	logOpts := logfile.Options{
+		MaxSize:    cfg.Log.MaxSize,
+		MaxAge:     cfg.Log.MaxAge,
+		MaxBackups: cfg.Log.MaxBackups,
+		Compress:   cfg.Log.Compress,
+	}
+
+	logOut, err := logfile.Open(cfg.Log.Path, logOpts)
+	if err != nil {
+		log.Error("failed to open log file", sl.Err(err))
+		os.Exit(1)
+	}
+	defer logOut.Close()
+
+	log = setupLogger(cfg.Env, logLevel, logOut)
+
+	// Журнал запросов можно писать отдельно от логов приложения
+	accessLog := log
+	if cfg.AccessLog.Path != "" {
+		accessOut, err := logfile.Open(cfg.AccessLog.Path, logOpts)
+		if err != nil {
+			log.Error("failed to open access log file", sl.Err(err))
+			os.Exit(1)
+		}
+		defer accessOut.Close()
+
+		accessLog = setupLogger(cfg.Env, logLevel, accessOut)
+	}
+
+	if err := setLogLevel(logLevel, cfg.Env, cfg.LogLevel); err != nil {
+		log.Error("invalid log level", sl.Err(err))
+		os.Exit(1)
+	}
+
+	// Ошибки из логов и паники обработчиков уходят в Sentry
+	var sentryHub *sentry.Hub
+	if cfg.Sentry.DSN != "" {
+		environment := cfg.Sentry.Environment
+		if environment == "" {
+			environment = cfg.Env
+		}
+
+		client, err := sentry.NewClient(sentry.ClientOptions{
+			Dsn:              cfg.Sentry.DSN,
+			Environment:      environment,
+			SampleRate:       cfg.Sentry.SampleRate,
+			AttachStacktrace: true,
+		})
+		if err != nil {
+			log.Error("failed to init sentry", sl.Err(err))
+			os.Exit(1)
+		}
+
+		sentryHub = sentry.NewHub(client, sentry.NewScope())
+		log = slog.New(slogsentry.New(log.Handler(), sentryHub))
+	}
+
+	log.Info(
+		"starting url-shortener",
+		slog.String("env", cfg.Env),
+		slog.String("version", "123"),
+	)
+	log.Debug("debug messages are enabled")
+
+	if !redirect.ValidCode(cfg.Redirect.Code) {
+		log.Error("invalid redirect code", slog.Int("code", cfg.Redirect.Code))
+		os.Exit(1)
+	}
+
+	aliascheck.Reserve(cfg.Aliases.Reserved...)
+
+	tenantMappings := make([]mwTenant.Mapping, len(cfg.Tenants))
+	for i, t := range cfg.Tenants {
+		tenantMappings[i] = mwTenant.Mapping{Tenant: t.Name, Hosts: t.Hosts, PathPrefix: t.PathPrefix}
+	}
+
+	tenants, err := mwTenant.NewResolver(tenantMappings)
+	if err != nil {
+		log.Error("invalid tenants", sl.Err(err))
+		os.Exit(1)
+	}
+
+	// Префикс тенанта в пути не может быть alias тенанта по умолчанию
+	aliascheck.Reserve(tenants.Prefixes()...)
+
+	storage, err := factory.New(cfg)
+	if err != nil {
+		log.Error("failed to init storage", sl.Err(err))
+		os.Exit(1)
+	}
+
+	// Копии снимаются с самой базы, до кешей и прочих оберток
+	var (
+		backupSchedule *cron.Schedule
+		backupTarget   backup.Store
+	)
+	backupDB, canBackup := storage.(backup.Backuper)
+	if cfg.Backup.Schedule != "" {
+		if !canBackup {
+			log.Warn("scheduled backups are supported by sqlite storage only", slog.String("driver", cfg.Storage.Driver))
+		} else {
+			if backupSchedule, err = cron.Parse(cfg.Backup.Schedule); err != nil {
+				log.Error("invalid backup schedule", sl.Err(err))
+				os.Exit(1)
+			}
+			if backupTarget, err = backupStore(cfg); err != nil {
+				log.Error("failed to open backup store", sl.Err(err))
+				os.Exit(1)
+			}
+		}
+	}
+
+	var aliasFilter *storageBloom.Storage
+	if cfg.AliasFilter.Enabled {
+		aliasFilter = storageBloom.New(storage, cfg.AliasFilter.Capacity, cfg.AliasFilter.FalsePositiveRate)
+		storage = aliasFilter
+	}
+
+	// Общий кеш в Redis перед SQL-хранилищем, локальный кеш стоит перед ним
+	if cfg.Cache.Redis.Addr != "" {
+		if cfg.Storage.Driver == factory.DriverRedis {
+			log.Warn("redis cache is not used with redis storage")
+		} else {
+			redisCfg := cfg.Cache.Redis
+
+			storage, err = rediscache.New(storage, log, rediscache.Options{
+				Addr:      redisCfg.Addr,
+				Password:  redisCfg.Password,
+				DB:        redisCfg.DB,
+				KeyPrefix: redisCfg.KeyPrefix,
+				TTL:       redisCfg.TTL,
+			})
+			if err != nil {
+				log.Error("failed to init redis cache", sl.Err(err))
+				os.Exit(1)
+			}
+		}
+	}
+
+	if cfg.Cache.Enabled {
+		storage = storageCache.New(storage, cfg.Cache.Size, cfg.Cache.TTL, cfg.Cache.NegativeTTL)
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	if cfg.Metrics.Enabled {
+		storage = storageMetrics.New(storage, reg)
+	}
+
+	shutdownTracing := func(context.Context) error { return nil }
+	if cfg.Tracing.Enabled {
+		shutdownTracing, err = tracing.New(context.Background(), tracing.Options{
+			Endpoint:    cfg.Tracing.Endpoint,
+			Insecure:    cfg.Tracing.Insecure,
+			ServiceName: cfg.Tracing.ServiceName,
+			SampleRatio: cfg.Tracing.SampleRatio,
+		})
+		if err != nil {
+			log.Error("failed to init tracing", sl.Err(err))
+			os.Exit(1)
+		}
+
+		storage = storageTracing.New(storage, cfg.Storage.Driver)
+	}
+
+	// События о создании, удалении ссылок и переходах для внешних систем аналитики
+	var (
+		publisher      *events.Publisher
+		linkPublishers storageEvents.Multi
+	)
+	if cfg.Events.Broker != config.EventsBrokerNone {
+		sink, err := newEventSink(cfg.Events)
+		if err != nil {
+			log.Error("failed to init event broker", sl.Err(err))
+			os.Exit(1)
+		}
+
+		publisher = events.New(log, sink, cfg.Events.BufferSize, cfg.Events.FlushInterval)
+		linkPublishers = append(linkPublishers, publisher)
+	}
+
+	webhookOpts := webhook.Options{
+		Workers:      cfg.Webhook.Workers,
+		BufferSize:   cfg.Webhook.BufferSize,
+		Timeout:      cfg.Webhook.Timeout,
+		MaxRetries:   cfg.Webhook.MaxRetries,
+		RetryBackoff: cfg.Webhook.RetryBackoff,
+	}
+
+	// Те же события с подписью уходят на вебхуки, подписанные через /admin/webhooks
+	var webhookDispatcher *webhook.Dispatcher
+	if cfg.Webhook.Enabled {
+		webhookDispatcher = webhook.NewDispatcher(log, storage, webhookOpts)
+		linkPublishers = append(linkPublishers, webhookDispatcher)
+	}
+
+	// Метаданные страниц новых ссылок загружаются в фоне по событию link_created
+	var pageMetaCollector *pagemeta.Collector
+	if cfg.PageMeta.Enabled {
+		pageMetaCollector = pagemeta.New(log, pagetitle.New(pagetitle.Options{Timeout: cfg.PageMeta.Timeout}), storage,
+			pagemeta.Options{
+				Workers:    cfg.PageMeta.Workers,
+				BufferSize: cfg.PageMeta.BufferSize,
+				Timeout:    cfg.PageMeta.Timeout,
+			})
+		linkPublishers = append(linkPublishers, pageMetaCollector)
+	}
+
+	if len(linkPublishers) > 0 {
+		storage = storageEvents.New(storage, linkPublishers)
+	}
+
+	// Фоновые задачи останавливаются вместе с сервером
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Фоновая очистка ссылок с истекшим сроком действия, архивация неактивных
+	// и удаление старых переходов
+	janitorDone := make(chan struct{})
+	go func() {
+		defer close(janitorDone)
+
+		janitor.Run(
+			bgCtx, log, storage,
+			cfg.Storage.PurgeInterval, cfg.Storage.DeletedRetention, cfg.Storage.ArchiveAfter, cfg.Privacy.ClickRetention,
+		)
+	}()
+
+	backupDone := make(chan struct{})
+	go func() {
+		defer close(backupDone)
+
+		if backupSchedule != nil {
+			backup.Run(bgCtx, log, backupDB, backupTarget, backupSchedule, cfg.Backup.Keep)
+		}
+	}()
+
+	// Переходы сворачиваются в почасовые счетчики для графиков по ссылкам
+	rollupDone := make(chan struct{})
+	go func() {
+		defer close(rollupDone)
+
+		rollup.Run(bgCtx, log, storage, cfg.Analytics.RollupInterval, cfg.Analytics.RollupDelay)
+	}()
+
+	// Фильтр строится в фоне, до этого все запросы идут в хранилище
+	aliasFilterDone := make(chan struct{})
+	go func() {
+		defer close(aliasFilterDone)
+
+		if aliasFilter != nil {
+			aliasFilter.Run(bgCtx, log, cfg.AliasFilter.RebuildInterval)
+		}
+	}()
+
+	// Проверка адресов по спискам вредоносных сайтов
+	var (
+		screener  screening.Chain
+		blocklist *screening.Blocklist
+	)
+	if cfg.Screening.BlocklistPath != "" {
+		blocklist, err = screening.LoadBlocklist(cfg.Screening.BlocklistPath)
+		if err != nil {
+			log.Error("failed to load blocklist", sl.Err(err))
+			os.Exit(1)
+		}
+
+		screener = append(screener, blocklist)
+	}
+	if cfg.Screening.SafeBrowsingKey != "" {
+		screener = append(screener, screening.NewSafeBrowsing(cfg.Screening.SafeBrowsingKey, "", cfg.Screening.Timeout))
+	}
+	// Хосты, забаненные по жалобам, проверяются всегда: повторная проверка
+	// помещает в карантин остальные ссылки на них
+	screener = append(screener, screening.NewBanned(storage))
+
+	recheckDone := make(chan struct{})
+	go func() {
+		defer close(recheckDone)
+
+		screening.Run(bgCtx, log, storage, screener, cfg.Screening.RecheckInterval)
+	}()
+
+	// Счетчик переходов: пишет в хранилище пачками в фоне, не замедляя редирект
+	hitCounter := hitcounter.New(log, storage, cfg.HitCounter.BufferSize, cfg.HitCounter.BatchSize, cfg.HitCounter.FlushInterval)
+
+	// Без базы GeoIP ссылки с geo_targets ведут всех на основной адрес,
+	// а у переходов в статистике нет страны
+	var (
+		geoReader  *geoip.Reloader
+		geoLocator redirect.GeoLocator
+	)
+	if cfg.GeoIP.DatabasePath != "" {
+		geoReader, err = geoip.OpenReloader(cfg.GeoIP.DatabasePath)
+		if err != nil {
+			log.Error("failed to open geoip database", sl.Err(err))
+			os.Exit(1)
+		}
+
+		geoLocator = geoReader
+	}
+
+	// Обновленная база подхватывается без перезапуска
+	geoIPDone := make(chan struct{})
+	go func() {
+		defer close(geoIPDone)
+
+		if geoReader != nil {
+			geoReader.Run(bgCtx, log, cfg.GeoIP.ReloadInterval)
+		}
+	}()
+
+	// Журнал переходов для статистики: тоже пишется в фоне
+	var clickRecorder interface {
+		redirect.ClickRecorder
+		Close()
+	} = analytics.Nop{}
+	if cfg.Analytics.Enabled {
+		anonymizer, err := analytics.NewAnonymizer(cfg.Privacy.IPMode, cfg.Privacy.IPHashKey)
+		if err != nil {
+			log.Error("invalid privacy ip mode", sl.Err(err))
+			os.Exit(1)
+		}
+
+		clickRecorder = analytics.New(
+			log, storage, geoLocator, anonymizer, cfg.Analytics.BufferSize, cfg.Analytics.FlushInterval,
+		)
+	}
+	if publisher != nil {
+		// Publisher закрывается вместе с журналом переходов
+		clickRecorder = analytics.Multi{clickRecorder, publisher}
+	}
+	if webhookDispatcher != nil {
+		clickRecorder = analytics.Multi{clickRecorder, webhookDispatcher}
+	}
+
+	// Уведомления о переходах отправляются в фоне с повторами
+	var webhookNotifier interface {
+		redirect.WebhookNotifier
+		deadlink.Notifier
+		Close()
+	} = webhook.Nop{}
+	if cfg.Webhook.Enabled {
+		webhookNotifier = webhook.New(log, webhookOpts)
+	}
+
+	// Подписки вебхуков перечитываются в фоне, изменения через API применяются не сразу
+	webhooksDone := make(chan struct{})
+	go func() {
+		defer close(webhooksDone)
+
+		if webhookDispatcher != nil {
+			webhookDispatcher.Run(bgCtx, log, cfg.Webhook.ReloadInterval)
+		}
+	}()
+
+	// Проверка назначений ссылок: мертвые помечаются, владельцы узнают через webhook
+	var deadNotifier deadlink.Notifier
+	if cfg.DeadLinks.Notify {
+		deadNotifier = webhookNotifier
+	}
+	deadLinkChecker := deadlink.New(storage, deadNotifier, deadlink.Options{
+		Timeout:      cfg.DeadLinks.Timeout,
+		AllowPrivate: !cfg.URLCheck.BlockPrivate,
+	})
+
+	deadLinksDone := make(chan struct{})
+	go func() {
+		defer close(deadLinksDone)
+
+		deadLinkChecker.Run(bgCtx, log, cfg.DeadLinks.Interval)
+	}()
+
+	// За балансировщиком адрес клиента берется из заголовков доверенных прокси
+	trustedProxies, err := clientip.ParsePrefixes(cfg.ClientIP.TrustedProxies)
+	if err != nil {
+		log.Error("invalid trusted proxies", sl.Err(err))
+		os.Exit(1)
+	}
+	ipResolver, err := clientip.New(trustedProxies, cfg.ClientIP.Header)
+	if err != nil {
+		log.Error("invalid client ip header", sl.Err(err))
+		os.Exit(1)
+	}
+	realIP := mwRealIP.New(ipResolver)
+
+	router := chi.NewRouter()
+
+	router.Use(middleware.RequestID)
+	router.Use(realIP)
+	if cfg.Tracing.Enabled {
+		router.Use(mwTracing.New())
+	}
+	if cfg.Metrics.Enabled {
+		router.Use(mwMetrics.New(reg))
+	}
+	// Текстовый лог chi пишет каждый запрос, в проде хватает выборочного журнала mwLogger.
+	// Он не скрывает параметры запроса, поэтому запросы с ключом в token в него не попадают
+	if cfg.Env == envLocal {
+		router.Use(middleware.Maybe(middleware.Logger, func(r *http.Request) bool {
+			return !r.URL.Query().Has(mwAPIKey.QueryParam)
+		}))
+	}
+	router.Use(mwLogger.New(accessLog, mwLogger.Options{
+		SampleRedirects: cfg.AccessLog.SampleRedirects,
+		SkipUserAgent:   cfg.AccessLog.SkipUserAgent,
+		SkipQuery:       cfg.AccessLog.SkipQuery,
+		SkipPaths:       cfg.AccessLog.SkipPaths,
+	}))
+	router.Use(middleware.Recoverer)
+	if sentryHub != nil {
+		router.Use(mwSentry.New(sentryHub))
+	}
+	// Preflight-запросы не несут учетных данных, поэтому CORS идет до аутентификации
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		router.Use(mwCORS.New(mwCORS.Options{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.CORS.AllowedHeaders,
+			ExposedHeaders:   cfg.CORS.ExposedHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           cfg.CORS.MaxAge,
+			// У быстрого создания своя политика: его вызывают с любых сайтов
+			SkipPaths: []string{quickCreatePath},
+		}))
+	}
+	// Тенант определяется до маршрутизации: его префикс отрезается от пути
+	router.Use(mwTenant.New(tenants))
+	router.Use(middleware.URLFormat)
+
+	// Пробы для Kubernetes и балансировщиков
+	router.Get("/health", health.NewLive())
+	router.Get("/ready", health.NewReady(log, storage, cfg.HTTPServer.ReadyTimeout))
+
+	// robots.txt и favicon.ico; URLFormat отрезает расширение от пути маршрута
+	robots := static.Robots(cfg.Static.RobotsAllow)
+	if cfg.Static.RobotsPath != "" {
+		if robots, err = os.ReadFile(cfg.Static.RobotsPath); err != nil {
+			log.Error("failed to read robots.txt", sl.Err(err))
+			os.Exit(1)
+		}
+	}
+	favicon := static.Favicon()
+	if cfg.Static.FaviconPath != "" {
+		if favicon, err = os.ReadFile(cfg.Static.FaviconPath); err != nil {
+			log.Error("failed to read favicon", sl.Err(err))
+			os.Exit(1)
+		}
+	}
+	router.Get("/robots", static.NewRobots(robots, cfg.Static.MaxAge))
+	router.Get("/favicon", static.NewFavicon(favicon, cfg.Static.MaxAge))
+
+	// Учетные данные из конфига нужны для веб-интерфейса, выдачи и отзыва API-ключей и восстановления ссылок
+	adminAuth := middleware.BasicAuth("url-shortener", map[string]string{
+		cfg.HTTPServer.User: cfg.HTTPServer.Password,
+	})
+
+	// Внутренний адрес для операторов: профилирование и, если включено, управление сервисом
+	var (
+		internalRouter *chi.Mux
+		internalSrv    *http.Server
+	)
+	if cfg.Internal.Address != "" {
+		internalRouter = chi.NewRouter()
+		internalRouter.Use(middleware.RequestID)
+		internalRouter.Use(realIP)
+		internalRouter.Use(mwLogger.New(accessLog, mwLogger.Options{}))
+		internalRouter.Use(middleware.Recoverer)
+		if sentryHub != nil {
+			internalRouter.Use(mwSentry.New(sentryHub))
+		}
+		if cfg.Internal.Management {
+			internalRouter.Use(mwTenant.New(tenants))
+			internalRouter.Use(middleware.URLFormat)
+		}
+
+		// Профилирование доступно только на внутреннем адресе и только администратору
+		if cfg.Internal.Debug {
+			internalRouter.Group(func(r chi.Router) {
+				r.Use(adminAuth)
+				r.Mount("/debug", middleware.Profiler())
+			})
+		}
+
+		// WriteTimeout не задан: профиль CPU по умолчанию снимается 30 секунд
+		internalSrv = &http.Server{
+			Handler:           internalRouter,
+			ReadHeaderTimeout: cfg.HTTPServer.Timeout,
+			IdleTimeout:       cfg.HTTPServer.IdleTimeout,
+		}
+	} else {
+		if cfg.Internal.Debug {
+			log.Warn("debug endpoints need internal.address, they are disabled")
+		}
+		if cfg.Internal.Management {
+			log.Warn("management endpoints need internal.address, they stay on the public one")
+		}
+	}
+
+	// Маршруты управления остаются на публичном адресе, пока их не перенесли на внутренний
+	var mgmt chi.Router = router
+	if internalRouter != nil && cfg.Internal.Management {
+		mgmt = internalRouter
+	}
+
+	// Управление можно закрыть от всех, кроме сетей офиса и VPN
+	ipFilter := func(next http.Handler) http.Handler { return next }
+	redirectFilter := ipFilter
+	if len(cfg.IPFilter.Allow) > 0 || len(cfg.IPFilter.Deny) > 0 {
+		allow, err := clientip.ParsePrefixes(cfg.IPFilter.Allow)
+		if err != nil {
+			log.Error("invalid ip filter", sl.Err(err))
+			os.Exit(1)
+		}
+		deny, err := clientip.ParsePrefixes(cfg.IPFilter.Deny)
+		if err != nil {
+			log.Error("invalid ip filter", sl.Err(err))
+			os.Exit(1)
+		}
+
+		ipFilter = mwIPFilter.New(log, mwIPFilter.Options{Allow: allow, Deny: deny})
+		if cfg.IPFilter.Redirects {
+			redirectFilter = ipFilter
+		}
+	}
+	mgmt = mgmt.With(ipFilter)
+
+	// Ссылка должна открываться быстро, управлению дается больше времени
+	redirectTimeout := mwTimeout.New(log, cfg.Timeouts.Redirect)
+	mgmtTimeout := mwTimeout.New(log, cfg.Timeouts.Management)
+
+	// Сжимаются только ответы, которые могут быть большими
+	compress := func(next http.Handler) http.Handler { return next }
+	if cfg.Compression.Enabled {
+		compress = middleware.Compress(cfg.Compression.Level, cfg.Compression.ContentTypes...)
+	}
+
+	mgmt.Route("/admin", func(r chi.Router) {
+		r.Use(adminAuth, mgmtTimeout)
+
+		// Веб-интерфейс для тех, кто не работает с API напрямую
+		r.Get("/", admin.New())
+
+		r.Post("/api-keys", create.New(log, storage))
+		r.Delete("/api-keys/{id}", revoke.New(log, storage))
+		r.Post("/domains", domainCreate.New(log, storage))
+		r.Get("/domains", domainList.New(log, storage, cfg.Domains))
+		r.Delete("/domains/{host}", domainDelete.New(log, storage))
+		r.Post("/urls/{alias}/restore", restore.New(log, storage))
+		r.Get("/reports", reportList.New(log, storage))
+		r.Post("/reports/resolve", reportResolve.New(log, storage))
+		r.Get("/bans", banList.New(log, storage))
+		r.Get("/stats", adminStats.New(log, storage, cfg.AdminStats.CacheTTL))
+		r.With(compress).Get("/stats/export", export.NewBulk(log, storage))
+		r.Delete("/bans/{host}", banDelete.New(log, storage))
+		r.Post("/webhooks", webhookCreate.New(log, storage))
+		r.Get("/webhooks", webhookList.New(log, storage))
+		r.Patch("/webhooks/{id}", webhookUpdate.New(log, storage))
+		r.Delete("/webhooks/{id}", webhookDelete.New(log, storage))
+		r.Get("/webhooks/{id}/deliveries", webhookDeliveries.New(log, storage))
+		r.Put("/loglevel", loglevel.New(log, logLevel))
+	})
+
+	// Описание API собирается из структур обработчиков и не расходится с кодом.
+	// URLFormat отрезает расширение, поэтому /openapi.json попадает в маршрут /openapi
+	mgmt.With(adminAuth).Get("/openapi", docs.NewSpec(docs.Spec()))
+	mgmt.With(adminAuth).Get("/docs", docs.NewUI("/openapi.json"))
+
+	// На /url пускаем по API-ключу или подписи запроса, а если включены учетные записи - еще и по JWT
+	urlAuth := mwSignature.New(log, storage, mwSignature.Options{
+		MaxSkew:     cfg.Signature.MaxSkew,
+		MaxBodySize: cfg.Signature.MaxBodySize,
+	}, mwAPIKey.New(log, storage))
+	if cfg.Auth.JWTSecret != "" {
+		userMail := usermail.New(storage, newMailer(log, cfg.SMTP), usermail.Options{
+			AppURL:          cfg.Auth.AppURL,
+			VerificationTTL: cfg.Auth.VerificationTTL,
+			ResetTTL:        cfg.Auth.ResetTTL,
+		})
+
+		ssoProviders := make([]oidc.Provider, len(cfg.Auth.SSO))
+		for i, p := range cfg.Auth.SSO {
+			provider, err := newSSOProvider(p)
+			if err != nil {
+				log.Error("failed to init sso provider", slog.String("name", p.Name), sl.Err(err))
+				os.Exit(1)
+			}
+
+			ssoProviders[i] = provider
+		}
+
+		mgmt.Route("/auth", func(r chi.Router) {
+			r.Use(mgmtTimeout)
+
+			r.Post("/register", register.New(log, storage, userMail))
+			r.Post("/login", login.New(log, storage, cfg.Auth.JWTSecret, cfg.Auth.TokenTTL, cfg.Auth.RequireVerification))
+			r.Post("/verify", verify.New(log, storage))
+			r.Post("/password/forgot", forgot.New(log, storage, userMail))
+			r.Post("/password/reset", reset.New(log, storage))
+
+			for i, p := range cfg.Auth.SSO {
+				r.Get("/sso/"+p.Name+"/login", sso.NewLogin(log, ssoProviders[i]))
+				r.Get("/sso/"+p.Name+"/callback", sso.NewCallback(log, ssoProviders[i], storage, sso.Options{
+					Secret:         cfg.Auth.JWTSecret,
+					TokenTTL:       cfg.Auth.TokenTTL,
+					AllowedDomains: p.AllowedDomains,
+					AppURL:         cfg.Auth.AppURL,
+				}))
+			}
+		})
+
+		urlAuth = mwAuth.New(log, cfg.Auth.JWTSecret, urlAuth)
+
+		// Роли пользователей меняют только администраторы
+		mgmt.Route("/users", func(r chi.Router) {
+			r.Use(urlAuth, mgmtTimeout, mwRole.New(log, acl.RoleAdmin))
+
+			r.Put("/role", userRole.New(log, storage))
+		})
+	}
+
+	// Читать может любая роль, изменять ссылки - редакторы и администраторы (см. пакет acl)
+	editor := mwRole.New(log, acl.RoleEditor)
+
+	// Ограничение частоты запросов против перебора alias и злоупотреблений
+	saveLimit := func(next http.Handler) http.Handler { return next }
+	redirectLimit := saveLimit
+	reportLimit := saveLimit
+	var saveLimiter, redirectLimiter, reportLimiter *mwRateLimit.Limiter
+	if cfg.RateLimit.Enabled {
+		saveLimiter = mwRateLimit.NewLimiter(cfg.RateLimit.SaveRPS, cfg.RateLimit.SaveBurst)
+		redirectLimiter = mwRateLimit.NewLimiter(cfg.RateLimit.RedirectRPS, cfg.RateLimit.RedirectBurst)
+		reportLimiter = mwRateLimit.NewLimiter(cfg.RateLimit.ReportRPS, cfg.RateLimit.ReportBurst)
+		saveLimit = mwRateLimit.New(log, saveLimiter, mwRateLimit.ByClient)
+		redirectLimit = mwRateLimit.New(log, redirectLimiter, mwRateLimit.ByIP)
+		reportLimit = mwRateLimit.New(log, reportLimiter, mwRateLimit.ByIP)
+	}
+
+	aliasOpts, err := newAliasOptions(cfg.Aliases)
+	if err != nil {
+		log.Error("invalid aliases config", sl.Err(err))
+		os.Exit(1)
+	}
+
+	domainPolicy, err := urlcheck.NewDomainPolicy(cfg.URLCheck.BlockDomains, cfg.URLCheck.AllowDomains)
+	if err != nil {
+		log.Error("invalid destination domains", sl.Err(err))
+		os.Exit(1)
+	}
+
+	checker := urlcheck.New(urlcheck.Options{
+		Schemes:       cfg.URLCheck.Schemes,
+		MaxLength:     cfg.URLCheck.MaxLength,
+		BlockPrivate:  cfg.URLCheck.BlockPrivate,
+		StripFragment: cfg.URLCheck.StripFragment,
+		Domains:       domainPolicy,
+	})
+
+	domainRegistry := domains.NewRegistry(cfg.Domains, storage)
+
+	// Ссылки на сам сервис зацикливают переходы
+	selfHosts := append(append([]string{}, cfg.URLCheck.SelfHosts...), cfg.HTTPServer.TLS.Hosts...)
+	for _, t := range cfg.Tenants {
+		selfHosts = append(selfHosts, t.Hosts...)
+	}
+
+	loopChecker := loopcheck.New(domainRegistry, loopcheck.Options{
+		Hosts:   selfHosts,
+		MaxHops: cfg.URLCheck.ResolveHops,
+		Timeout: cfg.URLCheck.ResolveTimeout,
+	})
+
+	// POST /url и чат-боты создают ссылки с одними и теми же проверками и правами
+	linkService := shortener.New(log, storage, aliasOpts, checker, screener, loopChecker)
+
+	telegramDone := make(chan struct{})
+	go func() {
+		defer close(telegramDone)
+
+		if cfg.Telegram.Token == "" {
+			return
+		}
+
+		users := make(map[int64]string, len(cfg.Telegram.Users))
+		for _, u := range cfg.Telegram.Users {
+			users[u.ID] = u.Email
+		}
+
+		telegram.New(log, linkService, storage, telegram.Options{
+			Token:       cfg.Telegram.Token,
+			Users:       users,
+			BaseURL:     cfg.Telegram.BaseURL,
+			PollTimeout: cfg.Telegram.PollTimeout,
+		}).Run(bgCtx)
+	}()
+
+	bodyLimit := mwBodyLimit.New(log, cfg.HTTPServer.MaxBodySize)
+
+	mgmt.Route("/url", func(r chi.Router) {
+		r.Use(urlAuth, mgmtTimeout)
+
+		r.With(editor, saveLimit, bodyLimit).Post("/", save.New(log, linkService, domainRegistry))
+		r.With(editor, saveLimit, bodyLimit).Post("/batch", save.NewBatch(log, linkService, cfg.HTTPServer.MaxBatchSize, domainRegistry))
+		r.With(compress).Get("/", list.New(log, storage))
+		r.With(compress).Get("/export", transfer.NewExport(log, storage))
+		r.With(editor, saveLimit).Post("/import", transfer.NewImport(log, storage, checker, screener, loopChecker))
+		r.Get("/{alias}", info.New(log, storage))
+		r.With(editor).Patch("/{alias}", update.New(log, storage, checker, screener, loopChecker))
+		r.With(compress).Get("/{alias}/stats", stats.New(log, storage))
+		r.With(compress).Get("/{alias}/stats/timeseries", timeseries.New(log, storage))
+		r.With(compress).Get("/{alias}/stats/export", export.New(log, storage))
+		r.With(editor).Delete("/{alias}", del.New(log, storage))
+	})
+
+	// Жалобы принимаются без авторизации, поэтому их ограничивает частота и, если задан secret, CAPTCHA
+	var captchaVerifier reportCreate.CaptchaVerifier
+	if cfg.Captcha.Secret != "" {
+		captchaVerifier = captcha.New(cfg.Captcha.VerifyURL, cfg.Captcha.Secret, cfg.Captcha.Timeout)
+	}
+	router.With(redirectFilter, reportLimit, mgmtTimeout, bodyLimit).Post("/report/{alias}", reportCreate.New(log, storage, captchaVerifier))
+
+	// Букмарклеты и расширения создают ссылку одним GET-запросом с любого сайта.
+	// Ключ можно передать в параметре token: заголовок потребовал бы preflight
+	if cfg.QuickCreate.Enabled {
+		quickCORS := mwCORS.New(mwCORS.Options{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{http.MethodGet},
+			AllowedHeaders: []string{mwAPIKey.Header},
+			MaxAge:         cfg.CORS.MaxAge,
+		})
+
+		// Ключ в ссылке легко утекает, поэтому фильтр сетей управления действует и здесь
+		router.Route(quickCreatePath, func(r chi.Router) {
+			r.Use(ipFilter, quickCORS)
+
+			r.Options("/", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) })
+			r.With(mgmtTimeout, mwAPIKey.NewWithQuery(log, storage), saveLimit).
+				Get("/", quick.New(log, linkService, cfg.QuickCreate.BaseURL))
+		})
+	}
+
+	// Slack обращается с публичных адресов, запросы подтверждает подпись приложения
+	if cfg.Slack.SigningSecret != "" {
+		workspaces := make(map[string]string, len(cfg.Slack.Workspaces))
+		for _, ws := range cfg.Slack.Workspaces {
+			workspaces[ws.TeamID] = ws.APIKey
+		}
+
+		router.With(mgmtTimeout).Post("/slack", slack.New(log, linkService, storage, slack.Options{
+			SigningSecret: cfg.Slack.SigningSecret,
+			Workspaces:    workspaces,
+			BaseURL:       cfg.Slack.BaseURL,
+		}))
+	}
+
+	if cfg.Metrics.Enabled {
+		mgmt.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	}
+
+	// Предпросмотр ссылки: /{alias}+ как в bit.ly и /preview/{alias}
+	var pageTitler preview.PageTitler
+	if cfg.Preview.FetchTitle {
+		pageTitler = pagetitle.New(pagetitle.Options{Timeout: cfg.Preview.TitleTimeout})
+	}
+
+	pages, err := errpage.New(cfg.Redirect.NotFoundPage, cfg.Redirect.ExpiredPage)
+	if err != nil {
+		log.Error("failed to load error pages", sl.Err(err))
+		os.Exit(1)
+	}
+
+	previewHandler := preview.New(log, storage, pageTitler, pages)
+	router.With(redirectFilter, redirectLimit, redirectTimeout).Get("/{alias}+", previewHandler)
+	router.With(redirectFilter, redirectLimit, redirectTimeout).Get("/preview/{alias}", previewHandler)
+
+	// Метаданные назначения для превью в мессенджерах, без перехода по ссылке
+	router.With(redirectFilter, redirectLimit, redirectTimeout).Get("/unfurl/{alias}", unfurl.New(log, storage))
+
+	var destinationPolicy redirect.DestinationPolicy
+	if cfg.URLCheck.CheckRedirects {
+		destinationPolicy = domainPolicy
+	}
+
+	redirectHandler := redirect.New(
+		log, storage, storage, hitCounter, clickRecorder, webhookNotifier, geoLocator, destinationPolicy, pages,
+		cfg.Redirect.Code, cfg.Redirect.CacheMaxAge, cfg.Redirect.CountHead,
+	)
+	router.With(redirectFilter, redirectLimit, redirectTimeout).Get("/{alias}", redirectHandler)
+	// Проверки ссылок и превью мессенджеров запрашивают только заголовки
+	router.With(redirectFilter, redirectLimit, redirectTimeout).Head("/{alias}", redirectHandler)
+
+	log.Info("starting server", slog.String("address", cfg.Address))
+
+	// ❗Graceful shutdown
+
+	// Анализ  от google:
+	// 1️⃣ Инициализация канала сигналов (done)
+	// done: Это наш "стоп-кран".
+	// Это буферизованный канал, который будет ожидать системные сигналы.
+	done := make(chan os.Signal, 1)
+	// signal.Notify: Регистрирует канал done для получения уведомлений,
+	// когда операционная система отправляет сигналы прерывания (Ctrl+C),
+	// SIGINT или SIGTERM (используется в Docker, Kubernetes, systemd для завершения процессов).
+	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+
+	// 2️⃣ Конфигурация и запуск сервера
+	// http.Server: Сервер корректно сконфигурирован с таймаутами для чтения/записи,
+	// что очень важно для продакшена.
+	srv := &http.Server{
+		Addr:         cfg.Address,
+		Handler:      router,
+		ReadTimeout:  cfg.HTTPServer.Timeout,
+		WriteTimeout: cfg.HTTPServer.Timeout,
+		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
+	}
+
+	serve, challengeSrv, err := setupTLS(srv, cfg.HTTPServer.TLS)
+	if err != nil {
+		log.Error("failed to setup tls", sl.Err(err))
+		os.Exit(1)
+	}
+
+	var h3 *http3.Server
+	if cfg.HTTPServer.TLS.HTTP3 {
+		if cfg.HTTPServer.TLS.Mode == config.TLSModeOff {
+			log.Warn("http3 needs tls, it is disabled")
+		} else if h3, err = setupHTTP3(srv, cfg.HTTPServer); err != nil {
+			log.Error("failed to setup http3", sl.Err(err))
+			os.Exit(1)
+		}
+	}
+
+	socketMode, err := strconv.ParseUint(cfg.HTTPServer.SocketMode, 8, 32)
+	if err != nil {
+		log.Error("invalid socket mode", sl.Err(err))
+		os.Exit(1)
+	}
+
+	// Сокет открывается до запуска горутины, чтобы занятый адрес останавливал старт
+	ln, err := listener.Listen(cfg.Address, fs.FileMode(socketMode))
+	if err != nil {
+		log.Error("failed to listen", sl.Err(err))
+		os.Exit(1)
+	}
+
+	// Отдельная горутина: Сервер запускается в своей собственной горутине.
+	// Это необходимо, так как Serve() является блокирующим вызовом.
+	go func() {
+		if err := serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("failed to start server", sl.Err(err))
+		}
+	}()
+
+	if h3 != nil {
+		log.Info("starting http3 server", slog.String("address", h3.Addr))
+
+		go func() {
+			if err := h3.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("failed to start http3 server", sl.Err(err))
+			}
+		}()
+	}
+
+	if internalSrv != nil {
+		internalLn, err := listener.Listen(cfg.Internal.Address, fs.FileMode(socketMode))
+		if err != nil {
+			log.Error("failed to listen on internal address", sl.Err(err))
+			os.Exit(1)
+		}
+
+		log.Info("starting internal server", slog.String("address", cfg.Internal.Address))
+
+		go func() {
+			if err := internalSrv.Serve(internalLn); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("failed to start internal server", sl.Err(err))
+			}
+		}()
+	}
+
+	if challengeSrv != nil {
+		go func() {
+			if err := challengeSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("failed to start acme challenge server", sl.Err(err))
+			}
+		}()
+	}
+
+	log.Info("server started")
+
+	// SIGHUP перечитывает конфиг: уровень логов, лимиты, зарезервированные alias, списки доменов и блоклист.
+	// Сервер при этом не останавливается, начатые запросы не обрываются
+	reload := &reloader{
+		args:            args,
+		logLevel:        logLevel,
+		saveLimiter:     saveLimiter,
+		redirectLimiter: redirectLimiter,
+		reportLimiter:   reportLimiter,
+		blocklist:       blocklist,
+		domainPolicy:    domainPolicy,
+		tenantPrefixes:  tenants.Prefixes(),
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	go func() {
+		for {
+			select {
+			case <-hup:
+				if err := reload.reload(); err != nil {
+					log.Error("failed to reload config", sl.Err(err))
+					continue
+				}
+
+				log.Info("config reloaded")
+			case <-bgCtx.Done():
+				return
+			}
+		}
+	}()
+
+	// 3️⃣ Ожидание сигнала остановки
+	// <-done: Это критическая точка синхронизации. Основная горутина main блокируется здесь.
+	// Она будет ждать, пока в канал done не придет системный сигнал.
+	// Как только пользователь нажимает Ctrl+C, канал разблокируется, и выполнение продолжается.
+	<-done
+	log.Info("stopping server")
+
+	// 4️⃣ Корректное завершение с таймаутом (context.WithTimeout и Shutdown)
+	// context.WithTimeout: Создает контекст, который автоматически отменится через ShutdownTimeout.
+	// Это наша "страховка" от зависания сервера.
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTPServer.ShutdownTimeout)
+
+	// Всегда нужно отменять контекст, чтобы освободить его ресурсы
+	defer cancel()
+
+	// srv.Shutdown(ctx): Вызывает изящное (graceful) завершение работы.
+	// Он перестает принимать новые запросы, но дает активным запросам время завершиться.
+	// Он использует канал <-ctx.Done() (который находится внутри ctx), чтобы узнать, когда истечет лимит.
+	if err := srv.Shutdown(ctx); err != nil {
+		// Обработка ошибок: Если Shutdown возвращает ошибку
+		// (обычно context deadline exceeded), это логируется.
+		// Хранилище все равно закрываем ниже, чтобы не потерять буферизованные данные.
+		log.Error("failed to stop server", sl.Err(err))
+	}
+
+	if h3 != nil {
+		if err := h3.Shutdown(ctx); err != nil {
+			log.Error("failed to stop http3 server", sl.Err(err))
+		}
+	}
+
+	if internalSrv != nil {
+		if err := internalSrv.Shutdown(ctx); err != nil {
+			log.Error("failed to stop internal server", sl.Err(err))
+		}
+	}
+
+	if challengeSrv != nil {
+		if err := challengeSrv.Shutdown(ctx); err != nil {
+			log.Error("failed to stop acme challenge server", sl.Err(err))
+		}
+	}
+
+	// 5️⃣ Остановка фоновых задач и закрытие хранилища
+	// Новых запросов больше нет, поэтому сбрасываем накопленные переходы
+	// и только после этого закрываем хранилище, в которое они пишутся.
+	stopBackground()
+	<-janitorDone
+	<-aliasFilterDone
+	<-recheckDone
+	<-deadLinksDone
+	<-backupDone
+	<-rollupDone
+	<-geoIPDone
+	<-webhooksDone
+	<-telegramDone
+	hitCounter.Close()
+	clickRecorder.Close()
+	webhookNotifier.Close()
+	if pageMetaCollector != nil {
+		pageMetaCollector.Close()
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		log.Error("failed to flush traces", sl.Err(err))
+	}
+
+	if err := storage.Close(); err != nil {
+		log.Error("failed to close storage", sl.Err(err))
+	}
+
+	// События отправляются в фоне, без Flush последние из них потеряются
+	if sentryHub != nil {
+		sentryHub.Flush(cfg.HTTPServer.ShutdownTimeout)
+	}
+
+	log.Info("server stopped")
+
+	return nil
+}
+
+// newEventSink connects to the configured event broker.
+func newEventSink(cfg config.Events) (events.Sink, error) {
+	switch cfg.Broker {
+	case config.EventsBrokerKafka:
+		return events.NewKafka(cfg.Kafka.ProxyURL, cfg.Kafka.Topic, cfg.Timeout), nil
+	case config.EventsBrokerNATS:
+		return events.NewNATS(cfg.NATS.URL, cfg.NATS.SubjectPrefix, cfg.Timeout)
+	default:
+		return nil, fmt.Errorf("unknown events broker %q", cfg.Broker)
+	}
+}
+
+// newMailer returns the SMTP mailer, or the one writing emails to the log when SMTP is not configured.
+func newMailer(log *slog.Logger, cfg config.SMTP) mailer.Mailer {
+	if cfg.Host == "" {
+		log.Warn("smtp is not configured, emails to users are written to the log")
+
+		return mailer.NewLog(log)
+	}
+
+	return mailer.NewSMTP(mailer.SMTPOptions{
+		Host:     cfg.Host,
+		Port:     cfg.Port,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		From:     cfg.From,
+		Timeout:  cfg.Timeout,
+	})
+}
+
+// newSSOProvider returns the OAuth2 provider of the configured type.
+func newSSOProvider(cfg config.SSOProvider) (oidc.Provider, error) {
+	if cfg.Name == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("name, client_id and redirect_url are required")
+	}
+
+	oauthCfg := oidc.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       cfg.Scopes,
+	}
+
+	switch cfg.Type {
+	case "", config.SSOTypeOIDC:
+		if cfg.Issuer == "" {
+			return nil, errors.New("issuer is required")
+		}
+
+		return oidc.NewOIDC(cfg.Issuer, oauthCfg), nil
+	case config.SSOTypeGitHub:
+		return oidc.NewGitHub(oauthCfg), nil
+	default:
+		return nil, fmt.Errorf("unknown sso provider type %q", cfg.Type)
+	}
+}
+
+// newAliasOptions returns options of the configured alias generator.
+func newAliasOptions(cfg config.Aliases) (shortener.AliasOptions, error) {
+	opts := shortener.AliasOptions{
+		Length:   cfg.Length,
+		Attempts: cfg.GenerateAttempts,
+	}
+
+	switch cfg.Generator {
+	case config.AliasGeneratorRandom:
+	case config.AliasGeneratorSnowflake:
+		g, err := snowflake.New(cfg.NodeID)
+		if err != nil {
+			return shortener.AliasOptions{}, err
+		}
+
+		opts.Sequence = g
+	default:
+		return shortener.AliasOptions{}, fmt.Errorf("unknown alias generator %q", cfg.Generator)
+	}
+
+	return opts, nil
+}
+
+// setupTLS configures srv for the TLS mode and returns the function starting it.
+// In autocert mode it also returns the server answering ACME HTTP-01 challenges.
+func setupTLS(srv *http.Server, cfg config.TLS) (func(net.Listener) error, *http.Server, error) {
+	var challengeSrv *http.Server
+
+	switch cfg.Mode {
+	case config.TLSModeOff:
+		return srv.Serve, nil, nil
+	case config.TLSModeFiles:
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return nil, nil, errors.New("cert_file and key_file are required")
+		}
+
+		// Сертификат загружается в TLSConfig, чтобы его разделял сервер HTTP/3
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load certificate: %w", err)
+		}
+
+		srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	case config.TLSModeAutocert:
+		if len(cfg.Hosts) == 0 {
+			return nil, nil, errors.New("hosts are required for autocert")
+		}
+
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.Hosts...),
+			Cache:      autocert.DirCache(cfg.CacheDir),
+		}
+
+		srv.TLSConfig = m.TLSConfig()
+
+		// Без ответа на HTTP-01 Let's Encrypt не выдаст сертификат;
+		// остальные запросы по HTTP перенаправляются на HTTPS
+		challengeSrv = &http.Server{
+			Addr:              cfg.HTTPAddress,
+			Handler:           m.HTTPHandler(nil),
+			ReadHeaderTimeout: srv.ReadTimeout,
+		}
+	default:
+		return nil, nil, fmt.Errorf("unknown tls mode %q", cfg.Mode)
+	}
+
+	if err := setupHTTP2(srv, cfg.HTTP2); err != nil {
+		return nil, nil, fmt.Errorf("failed to configure http2: %w", err)
+	}
+
+	return func(ln net.Listener) error { return srv.ServeTLS(ln, "", "") }, challengeSrv, nil
+}
+
+// setupHTTP2 serves HTTP/2 to clients negotiating h2 or, when it is disabled,
+// keeps all clients on HTTP/1.1. srv.TLSConfig must be set.
+func setupHTTP2(srv *http.Server, enabled bool) error {
+	if enabled {
+		return http2.ConfigureServer(srv, &http2.Server{IdleTimeout: srv.IdleTimeout})
+	}
+
+	// Непустая TLSNextProto отключает встроенный HTTP/2, а без h2 в ALPN клиент его и не попросит
+	srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	srv.TLSConfig.NextProtos = slices.DeleteFunc(slices.Clone(srv.TLSConfig.NextProtos), func(proto string) bool {
+		return proto == http2.NextProtoTLS
+	})
+
+	return nil
+}
+
+// setupHTTP3 returns the HTTP/3 server sharing the handler and certificates of srv
+// and makes srv advertise it to clients in the Alt-Svc header.
+func setupHTTP3(srv *http.Server, cfg config.HTTPServer) (*http3.Server, error) {
+	address := cfg.TLS.HTTP3Address
+	if address == "" {
+		if strings.HasPrefix(cfg.Address, listener.UnixPrefix) || strings.HasPrefix(cfg.Address, listener.SystemdPrefix) {
+			return nil, errors.New("http3_address is required when address is not host:port")
+		}
+
+		address = cfg.Address
+	}
+
+	h3 := &http3.Server{
+		Addr:        address,
+		Handler:     srv.Handler,
+		TLSConfig:   http3.ConfigureTLSConfig(srv.TLSConfig),
+		IdleTimeout: srv.IdleTimeout,
+	}
+
+	handler := srv.Handler
+	srv.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Клиент узнает про HTTP/3 из ответа по TCP и следующие запросы шлет по QUIC.
+		// Ошибка означает, что UDP-порт еще не открыт, тогда заголовок не нужен
+		_ = h3.SetQUICHeaders(w.Header())
+
+		handler.ServeHTTP(w, r)
+	})
+
+	return h3, nil
+}
@@ -0,0 +1,108 @@
+// Package acl decides what a client may do with links. Users and API keys have
+// a role, and each role has the permissions of the roles before it:
+//
+//	action                                      viewer  editor  admin
+//	list links, read their details and stats    +       +       +
+//	export links                                +       +       +
+//	create and import links                     -       +       +
+//	update and delete own links                 -       +       +
+//	update and delete links of everyone         -       -       +
+//	set roles of users                          -       -       +
+//
+// A link is owned by the user or the API key it was saved with.
+package acl
+
+import (
+	"context"
+
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/jwt"
+	"url-shortener/internal/storage"
+)
+
+// Roles of users and API keys. Empty role means RoleEditor: users and keys created
+// before roles were introduced keep managing their links.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// levels orders roles by permissions.
+var levels = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// ValidRole reports whether role is one of the known roles or empty.
+func ValidRole(role string) bool {
+	_, ok := levels[role]
+
+	return ok || role == ""
+}
+
+// Allows reports whether role has the permissions of required.
+func Allows(role, required string) bool {
+	return levels[normalize(role)] >= levels[required]
+}
+
+func normalize(role string) string {
+	if role == "" {
+		return RoleEditor
+	}
+
+	return role
+}
+
+// Principal is the client of a request.
+type Principal struct {
+	UserID   int64
+	APIKeyID int64
+	Admin    bool
+}
+
+type ctxKey struct{}
+
+// WithRole returns a copy of ctx carrying the role of the client.
+func WithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, normalize(role))
+}
+
+// Role returns the role of the client, RoleEditor if it is not set.
+func Role(ctx context.Context) string {
+	role, _ := ctx.Value(ctxKey{}).(string)
+
+	return normalize(role)
+}
+
+// IsAdmin reports whether the client is an admin.
+func IsAdmin(ctx context.Context) bool {
+	return Role(ctx) == RoleAdmin
+}
+
+// FromContext returns the client authenticated by the middleware.
+func FromContext(ctx context.Context) Principal {
+	return Principal{
+		UserID:   jwt.UserID(ctx),
+		APIKeyID: apikey.KeyID(ctx),
+		Admin:    IsAdmin(ctx),
+	}
+}
+
+// CanManage reports whether p may update or delete u. Links saved by a user belong
+// to the user, links saved with an API key without a user belong to the key.
+// Links without an owner, e.g. saved before API keys were introduced, are managed
+// by admins only.
+func (p Principal) CanManage(u storage.URL) bool {
+	switch {
+	case p.Admin:
+		return true
+	case p.UserID != 0:
+		return u.UserID == p.UserID
+	case p.APIKeyID != 0:
+		return u.UserID == 0 && u.APIKeyID == p.APIKeyID
+	default:
+		return false
+	}
+}
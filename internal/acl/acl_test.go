@@ -0,0 +1,82 @@
+package acl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/jwt"
+	"url-shortener/internal/storage"
+)
+
+func TestCanManage(t *testing.T) {
+	cases := []struct {
+		name      string
+		principal Principal
+		url       storage.URL
+		want      bool
+	}{
+		{name: "Admin", principal: Principal{APIKeyID: 1, Admin: true}, url: storage.URL{UserID: 7}, want: true},
+		{name: "Own user link", principal: Principal{UserID: 7}, url: storage.URL{UserID: 7}, want: true},
+		{name: "Other user link", principal: Principal{UserID: 7}, url: storage.URL{UserID: 8}},
+		{name: "Own key link", principal: Principal{APIKeyID: 1}, url: storage.URL{APIKeyID: 1}, want: true},
+		{name: "Other key link", principal: Principal{APIKeyID: 1}, url: storage.URL{APIKeyID: 2}},
+		{name: "User link by key", principal: Principal{APIKeyID: 1}, url: storage.URL{UserID: 7}},
+		{name: "Link without owner", principal: Principal{APIKeyID: 1}, url: storage.URL{}},
+		{name: "Anonymous", url: storage.URL{}},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, tc.principal.CanManage(tc.url))
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	ctx := apikey.WithKeyID(context.Background(), 3)
+	assert.Equal(t, Principal{APIKeyID: 3}, FromContext(ctx))
+
+	ctx = WithRole(jwt.WithUserID(context.Background(), 7), RoleAdmin)
+	assert.Equal(t, Principal{UserID: 7, Admin: true}, FromContext(ctx))
+}
+
+func TestAllows(t *testing.T) {
+	cases := []struct {
+		role     string
+		required string
+		want     bool
+	}{
+		{role: RoleViewer, required: RoleViewer, want: true},
+		{role: RoleViewer, required: RoleEditor},
+		{role: RoleEditor, required: RoleViewer, want: true},
+		{role: RoleEditor, required: RoleAdmin},
+		{role: RoleAdmin, required: RoleEditor, want: true},
+		// Роль не задана у ключей и пользователей, созданных до появления ролей
+		{role: "", required: RoleEditor, want: true},
+		{role: "", required: RoleAdmin},
+		{role: "owner", required: RoleViewer},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.role+"/"+tc.required, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, Allows(tc.role, tc.required))
+		})
+	}
+}
+
+func TestRole(t *testing.T) {
+	assert.Equal(t, RoleEditor, Role(context.Background()))
+	assert.Equal(t, RoleViewer, Role(WithRole(context.Background(), RoleViewer)))
+	assert.True(t, IsAdmin(WithRole(context.Background(), RoleAdmin)))
+}
@@ -0,0 +1,335 @@
+package analytics
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/geoip"
+	"url-shortener/internal/lib/hll"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// ClickEventsSaver is an interface for persisting click events.
+type ClickEventsSaver interface {
+	SaveClickEvents(ctx context.Context, events []storage.ClickEvent) error
+}
+
+// Locator is an interface for looking up the location of visitors by IP.
+type Locator interface {
+	Lookup(ip net.IP) (geoip.Location, error)
+}
+
+// Recorder writes click events to storage in batches from a background goroutine.
+// Events are anonymized before they leave the process memory.
+type Recorder struct {
+	log           *slog.Logger
+	store         ClickEventsSaver
+	locator       Locator
+	anonymize     Anonymizer
+	flushInterval time.Duration
+	batchSize     int
+
+	events chan storage.ClickEvent
+	done   chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+}
+
+// New starts the recorder. Events are saved every flushInterval or once the buffer is
+// full; a zero flushInterval saves them by full buffers only.
+// locator may be nil, then the location of clicks is unknown.
+// anonymize may be nil, then IPs are truncated by AnonymizeIP.
+func New(
+	log *slog.Logger,
+	store ClickEventsSaver,
+	locator Locator,
+	anonymize Anonymizer,
+	bufferSize int,
+	flushInterval time.Duration,
+) *Recorder {
+	if anonymize == nil {
+		anonymize = AnonymizeIP
+	}
+
+	rec := &Recorder{
+		log:           log.With(slog.String("component", "analytics")),
+		store:         store,
+		locator:       locator,
+		anonymize:     anonymize,
+		flushInterval: flushInterval,
+		batchSize:     bufferSize,
+		events:        make(chan storage.ClickEvent, bufferSize),
+		done:          make(chan struct{}),
+	}
+
+	rec.wg.Add(1)
+	go rec.run()
+
+	return rec
+}
+
+// Record queues click event. It never blocks: if the buffer is full, the event is dropped.
+func (rec *Recorder) Record(e storage.ClickEvent) {
+	select {
+	case rec.events <- e:
+	default:
+		rec.log.Warn("click event buffer is full, event dropped", slog.String("alias", e.Alias))
+	}
+}
+
+// Close stops the recorder and flushes buffered events.
+func (rec *Recorder) Close() {
+	rec.once.Do(func() {
+		close(rec.done)
+	})
+
+	rec.wg.Wait()
+}
+
+func (rec *Recorder) run() {
+	defer rec.wg.Done()
+
+	// Без интервала канал остается nil и сброс идет только по размеру пачки
+	var tick <-chan time.Time
+	if rec.flushInterval > 0 {
+		ticker := time.NewTicker(rec.flushInterval)
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	var batch []storage.ClickEvent
+
+	for {
+		select {
+		case e := <-rec.events:
+			batch = append(batch, rec.prepare(e))
+			if len(batch) >= rec.batchSize {
+				rec.flush(batch)
+				batch = nil
+			}
+		case <-tick:
+			rec.flush(batch)
+			batch = nil
+		case <-rec.done:
+			for {
+				select {
+				case e := <-rec.events:
+					batch = append(batch, rec.prepare(e))
+				default:
+					rec.flush(batch)
+
+					return
+				}
+			}
+		}
+	}
+}
+
+func (rec *Recorder) flush(batch []storage.ClickEvent) {
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := rec.store.SaveClickEvents(context.Background(), batch); err != nil {
+		rec.log.Error("failed to save click events", slog.Int("count", len(batch)), sl.Err(err))
+	}
+}
+
+// prepare anonymizes the event and fills derived fields.
+func (rec *Recorder) prepare(e storage.ClickEvent) storage.ClickEvent {
+	// Посетитель и место определяются по полному адресу, поэтому до анонимизации
+	e.Visitor = hll.Hash(e.IP, e.UserAgent)
+	e.Country, e.City = rec.locate(e.IP)
+	e.IP = rec.anonymize(e.IP)
+	e.Browser = Browser(e.UserAgent)
+	e.ReferrerHost = ReferrerHost(e.Referrer)
+	e.UTMSource = utmValue(e.UTMSource)
+	e.UTMMedium = utmValue(e.UTMMedium)
+	e.UTMCampaign = utmValue(e.UTMCampaign)
+
+	return e
+}
+
+func (rec *Recorder) locate(ip string) (country, city string) {
+	if rec.locator == nil {
+		return "", ""
+	}
+
+	loc, err := rec.locator.Lookup(net.ParseIP(ip))
+	if err != nil {
+		if !errors.Is(err, geoip.ErrNotFound) {
+			rec.log.Error("failed to look up location", sl.Err(err))
+		}
+
+		return "", ""
+	}
+
+	return loc.Country, loc.City
+}
+
+// ReferrerHost returns the lowercased host of the referrer without "www.", empty for
+// an empty referrer and "unknown" if the referrer has no host.
+func ReferrerHost(referrer string) string {
+	if referrer == "" {
+		return ""
+	}
+
+	u, err := url.Parse(referrer)
+	if err != nil || u.Hostname() == "" {
+		return "unknown"
+	}
+
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// maxUTMLength limits utm_* values, which come straight from the visitor's URL.
+const maxUTMLength = 100
+
+func utmValue(v string) string {
+	v = strings.TrimSpace(v)
+	if len(v) > maxUTMLength {
+		// Обрезка могла разрезать многобайтовый символ
+		v = strings.ToValidUTF8(v[:maxUTMLength], "")
+	}
+
+	return v
+}
+
+// IP modes of click events.
+const (
+	// IPModeTruncate keeps the network of the address, see AnonymizeIP.
+	IPModeTruncate = "truncate"
+	// IPModeHash keeps a keyed hash of the address: clicks from one address can be
+	// matched, but the address can't be recovered without the key.
+	IPModeHash = "hash"
+	// IPModeDrop keeps no address at all.
+	IPModeDrop = "drop"
+)
+
+// Anonymizer turns the client IP into the value stored with the click event.
+type Anonymizer func(ip string) string
+
+// NewAnonymizer returns the anonymizer of the IP mode. Hash mode uses hashKey, or a
+// random key if it is empty, so that hashes can't be matched across restarts.
+func NewAnonymizer(mode string, hashKey string) (Anonymizer, error) {
+	const op = "analytics.NewAnonymizer"
+
+	switch mode {
+	case IPModeTruncate, "":
+		return AnonymizeIP, nil
+	case IPModeDrop:
+		return func(string) string { return "" }, nil
+	case IPModeHash:
+		key := []byte(hashKey)
+		if len(key) == 0 {
+			key = make([]byte, 32)
+			if _, err := rand.Read(key); err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		return func(ip string) string { return HashIP(key, ip) }, nil
+	default:
+		return nil, fmt.Errorf("%s: unknown ip mode %q", op, mode)
+	}
+}
+
+// hashLength is the length of IP hashes in bytes: 128 bits are enough to tell
+// addresses apart and take half the space of the full HMAC.
+const hashLength = 16
+
+// HashIP returns the hex HMAC-SHA256 of the normalized address under key.
+// Unparsable values are dropped.
+func HashIP(key []byte, ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	// Один адрес в разной записи (::ffff:1.2.3.4 и 1.2.3.4) дает один хеш
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(parsed.String()))
+
+	return hex.EncodeToString(mac.Sum(nil)[:hashLength])
+}
+
+// AnonymizeIP zeroes the last octet of IPv4 addresses and all but the first 48 bits of IPv6 ones.
+// Unparsable values are dropped.
+func AnonymizeIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// Browser returns the browser family of the user agent.
+func Browser(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	// Порядок важен: UA Edge и Opera содержат "chrome", а UA Chrome содержит "safari"
+	switch {
+	case ua == "":
+		return "unknown"
+	case strings.Contains(ua, "bot"), strings.Contains(ua, "spider"), strings.Contains(ua, "crawl"):
+		return "bot"
+	case strings.Contains(ua, "edg/"), strings.Contains(ua, "edge/"):
+		return "edge"
+	case strings.Contains(ua, "opr/"), strings.Contains(ua, "opera"):
+		return "opera"
+	case strings.Contains(ua, "firefox/"), strings.Contains(ua, "fxios/"):
+		return "firefox"
+	case strings.Contains(ua, "chrome/"), strings.Contains(ua, "crios/"):
+		return "chrome"
+	case strings.Contains(ua, "safari/"):
+		return "safari"
+	case strings.Contains(ua, "curl/"), strings.Contains(ua, "wget/"), strings.Contains(ua, "go-http-client"):
+		return "cli"
+	default:
+		return "other"
+	}
+}
+
+// Multi passes click events to every recorder.
+type Multi []interface {
+	Record(e storage.ClickEvent)
+	Close()
+}
+
+func (m Multi) Record(e storage.ClickEvent) {
+	for _, rec := range m {
+		rec.Record(e)
+	}
+}
+
+func (m Multi) Close() {
+	for _, rec := range m {
+		rec.Close()
+	}
+}
+
+// Nop discards click events. It is used when analytics is disabled.
+type Nop struct{}
+
+func (Nop) Record(storage.ClickEvent) {}
+
+func (Nop) Close() {}
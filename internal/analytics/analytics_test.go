@@ -0,0 +1,195 @@
+package analytics
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/geoip"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestAnonymizeIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{ip: "203.0.113.42", want: "203.0.113.0"},
+		{ip: "2001:db8:85a3:8d3:1319:8a2e:370:7348", want: "2001:db8:85a3::"},
+		{ip: "not an ip", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.want, AnonymizeIP(tt.ip))
+		})
+	}
+}
+
+func TestNewAnonymizer(t *testing.T) {
+	truncate, err := NewAnonymizer(IPModeTruncate, "")
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.0", truncate("203.0.113.42"))
+
+	drop, err := NewAnonymizer(IPModeDrop, "")
+	require.NoError(t, err)
+	assert.Empty(t, drop("203.0.113.42"))
+
+	hash, err := NewAnonymizer(IPModeHash, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, HashIP([]byte("secret"), "203.0.113.42"), hash("203.0.113.42"))
+	assert.Len(t, hash("203.0.113.42"), 2*hashLength)
+	assert.Equal(t, hash("203.0.113.42"), hash("::ffff:203.0.113.42"))
+	assert.NotEqual(t, hash("203.0.113.42"), hash("203.0.113.43"))
+	assert.Empty(t, hash("not an ip"))
+
+	// Без ключа хеши разных запусков не совпадают
+	random1, err := NewAnonymizer(IPModeHash, "")
+	require.NoError(t, err)
+	random2, err := NewAnonymizer(IPModeHash, "")
+	require.NoError(t, err)
+	assert.NotEqual(t, random1("203.0.113.42"), random2("203.0.113.42"))
+
+	_, err = NewAnonymizer("mask", "")
+	assert.Error(t, err)
+}
+
+func TestBrowser(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want string
+	}{
+		{
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36",
+			want: "chrome",
+		},
+		{
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36 Edg/120.0",
+			want: "edge",
+		},
+		{
+			ua:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15",
+			want: "safari",
+		},
+		{
+			ua:   "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			want: "firefox",
+		},
+		{
+			ua:   "Googlebot/2.1 (+http://www.google.com/bot.html)",
+			want: "bot",
+		},
+		{
+			ua:   "",
+			want: "unknown",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, Browser(tt.ua))
+		})
+	}
+}
+
+func TestReferrerHost(t *testing.T) {
+	tests := []struct {
+		referrer string
+		want     string
+	}{
+		{referrer: "https://www.Google.com/search?q=x", want: "google.com"},
+		{referrer: "https://t.me:443/channel", want: "t.me"},
+		{referrer: "android-app://com.google.android.gm/", want: "com.google.android.gm"},
+		{referrer: "not a url", want: "unknown"},
+		{referrer: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.referrer, func(t *testing.T) {
+			assert.Equal(t, tt.want, ReferrerHost(tt.referrer))
+		})
+	}
+}
+
+func TestPrepare_UTM(t *testing.T) {
+	rec := &Recorder{anonymize: AnonymizeIP}
+
+	e := rec.prepare(storage.ClickEvent{
+		UTMSource:   " newsletter ",
+		UTMMedium:   "email",
+		UTMCampaign: strings.Repeat("я", maxUTMLength),
+	})
+
+	assert.Equal(t, "newsletter", e.UTMSource)
+	assert.Equal(t, "email", e.UTMMedium)
+	assert.Equal(t, strings.Repeat("я", maxUTMLength/2), e.UTMCampaign)
+}
+
+type locatorFunc func(ip net.IP) (geoip.Location, error)
+
+func (f locatorFunc) Lookup(ip net.IP) (geoip.Location, error) {
+	return f(ip)
+}
+
+func TestPrepare_Location(t *testing.T) {
+	rec := &Recorder{
+		anonymize: AnonymizeIP,
+		locator: locatorFunc(func(ip net.IP) (geoip.Location, error) {
+			if ip.String() != "203.0.113.42" {
+				return geoip.Location{}, geoip.ErrNotFound
+			}
+
+			return geoip.Location{Country: "DE", Continent: "EU", City: "Berlin"}, nil
+		}),
+	}
+
+	// Место определяется по полному адресу, сохраняется анонимизированный
+	e := rec.prepare(storage.ClickEvent{IP: "203.0.113.42"})
+	assert.Equal(t, "DE", e.Country)
+	assert.Equal(t, "Berlin", e.City)
+	assert.Equal(t, "203.0.113.0", e.IP)
+
+	e = rec.prepare(storage.ClickEvent{IP: "198.51.100.1"})
+	assert.Empty(t, e.Country)
+	assert.Empty(t, e.City)
+}
+
+type memSaver struct {
+	mu     sync.Mutex
+	events []storage.ClickEvent
+}
+
+func (s *memSaver) SaveClickEvents(_ context.Context, events []storage.ClickEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, events...)
+
+	return nil
+}
+
+func (s *memSaver) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.events)
+}
+
+func TestRecorder_ZeroInterval(t *testing.T) {
+	store := &memSaver{}
+
+	// Нулевой интервал означает сброс только полными пачками, без паники тикера
+	rec := New(slogdiscard.NewDiscardLogger(), store, nil, nil, 2, 0)
+	defer rec.Close()
+
+	rec.Record(storage.ClickEvent{Alias: "a"})
+	rec.Record(storage.ClickEvent{Alias: "b"})
+
+	require.Eventually(t, func() bool {
+		return store.count() == 2
+	}, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,246 @@
+// Package backup makes copies of the SQLite database on a cron schedule, keeps the
+// newest of them and restores the database from a copy.
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/cron"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+var (
+	ErrNoBackups = errors.New("no backups")
+	ErrNotSQLite = errors.New("backup is not a SQLite database")
+)
+
+// Names of backups sort in the order they were made.
+const (
+	namePrefix = "url-shortener-"
+	nameSuffix = ".db"
+	timeLayout = "20060102T150405Z"
+)
+
+// sqliteHeader starts every SQLite database file.
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+// Backuper writes a consistent copy of the database to a file, see sqlite.Storage.Backup.
+type Backuper interface {
+	Backup(ctx context.Context, path string) error
+}
+
+// Store is where backups are kept: a local directory or an S3-compatible bucket, see
+// blob.Dir and blob.S3.
+type Store interface {
+	Put(ctx context.Context, name string, r io.Reader) error
+	// Get returns blob.ErrNotFound if there is no backup with the name.
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns names of all files of the store, in any order.
+	List(ctx context.Context) ([]string, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// Run makes a backup at every time of schedule until ctx is done and keeps the
+// newest keep backups, all of them if keep is 0.
+func Run(ctx context.Context, log *slog.Logger, db Backuper, store Store, schedule *cron.Schedule, keep int) {
+	const op = "backup.Run"
+
+	log = log.With(slog.String("op", op))
+
+	for {
+		next := schedule.Next(time.Now().UTC())
+		if next.IsZero() {
+			log.Warn("backup schedule never matches")
+
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return
+		case <-timer.C:
+			name, err := Create(ctx, db, store, keep)
+			if err != nil {
+				log.Error("failed to back up database", sl.Err(err))
+
+				continue
+			}
+
+			log.Info("database backed up", slog.String("name", name))
+		}
+	}
+}
+
+// Create makes a backup of db, puts it to store and removes the backups beyond the
+// newest keep. It returns the name of the backup.
+func Create(ctx context.Context, db Backuper, store Store, keep int) (string, error) {
+	const op = "backup.Create"
+
+	name := namePrefix + time.Now().UTC().Format(timeLayout) + nameSuffix
+
+	// VACUUM INTO пишет только в файл, поэтому копия сначала ложится во временный каталог
+	tmp, err := os.MkdirTemp("", "url-shortener-backup")
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer os.RemoveAll(tmp)
+
+	path := filepath.Join(tmp, name)
+	if err := db.Backup(ctx, path); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer f.Close()
+
+	if err := store.Put(ctx, name, f); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := prune(ctx, store, keep); err != nil {
+		return name, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return name, nil
+}
+
+// List returns names of the backups in store, oldest first.
+func List(ctx context.Context, store Store) ([]string, error) {
+	const op = "backup.List"
+
+	names, err := store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	backups := names[:0]
+	for _, name := range names {
+		if isBackup(name) {
+			backups = append(backups, name)
+		}
+	}
+
+	sort.Strings(backups)
+
+	return backups, nil
+}
+
+func isBackup(name string) bool {
+	stamp, ok := strings.CutPrefix(name, namePrefix)
+	if !ok {
+		return false
+	}
+	stamp, ok = strings.CutSuffix(stamp, nameSuffix)
+	if !ok {
+		return false
+	}
+
+	_, err := time.Parse(timeLayout, stamp)
+
+	return err == nil
+}
+
+func prune(ctx context.Context, store Store, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	backups, err := List(ctx, store)
+	if err != nil {
+		return err
+	}
+
+	for len(backups) > keep {
+		if err := store.Delete(ctx, backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+// Restore replaces the database at dst with the backup name, the newest one if name is
+// empty, and returns the name. The server must be stopped: open connections would keep
+// using the replaced file.
+func Restore(ctx context.Context, store Store, name, dst string) (string, error) {
+	const op = "backup.Restore"
+
+	if name == "" {
+		backups, err := List(ctx, store)
+		if err != nil {
+			return "", err
+		}
+		if len(backups) == 0 {
+			return "", fmt.Errorf("%s: %w", op, ErrNoBackups)
+		}
+
+		name = backups[len(backups)-1]
+	}
+
+	r, err := store.Get(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer r.Close()
+
+	// Пишем рядом с базой и переименовываем, чтобы оборванное восстановление не испортило ее
+	tmp := dst + ".restore"
+	if err := writeDatabase(tmp, r); err != nil {
+		_ = os.Remove(tmp)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := os.Rename(tmp, dst); err != nil {
+		_ = os.Remove(tmp)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Журнал WAL старой базы нельзя применять к восстановленной
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dst + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return name, nil
+}
+
+func writeDatabase(path string, r io.Reader) error {
+	header := make([]byte, len(sqliteHeader))
+	if _, err := io.ReadFull(r, header); err != nil || !bytes.Equal(header, sqliteHeader) {
+		return ErrNotSQLite
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, io.MultiReader(bytes.NewReader(header), r))
+	if err == nil {
+		err = f.Sync()
+	}
+
+	return errors.Join(err, f.Close())
+}
@@ -0,0 +1,110 @@
+package backup_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/backup"
+	"url-shortener/internal/blob"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/sqlite"
+)
+
+func TestCreateRestore(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "storage.db")
+
+	s, err := sqlite.New(dbPath, sqlite.Options{JournalMode: "WAL"})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "kept", URL: "https://kept.example"})
+	require.NoError(t, err)
+
+	store, err := blob.NewDir(filepath.Join(t.TempDir(), "backups"))
+	require.NoError(t, err)
+
+	name, err := backup.Create(ctx, s, store, 0)
+	require.NoError(t, err)
+
+	// Ссылка после копии в восстановленную базу не попадет
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "lost", URL: "https://lost.example"})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	restored, err := backup.Restore(ctx, store, "", dbPath)
+	require.NoError(t, err)
+	require.Equal(t, name, restored)
+
+	s, err = sqlite.New(dbPath, sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	_, err = s.GetURLInfo(ctx, "kept")
+	require.NoError(t, err)
+	_, err = s.GetURLInfo(ctx, "lost")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestCreate_Keep(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := blob.NewDir(t.TempDir())
+	require.NoError(t, err)
+
+	// Чужие файлы в каталоге не считаются копиями и не удаляются
+	require.NoError(t, store.Put(ctx, "notes.txt", strings.NewReader("notes")))
+
+	for _, name := range []string{
+		"url-shortener-20261001T030000Z.db",
+		"url-shortener-20261002T030000Z.db",
+		"url-shortener-20261003T030000Z.db",
+	} {
+		require.NoError(t, store.Put(ctx, name, strings.NewReader("SQLite format 3\x00")))
+	}
+
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	name, err := backup.Create(ctx, s, store, 2)
+	require.NoError(t, err)
+
+	backups, err := backup.List(ctx, store)
+	require.NoError(t, err)
+	require.Equal(t, []string{"url-shortener-20261003T030000Z.db", name}, backups)
+
+	names, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Contains(t, names, "notes.txt")
+}
+
+func TestRestore_Errors(t *testing.T) {
+	ctx := context.Background()
+	dst := filepath.Join(t.TempDir(), "storage.db")
+
+	store, err := blob.NewDir(t.TempDir())
+	require.NoError(t, err)
+
+	_, err = backup.Restore(ctx, store, "", dst)
+	require.ErrorIs(t, err, backup.ErrNoBackups)
+
+	_, err = backup.Restore(ctx, store, "url-shortener-20261001T030000Z.db", dst)
+	require.ErrorIs(t, err, blob.ErrNotFound)
+
+	require.NoError(t, store.Put(ctx, "url-shortener-20261001T030000Z.db", strings.NewReader("not a database")))
+
+	_, err = backup.Restore(ctx, store, "", dst)
+	require.ErrorIs(t, err, backup.ErrNotSQLite)
+
+	// Неудачное восстановление не оставляет файлов
+	_, err = os.Stat(dst)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(dst + ".restore")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
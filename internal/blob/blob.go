@@ -0,0 +1,22 @@
+// Package blob stores named objects such as backups and exports in a local directory
+// or in an S3-compatible bucket: AWS S3, MinIO and others speaking its API.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Bucket is a flat store of objects. Dir and S3 implement it.
+type Bucket interface {
+	Put(ctx context.Context, name string, r io.Reader) error
+	// Get returns ErrNotFound if there is no object with the name.
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns names of all objects, in any order.
+	List(ctx context.Context) ([]string, error)
+	// Delete doesn't fail if there is no object with the name.
+	Delete(ctx context.Context, name string) error
+}
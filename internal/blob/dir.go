@@ -0,0 +1,83 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Dir keeps objects as files of a local directory.
+type Dir struct {
+	path string
+}
+
+// NewDir returns the store in the directory path, creating it if needed.
+func NewDir(path string) (*Dir, error) {
+	const op = "blob.NewDir"
+
+	if err := os.MkdirAll(path, 0o700); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Dir{path: path}, nil
+}
+
+func (d *Dir) Put(_ context.Context, name string, r io.Reader) error {
+	name = filepath.Base(name)
+
+	// Недописанный файл не должен попасть в список
+	f, err := os.CreateTemp(d.path, "."+name+".*")
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, r)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err = errors.Join(err, f.Close()); err == nil {
+		err = os.Rename(f.Name(), filepath.Join(d.path, name))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+
+	return err
+}
+
+func (d *Dir) Get(_ context.Context, name string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(d.path, filepath.Base(name)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return f, err
+}
+
+func (d *Dir) List(_ context.Context) ([]string, error) {
+	entries, err := os.ReadDir(d.path)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.Type().IsRegular() {
+			names = append(names, e.Name())
+		}
+	}
+
+	return names, nil
+}
+
+func (d *Dir) Delete(_ context.Context, name string) error {
+	err := os.Remove(filepath.Join(d.path, filepath.Base(name)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
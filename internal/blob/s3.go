@@ -0,0 +1,357 @@
+package blob
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Server-side encryption modes, see S3Options.Encryption.
+const (
+	// EncryptionS3 encrypts objects with keys managed by the storage (SSE-S3).
+	EncryptionS3 = "sse-s3"
+	// EncryptionKMS encrypts objects with a KMS key, S3Options.KMSKeyID or the
+	// default key of the bucket (SSE-KMS).
+	EncryptionKMS = "sse-kms"
+	// EncryptionCustomer encrypts objects with S3Options.CustomerKey, which the
+	// storage doesn't keep; it must be sent to read the objects (SSE-C).
+	EncryptionCustomer = "sse-c"
+)
+
+var ErrInvalidOptions = errors.New("invalid s3 options")
+
+type S3Options struct {
+	// Endpoint is the base URL of the storage, e.g. "https://s3.eu-west-1.amazonaws.com"
+	// or "http://localhost:9000" for MinIO.
+	Endpoint string
+	Region   string
+	Bucket   string
+	// PathStyle addresses the bucket as Endpoint/Bucket instead of Bucket.host of
+	// Endpoint; MinIO needs it.
+	PathStyle       bool
+	AccessKeyID     string
+	SecretAccessKey string
+	// Prefix is prepended to the names of objects, so that a bucket can be shared.
+	Prefix string
+	// Encryption is "", EncryptionS3, EncryptionKMS or EncryptionCustomer.
+	Encryption string
+	KMSKeyID   string
+	// CustomerKey is the base64 encoded 256-bit key of EncryptionCustomer.
+	CustomerKey string
+	Timeout     time.Duration
+}
+
+// S3 is a client of an S3-compatible bucket. Requests are signed with AWS Signature
+// Version 4. Objects are uploaded with a single PUT, so they are limited to 5 GiB.
+type S3 struct {
+	endpoint *url.URL
+	opts     S3Options
+	// customerKeyMD5 is the base64 MD5 of the decoded CustomerKey, sent with it.
+	customerKeyMD5 string
+	client         *http.Client
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+func NewS3(opts S3Options) (*S3, error) {
+	const op = "blob.NewS3"
+
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("%s: %w: endpoint %q", op, ErrInvalidOptions, opts.Endpoint)
+	}
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("%s: %w: bucket is required", op, ErrInvalidOptions)
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+
+	s := &S3{
+		endpoint: endpoint,
+		opts:     opts,
+		client:   &http.Client{Timeout: opts.Timeout},
+		now:      time.Now,
+	}
+
+	switch opts.Encryption {
+	case "", EncryptionS3, EncryptionKMS:
+	case EncryptionCustomer:
+		key, err := base64.StdEncoding.DecodeString(opts.CustomerKey)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("%s: %w: customer key must be 32 bytes in base64", op, ErrInvalidOptions)
+		}
+		// Ключ передается в заголовке, по HTTP он ушел бы открытым
+		if endpoint.Scheme != "https" {
+			return nil, fmt.Errorf("%s: %w: %s needs an https endpoint", op, ErrInvalidOptions, EncryptionCustomer)
+		}
+
+		sum := md5.Sum(key)
+		s.customerKeyMD5 = base64.StdEncoding.EncodeToString(sum[:])
+	default:
+		return nil, fmt.Errorf("%s: %w: unknown encryption %q", op, ErrInvalidOptions, opts.Encryption)
+	}
+
+	return s, nil
+}
+
+// Sub returns the client of the objects under prefix, e.g. "backups/".
+func (s *S3) Sub(prefix string) *S3 {
+	sub := *s
+	sub.opts.Prefix += prefix
+
+	return &sub
+}
+
+func (s *S3) Put(ctx context.Context, name string, r io.Reader) error {
+	const op = "blob.S3.Put"
+
+	// Подпись и Content-Length требуют знать тело заранее
+	body, size, sum, cleanup, err := spool(r)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer cleanup()
+
+	// Пустое тело без NoBody ушло бы chunked, а S3 требует длину
+	if size == 0 {
+		body = http.NoBody
+	}
+
+	req, err := s.request(ctx, http.MethodPut, s.opts.Prefix+name, nil, body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	req.ContentLength = size
+
+	switch s.opts.Encryption {
+	case EncryptionS3:
+		req.Header.Set("X-Amz-Server-Side-Encryption", "AES256")
+	case EncryptionKMS:
+		req.Header.Set("X-Amz-Server-Side-Encryption", "aws:kms")
+		if s.opts.KMSKeyID != "" {
+			req.Header.Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", s.opts.KMSKeyID)
+		}
+	}
+	s.setCustomerKey(req)
+
+	res, err := s.do(req, sum)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	_ = res.Body.Close()
+
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	const op = "blob.S3.Get"
+
+	req, err := s.request(ctx, http.MethodGet, s.opts.Prefix+name, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	s.setCustomerKey(req)
+
+	res, err := s.do(req, emptySHA256)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res.Body, nil
+}
+
+// listResult is the response of ListObjectsV2.
+type listResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List returns names of the objects under Prefix, without it.
+func (s *S3) List(ctx context.Context) ([]string, error) {
+	const op = "blob.S3.List"
+
+	var names []string
+
+	query := url.Values{"list-type": {"2"}}
+	if s.opts.Prefix != "" {
+		query.Set("prefix", s.opts.Prefix)
+	}
+
+	for {
+		req, err := s.request(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		res, err := s.do(req, emptySHA256)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		var result listResult
+		err = xml.NewDecoder(res.Body).Decode(&result)
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, c := range result.Contents {
+			names = append(names, strings.TrimPrefix(c.Key, s.opts.Prefix))
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return names, nil
+		}
+
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+func (s *S3) Delete(ctx context.Context, name string) error {
+	const op = "blob.S3.Delete"
+
+	req, err := s.request(ctx, http.MethodDelete, s.opts.Prefix+name, nil, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := s.do(req, emptySHA256)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	_ = res.Body.Close()
+
+	return nil
+}
+
+// request builds the request to the object key, or to the bucket if key is empty.
+func (s *S3) request(ctx context.Context, method, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	u := *s.endpoint
+	path := strings.TrimSuffix(u.Path, "/")
+
+	if s.opts.PathStyle {
+		path += "/" + s.opts.Bucket
+	} else {
+		u.Host = s.opts.Bucket + "." + u.Host
+	}
+	path += "/" + key
+
+	u.Path = path
+	// Ключ кодируется так же, как в подписи, иначе подпись не сойдется
+	u.RawPath = uriEncode(path, false)
+	u.RawQuery = canonicalQuery(query)
+
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+func (s *S3) setCustomerKey(req *http.Request) {
+	if s.opts.Encryption != EncryptionCustomer {
+		return
+	}
+
+	req.Header.Set("X-Amz-Server-Side-Encryption-Customer-Algorithm", "AES256")
+	req.Header.Set("X-Amz-Server-Side-Encryption-Customer-Key", s.opts.CustomerKey)
+	req.Header.Set("X-Amz-Server-Side-Encryption-Customer-Key-Md5", s.customerKeyMD5)
+}
+
+// s3Error is the error response of S3.
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// do signs and sends req with the body of payloadHash and returns the successful
+// response. A missing object or bucket is ErrNotFound.
+func (s *S3) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.sign(req, payloadHash, s.now())
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return res, nil
+	}
+	defer res.Body.Close()
+
+	var e s3Error
+	// У ответов на HEAD и некоторых ошибок тела нет
+	_ = xml.NewDecoder(io.LimitReader(res.Body, 64<<10)).Decode(&e)
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if e.Code != "" {
+		return nil, fmt.Errorf("s3 %s: %s: %s", res.Status, e.Code, e.Message)
+	}
+
+	return nil, fmt.Errorf("s3 %s", res.Status)
+}
+
+// spool returns r as a seekable body with its size and SHA-256. Files and other
+// seekers are read twice, the rest is copied to a temporary file.
+func spool(r io.Reader) (body io.Reader, size int64, sum string, cleanup func(), err error) {
+	h := sha256.New()
+	cleanup = func() {}
+
+	if rs, ok := r.(io.ReadSeeker); ok {
+		start, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, 0, "", cleanup, err
+		}
+		if size, err = io.Copy(h, rs); err != nil {
+			return nil, 0, "", cleanup, err
+		}
+		if _, err = rs.Seek(start, io.SeekStart); err != nil {
+			return nil, 0, "", cleanup, err
+		}
+
+		return io.LimitReader(rs, size), size, hexSum(h), cleanup, nil
+	}
+
+	f, err := os.CreateTemp("", "url-shortener-blob")
+	if err != nil {
+		return nil, 0, "", cleanup, err
+	}
+	cleanup = func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}
+
+	if size, err = io.Copy(io.MultiWriter(f, h), r); err != nil {
+		cleanup()
+
+		return nil, 0, "", func() {}, err
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+
+		return nil, 0, "", func() {}, err
+	}
+
+	return f, size, hexSum(h), cleanup, nil
+}
+
+func hexSum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
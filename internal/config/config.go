@@ -1,43 +1,738 @@
 package config
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"reflect"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	Env         string `yaml:"env" env-default:"local"`
-	StoragePath string `yaml:"storage_path" env-required:"true"`
+	Env string `yaml:"env" env:"US_ENV" env-default:"local"`
+	// LogLevel is "debug", "info", "warn" or "error", empty for the default of Env.
+	LogLevel    string `yaml:"log_level" env:"US_LOG_LEVEL"`
+	Log         Log    `yaml:"log"`
+	StoragePath string `yaml:"storage_path" env:"US_STORAGE_PATH"`
+	Storage     `yaml:"storage"`
 	HTTPServer  `yaml:"http_server"`
+	Internal    Internal    `yaml:"internal"`
+	HitCounter  HitCounter  `yaml:"hit_counter"`
+	Analytics   Analytics   `yaml:"analytics"`
+	Metrics     Metrics     `yaml:"metrics"`
+	Tracing     Tracing     `yaml:"tracing"`
+	Sentry      Sentry      `yaml:"sentry"`
+	Auth        Auth        `yaml:"auth"`
+	SMTP        SMTP        `yaml:"smtp"`
+	Signature   Signature   `yaml:"signature"`
+	RateLimit   RateLimit   `yaml:"rate_limit"`
+	CORS        CORS        `yaml:"cors"`
+	ClientIP    ClientIP    `yaml:"client_ip"`
+	IPFilter    IPFilter    `yaml:"ip_filter"`
+	Compression Compression `yaml:"compression"`
+	AccessLog   AccessLog   `yaml:"access_log"`
+	Redirect    Redirect    `yaml:"redirect"`
+	Timeouts    Timeouts    `yaml:"timeouts"`
+	Cache       Cache       `yaml:"cache"`
+	AliasFilter AliasFilter `yaml:"alias_filter"`
+	Aliases     Aliases     `yaml:"aliases"`
+	URLCheck    URLCheck    `yaml:"url_check"`
+	Screening   Screening   `yaml:"screening"`
+	DeadLinks   DeadLinks   `yaml:"dead_links"`
+	Backup      Backup      `yaml:"backup"`
+	S3          S3          `yaml:"s3"`
+	AdminStats  AdminStats  `yaml:"admin_stats"`
+	Captcha     Captcha     `yaml:"captcha"`
+	Preview     Preview     `yaml:"preview"`
+	PageMeta    PageMeta    `yaml:"page_meta"`
+	Static      Static      `yaml:"static"`
+	Webhook     Webhook     `yaml:"webhook"`
+	Events      Events      `yaml:"events"`
+	GeoIP       GeoIP       `yaml:"geoip"`
+	Privacy     Privacy     `yaml:"privacy"`
+	Telegram    Telegram    `yaml:"telegram"`
+	Slack       Slack       `yaml:"slack"`
+	QuickCreate QuickCreate `yaml:"quick_create"`
+	// Tenants are set in the config file only; without them all links share one namespace.
+	Tenants []Tenant `yaml:"tenants"`
+	// Domains are short domains links can be bound to, in addition to the ones
+	// registered via the admin API.
+	Domains []string `yaml:"domains" env:"US_DOMAINS"`
+}
+
+// Tenant is a namespace of aliases, e.g. of a brand hosted on the same deployment.
+// Requests are mapped to it by host or by the first path segment.
+type Tenant struct {
+	Name string `yaml:"name"`
+	// Hosts are the domains of the tenant, e.g. "go.brand.example".
+	Hosts []string `yaml:"hosts"`
+	// PathPrefix serves the tenant under a path, e.g. "/brand"; empty means none.
+	PathPrefix string `yaml:"path_prefix"`
+}
+
+// Event broker types.
+const (
+	EventsBrokerNone  = ""
+	EventsBrokerKafka = "kafka"
+	EventsBrokerNATS  = "nats"
+)
+
+type Events struct {
+	// Broker is "" (no events), "kafka" or "nats".
+	Broker        string        `yaml:"broker" env:"US_EVENTS_BROKER"`
+	BufferSize    int           `yaml:"buffer_size" env:"US_EVENTS_BUFFER_SIZE" env-default:"10000"`
+	FlushInterval time.Duration `yaml:"flush_interval" env:"US_EVENTS_FLUSH_INTERVAL" env-default:"1s"`
+	// Timeout limits sending of one batch of events.
+	Timeout time.Duration `yaml:"timeout" env:"US_EVENTS_TIMEOUT" env-default:"5s"`
+	Kafka   Kafka         `yaml:"kafka"`
+	NATS    NATS          `yaml:"nats"`
+}
+
+type Kafka struct {
+	// ProxyURL is the Kafka REST Proxy (v2 API) events are produced through.
+	ProxyURL string `yaml:"proxy_url" env:"US_KAFKA_PROXY_URL" env-default:"http://localhost:8082"`
+	Topic    string `yaml:"topic" env:"US_KAFKA_TOPIC" env-default:"url-shortener.events"`
+}
+
+type NATS struct {
+	URL string `yaml:"url" env:"US_NATS_URL" env-default:"nats://localhost:4222"`
+	// SubjectPrefix is followed by the event type, e.g. "url-shortener.link_clicked".
+	SubjectPrefix string `yaml:"subject_prefix" env:"US_NATS_SUBJECT_PREFIX" env-default:"url-shortener"`
+}
+
+type Webhook struct {
+	// Enabled turns on delivery of click events to the webhook urls of links and of
+	// link events to the webhooks subscribed via the admin API.
+	Enabled    bool          `yaml:"enabled" env:"US_WEBHOOK_ENABLED" env-default:"true"`
+	Workers    int           `yaml:"workers" env:"US_WEBHOOK_WORKERS" env-default:"4"`
+	BufferSize int           `yaml:"buffer_size" env:"US_WEBHOOK_BUFFER_SIZE" env-default:"1000"`
+	Timeout    time.Duration `yaml:"timeout" env:"US_WEBHOOK_TIMEOUT" env-default:"5s"`
+	// MaxRetries and RetryBackoff control retries of failed deliveries; the backoff doubles every retry.
+	MaxRetries   int           `yaml:"max_retries" env:"US_WEBHOOK_MAX_RETRIES" env-default:"3"`
+	RetryBackoff time.Duration `yaml:"retry_backoff" env:"US_WEBHOOK_RETRY_BACKOFF" env-default:"1s"`
+	// ReloadInterval is how soon changes of webhooks made via the admin API take effect.
+	ReloadInterval time.Duration `yaml:"reload_interval" env:"US_WEBHOOK_RELOAD_INTERVAL" env-default:"30s"`
+}
+
+// GeoIP locates visitors for geo-targeted redirects and click analytics; it is off
+// without a database.
+type GeoIP struct {
+	// DatabasePath is a MaxMind GeoLite2/GeoIP2 Country or City database (.mmdb);
+	// only City databases give cities of clicks.
+	DatabasePath string `yaml:"database_path" env:"US_GEOIP_DATABASE_PATH"`
+	// ReloadInterval is how often the file is checked for updates, e.g. by geoipupdate;
+	// zero loads it once.
+	ReloadInterval time.Duration `yaml:"reload_interval" env:"US_GEOIP_RELOAD_INTERVAL" env-default:"1m"`
+}
+
+// Telegram runs a bot shortening urls from chats; it is off without a token.
+type Telegram struct {
+	Token string `yaml:"token" env:"US_TELEGRAM_TOKEN"`
+	// BaseURL prefixes aliases in replies, e.g. "https://sho.rt".
+	BaseURL string `yaml:"base_url" env:"US_TELEGRAM_BASE_URL"`
+	// PollTimeout is how long a request for updates waits for new messages.
+	PollTimeout time.Duration `yaml:"poll_timeout" env:"US_TELEGRAM_POLL_TIMEOUT" env-default:"30s"`
+	// Users are set in the config file only; the bot answers anyone else with their
+	// Telegram user id, to be added here.
+	Users []TelegramUser `yaml:"users"`
+}
+
+// TelegramUser lets the Telegram user manage links as the account with the email.
+type TelegramUser struct {
+	ID    int64  `yaml:"id"`
+	Email string `yaml:"email"`
+}
+
+// Slack serves a slash command, e.g. /shorten, at POST /slack; it is off without
+// a signing secret.
+type Slack struct {
+	// SigningSecret is the signing secret of the Slack app.
+	SigningSecret string `yaml:"signing_secret" env:"US_SLACK_SIGNING_SECRET"`
+	// BaseURL prefixes aliases in replies, e.g. "https://sho.rt"; the host of the
+	// request if empty.
+	BaseURL string `yaml:"base_url" env:"US_SLACK_BASE_URL"`
+	// Workspaces are set in the config file only.
+	Workspaces []SlackWorkspace `yaml:"workspaces"`
+}
+
+// SlackWorkspace lets the Slack workspace create links with the API key, in its tenant
+// and with its role.
+type SlackWorkspace struct {
+	TeamID string `yaml:"team_id"`
+	APIKey string `yaml:"api_key"`
+}
+
+// QuickCreate serves GET /api/shorten?url= for bookmarklets and browser extensions:
+// one request with the API key in the X-API-Key header or the token query parameter,
+// allowed from any origin.
+type QuickCreate struct {
+	Enabled bool `yaml:"enabled" env:"US_QUICK_CREATE_ENABLED" env-default:"true"`
+	// BaseURL prefixes aliases of returned short urls, e.g. "https://sho.rt"; the
+	// scheme and host of the request if empty.
+	BaseURL string `yaml:"base_url" env:"US_QUICK_CREATE_BASE_URL"`
+}
+
+// Privacy limits personal data kept with click events, e.g. for GDPR compliance.
+type Privacy struct {
+	// IPMode is how client IPs are stored: "truncate" (network only), "hash" (keyed
+	// hash of the address) or "drop".
+	IPMode string `yaml:"ip_mode" env:"US_PRIVACY_IP_MODE" env-default:"truncate"`
+	// IPHashKey is the key of the "hash" mode; without it a random key is generated on
+	// every start, so hashes can't be matched across restarts.
+	IPHashKey string `yaml:"ip_hash_key" env:"US_PRIVACY_IP_HASH_KEY"`
+	// ClickRetention is how long click events are kept; hourly counts and unique
+	// visitor estimates outlive them. Zero keeps events forever.
+	ClickRetention time.Duration `yaml:"click_retention" env:"US_PRIVACY_CLICK_RETENTION"`
+}
+
+type Preview struct {
+	// FetchTitle shows the title of the destination page; the service requests the page for it.
+	FetchTitle   bool          `yaml:"fetch_title" env:"US_PREVIEW_FETCH_TITLE" env-default:"true"`
+	TitleTimeout time.Duration `yaml:"title_timeout" env:"US_PREVIEW_TITLE_TIMEOUT" env-default:"2s"`
+}
+
+// PageMeta fetches the title and OpenGraph metadata of the destination page of new
+// links in the background and stores them for lists, previews and the admin UI.
+type PageMeta struct {
+	// Enabled makes the service request the destination of every new link.
+	Enabled bool `yaml:"enabled" env:"US_PAGE_META_ENABLED" env-default:"false"`
+	// Timeout limits fetching of one page.
+	Timeout    time.Duration `yaml:"timeout" env:"US_PAGE_META_TIMEOUT" env-default:"3s"`
+	Workers    int           `yaml:"workers" env:"US_PAGE_META_WORKERS" env-default:"2"`
+	BufferSize int           `yaml:"buffer_size" env:"US_PAGE_META_BUFFER_SIZE" env-default:"1000"`
+}
+
+// Static configures robots.txt and favicon.ico.
+type Static struct {
+	// RobotsPath is a robots.txt served instead of the generated one.
+	RobotsPath string `yaml:"robots_path" env:"US_STATIC_ROBOTS_PATH"`
+	// RobotsAllow are paths crawlers may visit by the generated robots.txt, all others are disallowed.
+	RobotsAllow []string `yaml:"robots_allow" env:"US_STATIC_ROBOTS_ALLOW"`
+	// FaviconPath is an icon served instead of the built-in one.
+	FaviconPath string        `yaml:"favicon_path" env:"US_STATIC_FAVICON_PATH"`
+	MaxAge      time.Duration `yaml:"max_age" env:"US_STATIC_MAX_AGE" env-default:"24h"`
+}
+
+// Screening of link destinations is on when any source is configured. Hosts banned
+// via abuse reports are screened always.
+type Screening struct {
+	// BlocklistPath is a file of blocked hosts and urls, one per line.
+	BlocklistPath   string        `yaml:"blocklist_path" env:"US_SCREENING_BLOCKLIST_PATH"`
+	SafeBrowsingKey string        `yaml:"safe_browsing_key" env:"US_SCREENING_SAFE_BROWSING_KEY"`
+	Timeout         time.Duration `yaml:"timeout" env:"US_SCREENING_TIMEOUT" env-default:"2s"`
+	// RecheckInterval is how often saved links are screened again; flagged ones are quarantined.
+	RecheckInterval time.Duration `yaml:"recheck_interval" env:"US_SCREENING_RECHECK_INTERVAL" env-default:"24h"`
+}
+
+// DeadLinks is the background check of destinations of saved links: the ones returning
+// 404 or 410 or without DNS records are marked dead.
+type DeadLinks struct {
+	// Interval is how often all links are checked, 0 disables the check.
+	Interval time.Duration `yaml:"interval" env:"US_DEAD_LINKS_INTERVAL" env-default:"0"`
+	// Timeout limits the check of one destination, including redirects.
+	Timeout time.Duration `yaml:"timeout" env:"US_DEAD_LINKS_TIMEOUT" env-default:"5s"`
+	// Notify sends a "dead" event to the webhook_url of links found dead; it needs Webhook.Enabled.
+	Notify bool `yaml:"notify" env:"US_DEAD_LINKS_NOTIFY" env-default:"false"`
+}
+
+// Backup is the scheduled copy of the SQLite database; other drivers have their own
+// backup tools.
+type Backup struct {
+	// Schedule is a cron expression in UTC, e.g. "0 3 * * *" or "@daily"; empty disables backups.
+	Schedule string `yaml:"schedule" env:"US_BACKUP_SCHEDULE"`
+	// Target is "dir" to keep backups in Dir or "s3" to upload them to the S3 bucket,
+	// under the "backups/" prefix.
+	Target string `yaml:"target" env:"US_BACKUP_TARGET" env-default:"dir"`
+	// Dir is the local directory backups are written to.
+	Dir string `yaml:"dir" env:"US_BACKUP_DIR" env-default:"./backups"`
+	// Keep is the number of the newest backups kept, 0 keeps all of them.
+	Keep int `yaml:"keep" env:"US_BACKUP_KEEP" env-default:"7"`
+}
+
+// AdminStats is the /admin/stats endpoint for ops dashboards.
+type AdminStats struct {
+	// CacheTTL is how long the aggregates are reused, 0 computes them on every request.
+	CacheTTL time.Duration `yaml:"cache_ttl" env:"US_ADMIN_STATS_CACHE_TTL" env-default:"1m"`
+}
+
+// S3 is the S3-compatible bucket (AWS S3, MinIO) for backups and exports.
+type S3 struct {
+	// Endpoint is the base URL, e.g. "https://s3.eu-west-1.amazonaws.com" or "http://minio:9000".
+	Endpoint string `yaml:"endpoint" env:"US_S3_ENDPOINT"`
+	Region   string `yaml:"region" env:"US_S3_REGION,AWS_REGION" env-default:"us-east-1"`
+	Bucket   string `yaml:"bucket" env:"US_S3_BUCKET"`
+	// PathStyle puts the bucket into the path instead of the host name; MinIO needs it.
+	PathStyle       bool   `yaml:"path_style" env:"US_S3_PATH_STYLE" env-default:"false"`
+	AccessKeyID     string `yaml:"access_key_id" env:"US_S3_ACCESS_KEY_ID,AWS_ACCESS_KEY_ID"`
+	SecretAccessKey string `yaml:"secret_access_key" env:"US_S3_SECRET_ACCESS_KEY,AWS_SECRET_ACCESS_KEY"`
+	// Prefix is prepended to all object names, so that deployments can share a bucket.
+	Prefix string `yaml:"prefix" env:"US_S3_PREFIX"`
+	// Encryption is the server-side encryption of uploaded objects: "" (the bucket
+	// default), "sse-s3", "sse-kms" with KMSKeyID or "sse-c" with CustomerKey.
+	Encryption string `yaml:"encryption" env:"US_S3_ENCRYPTION"`
+	KMSKeyID   string `yaml:"kms_key_id" env:"US_S3_KMS_KEY_ID"`
+	// CustomerKey is the base64 256-bit key of "sse-c"; objects can't be read without it.
+	CustomerKey string        `yaml:"customer_key" env:"US_S3_CUSTOMER_KEY"`
+	Timeout     time.Duration `yaml:"timeout" env:"US_S3_TIMEOUT" env-default:"5m"`
+}
+
+type URLCheck struct {
+	// Schemes are allowed schemes of shortened URLs.
+	Schemes   []string `yaml:"schemes" env:"US_URL_SCHEMES" env-default:"http,https"`
+	MaxLength int      `yaml:"max_length" env:"US_URL_MAX_LENGTH" env-default:"2048"`
+	// BlockPrivate rejects links to loopback and private network addresses (SSRF protection).
+	BlockPrivate  bool `yaml:"block_private" env:"US_URL_BLOCK_PRIVATE" env-default:"false"`
+	StripFragment bool `yaml:"strip_fragment" env:"US_URL_STRIP_FRAGMENT" env-default:"true"`
+	// BlockDomains and AllowDomains limit destination domains; * matches any characters,
+	// e.g. "*.example.com" matches subdomains, but not example.com. Blocked domains are
+	// rejected even if allowed, an empty allow list allows any domain.
+	BlockDomains []string `yaml:"block_domains" env:"US_URL_BLOCK_DOMAINS"`
+	AllowDomains []string `yaml:"allow_domains" env:"US_URL_ALLOW_DOMAINS"`
+	// CheckRedirects applies the domain lists on redirects too, so links saved before
+	// a domain was blocked stop working.
+	CheckRedirects bool `yaml:"check_redirects" env:"US_URL_CHECK_REDIRECTS" env-default:"false"`
+	// SelfHosts are other hosts of the service, e.g. of a CDN in front of it. Links to
+	// them, to short domains and to hosts of tenants and TLS are rejected as loops.
+	SelfHosts []string `yaml:"self_hosts" env:"US_URL_SELF_HOSTS"`
+	// ResolveHops is how many redirects of a destination are followed on save looking
+	// for a loop, 0 disables following. ResolveTimeout limits the whole chain, keep it
+	// shorter than Timeouts.Management.
+	ResolveHops    int           `yaml:"resolve_hops" env:"US_URL_RESOLVE_HOPS" env-default:"0"`
+	ResolveTimeout time.Duration `yaml:"resolve_timeout" env:"US_URL_RESOLVE_TIMEOUT" env-default:"2s"`
+}
+
+// Alias generators.
+const (
+	AliasGeneratorRandom    = "random"
+	AliasGeneratorSnowflake = "snowflake"
+)
+
+type Aliases struct {
+	// Generator is "random" or "snowflake". Snowflake aliases are sequential and unique
+	// across instances with different node IDs, so they never collide with each other.
+	Generator string `yaml:"generator" env:"US_ALIASES_GENERATOR" env-default:"random"`
+	// NodeID must be unique per instance for the snowflake generator, from 0 to 1023.
+	NodeID int `yaml:"node_id" env:"US_ALIASES_NODE_ID" env-default:"0"`
+	// Length is the length of random aliases; it grows when generated aliases collide.
+	Length int `yaml:"length" env:"US_ALIASES_LENGTH" env-default:"6"`
+	// GenerateAttempts limits tries to find a free alias before the save fails.
+	GenerateAttempts int `yaml:"generate_attempts" env:"US_ALIASES_GENERATE_ATTEMPTS" env-default:"5"`
+	// Reserved can't be used as aliases in addition to the service routes (url, admin, health, ...).
+	Reserved []string `yaml:"reserved" env:"US_ALIASES_RESERVED" env-default:"static,favicon.ico,robots.txt"`
+}
+
+type Cache struct {
+	// Enabled keeps recently resolved links in memory, so hot redirects don't hit storage.
+	Enabled bool          `yaml:"enabled" env:"US_CACHE_ENABLED" env-default:"true"`
+	Size    int           `yaml:"size" env:"US_CACHE_SIZE" env-default:"10000"`
+	TTL     time.Duration `yaml:"ttl" env:"US_CACHE_TTL" env-default:"1m"`
+	// NegativeTTL keeps "not found" results of unknown aliases, zero disables it. Links saved
+	// by other instances sharing the storage may be not found here for up to NegativeTTL.
+	NegativeTTL time.Duration `yaml:"negative_ttl" env:"US_CACHE_NEGATIVE_TTL" env-default:"0s"`
+	// Redis shares cached links between instances in front of SQL storage; it is
+	// used in addition to the in-memory cache when its address is set.
+	Redis CacheRedis `yaml:"redis"`
+}
+
+type CacheRedis struct {
+	Addr     string `yaml:"addr" env:"US_CACHE_REDIS_ADDR"`
+	Password string `yaml:"password" env:"US_CACHE_REDIS_PASSWORD"`
+	DB       int    `yaml:"db" env:"US_CACHE_REDIS_DB" env-default:"0"`
+	// KeyPrefix must differ from storage.redis.key_prefix if both share one Redis.
+	KeyPrefix string        `yaml:"key_prefix" env:"US_CACHE_REDIS_KEY_PREFIX" env-default:"url-shortener:cache:"`
+	TTL       time.Duration `yaml:"ttl" env:"US_CACHE_REDIS_TTL" env-default:"10m"`
+}
+
+// AliasFilter keeps a bloom filter of existing aliases, so lookups of unknown ones
+// are answered with 404 without a storage query.
+type AliasFilter struct {
+	// Enabled should be set only if this instance is the only one saving links: links
+	// saved by other instances are not found here until the next rebuild.
+	Enabled bool `yaml:"enabled" env:"US_ALIAS_FILTER_ENABLED" env-default:"false"`
+	// Capacity is the expected number of links; the filter grows on rebuild if there are more.
+	Capacity          int     `yaml:"capacity" env:"US_ALIAS_FILTER_CAPACITY" env-default:"1000000"`
+	FalsePositiveRate float64 `yaml:"false_positive_rate" env:"US_ALIAS_FILTER_FALSE_POSITIVE_RATE" env-default:"0.01"`
+	// RebuildInterval is how often the filter is rebuilt from the storage, zero to build it only on startup.
+	RebuildInterval time.Duration `yaml:"rebuild_interval" env:"US_ALIAS_FILTER_REBUILD_INTERVAL" env-default:"1h"`
+}
+
+type Redirect struct {
+	// Code is the default redirect status: 301, 302, 307 or 308. Links may override it.
+	Code int `yaml:"code" env:"US_REDIRECT_CODE" env-default:"302"`
+	// CacheMaxAge is how long clients may cache permanent (301, 308) redirects.
+	CacheMaxAge time.Duration `yaml:"cache_max_age" env:"US_REDIRECT_CACHE_MAX_AGE" env-default:"1h"`
+	// NotFoundPage and ExpiredPage are html/template files shown instead of the built-in
+	// pages of missing and expired links; clients asking for JSON by Accept get JSON.
+	NotFoundPage string `yaml:"not_found_page" env:"US_REDIRECT_NOT_FOUND_PAGE"`
+	ExpiredPage  string `yaml:"expired_page" env:"US_REDIRECT_EXPIRED_PAGE"`
+	// CountHead counts HEAD requests, e.g. of link checkers and unfurlers, as clicks.
+	CountHead bool `yaml:"count_head" env:"US_REDIRECT_COUNT_HEAD"`
+}
+
+type RateLimit struct {
+	Enabled bool `yaml:"enabled" env:"US_RATE_LIMIT_ENABLED" env-default:"true"`
+	// SaveRPS and SaveBurst limit link creation per API key, user or IP.
+	SaveRPS   float64 `yaml:"save_rps" env:"US_RATE_LIMIT_SAVE_RPS" env-default:"1"`
+	SaveBurst int     `yaml:"save_burst" env:"US_RATE_LIMIT_SAVE_BURST" env-default:"10"`
+	// RedirectRPS and RedirectBurst limit redirects per IP.
+	RedirectRPS   float64 `yaml:"redirect_rps" env:"US_RATE_LIMIT_REDIRECT_RPS" env-default:"20"`
+	RedirectBurst int     `yaml:"redirect_burst" env:"US_RATE_LIMIT_REDIRECT_BURST" env-default:"40"`
+	// ReportRPS and ReportBurst limit abuse reports per IP.
+	ReportRPS   float64 `yaml:"report_rps" env:"US_RATE_LIMIT_REPORT_RPS" env-default:"0.1"`
+	ReportBurst int     `yaml:"report_burst" env:"US_RATE_LIMIT_REPORT_BURST" env-default:"5"`
+}
+
+// Timeouts cancel contexts of requests, interrupting their storage calls. They should be
+// shorter than HTTPServer.Timeout, which closes the connection without a response.
+type Timeouts struct {
+	// Redirect limits redirects and previews, 0 disables the limit.
+	Redirect time.Duration `yaml:"redirect" env:"US_TIMEOUTS_REDIRECT" env-default:"2s"`
+	// Management limits /url, /admin and /auth.
+	Management time.Duration `yaml:"management" env:"US_TIMEOUTS_MANAGEMENT" env-default:"3s"`
+}
+
+// CORS lets browser apps on other origins, e.g. dashboards and extensions, call the API.
+type CORS struct {
+	// AllowedOrigins are like "https://dash.example", "*" allows any origin. Empty disables CORS.
+	AllowedOrigins []string `yaml:"allowed_origins" env:"US_CORS_ALLOWED_ORIGINS"`
+	AllowedMethods []string `yaml:"allowed_methods" env:"US_CORS_ALLOWED_METHODS" env-default:"GET,POST,PATCH,DELETE"`
+	AllowedHeaders []string `yaml:"allowed_headers" env:"US_CORS_ALLOWED_HEADERS" env-default:"Authorization,Content-Type,If-Match,If-None-Match,X-API-Key"`
+	ExposedHeaders []string `yaml:"exposed_headers" env:"US_CORS_EXPOSED_HEADERS" env-default:"ETag,Retry-After"`
+	// AllowCredentials lets browsers send cookies and HTTP authentication.
+	AllowCredentials bool          `yaml:"allow_credentials" env:"US_CORS_ALLOW_CREDENTIALS"`
+	MaxAge           time.Duration `yaml:"max_age" env:"US_CORS_MAX_AGE" env-default:"10m"`
+}
+
+// ClientIP tells how to find the client address behind load balancers and other proxies.
+// The address is used by rate limits, analytics, geo targeting, logs and IPFilter.
+// Headers of peers not in TrustedProxies are ignored as spoofed.
+type ClientIP struct {
+	// TrustedProxies are CIDRs or addresses of proxies whose Header is believed.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"US_CLIENT_IP_TRUSTED_PROXIES"`
+	// Header is "X-Forwarded-For" or "X-Real-IP", empty ignores both.
+	Header string `yaml:"header" env:"US_CLIENT_IP_HEADER" env-default:"X-Forwarded-For"`
+}
+
+// IPFilter locks the management routes, e.g. the admin API, and GET /api/shorten to networks
+// like office or VPN ranges.
+type IPFilter struct {
+	// Allow are CIDRs or addresses let in; empty lets in any not denied.
+	Allow []string `yaml:"allow" env:"US_IP_FILTER_ALLOW"`
+	// Deny are CIDRs or addresses rejected even if allowed.
+	Deny []string `yaml:"deny" env:"US_IP_FILTER_DENY"`
+	// Redirects applies the lists to redirects, previews and abuse reports too.
+	Redirects bool `yaml:"redirects" env:"US_IP_FILTER_REDIRECTS" env-default:"false"`
+}
+
+// Compression gzips or deflates large JSON responses of the list, stats and export endpoints.
+type Compression struct {
+	Enabled bool `yaml:"enabled" env:"US_COMPRESSION_ENABLED" env-default:"true"`
+	// Level is from 1 (fastest) to 9 (smallest).
+	Level int `yaml:"level" env:"US_COMPRESSION_LEVEL" env-default:"5"`
+	// ContentTypes are compressed, "text/*" matches all subtypes; other responses are sent as is.
+	ContentTypes []string `yaml:"content_types" env:"US_COMPRESSION_CONTENT_TYPES" env-default:"application/json,application/x-ndjson,text/csv"`
+}
+
+// Log is the output of the application log, access logs included unless AccessLog.Path is set.
+type Log struct {
+	// Path of the log file, empty for stdout.
+	Path string `yaml:"path" env:"US_LOG_PATH"`
+	// MaxSize is the size in megabytes at which log files are rotated.
+	MaxSize int `yaml:"max_size" env:"US_LOG_MAX_SIZE" env-default:"100"`
+	// MaxAge is how long rotated files are kept, 0 keeps them.
+	MaxAge time.Duration `yaml:"max_age" env:"US_LOG_MAX_AGE" env-default:"720h"`
+	// MaxBackups is how many rotated files are kept, 0 keeps all.
+	MaxBackups int  `yaml:"max_backups" env:"US_LOG_MAX_BACKUPS" env-default:"10"`
+	Compress   bool `yaml:"compress" env:"US_LOG_COMPRESS"`
+}
+
+// AccessLog keeps the request log readable under heavy redirect traffic.
+type AccessLog struct {
+	// Path of a separate access log file, rotated like Log. Empty writes to the application log.
+	Path string `yaml:"path" env:"US_ACCESS_LOG_PATH"`
+	// SampleRedirects logs one of every N successful redirects; 0 and 1 log all of them.
+	SampleRedirects int  `yaml:"sample_redirects" env:"US_ACCESS_LOG_SAMPLE_REDIRECTS" env-default:"1"`
+	SkipUserAgent   bool `yaml:"skip_user_agent" env:"US_ACCESS_LOG_SKIP_USER_AGENT"`
+	SkipQuery       bool `yaml:"skip_query" env:"US_ACCESS_LOG_SKIP_QUERY"`
+	// SkipPaths are not logged, e.g. health checks.
+	SkipPaths []string `yaml:"skip_paths" env:"US_ACCESS_LOG_SKIP_PATHS" env-default:"/health,/ready"`
+}
+
+type Auth struct {
+	// JWTSecret signs user tokens. Empty secret disables user accounts.
+	JWTSecret string        `yaml:"jwt_secret" env:"US_AUTH_JWT_SECRET,JWT_SECRET"`
+	TokenTTL  time.Duration `yaml:"token_ttl" env:"US_AUTH_TOKEN_TTL" env-default:"1h"`
+	// RequireVerification denies login to users who haven't confirmed the email.
+	RequireVerification bool          `yaml:"require_verification" env:"US_AUTH_REQUIRE_VERIFICATION"`
+	VerificationTTL     time.Duration `yaml:"verification_ttl" env:"US_AUTH_VERIFICATION_TTL" env-default:"24h"`
+	ResetTTL            time.Duration `yaml:"reset_ttl" env:"US_AUTH_RESET_TTL" env-default:"1h"`
+	// AppURL is the frontend the links in emails lead to: "<app_url>/verify-email?token=..."
+	// and "<app_url>/reset-password?token=...". Empty AppURL sends the tokens themselves.
+	AppURL string `yaml:"app_url" env:"US_AUTH_APP_URL"`
+	// SSO providers are set in the config file only.
+	SSO []SSOProvider `yaml:"sso"`
+}
+
+// SSO provider types.
+const (
+	SSOTypeOIDC   = "oidc"
+	SSOTypeGitHub = "github"
+)
+
+// SSOProvider is an OAuth2 provider users log in with at /auth/sso/<name>/login.
+// After the login the user gets a JWT: in the fragment of "<app_url>/login#token=..."
+// or, without app_url, in the JSON response of the callback.
+type SSOProvider struct {
+	Name string `yaml:"name"`
+	// Type is "oidc" (OpenID Connect, e.g. Google or Keycloak; the default) or "github".
+	Type string `yaml:"type"`
+	// Issuer of an OpenID Connect provider, e.g. "https://accounts.google.com".
+	Issuer       string `yaml:"issuer"`
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	// RedirectURL is the callback registered at the provider,
+	// e.g. "https://sho.rt/auth/sso/google/callback".
+	RedirectURL string `yaml:"redirect_url"`
+	// Scopes are requested in addition to the ones needed to get the email.
+	Scopes []string `yaml:"scopes"`
+	// AllowedDomains limit emails of users who may log in, e.g. "example.com"; empty allows any.
+	AllowedDomains []string `yaml:"allowed_domains"`
+}
+
+// SMTP sends emails to users, e.g. to confirm the email or reset the password.
+type SMTP struct {
+	// Host of the server, empty writes emails to the log instead of sending them.
+	Host     string `yaml:"host" env:"US_SMTP_HOST"`
+	Port     int    `yaml:"port" env:"US_SMTP_PORT" env-default:"587"`
+	Username string `yaml:"username" env:"US_SMTP_USERNAME"`
+	Password string `yaml:"password" env:"US_SMTP_PASSWORD"`
+	From     string `yaml:"from" env:"US_SMTP_FROM" env-default:"url-shortener@localhost"`
+	// Timeout limits sending of one email.
+	Timeout time.Duration `yaml:"timeout" env:"US_SMTP_TIMEOUT" env-default:"10s"`
+}
+
+// Signature authenticates requests signed with signing secrets of API keys.
+type Signature struct {
+	// MaxSkew is how far the timestamp of a request may be from the server time,
+	// nonces are remembered for as long.
+	MaxSkew time.Duration `yaml:"max_skew" env:"US_SIGNATURE_MAX_SKEW" env-default:"5m"`
+	// MaxBodySize limits bodies of signed requests, they are hashed in memory.
+	MaxBodySize int64 `yaml:"max_body_size" env:"US_SIGNATURE_MAX_BODY_SIZE" env-default:"10485760"`
+}
+
+// Captcha protects abuse reports with a siteverify API, e.g. of hCaptcha, Turnstile
+// or reCAPTCHA. It is off when Secret is empty.
+type Captcha struct {
+	VerifyURL string        `yaml:"verify_url" env:"US_CAPTCHA_VERIFY_URL" env-default:"https://hcaptcha.com/siteverify"`
+	Secret    string        `yaml:"secret" env:"US_CAPTCHA_SECRET"`
+	Timeout   time.Duration `yaml:"timeout" env:"US_CAPTCHA_TIMEOUT" env-default:"5s"`
+}
+
+type Tracing struct {
+	// Enabled exports OpenTelemetry spans via OTLP/HTTP.
+	Enabled     bool    `yaml:"enabled" env:"US_TRACING_ENABLED" env-default:"false"`
+	Endpoint    string  `yaml:"endpoint" env:"US_TRACING_ENDPOINT,OTEL_EXPORTER_OTLP_ENDPOINT" env-default:"localhost:4318"`
+	Insecure    bool    `yaml:"insecure" env:"US_TRACING_INSECURE" env-default:"true"`
+	ServiceName string  `yaml:"service_name" env:"US_TRACING_SERVICE_NAME" env-default:"url-shortener"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"US_TRACING_SAMPLE_RATIO" env-default:"1"`
+}
+
+// Sentry receives panics and errors logged with sl.Err.
+type Sentry struct {
+	// DSN of the Sentry project, empty disables reporting.
+	DSN string `yaml:"dsn" env:"US_SENTRY_DSN,SENTRY_DSN"`
+	// Environment tags events, Env by default.
+	Environment string `yaml:"environment" env:"US_SENTRY_ENVIRONMENT"`
+	// SampleRate is the share of reported events, from 0 to 1.
+	SampleRate float64 `yaml:"sample_rate" env:"US_SENTRY_SAMPLE_RATE" env-default:"1"`
+}
+
+// Internal is a listener for operators, it should be bound to localhost or a private network.
+type Internal struct {
+	// Address of the listener, empty disables it. Accepts the same forms as HTTPServer.Address.
+	Address string `yaml:"address" env:"US_INTERNAL_ADDRESS"`
+	// Debug serves net/http/pprof and expvar under /debug, behind the admin credentials.
+	Debug bool `yaml:"debug" env:"US_INTERNAL_DEBUG"`
+	// Management moves /url, /auth, /admin, /docs and /metrics to the listener,
+	// so the public address serves only redirects, previews and health checks.
+	Management bool `yaml:"management" env:"US_INTERNAL_MANAGEMENT"`
+}
+
+type Metrics struct {
+	// Enabled exposes Prometheus metrics at /metrics.
+	Enabled bool `yaml:"enabled" env:"US_METRICS_ENABLED" env-default:"true"`
+}
+
+type Analytics struct {
+	// Enabled turns on recording of every click (time, referrer, user agent, anonymized IP).
+	Enabled       bool          `yaml:"enabled" env:"US_ANALYTICS_ENABLED" env-default:"true"`
+	BufferSize    int           `yaml:"buffer_size" env:"US_ANALYTICS_BUFFER_SIZE" env-default:"10000"`
+	FlushInterval time.Duration `yaml:"flush_interval" env:"US_ANALYTICS_FLUSH_INTERVAL" env-default:"1s"`
+	// RollupInterval is how often clicks are rolled up into hourly counts for time
+	// series; 0 disables the rollup and time series count raw clicks.
+	RollupInterval time.Duration `yaml:"rollup_interval" env:"US_ANALYTICS_ROLLUP_INTERVAL" env-default:"5m"`
+	// RollupDelay is how long after the end of an hour it is rolled up, so that
+	// buffered clicks are saved by then.
+	RollupDelay time.Duration `yaml:"rollup_delay" env:"US_ANALYTICS_ROLLUP_DELAY" env-default:"5m"`
+}
+
+type HitCounter struct {
+	// BufferSize is the number of hits which may wait for flush; extra hits are dropped.
+	BufferSize int `yaml:"buffer_size" env:"US_HIT_COUNTER_BUFFER_SIZE" env-default:"10000"`
+	// Hits are written every FlushInterval or once BatchSize hits are collected, zero
+	// BatchSize flushes only by interval, zero FlushInterval only by batch size; if both
+	// are zero, hits are flushed every second.
+	BatchSize     int           `yaml:"batch_size" env:"US_HIT_COUNTER_BATCH_SIZE" env-default:"1000"`
+	FlushInterval time.Duration `yaml:"flush_interval" env:"US_HIT_COUNTER_FLUSH_INTERVAL" env-default:"1s"`
+}
+
+type Storage struct {
+	// Driver selects storage backend: "sqlite" (default), "postgres" or "redis".
+	Driver string `yaml:"driver" env:"US_STORAGE_DRIVER" env-default:"sqlite"`
+	// PurgeInterval is how often expired and long deleted links are removed. Zero disables purging.
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"US_STORAGE_PURGE_INTERVAL" env-default:"1h"`
+	// DeletedRetention is how long deleted links can be restored before they are purged.
+	DeletedRetention time.Duration `yaml:"deleted_retention" env:"US_STORAGE_DELETED_RETENTION" env-default:"720h"`
+	// ArchiveAfter moves links neither clicked nor updated for this long to the archive
+	// on every purge; they are restored on the next access. Zero disables archival,
+	// the redis driver doesn't archive.
+	ArchiveAfter time.Duration `yaml:"archive_after" env:"US_STORAGE_ARCHIVE_AFTER" env-default:"0"`
+	SQLite       SQLite        `yaml:"sqlite"`
+	Postgres     Postgres      `yaml:"postgres"`
+	Redis        Redis         `yaml:"redis"`
+}
+
+type SQLite struct {
+	// JournalMode WAL lets reads run concurrently with writes.
+	JournalMode string `yaml:"journal_mode" env:"US_SQLITE_JOURNAL_MODE" env-default:"WAL"`
+	// BusyTimeout is how long to wait for a lock before failing with "database is locked".
+	BusyTimeout  time.Duration `yaml:"busy_timeout" env:"US_SQLITE_BUSY_TIMEOUT" env-default:"5s"`
+	ForeignKeys  bool          `yaml:"foreign_keys" env:"US_SQLITE_FOREIGN_KEYS" env-default:"true"`
+	MaxReadConns int           `yaml:"max_read_conns" env:"US_SQLITE_MAX_READ_CONNS" env-default:"4"`
+}
+
+type Postgres struct {
+	DSN             string        `yaml:"dsn" env:"US_POSTGRES_DSN,DATABASE_DSN"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"US_POSTGRES_MAX_OPEN_CONNS" env-default:"10"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"US_POSTGRES_MAX_IDLE_CONNS" env-default:"5"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"US_POSTGRES_CONN_MAX_LIFETIME" env-default:"30m"`
+}
+
+type Redis struct {
+	Addr      string        `yaml:"addr" env:"US_REDIS_ADDR" env-default:"localhost:6379"`
+	Password  string        `yaml:"password" env:"US_REDIS_PASSWORD,REDIS_PASSWORD"`
+	DB        int           `yaml:"db" env:"US_REDIS_DB" env-default:"0"`
+	KeyPrefix string        `yaml:"key_prefix" env:"US_REDIS_KEY_PREFIX" env-default:"url-shortener:"`
+	TTL       time.Duration `yaml:"ttl" env:"US_REDIS_TTL" env-default:"0s"`
 }
 
 type HTTPServer struct {
-	Address     string        `yaml:"address" env-default:"localhost:8080"`
-	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
-	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
-	User        string        `yaml:"user" env-required:"true"`
-	Password    string        `yaml:"password" env-required:"true" env:"HTTP_SERVER_PASSWORD"`
+	// Address is host:port, "unix:/path" for a unix socket or "systemd:name"
+	// for a socket passed by systemd with FileDescriptorName=name.
+	Address string `yaml:"address" env:"US_HTTP_ADDRESS" env-default:"localhost:8080"`
+	// SocketMode is the octal file mode of unix sockets, public and internal.
+	SocketMode  string        `yaml:"socket_mode" env:"US_HTTP_SOCKET_MODE" env-default:"0660"`
+	Timeout     time.Duration `yaml:"timeout" env:"US_HTTP_TIMEOUT" env-default:"4s"`
+	IdleTimeout time.Duration `yaml:"idle_timeout" env:"US_HTTP_IDLE_TIMEOUT" env-default:"60s"`
+	// ShutdownTimeout is how long active requests may take to finish on shutdown.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"US_HTTP_SHUTDOWN_TIMEOUT" env-default:"10s"`
+	// ReadyTimeout limits the storage check of GET /ready.
+	ReadyTimeout time.Duration `yaml:"ready_timeout" env:"US_HTTP_READY_TIMEOUT" env-default:"1s"`
+	// MaxBatchSize limits the number of links in POST /url/batch.
+	MaxBatchSize int `yaml:"max_batch_size" env:"US_HTTP_MAX_BATCH_SIZE" env-default:"100"`
+	// MaxBodySize limits request bodies of POST /url and POST /url/batch, in bytes.
+	MaxBodySize int64  `yaml:"max_body_size" env:"US_HTTP_MAX_BODY_SIZE" env-default:"1048576"`
+	User        string `yaml:"user" env:"US_HTTP_USER" env-required:"true"`
+	Password    string `yaml:"password" env-required:"true" env:"US_HTTP_PASSWORD,HTTP_SERVER_PASSWORD"`
+	TLS         TLS    `yaml:"tls"`
+}
+
+// TLS modes of the HTTP server.
+const (
+	TLSModeOff      = ""
+	TLSModeFiles    = "files"
+	TLSModeAutocert = "autocert"
+)
+
+type TLS struct {
+	// Mode is "" (plain HTTP), "files" (CertFile and KeyFile) or "autocert" (Let's Encrypt).
+	Mode     string `yaml:"mode" env:"US_TLS_MODE"`
+	CertFile string `yaml:"cert_file" env:"US_TLS_CERT_FILE"`
+	KeyFile  string `yaml:"key_file" env:"US_TLS_KEY_FILE"`
+	// Hosts is the allowlist of domains autocert requests certificates for.
+	Hosts []string `yaml:"hosts" env:"US_TLS_HOSTS"`
+	// CacheDir keeps issued certificates between restarts.
+	CacheDir string `yaml:"cache_dir" env:"US_TLS_CACHE_DIR" env-default:"./certs"`
+	// HTTPAddress serves HTTP-01 challenges and redirects the rest to HTTPS.
+	HTTPAddress string `yaml:"http_address" env:"US_TLS_HTTP_ADDRESS" env-default:"0.0.0.0:80"`
+	// HTTP2 serves HTTP/2 to clients negotiating h2.
+	HTTP2 bool `yaml:"http2" env:"US_TLS_HTTP2" env-default:"true"`
+	// HTTP3 also serves HTTP/3 over QUIC and advertises it in Alt-Svc. Experimental.
+	HTTP3 bool `yaml:"http3" env:"US_TLS_HTTP3"`
+	// HTTP3Address is the UDP address of HTTP/3, the address of the server by default.
+	HTTP3Address string `yaml:"http3_address" env:"US_TLS_HTTP3_ADDRESS"`
 }
 
-func MustLoad() *Config {
-	configPath := os.Getenv("CONFIG_PATH")
-	if configPath == "" {
-		log.Fatal("CONFIG_PATH is not set")
+// MustLoad loads config from the file, environment and command-line args, see LoadFlags.
+func MustLoad(fs *flag.FlagSet, args []string) *Config {
+	cfg, err := LoadFlags(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatalf("cannot read config: %s", err)
 	}
 
-	// check if file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		log.Fatalf("config file does not exist: %s", configPath)
+	return cfg
+}
+
+// Load reads config with precedence flags > environment > file > defaults.
+// The file is optional: its path is taken from -config flag or CONFIG_PATH.
+// Every value has a flag named by its yaml path, e.g. -http_server.address,
+// and an environment variable, e.g. US_HTTP_ADDRESS.
+func Load(args []string) (*Config, error) {
+	return LoadFlags(flag.NewFlagSet("url-shortener", flag.ContinueOnError), args)
+}
+
+// LoadFlags is Load which registers config flags in fs, so commands can add their own flags to it.
+func LoadFlags(fs *flag.FlagSet, args []string) (*Config, error) {
+	configPath := fs.String("config", os.Getenv("CONFIG_PATH"), "path to config file (env CONFIG_PATH)")
+
+	flags := make(map[string]string)
+	registerFlags(fs, reflect.TypeOf(Config{}), "", flags)
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
 	}
 
 	var cfg Config
 
-	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
-		log.Fatalf("cannot read config: %s", err)
+	// Флаги применяем и до чтения, чтобы обязательные значения можно было
+	// передать только флагом, и после - чтобы они перекрыли файл и окружение
+	if err := applyFlags(&cfg, flags); err != nil {
+		return nil, err
+	}
+
+	if *configPath != "" {
+		// check if file exists
+		if _, err := os.Stat(*configPath); os.IsNotExist(err) {
+			return nil, fmt.Errorf("config file does not exist: %s", *configPath)
+		}
+
+		if err := cleanenv.ReadConfig(*configPath, &cfg); err != nil {
+			return nil, err
+		}
+	} else if err := cleanenv.ReadEnv(&cfg); err != nil {
+		return nil, err
+	}
+
+	if err := applyFlags(&cfg, flags); err != nil {
+		return nil, err
 	}
 
-	return &cfg
+	return &cfg, nil
 }
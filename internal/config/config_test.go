@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_Precedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+env: "prod"
+http_server:
+  address: "file:8080"
+  timeout: 1s
+  idle_timeout: 2s
+  user: "user"
+  password: "pass"
+tenants:
+  - name: "brand"
+    hosts: ["go.brand.example"]
+    path_prefix: "/brand"
+`), 0o600))
+
+	t.Setenv("CONFIG_PATH", path)
+	t.Setenv("US_HTTP_ADDRESS", "env:8080")
+	t.Setenv("US_HTTP_TIMEOUT", "3s")
+
+	cfg, err := Load([]string{"-http_server.address", "flag:8080", "-storage.driver=postgres"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "flag:8080", cfg.Address)
+	assert.Equal(t, 3*time.Second, cfg.HTTPServer.Timeout)
+	assert.Equal(t, 2*time.Second, cfg.IdleTimeout)
+	assert.Equal(t, "prod", cfg.Env)
+	assert.Equal(t, "postgres", cfg.Storage.Driver)
+	// Значение по умолчанию
+	assert.Equal(t, 10*time.Second, cfg.ShutdownTimeout)
+	assert.Equal(t, []Tenant{{Name: "brand", Hosts: []string{"go.brand.example"}, PathPrefix: "/brand"}}, cfg.Tenants)
+}
+
+func TestLoad_WithoutFile(t *testing.T) {
+	t.Setenv("CONFIG_PATH", "")
+	t.Setenv("US_HTTP_PASSWORD", "pass")
+
+	cfg, err := Load([]string{"-http_server.user=user", "-http_server.tls.hosts=a.com,b.com"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "user", cfg.User)
+	assert.Equal(t, "pass", cfg.Password)
+	assert.Equal(t, []string{"a.com", "b.com"}, cfg.TLS.Hosts)
+	assert.Equal(t, "localhost:8080", cfg.Address)
+}
+
+func TestLoad_InvalidFlag(t *testing.T) {
+	t.Setenv("CONFIG_PATH", "")
+
+	_, err := Load([]string{"-http_server.timeout=abc"})
+	require.Error(t, err)
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// registerFlags defines a flag for every config value of t, named by its yaml path.
+// Values of the flags which are set are collected into values.
+func registerFlags(fs *flag.FlagSet, t reflect.Type, prefix string, values map[string]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name := prefix + yamlName(field)
+
+		if field.Type.Kind() == reflect.Struct {
+			registerFlags(fs, field.Type, name+".", values)
+
+			continue
+		}
+
+		// Списки структур задаются только в файле конфига
+		if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct {
+			continue
+		}
+
+		usage := name
+		if env, ok := field.Tag.Lookup("env"); ok {
+			usage = "env " + strings.Split(env, ",")[0]
+		}
+
+		fs.Func(name, usage, func(v string) error {
+			values[name] = v
+
+			return nil
+		})
+	}
+}
+
+// applyFlags sets config values from flags collected by registerFlags.
+func applyFlags(cfg *Config, values map[string]string) error {
+	return applyStruct(reflect.ValueOf(cfg).Elem(), "", values)
+}
+
+func applyStruct(v reflect.Value, prefix string, values map[string]string) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name := prefix + yamlName(t.Field(i))
+
+		if t.Field(i).Type.Kind() == reflect.Struct {
+			if err := applyStruct(v.Field(i), name+".", values); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		raw, ok := values[name]
+		if !ok {
+			continue
+		}
+
+		if err := setValue(v.Field(i), raw); err != nil {
+			return fmt.Errorf("invalid value %q for flag -%s: %w", raw, name, err)
+		}
+	}
+
+	return nil
+}
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+
+		v.Set(reflect.ValueOf(strings.Split(raw, ",")))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+func yamlName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+
+	return name
+}
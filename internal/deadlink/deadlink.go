@@ -0,0 +1,236 @@
+// Package deadlink periodically checks destinations of stored links and marks
+// the links whose destinations are gone: not found, removed or without DNS records.
+package deadlink
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/urlcheck"
+	"url-shortener/internal/storage"
+)
+
+// Reasons a destination is considered dead, see storage.URL.DeadReason.
+const (
+	ReasonNotFound = "404"
+	ReasonGone     = "410"
+	// ReasonDNS means the host of the destination does not exist.
+	ReasonDNS = "dns"
+)
+
+// pageSize is the number of links checked at once.
+const pageSize = 500
+
+// URLMarker is an interface for listing links and changing their dead state.
+type URLMarker interface {
+	ListURLs(ctx context.Context, filter storage.ListFilter) ([]storage.URL, string, error)
+	MarkURLDead(ctx context.Context, alias, reason string) error
+}
+
+// Notifier tells the owner of the link that its destination is dead, see webhook.Notifier.
+type Notifier interface {
+	NotifyDead(webhookURL, alias, reason string)
+}
+
+type Options struct {
+	// Timeout limits the check of one destination, including redirects.
+	Timeout time.Duration
+	// AllowPrivate allows checking loopback and private addresses.
+	AllowPrivate bool
+}
+
+// Checker checks destinations with HEAD requests, falling back to GET for servers
+// which don't support HEAD. Redirects are followed, the final response decides.
+type Checker struct {
+	store URLMarker
+	// notifier is nil when owners are not notified.
+	notifier Notifier
+	client   *http.Client
+}
+
+func New(store URLMarker, notifier Notifier, opts Options) *Checker {
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivate {
+		dialer.Control = urlcheck.DenyPrivate
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// Через прокси проверка адреса в dialer не сработала бы
+	transport.Proxy = nil
+
+	return &Checker{
+		store:    store,
+		notifier: notifier,
+		client:   &http.Client{Timeout: opts.Timeout, Transport: transport},
+	}
+}
+
+// Run checks all links every interval until ctx is done.
+func (c *Checker) Run(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	const op = "deadlink.Run"
+
+	log = log.With(slog.String("op", op))
+
+	if interval <= 0 {
+		log.Info("dead link check is disabled")
+
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			dead, revived, err := c.CheckAll(ctx)
+			if err != nil {
+				log.Error("failed to check links", sl.Err(err))
+			}
+			if dead > 0 || revived > 0 {
+				log.Info("links checked", slog.Int("dead", dead), slog.Int("revived", revived))
+			}
+		}
+	}
+}
+
+// CheckAll checks all links once and returns the numbers of links found dead and of
+// dead links which work again. Destinations which fail for other reasons, e.g. time
+// out, keep the state of the link, so a flaky server doesn't flip it back and forth.
+func (c *Checker) CheckAll(ctx context.Context) (dead, revived int, err error) {
+	filter := storage.ListFilter{Limit: pageSize}
+
+	for {
+		urls, next, err := c.store.ListURLs(ctx, filter)
+		if err != nil {
+			return dead, revived, err
+		}
+
+		// Одно назначение бывает у многих ссылок, проверяем его один раз за страницу
+		checked := make(map[string]result)
+
+		for _, u := range urls {
+			reason, known := c.linkReason(ctx, u, checked)
+			if ctx.Err() != nil {
+				return dead, revived, ctx.Err()
+			}
+			if !known || reason == u.DeadReason {
+				continue
+			}
+
+			if err := c.store.MarkURLDead(ctx, u.Alias, reason); err != nil {
+				if errors.Is(err, storage.ErrURLNotFound) {
+					// Ссылку удалили во время проверки
+					continue
+				}
+
+				return dead, revived, err
+			}
+
+			if reason == "" {
+				revived++
+
+				continue
+			}
+
+			dead++
+
+			// Владелец узнает только о ссылках, которые были живы
+			if c.notifier != nil && u.DeadReason == "" && u.WebhookURL != "" {
+				c.notifier.NotifyDead(u.WebhookURL, u.Alias, reason)
+			}
+		}
+
+		if next == "" {
+			return dead, revived, nil
+		}
+
+		filter.Cursor = next
+	}
+}
+
+type result struct {
+	reason string
+	known  bool
+}
+
+// linkReason returns the dead reason of the first dead destination of the link, or
+// an empty reason if all of them work. known is false if a destination can't be
+// checked and none of the others is dead.
+func (c *Checker) linkReason(ctx context.Context, u storage.URL, checked map[string]result) (reason string, known bool) {
+	known = true
+
+	for _, destination := range u.Destinations() {
+		res, ok := checked[destination]
+		if !ok {
+			res.reason, res.known = c.Check(ctx, destination)
+			checked[destination] = res
+		}
+
+		if res.reason != "" {
+			return res.reason, true
+		}
+		if !res.known {
+			known = false
+		}
+	}
+
+	return "", known
+}
+
+// Check requests rawURL and returns the dead reason, empty if the destination works.
+// known is false if the state of the destination can't be told.
+func (c *Checker) Check(ctx context.Context, rawURL string) (reason string, known bool) {
+	status, err := c.status(ctx, http.MethodHead, rawURL)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = c.status(ctx, http.MethodGet, rawURL)
+	}
+
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return ReasonDNS, true
+		}
+
+		return "", false
+	}
+
+	switch {
+	case status == http.StatusNotFound:
+		return ReasonNotFound, true
+	case status == http.StatusGone:
+		return ReasonGone, true
+	case status == http.StatusTooManyRequests || status >= 500:
+		// Сбой или перегрузка сервера не говорят о том, есть ли страница
+		return "", false
+	default:
+		return "", true
+	}
+}
+
+func (c *Checker) status(ctx context.Context, method, rawURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("User-Agent", "url-shortener-deadlink")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	// Тело не нужно, ответ GET может быть большим
+	_ = res.Body.Close()
+
+	return res.StatusCode, nil
+}
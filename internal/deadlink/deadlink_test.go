@@ -0,0 +1,127 @@
+package deadlink_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/deadlink"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/sqlite"
+)
+
+type notice struct {
+	webhookURL, alias, reason string
+}
+
+type fakeNotifier struct {
+	mu      sync.Mutex
+	notices []notice
+}
+
+func (n *fakeNotifier) NotifyDead(webhookURL, alias, reason string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.notices = append(n.notices, notice{webhookURL, alias, reason})
+}
+
+func TestCheckAll(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/moved":
+			http.Redirect(w, r, "/missing", http.StatusFound)
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		case "/get-only":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	for _, u := range []storage.URL{
+		{Alias: "ok", URL: srv.URL + "/ok"},
+		{Alias: "moved", URL: srv.URL + "/moved", WebhookURL: "https://hooks.example/moved"},
+		{Alias: "gone", URL: srv.URL + "/gone"},
+		{Alias: "get-only", URL: srv.URL + "/get-only"},
+		{Alias: "split", URL: srv.URL + "/ok", Split: storage.Split{Variants: []storage.Variant{
+			{Name: "b", URL: srv.URL + "/gone", Weight: 1},
+		}}},
+		{Alias: "broken", URL: srv.URL + "/broken"},
+		{Alias: "revived", URL: srv.URL + "/ok"},
+	} {
+		_, err := s.SaveURL(ctx, u)
+		require.NoError(t, err)
+	}
+
+	// Ссылки, найденные мертвыми раньше
+	require.NoError(t, s.MarkURLDead(ctx, "broken", deadlink.ReasonNotFound))
+	require.NoError(t, s.MarkURLDead(ctx, "revived", deadlink.ReasonGone))
+
+	notifier := &fakeNotifier{}
+	checker := deadlink.New(s, notifier, deadlink.Options{Timeout: time.Second, AllowPrivate: true})
+
+	dead, revived, err := checker.CheckAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 3, dead)
+	require.Equal(t, 1, revived)
+
+	for alias, want := range map[string]string{
+		"ok":       "",
+		"moved":    deadlink.ReasonNotFound,
+		"gone":     deadlink.ReasonGone,
+		"get-only": "",
+		"split":    deadlink.ReasonGone,
+		// Ошибка сервера не меняет состояние ссылки
+		"broken":  deadlink.ReasonNotFound,
+		"revived": "",
+	} {
+		u, err := s.GetURLInfo(ctx, alias)
+		require.NoError(t, err)
+		require.Equal(t, want, u.DeadReason, alias)
+	}
+
+	require.Equal(t, []notice{{"https://hooks.example/moved", "moved", deadlink.ReasonNotFound}}, notifier.notices)
+
+	// Повторная проверка ничего не меняет и не уведомляет снова
+	dead, revived, err = checker.CheckAll(ctx)
+	require.NoError(t, err)
+	require.Zero(t, dead)
+	require.Zero(t, revived)
+	require.Len(t, notifier.notices, 1)
+}
+
+func TestCheck_PrivateAddress(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+
+	checker := deadlink.New(nil, nil, deadlink.Options{Timeout: time.Second})
+
+	// Адрес не проверяется, а не объявляется мертвым
+	reason, known := checker.Check(context.Background(), srv.URL)
+	require.Empty(t, reason)
+	require.False(t, known)
+}
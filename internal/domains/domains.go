@@ -0,0 +1,86 @@
+// Package domains keeps track of the short domains the service answers on.
+package domains
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"url-shortener/internal/storage"
+)
+
+// Normalize returns host in lower case without the port and the trailing dot,
+// so values of the Host header can be compared with registered domains.
+func Normalize(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// Serves reports whether a link bound to domain can be opened via host.
+// Links without a domain are served on every host.
+func Serves(domain, host string) bool {
+	return domain == "" || domain == Normalize(host)
+}
+
+// DomainGetter is an interface for looking up domains registered via the admin API.
+type DomainGetter interface {
+	GetDomain(ctx context.Context, host string) (storage.Domain, error)
+}
+
+// Registry knows domains from the config and the ones registered via the admin API.
+type Registry struct {
+	configured map[string]struct{}
+	getter     DomainGetter
+}
+
+// NewRegistry creates a registry of configured hosts; getter may be nil if
+// domains are not registered at runtime.
+func NewRegistry(configured []string, getter DomainGetter) *Registry {
+	r := &Registry{
+		configured: make(map[string]struct{}, len(configured)),
+		getter:     getter,
+	}
+
+	for _, host := range configured {
+		if host = strings.TrimSpace(host); host != "" {
+			r.configured[Normalize(host)] = struct{}{}
+		}
+	}
+
+	return r
+}
+
+// Configured reports whether host is set in the config.
+func (r *Registry) Configured(host string) bool {
+	_, ok := r.configured[Normalize(host)]
+
+	return ok
+}
+
+// Exists reports whether host is a known short domain.
+func (r *Registry) Exists(ctx context.Context, host string) (bool, error) {
+	const op = "domains.Registry.Exists"
+
+	if r.Configured(host) {
+		return true, nil
+	}
+
+	if r.getter == nil {
+		return false, nil
+	}
+
+	_, err := r.getter.GetDomain(ctx, Normalize(host))
+	if errors.Is(err, storage.ErrDomainNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,61 @@
+package domains_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domains"
+	"url-shortener/internal/storage"
+)
+
+type getterStub map[string]error
+
+func (g getterStub) GetDomain(_ context.Context, host string) (storage.Domain, error) {
+	if err, ok := g[host]; ok {
+		return storage.Domain{}, err
+	}
+
+	return storage.Domain{Host: host}, nil
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "go.example", domains.Normalize("GO.example:8080"))
+	assert.Equal(t, "go.example", domains.Normalize("go.example."))
+	assert.Equal(t, "::1", domains.Normalize("[::1]:8080"))
+}
+
+func TestServes(t *testing.T) {
+	assert.True(t, domains.Serves("", "any.example"))
+	assert.True(t, domains.Serves("go.example", "Go.Example:443"))
+	assert.False(t, domains.Serves("go.example", "sho.rt"))
+}
+
+func TestRegistry_Exists(t *testing.T) {
+	registry := domains.NewRegistry([]string{"Sho.rt"}, getterStub{
+		"unknown.example": storage.ErrDomainNotFound,
+		"broken.example":  errors.New("unexpected error"),
+	})
+
+	ok, err := registry.Exists(context.Background(), "sho.rt:443")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = registry.Exists(context.Background(), "go.brand.example")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = registry.Exists(context.Background(), "unknown.example")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = registry.Exists(context.Background(), "broken.example")
+	assert.Error(t, err)
+
+	ok, err = domains.NewRegistry(nil, nil).Exists(context.Background(), "sho.rt")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
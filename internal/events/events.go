@@ -0,0 +1,177 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// Event types.
+const (
+	TypeLinkCreated = "link_created"
+	TypeLinkDeleted = "link_deleted"
+	TypeLinkClicked = "link_clicked"
+	// TypeLinkExpired is published when the janitor removes the expired link.
+	TypeLinkExpired = "link_expired"
+)
+
+// Event is a message about a link published to the broker as JSON.
+type Event struct {
+	Type  string    `json:"type"`
+	Alias string    `json:"alias"`
+	Time  time.Time `json:"time"`
+	// URL is set for link_created.
+	URL string `json:"url,omitempty"`
+	// Referrer, UserAgent and Variant are set for link_clicked.
+	Referrer  string `json:"referrer,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Variant   string `json:"variant,omitempty"`
+}
+
+// Message is an encoded event; Key is the alias, so events of a link keep their order.
+type Message struct {
+	Type  string
+	Key   string
+	Value []byte
+}
+
+// Sink sends messages to a broker. Send is expected to limit its own duration.
+type Sink interface {
+	Send(ctx context.Context, msgs []Message) error
+	Close() error
+}
+
+// Publisher sends events to the sink in batches from a background goroutine,
+// so a slow broker doesn't delay requests.
+type Publisher struct {
+	log           *slog.Logger
+	sink          Sink
+	flushInterval time.Duration
+	batchSize     int
+
+	events chan Event
+	done   chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+}
+
+// New starts the publisher. Events are sent every flushInterval or once the buffer is
+// full; a zero flushInterval sends them by full buffers only.
+func New(log *slog.Logger, sink Sink, bufferSize int, flushInterval time.Duration) *Publisher {
+	p := &Publisher{
+		log:           log.With(slog.String("component", "events")),
+		sink:          sink,
+		flushInterval: flushInterval,
+		batchSize:     bufferSize,
+		events:        make(chan Event, bufferSize),
+		done:          make(chan struct{}),
+	}
+
+	p.wg.Add(1)
+	go p.run()
+
+	return p
+}
+
+// Publish queues the event. It never blocks: if the buffer is full, the event is dropped.
+func (p *Publisher) Publish(e Event) {
+	select {
+	case p.events <- e:
+	default:
+		p.log.Warn("event buffer is full, event dropped", slog.String("type", e.Type), slog.String("alias", e.Alias))
+	}
+}
+
+// Record publishes link_clicked, so Publisher can be used as a click recorder.
+func (p *Publisher) Record(e storage.ClickEvent) {
+	p.Publish(Event{
+		Type:      TypeLinkClicked,
+		Alias:     e.Alias,
+		Time:      e.Time,
+		Referrer:  e.Referrer,
+		UserAgent: e.UserAgent,
+		Variant:   e.Variant,
+	})
+}
+
+// Close stops the publisher, sends buffered events and closes the sink.
+func (p *Publisher) Close() {
+	p.once.Do(func() {
+		close(p.done)
+		p.wg.Wait()
+
+		if err := p.sink.Close(); err != nil {
+			p.log.Error("failed to close event sink", sl.Err(err))
+		}
+	})
+}
+
+func (p *Publisher) run() {
+	defer p.wg.Done()
+
+	// Без интервала канал остается nil и отправка идет только по размеру пачки
+	var tick <-chan time.Time
+	if p.flushInterval > 0 {
+		ticker := time.NewTicker(p.flushInterval)
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	var batch []Event
+
+	for {
+		select {
+		case e := <-p.events:
+			batch = append(batch, e)
+			if len(batch) >= p.batchSize {
+				p.flush(batch)
+				batch = nil
+			}
+		case <-tick:
+			p.flush(batch)
+			batch = nil
+		case <-p.done:
+			for {
+				select {
+				case e := <-p.events:
+					batch = append(batch, e)
+				default:
+					p.flush(batch)
+
+					return
+				}
+			}
+		}
+	}
+}
+
+func (p *Publisher) flush(batch []Event) {
+	if len(batch) == 0 {
+		return
+	}
+
+	msgs := make([]Message, 0, len(batch))
+	for _, e := range batch {
+		e.Time = e.Time.UTC()
+
+		value, err := json.Marshal(e)
+		if err != nil {
+			p.log.Error("failed to encode event", sl.Err(err))
+
+			continue
+		}
+
+		msgs = append(msgs, Message{Type: e.Type, Key: e.Alias, Value: value})
+	}
+
+	if err := p.sink.Send(context.Background(), msgs); err != nil {
+		p.log.Error("failed to send events", slog.Int("count", len(msgs)), sl.Err(err))
+	}
+}
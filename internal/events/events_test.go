@@ -0,0 +1,150 @@
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/events"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+type sinkStub struct {
+	mu     sync.Mutex
+	msgs   []events.Message
+	closed bool
+}
+
+func (s *sinkStub) Send(_ context.Context, msgs []events.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.msgs = append(s.msgs, msgs...)
+
+	return nil
+}
+
+func (s *sinkStub) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+
+	return nil
+}
+
+func TestPublisher_FlushesOnClose(t *testing.T) {
+	sink := &sinkStub{}
+
+	// Интервал больше времени теста: события должны уйти при закрытии
+	p := events.New(slogdiscard.NewDiscardLogger(), sink, 100, time.Hour)
+
+	clickedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+	p.Publish(events.Event{Type: events.TypeLinkCreated, Alias: "alias", URL: "https://example.com", Time: clickedAt})
+	p.Record(storage.ClickEvent{Alias: "alias", Time: clickedAt, Referrer: "https://ref.example/", IP: "203.0.113.42"})
+
+	p.Close()
+
+	require.True(t, sink.closed)
+	require.Len(t, sink.msgs, 2)
+
+	assert.Equal(t, events.TypeLinkCreated, sink.msgs[0].Type)
+	assert.Equal(t, "alias", sink.msgs[0].Key)
+
+	var clicked map[string]any
+	require.NoError(t, json.Unmarshal(sink.msgs[1].Value, &clicked))
+	assert.Equal(t, map[string]any{
+		"type":     events.TypeLinkClicked,
+		"alias":    "alias",
+		"time":     "2024-05-01T09:00:00Z",
+		"referrer": "https://ref.example/",
+	}, clicked)
+}
+
+func TestPublisher_ZeroInterval(t *testing.T) {
+	sink := &sinkStub{}
+
+	// Нулевой интервал означает отправку только полными пачками, без паники тикера
+	p := events.New(slogdiscard.NewDiscardLogger(), sink, 2, 0)
+	defer p.Close()
+
+	p.Publish(events.Event{Type: events.TypeLinkCreated, Alias: "a", Time: time.Now()})
+	p.Publish(events.Event{Type: events.TypeLinkDeleted, Alias: "a", Time: time.Now()})
+
+	require.Eventually(t, func() bool {
+		sink.mu.Lock()
+		defer sink.mu.Unlock()
+
+		return len(sink.msgs) == 2
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestKafka_Send(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   int
+		response string
+		wantErr  bool
+	}{
+		{
+			name:     "Success",
+			status:   http.StatusOK,
+			response: `{"offsets":[{"partition":0,"offset":1,"error_code":null,"error":null}]}`,
+		},
+		{
+			name:     "Record rejected",
+			status:   http.StatusOK,
+			response: `{"offsets":[{"partition":null,"offset":null,"error_code":50002,"error":"timeout"}]}`,
+			wantErr:  true,
+		},
+		{
+			name:     "Unknown topic",
+			status:   http.StatusNotFound,
+			response: `{"error_code":40401,"message":"Topic not found"}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/topics/url-shortener.events", r.URL.Path)
+				assert.Equal(t, "application/vnd.kafka.json.v2+json", r.Header.Get("Content-Type"))
+
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.JSONEq(t, `{"records":[{"key":"alias","value":{"type":"link_deleted"}}]}`, string(body))
+
+				w.WriteHeader(tc.status)
+				_, _ = io.WriteString(w, tc.response)
+			}))
+			defer srv.Close()
+
+			k := events.NewKafka(srv.URL+"/", "url-shortener.events", time.Second)
+			defer k.Close()
+
+			err := k.Send(context.Background(), []events.Message{
+				{Type: events.TypeLinkDeleted, Key: "alias", Value: []byte(`{"type":"link_deleted"}`)},
+			})
+			if tc.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const kafkaContentType = "application/vnd.kafka.json.v2+json"
+
+// Kafka produces messages to a topic through the Kafka REST Proxy v2 API
+// (Confluent REST Proxy, Redpanda HTTP Proxy). The alias is the record key,
+// so events of a link go to the same partition.
+type Kafka struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewKafka creates a Kafka sink producing to topic via the REST proxy at proxyURL.
+func NewKafka(proxyURL, topic string, timeout time.Duration) *Kafka {
+	return &Kafka{
+		endpoint: strings.TrimSuffix(proxyURL, "/") + "/topics/" + url.PathEscape(topic),
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+type kafkaRecord struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+type kafkaProduceRequest struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		ErrorCode *int `json:"error_code"`
+	} `json:"offsets"`
+}
+
+func (k *Kafka) Send(ctx context.Context, msgs []Message) error {
+	const op = "events.Kafka.Send"
+
+	records := make([]kafkaRecord, len(msgs))
+	for i, m := range msgs {
+		records[i] = kafkaRecord{Key: m.Key, Value: m.Value}
+	}
+
+	body, err := json.Marshal(kafkaProduceRequest{Records: records})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	res, err := k.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+
+		return fmt.Errorf("%s: unexpected status %d: %s", op, res.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	var produced kafkaProduceResponse
+	if err := json.NewDecoder(res.Body).Decode(&produced); err != nil {
+		return fmt.Errorf("%s: decode response: %w", op, err)
+	}
+
+	// Прокси отвечает 200, даже если часть записей не принята брокером
+	failed := 0
+	for _, o := range produced.Offsets {
+		if o.ErrorCode != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s: %d of %d records rejected", op, failed, len(msgs))
+	}
+
+	return nil
+}
+
+func (k *Kafka) Close() error {
+	k.client.CloseIdleConnections()
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATS publishes messages to "<prefix>.<type>" subjects, e.g. "url-shortener.link_clicked".
+type NATS struct {
+	conn    *nats.Conn
+	prefix  string
+	timeout time.Duration
+}
+
+// NewNATS connects to the NATS server; the client reconnects by itself when the connection drops.
+func NewNATS(url, subjectPrefix string, timeout time.Duration) (*NATS, error) {
+	conn, err := nats.Connect(url, nats.Name("url-shortener"), nats.Timeout(timeout), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("events.NewNATS: %w", err)
+	}
+
+	return &NATS{conn: conn, prefix: subjectPrefix, timeout: timeout}, nil
+}
+
+func (n *NATS) Send(ctx context.Context, msgs []Message) error {
+	for _, m := range msgs {
+		if err := n.conn.Publish(n.prefix+"."+m.Type, m.Value); err != nil {
+			return err
+		}
+	}
+
+	// Publish только пишет в буфер клиента, Flush дожидается отправки на сервер
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	return n.conn.FlushWithContext(ctx)
+}
+
+func (n *NATS) Close() error {
+	n.conn.Close()
+
+	return nil
+}
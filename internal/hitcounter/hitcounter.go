@@ -0,0 +1,139 @@
+package hitcounter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// HitsIncrementer is an interface for persisting aggregated hits.
+type HitsIncrementer interface {
+	IncrementHits(ctx context.Context, hits map[string]int64) error
+}
+
+// defaultFlushInterval replaces a zero flushInterval when batchSize is zero too.
+const defaultFlushInterval = time.Second
+
+// Counter counts alias hits off the request path: Hit only sends the alias
+// to a buffered channel, and a background goroutine aggregates hits
+// and flushes them to storage in batches: every flushInterval or as soon as
+// batchSize hits are collected, whichever comes first.
+type Counter struct {
+	log           *slog.Logger
+	store         HitsIncrementer
+	flushInterval time.Duration
+	batchSize     int
+
+	hits chan string
+	done chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+// New starts a counter; a zero batchSize flushes hits only every flushInterval, a zero
+// flushInterval only by batchSize. If both are zero, hits are flushed every
+// defaultFlushInterval.
+func New(log *slog.Logger, store HitsIncrementer, bufferSize, batchSize int, flushInterval time.Duration) *Counter {
+	log = log.With(slog.String("component", "hitcounter"))
+
+	// Без обоих ограничений переходы копились бы в памяти до остановки
+	if batchSize <= 0 && flushInterval <= 0 {
+		log.Warn("neither batch size nor flush interval is set, using default flush interval",
+			slog.Duration("flush_interval", defaultFlushInterval))
+
+		flushInterval = defaultFlushInterval
+	}
+
+	c := &Counter{
+		log:           log,
+		store:         store,
+		flushInterval: flushInterval,
+		batchSize:     batchSize,
+		hits:          make(chan string, bufferSize),
+		done:          make(chan struct{}),
+	}
+
+	c.wg.Add(1)
+	go c.run()
+
+	return c
+}
+
+// Hit registers one hit of alias. It never blocks: if the buffer is full, the hit is dropped.
+func (c *Counter) Hit(alias string) {
+	select {
+	case c.hits <- alias:
+	default:
+		c.log.Warn("hit buffer is full, hit dropped", slog.String("alias", alias))
+	}
+}
+
+// Close stops the counter and flushes buffered hits.
+func (c *Counter) Close() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+
+	c.wg.Wait()
+}
+
+func (c *Counter) run() {
+	defer c.wg.Done()
+
+	// Без интервала канал остается nil и сброс идет только по размеру пачки
+	var tick <-chan time.Time
+	if c.flushInterval > 0 {
+		ticker := time.NewTicker(c.flushInterval)
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	pending := make(map[string]int64)
+	// count is the number of hits in pending, it may be more than the number of aliases
+	var count int
+
+	for {
+		select {
+		case alias := <-c.hits:
+			pending[alias]++
+			count++
+
+			if c.batchSize > 0 && count >= c.batchSize {
+				c.flush(pending)
+				pending = make(map[string]int64)
+				count = 0
+			}
+		case <-tick:
+			c.flush(pending)
+			pending = make(map[string]int64)
+			count = 0
+		case <-c.done:
+			// Забираем всё, что осталось в буфере, и сбрасываем в хранилище
+			for {
+				select {
+				case alias := <-c.hits:
+					pending[alias]++
+				default:
+					c.flush(pending)
+
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *Counter) flush(pending map[string]int64) {
+	if len(pending) == 0 {
+		return
+	}
+
+	if err := c.store.IncrementHits(context.Background(), pending); err != nil {
+		c.log.Error("failed to flush hits", slog.Int("aliases", len(pending)), sl.Err(err))
+	}
+}
@@ -0,0 +1,111 @@
+package hitcounter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+type memStore struct {
+	mu   sync.Mutex
+	hits map[string]int64
+}
+
+func (s *memStore) IncrementHits(_ context.Context, hits map[string]int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for alias, n := range hits {
+		s.hits[alias] += n
+	}
+
+	return nil
+}
+
+func (s *memStore) get(alias string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.hits[alias]
+}
+
+func TestCounter_FlushOnClose(t *testing.T) {
+	store := &memStore{hits: make(map[string]int64)}
+
+	c := New(slogdiscard.NewDiscardLogger(), store, 100, 0, time.Hour)
+
+	for i := 0; i < 10; i++ {
+		c.Hit("a")
+	}
+	c.Hit("b")
+
+	c.Close()
+
+	require.Equal(t, map[string]int64{"a": 10, "b": 1}, store.hits)
+}
+
+func TestCounter_FlushOnBatchSize(t *testing.T) {
+	store := &memStore{hits: make(map[string]int64)}
+
+	c := New(slogdiscard.NewDiscardLogger(), store, 100, 5, time.Hour)
+	defer c.Close()
+
+	for i := 0; i < 4; i++ {
+		c.Hit("a")
+	}
+	c.Hit("b")
+
+	// Интервал не наступит, пачка из 5 переходов сбрасывается сразу
+	require.Eventually(t, func() bool {
+		return store.get("a") == 4 && store.get("b") == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestCounter_FlushOnInterval(t *testing.T) {
+	store := &memStore{hits: make(map[string]int64)}
+
+	c := New(slogdiscard.NewDiscardLogger(), store, 100, 1000, 10*time.Millisecond)
+	defer c.Close()
+
+	c.Hit("a")
+
+	require.Eventually(t, func() bool {
+		return store.get("a") == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestCounter_ZeroInterval(t *testing.T) {
+	store := &memStore{hits: make(map[string]int64)}
+
+	// Нулевой интервал означает сброс только по размеру пачки, без паники тикера
+	c := New(slogdiscard.NewDiscardLogger(), store, 100, 2, 0)
+	defer c.Close()
+
+	c.Hit("a")
+	c.Hit("a")
+
+	require.Eventually(t, func() bool {
+		return store.get("a") == 2
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestCounter_NoLimits(t *testing.T) {
+	store := &memStore{hits: make(map[string]int64)}
+
+	// Без размера пачки и интервала переходы все равно сбрасываются по интервалу по умолчанию
+	c := New(slogdiscard.NewDiscardLogger(), store, 100, 0, 0)
+	defer c.Close()
+
+	require.Equal(t, defaultFlushInterval, c.flushInterval)
+
+	c.Hit("a")
+
+	require.Eventually(t, func() bool {
+		return store.get("a") == 1
+	}, 3*defaultFlushInterval, 10*time.Millisecond)
+}
@@ -0,0 +1,19 @@
+package admin
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:embed index.html
+var indexHTML []byte
+
+// New returns handler serving the admin UI. The page talks to the JSON API with
+// an API key, which can be issued from the page itself via /admin/api-keys.
+func New() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write(indexHTML)
+	}
+}
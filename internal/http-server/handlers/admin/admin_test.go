@@ -0,0 +1,20 @@
+package admin_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/admin"
+)
+
+func TestNew(t *testing.T) {
+	rr := httptest.NewRecorder()
+	admin.New().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Header().Get("Content-Type"), "text/html")
+	require.Contains(t, rr.Body.String(), "url-shortener admin")
+}
@@ -0,0 +1,119 @@
+package stats
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type Clicks struct {
+	// Today counts clicks since the start of the UTC day.
+	Today   int64 `json:"today"`
+	Last7d  int64 `json:"last_7d"`
+	Last30d int64 `json:"last_30d"`
+}
+
+type Link struct {
+	// Alias is the key of the link with the tenant prefix.
+	Alias string `json:"alias"`
+	Hits  int64  `json:"hits"`
+}
+
+type Day struct {
+	// Day is YYYY-MM-DD in UTC.
+	Day   string `json:"day"`
+	Count int64  `json:"count"`
+}
+
+type Response struct {
+	resp.Response
+	// Links is the number of links, without deleted and archived ones.
+	Links  int64  `json:"links"`
+	Clicks Clicks `json:"clicks"`
+	// TopLinks are the 10 links with the most hits, top first.
+	TopLinks []Link `json:"top_links"`
+	// CreatedByDay is the number of links created on every day of the last 30 days;
+	// days without new links are omitted.
+	CreatedByDay []Day `json:"created_by_day"`
+	// GeneratedAt is the time the numbers were computed, earlier than now if cached.
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// ServiceStatsGetter is an interface for getting aggregates of all links.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=ServiceStatsGetter
+type ServiceStatsGetter interface {
+	GetServiceStats(ctx context.Context, now time.Time) (storage.ServiceStats, error)
+}
+
+// New returns totals, top links and the creation rate for ops dashboards. With
+// cacheTTL above zero the result is reused for that long, so that dashboards polling
+// the endpoint don't aggregate the storage on every request.
+func New(log *slog.Logger, statsGetter ServiceStatsGetter, cacheTTL time.Duration) http.HandlerFunc {
+	var (
+		mu     sync.Mutex
+		cached Response
+	)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.stats.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Пока один запрос считает статистику, остальные ждут его результата
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := time.Now()
+		if cacheTTL > 0 && now.Before(cached.GeneratedAt.Add(cacheTTL)) {
+			render.JSON(w, r, cached)
+
+			return
+		}
+
+		stats, err := statsGetter.GetServiceStats(r.Context(), now)
+		if err != nil {
+			log.Error("failed to get service stats", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		res := Response{
+			Response: resp.OK(),
+			Links:    stats.Links,
+			Clicks: Clicks{
+				Today:   stats.ClicksToday,
+				Last7d:  stats.Clicks7d,
+				Last30d: stats.Clicks30d,
+			},
+			TopLinks:     make([]Link, 0, len(stats.TopLinks)),
+			CreatedByDay: make([]Day, 0, len(stats.CreatedByDay)),
+			GeneratedAt:  now.UTC(),
+		}
+		for _, c := range stats.TopLinks {
+			res.TopLinks = append(res.TopLinks, Link{Alias: c.Key, Hits: c.Count})
+		}
+		for _, c := range stats.CreatedByDay {
+			res.CreatedByDay = append(res.CreatedByDay, Day{Day: c.Key, Count: c.Count})
+		}
+
+		cached = res
+
+		render.JSON(w, r, res)
+	}
+}
@@ -0,0 +1,104 @@
+package stats_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/admin/stats"
+	"url-shortener/internal/http-server/handlers/admin/stats/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+var serviceStats = storage.ServiceStats{
+	Links:        3,
+	ClicksToday:  1,
+	Clicks7d:     5,
+	Clicks30d:    9,
+	TopLinks:     []storage.Count{{Key: "top", Count: 7}, {Key: "next", Count: 2}},
+	CreatedByDay: []storage.Count{{Key: "2026-10-01", Count: 1}, {Key: "2026-10-16", Count: 2}},
+}
+
+func TestStatsHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		statsErr  error
+		respCode  int
+		respError string
+	}{
+		{
+			name:     "Success",
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "GetServiceStats Error",
+			statsErr:  errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			statsGetterMock := mocks.NewServiceStatsGetter(t)
+			statsGetterMock.On("GetServiceStats", mock.Anything, mock.AnythingOfType("time.Time")).
+				Return(serviceStats, tc.statsErr).Once()
+
+			handler := stats.New(slogdiscard.NewDiscardLogger(), statsGetterMock, 0)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var res stats.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+			require.Equal(t, tc.respError, res.Error.Error())
+
+			if tc.respError != "" {
+				return
+			}
+
+			require.Equal(t, int64(3), res.Links)
+			require.Equal(t, stats.Clicks{Today: 1, Last7d: 5, Last30d: 9}, res.Clicks)
+			require.Equal(t, []stats.Link{{Alias: "top", Hits: 7}, {Alias: "next", Hits: 2}}, res.TopLinks)
+			require.Equal(t, []stats.Day{{Day: "2026-10-01", Count: 1}, {Day: "2026-10-16", Count: 2}}, res.CreatedByDay)
+		})
+	}
+}
+
+func TestStatsHandler_Cache(t *testing.T) {
+	statsGetterMock := mocks.NewServiceStatsGetter(t)
+	// Второй запрос отдается из кеша
+	statsGetterMock.On("GetServiceStats", mock.Anything, mock.AnythingOfType("time.Time")).
+		Return(serviceStats, nil).Once()
+
+	handler := stats.New(slogdiscard.NewDiscardLogger(), statsGetterMock, time.Minute)
+
+	var generated []time.Time
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/stats", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var res stats.Response
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		require.Equal(t, int64(3), res.Links)
+
+		generated = append(generated, res.GeneratedAt)
+	}
+
+	require.Equal(t, generated[0], generated[1])
+}
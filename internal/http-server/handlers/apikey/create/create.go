@@ -0,0 +1,155 @@
+package create
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/signature"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	Name string `json:"name" validate:"required"`
+	// Tenant binds the key to the tenant's links; empty means the default tenant,
+	// whose keys act in the tenant of the request.
+	Tenant string `json:"tenant,omitempty"`
+	// Role is "viewer", "editor" or "admin", see package acl; empty means editor.
+	Role string `json:"role,omitempty" validate:"omitempty,oneof=viewer editor admin"`
+	// Signing also issues a secret for signing requests instead of sending the key, see package signature.
+	Signing bool `json:"signing,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	ID int64 `json:"id,omitempty"`
+	// Key is shown only once: the storage keeps its hash only.
+	Key string `json:"key,omitempty"`
+	// SigningSecret is shown only once as well.
+	SigningSecret string `json:"signing_secret,omitempty"`
+}
+
+// APIKeySaver is an interface for saving api keys.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=APIKeySaver
+type APIKeySaver interface {
+	SaveAPIKey(ctx context.Context, key storage.APIKey) (int64, error)
+}
+
+func New(log *slog.Logger, keySaver APIKeySaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apikey.create.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "empty request"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to decode request"))
+
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(r, validateErr))
+
+			return
+		}
+
+		if req.Tenant != "" {
+			if err := tenant.Validate(req.Tenant); err != nil {
+				log.Info("invalid tenant", slog.String("tenant", req.Tenant), sl.Err(err))
+
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
+
+				return
+			}
+		}
+
+		key, err := apikey.Generate()
+		if err != nil {
+			log.Error("failed to generate api key", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		var signingSecret string
+		if req.Signing {
+			signingSecret, err = signature.GenerateSecret()
+			if err != nil {
+				log.Error("failed to generate signing secret", sl.Err(err))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+				return
+			}
+		}
+
+		id, err := keySaver.SaveAPIKey(r.Context(), storage.APIKey{
+			Name:          req.Name,
+			Hash:          apikey.Hash(key),
+			Tenant:        req.Tenant,
+			Role:          req.Role,
+			SigningSecret: signingSecret,
+		})
+		if err != nil {
+			log.Error("failed to save api key", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("api key created",
+			slog.Int64("id", id),
+			slog.String("name", req.Name),
+			slog.String("tenant", req.Tenant),
+			slog.String("role", req.Role),
+			slog.Bool("signing", req.Signing),
+		)
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{
+			Response:      resp.OK(),
+			ID:            id,
+			Key:           key,
+			SigningSecret: signingSecret,
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package create_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/apikey/create"
+	"url-shortener/internal/http-server/handlers/apikey/create/mocks"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestCreateHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		respCode  int
+		respError string
+		mockError error
+		mockCall  bool
+		tenant    string
+		role      string
+		signing   bool
+	}{
+		{
+			name:     "Success",
+			body:     `{"name": "ci"}`,
+			respCode: http.StatusCreated,
+			mockCall: true,
+		},
+		{
+			name:     "Tenant",
+			body:     `{"name": "ci", "tenant": "brand"}`,
+			respCode: http.StatusCreated,
+			mockCall: true,
+			tenant:   "brand",
+		},
+		{
+			name:     "Admin",
+			body:     `{"name": "ci", "role": "admin"}`,
+			respCode: http.StatusCreated,
+			mockCall: true,
+			role:     "admin",
+		},
+		{
+			name:     "Viewer",
+			body:     `{"name": "ci", "role": "viewer"}`,
+			respCode: http.StatusCreated,
+			mockCall: true,
+			role:     "viewer",
+		},
+		{
+			name:     "Signing",
+			body:     `{"name": "ci", "signing": true}`,
+			respCode: http.StatusCreated,
+			mockCall: true,
+			signing:  true,
+		},
+		{
+			name:      "Unknown role",
+			body:      `{"name": "ci", "role": "owner"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field Role is not valid",
+		},
+		{
+			name:      "Invalid tenant",
+			body:      `{"name": "ci", "tenant": "brand.example"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "invalid tenant",
+		},
+		{
+			name:      "Empty name",
+			body:      `{}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field Name is a required field",
+		},
+		{
+			name:      "SaveAPIKey Error",
+			body:      `{"name": "ci"}`,
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+			mockError: errors.New("unexpected error"),
+			mockCall:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			keySaverMock := mocks.NewAPIKeySaver(t)
+
+			var savedHash, savedSecret string
+			if tc.mockCall {
+				keySaverMock.On("SaveAPIKey", mock.Anything, mock.MatchedBy(func(k storage.APIKey) bool {
+					savedHash = k.Hash
+					savedSecret = k.SigningSecret
+
+					return k.Name == "ci" && k.Hash != "" && k.Tenant == tc.tenant && k.Role == tc.role
+				})).
+					Return(int64(1), tc.mockError).
+					Once()
+			}
+
+			handler := create.New(slogdiscard.NewDiscardLogger(), keySaverMock)
+
+			req, err := http.NewRequest(http.MethodPost, "/admin/api-keys", bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp create.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+
+			if tc.respCode == http.StatusCreated {
+				// Сохраняется только хеш выданного ключа
+				require.Equal(t, apikey.Hash(resp.Key), savedHash)
+				require.Equal(t, resp.SigningSecret, savedSecret)
+				require.Equal(t, tc.signing, resp.SigningSecret != "")
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package revoke
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// APIKeyRevoker is an interface for revoking api keys by id.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=APIKeyRevoker
+type APIKeyRevoker interface {
+	RevokeAPIKey(ctx context.Context, id int64) error
+}
+
+func New(log *slog.Logger, keyRevoker APIKeyRevoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apikey.revoke.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			log.Info("invalid api key id", slog.String("id", chi.URLParam(r, "id")))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid request"))
+
+			return
+		}
+
+		err = keyRevoker.RevokeAPIKey(r.Context(), id)
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			log.Info("api key not found", slog.Int64("id", id))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to revoke api key", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("api key revoked", slog.Int64("id", id))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,109 @@
+package forgot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// UserProvider is an interface for getting user accounts by email.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=UserProvider
+type UserProvider interface {
+	GetUser(ctx context.Context, email string) (storage.User, error)
+}
+
+// ResetSender is an interface for emailing users a link to set a new password.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=ResetSender
+type ResetSender interface {
+	SendPasswordReset(ctx context.Context, email string) error
+}
+
+// New returns the handler which emails a password reset link. It answers the same
+// for known and unknown emails, so it can't be used to find out registered ones.
+func New(log *slog.Logger, userProvider UserProvider, resetSender ResetSender) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.forgot.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "empty request"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to decode request"))
+
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Info("invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(r, validateErr))
+
+			return
+		}
+
+		_, err = userProvider.GetUser(r.Context(), req.Email)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found", slog.String("email", req.Email))
+
+			render.JSON(w, r, resp.OK())
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get user", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		if err := resetSender.SendPasswordReset(r.Context(), req.Email); err != nil {
+			log.Error("failed to send password reset email", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("password reset email sent", slog.String("email", req.Email))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
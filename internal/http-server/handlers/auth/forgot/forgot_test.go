@@ -0,0 +1,109 @@
+package forgot_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/auth/forgot"
+	"url-shortener/internal/http-server/handlers/auth/forgot/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestForgotHandler(t *testing.T) {
+	const email = "user@example.com"
+
+	cases := []struct {
+		name      string
+		body      string
+		respCode  int
+		respError string
+		getCall   bool
+		getError  error
+		sendCall  bool
+		sendError error
+	}{
+		{
+			name:     "Success",
+			body:     `{"email": "user@example.com"}`,
+			respCode: http.StatusOK,
+			getCall:  true,
+			sendCall: true,
+		},
+		{
+			// Ответ не отличается от ответа для зарегистрированного email
+			name:     "Unknown user",
+			body:     `{"email": "user@example.com"}`,
+			respCode: http.StatusOK,
+			getCall:  true,
+			getError: storage.ErrUserNotFound,
+		},
+		{
+			name:      "Invalid email",
+			body:      `{"email": "user"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field Email is not valid",
+		},
+		{
+			name:      "GetUser Error",
+			body:      `{"email": "user@example.com"}`,
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+			getCall:   true,
+			getError:  errors.New("unexpected error"),
+		},
+		{
+			name:      "Send error",
+			body:      `{"email": "user@example.com"}`,
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+			getCall:   true,
+			sendCall:  true,
+			sendError: errors.New("smtp unavailable"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			userProviderMock := mocks.NewUserProvider(t)
+			resetSenderMock := mocks.NewResetSender(t)
+
+			if tc.getCall {
+				userProviderMock.On("GetUser", mock.Anything, email).
+					Return(storage.User{ID: 1, Email: email}, tc.getError).
+					Once()
+			}
+			if tc.sendCall {
+				resetSenderMock.On("SendPasswordReset", mock.Anything, email).
+					Return(tc.sendError).
+					Once()
+			}
+
+			handler := forgot.New(slogdiscard.NewDiscardLogger(), userProviderMock, resetSenderMock)
+
+			req, err := http.NewRequest(http.MethodPost, "/auth/password/forgot", bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			require.Equal(t, tc.respError, body.Error.Error())
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package login
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/jwt"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type Response struct {
+	resp.Response
+	Token string `json:"token,omitempty"`
+}
+
+// UserProvider is an interface for getting user accounts by email.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=UserProvider
+type UserProvider interface {
+	GetUser(ctx context.Context, email string) (storage.User, error)
+}
+
+// New returns the login handler. With requireVerification users who haven't confirmed
+// the email are denied.
+func New(
+	log *slog.Logger,
+	userProvider UserProvider,
+	secret string,
+	tokenTTL time.Duration,
+	requireVerification bool,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.login.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "empty request"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to decode request"))
+
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Info("invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(r, validateErr))
+
+			return
+		}
+
+		user, err := userProvider.GetUser(r.Context(), req.Email)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found", slog.String("email", req.Email))
+
+			invalidCredentials(w, r)
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get user", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(req.Password)); err != nil {
+			log.Info("invalid password", slog.Int64("user_id", user.ID))
+
+			invalidCredentials(w, r)
+
+			return
+		}
+
+		// Проверяем после пароля, чтобы не раскрывать, подтвержден ли чужой email
+		if requireVerification && user.VerifiedAt.IsZero() {
+			log.Info("email is not verified", slog.Int64("user_id", user.ID))
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error(r, resp.CodeForbidden, "email is not verified"))
+
+			return
+		}
+
+		token, err := jwt.NewToken(user, secret, tokenTTL)
+		if err != nil {
+			log.Error("failed to issue token", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("user logged in", slog.Int64("user_id", user.ID))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Token:    token,
+		})
+	}
+}
+
+// invalidCredentials doesn't tell unknown email from wrong password.
+func invalidCredentials(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, http.StatusUnauthorized)
+	render.JSON(w, r, resp.Error(r, resp.CodeUnauthorized, "invalid email or password"))
+}
@@ -0,0 +1,121 @@
+package login_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"url-shortener/internal/http-server/handlers/auth/login"
+	"url-shortener/internal/http-server/handlers/auth/login/mocks"
+	"url-shortener/internal/lib/jwt"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestLoginHandler(t *testing.T) {
+	const secret = "test-secret"
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	user := storage.User{ID: 7, Email: "user@example.com", PassHash: passHash, Tenant: "brand", VerifiedAt: time.Now()}
+
+	cases := []struct {
+		name       string
+		password   string
+		respCode   int
+		respError  string
+		mockError  error
+		unverified bool
+	}{
+		{
+			name:     "Success",
+			password: "password123",
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Wrong password",
+			password:  "wrong",
+			respCode:  http.StatusUnauthorized,
+			respError: "invalid email or password",
+		},
+		{
+			name:       "Not verified",
+			password:   "password123",
+			respCode:   http.StatusForbidden,
+			respError:  "email is not verified",
+			unverified: true,
+		},
+		{
+			name:       "Not verified, wrong password",
+			password:   "wrong",
+			respCode:   http.StatusUnauthorized,
+			respError:  "invalid email or password",
+			unverified: true,
+		},
+		{
+			name:      "Unknown user",
+			password:  "password123",
+			respCode:  http.StatusUnauthorized,
+			respError: "invalid email or password",
+			mockError: storage.ErrUserNotFound,
+		},
+		{
+			name:      "GetUser Error",
+			password:  "password123",
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			userProviderMock := mocks.NewUserProvider(t)
+
+			user := user
+			if tc.unverified {
+				user.VerifiedAt = time.Time{}
+			}
+
+			userProviderMock.On("GetUser", mock.Anything, user.Email).
+				Return(user, tc.mockError).
+				Once()
+
+			handler := login.New(slogdiscard.NewDiscardLogger(), userProviderMock, secret, time.Hour, true)
+
+			body, err := json.Marshal(login.Request{Email: user.Email, Password: tc.password})
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp login.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+
+			if tc.respCode == http.StatusOK {
+				claims, err := jwt.ParseToken(resp.Token, secret)
+				require.NoError(t, err)
+				require.Equal(t, jwt.Claims{UserID: user.ID, Tenant: user.Tenant}, claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package register
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+type Response struct {
+	resp.Response
+	UserID int64 `json:"user_id,omitempty"`
+}
+
+// UserSaver is an interface for creating user accounts.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=UserSaver
+type UserSaver interface {
+	SaveUser(ctx context.Context, user storage.User) (int64, error)
+}
+
+// VerificationSender is an interface for emailing users a link to confirm the email.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=VerificationSender
+type VerificationSender interface {
+	SendVerification(ctx context.Context, email string) error
+}
+
+func New(log *slog.Logger, userSaver UserSaver, verificationSender VerificationSender) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.register.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "empty request"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to decode request"))
+
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			// Пароль в лог не пишем
+			log.Info("invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(r, validateErr))
+
+			return
+		}
+
+		passHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			log.Error("failed to hash password", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		// Пользователь принадлежит тенанту, на адрес которого пришла регистрация
+		id, err := userSaver.SaveUser(r.Context(), storage.User{
+			Email:    req.Email,
+			PassHash: passHash,
+			Tenant:   tenant.FromContext(r.Context()),
+		})
+		if errors.Is(err, storage.ErrUserExists) {
+			log.Info("user already exists", slog.String("email", req.Email))
+
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, resp.Error(r, resp.CodeConflict, "user already exists"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to save user", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("user registered", slog.Int64("id", id))
+
+		// Пользователь уже создан, поэтому ошибку письма не возвращаем:
+		// новое письмо придет при сбросе пароля, он тоже подтверждает email
+		if err := verificationSender.SendVerification(r.Context(), req.Email); err != nil {
+			log.Error("failed to send verification email", sl.Err(err))
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			UserID:   id,
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package register_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"url-shortener/internal/http-server/handlers/auth/register"
+	"url-shortener/internal/http-server/handlers/auth/register/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+func TestRegisterHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		respCode  int
+		respError string
+		mockError error
+		mockCall  bool
+		tenant    string
+		sendCall  bool
+		sendError error
+	}{
+		{
+			name:     "Success",
+			body:     `{"email": "user@example.com", "password": "password123"}`,
+			respCode: http.StatusCreated,
+			mockCall: true,
+			sendCall: true,
+		},
+		{
+			name:     "Tenant",
+			body:     `{"email": "user@example.com", "password": "password123"}`,
+			respCode: http.StatusCreated,
+			mockCall: true,
+			tenant:   "brand",
+			sendCall: true,
+		},
+		{
+			name:      "Send error",
+			body:      `{"email": "user@example.com", "password": "password123"}`,
+			respCode:  http.StatusCreated,
+			mockCall:  true,
+			sendCall:  true,
+			sendError: errors.New("smtp unavailable"),
+		},
+		{
+			name:      "Invalid email",
+			body:      `{"email": "user", "password": "password123"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field Email is not valid",
+		},
+		{
+			name:      "Short password",
+			body:      `{"email": "user@example.com", "password": "short"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field Password is not valid",
+		},
+		{
+			name:      "User exists",
+			body:      `{"email": "user@example.com", "password": "password123"}`,
+			respCode:  http.StatusConflict,
+			respError: "user already exists",
+			mockError: storage.ErrUserExists,
+			mockCall:  true,
+		},
+		{
+			name:      "SaveUser Error",
+			body:      `{"email": "user@example.com", "password": "password123"}`,
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+			mockError: errors.New("unexpected error"),
+			mockCall:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			userSaverMock := mocks.NewUserSaver(t)
+
+			if tc.mockCall {
+				userSaverMock.On("SaveUser", mock.Anything, mock.MatchedBy(func(user storage.User) bool {
+					return user.Email == "user@example.com" && user.Tenant == tc.tenant &&
+						bcrypt.CompareHashAndPassword(user.PassHash, []byte("password123")) == nil
+				})).
+					Return(int64(1), tc.mockError).
+					Once()
+			}
+
+			senderMock := mocks.NewVerificationSender(t)
+
+			if tc.sendCall {
+				senderMock.On("SendVerification", mock.Anything, "user@example.com").
+					Return(tc.sendError).
+					Once()
+			}
+
+			handler := register.New(slogdiscard.NewDiscardLogger(), userSaverMock, senderMock)
+
+			req, err := http.NewRequest(http.MethodPost, "/auth/register", bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+			req = req.WithContext(tenant.WithTenant(req.Context(), tc.tenant))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp register.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package reset
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/usermail"
+)
+
+type Request struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+// PasswordResetter is an interface for setting passwords of users by emailed tokens.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=PasswordResetter
+type PasswordResetter interface {
+	ConsumeUserToken(ctx context.Context, hash, purpose string) (storage.UserToken, error)
+	SetUserPassword(ctx context.Context, email string, passHash []byte) error
+}
+
+func New(log *slog.Logger, passwordResetter PasswordResetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.reset.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "empty request"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to decode request"))
+
+			return
+		}
+
+		// Проверяем запрос до того, как токен будет израсходован
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			// Пароль в лог не пишем
+			log.Info("invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(r, validateErr))
+
+			return
+		}
+
+		passHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			log.Error("failed to hash password", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		token, err := passwordResetter.ConsumeUserToken(r.Context(), usermail.HashToken(req.Token), storage.TokenResetPassword)
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			log.Info("token not found")
+
+			invalidToken(w, r)
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to consume token", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		err = passwordResetter.SetUserPassword(r.Context(), token.Email, passHash)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found", slog.String("email", token.Email))
+
+			invalidToken(w, r)
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to set password", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("password reset", slog.String("email", token.Email))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+func invalidToken(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, http.StatusBadRequest)
+	render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid or expired token"))
+}
@@ -0,0 +1,128 @@
+package reset_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"url-shortener/internal/http-server/handlers/auth/reset"
+	"url-shortener/internal/http-server/handlers/auth/reset/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/usermail"
+)
+
+func TestResetHandler(t *testing.T) {
+	const email = "user@example.com"
+
+	cases := []struct {
+		name         string
+		body         string
+		respCode     int
+		respError    string
+		consumeCall  bool
+		consumeError error
+		setCall      bool
+		setError     error
+	}{
+		{
+			name:        "Success",
+			body:        `{"token": "secret", "password": "new-password"}`,
+			respCode:    http.StatusOK,
+			consumeCall: true,
+			setCall:     true,
+		},
+		{
+			// Токен не расходуется, если пароль не подходит
+			name:      "Short password",
+			body:      `{"token": "secret", "password": "short"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field Password is not valid",
+		},
+		{
+			name:      "Empty token",
+			body:      `{"password": "new-password"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field Token is a required field",
+		},
+		{
+			name:         "Unknown token",
+			body:         `{"token": "secret", "password": "new-password"}`,
+			respCode:     http.StatusBadRequest,
+			respError:    "invalid or expired token",
+			consumeCall:  true,
+			consumeError: storage.ErrTokenNotFound,
+		},
+		{
+			name:         "ConsumeUserToken Error",
+			body:         `{"token": "secret", "password": "new-password"}`,
+			respCode:     http.StatusInternalServerError,
+			respError:    "internal error",
+			consumeCall:  true,
+			consumeError: errors.New("unexpected error"),
+		},
+		{
+			name:        "Deleted user",
+			body:        `{"token": "secret", "password": "new-password"}`,
+			respCode:    http.StatusBadRequest,
+			respError:   "invalid or expired token",
+			consumeCall: true,
+			setCall:     true,
+			setError:    storage.ErrUserNotFound,
+		},
+		{
+			name:        "SetUserPassword Error",
+			body:        `{"token": "secret", "password": "new-password"}`,
+			respCode:    http.StatusInternalServerError,
+			respError:   "internal error",
+			consumeCall: true,
+			setCall:     true,
+			setError:    errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			resetterMock := mocks.NewPasswordResetter(t)
+
+			if tc.consumeCall {
+				resetterMock.On("ConsumeUserToken", mock.Anything, usermail.HashToken("secret"), storage.TokenResetPassword).
+					Return(storage.UserToken{Email: email}, tc.consumeError).
+					Once()
+			}
+			if tc.setCall {
+				resetterMock.On("SetUserPassword", mock.Anything, email, mock.MatchedBy(func(passHash []byte) bool {
+					return bcrypt.CompareHashAndPassword(passHash, []byte("new-password")) == nil
+				})).
+					Return(tc.setError).
+					Once()
+			}
+
+			handler := reset.New(slogdiscard.NewDiscardLogger(), resetterMock)
+
+			req, err := http.NewRequest(http.MethodPost, "/auth/password/reset", bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			require.Equal(t, tc.respError, body.Error.Error())
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package sso
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/jwt"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/oidc"
+	"url-shortener/internal/storage"
+)
+
+// stateCookie keeps state and nonce of the login between the redirect to the provider and the callback.
+const (
+	stateCookie = "sso_state"
+	stateTTL    = 10 * time.Minute
+)
+
+type Response struct {
+	resp.Response
+	Token string `json:"token,omitempty"`
+}
+
+// Provider is an interface for logging users in with an OAuth2 provider, see package oidc.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=Provider
+type Provider interface {
+	AuthCodeURL(ctx context.Context, state, nonce string) (string, error)
+	Exchange(ctx context.Context, code, nonce string) (oidc.Identity, error)
+}
+
+// UserStore is an interface for finding and creating accounts of users logged in with a provider.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=UserStore
+type UserStore interface {
+	GetUser(ctx context.Context, email string) (storage.User, error)
+	SaveUser(ctx context.Context, user storage.User) (int64, error)
+	SetUserPassword(ctx context.Context, email string, passHash []byte) error
+}
+
+type Options struct {
+	Secret   string
+	TokenTTL time.Duration
+	// AllowedDomains limit emails of users who may log in, e.g. "example.com"; empty allows any.
+	AllowedDomains []string
+	// AppURL receives the token as "<app_url>/login#token=..."; empty responds with JSON.
+	AppURL string
+}
+
+// NewLogin returns the handler which sends the user to the login page of the provider.
+func NewLogin(log *slog.Logger, provider Provider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.sso.NewLogin"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		state, err := oidc.NewState()
+		if err != nil {
+			log.Error("failed to generate state", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		nonce, err := oidc.NewState()
+		if err != nil {
+			log.Error("failed to generate nonce", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		authURL, err := provider.AuthCodeURL(r.Context(), state, nonce)
+		if err != nil {
+			log.Error("failed to get login page of provider", sl.Err(err))
+
+			render.Status(r, http.StatusBadGateway)
+			render.JSON(w, r, resp.Error(r, resp.CodeUnavailable, "provider is unavailable"))
+
+			return
+		}
+
+		// Lax, потому что провайдер возвращает пользователя переходом с другого сайта
+		http.SetCookie(w, &http.Cookie{
+			Name:     stateCookie,
+			Value:    state + "." + nonce,
+			Path:     "/",
+			MaxAge:   int(stateTTL.Seconds()),
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		http.Redirect(w, r, authURL, http.StatusFound)
+	}
+}
+
+// NewCallback returns the handler the provider sends the user back to. It creates
+// an account for a new email and issues a JWT like login.
+func NewCallback(log *slog.Logger, provider Provider, userStore UserStore, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.sso.NewCallback"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		q := r.URL.Query()
+
+		if e := q.Get("error"); e != "" {
+			log.Info("login denied by provider", slog.String("error", e))
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error(r, resp.CodeUnauthorized, "login denied by provider"))
+
+			return
+		}
+
+		// Состояние сверяем с cookie браузера, начавшего вход, против CSRF
+		var state, nonce string
+		if cookie, err := r.Cookie(stateCookie); err == nil {
+			state, nonce, _ = strings.Cut(cookie.Value, ".")
+		}
+		if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(q.Get("state"))) != 1 {
+			log.Info("invalid state")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid state, start the login again"))
+
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{Name: stateCookie, Path: "/", MaxAge: -1})
+
+		identity, err := provider.Exchange(r.Context(), q.Get("code"), nonce)
+		if errors.Is(err, oidc.ErrEmailNotVerified) {
+			log.Info("email is not verified by provider")
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error(r, resp.CodeForbidden, "email is not verified"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to exchange code", sl.Err(err))
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error(r, resp.CodeUnauthorized, "authentication failed"))
+
+			return
+		}
+
+		if !allowedDomain(identity.Email, opts.AllowedDomains) {
+			log.Info("email domain is not allowed", slog.String("email", identity.Email))
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error(r, resp.CodeForbidden, "email domain is not allowed"))
+
+			return
+		}
+
+		user, err := account(r.Context(), userStore, identity.Email)
+		if err != nil {
+			log.Error("failed to get account", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		token, err := jwt.NewToken(user, opts.Secret, opts.TokenTTL)
+		if err != nil {
+			log.Error("failed to issue token", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("user logged in", slog.Int64("user_id", user.ID))
+
+		// Во фрагменте токен не уходит на сервер приложения и не попадает в его журналы
+		if opts.AppURL != "" {
+			http.Redirect(w, r, strings.TrimSuffix(opts.AppURL, "/")+"/login#token="+token, http.StatusFound)
+
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Token:    token,
+		})
+	}
+}
+
+// account returns the user with email, creating it if there is none. The provider
+// has verified the email, so an account registered with it but not confirmed loses
+// its password: whoever registered it could be someone else.
+func account(ctx context.Context, userStore UserStore, email string) (storage.User, error) {
+	user, err := userStore.GetUser(ctx, email)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		user = storage.User{
+			Email: email,
+			// Пустой хеш не совпадает ни с одним паролем, вход возможен только через провайдера
+			PassHash:   []byte{},
+			Tenant:     tenant.FromContext(ctx),
+			VerifiedAt: time.Now(),
+		}
+
+		user.ID, err = userStore.SaveUser(ctx, user)
+
+		return user, err
+	}
+	if err != nil {
+		return storage.User{}, err
+	}
+
+	if user.VerifiedAt.IsZero() {
+		if err := userStore.SetUserPassword(ctx, email, []byte{}); err != nil {
+			return storage.User{}, err
+		}
+	}
+
+	return user, nil
+}
+
+func allowedDomain(email string, domains []string) bool {
+	if len(domains) == 0 {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+
+	return slices.ContainsFunc(domains, func(d string) bool {
+		return strings.EqualFold(d, email[at+1:])
+	})
+}
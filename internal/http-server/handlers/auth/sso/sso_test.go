@@ -0,0 +1,265 @@
+package sso_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/auth/sso"
+	"url-shortener/internal/http-server/handlers/auth/sso/mocks"
+	"url-shortener/internal/lib/jwt"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/oidc"
+	"url-shortener/internal/storage"
+)
+
+const secret = "test-secret"
+
+func TestLogin(t *testing.T) {
+	providerMock := mocks.NewProvider(t)
+
+	var state, nonce string
+	providerMock.On("AuthCodeURL", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { state, nonce = args.String(1), args.String(2) }).
+		Return("https://provider.example/authorize?state=x", nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/sso/google/login", nil)
+	rr := httptest.NewRecorder()
+	sso.NewLogin(slogdiscard.NewDiscardLogger(), providerMock).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "https://provider.example/authorize?state=x", rr.Header().Get("Location"))
+
+	require.NotEmpty(t, state)
+	require.NotEmpty(t, nonce)
+	assert.NotEqual(t, state, nonce)
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, state+"."+nonce, cookies[0].Value)
+	assert.True(t, cookies[0].HttpOnly)
+}
+
+func TestLogin_ProviderUnavailable(t *testing.T) {
+	providerMock := mocks.NewProvider(t)
+	providerMock.On("AuthCodeURL", mock.Anything, mock.Anything, mock.Anything).
+		Return("", errors.New("discovery failed")).
+		Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/sso/google/login", nil)
+	rr := httptest.NewRecorder()
+	sso.NewLogin(slogdiscard.NewDiscardLogger(), providerMock).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusBadGateway, rr.Code)
+	assert.Empty(t, rr.Result().Cookies())
+}
+
+func TestCallback(t *testing.T) {
+	const email = "user@example.com"
+
+	cases := []struct {
+		name         string
+		query        string
+		cookie       string
+		exchangeCall bool
+		exchangeErr  error
+		domains      []string
+		appURL       string
+		getCall      bool
+		user         storage.User
+		getErr       error
+		saveCall     bool
+		resetCall    bool
+		respCode     int
+		respError    string
+		wantUserID   int64
+	}{
+		{
+			name:         "New user",
+			query:        "state=state&code=code",
+			cookie:       "state.nonce",
+			exchangeCall: true,
+			getCall:      true,
+			getErr:       storage.ErrUserNotFound,
+			saveCall:     true,
+			respCode:     http.StatusOK,
+			wantUserID:   2,
+		},
+		{
+			name:         "Existing user",
+			query:        "state=state&code=code",
+			cookie:       "state.nonce",
+			exchangeCall: true,
+			getCall:      true,
+			domains:      []string{"other.com", "Example.com"},
+			user:         storage.User{ID: 7, Email: email, VerifiedAt: time.Now()},
+			respCode:     http.StatusOK,
+			wantUserID:   7,
+		},
+		{
+			// Пароль мог задать кто угодно, раз email не подтвержден
+			name:         "Unverified user",
+			query:        "state=state&code=code",
+			cookie:       "state.nonce",
+			exchangeCall: true,
+			getCall:      true,
+			user:         storage.User{ID: 7, Email: email},
+			resetCall:    true,
+			respCode:     http.StatusOK,
+			wantUserID:   7,
+		},
+		{
+			name:         "Redirect to app",
+			query:        "state=state&code=code",
+			cookie:       "state.nonce",
+			exchangeCall: true,
+			getCall:      true,
+			appURL:       "https://sho.rt/app/",
+			user:         storage.User{ID: 7, Email: email, VerifiedAt: time.Now()},
+			respCode:     http.StatusFound,
+			wantUserID:   7,
+		},
+		{
+			name:      "Denied by provider",
+			query:     "error=access_denied&state=state",
+			cookie:    "state.nonce",
+			respCode:  http.StatusUnauthorized,
+			respError: "login denied by provider",
+		},
+		{
+			name:      "Other state",
+			query:     "state=forged&code=code",
+			cookie:    "state.nonce",
+			respCode:  http.StatusBadRequest,
+			respError: "invalid state, start the login again",
+		},
+		{
+			name:      "No cookie",
+			query:     "state=state&code=code",
+			respCode:  http.StatusBadRequest,
+			respError: "invalid state, start the login again",
+		},
+		{
+			name:         "Email not verified",
+			query:        "state=state&code=code",
+			cookie:       "state.nonce",
+			exchangeCall: true,
+			exchangeErr:  oidc.ErrEmailNotVerified,
+			respCode:     http.StatusForbidden,
+			respError:    "email is not verified",
+		},
+		{
+			name:         "Exchange error",
+			query:        "state=state&code=code",
+			cookie:       "state.nonce",
+			exchangeCall: true,
+			exchangeErr:  errors.New("invalid_grant"),
+			respCode:     http.StatusUnauthorized,
+			respError:    "authentication failed",
+		},
+		{
+			name:         "Domain not allowed",
+			query:        "state=state&code=code",
+			cookie:       "state.nonce",
+			exchangeCall: true,
+			domains:      []string{"corp.example"},
+			respCode:     http.StatusForbidden,
+			respError:    "email domain is not allowed",
+		},
+		{
+			name:         "GetUser Error",
+			query:        "state=state&code=code",
+			cookie:       "state.nonce",
+			exchangeCall: true,
+			getCall:      true,
+			getErr:       errors.New("unexpected error"),
+			respCode:     http.StatusInternalServerError,
+			respError:    "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			providerMock := mocks.NewProvider(t)
+			userStoreMock := mocks.NewUserStore(t)
+
+			if tc.exchangeCall {
+				providerMock.On("Exchange", mock.Anything, "code", "nonce").
+					Return(oidc.Identity{Email: email}, tc.exchangeErr).
+					Once()
+			}
+			if tc.getCall {
+				userStoreMock.On("GetUser", mock.Anything, email).
+					Return(tc.user, tc.getErr).
+					Once()
+			}
+			if tc.saveCall {
+				userStoreMock.On("SaveUser", mock.Anything, mock.MatchedBy(func(u storage.User) bool {
+					return u.Email == email && len(u.PassHash) == 0 && !u.VerifiedAt.IsZero()
+				})).
+					Return(int64(2), nil).
+					Once()
+			}
+			if tc.resetCall {
+				userStoreMock.On("SetUserPassword", mock.Anything, email, []byte{}).
+					Return(nil).
+					Once()
+			}
+
+			handler := sso.NewCallback(slogdiscard.NewDiscardLogger(), providerMock, userStoreMock, sso.Options{
+				Secret:         secret,
+				TokenTTL:       time.Hour,
+				AllowedDomains: tc.domains,
+				AppURL:         tc.appURL,
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/auth/sso/google/callback?"+tc.query, nil)
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "sso_state", Value: tc.cookie})
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			if tc.respCode == http.StatusFound {
+				location := rr.Header().Get("Location")
+				require.True(t, strings.HasPrefix(location, "https://sho.rt/app/login#token="), location)
+
+				assertToken(t, strings.TrimPrefix(location, "https://sho.rt/app/login#token="), tc.wantUserID)
+
+				return
+			}
+
+			var resp sso.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+
+			if tc.respCode == http.StatusOK {
+				assertToken(t, resp.Token, tc.wantUserID)
+			}
+		})
+	}
+}
+
+func assertToken(t *testing.T, token string, userID int64) {
+	t.Helper()
+
+	claims, err := jwt.ParseToken(token, secret)
+	require.NoError(t, err)
+	assert.Equal(t, userID, claims.UserID)
+}
@@ -0,0 +1,115 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/usermail"
+)
+
+type Request struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// UserVerifier is an interface for confirming emails of users by emailed tokens.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=UserVerifier
+type UserVerifier interface {
+	ConsumeUserToken(ctx context.Context, hash, purpose string) (storage.UserToken, error)
+	VerifyUser(ctx context.Context, email string) error
+}
+
+func New(log *slog.Logger, userVerifier UserVerifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.verify.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "empty request"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to decode request"))
+
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Info("invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(r, validateErr))
+
+			return
+		}
+
+		token, err := userVerifier.ConsumeUserToken(r.Context(), usermail.HashToken(req.Token), storage.TokenVerifyEmail)
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			log.Info("token not found")
+
+			invalidToken(w, r)
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to consume token", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		err = userVerifier.VerifyUser(r.Context(), token.Email)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found", slog.String("email", token.Email))
+
+			invalidToken(w, r)
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to verify user", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("email verified", slog.String("email", token.Email))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+func invalidToken(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, http.StatusBadRequest)
+	render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid or expired token"))
+}
@@ -0,0 +1,118 @@
+package verify_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/auth/verify"
+	"url-shortener/internal/http-server/handlers/auth/verify/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/usermail"
+)
+
+func TestVerifyHandler(t *testing.T) {
+	const email = "user@example.com"
+
+	cases := []struct {
+		name         string
+		body         string
+		respCode     int
+		respError    string
+		consumeCall  bool
+		consumeError error
+		verifyCall   bool
+		verifyError  error
+	}{
+		{
+			name:        "Success",
+			body:        `{"token": "secret"}`,
+			respCode:    http.StatusOK,
+			consumeCall: true,
+			verifyCall:  true,
+		},
+		{
+			name:      "Empty token",
+			body:      `{"token": ""}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field Token is a required field",
+		},
+		{
+			name:         "Unknown token",
+			body:         `{"token": "secret"}`,
+			respCode:     http.StatusBadRequest,
+			respError:    "invalid or expired token",
+			consumeCall:  true,
+			consumeError: storage.ErrTokenNotFound,
+		},
+		{
+			name:         "ConsumeUserToken Error",
+			body:         `{"token": "secret"}`,
+			respCode:     http.StatusInternalServerError,
+			respError:    "internal error",
+			consumeCall:  true,
+			consumeError: errors.New("unexpected error"),
+		},
+		{
+			name:        "Deleted user",
+			body:        `{"token": "secret"}`,
+			respCode:    http.StatusBadRequest,
+			respError:   "invalid or expired token",
+			consumeCall: true,
+			verifyCall:  true,
+			verifyError: storage.ErrUserNotFound,
+		},
+		{
+			name:        "VerifyUser Error",
+			body:        `{"token": "secret"}`,
+			respCode:    http.StatusInternalServerError,
+			respError:   "internal error",
+			consumeCall: true,
+			verifyCall:  true,
+			verifyError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			userVerifierMock := mocks.NewUserVerifier(t)
+
+			if tc.consumeCall {
+				userVerifierMock.On("ConsumeUserToken", mock.Anything, usermail.HashToken("secret"), storage.TokenVerifyEmail).
+					Return(storage.UserToken{Email: email}, tc.consumeError).
+					Once()
+			}
+			if tc.verifyCall {
+				userVerifierMock.On("VerifyUser", mock.Anything, email).
+					Return(tc.verifyError).
+					Once()
+			}
+
+			handler := verify.New(slogdiscard.NewDiscardLogger(), userVerifierMock)
+
+			req, err := http.NewRequest(http.MethodPost, "/auth/verify", bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			require.Equal(t, tc.respError, body.Error.Error())
+		})
+	}
+}
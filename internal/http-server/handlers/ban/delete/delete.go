@@ -0,0 +1,69 @@
+package delete
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/domains"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// HostUnbanner is an interface for lifting bans of destination hosts.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=HostUnbanner
+type HostUnbanner interface {
+	UnbanHost(ctx context.Context, host string) error
+}
+
+// New lifts the ban of the host. Links taken down with the ban stay deleted and
+// can be restored one by one; quarantined links are released on the next recheck.
+func New(log *slog.Logger, hostUnbanner HostUnbanner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.ban.delete.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		host := domains.Normalize(chi.URLParam(r, "host"))
+		if host == "" {
+			log.Info("host is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid request"))
+
+			return
+		}
+
+		err := hostUnbanner.UnbanHost(r.Context(), host)
+		if errors.Is(err, storage.ErrHostNotBanned) {
+			log.Info("host is not banned", slog.String("host", host))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to unban host", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("host unbanned", slog.String("host", host))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,61 @@
+package list
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type Host struct {
+	Host      string    `json:"host"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Response struct {
+	resp.Response
+	Hosts []Host `json:"hosts"`
+}
+
+// BannedHostLister is an interface for listing banned destination hosts.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=BannedHostLister
+type BannedHostLister interface {
+	ListBannedHosts(ctx context.Context) ([]storage.BannedHost, error)
+}
+
+// New lists destination hosts banned by reviewers of abuse reports, sorted by host.
+func New(log *slog.Logger, bannedHostLister BannedHostLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.ban.list.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		banned, err := bannedHostLister.ListBannedHosts(r.Context())
+		if err != nil {
+			log.Error("failed to list banned hosts", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		res := Response{Response: resp.OK(), Hosts: make([]Host, 0, len(banned))}
+		for _, b := range banned {
+			res.Hosts = append(res.Hosts, Host{Host: b.Host, CreatedAt: b.CreatedAt})
+		}
+
+		render.JSON(w, r, res)
+	}
+}
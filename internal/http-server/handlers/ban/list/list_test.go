@@ -0,0 +1,62 @@
+package list_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/ban/list"
+	"url-shortener/internal/http-server/handlers/ban/list/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestListHandler(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	bannedHostListerMock := mocks.NewBannedHostLister(t)
+	bannedHostListerMock.On("ListBannedHosts", mock.Anything).
+		Return([]storage.BannedHost{
+			{Host: "evil.example", CreatedAt: createdAt},
+			{Host: "phish.example", CreatedAt: createdAt},
+		}, nil).
+		Once()
+
+	handler := list.New(slogdiscard.NewDiscardLogger(), bannedHostListerMock)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/bans", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp list.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Equal(t, []list.Host{
+		{Host: "evil.example", CreatedAt: createdAt},
+		{Host: "phish.example", CreatedAt: createdAt},
+	}, resp.Hosts)
+}
+
+func TestListHandler_Error(t *testing.T) {
+	bannedHostListerMock := mocks.NewBannedHostLister(t)
+	bannedHostListerMock.On("ListBannedHosts", mock.Anything).
+		Return(nil, errors.New("unexpected error")).
+		Once()
+
+	handler := list.New(slogdiscard.NewDiscardLogger(), bannedHostListerMock)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/bans", nil))
+
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	var resp list.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Equal(t, "internal error", resp.Error.Error())
+}
@@ -0,0 +1,585 @@
+package docs
+
+import (
+	_ "embed"
+	"html/template"
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	adminStats "url-shortener/internal/http-server/handlers/admin/stats"
+	"url-shortener/internal/http-server/handlers/apikey/create"
+	"url-shortener/internal/http-server/handlers/auth/forgot"
+	"url-shortener/internal/http-server/handlers/auth/login"
+	"url-shortener/internal/http-server/handlers/auth/register"
+	"url-shortener/internal/http-server/handlers/auth/reset"
+	"url-shortener/internal/http-server/handlers/auth/sso"
+	"url-shortener/internal/http-server/handlers/auth/verify"
+	banList "url-shortener/internal/http-server/handlers/ban/list"
+	domainCreate "url-shortener/internal/http-server/handlers/domain/create"
+	domainList "url-shortener/internal/http-server/handlers/domain/list"
+	"url-shortener/internal/http-server/handlers/loglevel"
+	reportCreate "url-shortener/internal/http-server/handlers/report/create"
+	reportList "url-shortener/internal/http-server/handlers/report/list"
+	reportResolve "url-shortener/internal/http-server/handlers/report/resolve"
+	"url-shortener/internal/http-server/handlers/slack"
+	"url-shortener/internal/http-server/handlers/unfurl"
+	"url-shortener/internal/http-server/handlers/url/export"
+	"url-shortener/internal/http-server/handlers/url/info"
+	"url-shortener/internal/http-server/handlers/url/list"
+	"url-shortener/internal/http-server/handlers/url/quick"
+	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/http-server/handlers/url/stats"
+	"url-shortener/internal/http-server/handlers/url/timeseries"
+	"url-shortener/internal/http-server/handlers/url/transfer"
+	"url-shortener/internal/http-server/handlers/url/update"
+	userRole "url-shortener/internal/http-server/handlers/user/role"
+	webhookCreate "url-shortener/internal/http-server/handlers/webhook/create"
+	webhookDeliveries "url-shortener/internal/http-server/handlers/webhook/deliveries"
+	webhookList "url-shortener/internal/http-server/handlers/webhook/list"
+	webhookUpdate "url-shortener/internal/http-server/handlers/webhook/update"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/openapi"
+	"url-shortener/internal/lib/signature"
+	linkTransfer "url-shortener/internal/transfer"
+)
+
+//go:embed ui.html
+var uiHTML string
+
+var uiTemplate = template.Must(template.New("ui").Parse(uiHTML))
+
+// Spec returns the OpenAPI document of the service. Schemas are built from the
+// request and response structs of the handlers, so they can't drift apart.
+func Spec() *openapi.Document {
+	doc := openapi.New("url-shortener", "1.0.0")
+
+	doc.Components.SecuritySchemes = map[string]openapi.SecurityScheme{
+		"apiKey": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+		"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+		"basic":  {Type: "http", Scheme: "basic"},
+		"signature": {
+			Type: "apiKey", In: "header", Name: signature.HeaderSignature,
+			Description: "Hex HMAC-SHA256 with the signing secret of the API key over the method, the path with the query, " +
+				"X-Timestamp, X-Nonce and the SHA-256 of the body joined with newlines; X-Key-ID is the id of the key.",
+		},
+	}
+
+	var (
+		urlAuth   = []openapi.SecurityRequirement{{"apiKey": {}}, {"bearer": {}}, {"signature": {}}}
+		adminAuth = []openapi.SecurityRequirement{{"basic": {}}}
+		alias     = pathParam("alias", "alias of the link")
+		ok        = doc.JSONResponse("OK", resp.Response{})
+	)
+
+	doc.Add(http.MethodGet, "/health", openapi.Operation{
+		Summary:   "Liveness probe",
+		Tags:      []string{"health"},
+		Responses: map[string]openapi.Response{"200": ok},
+	})
+	doc.Add(http.MethodGet, "/ready", openapi.Operation{
+		Summary: "Readiness probe",
+		Tags:    []string{"health"},
+		Responses: map[string]openapi.Response{
+			"200": ok,
+			"503": doc.JSONResponse("storage is unavailable", resp.Response{}),
+		},
+	})
+
+	doc.Add(http.MethodPost, "/admin/api-keys", openapi.Operation{
+		Summary:     "Create API key",
+		Tags:        []string{"admin"},
+		RequestBody: doc.JSONBody(create.Request{}),
+		Responses:   map[string]openapi.Response{"200": doc.JSONResponse("OK", create.Response{})},
+		Security:    adminAuth,
+	})
+	doc.Add(http.MethodDelete, "/admin/api-keys/{id}", openapi.Operation{
+		Summary:    "Revoke API key",
+		Tags:       []string{"admin"},
+		Parameters: []openapi.Parameter{pathParam("id", "id of the API key")},
+		Responses:  map[string]openapi.Response{"200": ok},
+		Security:   adminAuth,
+	})
+	doc.Add(http.MethodPost, "/admin/domains", openapi.Operation{
+		Summary:     "Register short domain",
+		Tags:        []string{"admin"},
+		RequestBody: doc.JSONBody(domainCreate.Request{}),
+		Responses: map[string]openapi.Response{
+			"201": doc.JSONResponse("Created", domainCreate.Response{}),
+			"409": doc.JSONResponse("domain already exists", resp.Response{}),
+		},
+		Security: adminAuth,
+	})
+	doc.Add(http.MethodGet, "/admin/domains", openapi.Operation{
+		Summary:   "List short domains",
+		Tags:      []string{"admin"},
+		Responses: map[string]openapi.Response{"200": doc.JSONResponse("OK", domainList.Response{})},
+		Security:  adminAuth,
+	})
+	doc.Add(http.MethodDelete, "/admin/domains/{host}", openapi.Operation{
+		Summary:    "Unregister short domain",
+		Tags:       []string{"admin"},
+		Parameters: []openapi.Parameter{pathParam("host", "short domain")},
+		Responses:  map[string]openapi.Response{"200": ok},
+		Security:   adminAuth,
+	})
+	doc.Add(http.MethodPost, "/admin/urls/{alias}/restore", openapi.Operation{
+		Summary:    "Restore deleted link",
+		Tags:       []string{"admin"},
+		Parameters: []openapi.Parameter{alias},
+		Responses:  map[string]openapi.Response{"200": ok},
+		Security:   adminAuth,
+	})
+	doc.Add(http.MethodGet, "/admin/reports", openapi.Operation{
+		Summary: "List abuse reports, oldest first",
+		Tags:    []string{"admin"},
+		Parameters: []openapi.Parameter{
+			queryParam("status", `"open" (default), "dismissed", "disabled", "banned" or "all"`, &openapi.Schema{Type: "string"}),
+			queryParam("limit", "at most 500, 100 by default", &openapi.Schema{Type: "integer"}),
+		},
+		Responses: map[string]openapi.Response{"200": doc.JSONResponse("OK", reportList.Response{})},
+		Security:  adminAuth,
+	})
+	doc.Add(http.MethodPost, "/admin/reports/resolve", openapi.Operation{
+		Summary:     "Dismiss open reports of a link, disable it or ban the host of its destination",
+		Tags:        []string{"admin"},
+		RequestBody: doc.JSONBody(reportResolve.Request{}),
+		Responses: map[string]openapi.Response{
+			"200": doc.JSONResponse("OK", reportResolve.Response{}),
+			"404": doc.JSONResponse("no open reports or link not found", resp.Response{}),
+		},
+		Security: adminAuth,
+	})
+	doc.Add(http.MethodGet, "/admin/bans", openapi.Operation{
+		Summary:   "List banned destination hosts",
+		Tags:      []string{"admin"},
+		Responses: map[string]openapi.Response{"200": doc.JSONResponse("OK", banList.Response{})},
+		Security:  adminAuth,
+	})
+	doc.Add(http.MethodDelete, "/admin/bans/{host}", openapi.Operation{
+		Summary:    "Lift ban of destination host",
+		Tags:       []string{"admin"},
+		Parameters: []openapi.Parameter{pathParam("host", "banned host")},
+		Responses:  map[string]openapi.Response{"200": ok},
+		Security:   adminAuth,
+	})
+	doc.Add(http.MethodGet, "/admin/stats", openapi.Operation{
+		Summary:   "Totals, top links and creation rate of the service, cached for admin_stats.cache_ttl",
+		Tags:      []string{"admin"},
+		Responses: map[string]openapi.Response{"200": doc.JSONResponse("OK", adminStats.Response{})},
+		Security:  adminAuth,
+	})
+	clickExportParams := []openapi.Parameter{
+		queryParam("format", `"csv" (default) or "excel": CSV with a UTF-8 BOM and escaped formulas`, &openapi.Schema{
+			Type: "string",
+			Enum: []any{export.FormatCSV, export.FormatExcel},
+		}),
+		queryParam("data", `"events" (default) or "daily" clicks, in UTC`, &openapi.Schema{
+			Type: "string",
+			Enum: []any{export.DataEvents, export.DataDaily},
+		}),
+		queryParam("from", "RFC 3339 time, 30 days before to by default", &openapi.Schema{Type: "string", Format: "date-time"}),
+		queryParam("to", "RFC 3339 time, now by default", &openapi.Schema{Type: "string", Format: "date-time"}),
+	}
+	clicksCSV := map[string]openapi.MediaType{"text/csv": {Schema: &openapi.Schema{Type: "string"}}}
+
+	doc.Add(http.MethodGet, "/admin/stats/export", openapi.Operation{
+		Summary:    "Export clicks of all links for a date range",
+		Tags:       []string{"admin"},
+		Parameters: clickExportParams,
+		Responses: map[string]openapi.Response{
+			"200": {Description: "click events or clicks per link and day", Content: clicksCSV},
+			"400": doc.JSONResponse("invalid format, data or range", resp.Response{}),
+		},
+		Security: adminAuth,
+	})
+	webhookID := pathParam("id", "id of the webhook")
+
+	doc.Add(http.MethodPost, "/admin/webhooks", openapi.Operation{
+		Summary: "Subscribe endpoint to link.created, link.deleted, link.clicked or link.expired events, " +
+			"signed with the returned secret",
+		Tags:        []string{"admin"},
+		RequestBody: doc.JSONBody(webhookCreate.Request{}),
+		Responses: map[string]openapi.Response{
+			"201": doc.JSONResponse("Created", webhookCreate.Response{}),
+			"400": doc.JSONResponse("invalid url or unknown event", resp.Response{}),
+		},
+		Security: adminAuth,
+	})
+	doc.Add(http.MethodGet, "/admin/webhooks", openapi.Operation{
+		Summary:   "List webhooks",
+		Tags:      []string{"admin"},
+		Responses: map[string]openapi.Response{"200": doc.JSONResponse("OK", webhookList.Response{})},
+		Security:  adminAuth,
+	})
+	doc.Add(http.MethodPatch, "/admin/webhooks/{id}", openapi.Operation{
+		Summary:     "Change URL or events of webhook",
+		Tags:        []string{"admin"},
+		Parameters:  []openapi.Parameter{webhookID},
+		RequestBody: doc.JSONBody(webhookUpdate.Request{}),
+		Responses: map[string]openapi.Response{
+			"200": doc.JSONResponse("OK", webhookUpdate.Response{}),
+			"404": doc.JSONResponse("webhook not found", resp.Response{}),
+		},
+		Security: adminAuth,
+	})
+	doc.Add(http.MethodDelete, "/admin/webhooks/{id}", openapi.Operation{
+		Summary:    "Delete webhook with its delivery log",
+		Tags:       []string{"admin"},
+		Parameters: []openapi.Parameter{webhookID},
+		Responses:  map[string]openapi.Response{"200": ok},
+		Security:   adminAuth,
+	})
+	doc.Add(http.MethodGet, "/admin/webhooks/{id}/deliveries", openapi.Operation{
+		Summary: "Latest deliveries of webhook, newest first",
+		Tags:    []string{"admin"},
+		Parameters: []openapi.Parameter{
+			webhookID,
+			queryParam("limit", "at most 100, 50 by default", &openapi.Schema{Type: "integer"}),
+		},
+		Responses: map[string]openapi.Response{
+			"200": doc.JSONResponse("OK", webhookDeliveries.Response{}),
+			"404": doc.JSONResponse("webhook not found", resp.Response{}),
+		},
+		Security: adminAuth,
+	})
+	doc.Add(http.MethodPut, "/admin/loglevel", openapi.Operation{
+		Summary:     "Change log level until restart or config reload",
+		Tags:        []string{"admin"},
+		RequestBody: doc.JSONBody(loglevel.Request{}),
+		Responses:   map[string]openapi.Response{"200": doc.JSONResponse("OK", loglevel.Response{})},
+		Security:    adminAuth,
+	})
+
+	doc.Add(http.MethodPost, "/auth/register", openapi.Operation{
+		Summary:     "Register user",
+		Tags:        []string{"auth"},
+		RequestBody: doc.JSONBody(register.Request{}),
+		Responses:   map[string]openapi.Response{"200": doc.JSONResponse("OK", register.Response{})},
+	})
+	doc.Add(http.MethodPost, "/auth/login", openapi.Operation{
+		Summary:     "Issue JWT",
+		Tags:        []string{"auth"},
+		RequestBody: doc.JSONBody(login.Request{}),
+		Responses: map[string]openapi.Response{
+			"200": doc.JSONResponse("OK", login.Response{}),
+			"403": doc.JSONResponse("email is not verified", resp.Response{}),
+		},
+	})
+	doc.Add(http.MethodPost, "/auth/verify", openapi.Operation{
+		Summary:     "Confirm email by the emailed token",
+		Tags:        []string{"auth"},
+		RequestBody: doc.JSONBody(verify.Request{}),
+		Responses: map[string]openapi.Response{
+			"200": ok,
+			"400": doc.JSONResponse("invalid or expired token", resp.Response{}),
+		},
+	})
+	doc.Add(http.MethodPost, "/auth/password/forgot", openapi.Operation{
+		Summary:     "Email password reset link, answers the same for unknown emails",
+		Tags:        []string{"auth"},
+		RequestBody: doc.JSONBody(forgot.Request{}),
+		Responses:   map[string]openapi.Response{"200": ok},
+	})
+	doc.Add(http.MethodPost, "/auth/password/reset", openapi.Operation{
+		Summary:     "Set new password by the emailed token, confirms the email as well",
+		Tags:        []string{"auth"},
+		RequestBody: doc.JSONBody(reset.Request{}),
+		Responses: map[string]openapi.Response{
+			"200": ok,
+			"400": doc.JSONResponse("invalid or expired token", resp.Response{}),
+		},
+	})
+	ssoProvider := pathParam("provider", "name of the SSO provider from the config")
+	doc.Add(http.MethodGet, "/auth/sso/{provider}/login", openapi.Operation{
+		Summary:    "Redirect to the login page of the SSO provider",
+		Tags:       []string{"auth"},
+		Parameters: []openapi.Parameter{ssoProvider},
+		Responses: map[string]openapi.Response{
+			"302": {Description: "login page of the provider"},
+			"502": doc.JSONResponse("provider is unavailable", resp.Response{}),
+		},
+	})
+	doc.Add(http.MethodGet, "/auth/sso/{provider}/callback", openapi.Operation{
+		Summary: "Issue JWT after the login at the SSO provider",
+		Tags:    []string{"auth"},
+		Parameters: []openapi.Parameter{
+			ssoProvider,
+			queryParam("code", "authorization code", &openapi.Schema{Type: "string"}),
+			queryParam("state", "state of the login", &openapi.Schema{Type: "string"}),
+		},
+		Responses: map[string]openapi.Response{
+			"200": doc.JSONResponse("OK, without app_url", sso.Response{}),
+			"302": {Description: "redirect to <app_url>/login#token=..."},
+			"403": doc.JSONResponse("email is not verified or its domain is not allowed", resp.Response{}),
+		},
+	})
+	doc.Add(http.MethodPut, "/users/role", openapi.Operation{
+		Summary:     "Set role of user",
+		Tags:        []string{"auth"},
+		RequestBody: doc.JSONBody(userRole.Request{}),
+		Responses: map[string]openapi.Response{
+			"200": ok,
+			"403": doc.JSONResponse("client is not an admin", resp.Response{}),
+			"404": doc.JSONResponse("user not found", resp.Response{}),
+		},
+		Security: urlAuth,
+	})
+
+	doc.Add(http.MethodPost, "/url", openapi.Operation{
+		Summary:     "Shorten URL",
+		Tags:        []string{"url"},
+		RequestBody: doc.JSONBody(save.Request{}),
+		Responses: map[string]openapi.Response{
+			"200": doc.JSONResponse("OK", save.Response{}),
+			"429": doc.JSONResponse("rate limit exceeded", resp.Response{}),
+		},
+		Security: urlAuth,
+	})
+	doc.Add(http.MethodPost, "/url/batch", openapi.Operation{
+		Summary:     "Shorten URLs in batch",
+		Tags:        []string{"url"},
+		RequestBody: doc.JSONBody([]save.Request{}),
+		Responses: map[string]openapi.Response{
+			"200": doc.JSONResponse("OK", save.BatchResponse{}),
+			"413": doc.JSONResponse("too many urls", resp.Response{}),
+		},
+		Security: urlAuth,
+	})
+	doc.Add(http.MethodGet, "/url", openapi.Operation{
+		Summary: "List links",
+		Tags:    []string{"url"},
+		Parameters: []openapi.Parameter{
+			queryParam("prefix", "alias prefix", &openapi.Schema{Type: "string"}),
+			queryParam("created_from", "RFC 3339 time", &openapi.Schema{Type: "string", Format: "date-time"}),
+			queryParam("created_to", "RFC 3339 time", &openapi.Schema{Type: "string", Format: "date-time"}),
+			queryParam("tag", "links with the tag", &openapi.Schema{Type: "string"}),
+			queryParam("q", "text in the alias, URL or description", &openapi.Schema{Type: "string"}),
+			queryParam("owner", `links of "me", "user:<id>" or "key:<id>"`, &openapi.Schema{Type: "string"}),
+			queryParam("dead", "links with dead destinations only", &openapi.Schema{Type: "boolean"}),
+			queryParam("archived", "archived links instead of active ones", &openapi.Schema{Type: "boolean"}),
+			queryParam("cursor", "next_cursor of the previous page", &openapi.Schema{Type: "string"}),
+			queryParam("limit", "page size", &openapi.Schema{Type: "integer"}),
+		},
+		Responses: map[string]openapi.Response{"200": doc.JSONResponse("OK", list.Response{})},
+		Security:  urlAuth,
+	})
+	links := map[string]openapi.MediaType{
+		"application/x-ndjson": {Schema: doc.Schema(linkTransfer.Record{})},
+		"text/csv":             {Schema: &openapi.Schema{Type: "string"}},
+	}
+	format := queryParam("format", "ndjson (default) or csv", &openapi.Schema{
+		Type: "string",
+		Enum: []any{linkTransfer.FormatNDJSON, linkTransfer.FormatCSV},
+	})
+
+	doc.Add(http.MethodGet, "/url/export", openapi.Operation{
+		Summary:    "Export links",
+		Tags:       []string{"url"},
+		Parameters: []openapi.Parameter{format},
+		Responses:  map[string]openapi.Response{"200": {Description: "links, one per line", Content: links}},
+		Security:   urlAuth,
+	})
+	doc.Add(http.MethodPost, "/url/import", openapi.Operation{
+		Summary: "Import links",
+		Tags:    []string{"url"},
+		Parameters: []openapi.Parameter{
+			format,
+			queryParam("conflict", "what to do with taken aliases", &openapi.Schema{
+				Type: "string",
+				Enum: []any{linkTransfer.ConflictSkip, linkTransfer.ConflictOverwrite, linkTransfer.ConflictRename},
+			}),
+		},
+		RequestBody: &openapi.RequestBody{Required: true, Content: links},
+		Responses:   map[string]openapi.Response{"200": doc.JSONResponse("OK", transfer.ImportResponse{})},
+		Security:    urlAuth,
+	})
+	doc.Add(http.MethodGet, "/url/{alias}", openapi.Operation{
+		Summary:    "Get link info",
+		Tags:       []string{"url"},
+		Parameters: []openapi.Parameter{alias},
+		Responses:  map[string]openapi.Response{"200": doc.JSONResponse("OK", info.Response{})},
+		Security:   urlAuth,
+	})
+	doc.Add(http.MethodPatch, "/url/{alias}", openapi.Operation{
+		Summary:     "Update link",
+		Tags:        []string{"url"},
+		Parameters:  []openapi.Parameter{alias},
+		RequestBody: doc.JSONBody(update.Request{}),
+		Responses:   map[string]openapi.Response{"200": doc.JSONResponse("OK", update.Response{})},
+		Security:    urlAuth,
+	})
+	doc.Add(http.MethodGet, "/url/{alias}/stats", openapi.Operation{
+		Summary:    "Get click statistics",
+		Tags:       []string{"url"},
+		Parameters: []openapi.Parameter{alias},
+		Responses:  map[string]openapi.Response{"200": doc.JSONResponse("OK", stats.Response{})},
+		Security:   urlAuth,
+	})
+	doc.Add(http.MethodGet, "/url/{alias}/stats/timeseries", openapi.Operation{
+		Summary: "Get clicks in hour or day buckets",
+		Tags:    []string{"url"},
+		Parameters: []openapi.Parameter{
+			alias,
+			queryParam("interval", `"hour" (default) or "day", in UTC`, &openapi.Schema{Type: "string"}),
+			queryParam("from", "RFC 3339 time, 24 hours or 30 days before to by default", &openapi.Schema{Type: "string", Format: "date-time"}),
+			queryParam("to", "RFC 3339 time, now by default", &openapi.Schema{Type: "string", Format: "date-time"}),
+		},
+		Responses: map[string]openapi.Response{
+			"200": doc.JSONResponse("OK, at most 1000 buckets", timeseries.Response{}),
+			"400": doc.JSONResponse("invalid interval or range", resp.Response{}),
+		},
+		Security: urlAuth,
+	})
+	doc.Add(http.MethodGet, "/url/{alias}/stats/export", openapi.Operation{
+		Summary:    "Export clicks of the link",
+		Tags:       []string{"url"},
+		Parameters: append([]openapi.Parameter{alias}, clickExportParams...),
+		Responses: map[string]openapi.Response{
+			"200": {Description: "click events or clicks per day", Content: clicksCSV},
+			"400": doc.JSONResponse("invalid format, data or range", resp.Response{}),
+		},
+		Security: urlAuth,
+	})
+	doc.Add(http.MethodDelete, "/url/{alias}", openapi.Operation{
+		Summary:    "Delete link",
+		Tags:       []string{"url"},
+		Parameters: []openapi.Parameter{alias},
+		Responses:  map[string]openapi.Response{"200": ok},
+		Security:   urlAuth,
+	})
+
+	doc.Add(http.MethodGet, "/{alias}", openapi.Operation{
+		Summary:    "Redirect to the original URL",
+		Tags:       []string{"redirect"},
+		Parameters: []openapi.Parameter{alias},
+		Responses: map[string]openapi.Response{
+			"302": {Description: "redirect, the code depends on the link and config"},
+			"200": {Description: "HTML warning page for quarantined links"},
+			"404": doc.JSONResponse("link not found; JSON for Accept: application/json, an HTML page otherwise", resp.Response{}),
+			"410": doc.JSONResponse("link expired; JSON for Accept: application/json, an HTML page otherwise", resp.Response{}),
+		},
+	})
+	doc.Add(http.MethodHead, "/{alias}", openapi.Operation{
+		Summary:    "Headers of the redirect without a body, not counted as a click by default",
+		Tags:       []string{"redirect"},
+		Parameters: []openapi.Parameter{alias},
+		Responses: map[string]openapi.Response{
+			"302": {Description: "redirect with the Location of the destination"},
+			"404": {Description: "link not found"},
+			"410": {Description: "link expired"},
+		},
+	})
+	doc.Add(http.MethodGet, "/preview/{alias}", openapi.Operation{
+		Summary:    "Preview the destination instead of redirecting, also served at /{alias}+",
+		Tags:       []string{"redirect"},
+		Parameters: []openapi.Parameter{alias},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "HTML page with the destination, its title and click count"},
+			"404": doc.JSONResponse("link not found; JSON for Accept: application/json, an HTML page otherwise", resp.Response{}),
+			"410": {Description: "HTML page of an expired link"},
+		},
+	})
+
+	doc.Add(http.MethodGet, "/unfurl/{alias}", openapi.Operation{
+		Summary: "Stored OpenGraph metadata of the destination for rich previews, without the redirect",
+		Tags:    []string{"redirect"},
+		Parameters: []openapi.Parameter{
+			alias,
+			queryParam("format", `"oembed" for an oEmbed link response`, &openapi.Schema{
+				Type: "string",
+				Enum: []any{unfurl.FormatOEmbed},
+			}),
+		},
+		Responses: map[string]openapi.Response{
+			"200": doc.JSONResponse("OK; metadata fields are omitted until the page is fetched", unfurl.Response{}),
+			"403": doc.JSONResponse("link is quarantined or one-time", resp.Response{}),
+			"404": doc.JSONResponse("link not found", resp.Response{}),
+			"410": doc.JSONResponse("link expired or reached its click limit", resp.Response{}),
+		},
+	})
+
+	doc.Add(http.MethodPost, "/report/{alias}", openapi.Operation{
+		Summary:     "Report abuse of a link, rate-limited per IP",
+		Tags:        []string{"report"},
+		Parameters:  []openapi.Parameter{alias},
+		RequestBody: doc.JSONBody(reportCreate.Request{}),
+		Responses: map[string]openapi.Response{
+			"201": doc.JSONResponse("Created", reportCreate.Response{}),
+			"400": doc.JSONResponse("invalid report or captcha is not solved", resp.Response{}),
+			"404": doc.JSONResponse("link not found", resp.Response{}),
+		},
+	})
+
+	doc.Add(http.MethodGet, "/api/shorten", openapi.Operation{
+		Summary: "Shorten url in one request, for bookmarklets and browser extensions; allowed from any origin",
+		Tags:    []string{"url"},
+		Parameters: []openapi.Parameter{
+			{Name: "url", In: "query", Required: true, Schema: &openapi.Schema{Type: "string", Format: "uri"}},
+			queryParam("token", "the API key, if it is not sent in the X-API-Key header", &openapi.Schema{Type: "string"}),
+			queryParam("format", `"json" for a JSON response instead of plain text`, &openapi.Schema{Type: "string"}),
+		},
+		Responses: map[string]openapi.Response{
+			"201": {
+				Description: "the short url as plain text, or as JSON",
+				Content: map[string]openapi.MediaType{
+					"text/plain":       {Schema: &openapi.Schema{Type: "string"}},
+					"application/json": {Schema: doc.Schema(quick.Response{})},
+				},
+			},
+			"400": doc.JSONResponse("missing or rejected url", resp.Response{}),
+			"401": doc.JSONResponse("missing or invalid API key", resp.Response{}),
+			"403": doc.JSONResponse("role of the key does not allow creating links", resp.Response{}),
+		},
+		Security: []openapi.SecurityRequirement{{"apiKey": {}}},
+	})
+
+	doc.Add(http.MethodPost, "/slack", openapi.Operation{
+		Summary: "Slash command of a Slack app shortening the url in its text with the API key of the workspace",
+		Tags:    []string{"integrations"},
+		Parameters: []openapi.Parameter{
+			{Name: slack.HeaderTimestamp, In: "header", Required: true, Schema: &openapi.Schema{Type: "string"}},
+			{Name: slack.HeaderSignature, In: "header", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": doc.JSONResponse("the reply shown in Slack", slack.Response{}),
+			"401": doc.JSONResponse("invalid or stale signature", resp.Response{}),
+		},
+	})
+
+	return doc
+}
+
+// NewSpec returns handler serving the OpenAPI document as JSON.
+func NewSpec(doc *openapi.Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, doc)
+	}
+}
+
+// NewUI returns handler serving Swagger UI for the document at specURL.
+func NewUI(specURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = uiTemplate.Execute(w, struct{ SpecURL string }{specURL})
+	}
+}
+
+func pathParam(name, description string) openapi.Parameter {
+	return openapi.Parameter{
+		Name:        name,
+		In:          "path",
+		Description: description,
+		Required:    true,
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+}
+
+func queryParam(name, description string, schema *openapi.Schema) openapi.Parameter {
+	return openapi.Parameter{
+		Name:        name,
+		In:          "query",
+		Description: description,
+		Schema:      schema,
+	}
+}
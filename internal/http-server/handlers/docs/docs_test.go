@@ -0,0 +1,70 @@
+package docs_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/docs"
+)
+
+func TestSpec(t *testing.T) {
+	doc := docs.Spec()
+
+	for path, methods := range map[string][]string{
+		"/url":                        {"get", "post"},
+		"/url/batch":                  {"post"},
+		"/url/export":                 {"get"},
+		"/url/import":                 {"post"},
+		"/url/{alias}":                {"get", "patch", "delete"},
+		"/url/{alias}/stats":          {"get"},
+		"/admin/api-keys":             {"post"},
+		"/admin/api-keys/{id}":        {"delete"},
+		"/admin/urls/{alias}/restore": {"post"},
+		"/admin/loglevel":             {"put"},
+		"/admin/webhooks":             {"get", "post"},
+		"/admin/webhooks/{id}":        {"patch", "delete"},
+		"/auth/register":              {"post"},
+		"/auth/login":                 {"post"},
+		"/{alias}":                    {"get", "head"},
+		"/preview/{alias}":            {"get"},
+		"/unfurl/{alias}":             {"get"},
+		"/slack":                      {"post"},
+		"/api/shorten":                {"get"},
+	} {
+		for _, method := range methods {
+			assert.Contains(t, doc.Paths[path], method, "%s %s", method, path)
+		}
+	}
+
+	saveReq := doc.Components.Schemas["save.Request"]
+	require.NotNil(t, saveReq)
+	assert.Equal(t, []string{"url"}, saveReq.Required)
+	assert.Equal(t, "uri", saveReq.Properties["url"].Format)
+	assert.Contains(t, saveReq.Properties, "dedupe")
+}
+
+func TestNewSpec(t *testing.T) {
+	rr := httptest.NewRecorder()
+	docs.NewSpec(docs.Spec()).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "3.0.3", body["openapi"])
+}
+
+func TestNewUI(t *testing.T) {
+	rr := httptest.NewRecorder()
+	docs.NewUI("/openapi.json").ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.True(t, strings.HasPrefix(rr.Header().Get("Content-Type"), "text/html"))
+	assert.Contains(t, rr.Body.String(), `url: "/openapi.json"`)
+}
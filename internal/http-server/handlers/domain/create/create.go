@@ -0,0 +1,106 @@
+package create
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/domains"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	// Host is the short domain, e.g. "go.brand.example". Its DNS must point to the service.
+	Host string `json:"host" validate:"required,fqdn"`
+}
+
+type Response struct {
+	resp.Response
+	Host string `json:"host,omitempty"`
+}
+
+// DomainSaver is an interface for registering short domains.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=DomainSaver
+type DomainSaver interface {
+	SaveDomain(ctx context.Context, domain storage.Domain) error
+}
+
+func New(log *slog.Logger, domainSaver DomainSaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.domain.create.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "empty request"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to decode request"))
+
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(r, validateErr))
+
+			return
+		}
+
+		host := domains.Normalize(req.Host)
+
+		err = domainSaver.SaveDomain(r.Context(), storage.Domain{Host: host, CreatedAt: time.Now()})
+		if errors.Is(err, storage.ErrDomainExists) {
+			log.Info("domain already exists", slog.String("host", host))
+
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, resp.Error(r, resp.CodeConflict, "domain already exists"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to save domain", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("domain registered", slog.String("host", host))
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Host:     host,
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package create_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/domain/create"
+	"url-shortener/internal/http-server/handlers/domain/create/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestCreateHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		respCode  int
+		respError string
+		mockError error
+		mockCall  bool
+	}{
+		{
+			name:     "Success",
+			body:     `{"host": "Go.Brand.Example"}`,
+			respCode: http.StatusCreated,
+			mockCall: true,
+		},
+		{
+			name:      "Empty host",
+			body:      `{}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field Host is a required field",
+		},
+		{
+			name:      "Invalid host",
+			body:      `{"host": "https://go.brand.example/"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field Host is not valid",
+		},
+		{
+			name:      "Domain exists",
+			body:      `{"host": "go.brand.example"}`,
+			respCode:  http.StatusConflict,
+			respError: "domain already exists",
+			mockError: storage.ErrDomainExists,
+			mockCall:  true,
+		},
+		{
+			name:      "SaveDomain Error",
+			body:      `{"host": "go.brand.example"}`,
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+			mockError: errors.New("unexpected error"),
+			mockCall:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			domainSaverMock := mocks.NewDomainSaver(t)
+
+			if tc.mockCall {
+				domainSaverMock.On("SaveDomain", mock.Anything, mock.MatchedBy(func(d storage.Domain) bool {
+					return d.Host == "go.brand.example" && !d.CreatedAt.IsZero()
+				})).
+					Return(tc.mockError).
+					Once()
+			}
+
+			handler := create.New(slogdiscard.NewDiscardLogger(), domainSaverMock)
+
+			req, err := http.NewRequest(http.MethodPost, "/admin/domains", bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp create.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+
+			if tc.respCode == http.StatusCreated {
+				require.Equal(t, "go.brand.example", resp.Host)
+			}
+		})
+	}
+}
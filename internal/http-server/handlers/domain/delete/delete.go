@@ -0,0 +1,69 @@
+package delete
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/domains"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// DomainDeleter is an interface for unregistering short domains.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=DomainDeleter
+type DomainDeleter interface {
+	DeleteDomain(ctx context.Context, host string) error
+}
+
+// New unregisters the domain. Links bound to it are kept, but can't be opened
+// until the domain is registered again. Domains from the config can't be deleted.
+func New(log *slog.Logger, domainDeleter DomainDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.domain.delete.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		host := domains.Normalize(chi.URLParam(r, "host"))
+		if host == "" {
+			log.Info("host is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid request"))
+
+			return
+		}
+
+		err := domainDeleter.DeleteDomain(r.Context(), host)
+		if errors.Is(err, storage.ErrDomainNotFound) {
+			log.Info("domain not found", slog.String("host", host))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to delete domain", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("domain deleted", slog.String("host", host))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,73 @@
+package delete_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/domain/delete"
+	"url-shortener/internal/http-server/handlers/domain/delete/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestDeleteHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		respCode  int
+		respError string
+		mockError error
+	}{
+		{
+			name:     "Success",
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Not found",
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+			mockError: storage.ErrDomainNotFound,
+		},
+		{
+			name:      "DeleteDomain Error",
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			domainDeleterMock := mocks.NewDomainDeleter(t)
+			domainDeleterMock.On("DeleteDomain", mock.Anything, "go.brand.example").
+				Return(tc.mockError).
+				Once()
+
+			r := chi.NewRouter()
+			r.Delete("/admin/domains/{host}", delete.New(slogdiscard.NewDiscardLogger(), domainDeleterMock))
+
+			req, err := http.NewRequest(http.MethodDelete, "/admin/domains/Go.Brand.Example", nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			require.Equal(t, tc.respError, body.Error.Error())
+		})
+	}
+}
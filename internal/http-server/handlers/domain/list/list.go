@@ -0,0 +1,87 @@
+package list
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/domains"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type Domain struct {
+	Host string `json:"host"`
+	// Configured domains are set in the config and can't be deleted via the API.
+	Configured bool       `json:"configured,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	Domains []Domain `json:"domains"`
+}
+
+// DomainLister is an interface for listing short domains registered via the API.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=DomainLister
+type DomainLister interface {
+	ListDomains(ctx context.Context) ([]storage.Domain, error)
+}
+
+// New lists configured domains and the ones registered via the API, sorted by host.
+func New(log *slog.Logger, domainLister DomainLister, configured []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.domain.list.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		registered, err := domainLister.ListDomains(r.Context())
+		if err != nil {
+			log.Error("failed to list domains", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		res := Response{Response: resp.OK(), Domains: make([]Domain, 0, len(configured)+len(registered))}
+		seen := make(map[string]struct{}, len(configured))
+
+		for _, host := range configured {
+			host = domains.Normalize(host)
+			if _, ok := seen[host]; ok || host == "" {
+				continue
+			}
+			seen[host] = struct{}{}
+
+			res.Domains = append(res.Domains, Domain{Host: host, Configured: true})
+		}
+
+		for _, d := range registered {
+			// Домен из конфига мог быть зарегистрирован и через API
+			if _, ok := seen[d.Host]; ok {
+				continue
+			}
+
+			createdAt := d.CreatedAt
+			res.Domains = append(res.Domains, Domain{Host: d.Host, CreatedAt: &createdAt})
+		}
+
+		sort.Slice(res.Domains, func(i, j int) bool {
+			return res.Domains[i].Host < res.Domains[j].Host
+		})
+
+		render.JSON(w, r, res)
+	}
+}
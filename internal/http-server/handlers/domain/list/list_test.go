@@ -0,0 +1,63 @@
+package list_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/domain/list"
+	"url-shortener/internal/http-server/handlers/domain/list/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestListHandler(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	domainListerMock := mocks.NewDomainLister(t)
+	domainListerMock.On("ListDomains", mock.Anything, mock.Anything).
+		Return([]storage.Domain{
+			{Host: "go.brand.example", CreatedAt: createdAt},
+			{Host: "sho.rt", CreatedAt: createdAt},
+		}, nil).
+		Once()
+
+	handler := list.New(slogdiscard.NewDiscardLogger(), domainListerMock, []string{"Sho.rt", "a.example"})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/domains", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp list.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Equal(t, []list.Domain{
+		{Host: "a.example", Configured: true},
+		{Host: "go.brand.example", CreatedAt: &createdAt},
+		{Host: "sho.rt", Configured: true},
+	}, resp.Domains)
+}
+
+func TestListHandler_Error(t *testing.T) {
+	domainListerMock := mocks.NewDomainLister(t)
+	domainListerMock.On("ListDomains", mock.Anything, mock.Anything).
+		Return(nil, errors.New("unexpected error")).
+		Once()
+
+	handler := list.New(slogdiscard.NewDiscardLogger(), domainListerMock, nil)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/domains", nil))
+
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	var resp list.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Equal(t, "internal error", resp.Error.Error())
+}
@@ -0,0 +1,53 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// StoragePinger is an interface for checking storage connectivity.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=StoragePinger
+type StoragePinger interface {
+	Ping(ctx context.Context) error
+}
+
+// NewLive returns liveness handler: the process is up and serves requests.
+func NewLive() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+// NewReady returns readiness handler: the storage answers within timeout.
+func NewReady(log *slog.Logger, pinger StoragePinger, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.health.NewReady"
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		if err := pinger.Ping(ctx); err != nil {
+			log.Error("storage is unavailable",
+				slog.String("op", op),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				sl.Err(err),
+			)
+
+			render.Status(r, http.StatusServiceUnavailable)
+			render.JSON(w, r, resp.Error(r, resp.CodeUnavailable, "storage is unavailable"))
+
+			return
+		}
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,67 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/health"
+	"url-shortener/internal/http-server/handlers/health/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestLiveHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	health.NewLive().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestReadyHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		mockError error
+		respCode  int
+	}{
+		{
+			name:     "Ready",
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Storage unavailable",
+			mockError: errors.New("connection refused"),
+			respCode:  http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			pingerMock := mocks.NewStoragePinger(t)
+
+			pingerMock.On("Ping", mock.MatchedBy(func(ctx context.Context) bool {
+				_, ok := ctx.Deadline()
+
+				return ok
+			})).
+				Return(tc.mockError).
+				Once()
+
+			handler := health.NewReady(slogdiscard.NewDiscardLogger(), pingerMock, time.Second)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+			require.Equal(t, tc.respCode, rr.Code)
+		})
+	}
+}
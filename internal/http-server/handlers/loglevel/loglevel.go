@@ -0,0 +1,92 @@
+package loglevel
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+type Request struct {
+	Level string `json:"level" validate:"required,oneof=debug info warn error"`
+}
+
+type Response struct {
+	resp.Response
+	Level string `json:"level,omitempty"`
+}
+
+// New switches the level of the service logger. The level lasts until the restart
+// or until the config is reloaded by SIGHUP.
+func New(log *slog.Logger, level *slog.LevelVar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.loglevel.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "empty request"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to decode request"))
+
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(r, validateErr))
+
+			return
+		}
+
+		var newLevel slog.Level
+		if err := newLevel.UnmarshalText([]byte(req.Level)); err != nil {
+			log.Error("failed to parse level", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid level"))
+
+			return
+		}
+
+		// Пишем до смены уровня, иначе при переходе на warn запись потеряется
+		log.Info("log level changed",
+			slog.String("from", level.Level().String()),
+			slog.String("to", newLevel.String()),
+		)
+
+		level.Set(newLevel)
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Level:    strings.ToLower(newLevel.String()),
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package loglevel_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/http-server/handlers/loglevel"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestLogLevelHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		respCode  int
+		respError string
+		respLevel string
+		level     slog.Level
+	}{
+		{
+			name:      "Debug",
+			body:      `{"level": "debug"}`,
+			respCode:  http.StatusOK,
+			respLevel: "debug",
+			level:     slog.LevelDebug,
+		},
+		{
+			name:      "Error",
+			body:      `{"level": "error"}`,
+			respCode:  http.StatusOK,
+			respLevel: "error",
+			level:     slog.LevelError,
+		},
+		{
+			name:      "Unknown level",
+			body:      `{"level": "verbose"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field Level is not valid",
+			level:     slog.LevelInfo,
+		},
+		{
+			name:      "Empty level",
+			body:      `{}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field Level is a required field",
+			level:     slog.LevelInfo,
+		},
+		{
+			name:      "Empty request",
+			respCode:  http.StatusBadRequest,
+			respError: "empty request",
+			level:     slog.LevelInfo,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			level := new(slog.LevelVar)
+			level.Set(slog.LevelInfo)
+
+			handler := loglevel.New(slogdiscard.NewDiscardLogger(), level)
+
+			req, err := http.NewRequest(http.MethodPut, "/admin/loglevel", bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+			require.Equal(t, tc.level, level.Level())
+
+			var resp loglevel.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+			require.Equal(t, tc.respLevel, resp.Level)
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package preview
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/domains"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/errpage"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+//go:embed preview.html
+var previewHTML string
+
+var previewTemplate = template.Must(template.New("preview").Parse(previewHTML))
+
+// URLInfoGetter is an interface for getting saved url details by alias.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLInfoGetter
+type URLInfoGetter interface {
+	GetURLInfo(ctx context.Context, alias string) (storage.URL, error)
+}
+
+// PageTitler is an interface for fetching the title of the destination page.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=PageTitler
+type PageTitler interface {
+	Title(ctx context.Context, rawURL string) (string, error)
+}
+
+type page struct {
+	Alias            string
+	URL              string
+	Host             string
+	Title            string
+	Description      string
+	Hits             int64
+	CreatedAt        time.Time
+	Expired          bool
+	Exhausted        bool
+	QuarantineReason string
+}
+
+// New renders an HTML page describing where the link leads instead of redirecting,
+// so recipients can inspect it first. The stored metadata of the destination page is
+// shown if it was fetched, otherwise pageTitler fetches the title; it may be nil, then
+// no title is shown.
+// Previews don't count as clicks. Missing links are answered like by the redirect:
+// JSON to clients which ask for it, the page from pages to everyone else. One-time
+// links are answered as missing, their destination is meant for the recipient only.
+func New(log *slog.Logger, urlInfoGetter URLInfoGetter, pageTitler PageTitler, pages *errpage.Pages) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.preview.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid request"))
+
+			return
+		}
+
+		// Ссылки хранятся в пространстве имен тенанта
+		alias = tenant.Key(tenant.FromContext(r.Context()), alias)
+
+		u, err := urlInfoGetter.GetURLInfo(r.Context(), alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", "alias", alias)
+
+			renderNotFound(log, w, r, pages)
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url info", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		if !domains.Serves(u.Domain, r.Host) {
+			log.Info("url is bound to another domain", slog.String("domain", u.Domain), slog.String("host", r.Host))
+
+			renderNotFound(log, w, r, pages)
+
+			return
+		}
+
+		// Адрес одноразовой ссылки предназначен только получателю, превью его раскрыло бы
+		if u.BurnAfterReading {
+			log.Info("one-time link is not previewed", "alias", alias)
+
+			renderNotFound(log, w, r, pages)
+
+			return
+		}
+
+		p := page{
+			Alias:            tenant.Alias(tenant.FromContext(r.Context()), u.Alias),
+			URL:              u.URL,
+			Hits:             u.Hits,
+			CreatedAt:        u.CreatedAt,
+			Expired:          !u.ExpiresAt.IsZero() && !time.Now().Before(u.ExpiresAt),
+			Exhausted:        !u.ExhaustedAt.IsZero(),
+			QuarantineReason: u.QuarantineReason,
+		}
+		// Метаданные, загруженные при создании ссылки, избавляют от запроса страницы
+		if u.QuarantineReason == "" {
+			p.Title = u.Page.Title
+			p.Description = u.Page.Description
+		}
+		if parsed, err := url.Parse(u.URL); err == nil {
+			p.Host = parsed.Host
+		}
+
+		// Страницу из карантина не запрашиваем
+		if pageTitler != nil && p.Title == "" && u.QuarantineReason == "" && !p.Expired && !p.Exhausted {
+			p.Title, err = pageTitler.Title(r.Context(), u.URL)
+			if err != nil {
+				log.Info("failed to fetch page title", sl.Err(err))
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if p.Expired || p.Exhausted {
+			w.WriteHeader(http.StatusGone)
+		}
+
+		if err := previewTemplate.Execute(w, p); err != nil {
+			log.Error("failed to render preview", sl.Err(err))
+		}
+	}
+}
+
+func renderNotFound(log *slog.Logger, w http.ResponseWriter, r *http.Request, pages *errpage.Pages) {
+	if pages != nil {
+		w.Header().Add("Vary", "Accept")
+
+		if !errpage.WantsJSON(r) {
+			data := errpage.Data{Alias: chi.URLParam(r, "alias"), Host: r.Host}
+			if err := pages.NotFound(w, data); err != nil {
+				log.Error("failed to render not found page", sl.Err(err))
+			}
+
+			return
+		}
+	}
+
+	render.Status(r, http.StatusNotFound)
+	render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "not found"))
+}
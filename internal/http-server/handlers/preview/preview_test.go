@@ -0,0 +1,144 @@
+package preview_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/preview"
+	"url-shortener/internal/http-server/handlers/preview/mocks"
+	"url-shortener/internal/lib/errpage"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestPreviewHandler(t *testing.T) {
+	cases := []struct {
+		name       string
+		url        storage.URL
+		mockError  error
+		title      string
+		titleError error
+		// fetchTitle is false when the page must not be requested.
+		fetchTitle bool
+		accept     string
+		respCode   int
+		contains   []string
+		excludes   []string
+	}{
+		{
+			name:       "Success",
+			url:        storage.URL{Alias: "test_alias", URL: "https://example.com/page", Hits: 42},
+			title:      "Example <Page>",
+			fetchTitle: true,
+			respCode:   http.StatusOK,
+			contains: []string{
+				"Example &lt;Page&gt;", "https://example.com/page", "example.com", "<dd>42</dd>",
+				`href="https://example.com/page"`,
+			},
+		},
+		{
+			name:       "Title unavailable",
+			url:        storage.URL{Alias: "test_alias", URL: "https://example.com/"},
+			titleError: errors.New("timeout"),
+			fetchTitle: true,
+			respCode:   http.StatusOK,
+			contains:   []string{"https://example.com/"},
+			excludes:   []string{"Page title"},
+		},
+		{
+			name: "Stored metadata",
+			url: storage.URL{Alias: "test_alias", URL: "https://example.com/page", Page: storage.PageMetadata{
+				Title: "Stored title", Description: "About the page",
+			}},
+			respCode: http.StatusOK,
+			contains: []string{"Stored title", "About the page"},
+		},
+		{
+			name:     "Quarantined",
+			url:      storage.URL{Alias: "test_alias", URL: "https://phish.example/", QuarantineReason: "malware"},
+			respCode: http.StatusOK,
+			contains: []string{"flagged as malicious (malware)"},
+			excludes: []string{"href="},
+		},
+		{
+			name:     "Expired",
+			url:      storage.URL{Alias: "test_alias", URL: "https://example.com/", ExpiresAt: time.Now().Add(-time.Hour)},
+			respCode: http.StatusGone,
+			contains: []string{"expired"},
+			excludes: []string{"href="},
+		},
+		{
+			name:     "Exhausted",
+			url:      storage.URL{Alias: "test_alias", URL: "https://example.com/", MaxClicks: 1, ExhaustedAt: time.Now()},
+			respCode: http.StatusGone,
+			contains: []string{"click limit"},
+			excludes: []string{"href="},
+		},
+		{
+			name:     "One-time link",
+			url:      storage.URL{Alias: "test_alias", URL: "https://example.com/secret", BurnAfterReading: true},
+			respCode: http.StatusNotFound,
+			contains: []string{"<h1>Link not found</h1>"},
+			excludes: []string{"https://example.com/secret"},
+		},
+		{
+			name:      "Not found",
+			url:       storage.URL{Alias: "missing"},
+			mockError: storage.ErrURLNotFound,
+			respCode:  http.StatusNotFound,
+			contains:  []string{"<h1>Link not found</h1>"},
+		},
+		{
+			name:      "Not found JSON",
+			url:       storage.URL{Alias: "missing"},
+			mockError: storage.ErrURLNotFound,
+			accept:    "application/json",
+			respCode:  http.StatusNotFound,
+			contains:  []string{`"code":"not_found"`},
+		},
+	}
+
+	pages, err := errpage.New("", "")
+	require.NoError(t, err)
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			getterMock := mocks.NewURLInfoGetter(t)
+			getterMock.On("GetURLInfo", mock.Anything, tc.url.Alias).Return(tc.url, tc.mockError).Once()
+
+			titlerMock := mocks.NewPageTitler(t)
+			if tc.fetchTitle {
+				titlerMock.On("Title", mock.Anything, tc.url.URL).Return(tc.title, tc.titleError).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{alias}+", preview.New(slogdiscard.NewDiscardLogger(), getterMock, titlerMock, pages))
+
+			req := httptest.NewRequest(http.MethodGet, "/"+tc.url.Alias+"+", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+			for _, s := range tc.contains {
+				require.Contains(t, rr.Body.String(), s)
+			}
+			for _, s := range tc.excludes {
+				require.NotContains(t, rr.Body.String(), s)
+			}
+		})
+	}
+}
@@ -1,16 +1,29 @@
 package redirect
 
 import (
+	"context"
+	_ "embed"
 	"errors"
+	"fmt"
+	"html/template"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"golang.org/x/exp/slog"
 
+	"url-shortener/internal/domains"
 	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/errpage"
+	"url-shortener/internal/lib/geoip"
 	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/querytpl"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/lib/uadetect"
 	"url-shortener/internal/storage"
 )
 
@@ -18,10 +31,102 @@ import (
 //
 //go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLGetter
 type URLGetter interface {
-	GetURL(alias string) (string, error)
+	GetURL(ctx context.Context, alias string) (storage.URL, error)
 }
 
-func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
+// ClickLimiter is an interface for counting clicks of links with a click limit
+// and burning one-time links.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=ClickLimiter
+type ClickLimiter interface {
+	ConsumeClick(ctx context.Context, alias string) error
+	BurnURL(ctx context.Context, alias string) error
+}
+
+// HitCounter is an interface for counting redirects. Hit must not block.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=HitCounter
+type HitCounter interface {
+	Hit(alias string)
+}
+
+// ClickRecorder is an interface for recording click events. Record must not block.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=ClickRecorder
+type ClickRecorder interface {
+	Record(e storage.ClickEvent)
+}
+
+// WebhookNotifier is an interface for notifying link webhooks of clicks. Notify must not block.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=WebhookNotifier
+type WebhookNotifier interface {
+	Notify(webhookURL string, e storage.ClickEvent)
+}
+
+// GeoLocator is an interface for looking up the location of visitors by IP.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=GeoLocator
+type GeoLocator interface {
+	Lookup(ip net.IP) (geoip.Location, error)
+}
+
+// DestinationPolicy is an interface for checking destinations against the domain lists of the config.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=DestinationPolicy
+type DestinationPolicy interface {
+	Allowed(rawURL string) bool
+}
+
+//go:embed warning.html
+var warningHTML string
+
+var warningTemplate = template.Must(template.New("warning").Parse(warningHTML))
+
+// ValidCode reports whether code can be used as the redirect status.
+func ValidCode(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
+}
+
+// New redirects to the link's URL with its own redirect code or defaultCode if it has none.
+// Permanent redirects are cached by clients for cacheMaxAge, but not longer than the link lives.
+// Quarantined links show a warning page instead of redirecting. Clicks of links with
+// a click limit are counted synchronously, so the limit can't be exceeded. One-time
+// links are deleted by the first redirect; visitors losing the race get 404.
+// Links with a webhook URL notify it of every click. Links with device targets send
+// visitors to the destination of their platform (iOS, Android, desktop). Links with
+// geo targets send the rest to the destination of their country or continent;
+// geoLocator may be nil if no GeoIP database is configured. Links with a split send
+// the remaining visitors to a weighted random variant and record it in the click.
+// Query parameter templates of the link, and incoming query parameters if the link
+// passes them, are added to whichever destination is chosen.
+// Links with a destination on a domain the destinationPolicy doesn't allow are
+// answered with 403; destinationPolicy may be nil to only check domains on save.
+// Missing and expired links are answered with JSON to clients which ask for it by
+// Accept and with HTML pages from pages to everyone else; pages may be nil to always
+// answer with JSON.
+// HEAD requests, e.g. of link checkers and messaging apps unfurling links, get the
+// same headers without a body and are counted as clicks only if countHead is set.
+// One-time links answer HEAD with 204 and no Location and are never burnt by it.
+func New(
+	log *slog.Logger,
+	urlGetter URLGetter,
+	clickLimiter ClickLimiter,
+	hitCounter HitCounter,
+	clickRecorder ClickRecorder,
+	webhookNotifier WebhookNotifier,
+	geoLocator GeoLocator,
+	destinationPolicy DestinationPolicy,
+	pages *errpage.Pages,
+	defaultCode int,
+	cacheMaxAge time.Duration,
+	countHead bool,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.redirect.New"
 
@@ -34,30 +139,307 @@ func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 		if alias == "" {
 			log.Info("alias is empty")
 
-			render.JSON(w, r, resp.Error("invalid request"))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid request"))
 
 			return
 		}
 
-		resURL, err := urlGetter.GetURL(alias)
+		// Ссылки хранятся в пространстве имен тенанта
+		alias = tenant.Key(tenant.FromContext(r.Context()), alias)
+
+		u, err := urlGetter.GetURL(r.Context(), alias)
 		if errors.Is(err, storage.ErrURLNotFound) {
 			log.Info("url not found", "alias", alias)
 
-			render.JSON(w, r, resp.Error("not found"))
+			renderNotFound(log, w, r, pages)
+
+			return
+		}
+		if errors.Is(err, storage.ErrURLExpired) {
+			log.Info("url expired", "alias", alias)
+
+			renderExpired(log, w, r, pages, false)
+
+			return
+		}
+		if errors.Is(err, storage.ErrURLExhausted) {
+			log.Info("url exhausted", "alias", alias)
+
+			renderExpired(log, w, r, pages, true)
 
 			return
 		}
 		if err != nil {
 			log.Error("failed to get url", sl.Err(err))
 
-			render.JSON(w, r, resp.Error("internal error"))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
 
 			return
 		}
 
-		log.Info("got url", slog.String("url", resURL))
+		// Ссылка, привязанная к домену, не открывается через другие домены
+		if !domains.Serves(u.Domain, r.Host) {
+			log.Info("url is bound to another domain", slog.String("domain", u.Domain), slog.String("host", r.Host))
+
+			renderNotFound(log, w, r, pages)
+
+			return
+		}
+
+		log.Info("got url", slog.String("url", u.URL))
+
+		if u.QuarantineReason != "" {
+			log.Info("url is quarantined", slog.String("reason", u.QuarantineReason))
+
+			renderWarning(w, u)
+
+			return
+		}
+
+		// Ссылка могла быть сохранена до того, как домен попал в список
+		if destinationPolicy != nil {
+			for _, destination := range u.Destinations() {
+				if destinationPolicy.Allowed(destination) {
+					continue
+				}
+
+				log.Info("destination domain is not allowed", slog.String("destination", destination))
+
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.Error(r, resp.CodeForbidden, "destination domain is not allowed"))
+
+				return
+			}
+		}
+
+		// Мессенджеры запрашивают HEAD еще до получателя: одноразовая ссылка
+		// не раскрывает адрес и не сгорает, даже если HEAD считается переходом
+		if r.Method == http.MethodHead && u.BurnAfterReading {
+			log.Debug("head request of one-time link")
+
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusNoContent)
+
+			return
+		}
+
+		// HEAD только проверяет ссылку: боты и проверки ссылок не должны
+		// искажать статистику и расходовать лимит переходов
+		count := r.Method != http.MethodHead || countHead
+		if !count {
+			log.Debug("head request is not counted")
+		} else if u.BurnAfterReading {
+			err := clickLimiter.BurnURL(r.Context(), alias)
+			if errors.Is(err, storage.ErrURLNotFound) {
+				// Ссылку уже сжег одновременный переход
+				log.Info("url already burnt", "alias", alias)
+
+				renderNotFound(log, w, r, pages)
+
+				return
+			}
+			if err != nil {
+				log.Error("failed to burn url", sl.Err(err))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+				return
+			}
+		} else if u.MaxClicks > 0 {
+			err := clickLimiter.ConsumeClick(r.Context(), alias)
+			if errors.Is(err, storage.ErrURLExhausted) {
+				log.Info("url exhausted", "alias", alias)
+
+				renderExpired(log, w, r, pages, true)
+
+				return
+			}
+			if err != nil {
+				log.Error("failed to count click", sl.Err(err))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+				return
+			}
+		} else {
+			hitCounter.Hit(alias)
+		}
+
+		// Кампании размечают короткую ссылку utm-параметрами: ?utm_source=newsletter
+		query := r.URL.Query()
+		click := storage.ClickEvent{
+			Alias:       alias,
+			Time:        time.Now(),
+			Referrer:    r.Referer(),
+			UserAgent:   r.UserAgent(),
+			IP:          clientIP(r),
+			UTMSource:   query.Get("utm_source"),
+			UTMMedium:   query.Get("utm_medium"),
+			UTMCampaign: query.Get("utm_campaign"),
+		}
+
+		target := u.URL
+		if deviceTarget, ok := u.DeviceTargets[string(uadetect.Detect(click.UserAgent))]; ok {
+			target = deviceTarget
+		} else if geo, ok := geoTarget(log, geoLocator, u, click.IP); ok {
+			target = geo
+		} else if variant, ok := pickVariant(w, r, u, click.IP); ok {
+			target = variant.URL
+			click.Variant = variant.Name
+		}
+
+		// Ссылка с битым адресом не должна перестать работать из-за шаблона
+		withQuery, err := querytpl.Apply(target, u.QueryParams, query, u.PassQuery,
+			querytpl.Vars{Alias: chi.URLParam(r, "alias"), Variant: click.Variant})
+		if err != nil {
+			log.Error("failed to apply query params", sl.Err(err))
+		} else {
+			target = withQuery
+		}
+
+		// Владелец отключил статистику ссылки: переход учитывается только в hits
+		if count && !u.NoAnalytics {
+			clickRecorder.Record(click)
+		}
+		if count && u.WebhookURL != "" {
+			webhookNotifier.Notify(u.WebhookURL, click)
+		}
+
+		code := u.RedirectCode
+		if code == 0 {
+			code = defaultCode
+		}
+
+		// Закешированный клиентом редирект обошел бы ограничение переходов,
+		// а для A/B-теста не был бы учтен в статистике вариантов
+		if u.MaxClicks > 0 || u.BurnAfterReading || len(u.Split.Variants) > 0 {
+			w.Header().Set("Cache-Control", "no-store")
+		} else if cacheControl := cacheControl(code, u.ExpiresAt, cacheMaxAge); cacheControl != "" {
+			// Адрес перехода зависит от посетителя, общим кешам его хранить нельзя
+			if len(u.GeoTargets) > 0 || len(u.DeviceTargets) > 0 {
+				cacheControl = strings.Replace(cacheControl, "public", "private", 1)
+			}
+
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+
+		if len(u.DeviceTargets) > 0 {
+			w.Header().Add("Vary", "User-Agent")
+		}
 
 		// redirect to found url
-		http.Redirect(w, r, resURL, http.StatusFound)
+		http.Redirect(w, r, target, code)
+	}
+}
+
+// geoTarget returns the destination for the visitor location; false if the location
+// is unknown or has no target.
+func geoTarget(log *slog.Logger, geoLocator GeoLocator, u storage.URL, ip string) (string, bool) {
+	if len(u.GeoTargets) == 0 || geoLocator == nil {
+		return "", false
+	}
+
+	loc, err := geoLocator.Lookup(net.ParseIP(ip))
+	if err != nil {
+		if !errors.Is(err, geoip.ErrNotFound) {
+			log.Error("failed to look up location", sl.Err(err))
+		}
+
+		return "", false
+	}
+
+	target, ok := u.GeoTargets.Match(loc.Country, loc.Continent)
+	if !ok {
+		return "", false
+	}
+
+	log.Info("geo target matched", slog.String("country", loc.Country), slog.String("url", target))
+
+	return target, true
+}
+
+func renderNotFound(log *slog.Logger, w http.ResponseWriter, r *http.Request, pages *errpage.Pages) {
+	if pages != nil {
+		w.Header().Add("Vary", "Accept")
+
+		if !errpage.WantsJSON(r) {
+			if err := pages.NotFound(w, pageData(r, false)); err != nil {
+				log.Error("failed to render not found page", sl.Err(err))
+			}
+
+			return
+		}
+	}
+
+	render.Status(r, http.StatusNotFound)
+	render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "not found"))
+}
+
+// renderExpired answers 410 for expired links and for links which reached their click limit.
+func renderExpired(log *slog.Logger, w http.ResponseWriter, r *http.Request, pages *errpage.Pages, exhausted bool) {
+	if pages != nil {
+		w.Header().Add("Vary", "Accept")
+
+		if !errpage.WantsJSON(r) {
+			if err := pages.Expired(w, pageData(r, exhausted)); err != nil {
+				log.Error("failed to render expired page", sl.Err(err))
+			}
+
+			return
+		}
+	}
+
+	render.Status(r, http.StatusGone)
+	if exhausted {
+		render.JSON(w, r, resp.Error(r, resp.CodeExhausted, "url reached its click limit"))
+	} else {
+		render.JSON(w, r, resp.Error(r, resp.CodeExpired, "url expired"))
 	}
 }
+
+func pageData(r *http.Request, exhausted bool) errpage.Data {
+	return errpage.Data{Alias: chi.URLParam(r, "alias"), Host: r.Host, Exhausted: exhausted}
+}
+
+// renderWarning writes the interstitial page of a quarantined link. The page is
+// not cached, so the link works again as soon as it is released.
+func renderWarning(w http.ResponseWriter, u storage.URL) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+
+	_ = warningTemplate.Execute(w, u)
+}
+
+// cacheControl returns Cache-Control header value for permanent redirects.
+// Without it browsers cache 301 and 308 forever, so later changes of the link are never seen.
+func cacheControl(code int, expiresAt time.Time, maxAge time.Duration) string {
+	if code != http.StatusMovedPermanently && code != http.StatusPermanentRedirect {
+		return ""
+	}
+
+	if !expiresAt.IsZero() {
+		if untilExpiry := time.Until(expiresAt); untilExpiry < maxAge {
+			maxAge = untilExpiry
+		}
+	}
+
+	if maxAge <= 0 {
+		return "no-cache"
+	}
+
+	return fmt.Sprintf("public, max-age=%d", int64(maxAge.Seconds()))
+}
+
+// clientIP returns host part of the request remote address.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -1,17 +1,26 @@
 package redirect_test
 
 import (
+	"errors"
+	"net"
+	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"url-shortener/internal/http-server/handlers/redirect"
 	"url-shortener/internal/http-server/handlers/redirect/mocks"
 	"url-shortener/internal/lib/api"
+	"url-shortener/internal/lib/errpage"
+	"url-shortener/internal/lib/geoip"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
 )
 
 func TestSaveHandler(t *testing.T) {
@@ -32,14 +41,26 @@ func TestSaveHandler(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			urlGetterMock := mocks.NewURLGetter(t)
+			hitCounterMock := mocks.NewHitCounter(t)
 
 			if tc.respError == "" || tc.mockError != nil {
-				urlGetterMock.On("GetURL", tc.alias).
-					Return(tc.url, tc.mockError).Once()
+				urlGetterMock.On("GetURL", mock.Anything, tc.alias).
+					Return(storage.URL{Alias: tc.alias, URL: tc.url}, tc.mockError).Once()
+			}
+			clickRecorderMock := mocks.NewClickRecorder(t)
+
+			if tc.respError == "" && tc.mockError == nil {
+				hitCounterMock.On("Hit", tc.alias).Once()
+				clickRecorderMock.On("Record", mock.MatchedBy(func(e storage.ClickEvent) bool {
+					return e.Alias == tc.alias && e.IP != ""
+				})).Once()
 			}
 
 			r := chi.NewRouter()
-			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock))
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(), urlGetterMock, mocks.NewClickLimiter(t), hitCounterMock, clickRecorderMock,
+				mocks.NewWebhookNotifier(t), mocks.NewGeoLocator(t), nil, nil, http.StatusFound, time.Hour, false,
+			))
 
 			ts := httptest.NewServer(r)
 			defer ts.Close()
@@ -52,3 +73,930 @@ func TestSaveHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestRedirectHandler_Errors(t *testing.T) {
+	const browserAccept = "text/html,application/xhtml+xml,*/*;q=0.8"
+
+	cases := []struct {
+		name        string
+		alias       string
+		accept      string
+		respCode    int
+		contentType string
+		mockError   error
+	}{
+		{
+			name:        "Not found",
+			alias:       "missing_alias",
+			accept:      "application/json",
+			respCode:    http.StatusNotFound,
+			contentType: "application/json",
+			mockError:   storage.ErrURLNotFound,
+		},
+		{
+			name:        "Expired",
+			alias:       "expired_alias",
+			accept:      "application/json",
+			respCode:    http.StatusGone,
+			contentType: "application/json",
+			mockError:   storage.ErrURLExpired,
+		},
+		{
+			name:        "Exhausted",
+			alias:       "exhausted_alias",
+			accept:      "application/json",
+			respCode:    http.StatusGone,
+			contentType: "application/json",
+			mockError:   storage.ErrURLExhausted,
+		},
+		{
+			name:        "Internal error",
+			alias:       "alias",
+			accept:      "application/json",
+			respCode:    http.StatusInternalServerError,
+			contentType: "application/json",
+			mockError:   errors.New("storage is down"),
+		},
+		{
+			name:        "Not found page",
+			alias:       "missing_alias",
+			accept:      browserAccept,
+			respCode:    http.StatusNotFound,
+			contentType: "text/html",
+			mockError:   storage.ErrURLNotFound,
+		},
+		{
+			name:        "Not found page without Accept",
+			alias:       "missing_alias",
+			respCode:    http.StatusNotFound,
+			contentType: "text/html",
+			mockError:   storage.ErrURLNotFound,
+		},
+		{
+			name:        "Expired page",
+			alias:       "expired_alias",
+			accept:      browserAccept,
+			respCode:    http.StatusGone,
+			contentType: "text/html",
+			mockError:   storage.ErrURLExpired,
+		},
+		{
+			name:        "Exhausted page",
+			alias:       "exhausted_alias",
+			accept:      browserAccept,
+			respCode:    http.StatusGone,
+			contentType: "text/html",
+			mockError:   storage.ErrURLExhausted,
+		},
+	}
+
+	pages, err := errpage.New("", "")
+	require.NoError(t, err)
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLGetter(t)
+			hitCounterMock := mocks.NewHitCounter(t)
+			clickRecorderMock := mocks.NewClickRecorder(t)
+
+			urlGetterMock.On("GetURL", mock.Anything, tc.alias).
+				Return(storage.URL{}, tc.mockError).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(), urlGetterMock, mocks.NewClickLimiter(t), hitCounterMock, clickRecorderMock,
+				mocks.NewWebhookNotifier(t), mocks.NewGeoLocator(t), nil, pages, http.StatusFound, time.Hour, false,
+			))
+
+			req := httptest.NewRequest(http.MethodGet, "/"+tc.alias, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.respCode, rr.Code)
+			assert.Contains(t, rr.Header().Get("Content-Type"), tc.contentType)
+			if tc.respCode != http.StatusInternalServerError {
+				assert.Equal(t, "Accept", rr.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestRedirectHandler_Codes(t *testing.T) {
+	cases := []struct {
+		name         string
+		redirectCode int
+		expiresIn    time.Duration
+		respCode     int
+		cacheControl string
+	}{
+		{
+			name:     "Default code",
+			respCode: http.StatusFound,
+		},
+		{
+			name:         "Temporary link code",
+			redirectCode: http.StatusTemporaryRedirect,
+			respCode:     http.StatusTemporaryRedirect,
+		},
+		{
+			name:         "Permanent link code",
+			redirectCode: http.StatusMovedPermanently,
+			respCode:     http.StatusMovedPermanently,
+			cacheControl: "public, max-age=3600",
+		},
+		{
+			name:         "Permanent link expiring soon",
+			redirectCode: http.StatusPermanentRedirect,
+			expiresIn:    10 * time.Minute,
+			respCode:     http.StatusPermanentRedirect,
+			cacheControl: "public, max-age=599",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			u := storage.URL{Alias: "alias", URL: "https://www.google.com/", RedirectCode: tc.redirectCode}
+			if tc.expiresIn != 0 {
+				u.ExpiresAt = time.Now().Add(tc.expiresIn)
+			}
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetURL", mock.Anything, u.Alias).Return(u, nil).Once()
+
+			hitCounterMock := mocks.NewHitCounter(t)
+			hitCounterMock.On("Hit", u.Alias).Once()
+
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			clickRecorderMock.On("Record", mock.Anything).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(), urlGetterMock, mocks.NewClickLimiter(t), hitCounterMock, clickRecorderMock,
+				mocks.NewWebhookNotifier(t), mocks.NewGeoLocator(t), nil, nil, http.StatusFound, time.Hour, false,
+			))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+u.Alias, nil))
+
+			assert.Equal(t, tc.respCode, rr.Code)
+			assert.Equal(t, u.URL, rr.Header().Get("Location"))
+			assert.Equal(t, tc.cacheControl, rr.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestRedirectHandler_Quarantined(t *testing.T) {
+	u := storage.URL{Alias: "alias", URL: "https://phish.example/login?a=1&b=2", QuarantineReason: "social_engineering"}
+
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetURL", mock.Anything, u.Alias).Return(u, nil).Once()
+
+	// Переходы по ссылкам в карантине не считаются
+	hitCounterMock := mocks.NewHitCounter(t)
+	clickRecorderMock := mocks.NewClickRecorder(t)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(
+		slogdiscard.NewDiscardLogger(), urlGetterMock, mocks.NewClickLimiter(t), hitCounterMock, clickRecorderMock,
+		mocks.NewWebhookNotifier(t), mocks.NewGeoLocator(t), nil, nil, http.StatusFound, time.Hour, false,
+	))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+u.Alias, nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("Location"))
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+	assert.Contains(t, rr.Body.String(), "social_engineering")
+	assert.Contains(t, rr.Body.String(), "https://phish.example/login?a=1&amp;b=2")
+}
+
+func TestRedirectHandler_NoAnalytics(t *testing.T) {
+	u := storage.URL{Alias: "alias", URL: "https://www.example.com/", NoAnalytics: true}
+
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetURL", mock.Anything, u.Alias).Return(u, nil).Once()
+
+	hitCounterMock := mocks.NewHitCounter(t)
+	hitCounterMock.On("Hit", u.Alias).Once()
+
+	// Событие перехода не записывается
+	clickRecorderMock := mocks.NewClickRecorder(t)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(
+		slogdiscard.NewDiscardLogger(), urlGetterMock, mocks.NewClickLimiter(t), hitCounterMock, clickRecorderMock,
+		mocks.NewWebhookNotifier(t), mocks.NewGeoLocator(t), nil, nil, http.StatusFound, time.Hour, false,
+	))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+u.Alias, nil))
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, u.URL, rr.Header().Get("Location"))
+}
+
+func TestRedirectHandler_Head(t *testing.T) {
+	cases := []struct {
+		name      string
+		countHead bool
+	}{
+		{
+			name: "Not counted",
+		},
+		{
+			name:      "Counted",
+			countHead: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			u := storage.URL{Alias: "alias", URL: "https://www.example.com/", WebhookURL: "https://hooks.example/clicks"}
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetURL", mock.Anything, u.Alias).Return(u, nil).Once()
+
+			hitCounterMock := mocks.NewHitCounter(t)
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			webhookNotifierMock := mocks.NewWebhookNotifier(t)
+
+			if tc.countHead {
+				hitCounterMock.On("Hit", u.Alias).Once()
+				clickRecorderMock.On("Record", mock.Anything).Once()
+				webhookNotifierMock.On("Notify", u.WebhookURL, mock.Anything).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Head("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(), urlGetterMock, mocks.NewClickLimiter(t), hitCounterMock, clickRecorderMock,
+				webhookNotifierMock, mocks.NewGeoLocator(t), nil, nil, http.StatusFound, time.Hour, tc.countHead,
+			))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodHead, "/"+u.Alias, nil))
+
+			assert.Equal(t, http.StatusFound, rr.Code)
+			assert.Equal(t, u.URL, rr.Header().Get("Location"))
+			assert.Empty(t, rr.Body.String())
+		})
+	}
+}
+
+func TestRedirectHandler_HeadBurnAfterReading(t *testing.T) {
+	cases := []struct {
+		name      string
+		countHead bool
+	}{
+		{
+			name: "Not counted",
+		},
+		{
+			name:      "Counted",
+			countHead: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			u := storage.URL{Alias: "alias", URL: "https://www.example.com/", BurnAfterReading: true}
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetURL", mock.Anything, u.Alias).Return(u, nil).Once()
+
+			// Ни BurnURL, ни учета перехода: моки без ожиданий падают на любом вызове
+			r := chi.NewRouter()
+			r.Head("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(), urlGetterMock, mocks.NewClickLimiter(t), mocks.NewHitCounter(t),
+				mocks.NewClickRecorder(t), mocks.NewWebhookNotifier(t), mocks.NewGeoLocator(t), nil, nil,
+				http.StatusFound, time.Hour, tc.countHead,
+			))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodHead, "/"+u.Alias, nil))
+
+			assert.Equal(t, http.StatusNoContent, rr.Code)
+			assert.Empty(t, rr.Header().Get("Location"))
+			assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+			assert.Empty(t, rr.Body.String())
+		})
+	}
+}
+
+func TestRedirectHandler_DestinationPolicy(t *testing.T) {
+	u := storage.URL{
+		Alias:         "alias",
+		URL:           "https://www.example.com/",
+		DeviceTargets: storage.DeviceTargets{"ios": "https://apps.evil.example/app"},
+	}
+
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetURL", mock.Anything, u.Alias).Return(u, nil).Twice()
+
+	destinationPolicyMock := mocks.NewDestinationPolicy(t)
+	destinationPolicyMock.On("Allowed", u.URL).Return(true).Twice()
+	destinationPolicyMock.On("Allowed", u.DeviceTargets["ios"]).Return(false).Once()
+	destinationPolicyMock.On("Allowed", u.DeviceTargets["ios"]).Return(true).Once()
+
+	// Переход по заблокированной ссылке не считается
+	hitCounterMock := mocks.NewHitCounter(t)
+	hitCounterMock.On("Hit", u.Alias).Once()
+
+	clickRecorderMock := mocks.NewClickRecorder(t)
+	clickRecorderMock.On("Record", mock.Anything).Once()
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(
+		slogdiscard.NewDiscardLogger(), urlGetterMock, mocks.NewClickLimiter(t), hitCounterMock, clickRecorderMock,
+		mocks.NewWebhookNotifier(t), mocks.NewGeoLocator(t), destinationPolicyMock, nil, http.StatusFound, time.Hour, false,
+	))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+u.Alias, nil))
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Empty(t, rr.Header().Get("Location"))
+
+	// Домен убрали из списка
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+u.Alias, nil))
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, u.URL, rr.Header().Get("Location"))
+}
+
+func TestRedirectHandler_MaxClicks(t *testing.T) {
+	cases := []struct {
+		name         string
+		consumeError error
+		respCode     int
+	}{
+		{
+			name:     "Clicks left",
+			respCode: http.StatusMovedPermanently,
+		},
+		{
+			name:         "Last click taken concurrently",
+			consumeError: storage.ErrURLExhausted,
+			respCode:     http.StatusGone,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			u := storage.URL{Alias: "alias", URL: "https://www.google.com/", MaxClicks: 1}
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetURL", mock.Anything, u.Alias).Return(u, nil).Once()
+
+			clickLimiterMock := mocks.NewClickLimiter(t)
+			clickLimiterMock.On("ConsumeClick", mock.Anything, u.Alias).Return(tc.consumeError).Once()
+
+			// Переходы по ссылкам с ограничением считает ConsumeClick
+			hitCounterMock := mocks.NewHitCounter(t)
+
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			if tc.consumeError == nil {
+				clickRecorderMock.On("Record", mock.Anything).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(), urlGetterMock, clickLimiterMock, hitCounterMock, clickRecorderMock,
+				mocks.NewWebhookNotifier(t), mocks.NewGeoLocator(t), nil, nil, http.StatusMovedPermanently, time.Hour, false,
+			))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+u.Alias, nil))
+
+			assert.Equal(t, tc.respCode, rr.Code)
+			if tc.consumeError == nil {
+				assert.Equal(t, u.URL, rr.Header().Get("Location"))
+				assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func TestRedirectHandler_BurnAfterReading(t *testing.T) {
+	cases := []struct {
+		name     string
+		burnErr  error
+		respCode int
+	}{
+		{
+			name:     "First click",
+			respCode: http.StatusFound,
+		},
+		{
+			name:     "Burnt concurrently",
+			burnErr:  storage.ErrURLNotFound,
+			respCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			u := storage.URL{Alias: "alias", URL: "https://www.example.com/", BurnAfterReading: true}
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetURL", mock.Anything, u.Alias).Return(u, nil).Once()
+
+			clickLimiterMock := mocks.NewClickLimiter(t)
+			clickLimiterMock.On("BurnURL", mock.Anything, u.Alias).Return(tc.burnErr).Once()
+
+			// Переход по одноразовой ссылке считает BurnURL
+			hitCounterMock := mocks.NewHitCounter(t)
+
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			if tc.burnErr == nil {
+				clickRecorderMock.On("Record", mock.Anything).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(), urlGetterMock, clickLimiterMock, hitCounterMock, clickRecorderMock,
+				mocks.NewWebhookNotifier(t), mocks.NewGeoLocator(t), nil, nil, http.StatusFound, time.Hour, false,
+			))
+
+			req := httptest.NewRequest(http.MethodGet, "/"+u.Alias, nil)
+			req.Header.Set("Accept", "application/json")
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.respCode, rr.Code)
+			if tc.burnErr == nil {
+				assert.Equal(t, u.URL, rr.Header().Get("Location"))
+				assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func TestRedirectHandler_Webhook(t *testing.T) {
+	u := storage.URL{Alias: "alias", URL: "https://www.google.com/", WebhookURL: "https://hooks.example/clicks"}
+
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetURL", mock.Anything, u.Alias).Return(u, nil).Once()
+
+	hitCounterMock := mocks.NewHitCounter(t)
+	hitCounterMock.On("Hit", u.Alias).Once()
+
+	clickRecorderMock := mocks.NewClickRecorder(t)
+	clickRecorderMock.On("Record", mock.Anything).Once()
+
+	webhookNotifierMock := mocks.NewWebhookNotifier(t)
+	webhookNotifierMock.On("Notify", u.WebhookURL, mock.MatchedBy(func(e storage.ClickEvent) bool {
+		return e.Alias == u.Alias && e.Referrer == "https://ref.example/" && e.UserAgent == "test-agent"
+	})).Once()
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(
+		slogdiscard.NewDiscardLogger(), urlGetterMock, mocks.NewClickLimiter(t), hitCounterMock, clickRecorderMock,
+		webhookNotifierMock, mocks.NewGeoLocator(t), nil, nil, http.StatusFound, time.Hour, false,
+	))
+
+	req := httptest.NewRequest(http.MethodGet, "/"+u.Alias, nil)
+	req.Header.Set("Referer", "https://ref.example/")
+	req.Header.Set("User-Agent", "test-agent")
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+}
+
+func TestRedirectHandler_Tenant(t *testing.T) {
+	u := storage.URL{Alias: "brand/alias", URL: "https://www.google.com/"}
+
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetURL", mock.Anything, u.Alias).Return(u, nil).Once()
+
+	hitCounterMock := mocks.NewHitCounter(t)
+	hitCounterMock.On("Hit", u.Alias).Once()
+
+	clickRecorderMock := mocks.NewClickRecorder(t)
+	clickRecorderMock.On("Record", mock.MatchedBy(func(e storage.ClickEvent) bool {
+		return e.Alias == u.Alias
+	})).Once()
+
+	r := chi.NewRouter()
+	// Тенант определяется по домену
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(tenant.WithTenant(r.Context(), "brand")))
+		})
+	})
+	r.Get("/{alias}", redirect.New(
+		slogdiscard.NewDiscardLogger(), urlGetterMock, mocks.NewClickLimiter(t), hitCounterMock, clickRecorderMock,
+		mocks.NewWebhookNotifier(t), mocks.NewGeoLocator(t), nil, nil, http.StatusFound, time.Hour, false,
+	))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/alias", nil))
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, u.URL, rr.Header().Get("Location"))
+}
+
+func TestRedirectHandler_Domain(t *testing.T) {
+	u := storage.URL{Alias: "alias", URL: "https://www.google.com/", Domain: "go.example"}
+
+	cases := []struct {
+		name     string
+		host     string
+		respCode int
+	}{
+		{
+			name:     "Own domain",
+			host:     "Go.Example:8080",
+			respCode: http.StatusFound,
+		},
+		{
+			name:     "Other domain",
+			host:     "sho.rt",
+			respCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetURL", mock.Anything, u.Alias).Return(u, nil).Once()
+
+			// На чужом домене переход не считается
+			hitCounterMock := mocks.NewHitCounter(t)
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			if tc.respCode == http.StatusFound {
+				hitCounterMock.On("Hit", u.Alias).Once()
+				clickRecorderMock.On("Record", mock.Anything).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(), urlGetterMock, mocks.NewClickLimiter(t), hitCounterMock, clickRecorderMock,
+				mocks.NewWebhookNotifier(t), mocks.NewGeoLocator(t), nil, nil, http.StatusFound, time.Hour, false,
+			))
+
+			req := httptest.NewRequest(http.MethodGet, "/alias", nil)
+			req.Host = tc.host
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.respCode, rr.Code)
+			if tc.respCode != http.StatusFound {
+				assert.Empty(t, rr.Header().Get("Location"))
+				assert.Contains(t, rr.Body.String(), "not found")
+			}
+		})
+	}
+}
+
+func TestRedirectHandler_GeoTargets(t *testing.T) {
+	u := storage.URL{
+		Alias:        "alias",
+		URL:          "https://example.com/",
+		RedirectCode: http.StatusMovedPermanently,
+		GeoTargets:   storage.GeoTargets{"US": "https://example.com/us", "EU": "https://example.com/eu"},
+	}
+
+	cases := []struct {
+		name      string
+		location  geoip.Location
+		lookupErr error
+		target    string
+	}{
+		{
+			name:     "Country",
+			location: geoip.Location{Country: "US", Continent: "NA"},
+			target:   "https://example.com/us",
+		},
+		{
+			name:     "Continent",
+			location: geoip.Location{Country: "DE", Continent: "EU"},
+			target:   "https://example.com/eu",
+		},
+		{
+			name:     "No target",
+			location: geoip.Location{Country: "JP", Continent: "AS"},
+			target:   u.URL,
+		},
+		{
+			name:      "Unknown address",
+			lookupErr: geoip.ErrNotFound,
+			target:    u.URL,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetURL", mock.Anything, u.Alias).Return(u, nil).Once()
+
+			hitCounterMock := mocks.NewHitCounter(t)
+			hitCounterMock.On("Hit", u.Alias).Once()
+
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			clickRecorderMock.On("Record", mock.Anything).Once()
+
+			geoLocatorMock := mocks.NewGeoLocator(t)
+			geoLocatorMock.On("Lookup", net.ParseIP("203.0.113.42")).Return(tc.location, tc.lookupErr).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(), urlGetterMock, mocks.NewClickLimiter(t), hitCounterMock, clickRecorderMock,
+				mocks.NewWebhookNotifier(t), geoLocatorMock, nil, nil, http.StatusFound, time.Hour, false,
+			))
+
+			req := httptest.NewRequest(http.MethodGet, "/alias", nil)
+			req.RemoteAddr = "203.0.113.42:54321"
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusMovedPermanently, rr.Code)
+			assert.Equal(t, tc.target, rr.Header().Get("Location"))
+			// Общие кеши не должны отдавать один адрес посетителям из разных стран
+			assert.Equal(t, "private, max-age=3600", rr.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestRedirectHandler_DeviceTargets(t *testing.T) {
+	u := storage.URL{
+		Alias: "alias",
+		URL:   "https://example.com/",
+		DeviceTargets: storage.DeviceTargets{
+			"ios":     "https://apps.apple.com/app/id1",
+			"android": "https://play.google.com/store/apps/details?id=app",
+		},
+		GeoTargets: storage.GeoTargets{"US": "https://example.com/us"},
+	}
+
+	cases := []struct {
+		name      string
+		userAgent string
+		target    string
+	}{
+		{
+			name:      "iOS",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148",
+			target:    "https://apps.apple.com/app/id1",
+		},
+		{
+			name:      "Android",
+			userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+			target:    "https://play.google.com/store/apps/details?id=app",
+		},
+		{
+			// Для десктопа правила нет: работает geo-таргетинг
+			name:      "Desktop",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			target:    "https://example.com/us",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetURL", mock.Anything, u.Alias).Return(u, nil).Once()
+
+			hitCounterMock := mocks.NewHitCounter(t)
+			hitCounterMock.On("Hit", u.Alias).Once()
+
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			clickRecorderMock.On("Record", mock.Anything).Once()
+
+			geoLocatorMock := mocks.NewGeoLocator(t)
+			geoLocatorMock.On("Lookup", mock.Anything).Return(geoip.Location{Country: "US", Continent: "NA"}, nil).Maybe()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(), urlGetterMock, mocks.NewClickLimiter(t), hitCounterMock, clickRecorderMock,
+				mocks.NewWebhookNotifier(t), geoLocatorMock, nil, nil, http.StatusFound, time.Hour, false,
+			))
+
+			req := httptest.NewRequest(http.MethodGet, "/alias", nil)
+			req.Header.Set("User-Agent", tc.userAgent)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusFound, rr.Code)
+			assert.Equal(t, tc.target, rr.Header().Get("Location"))
+			assert.Equal(t, "User-Agent", rr.Header().Get("Vary"))
+		})
+	}
+}
+
+func TestRedirectHandler_Split(t *testing.T) {
+	variants := map[string]string{
+		"A": "https://example.com/a",
+		"B": "https://example.com/b",
+	}
+
+	cases := []struct {
+		name   string
+		sticky string
+		// cookie is the variant remembered by a previous visit
+		cookie string
+		// variant is empty when any variant may be served
+		variant   string
+		setCookie bool
+	}{
+		{
+			name: "Random",
+		},
+		{
+			name:      "Cookie new visitor",
+			sticky:    storage.StickyCookie,
+			setCookie: true,
+		},
+		{
+			name:    "Cookie returning visitor",
+			sticky:  storage.StickyCookie,
+			cookie:  "B",
+			variant: "B",
+		},
+		{
+			// Варианта из cookie больше нет: посетитель получает новый
+			name:      "Cookie with removed variant",
+			sticky:    storage.StickyCookie,
+			cookie:    "C",
+			setCookie: true,
+		},
+		{
+			name:   "IP",
+			sticky: storage.StickyIP,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			u := storage.URL{
+				Alias: "alias",
+				URL:   "https://example.com/",
+				Split: storage.Split{
+					Variants: []storage.Variant{
+						{Name: "A", URL: variants["A"], Weight: 1},
+						{Name: "B", URL: variants["B"], Weight: 3},
+					},
+					Sticky: tc.sticky,
+				},
+			}
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetURL", mock.Anything, u.Alias).Return(u, nil)
+
+			hitCounterMock := mocks.NewHitCounter(t)
+			hitCounterMock.On("Hit", u.Alias)
+
+			var variant string
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			clickRecorderMock.On("Record", mock.Anything).Run(func(args mock.Arguments) {
+				variant = args.Get(0).(storage.ClickEvent).Variant
+			})
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(), urlGetterMock, mocks.NewClickLimiter(t), hitCounterMock, clickRecorderMock,
+				mocks.NewWebhookNotifier(t), mocks.NewGeoLocator(t), nil, nil, http.StatusMovedPermanently, time.Hour, false,
+			))
+
+			get := func(cookie *http.Cookie) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodGet, "/alias", nil)
+				req.RemoteAddr = "203.0.113.7:1234"
+				if cookie != nil {
+					req.AddCookie(cookie)
+				}
+
+				rr := httptest.NewRecorder()
+				r.ServeHTTP(rr, req)
+
+				return rr
+			}
+
+			var cookie *http.Cookie
+			if tc.cookie != "" {
+				// Имя cookie узнаем из первого перехода
+				cookies := get(nil).Result().Cookies()
+				require.Len(t, cookies, 1)
+
+				cookie = &http.Cookie{Name: cookies[0].Name, Value: tc.cookie}
+			}
+
+			rr := get(cookie)
+
+			require.Equal(t, http.StatusMovedPermanently, rr.Code)
+			assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+			assert.Equal(t, variants[variant], rr.Header().Get("Location"))
+			if tc.variant != "" {
+				assert.Equal(t, tc.variant, variant)
+			}
+
+			cookies := rr.Result().Cookies()
+			if tc.setCookie {
+				require.Len(t, cookies, 1)
+				assert.Equal(t, variant, cookies[0].Value)
+			} else {
+				assert.Empty(t, cookies)
+			}
+
+			if tc.sticky == storage.StickyIP {
+				first := variant
+				get(nil)
+				assert.Equal(t, first, variant)
+			}
+		})
+	}
+}
+
+func TestRedirectHandler_QueryParams(t *testing.T) {
+	cases := []struct {
+		name      string
+		passQuery bool
+		query     string
+		location  string
+	}{
+		{
+			name:     "Template",
+			query:    "?utm_source=newsletter",
+			location: "https://example.com/?id=1&utm_campaign=alias&utm_source=shortener",
+		},
+		{
+			name:      "Passthrough",
+			passQuery: true,
+			query:     "?utm_source=newsletter&ref=42",
+			location:  "https://example.com/?id=1&ref=42&utm_campaign=alias&utm_source=newsletter",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			u := storage.URL{
+				Alias:       "alias",
+				URL:         "https://example.com/?id=1",
+				QueryParams: storage.QueryParams{"utm_source": "shortener", "utm_campaign": "{alias}"},
+				PassQuery:   tc.passQuery,
+			}
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetURL", mock.Anything, u.Alias).Return(u, nil).Once()
+
+			hitCounterMock := mocks.NewHitCounter(t)
+			hitCounterMock.On("Hit", u.Alias).Once()
+
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			clickRecorderMock.On("Record", mock.Anything).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(), urlGetterMock, mocks.NewClickLimiter(t), hitCounterMock, clickRecorderMock,
+				mocks.NewWebhookNotifier(t), mocks.NewGeoLocator(t), nil, nil, http.StatusFound, time.Hour, false,
+			))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/alias"+tc.query, nil))
+
+			assert.Equal(t, http.StatusFound, rr.Code)
+			assert.Equal(t, tc.location, rr.Header().Get("Location"))
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package redirect
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"url-shortener/internal/storage"
+)
+
+// splitCookieMaxAge is how long a visitor stays on the variant of a sticky cookie split.
+const splitCookieMaxAge = 30 * 24 * time.Hour
+
+// pickVariant returns the A/B test variant of the link for the visitor. Variants of
+// cookie splits are remembered in a cookie set on w, variants of ip splits are derived
+// from the hash of the alias and the visitor address.
+func pickVariant(w http.ResponseWriter, r *http.Request, u storage.URL, ip string) (storage.Variant, bool) {
+	switch u.Split.Sticky {
+	case storage.StickyIP:
+		return u.Split.Pick(visitorHash(u.Alias, ip))
+	case storage.StickyCookie:
+		name := splitCookieName(u.Alias)
+
+		// Вариант мог быть удален из ссылки, тогда посетитель получит новый
+		if c, err := r.Cookie(name); err == nil {
+			if v, ok := u.Split.Variant(c.Value); ok {
+				return v, true
+			}
+		}
+
+		v, ok := u.Split.Pick(rand.Uint64())
+		if ok {
+			http.SetCookie(w, &http.Cookie{
+				Name:     name,
+				Value:    v.Name,
+				Path:     "/",
+				MaxAge:   int(splitCookieMaxAge.Seconds()),
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+
+		return v, ok
+	default:
+		return u.Split.Pick(rand.Uint64())
+	}
+}
+
+// splitCookieName returns the name of the cookie with the variant of the link.
+// Aliases may contain characters not allowed in cookie names, so the hash is used.
+func splitCookieName(alias string) string {
+	return "us_variant_" + strconv.FormatUint(visitorHash(alias, ""), 36)
+}
+
+func visitorHash(alias, ip string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(alias))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(ip))
+
+	return h.Sum64()
+}
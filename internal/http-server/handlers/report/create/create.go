@@ -0,0 +1,173 @@
+package create
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/clientip"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	// Category is "spam", "phishing", "malware", "illegal" or "other".
+	Category string `json:"category" validate:"required,oneof=spam phishing malware illegal other"`
+	Details  string `json:"details,omitempty" validate:"max=2000"`
+	// Contact is the email of the reporter for questions of the reviewers.
+	Contact string `json:"contact,omitempty" validate:"omitempty,email,max=254"`
+	// CaptchaToken is the solution of the CAPTCHA widget, required when CAPTCHA is enabled.
+	CaptchaToken string `json:"captcha_token,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	ID int64 `json:"id,omitempty"`
+}
+
+// ReportSaver is an interface for recording abuse reports of saved links.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=ReportSaver
+type ReportSaver interface {
+	GetURLInfo(ctx context.Context, alias string) (storage.URL, error)
+	SaveReport(ctx context.Context, report storage.Report) (int64, error)
+}
+
+// CaptchaVerifier is an interface for checking CAPTCHA solutions, see package captcha.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=CaptchaVerifier
+type CaptchaVerifier interface {
+	Verify(ctx context.Context, token, remoteIP string) (bool, error)
+}
+
+// New returns the public handler recording an abuse report of the link for the
+// admin queue. A nil captchaVerifier accepts reports without CAPTCHA.
+func New(log *slog.Logger, reportSaver ReportSaver, captchaVerifier CaptchaVerifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.report.create.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid request"))
+
+			return
+		}
+
+		// Ссылки хранятся в пространстве имен тенанта
+		alias = tenant.Key(tenant.FromContext(r.Context()), alias)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "empty request"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to decode request"))
+
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(r, validateErr))
+
+			return
+		}
+
+		if captchaVerifier != nil {
+			var remoteIP string
+			if ip := clientip.PeerIP(r); ip.IsValid() {
+				remoteIP = ip.String()
+			}
+
+			solved, err := captchaVerifier.Verify(r.Context(), req.CaptchaToken, remoteIP)
+			if err != nil {
+				log.Error("failed to verify captcha", sl.Err(err))
+
+				render.Status(r, http.StatusServiceUnavailable)
+				render.JSON(w, r, resp.Error(r, resp.CodeUnavailable, "captcha is unavailable"))
+
+				return
+			}
+			if !solved {
+				log.Info("captcha is not solved")
+
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "captcha is not solved"))
+
+				return
+			}
+		}
+
+		_, err = reportSaver.GetURLInfo(r.Context(), alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url info", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		id, err := reportSaver.SaveReport(r.Context(), storage.Report{
+			Alias:    alias,
+			Category: req.Category,
+			Details:  req.Details,
+			Contact:  req.Contact,
+		})
+		if err != nil {
+			log.Error("failed to save report", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("url reported", slog.Int64("id", id), slog.String("alias", alias), slog.String("category", req.Category))
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			ID:       id,
+		})
+	}
+}
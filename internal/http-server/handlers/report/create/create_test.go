@@ -0,0 +1,151 @@
+package create_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/report/create"
+	"url-shortener/internal/http-server/handlers/report/create/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestCreateHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		captcha   bool
+		solved    bool
+		verifyErr error
+		getCall   bool
+		getErr    error
+		saveCall  bool
+		saveErr   error
+		respCode  int
+		respError string
+	}{
+		{
+			name:     "Success",
+			body:     `{"category": "phishing", "details": "fake bank login", "contact": "me@example.com"}`,
+			getCall:  true,
+			saveCall: true,
+			respCode: http.StatusCreated,
+		},
+		{
+			name:     "Solved captcha",
+			body:     `{"category": "phishing", "details": "fake bank login", "contact": "me@example.com", "captcha_token": "token"}`,
+			captcha:  true,
+			solved:   true,
+			getCall:  true,
+			saveCall: true,
+			respCode: http.StatusCreated,
+		},
+		{
+			name:      "Unsolved captcha",
+			body:      `{"category": "phishing", "captcha_token": "token"}`,
+			captcha:   true,
+			respCode:  http.StatusBadRequest,
+			respError: "captcha is not solved",
+		},
+		{
+			name:      "Captcha unavailable",
+			body:      `{"category": "phishing", "captcha_token": "token"}`,
+			captcha:   true,
+			verifyErr: errors.New("timeout"),
+			respCode:  http.StatusServiceUnavailable,
+			respError: "captcha is unavailable",
+		},
+		{
+			name:      "Unknown category",
+			body:      `{"category": "boring"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field Category is not valid",
+		},
+		{
+			name:      "Invalid contact",
+			body:      `{"category": "spam", "contact": "me"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field Contact is not valid",
+		},
+		{
+			name:      "Empty body",
+			respCode:  http.StatusBadRequest,
+			respError: "empty request",
+		},
+		{
+			name:      "Unknown link",
+			body:      `{"category": "spam"}`,
+			getCall:   true,
+			getErr:    storage.ErrURLNotFound,
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+		},
+		{
+			name:      "SaveReport Error",
+			body:      `{"category": "spam"}`,
+			getCall:   true,
+			saveCall:  true,
+			saveErr:   errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			reportSaverMock := mocks.NewReportSaver(t)
+
+			var captchaVerifier create.CaptchaVerifier
+			if tc.captcha {
+				captchaMock := mocks.NewCaptchaVerifier(t)
+				captchaMock.On("Verify", mock.Anything, "token", "192.0.2.1").
+					Return(tc.solved, tc.verifyErr).
+					Once()
+
+				captchaVerifier = captchaMock
+			}
+
+			if tc.getCall {
+				reportSaverMock.On("GetURLInfo", mock.Anything, "alias").
+					Return(storage.URL{Alias: "alias", URL: "https://example.com"}, tc.getErr).
+					Once()
+			}
+			if tc.saveCall {
+				reportSaverMock.On("SaveReport", mock.Anything, mock.MatchedBy(func(r storage.Report) bool {
+					return r.Alias == "alias" && r.Category != ""
+				})).
+					Return(int64(1), tc.saveErr).
+					Once()
+			}
+
+			r := chi.NewRouter()
+			r.Post("/report/{alias}", create.New(slogdiscard.NewDiscardLogger(), reportSaverMock, captchaVerifier))
+
+			req := httptest.NewRequest(http.MethodPost, "/report/alias", bytes.NewReader([]byte(tc.body)))
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp create.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+
+			if tc.respCode == http.StatusCreated {
+				require.Equal(t, int64(1), resp.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package list
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 500
+)
+
+type Report struct {
+	ID int64 `json:"id"`
+	// Alias is the key of the link with the tenant prefix, as expected by the resolve endpoint.
+	Alias string `json:"alias"`
+	// URL is the destination of the link, empty if the link is deleted.
+	URL        string     `json:"url,omitempty"`
+	Category   string     `json:"category"`
+	Details    string     `json:"details,omitempty"`
+	Contact    string     `json:"contact,omitempty"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	Reports []Report `json:"reports"`
+}
+
+// ReportLister is an interface for listing abuse reports with the links they are about.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=ReportLister
+type ReportLister interface {
+	ListReports(ctx context.Context, status string, limit int) ([]storage.Report, error)
+	GetURLInfo(ctx context.Context, alias string) (storage.URL, error)
+}
+
+var (
+	errInvalidStatus = errors.New(`status must be "open", "dismissed", "disabled", "banned" or "all"`)
+	errInvalidLimit  = errors.New("limit must be between 1 and " + strconv.Itoa(maxLimit))
+)
+
+// New returns the review queue: reports with the status given by the "status"
+// query parameter, open ones by default, oldest first.
+func New(log *slog.Logger, reportLister ReportLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.report.list.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		status, limit, err := parseQuery(r)
+		if err != nil {
+			log.Info("invalid query", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
+
+			return
+		}
+
+		reports, err := reportLister.ListReports(r.Context(), status, limit)
+		if err != nil {
+			log.Error("failed to list reports", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		// На одну ссылку обычно приходит много жалоб
+		destinations := make(map[string]string)
+
+		res := Response{Response: resp.OK(), Reports: make([]Report, 0, len(reports))}
+		for _, report := range reports {
+			destination, ok := destinations[report.Alias]
+			if !ok {
+				u, err := reportLister.GetURLInfo(r.Context(), report.Alias)
+				if err != nil && !errors.Is(err, storage.ErrURLNotFound) {
+					log.Error("failed to get url info", sl.Err(err))
+
+					render.Status(r, http.StatusInternalServerError)
+					render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+					return
+				}
+
+				destination = u.URL
+				destinations[report.Alias] = destination
+			}
+
+			item := Report{
+				ID:        report.ID,
+				Alias:     report.Alias,
+				URL:       destination,
+				Category:  report.Category,
+				Details:   report.Details,
+				Contact:   report.Contact,
+				Status:    report.Status,
+				CreatedAt: report.CreatedAt,
+			}
+			if !report.ResolvedAt.IsZero() {
+				resolvedAt := report.ResolvedAt
+				item.ResolvedAt = &resolvedAt
+			}
+
+			res.Reports = append(res.Reports, item)
+		}
+
+		render.JSON(w, r, res)
+	}
+}
+
+func parseQuery(r *http.Request) (string, int, error) {
+	q := r.URL.Query()
+
+	status := q.Get("status")
+	switch status {
+	case "":
+		status = storage.ReportOpen
+	case "all":
+		status = ""
+	case storage.ReportOpen, storage.ReportDismissed, storage.ReportDisabled, storage.ReportBanned:
+	default:
+		return "", 0, errInvalidStatus
+	}
+
+	limit := defaultLimit
+	if v := q.Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxLimit {
+			return "", 0, errInvalidLimit
+		}
+	}
+
+	return status, limit, nil
+}
@@ -0,0 +1,114 @@
+package list_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/report/list"
+	"url-shortener/internal/http-server/handlers/report/list/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestListHandler(t *testing.T) {
+	reports := []storage.Report{
+		{ID: 1, Alias: "alias", Category: "spam", Status: storage.ReportOpen, CreatedAt: time.Now()},
+		{ID: 2, Alias: "alias", Category: "phishing", Status: storage.ReportOpen, CreatedAt: time.Now()},
+		{ID: 3, Alias: "gone", Category: "spam", Status: storage.ReportOpen, CreatedAt: time.Now()},
+	}
+
+	cases := []struct {
+		name       string
+		query      string
+		listCall   bool
+		wantStatus string
+		wantLimit  int
+		listErr    error
+		respCode   int
+		respError  string
+	}{
+		{
+			name:       "Open by default",
+			listCall:   true,
+			wantStatus: storage.ReportOpen,
+			wantLimit:  100,
+			respCode:   http.StatusOK,
+		},
+		{
+			name:      "All",
+			query:     "?status=all&limit=10",
+			listCall:  true,
+			wantLimit: 10,
+			respCode:  http.StatusOK,
+		},
+		{
+			name:      "Unknown status",
+			query:     "?status=closed",
+			respCode:  http.StatusBadRequest,
+			respError: `status must be "open", "dismissed", "disabled", "banned" or "all"`,
+		},
+		{
+			name:      "Invalid limit",
+			query:     "?limit=1000",
+			respCode:  http.StatusBadRequest,
+			respError: "limit must be between 1 and 500",
+		},
+		{
+			name:       "ListReports Error",
+			listCall:   true,
+			wantStatus: storage.ReportOpen,
+			wantLimit:  100,
+			listErr:    errors.New("unexpected error"),
+			respCode:   http.StatusInternalServerError,
+			respError:  "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			reportListerMock := mocks.NewReportLister(t)
+
+			if tc.listCall {
+				reportListerMock.On("ListReports", mock.Anything, tc.wantStatus, tc.wantLimit).
+					Return(reports, tc.listErr).
+					Once()
+			}
+			if tc.respCode == http.StatusOK {
+				// Адрес каждой ссылки запрашивается один раз
+				reportListerMock.On("GetURLInfo", mock.Anything, "alias").
+					Return(storage.URL{Alias: "alias", URL: "https://example.com"}, nil).
+					Once()
+				reportListerMock.On("GetURLInfo", mock.Anything, "gone").
+					Return(storage.URL{}, storage.ErrURLNotFound).
+					Once()
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/reports"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			list.New(slogdiscard.NewDiscardLogger(), reportListerMock).ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp list.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+
+			if tc.respCode == http.StatusOK {
+				require.Len(t, resp.Reports, 3)
+				require.Equal(t, "https://example.com", resp.Reports[1].URL)
+				require.Empty(t, resp.Reports[2].URL)
+			}
+		})
+	}
+}
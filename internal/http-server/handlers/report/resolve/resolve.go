@@ -0,0 +1,187 @@
+package resolve
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/domains"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// Actions of reviewers.
+const (
+	ActionDismiss = "dismiss"
+	ActionDisable = "disable"
+	ActionBan     = "ban"
+)
+
+type Request struct {
+	// Alias is the key of the link as listed in the reports.
+	Alias string `json:"alias" validate:"required"`
+	// Action is "dismiss" to keep the link, "disable" to take it down or "ban"
+	// to take it down and ban the host of its destination.
+	Action string `json:"action" validate:"required,oneof=dismiss disable ban"`
+}
+
+type Response struct {
+	resp.Response
+	// Resolved is the number of open reports of the link closed by the action.
+	Resolved   int64  `json:"resolved"`
+	BannedHost string `json:"banned_host,omitempty"`
+}
+
+// ReportResolver is an interface for taking reported links down and closing their reports.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=ReportResolver
+type ReportResolver interface {
+	GetURLInfo(ctx context.Context, alias string) (storage.URL, error)
+	DeleteURL(ctx context.Context, alias string) error
+	BanHost(ctx context.Context, host string) error
+	ResolveReports(ctx context.Context, alias, status string) (int64, error)
+}
+
+// New returns the handler applying the decision of a reviewer to all open reports
+// of the link. Disabled links are soft-deleted, so they can be restored. Banned
+// hosts are rejected on save; other links to them are quarantined on the next
+// screening recheck.
+func New(log *slog.Logger, reportResolver ReportResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.report.resolve.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "empty request"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to decode request"))
+
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, resp.ValidationError(r, validateErr).Error.Message))
+
+			return
+		}
+
+		res := Response{Response: resp.OK()}
+		status := storage.ReportDismissed
+
+		if req.Action == ActionBan {
+			u, err := reportResolver.GetURLInfo(r.Context(), req.Alias)
+			if errors.Is(err, storage.ErrURLNotFound) {
+				log.Info("url not found", slog.String("alias", req.Alias))
+
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "not found"))
+
+				return
+			}
+			if err != nil {
+				log.Error("failed to get url info", sl.Err(err))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+				return
+			}
+
+			destination, err := url.Parse(u.URL)
+			if err != nil || destination.Hostname() == "" {
+				log.Info("destination has no host", slog.String("url", u.URL))
+
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "destination has no host to ban"))
+
+				return
+			}
+
+			res.BannedHost = domains.Normalize(destination.Hostname())
+			if err := reportResolver.BanHost(r.Context(), res.BannedHost); err != nil {
+				log.Error("failed to ban host", sl.Err(err))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+				return
+			}
+
+			status = storage.ReportBanned
+		}
+
+		if req.Action == ActionDisable || req.Action == ActionBan {
+			// Ссылку могли удалить раньше, жалобы все равно закрываем
+			err := reportResolver.DeleteURL(r.Context(), req.Alias)
+			if err != nil && !errors.Is(err, storage.ErrURLNotFound) {
+				log.Error("failed to delete url", sl.Err(err))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+				return
+			}
+
+			if status != storage.ReportBanned {
+				status = storage.ReportDisabled
+			}
+		}
+
+		res.Resolved, err = reportResolver.ResolveReports(r.Context(), req.Alias, status)
+		if err != nil {
+			log.Error("failed to resolve reports", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		if req.Action == ActionDismiss && res.Resolved == 0 {
+			log.Info("no open reports", slog.String("alias", req.Alias))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "no open reports"))
+
+			return
+		}
+
+		log.Info("reports resolved",
+			slog.String("alias", req.Alias),
+			slog.String("status", status),
+			slog.Int64("resolved", res.Resolved),
+			slog.String("banned_host", res.BannedHost),
+		)
+
+		render.JSON(w, r, res)
+	}
+}
@@ -0,0 +1,188 @@
+package resolve_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/report/resolve"
+	"url-shortener/internal/http-server/handlers/report/resolve/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestResolveHandler(t *testing.T) {
+	cases := []struct {
+		name        string
+		action      string
+		getCall     bool
+		getURL      string
+		getErr      error
+		banHost     string
+		banErr      error
+		deleteCall  bool
+		deleteErr   error
+		resolveCall bool
+		wantStatus  string
+		resolved    int64
+		resolveErr  error
+		respCode    int
+		respError   string
+	}{
+		{
+			name:        "Dismiss",
+			action:      resolve.ActionDismiss,
+			resolveCall: true,
+			wantStatus:  storage.ReportDismissed,
+			resolved:    2,
+			respCode:    http.StatusOK,
+		},
+		{
+			name:        "Dismiss without reports",
+			action:      resolve.ActionDismiss,
+			resolveCall: true,
+			wantStatus:  storage.ReportDismissed,
+			respCode:    http.StatusNotFound,
+			respError:   "no open reports",
+		},
+		{
+			name:        "Disable",
+			action:      resolve.ActionDisable,
+			deleteCall:  true,
+			resolveCall: true,
+			wantStatus:  storage.ReportDisabled,
+			resolved:    1,
+			respCode:    http.StatusOK,
+		},
+		{
+			name:        "Disable deleted link",
+			action:      resolve.ActionDisable,
+			deleteCall:  true,
+			deleteErr:   storage.ErrURLNotFound,
+			resolveCall: true,
+			wantStatus:  storage.ReportDisabled,
+			resolved:    1,
+			respCode:    http.StatusOK,
+		},
+		{
+			name:        "Ban",
+			action:      resolve.ActionBan,
+			getCall:     true,
+			getURL:      "https://Evil.Example:8443/login",
+			banHost:     "evil.example",
+			deleteCall:  true,
+			resolveCall: true,
+			wantStatus:  storage.ReportBanned,
+			resolved:    3,
+			respCode:    http.StatusOK,
+		},
+		{
+			name:      "Ban unknown link",
+			action:    resolve.ActionBan,
+			getCall:   true,
+			getErr:    storage.ErrURLNotFound,
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+		},
+		{
+			name:      "Ban without host",
+			action:    resolve.ActionBan,
+			getCall:   true,
+			getURL:    "mailto:user@example.com",
+			respCode:  http.StatusBadRequest,
+			respError: "destination has no host to ban",
+		},
+		{
+			name:      "Unknown action",
+			action:    "delete",
+			respCode:  http.StatusBadRequest,
+			respError: "field Action is not valid",
+		},
+		{
+			name:      "BanHost Error",
+			action:    resolve.ActionBan,
+			getCall:   true,
+			getURL:    "https://evil.example",
+			banHost:   "evil.example",
+			banErr:    errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+		},
+		{
+			name:       "DeleteURL Error",
+			action:     resolve.ActionDisable,
+			deleteCall: true,
+			deleteErr:  errors.New("unexpected error"),
+			respCode:   http.StatusInternalServerError,
+			respError:  "internal error",
+		},
+		{
+			name:        "ResolveReports Error",
+			action:      resolve.ActionDismiss,
+			resolveCall: true,
+			wantStatus:  storage.ReportDismissed,
+			resolveErr:  errors.New("unexpected error"),
+			respCode:    http.StatusInternalServerError,
+			respError:   "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			reportResolverMock := mocks.NewReportResolver(t)
+
+			if tc.getCall {
+				reportResolverMock.On("GetURLInfo", mock.Anything, "alias").
+					Return(storage.URL{Alias: "alias", URL: tc.getURL}, tc.getErr).
+					Once()
+			}
+			if tc.banHost != "" {
+				reportResolverMock.On("BanHost", mock.Anything, tc.banHost).
+					Return(tc.banErr).
+					Once()
+			}
+			if tc.deleteCall {
+				reportResolverMock.On("DeleteURL", mock.Anything, "alias").
+					Return(tc.deleteErr).
+					Once()
+			}
+			if tc.resolveCall {
+				reportResolverMock.On("ResolveReports", mock.Anything, "alias", tc.wantStatus).
+					Return(tc.resolved, tc.resolveErr).
+					Once()
+			}
+
+			handler := resolve.New(slogdiscard.NewDiscardLogger(), reportResolverMock)
+
+			input, err := json.Marshal(resolve.Request{Alias: "alias", Action: tc.action})
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/admin/reports/resolve", bytes.NewReader(input))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp resolve.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+
+			if tc.respCode == http.StatusOK {
+				require.Equal(t, tc.resolved, resp.Resolved)
+				require.Equal(t, tc.banHost, resp.BannedHost)
+			}
+		})
+	}
+}
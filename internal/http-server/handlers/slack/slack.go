@@ -0,0 +1,232 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/signature"
+	"url-shortener/internal/shortener"
+	"url-shortener/internal/storage"
+)
+
+// Headers of requests signed by Slack.
+const (
+	HeaderTimestamp = "X-Slack-Request-Timestamp"
+	HeaderSignature = "X-Slack-Signature"
+)
+
+// signatureVersion prefixes signatures and strings to sign of Slack requests.
+const signatureVersion = "v0"
+
+const (
+	// maxSkew is how old a request may be, so captured requests can't be replayed later.
+	maxSkew = 5 * time.Minute
+	// maxBodySize limits request bodies, slash command payloads are small.
+	maxBodySize = 64 << 10
+)
+
+// responseEphemeral shows the reply to the user who typed the command only.
+const responseEphemeral = "ephemeral"
+
+const helpText = "Usage: /shorten https://example.com/long/url"
+
+// Response is the reply to a slash command shown in Slack.
+type Response struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// Shortener is an interface for shortening urls on behalf of an API key, see package shortener.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=Shortener
+type Shortener interface {
+	Shorten(ctx context.Context, account shortener.Account, rawURL string) (string, error)
+}
+
+// APIKeyGetter is an interface for looking up API keys the workspaces are mapped to.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=APIKeyGetter
+type APIKeyGetter interface {
+	GetAPIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error)
+}
+
+type Options struct {
+	// SigningSecret verifies that requests come from the Slack app.
+	SigningSecret string
+	// Workspaces map Slack team ids to API keys of the service. Links are created
+	// with the key of the workspace, in its tenant and with its role.
+	Workspaces map[string]string
+	// BaseURL prefixes aliases in replies, e.g. "https://sho.rt"; the host of the
+	// request is used if it is empty.
+	BaseURL string
+}
+
+// New returns handler of POST /slack serving a slash command, e.g. /shorten, of a
+// Slack app. The command text is the url to shorten; the short link is shown to the
+// user who typed the command only.
+func New(log *slog.Logger, urlShortener Shortener, keyGetter APIKeyGetter, opts Options) http.HandlerFunc {
+	// Ключи хранятся только в виде хешей, поэтому сопоставляем по хешу
+	keyHashes := make(map[string]string, len(opts.Workspaces))
+	for teamID, key := range opts.Workspaces {
+		keyHashes[teamID] = apikey.Hash(key)
+	}
+
+	baseURL := strings.TrimSuffix(opts.BaseURL, "/")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.slack.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+		if err != nil {
+			log.Info("failed to read request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to read request"))
+
+			return
+		}
+
+		if !Valid(opts.SigningSecret, r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature), body, time.Now()) {
+			log.Info("invalid slack signature")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error(r, resp.CodeUnauthorized, "unauthorized"))
+
+			return
+		}
+
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			log.Info("failed to parse slack command", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to decode request"))
+
+			return
+		}
+
+		teamID := form.Get("team_id")
+		log = log.With(slog.String("team_id", teamID), slog.String("slack_user_id", form.Get("user_id")))
+
+		// На ошибки Slack показывает пользователю только общий текст, поэтому
+		// объясняем их в ответе со статусом 200
+		hash, ok := keyHashes[teamID]
+		if !ok {
+			log.Info("slack workspace is not connected")
+
+			reply(w, r, "This workspace is not connected to the url shortener. Its team id is "+teamID+".")
+
+			return
+		}
+
+		key, err := keyGetter.GetAPIKeyByHash(r.Context(), hash)
+		if err == nil && !key.RevokedAt.IsZero() {
+			// Отозванный ключ отключает рабочее пространство
+			err = storage.ErrAPIKeyNotFound
+		}
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			log.Info("api key of slack workspace is not valid")
+
+			reply(w, r, "The API key of this workspace is not valid, contact the administrator.")
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get api key", sl.Err(err))
+
+			reply(w, r, "Something went wrong, try again later.")
+
+			return
+		}
+
+		rawURL := strings.TrimSpace(form.Get("text"))
+		if rawURL == "" || rawURL == "help" {
+			reply(w, r, helpText)
+
+			return
+		}
+		// Slack оборачивает распознанные ссылки: <https://example.com> или <https://example.com|example.com>
+		if strings.HasPrefix(rawURL, "<") && strings.HasSuffix(rawURL, ">") {
+			rawURL, _, _ = strings.Cut(rawURL[1:len(rawURL)-1], "|")
+		}
+
+		alias, err := urlShortener.Shorten(r.Context(), shortener.APIKeyAccount(key), rawURL)
+		if errors.Is(err, shortener.ErrRejected) {
+			log.Info("url rejected", sl.Err(err))
+
+			reply(w, r, "Can't shorten it: "+strings.TrimPrefix(err.Error(), shortener.ErrRejected.Error()+": "))
+
+			return
+		}
+		if errors.Is(err, shortener.ErrForbidden) {
+			log.Info("api key of slack workspace may not create links")
+
+			reply(w, r, "This workspace is not allowed to create links.")
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to shorten url", sl.Err(err))
+
+			reply(w, r, "Something went wrong, try again later.")
+
+			return
+		}
+
+		log.Info("url shortened", slog.String("alias", alias), slog.Int64("api_key_id", key.ID))
+
+		prefix := baseURL
+		if prefix == "" {
+			// Slack вызывает только адреса HTTPS
+			prefix = "https://" + r.Host
+		}
+
+		reply(w, r, prefix+"/"+alias)
+	}
+}
+
+// Valid reports whether the Slack signature of the body sent at timestamp (Unix
+// seconds) is valid and the request is not older than five minutes.
+func Valid(secret, timestamp, sig string, body []byte, now time.Time) bool {
+	if secret == "" {
+		return false
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	if d := now.Sub(time.Unix(ts, 0)); d > maxSkew || d < -maxSkew {
+		return false
+	}
+
+	sig, ok := strings.CutPrefix(sig, signatureVersion+"=")
+	if !ok {
+		return false
+	}
+
+	return signature.Valid(secret, signatureVersion+":"+timestamp+":"+string(body), sig)
+}
+
+func reply(w http.ResponseWriter, r *http.Request, text string) {
+	render.JSON(w, r, Response{ResponseType: responseEphemeral, Text: text})
+}
@@ -0,0 +1,190 @@
+package slack_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/slack"
+	"url-shortener/internal/http-server/handlers/slack/mocks"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/signature"
+	"url-shortener/internal/shortener"
+	"url-shortener/internal/storage"
+)
+
+const secret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+func sign(ts, body string) string {
+	return "v0=" + signature.Compute(secret, "v0:"+ts+":"+body)
+}
+
+func TestSlackHandler(t *testing.T) {
+	cases := []struct {
+		name string
+		team string
+		text string
+		// badSignature and stale break the signature check.
+		badSignature bool
+		stale        bool
+		key          storage.APIKey
+		keyErr       error
+		shortenCall  bool
+		shortenURL   string
+		shortenErr   error
+		respCode     int
+		reply        string
+	}{
+		{
+			name:        "Success",
+			team:        "T1",
+			text:        "https://example.com",
+			key:         storage.APIKey{ID: 3, Tenant: "brand"},
+			shortenCall: true,
+			shortenURL:  "https://example.com",
+			respCode:    http.StatusOK,
+			reply:       "https://sho.rt/abc123",
+		},
+		{
+			name:        "Link formatted by Slack",
+			team:        "T1",
+			text:        "<https://example.com|example.com>",
+			key:         storage.APIKey{ID: 3},
+			shortenCall: true,
+			shortenURL:  "https://example.com",
+			respCode:    http.StatusOK,
+			reply:       "https://sho.rt/abc123",
+		},
+		{
+			name:        "Rejected",
+			team:        "T1",
+			text:        "https://evil.example",
+			key:         storage.APIKey{ID: 3},
+			shortenCall: true,
+			shortenURL:  "https://evil.example",
+			shortenErr:  fmt.Errorf("%w: url is flagged as malicious: phishing", shortener.ErrRejected),
+			respCode:    http.StatusOK,
+			reply:       "Can't shorten it: url is flagged as malicious: phishing",
+		},
+		{
+			name:     "Help",
+			team:     "T1",
+			key:      storage.APIKey{ID: 3},
+			respCode: http.StatusOK,
+			reply:    "Usage: /shorten https://example.com/long/url",
+		},
+		{
+			name:     "Unknown workspace",
+			team:     "T2",
+			text:     "https://example.com",
+			respCode: http.StatusOK,
+			reply:    "This workspace is not connected to the url shortener. Its team id is T2.",
+		},
+		{
+			name:     "Revoked key",
+			team:     "T1",
+			text:     "https://example.com",
+			key:      storage.APIKey{ID: 3, RevokedAt: time.Now()},
+			respCode: http.StatusOK,
+			reply:    "The API key of this workspace is not valid, contact the administrator.",
+		},
+		{
+			name:     "Deleted key",
+			team:     "T1",
+			text:     "https://example.com",
+			keyErr:   storage.ErrAPIKeyNotFound,
+			respCode: http.StatusOK,
+			reply:    "The API key of this workspace is not valid, contact the administrator.",
+		},
+		{
+			name:         "Invalid signature",
+			team:         "T1",
+			text:         "https://example.com",
+			badSignature: true,
+			respCode:     http.StatusUnauthorized,
+		},
+		{
+			name:     "Stale request",
+			team:     "T1",
+			text:     "https://example.com",
+			stale:    true,
+			respCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			shortenerMock := mocks.NewShortener(t)
+			keyGetterMock := mocks.NewAPIKeyGetter(t)
+
+			if tc.key.ID != 0 || tc.keyErr != nil {
+				keyGetterMock.On("GetAPIKeyByHash", mock.Anything, apikey.Hash("key-1")).
+					Return(tc.key, tc.keyErr).Once()
+			}
+
+			if tc.shortenCall {
+				shortenerMock.On("Shorten", mock.Anything,
+					shortener.Account{APIKeyID: tc.key.ID, Tenant: tc.key.Tenant}, tc.shortenURL).
+					Return("abc123", tc.shortenErr).Once()
+			}
+
+			handler := slack.New(slogdiscard.NewDiscardLogger(), shortenerMock, keyGetterMock, slack.Options{
+				SigningSecret: secret,
+				Workspaces:    map[string]string{"T1": "key-1"},
+				BaseURL:       "https://sho.rt",
+			})
+
+			body := url.Values{
+				"team_id": {tc.team},
+				"user_id": {"U1"},
+				"command": {"/shorten"},
+				"text":    {tc.text},
+			}.Encode()
+
+			ts := time.Now()
+			if tc.stale {
+				ts = ts.Add(-10 * time.Minute)
+			}
+			timestamp := strconv.FormatInt(ts.Unix(), 10)
+
+			sig := sign(timestamp, body)
+			if tc.badSignature {
+				sig = sign(timestamp, body+"&text=other")
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/slack", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set(slack.HeaderTimestamp, timestamp)
+			req.Header.Set(slack.HeaderSignature, sig)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			if tc.reply == "" {
+				return
+			}
+
+			var res slack.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+
+			require.Equal(t, "ephemeral", res.ResponseType)
+			require.Equal(t, tc.reply, res.Text)
+		})
+	}
+}
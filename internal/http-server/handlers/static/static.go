@@ -0,0 +1,58 @@
+// Package static serves robots.txt and favicon.ico, which crawlers and browsers request
+// on every short domain; without them each request is a redirect lookup of a missing alias.
+package static
+
+import (
+	"bytes"
+	_ "embed"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//go:embed favicon.ico
+var favicon []byte
+
+// Favicon returns the built-in icon.
+func Favicon() []byte {
+	return favicon
+}
+
+// Robots returns robots.txt which disallows crawling of everything except the allowed paths.
+func Robots(allow []string) []byte {
+	var b bytes.Buffer
+
+	b.WriteString("User-agent: *\n")
+	for _, path := range allow {
+		b.WriteString("Allow: " + path + "\n")
+	}
+	b.WriteString("Disallow: /\n")
+
+	return b.Bytes()
+}
+
+// NewRobots returns handler serving body as robots.txt.
+func NewRobots(body []byte, maxAge time.Duration) http.HandlerFunc {
+	return newFile(body, "text/plain; charset=utf-8", maxAge)
+}
+
+// NewFavicon returns handler serving icon as favicon.ico.
+func NewFavicon(icon []byte, maxAge time.Duration) http.HandlerFunc {
+	return newFile(icon, "image/x-icon", maxAge)
+}
+
+func newFile(data []byte, contentType string, maxAge time.Duration) http.HandlerFunc {
+	cacheControl := "public, max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		_, _ = w.Write(data)
+	}
+}
@@ -0,0 +1,68 @@
+package static_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/static"
+)
+
+func TestRobots(t *testing.T) {
+	cases := []struct {
+		name  string
+		allow []string
+		want  string
+	}{
+		{
+			name: "Disallow all",
+			want: "User-agent: *\nDisallow: /\n",
+		},
+		{
+			name:  "Allowed paths",
+			allow: []string{"/docs", "/preview/"},
+			want:  "User-agent: *\nAllow: /docs\nAllow: /preview/\nDisallow: /\n",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, string(static.Robots(tc.allow)))
+		})
+	}
+}
+
+func TestNewRobots(t *testing.T) {
+	rr := httptest.NewRecorder()
+	static.NewRobots(static.Robots(nil), time.Hour).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=3600", rr.Header().Get("Cache-Control"))
+	assert.Equal(t, "User-agent: *\nDisallow: /\n", rr.Body.String())
+}
+
+func TestNewFavicon(t *testing.T) {
+	handler := static.NewFavicon(static.Favicon(), time.Hour)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/x-icon", rr.Header().Get("Content-Type"))
+	assert.Equal(t, static.Favicon(), rr.Body.Bytes())
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodHead, "/favicon.ico", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Body.Bytes())
+}
@@ -0,0 +1,158 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/domains"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/etag"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+// FormatOEmbed selects the oEmbed response in the format query parameter.
+const FormatOEmbed = "oembed"
+
+// Response is the OpenGraph metadata of the destination; field names follow og: properties.
+type Response struct {
+	resp.Response
+	Alias string `json:"alias,omitempty"`
+	// URL is the destination, so clients can link to it without the redirect.
+	URL         string `json:"url,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	// SiteName is the host of the destination.
+	SiteName string `json:"site_name,omitempty"`
+}
+
+// OEmbed is the oEmbed (https://oembed.com) "link" response for the destination.
+type OEmbed struct {
+	Version      string `json:"version"`
+	Type         string `json:"type"`
+	Title        string `json:"title,omitempty"`
+	ProviderName string `json:"provider_name,omitempty"`
+	ProviderURL  string `json:"provider_url,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+}
+
+// URLInfoGetter is an interface for getting saved url details by alias.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLInfoGetter
+type URLInfoGetter interface {
+	GetURLInfo(ctx context.Context, alias string) (storage.URL, error)
+}
+
+// New returns handler of GET /unfurl/{alias} answering with the stored metadata of
+// the destination page, see package pagemeta, so chat apps can render rich previews
+// of short links without following the redirect; the page is never requested here.
+// format=oembed returns it as an oEmbed response. Unfurls don't count as clicks.
+func New(log *slog.Logger, urlInfoGetter URLInfoGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.unfurl.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid request"))
+
+			return
+		}
+
+		tenantName := tenant.FromContext(r.Context())
+
+		u, err := urlInfoGetter.GetURLInfo(r.Context(), tenant.Key(tenantName, alias))
+		if err == nil && !domains.Serves(u.Domain, r.Host) {
+			log.Info("url is bound to another domain", slog.String("domain", u.Domain), slog.String("host", r.Host))
+
+			err = storage.ErrURLNotFound
+		}
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url info", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		switch {
+		case !u.ExpiresAt.IsZero() && !time.Now().Before(u.ExpiresAt):
+			render.Status(r, http.StatusGone)
+			render.JSON(w, r, resp.Error(r, resp.CodeExpired, "url expired"))
+
+			return
+		case !u.ExhaustedAt.IsZero():
+			render.Status(r, http.StatusGone)
+			render.JSON(w, r, resp.Error(r, resp.CodeExhausted, "url reached its click limit"))
+
+			return
+		case u.QuarantineReason != "":
+			// Опасную страницу не рекламируем превью
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error(r, resp.CodeForbidden, "url is flagged as malicious"))
+
+			return
+		case u.BurnAfterReading:
+			// Адрес одноразовой ссылки виден только тому, кто ее откроет
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error(r, resp.CodeForbidden, "one-time links are not unfurled"))
+
+			return
+		}
+
+		var siteName, siteURL string
+		if parsed, err := url.Parse(u.URL); err == nil {
+			siteName = parsed.Host
+			siteURL = parsed.Scheme + "://" + parsed.Host
+		}
+
+		if r.URL.Query().Get("format") == FormatOEmbed {
+			etag.JSON(w, r, OEmbed{
+				Version:      "1.0",
+				Type:         "link",
+				Title:        u.Page.Title,
+				ProviderName: siteName,
+				ProviderURL:  siteURL,
+				ThumbnailURL: u.Page.Image,
+			}, u.Version)
+
+			return
+		}
+
+		etag.JSON(w, r, Response{
+			Response:    resp.OK(),
+			Alias:       tenant.Alias(tenantName, u.Alias),
+			URL:         u.URL,
+			Title:       u.Page.Title,
+			Description: u.Page.Description,
+			Image:       u.Page.Image,
+			SiteName:    siteName,
+		}, u.Version)
+	}
+}
@@ -0,0 +1,151 @@
+package unfurl_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/unfurl"
+	"url-shortener/internal/http-server/handlers/unfurl/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestUnfurlHandler(t *testing.T) {
+	page := storage.PageMetadata{Title: "Example", Description: "About", Image: "https://example.com/og.png"}
+
+	cases := []struct {
+		name      string
+		url       storage.URL
+		mockError error
+		respCode  int
+		resp      unfurl.Response
+		respError string
+	}{
+		{
+			name:     "Success",
+			url:      storage.URL{Alias: "test_alias", URL: "https://example.com/page", Page: page},
+			respCode: http.StatusOK,
+			resp: unfurl.Response{
+				Alias:       "test_alias",
+				URL:         "https://example.com/page",
+				Title:       "Example",
+				Description: "About",
+				Image:       "https://example.com/og.png",
+				SiteName:    "example.com",
+			},
+		},
+		{
+			name:     "Not fetched yet",
+			url:      storage.URL{Alias: "test_alias", URL: "https://example.com/page"},
+			respCode: http.StatusOK,
+			resp:     unfurl.Response{Alias: "test_alias", URL: "https://example.com/page", SiteName: "example.com"},
+		},
+		{
+			name:      "Not found",
+			url:       storage.URL{Alias: "missing"},
+			mockError: storage.ErrURLNotFound,
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+		},
+		{
+			name:      "Other domain",
+			url:       storage.URL{Alias: "test_alias", URL: "https://example.com/", Domain: "go.brand.example"},
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+		},
+		{
+			name:      "Expired",
+			url:       storage.URL{Alias: "test_alias", URL: "https://example.com/", ExpiresAt: time.Now().Add(-time.Hour)},
+			respCode:  http.StatusGone,
+			respError: "url expired",
+		},
+		{
+			name:      "Quarantined",
+			url:       storage.URL{Alias: "test_alias", URL: "https://phish.example/", QuarantineReason: "malware", Page: page},
+			respCode:  http.StatusForbidden,
+			respError: "url is flagged as malicious",
+		},
+		{
+			name:      "One-time link",
+			url:       storage.URL{Alias: "test_alias", URL: "https://example.com/secret", BurnAfterReading: true},
+			respCode:  http.StatusForbidden,
+			respError: "one-time links are not unfurled",
+		},
+		{
+			name:      "Storage error",
+			url:       storage.URL{Alias: "test_alias"},
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			getterMock := mocks.NewURLInfoGetter(t)
+			getterMock.On("GetURLInfo", mock.Anything, tc.url.Alias).Return(tc.url, tc.mockError).Once()
+
+			r := chi.NewRouter()
+			r.Get("/unfurl/{alias}", unfurl.New(slogdiscard.NewDiscardLogger(), getterMock))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/unfurl/"+tc.url.Alias, nil))
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var res unfurl.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+
+			require.Equal(t, tc.respError, res.Error.Error())
+			if tc.respError != "" {
+				require.Empty(t, res.URL)
+
+				return
+			}
+
+			tc.resp.Response = res.Response
+			require.Equal(t, tc.resp, res)
+		})
+	}
+}
+
+func TestUnfurlHandler_OEmbed(t *testing.T) {
+	getterMock := mocks.NewURLInfoGetter(t)
+	getterMock.On("GetURLInfo", mock.Anything, "test_alias").Return(storage.URL{
+		Alias: "test_alias",
+		URL:   "https://example.com/page",
+		Page:  storage.PageMetadata{Title: "Example", Image: "https://example.com/og.png"},
+	}, nil).Once()
+
+	r := chi.NewRouter()
+	r.Get("/unfurl/{alias}", unfurl.New(slogdiscard.NewDiscardLogger(), getterMock))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/unfurl/test_alias?format=oembed", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var res unfurl.OEmbed
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+
+	require.Equal(t, unfurl.OEmbed{
+		Version:      "1.0",
+		Type:         "link",
+		Title:        "Example",
+		ProviderName: "example.com",
+		ProviderURL:  "https://example.com",
+		ThumbnailURL: "https://example.com/og.png",
+	}, res)
+}
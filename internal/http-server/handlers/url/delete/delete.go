@@ -0,0 +1,97 @@
+package delete
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/acl"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+// URLDeleter is an interface for deleting url by alias.
+// GetURLInfo is used to check the owner of the link, see acl.Principal.CanManage.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLDeleter
+type URLDeleter interface {
+	GetURLInfo(ctx context.Context, alias string) (storage.URL, error)
+	DeleteURL(ctx context.Context, alias string) error
+}
+
+func New(log *slog.Logger, urlDeleter URLDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.delete.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid request"))
+
+			return
+		}
+
+		// Ссылки хранятся в пространстве имен тенанта
+		alias = tenant.Key(tenant.FromContext(r.Context()), alias)
+
+		// Чужие ссылки выглядят несуществующими, удалить любую может только администратор
+		u, err := urlDeleter.GetURLInfo(r.Context(), alias)
+		if err == nil && !acl.FromContext(r.Context()).CanManage(u) {
+			log.Info("url is owned by another client", slog.String("alias", alias))
+
+			err = storage.ErrURLNotFound
+		}
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", "alias", alias)
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url info", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		err = urlDeleter.DeleteURL(r.Context(), alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", "alias", alias)
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to delete url", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("url deleted", slog.String("alias", alias))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,176 @@
+package delete_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/acl"
+	"url-shortener/internal/http-server/handlers/url/delete"
+	"url-shortener/internal/http-server/handlers/url/delete/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/jwt"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestDeleteHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		alias     string
+		respCode  int
+		respError string
+		infoError error
+		mockError error
+	}{
+		{
+			name:     "Success",
+			alias:    "test_alias",
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Not found",
+			alias:     "missing_alias",
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+			infoError: storage.ErrURLNotFound,
+		},
+		{
+			name:      "GetURLInfo Error",
+			alias:     "test_alias",
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+			infoError: errors.New("unexpected error"),
+		},
+		{
+			name:      "DeleteURL Error",
+			alias:     "test_alias",
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlDeleterMock := mocks.NewURLDeleter(t)
+
+			urlDeleterMock.On("GetURLInfo", mock.Anything, tc.alias).
+				Return(storage.URL{Alias: tc.alias, APIKeyID: 1}, tc.infoError).
+				Once()
+			if tc.infoError == nil {
+				urlDeleterMock.On("DeleteURL", mock.Anything, tc.alias).
+					Return(tc.mockError).
+					Once()
+			}
+
+			r := chi.NewRouter()
+			r.Delete("/url/{alias}", delete.New(slogdiscard.NewDiscardLogger(), urlDeleterMock))
+
+			req, err := http.NewRequest(http.MethodDelete, "/url/"+tc.alias, nil)
+			require.NoError(t, err)
+			req = req.WithContext(apikey.WithKeyID(req.Context(), 1))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			require.Equal(t, tc.respError, body.Error.Error())
+		})
+	}
+}
+
+func TestDeleteHandler_Owner(t *testing.T) {
+	const userID = 7
+
+	cases := []struct {
+		name     string
+		owner    storage.URL
+		ctx      func(ctx context.Context) context.Context
+		respCode int
+	}{
+		{
+			name:     "Own link",
+			owner:    storage.URL{UserID: userID},
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Foreign link",
+			owner:    storage.URL{UserID: userID + 1},
+			respCode: http.StatusNotFound,
+		},
+		{
+			name:     "Link of API key",
+			owner:    storage.URL{APIKeyID: 1},
+			respCode: http.StatusNotFound,
+		},
+		{
+			name:  "Other key link",
+			owner: storage.URL{APIKeyID: 2},
+			ctx: func(ctx context.Context) context.Context {
+				return apikey.WithKeyID(ctx, 1)
+			},
+			respCode: http.StatusNotFound,
+		},
+		{
+			name:  "Admin",
+			owner: storage.URL{UserID: userID + 1},
+			ctx: func(ctx context.Context) context.Context {
+				return acl.WithRole(apikey.WithKeyID(ctx, 1), acl.RoleAdmin)
+			},
+			respCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlDeleterMock := mocks.NewURLDeleter(t)
+
+			u := tc.owner
+			u.Alias = "test_alias"
+			urlDeleterMock.On("GetURLInfo", mock.Anything, "test_alias").
+				Return(u, nil).
+				Once()
+			if tc.respCode == http.StatusOK {
+				urlDeleterMock.On("DeleteURL", mock.Anything, "test_alias").
+					Return(nil).
+					Once()
+			}
+
+			r := chi.NewRouter()
+			r.Delete("/url/{alias}", delete.New(slogdiscard.NewDiscardLogger(), urlDeleterMock))
+
+			req, err := http.NewRequest(http.MethodDelete, "/url/test_alias", nil)
+			require.NoError(t, err)
+			if tc.ctx != nil {
+				req = req.WithContext(tc.ctx(req.Context()))
+			} else {
+				req = req.WithContext(jwt.WithUserID(req.Context(), userID))
+			}
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+		})
+	}
+}
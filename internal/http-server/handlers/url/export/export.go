@@ -0,0 +1,373 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+// Formats of exported clicks.
+const (
+	FormatCSV = "csv"
+	// FormatExcel is CSV which Excel opens as is: with a UTF-8 BOM and without cells
+	// it would take for formulas.
+	FormatExcel = "excel"
+)
+
+// Exported data.
+const (
+	DataEvents = "events"
+	DataDaily  = "daily"
+)
+
+// defaultPeriod is exported when the range is not set.
+const defaultPeriod = 30 * 24 * time.Hour
+
+var (
+	eventsHeader = []string{
+		"alias", "time", "referrer", "referrer_host", "user_agent", "browser", "ip", "variant",
+		"utm_source", "utm_medium", "utm_campaign", "country", "city",
+	}
+	dailyHeader = []string{"alias", "day", "clicks"}
+)
+
+// ClickExporter is an interface for reading clicks to export.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=ClickExporter
+type ClickExporter interface {
+	ExportClickEvents(ctx context.Context, filter storage.ClickFilter, fn func(storage.ClickEvent) error) error
+	GetHourlyClicks(ctx context.Context, alias string, from, to time.Time) ([]storage.ClickBucket, error)
+	GetDailyClicks(ctx context.Context, from, to time.Time) ([]storage.DailyClicks, error)
+}
+
+var (
+	errInvalidFormat = errors.New(`format must be "csv" or "excel"`)
+	errInvalidData   = errors.New(`data must be "events" or "daily"`)
+	errInvalidFrom   = errors.New("from must be an RFC 3339 time")
+	errInvalidTo     = errors.New("to must be an RFC 3339 time")
+	errInvalidRange  = errors.New("from must be before to")
+)
+
+type query struct {
+	format string
+	data   string
+	from   time.Time
+	to     time.Time
+}
+
+// New returns handler of GET /url/{alias}/stats/export streaming clicks of the link
+// as CSV: every click event or clicks per day (UTC), selected by the data query
+// parameter. The range is set by the "from" and "to" parameters, the last 30 days by
+// default; daily ranges are aligned to days.
+func New(log *slog.Logger, exporter ClickExporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.export.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid request"))
+
+			return
+		}
+
+		q, err := parseQuery(r, time.Now())
+		if err != nil {
+			log.Info("invalid query", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
+
+			return
+		}
+
+		key := tenant.Key(tenant.FromContext(r.Context()), alias)
+		out := newWriter(w, q, alias+"-"+q.data+".csv")
+
+		if q.data == DataDaily {
+			err = exportDays(r.Context(), exporter, out, key, alias, q)
+		} else {
+			err = exporter.ExportClickEvents(r.Context(), storage.ClickFilter{Alias: key, From: q.from, To: q.to},
+				func(e storage.ClickEvent) error {
+					// В выгрузке alias без пространства имен тенанта
+					e.Alias = alias
+
+					return out.writeEvent(e)
+				})
+		}
+
+		finish(w, r, log, out, err)
+	}
+}
+
+// NewBulk returns handler of GET /admin/stats/export streaming clicks of all links,
+// like New. Aliases are exported with their tenant namespace.
+func NewBulk(log *slog.Logger, exporter ClickExporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.export.NewBulk"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		q, err := parseQuery(r, time.Now())
+		if err != nil {
+			log.Info("invalid query", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
+
+			return
+		}
+
+		out := newWriter(w, q, q.data+".csv")
+
+		if q.data == DataDaily {
+			var days []storage.DailyClicks
+
+			days, err = exporter.GetDailyClicks(r.Context(), q.from, q.to)
+			for i := 0; err == nil && i < len(days); i++ {
+				err = out.writeDay(days[i].Alias, days[i].Day, days[i].Clicks)
+			}
+		} else {
+			err = exporter.ExportClickEvents(r.Context(), storage.ClickFilter{From: q.from, To: q.to}, out.writeEvent)
+		}
+
+		finish(w, r, log, out, err)
+	}
+}
+
+// exportDays writes clicks of the link per day summed from its hours.
+func exportDays(ctx context.Context, exporter ClickExporter, out *writer, key, alias string, q query) error {
+	hours, err := exporter.GetHourlyClicks(ctx, key, q.from, q.to)
+	if err != nil {
+		return err
+	}
+
+	var (
+		day    string
+		clicks int64
+	)
+
+	for _, h := range hours {
+		if d := h.Start.UTC().Format(time.DateOnly); d != day {
+			if day != "" {
+				if err := out.writeDay(alias, day, clicks); err != nil {
+					return err
+				}
+			}
+
+			day, clicks = d, 0
+		}
+
+		clicks += h.Clicks
+	}
+
+	if day == "" {
+		return nil
+	}
+
+	return out.writeDay(alias, day, clicks)
+}
+
+// finish completes the export. Errors before the first row are reported with a status,
+// after it the response can only be aborted.
+func finish(w http.ResponseWriter, r *http.Request, log *slog.Logger, out *writer, err error) {
+	if err == nil {
+		err = out.flush()
+	}
+
+	switch {
+	case err == nil:
+		log.Info("clicks exported", slog.Int("rows", out.rows))
+	case out.started:
+		// Часть ответа уже отправлена, сообщить клиенту об ошибке можно только обрывом
+		log.Error("failed to export clicks", slog.Int("rows", out.rows), sl.Err(err))
+
+		panic(http.ErrAbortHandler)
+	case errors.Is(err, storage.ErrURLNotFound):
+		log.Info("url not found")
+
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "not found"))
+	default:
+		log.Error("failed to export clicks", sl.Err(err))
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+	}
+}
+
+func parseQuery(r *http.Request, now time.Time) (query, error) {
+	v := r.URL.Query()
+
+	q := query{format: v.Get("format"), data: v.Get("data")}
+
+	switch q.format {
+	case "":
+		q.format = FormatCSV
+	case FormatCSV, FormatExcel:
+	default:
+		return query{}, errInvalidFormat
+	}
+
+	switch q.data {
+	case "":
+		q.data = DataEvents
+	case DataEvents, DataDaily:
+	default:
+		return query{}, errInvalidData
+	}
+
+	var err error
+
+	q.to = now
+	if s := v.Get("to"); s != "" {
+		if q.to, err = time.Parse(time.RFC3339, s); err != nil {
+			return query{}, errInvalidTo
+		}
+	}
+	q.to = q.to.UTC()
+
+	q.from = q.to.Add(-defaultPeriod)
+	if s := v.Get("from"); s != "" {
+		if q.from, err = time.Parse(time.RFC3339, s); err != nil {
+			return query{}, errInvalidFrom
+		}
+	}
+	q.from = q.from.UTC()
+
+	// Дни выгружаются целиком, включая неполный последний
+	if q.data == DataDaily {
+		const day = 24 * time.Hour
+
+		if down := q.to.Truncate(day); down.Before(q.to) {
+			q.to = down.Add(day)
+		}
+		q.from = q.from.Truncate(day)
+	}
+
+	if !q.from.Before(q.to) {
+		return query{}, errInvalidRange
+	}
+
+	return q, nil
+}
+
+// writer writes CSV rows, sending the headers with the first one.
+type writer struct {
+	w        http.ResponseWriter
+	csv      *csv.Writer
+	header   []string
+	excel    bool
+	filename string
+
+	started bool
+	rows    int
+}
+
+func newWriter(w http.ResponseWriter, q query, filename string) *writer {
+	header := eventsHeader
+	if q.data == DataDaily {
+		header = dailyHeader
+	}
+
+	return &writer{
+		w:        w,
+		csv:      csv.NewWriter(w),
+		header:   header,
+		excel:    q.format == FormatExcel,
+		filename: filename,
+	}
+}
+
+func (w *writer) start() error {
+	if w.started {
+		return nil
+	}
+
+	w.started = true
+
+	w.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.w.Header().Set("Content-Disposition", `attachment; filename="`+w.filename+`"`)
+
+	if w.excel {
+		// Без BOM Excel читает UTF-8 в кодировке системы
+		if _, err := io.WriteString(w.w, "\ufeff"); err != nil {
+			return err
+		}
+	}
+
+	return w.csv.Write(w.header)
+}
+
+func (w *writer) write(row []string) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	if w.excel {
+		for i, cell := range row {
+			row[i] = escapeFormula(cell)
+		}
+	}
+
+	w.rows++
+
+	return w.csv.Write(row)
+}
+
+func (w *writer) writeEvent(e storage.ClickEvent) error {
+	return w.write([]string{
+		e.Alias, e.Time.UTC().Format(time.RFC3339), e.Referrer, e.ReferrerHost, e.UserAgent, e.Browser, e.IP, e.Variant,
+		e.UTMSource, e.UTMMedium, e.UTMCampaign, e.Country, e.City,
+	})
+}
+
+func (w *writer) writeDay(alias, day string, clicks int64) error {
+	return w.write([]string{alias, day, strconv.FormatInt(clicks, 10)})
+}
+
+// flush writes buffered rows; an empty export still gets the header row.
+func (w *writer) flush() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	w.csv.Flush()
+
+	return w.csv.Error()
+}
+
+// escapeFormula prefixes cells Excel would evaluate as formulas, e.g. a referrer
+// "=HYPERLINK(...)" sent by a visitor, with an apostrophe.
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+
+	return cell
+}
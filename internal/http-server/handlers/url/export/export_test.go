@@ -0,0 +1,145 @@
+package export_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/export"
+	"url-shortener/internal/http-server/handlers/url/export/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+var click = storage.ClickEvent{
+	Alias:        "promo",
+	Time:         time.Date(2026, 10, 1, 12, 30, 0, 0, time.UTC),
+	Referrer:     "=HYPERLINK(\"https://evil.example\")",
+	ReferrerHost: "unknown",
+	Browser:      "chrome",
+	IP:           "203.0.113.0",
+	Country:      "DE",
+}
+
+func TestExportHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		query     string
+		events    bool
+		hourly    bool
+		exportErr error
+		respCode  int
+		body      string
+	}{
+		{
+			name:     "Events",
+			query:    "?from=2026-10-01T00:00:00Z&to=2026-10-02T00:00:00Z",
+			events:   true,
+			respCode: http.StatusOK,
+			body: "alias,time,referrer,referrer_host,user_agent,browser,ip,variant,utm_source,utm_medium,utm_campaign,country,city\n" +
+				"promo,2026-10-01T12:30:00Z,\"=HYPERLINK(\"\"https://evil.example\"\")\",unknown,,chrome,203.0.113.0,,,,,DE,\n",
+		},
+		{
+			name:     "Events for Excel",
+			query:    "?format=excel&from=2026-10-01T00:00:00Z&to=2026-10-02T00:00:00Z",
+			events:   true,
+			respCode: http.StatusOK,
+			body: "\ufeffalias,time,referrer,referrer_host,user_agent,browser,ip,variant,utm_source,utm_medium,utm_campaign,country,city\n" +
+				"promo,2026-10-01T12:30:00Z,\"'=HYPERLINK(\"\"https://evil.example\"\")\",unknown,,chrome,203.0.113.0,,,,,DE,\n",
+		},
+		{
+			name:     "Daily",
+			query:    "?data=daily&from=2026-10-01T05:00:00Z&to=2026-10-02T05:00:00Z",
+			hourly:   true,
+			respCode: http.StatusOK,
+			body:     "alias,day,clicks\npromo,2026-10-01,3\npromo,2026-10-02,4\n",
+		},
+		{
+			name:      "Not found",
+			events:    true,
+			exportErr: storage.ErrURLNotFound,
+			respCode:  http.StatusNotFound,
+		},
+		{
+			name:     "Invalid format",
+			query:    "?format=xlsx",
+			respCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Invalid range",
+			query:    "?from=2026-10-02T00:00:00Z&to=2026-10-01T00:00:00Z",
+			respCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			exporterMock := mocks.NewClickExporter(t)
+
+			if tc.events {
+				exporterMock.On("ExportClickEvents", mock.Anything, mock.MatchedBy(func(f storage.ClickFilter) bool {
+					return f.Alias == "promo"
+				}), mock.Anything).
+					Run(func(args mock.Arguments) {
+						if tc.exportErr == nil {
+							_ = args.Get(2).(func(storage.ClickEvent) error)(click)
+						}
+					}).
+					Return(tc.exportErr).Once()
+			}
+
+			if tc.hourly {
+				// Границы выравниваются по дням
+				exporterMock.On("GetHourlyClicks", mock.Anything, "promo",
+					time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 3, 0, 0, 0, 0, time.UTC)).
+					Return([]storage.ClickBucket{
+						{Start: time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC), Clicks: 1},
+						{Start: time.Date(2026, 10, 1, 23, 0, 0, 0, time.UTC), Clicks: 2},
+						{Start: time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC), Clicks: 4},
+					}, nil).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Get("/url/{alias}/stats/export", export.New(slogdiscard.NewDiscardLogger(), exporterMock))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/promo/stats/export"+tc.query, nil))
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			if tc.body != "" {
+				require.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+				require.Equal(t, tc.body, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestBulkExportHandler(t *testing.T) {
+	exporterMock := mocks.NewClickExporter(t)
+	exporterMock.On("GetDailyClicks", mock.Anything,
+		time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC)).
+		Return([]storage.DailyClicks{
+			{Alias: "a", Day: "2026-10-01", Clicks: 2},
+			{Alias: "b", Day: "2026-10-01", Clicks: 1},
+		}, nil).Once()
+
+	handler := export.NewBulk(slogdiscard.NewDiscardLogger(), exporterMock)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet,
+		"/admin/stats/export?data=daily&from=2026-10-01T00:00:00Z&to=2026-10-01T12:00:00Z", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, `attachment; filename="daily.csv"`, rr.Header().Get("Content-Disposition"))
+	require.Equal(t, "alias,day,clicks\na,2026-10-01,2\nb,2026-10-01,1\n", rr.Body.String())
+}
@@ -0,0 +1,168 @@
+package info
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/etag"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+type Response struct {
+	resp.Response
+	Alias     string     `json:"alias,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Hits      int64      `json:"hits"`
+	// APIKeyID and UserID identify the owner of the link.
+	APIKeyID int64 `json:"api_key_id,omitempty"`
+	UserID   int64 `json:"user_id,omitempty"`
+	// RedirectCode is omitted for links using the default redirect status.
+	RedirectCode int `json:"redirect_code,omitempty"`
+	// QuarantineReason is set when the destination was flagged as malicious.
+	QuarantineReason string `json:"quarantine_reason,omitempty"`
+	// MaxClicks is omitted for links without a click limit.
+	MaxClicks   int64      `json:"max_clicks,omitempty"`
+	ExhaustedAt *time.Time `json:"exhausted_at,omitempty"`
+	// BurnAfterReading is set for one-time links deleted by their first redirect.
+	BurnAfterReading bool   `json:"burn_after_reading,omitempty"`
+	WebhookURL       string `json:"webhook_url,omitempty"`
+	// Domain is omitted for links served on every domain.
+	Domain string `json:"domain,omitempty"`
+	// GeoTargets map country or continent codes of visitors to destinations.
+	GeoTargets map[string]string `json:"geo_targets,omitempty"`
+	// DeviceTargets map platforms of visitors to destinations.
+	DeviceTargets map[string]string `json:"device_targets,omitempty"`
+	// Split lists A/B test variants of the link with their weights.
+	Split *storage.Split `json:"split,omitempty"`
+	// QueryParams are added to the destination query on every redirect.
+	QueryParams map[string]string `json:"query_params,omitempty"`
+	PassQuery   bool              `json:"pass_query,omitempty"`
+	NoAnalytics bool              `json:"no_analytics,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Description string            `json:"description,omitempty"`
+	// DeadReason is set when the destination was found gone, e.g. "404" or "dns".
+	DeadReason string     `json:"dead_reason,omitempty"`
+	DeadSince  *time.Time `json:"dead_since,omitempty"`
+	// Page is the title and OpenGraph metadata of the destination, omitted until fetched.
+	Page *storage.PageMetadata `json:"page,omitempty"`
+}
+
+// URLInfoGetter is an interface for getting saved url details by alias.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLInfoGetter
+type URLInfoGetter interface {
+	GetURLInfo(ctx context.Context, alias string) (storage.URL, error)
+}
+
+func New(log *slog.Logger, urlInfoGetter URLInfoGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.info.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid request"))
+
+			return
+		}
+
+		// Ссылки хранятся в пространстве имен тенанта
+		alias = tenant.Key(tenant.FromContext(r.Context()), alias)
+
+		u, err := urlInfoGetter.GetURLInfo(r.Context(), alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", "alias", alias)
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url info", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		// Слабый тег меняется и с числом переходов, а версия в нем годится для If-Match
+		etag.JSON(w, r, Response{
+			Response:         resp.OK(),
+			Alias:            tenant.Alias(tenant.FromContext(r.Context()), u.Alias),
+			URL:              u.URL,
+			CreatedAt:        timePtr(u.CreatedAt),
+			UpdatedAt:        timePtr(u.UpdatedAt),
+			ExpiresAt:        timePtr(u.ExpiresAt),
+			Hits:             u.Hits,
+			APIKeyID:         u.APIKeyID,
+			UserID:           u.UserID,
+			RedirectCode:     u.RedirectCode,
+			QuarantineReason: u.QuarantineReason,
+			MaxClicks:        u.MaxClicks,
+			ExhaustedAt:      timePtr(u.ExhaustedAt),
+			BurnAfterReading: u.BurnAfterReading,
+			WebhookURL:       u.WebhookURL,
+			Domain:           u.Domain,
+			GeoTargets:       u.GeoTargets,
+			DeviceTargets:    u.DeviceTargets,
+			Split:            splitPtr(u.Split),
+			QueryParams:      u.QueryParams,
+			PassQuery:        u.PassQuery,
+			NoAnalytics:      u.NoAnalytics,
+			Tags:             u.Tags,
+			Description:      u.Description,
+			DeadReason:       u.DeadReason,
+			DeadSince:        timePtr(u.DeadSince),
+			Page:             pagePtr(u.Page),
+		}, u.Version)
+	}
+}
+
+// splitPtr returns nil for links without variants, so the split is omitted from JSON.
+func splitPtr(s storage.Split) *storage.Split {
+	if len(s.Variants) == 0 {
+		return nil
+	}
+
+	return &s
+}
+
+// pagePtr returns nil for links without page metadata, so it is omitted from JSON.
+func pagePtr(p storage.PageMetadata) *storage.PageMetadata {
+	if p == (storage.PageMetadata{}) {
+		return nil
+	}
+
+	return &p
+}
+
+// timePtr returns nil for zero time, so it is omitted from JSON.
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,117 @@
+package info_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/info"
+	"url-shortener/internal/http-server/handlers/url/info/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestInfoHandler(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		name      string
+		alias     string
+		mockURL   storage.URL
+		mockError error
+		respCode  int
+		respError string
+	}{
+		{
+			name:  "Success",
+			alias: "test_alias",
+			mockURL: storage.URL{
+				Alias:     "test_alias",
+				URL:       "https://google.com",
+				CreatedAt: createdAt,
+				Hits:      42,
+			},
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Not found",
+			alias:     "missing_alias",
+			mockError: storage.ErrURLNotFound,
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlInfoGetterMock := mocks.NewURLInfoGetter(t)
+
+			urlInfoGetterMock.On("GetURLInfo", mock.Anything, tc.alias).
+				Return(tc.mockURL, tc.mockError).
+				Once()
+
+			r := chi.NewRouter()
+			r.Get("/url/{alias}", info.New(slogdiscard.NewDiscardLogger(), urlInfoGetterMock))
+
+			req, err := http.NewRequest(http.MethodGet, "/url/"+tc.alias, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp info.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+			require.Equal(t, tc.mockURL.URL, resp.URL)
+			require.Equal(t, tc.mockURL.Hits, resp.Hits)
+
+			if tc.mockError == nil {
+				require.NotNil(t, resp.CreatedAt)
+				require.True(t, createdAt.Equal(*resp.CreatedAt))
+				require.Nil(t, resp.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestInfoHandlerNotModified(t *testing.T) {
+	urlInfoGetterMock := mocks.NewURLInfoGetter(t)
+
+	urlInfoGetterMock.On("GetURLInfo", mock.Anything, "test_alias").
+		Return(storage.URL{Alias: "test_alias", URL: "https://google.com", Hits: 1, Version: 2}, nil).
+		Twice()
+
+	r := chi.NewRouter()
+	r.Get("/url/{alias}", info.New(slogdiscard.NewDiscardLogger(), urlInfoGetterMock))
+
+	req := httptest.NewRequest(http.MethodGet, "/url/test_alias", nil)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	tag := rr.Header().Get("ETag")
+	require.Regexp(t, `^W/"2-[0-9a-f]+"$`, tag)
+
+	req = httptest.NewRequest(http.MethodGet, "/url/test_alias", nil)
+	req.Header.Set("If-None-Match", tag)
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusNotModified, rr.Code)
+	require.Empty(t, rr.Body.String())
+}
@@ -0,0 +1,277 @@
+package list
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/aliascheck"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/etag"
+	"url-shortener/internal/lib/jwt"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+	maxQueryLen  = 100
+)
+
+type URL struct {
+	Alias       string     `json:"alias"`
+	URL         string     `json:"url"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Domain      string     `json:"domain,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	Description string     `json:"description,omitempty"`
+	// DeadReason is set when the destination was found gone, see info.Response.
+	DeadReason string     `json:"dead_reason,omitempty"`
+	DeadSince  *time.Time `json:"dead_since,omitempty"`
+	// Page is the metadata of the destination page, see info.Response.
+	Page *storage.PageMetadata `json:"page,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	URLs       []URL  `json:"urls"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// URLLister is an interface for listing saved urls.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLLister
+type URLLister interface {
+	ListURLs(ctx context.Context, filter storage.ListFilter) ([]storage.URL, string, error)
+}
+
+// New returns handler of GET /url. Query parameters:
+// prefix (alias prefix), created_from and created_to (RFC 3339), tag, q (text in the alias,
+// URL or description), owner ("me", "user:<id>" or "key:<id>"), dead ("true" for links
+// with dead destinations only), archived ("true" for archived links), cursor and limit.
+// Users always get their own links only.
+func New(log *slog.Logger, urlLister URLLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.list.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		filter, err := parseFilter(r)
+		if err != nil {
+			log.Info("invalid query", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
+
+			return
+		}
+
+		// Тенант видит только свои ссылки, тенант по умолчанию - все
+		tenantName := tenant.FromContext(r.Context())
+		filter.AliasPrefix = tenant.Key(tenantName, filter.AliasPrefix)
+
+		urls, next, err := urlLister.ListURLs(r.Context(), filter)
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			log.Info("invalid cursor", slog.String("cursor", filter.Cursor))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid cursor"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to list urls", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		res := Response{
+			Response:   resp.OK(),
+			URLs:       make([]URL, 0, len(urls)),
+			NextCursor: next,
+		}
+		for _, u := range urls {
+			res.URLs = append(res.URLs, URL{
+				Alias:       tenant.Alias(tenantName, u.Alias),
+				URL:         u.URL,
+				CreatedAt:   timePtr(u.CreatedAt),
+				ExpiresAt:   timePtr(u.ExpiresAt),
+				Domain:      u.Domain,
+				Tags:        u.Tags,
+				Description: u.Description,
+				DeadReason:  u.DeadReason,
+				DeadSince:   timePtr(u.DeadSince),
+				Page:        pagePtr(u.Page),
+			})
+		}
+
+		// Клиенты, опрашивающие список, получают 304, пока он не изменился
+		etag.JSON(w, r, res, 0)
+	}
+}
+
+var (
+	errInvalidPrefix   = errors.New("prefix may contain only letters, digits, '-' and '_'")
+	errInvalidLimit    = errors.New("limit must be between 1 and " + strconv.Itoa(maxLimit))
+	errInvalidFrom     = errors.New("created_from must be RFC 3339 time")
+	errInvalidTo       = errors.New("created_to must be RFC 3339 time")
+	errInvalidTag      = errors.New("tag may contain only letters, digits, '-' and '_'")
+	errInvalidQuery    = errors.New("q must be at most " + strconv.Itoa(maxQueryLen) + " characters")
+	errInvalidOwner    = errors.New(`owner must be "me", "user:<id>" or "key:<id>"`)
+	errInvalidDead     = errors.New("dead must be a boolean")
+	errInvalidArchived = errors.New("archived must be a boolean")
+	errForeignOwner    = errors.New("users may list only their own links")
+)
+
+func parseFilter(r *http.Request) (storage.ListFilter, error) {
+	q := r.URL.Query()
+
+	filter := storage.ListFilter{
+		AliasPrefix: q.Get("prefix"),
+		// Теги хранятся в нижнем регистре
+		Tag:    strings.ToLower(q.Get("tag")),
+		Query:  strings.TrimSpace(q.Get("q")),
+		Cursor: q.Get("cursor"),
+		Limit:  defaultLimit,
+		// Пользователи видят только свои ссылки; с API-ключом - все
+		UserID: jwt.UserID(r.Context()),
+	}
+
+	if !aliascheck.HasValidCharset(filter.AliasPrefix) {
+		return storage.ListFilter{}, errInvalidPrefix
+	}
+
+	if !aliascheck.HasValidCharset(filter.Tag) {
+		return storage.ListFilter{}, errInvalidTag
+	}
+
+	if utf8.RuneCountInString(filter.Query) > maxQueryLen {
+		return storage.ListFilter{}, errInvalidQuery
+	}
+
+	if v := q.Get("owner"); v != "" {
+		if err := parseOwner(r, v, &filter); err != nil {
+			return storage.ListFilter{}, err
+		}
+	}
+
+	if v := q.Get("dead"); v != "" {
+		dead, err := strconv.ParseBool(v)
+		if err != nil {
+			return storage.ListFilter{}, errInvalidDead
+		}
+
+		filter.Dead = dead
+	}
+
+	if v := q.Get("archived"); v != "" {
+		archived, err := strconv.ParseBool(v)
+		if err != nil {
+			return storage.ListFilter{}, errInvalidArchived
+		}
+
+		filter.Archived = archived
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxLimit {
+			return storage.ListFilter{}, errInvalidLimit
+		}
+
+		filter.Limit = limit
+	}
+
+	if v := q.Get("created_from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return storage.ListFilter{}, errInvalidFrom
+		}
+
+		filter.CreatedFrom = t
+	}
+
+	if v := q.Get("created_to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return storage.ListFilter{}, errInvalidTo
+		}
+
+		filter.CreatedTo = t
+	}
+
+	return filter, nil
+}
+
+// parseOwner limits filter to the links of the owner: "me" is the client itself.
+func parseOwner(r *http.Request, owner string, filter *storage.ListFilter) error {
+	if owner == "me" {
+		// Пользователь уже ограничен своими ссылками
+		if filter.UserID == 0 {
+			filter.APIKeyID = apikey.KeyID(r.Context())
+		}
+
+		return nil
+	}
+
+	kind, v, ok := strings.Cut(owner, ":")
+	id, err := strconv.ParseInt(v, 10, 64)
+	if !ok || err != nil || id <= 0 {
+		return errInvalidOwner
+	}
+
+	switch kind {
+	case "user":
+		if filter.UserID != 0 && filter.UserID != id {
+			return errForeignOwner
+		}
+
+		filter.UserID = id
+	case "key":
+		if filter.UserID != 0 {
+			return errForeignOwner
+		}
+
+		filter.APIKeyID = id
+	default:
+		return errInvalidOwner
+	}
+
+	return nil
+}
+
+// pagePtr returns nil for links without page metadata, so it is omitted from JSON.
+func pagePtr(p storage.PageMetadata) *storage.PageMetadata {
+	if p == (storage.PageMetadata{}) {
+		return nil
+	}
+
+	return &p
+}
+
+// timePtr returns nil for zero time, so it is omitted from JSON.
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
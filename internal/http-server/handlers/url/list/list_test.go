@@ -0,0 +1,207 @@
+package list_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/list"
+	"url-shortener/internal/http-server/handlers/url/list/mocks"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/jwt"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestListHandler(t *testing.T) {
+	cases := []struct {
+		name       string
+		query      string
+		filter     *storage.ListFilter
+		mockURLs   []storage.URL
+		mockNext   string
+		respCode   int
+		respError  string
+		respLength int
+		userID     int64
+		apiKeyID   int64
+	}{
+		{
+			name:       "Defaults",
+			query:      "",
+			filter:     &storage.ListFilter{Limit: 50},
+			mockURLs:   []storage.URL{{Alias: "a1", URL: "https://a.com"}, {Alias: "a2", URL: "https://b.com"}},
+			mockNext:   "2",
+			respCode:   http.StatusOK,
+			respLength: 2,
+		},
+		{
+			name:  "Filters",
+			query: "?prefix=ab&limit=10&cursor=5&created_from=2024-01-01T00:00:00Z",
+			filter: &storage.ListFilter{
+				AliasPrefix: "ab",
+				Cursor:      "5",
+				Limit:       10,
+				CreatedFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Search",
+			query:    "?tag=Campaign-X&q=docs",
+			filter:   &storage.ListFilter{Limit: 50, Tag: "campaign-x", Query: "docs"},
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "User links",
+			userID:   7,
+			filter:   &storage.ListFilter{Limit: 50, UserID: 7},
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Invalid limit",
+			query:     "?limit=0",
+			respCode:  http.StatusBadRequest,
+			respError: "limit must be between 1 and 500",
+		},
+		{
+			name:      "Invalid prefix",
+			query:     "?prefix=a*",
+			respCode:  http.StatusBadRequest,
+			respError: "prefix may contain only letters, digits, '-' and '_'",
+		},
+		{
+			name:     "Own key links",
+			query:    "?owner=me",
+			apiKeyID: 3,
+			filter:   &storage.ListFilter{Limit: 50, APIKeyID: 3},
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Links of user",
+			query:    "?owner=user:7",
+			apiKeyID: 3,
+			filter:   &storage.ListFilter{Limit: 50, UserID: 7},
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Own user links",
+			query:    "?owner=me",
+			userID:   7,
+			filter:   &storage.ListFilter{Limit: 50, UserID: 7},
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Links of other user",
+			query:     "?owner=user:8",
+			userID:    7,
+			respCode:  http.StatusBadRequest,
+			respError: "users may list only their own links",
+		},
+		{
+			name:      "Invalid owner",
+			query:     "?owner=team:1",
+			respCode:  http.StatusBadRequest,
+			respError: `owner must be "me", "user:<id>" or "key:<id>"`,
+		},
+		{
+			name:      "Invalid tag",
+			query:     "?tag=a%20b",
+			respCode:  http.StatusBadRequest,
+			respError: "tag may contain only letters, digits, '-' and '_'",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlListerMock := mocks.NewURLLister(t)
+
+			if tc.filter != nil {
+				urlListerMock.On("ListURLs", mock.Anything, *tc.filter).
+					Return(tc.mockURLs, tc.mockNext, nil).
+					Once()
+			}
+
+			handler := list.New(slogdiscard.NewDiscardLogger(), urlListerMock)
+
+			req, err := http.NewRequest(http.MethodGet, "/url"+tc.query, nil)
+			require.NoError(t, err)
+			if tc.userID != 0 {
+				req = req.WithContext(jwt.WithUserID(req.Context(), tc.userID))
+			}
+			if tc.apiKeyID != 0 {
+				req = req.WithContext(apikey.WithKeyID(req.Context(), tc.apiKeyID))
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp list.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+			require.Len(t, resp.URLs, tc.respLength)
+			require.Equal(t, tc.mockNext, resp.NextCursor)
+		})
+	}
+}
+
+func TestListHandlerNotModified(t *testing.T) {
+	urlListerMock := mocks.NewURLLister(t)
+
+	urlListerMock.On("ListURLs", mock.Anything, mock.Anything).
+		Return([]storage.URL{{Alias: "a", URL: "https://a.example"}}, "", nil).
+		Twice()
+
+	handler := list.New(slogdiscard.NewDiscardLogger(), urlListerMock)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	tag := rr.Header().Get("ETag")
+	require.NotEmpty(t, tag)
+
+	req := httptest.NewRequest(http.MethodGet, "/url", nil)
+	req.Header.Set("If-None-Match", tag)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusNotModified, rr.Code)
+	require.Empty(t, rr.Body.String())
+}
+
+func TestListHandlerPage(t *testing.T) {
+	urlListerMock := mocks.NewURLLister(t)
+
+	urlListerMock.On("ListURLs", mock.Anything, mock.Anything).
+		Return([]storage.URL{
+			{Alias: "a", URL: "https://a.example", Page: storage.PageMetadata{Title: "A", Image: "https://a.example/og.png"}},
+			{Alias: "b", URL: "https://b.example"},
+		}, "", nil).
+		Once()
+
+	handler := list.New(slogdiscard.NewDiscardLogger(), urlListerMock)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	// Ссылки без загруженных метаданных идут без поля page
+	require.Contains(t, rr.Body.String(), `"page":{"title":"A","image":"https://a.example/og.png"}`)
+	require.Equal(t, 1, strings.Count(rr.Body.String(), `"page"`))
+}
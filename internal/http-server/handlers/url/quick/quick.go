@@ -0,0 +1,127 @@
+package quick
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/errpage"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/shortener"
+)
+
+// FormatJSON selects the JSON response in the format query parameter.
+const FormatJSON = "json"
+
+type Response struct {
+	resp.Response
+	Alias    string `json:"alias,omitempty"`
+	ShortURL string `json:"short_url,omitempty"`
+}
+
+// Shortener is an interface for shortening urls on behalf of the client, see package shortener.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=Shortener
+type Shortener interface {
+	Shorten(ctx context.Context, account shortener.Account, rawURL string) (string, error)
+}
+
+// New returns handler of GET /api/shorten?url= creating a link with a generated alias
+// in one request, for bookmarklets and browser extensions. The short url is returned
+// as plain text, or as JSON with format=json or "Accept: application/json". Short urls
+// start with baseURL, or with the scheme and host of the request if it is empty.
+func New(log *slog.Logger, urlShortener Shortener, baseURL string) http.HandlerFunc {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.quick.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		asJSON := r.URL.Query().Get("format") == FormatJSON || errpage.WantsJSON(r)
+		w.Header().Add("Vary", "Accept")
+
+		rawURL := r.URL.Query().Get("url")
+		if rawURL == "" {
+			log.Info("url is empty")
+
+			fail(w, r, asJSON, http.StatusBadRequest, resp.CodeBadRequest, "url is required")
+
+			return
+		}
+
+		alias, err := urlShortener.Shorten(r.Context(), shortener.FromContext(r.Context()), rawURL)
+		if errors.Is(err, shortener.ErrRejected) {
+			log.Info("url rejected", slog.String("url", rawURL), sl.Err(err))
+
+			fail(w, r, asJSON, http.StatusBadRequest, resp.CodeBadRequest,
+				strings.TrimPrefix(err.Error(), shortener.ErrRejected.Error()+": "))
+
+			return
+		}
+		if errors.Is(err, shortener.ErrForbidden) {
+			log.Info("role does not allow creating links")
+
+			fail(w, r, asJSON, http.StatusForbidden, resp.CodeForbidden, "forbidden")
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to add url", sl.Err(err))
+
+			fail(w, r, asJSON, http.StatusInternalServerError, resp.CodeInternal, "failed to add url")
+
+			return
+		}
+
+		log.Info("url added", slog.String("alias", alias))
+
+		shortURL := shortURLPrefix(r, baseURL) + "/" + alias
+
+		render.Status(r, http.StatusCreated)
+
+		if asJSON {
+			render.JSON(w, r, Response{Response: resp.OK(), Alias: alias, ShortURL: shortURL})
+
+			return
+		}
+
+		render.PlainText(w, r, shortURL)
+	}
+}
+
+// shortURLPrefix returns baseURL or, if it is empty, the scheme and host of the request.
+func shortURLPrefix(r *http.Request, baseURL string) string {
+	if baseURL != "" {
+		return baseURL
+	}
+
+	scheme := "http"
+	// За балансировщиком TLS завершается до сервиса
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host
+}
+
+func fail(w http.ResponseWriter, r *http.Request, asJSON bool, status int, code, message string) {
+	render.Status(r, status)
+
+	if asJSON {
+		render.JSON(w, r, resp.Error(r, code, message))
+
+		return
+	}
+
+	render.PlainText(w, r, message)
+}
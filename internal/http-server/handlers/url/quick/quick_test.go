@@ -0,0 +1,138 @@
+package quick_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/quick"
+	"url-shortener/internal/http-server/handlers/url/quick/mocks"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/shortener"
+)
+
+func TestQuickHandler(t *testing.T) {
+	cases := []struct {
+		name        string
+		query       string
+		accept      string
+		baseURL     string
+		shortenCall bool
+		shortenErr  error
+		respCode    int
+		body        string
+		// shortURL is checked in JSON responses instead of body.
+		shortURL string
+		respErr  string
+	}{
+		{
+			name:        "Plain text",
+			query:       "?url=https%3A%2F%2Fexample.com",
+			shortenCall: true,
+			respCode:    http.StatusCreated,
+			body:        "http://sho.rt/abc123",
+		},
+		{
+			name:        "Base URL",
+			query:       "?url=https%3A%2F%2Fexample.com",
+			baseURL:     "https://s.example/",
+			shortenCall: true,
+			respCode:    http.StatusCreated,
+			body:        "https://s.example/abc123",
+		},
+		{
+			name:        "JSON by format",
+			query:       "?url=https%3A%2F%2Fexample.com&format=json",
+			shortenCall: true,
+			respCode:    http.StatusCreated,
+			shortURL:    "http://sho.rt/abc123",
+		},
+		{
+			name:        "JSON by Accept",
+			query:       "?url=https%3A%2F%2Fexample.com",
+			accept:      "application/json",
+			shortenCall: true,
+			respCode:    http.StatusCreated,
+			shortURL:    "http://sho.rt/abc123",
+		},
+		{
+			name:     "Missing url",
+			respCode: http.StatusBadRequest,
+			body:     "url is required",
+		},
+		{
+			name:        "Rejected",
+			query:       "?url=https%3A%2F%2Fexample.com&format=json",
+			shortenCall: true,
+			shortenErr:  fmt.Errorf("%w: url is flagged as malicious: phishing", shortener.ErrRejected),
+			respCode:    http.StatusBadRequest,
+			respErr:     "url is flagged as malicious: phishing",
+		},
+		{
+			name:        "Forbidden",
+			query:       "?url=https%3A%2F%2Fexample.com",
+			shortenCall: true,
+			shortenErr:  shortener.ErrForbidden,
+			respCode:    http.StatusForbidden,
+			body:        "forbidden",
+		},
+		{
+			name:        "Storage error",
+			query:       "?url=https%3A%2F%2Fexample.com",
+			shortenCall: true,
+			shortenErr:  errors.New("unexpected error"),
+			respCode:    http.StatusInternalServerError,
+			body:        "failed to add url",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			shortenerMock := mocks.NewShortener(t)
+
+			if tc.shortenCall {
+				// Ссылка создается от имени ключа запроса в его тенанте
+				shortenerMock.On("Shorten", mock.Anything,
+					shortener.Account{APIKeyID: 7, Tenant: "brand", Role: "editor"}, "https://example.com").
+					Return("abc123", tc.shortenErr).Once()
+			}
+
+			handler := quick.New(slogdiscard.NewDiscardLogger(), shortenerMock, tc.baseURL)
+
+			req := httptest.NewRequest(http.MethodGet, "http://sho.rt/api/shorten"+tc.query, nil)
+			req = req.WithContext(tenant.WithTenant(apikey.WithKeyID(req.Context(), 7), "brand"))
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			if tc.body != "" {
+				require.Equal(t, tc.body, rr.Body.String())
+
+				return
+			}
+
+			var res quick.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+
+			require.Equal(t, tc.shortURL, res.ShortURL)
+			require.Equal(t, tc.respErr, res.Error.Error())
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package restore_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/restore"
+	"url-shortener/internal/http-server/handlers/url/restore/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestRestoreHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		alias     string
+		respCode  int
+		respError string
+		mockError error
+	}{
+		{
+			name:     "Success",
+			alias:    "test_alias",
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Not deleted",
+			alias:     "missing_alias",
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+			mockError: storage.ErrURLNotFound,
+		},
+		{
+			name:      "RestoreURL Error",
+			alias:     "test_alias",
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlRestorerMock := mocks.NewURLRestorer(t)
+
+			urlRestorerMock.On("RestoreURL", mock.Anything, tc.alias).
+				Return(tc.mockError).
+				Once()
+
+			r := chi.NewRouter()
+			r.Post("/admin/urls/{alias}/restore", restore.New(slogdiscard.NewDiscardLogger(), urlRestorerMock))
+
+			req, err := http.NewRequest(http.MethodPost, "/admin/urls/"+tc.alias+"/restore", nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			require.Equal(t, tc.respError, body.Error.Error())
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package save
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/domains"
+	"url-shortener/internal/lib/api/request"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/querytpl"
+	"url-shortener/internal/shortener"
+	"url-shortener/internal/storage"
+)
+
+// BatchResult is the result of a single link of the batch, in the order of the request.
+type BatchResult struct {
+	URL   string `json:"url"`
+	Alias string `json:"alias,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	resp.Response
+	Results []BatchResult `json:"results,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=BatchCreator
+
+// BatchCreator checks destinations of links and saves them at once, see shortener.Service.
+type BatchCreator interface {
+	CreateBatch(ctx context.Context, account shortener.Account, links []shortener.Link, host string) ([]shortener.Result, error)
+}
+
+// NewBatch saves an array of up to maxItems links at once. Invalid links and taken
+// aliases are reported per link and don't prevent the rest from being saved.
+func NewBatch(log *slog.Logger, creator BatchCreator, maxItems int, registry *domains.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.save.NewBatch"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var reqs []Request
+
+		err := request.DecodeJSON(r.Body, &reqs)
+		if errors.Is(err, io.EOF) || err == nil && len(reqs) == 0 {
+			log.Error("request body is empty")
+
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "empty request"))
+
+			return
+		}
+		if errors.Is(err, request.ErrTooLarge) {
+			log.Info("request body is too large", sl.Err(err))
+
+			render.Status(r, http.StatusRequestEntityTooLarge)
+			render.JSON(w, r, resp.Error(r, resp.CodeTooLarge, err.Error()))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to decode request: "+err.Error()))
+
+			return
+		}
+
+		if len(reqs) > maxItems {
+			log.Info("too many urls", slog.Int("count", len(reqs)))
+
+			render.Status(r, http.StatusRequestEntityTooLarge)
+			render.JSON(w, r, resp.Error(r, resp.CodeTooLarge, fmt.Sprintf("too many urls, max %d", maxItems)))
+
+			return
+		}
+
+		results := make([]BatchResult, len(reqs))
+		links := make([]shortener.Link, 0, len(reqs))
+		// positions[i] - индекс в results ссылки links[i]
+		positions := make([]int, 0, len(reqs))
+
+		validate := validator.New()
+		now := time.Now()
+
+		for i, req := range reqs {
+			results[i].URL = req.URL
+
+			if err := validate.Struct(req); err != nil {
+				results[i].Error = resp.ValidationError(r, err.(validator.ValidationErrors)).Error.Message
+
+				continue
+			}
+
+			geoTargets, err := normalizeGeoTargets(req.GeoTargets)
+			if err != nil {
+				results[i].Error = err.Error()
+
+				continue
+			}
+
+			deviceTargets, err := normalizeDeviceTargets(req.DeviceTargets)
+			if err != nil {
+				results[i].Error = err.Error()
+
+				continue
+			}
+
+			split, err := normalizeSplit(req.Split)
+			if err != nil {
+				results[i].Error = err.Error()
+
+				continue
+			}
+
+			if err := querytpl.Validate(req.QueryParams); err != nil {
+				results[i].Error = "invalid query_params: " + err.Error()
+
+				continue
+			}
+
+			tags, err := normalizeTags(req.Tags)
+			if err != nil {
+				results[i].Error = err.Error()
+
+				continue
+			}
+
+			req.Domain, err = normalizeDomain(r.Context(), registry, req.Domain)
+			if errors.Is(err, errUnknownDomain) {
+				results[i].Error = err.Error()
+
+				continue
+			}
+			if err != nil {
+				log.Error("failed to check domain", sl.Err(err))
+
+				results[i].Error = "failed to add url"
+
+				continue
+			}
+
+			expiresAt, err := expiration(req, now)
+			if err != nil {
+				results[i].Error = err.Error()
+
+				continue
+			}
+
+			links = append(links, shortener.Link{
+				URL: storage.URL{
+					URL:              req.URL,
+					ExpiresAt:        expiresAt,
+					RedirectCode:     req.RedirectCode,
+					MaxClicks:        req.MaxClicks,
+					BurnAfterReading: req.BurnAfterReading,
+					WebhookURL:       req.WebhookURL,
+					Domain:           req.Domain,
+					GeoTargets:       geoTargets,
+					DeviceTargets:    deviceTargets,
+					Split:            split,
+					QueryParams:      req.QueryParams,
+					PassQuery:        req.PassQuery,
+					NoAnalytics:      req.NoAnalytics,
+					Tags:             tags,
+					Description:      req.Description,
+				},
+				Alias:  req.Alias,
+				Dedupe: req.Dedupe,
+			})
+			positions = append(positions, i)
+		}
+
+		saved := 0
+
+		if len(links) > 0 {
+			created, err := creator.CreateBatch(r.Context(), shortener.FromContext(r.Context()), links, r.Host)
+			if errors.Is(err, shortener.ErrForbidden) {
+				log.Info("role does not allow creating links")
+
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.Error(r, resp.CodeForbidden, "forbidden"))
+
+				return
+			}
+			if err != nil {
+				log.Error("failed to add urls", sl.Err(err))
+
+				render.JSON(w, r, resp.Error(r, resp.CodeInternal, "failed to add urls"))
+
+				return
+			}
+
+			for i, res := range created {
+				pos := positions[i]
+
+				switch {
+				case res.Err == nil:
+					results[pos].Alias = res.Alias
+					saved++
+				case errors.Is(res.Err, shortener.ErrRejected):
+					results[pos].Error = rejection(res.Err)
+				case errors.Is(res.Err, storage.ErrURLExists):
+					results[pos].Error = "url already exists"
+				default:
+					log.Error("failed to add url", slog.String("url", results[pos].URL), sl.Err(res.Err))
+
+					results[pos].Error = "failed to add url"
+				}
+			}
+		}
+
+		log.Info("urls added", slog.Int("count", saved))
+
+		render.JSON(w, r, BatchResponse{
+			Response: resp.OK(),
+			Results:  results,
+		})
+	}
+}
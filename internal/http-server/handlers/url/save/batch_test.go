@@ -0,0 +1,158 @@
+package save_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/acl"
+	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/shortener"
+	"url-shortener/internal/storage"
+)
+
+func TestBatchHandler(t *testing.T) {
+	creatorMock := mocks.NewBatchCreator(t)
+	creatorMock.On("CreateBatch", mock.Anything, shortener.Account{Role: acl.RoleEditor}, mock.MatchedBy(func(links []shortener.Link) bool {
+		return len(links) == 5 && links[0].Alias == "first" && links[1].Alias == "taken" &&
+			links[2].Alias == "" && !links[2].URL.ExpiresAt.IsZero() && links[3].Alias == "flagged"
+	}), "example.com").
+		Return([]shortener.Result{
+			{Alias: "first"},
+			{Err: storage.ErrURLExists},
+			{Alias: "generated"},
+			{Err: fmt.Errorf("%w: url is flagged as malicious: blocklist", shortener.ErrRejected)},
+			{Err: errors.New("unexpected error")},
+		}, nil).
+		Once()
+
+	handler := save.NewBatch(slogdiscard.NewDiscardLogger(), creatorMock, 10, registry)
+
+	input := `[
+		{"url": "https://google.com", "alias": "first"},
+		{"url": "invalid url", "alias": "second"},
+		{"url": "https://google.com", "alias": "taken"},
+		{"url": "https://google.com", "ttl": "1h"},
+		{"url": "https://google.com", "ttl": "-1h"},
+		{"url": "https://phish.example/login", "alias": "flagged"},
+		{"url": "https://google.com", "geo_targets": {"Europe": "https://google.com/eu"}},
+		{"url": "https://google.com", "alias": "broken"}
+	]`
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "http://example.com/url/batch", bytes.NewReader([]byte(input))))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp save.BatchResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Len(t, resp.Results, 8)
+
+	require.Equal(t, save.BatchResult{URL: "https://google.com", Alias: "first"}, resp.Results[0])
+	require.Equal(t, "field URL is not a valid URL", resp.Results[1].Error)
+	require.Equal(t, "url already exists", resp.Results[2].Error)
+	require.Equal(t, save.BatchResult{URL: "https://google.com", Alias: "generated"}, resp.Results[3])
+	require.Equal(t, "ttl must be a positive duration, e.g. 24h", resp.Results[4].Error)
+	require.Equal(t, save.BatchResult{
+		URL:   "https://phish.example/login",
+		Error: "url is flagged as malicious: blocklist",
+	}, resp.Results[5])
+	require.Equal(t, `invalid geo_targets: unknown location code "EUROPE"`, resp.Results[6].Error)
+	require.Equal(t, save.BatchResult{URL: "https://google.com", Error: "failed to add url"}, resp.Results[7])
+}
+
+func TestBatchHandler_Errors(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     string
+		respError string
+		respCode  int
+		mockError error
+		// maxBody limits the body like the bodylimit middleware, zero means no limit
+		maxBody int64
+	}{
+		{
+			name:      "Empty body",
+			input:     "",
+			respError: "empty request",
+		},
+		{
+			name:      "Empty array",
+			input:     "[]",
+			respError: "empty request",
+		},
+		{
+			name:      "Unknown field",
+			input:     `[{"url": "https://a.com", "alais": "a"}]`,
+			respError: `failed to decode request: json: unknown field "alais"`,
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Body too large",
+			input:     `[{"url": "https://a.com"}]`,
+			respError: "request body is too large: limit is 8 bytes",
+			respCode:  http.StatusRequestEntityTooLarge,
+			maxBody:   8,
+		},
+		{
+			name:      "Too many urls",
+			input:     `[{"url": "https://a.com"}, {"url": "https://b.com"}, {"url": "https://c.com"}]`,
+			respError: "too many urls, max 2",
+			respCode:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:      "Forbidden",
+			input:     `[{"url": "https://a.com"}]`,
+			respError: "forbidden",
+			respCode:  http.StatusForbidden,
+			mockError: shortener.ErrForbidden,
+		},
+		{
+			name:      "CreateBatch Error",
+			input:     `[{"url": "https://a.com"}]`,
+			respError: "failed to add urls",
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			creatorMock := mocks.NewBatchCreator(t)
+			if tc.mockError != nil {
+				creatorMock.On("CreateBatch", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, tc.mockError).Once()
+			}
+
+			handler := save.NewBatch(slogdiscard.NewDiscardLogger(), creatorMock, 2, registry)
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/url/batch", bytes.NewReader([]byte(tc.input)))
+			if tc.maxBody > 0 {
+				req.Body = http.MaxBytesReader(rr, req.Body, tc.maxBody)
+			}
+			handler.ServeHTTP(rr, req)
+
+			respCode := tc.respCode
+			if respCode == 0 {
+				respCode = http.StatusOK
+			}
+			require.Equal(t, respCode, rr.Code)
+
+			var resp save.BatchResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+		})
+	}
+}
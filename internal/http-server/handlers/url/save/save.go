@@ -1,24 +1,85 @@
 package save
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
 	"golang.org/x/exp/slog"
 
+	"url-shortener/internal/domains"
+	"url-shortener/internal/lib/aliascheck"
+	"url-shortener/internal/lib/api/request"
 	resp "url-shortener/internal/lib/api/response"
 	"url-shortener/internal/lib/logger/sl"
-	"url-shortener/internal/lib/random"
+	"url-shortener/internal/lib/querytpl"
+	"url-shortener/internal/lib/uadetect"
+	"url-shortener/internal/shortener"
 	"url-shortener/internal/storage"
 )
 
 type Request struct {
 	URL   string `json:"url" validate:"required,url"`
 	Alias string `json:"alias,omitempty"`
+	// ExpiresAt and TTL (e.g. "24h") are mutually exclusive ways to set link expiration.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	TTL       string     `json:"ttl,omitempty"`
+	// RedirectCode overrides the default redirect status of the link.
+	RedirectCode int `json:"redirect_code,omitempty" validate:"omitempty,oneof=301 302 307 308"`
+	// MaxClicks makes the link exhausted after that many redirects, e.g. 1 for one-time links.
+	MaxClicks int64 `json:"max_clicks,omitempty" validate:"omitempty,min=1"`
+	// BurnAfterReading deletes the link on its first redirect; it can't be combined with MaxClicks.
+	BurnAfterReading bool `json:"burn_after_reading,omitempty" validate:"excluded_with=MaxClicks"`
+	// WebhookURL receives a POST with the click details on every redirect.
+	WebhookURL string `json:"webhook_url,omitempty" validate:"omitempty,url"`
+	// Domain binds the link to a registered short domain, e.g. "go.brand.example".
+	Domain string `json:"domain,omitempty"`
+	// GeoTargets send visitors to other destinations by their country ("DE") or continent ("EU") code.
+	GeoTargets map[string]string `json:"geo_targets,omitempty"`
+	// DeviceTargets send visitors on "ios", "android" or "desktop" to other destinations,
+	// e.g. app store pages. They take precedence over GeoTargets.
+	DeviceTargets map[string]string `json:"device_targets,omitempty"`
+	// Split divides the rest of visitors between weighted destinations for A/B tests.
+	Split *Split `json:"split,omitempty"`
+	// QueryParams are added to the destination query on every redirect, e.g. utm_source.
+	// Values may contain "{alias}" and "{variant}" placeholders.
+	QueryParams map[string]string `json:"query_params,omitempty"`
+	// PassQuery adds query parameters of the short link request to the destination.
+	PassQuery bool `json:"pass_query,omitempty"`
+	// NoAnalytics keeps clicks of the link out of statistics, only the hit count is kept.
+	NoAnalytics bool `json:"no_analytics,omitempty"`
+	// Tags label the link for search with GET /url?tag=, e.g. "campaign-x". They are
+	// case-insensitive and may contain letters, digits, '-' and '_'.
+	Tags []string `json:"tags,omitempty"`
+	// Description is free text which GET /url?q= searches along with the alias and URL.
+	Description string `json:"description,omitempty" validate:"omitempty,max=1000"`
+	// Dedupe returns the alias of an active link to the same URL saved by the same client
+	// instead of creating a new one. It is ignored when a custom alias is requested.
+	Dedupe bool `json:"dedupe,omitempty"`
+}
+
+// Split describes an A/B test of the link.
+type Split struct {
+	Variants []Variant `json:"variants"`
+	// Sticky keeps a visitor on the same variant: "cookie" remembers it in a cookie,
+	// "ip" derives it from the visitor address. Empty picks a variant on every redirect.
+	Sticky string `json:"sticky,omitempty"`
+}
+
+type Variant struct {
+	// Name is reported in click stats; it defaults to "A", "B" and so on.
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url"`
+	// Weight is the share of visitors relative to other variants, 1 by default.
+	Weight int `json:"weight,omitempty"`
 }
 
 type Response struct {
@@ -26,9 +87,6 @@ type Response struct {
 	Alias string `json:"alias,omitempty"`
 }
 
-// TODO: move to config if needed
-const aliasLength = 6
-
 // // вызов другой библиотеки генерации моков
 //go::generate mockgen -source=save.go -destination=mocks/URLSaver.go
 
@@ -40,13 +98,16 @@ const aliasLength = 6
 // // docker run -v ${PWD}:/src -w /src vektra/mockery:3
 // // docker run -v ${PWD}:/src -w /src vektra/mockery --all
 
-//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLSaver
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=Creator
 
-type URLSaver interface {
-	SaveURL(urlToSave string, alias string) (int64, error)
+// Creator checks destinations of links and saves them, see shortener.Service.
+type Creator interface {
+	Create(ctx context.Context, account shortener.Account, link shortener.Link, host string) (string, error)
 }
 
-func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
+// New saves a link. The request is validated here, destinations are checked and the
+// alias is chosen by the creator, like for links shortened by chat bots.
+func New(log *slog.Logger, creator Creator, registry *domains.Registry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.save.New"
 
@@ -57,20 +118,29 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 
 		var req Request
 
-		err := render.DecodeJSON(r.Body, &req)
+		err := request.DecodeJSON(r.Body, &req)
 		if errors.Is(err, io.EOF) {
 			// Такую ошибку встретим, если получили запрос с пустым телом.
 			// Обработаем её отдельно
 			log.Error("request body is empty")
 
-			render.JSON(w, r, resp.Error("empty request"))
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "empty request"))
+
+			return
+		}
+		if errors.Is(err, request.ErrTooLarge) {
+			log.Info("request body is too large", sl.Err(err))
+
+			render.Status(r, http.StatusRequestEntityTooLarge)
+			render.JSON(w, r, resp.Error(r, resp.CodeTooLarge, err.Error()))
 
 			return
 		}
 		if err != nil {
 			log.Error("failed to decode request body", sl.Err(err))
 
-			render.JSON(w, r, resp.Error("failed to decode request"))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to decode request: "+err.Error()))
 
 			return
 		}
@@ -82,38 +152,317 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 
 			log.Error("invalid request", sl.Err(err))
 
-			render.JSON(w, r, resp.ValidationError(validateErr))
+			render.JSON(w, r, resp.ValidationError(r, validateErr))
+
+			return
+		}
+
+		geoTargets, err := normalizeGeoTargets(req.GeoTargets)
+		if err != nil {
+			log.Info("geo targets rejected", sl.Err(err))
+
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
+
+			return
+		}
+
+		deviceTargets, err := normalizeDeviceTargets(req.DeviceTargets)
+		if err != nil {
+			log.Info("device targets rejected", sl.Err(err))
+
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
+
+			return
+		}
+
+		split, err := normalizeSplit(req.Split)
+		if err != nil {
+			log.Info("split rejected", sl.Err(err))
+
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
+
+			return
+		}
+
+		if err := querytpl.Validate(req.QueryParams); err != nil {
+			log.Info("query params rejected", sl.Err(err))
+
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid query_params: "+err.Error()))
+
+			return
+		}
+
+		tags, err := normalizeTags(req.Tags)
+		if err != nil {
+			log.Info("tags rejected", sl.Err(err))
+
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
 
 			return
 		}
 
-		alias := req.Alias
-		if alias == "" {
-			alias = random.NewRandomString(aliasLength)
+		req.Domain, err = normalizeDomain(r.Context(), registry, req.Domain)
+		if errors.Is(err, errUnknownDomain) {
+			log.Info("unknown domain", slog.String("domain", req.Domain))
+
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
+
+			return
 		}
+		if err != nil {
+			log.Error("failed to check domain", sl.Err(err))
 
-		id, err := urlSaver.SaveURL(req.URL, alias)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "failed to add url"))
+
+			return
+		}
+
+		expiresAt, err := expiration(req, time.Now())
+		if err != nil {
+			log.Info("invalid expiration", sl.Err(err))
+
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
+
+			return
+		}
+
+		u := storage.URL{
+			URL:              req.URL,
+			ExpiresAt:        expiresAt,
+			RedirectCode:     req.RedirectCode,
+			MaxClicks:        req.MaxClicks,
+			BurnAfterReading: req.BurnAfterReading,
+			WebhookURL:       req.WebhookURL,
+			Domain:           req.Domain,
+			GeoTargets:       geoTargets,
+			DeviceTargets:    deviceTargets,
+			Split:            split,
+			QueryParams:      req.QueryParams,
+			PassQuery:        req.PassQuery,
+			NoAnalytics:      req.NoAnalytics,
+			Tags:             tags,
+			Description:      req.Description,
+		}
+
+		link := shortener.Link{URL: u, Alias: req.Alias, Dedupe: req.Dedupe}
+
+		alias, err := creator.Create(r.Context(), shortener.FromContext(r.Context()), link, r.Host)
+		if errors.Is(err, shortener.ErrRejected) {
+			log.Info("url rejected", slog.String("url", req.URL), sl.Err(err))
+
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, rejection(err)))
+
+			return
+		}
+		if errors.Is(err, shortener.ErrForbidden) {
+			log.Info("role does not allow creating links")
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error(r, resp.CodeForbidden, "forbidden"))
+
+			return
+		}
 		if errors.Is(err, storage.ErrURLExists) {
 			log.Info("url already exists", slog.String("url", req.URL))
 
-			render.JSON(w, r, resp.Error("url already exists"))
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, resp.Error(r, resp.CodeConflict, "url already exists"))
 
 			return
 		}
 		if err != nil {
 			log.Error("failed to add url", sl.Err(err))
 
-			render.JSON(w, r, resp.Error("failed to add url"))
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "failed to add url"))
 
 			return
 		}
 
-		log.Info("url added", slog.Int64("id", id))
+		log.Info("url added", slog.String("alias", alias))
 
 		responseOK(w, r, alias)
 	}
 }
 
+// rejection returns the reason the creator rejected the link, meant for the client.
+func rejection(err error) string {
+	return strings.TrimPrefix(err.Error(), shortener.ErrRejected.Error()+": ")
+}
+
+// geoCodeLength is the length of ISO 3166-1 alpha-2 country codes and MaxMind continent codes.
+const geoCodeLength = 2
+
+// normalizeGeoTargets upper-cases location codes; destinations are checked by the creator.
+func normalizeGeoTargets(targets map[string]string) (storage.GeoTargets, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	normalized := make(storage.GeoTargets, len(targets))
+	for code, target := range targets {
+		code = strings.ToUpper(code)
+		if len(code) != geoCodeLength || strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return nil, fmt.Errorf("invalid geo_targets: unknown location code %q", code)
+		}
+
+		normalized[code] = target
+	}
+
+	return normalized, nil
+}
+
+// normalizeDeviceTargets lower-cases platforms; destinations are checked by the creator.
+func normalizeDeviceTargets(targets map[string]string) (storage.DeviceTargets, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	normalized := make(storage.DeviceTargets, len(targets))
+	for platform, target := range targets {
+		platform = strings.ToLower(platform)
+		if !uadetect.Valid(uadetect.Platform(platform)) {
+			return nil, fmt.Errorf("invalid device_targets: unknown platform %q", platform)
+		}
+
+		normalized[platform] = target
+	}
+
+	return normalized, nil
+}
+
+const (
+	maxVariants      = 10
+	maxVariantName   = 32
+	maxVariantWeight = 1000
+)
+
+// normalizeSplit names and weighs variants by default; destinations are checked by
+// the creator.
+func normalizeSplit(split *Split) (storage.Split, error) {
+	if split == nil || len(split.Variants) == 0 {
+		return storage.Split{}, nil
+	}
+
+	if len(split.Variants) < 2 || len(split.Variants) > maxVariants {
+		return storage.Split{}, fmt.Errorf("invalid split: from 2 to %d variants are allowed", maxVariants)
+	}
+
+	switch split.Sticky {
+	case "", storage.StickyCookie, storage.StickyIP:
+	default:
+		return storage.Split{}, fmt.Errorf("invalid split: unknown sticky mode %q", split.Sticky)
+	}
+
+	normalized := storage.Split{Variants: make([]storage.Variant, 0, len(split.Variants)), Sticky: split.Sticky}
+	names := make(map[string]bool, len(split.Variants))
+
+	for i, v := range split.Variants {
+		name := v.Name
+		if name == "" {
+			name = string(rune('A' + i))
+		}
+		// Имя варианта попадает в cookie, поэтому набор символов ограничен
+		if len(name) > maxVariantName || !aliascheck.HasValidCharset(name) {
+			return storage.Split{}, fmt.Errorf("invalid split: invalid variant name %q", name)
+		}
+		if names[name] {
+			return storage.Split{}, fmt.Errorf("invalid split: duplicate variant name %q", name)
+		}
+		names[name] = true
+
+		weight := v.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		if weight < 1 || weight > maxVariantWeight {
+			return storage.Split{}, fmt.Errorf("invalid split: %s: weight must be from 1 to %d", name, maxVariantWeight)
+		}
+
+		normalized.Variants = append(normalized.Variants, storage.Variant{Name: name, URL: v.URL, Weight: weight})
+	}
+
+	return normalized, nil
+}
+
+const (
+	maxTags   = 20
+	maxTagLen = 50
+)
+
+// normalizeTags returns lower-cased tags without duplicates.
+func normalizeTags(tags []string) (storage.Tags, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	if len(tags) > maxTags {
+		return nil, fmt.Errorf("too many tags, at most %d allowed", maxTags)
+	}
+
+	normalized := make(storage.Tags, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || len(tag) > maxTagLen || !aliascheck.HasValidCharset(tag) {
+			return nil, fmt.Errorf("invalid tag %q: must be 1 to %d letters, digits, '-' or '_'", tag, maxTagLen)
+		}
+
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+
+	return normalized, nil
+}
+
+var errUnknownDomain = errors.New("domain is not registered")
+
+// normalizeDomain returns the domain in canonical form, errUnknownDomain if it is not registered.
+func normalizeDomain(ctx context.Context, registry *domains.Registry, domain string) (string, error) {
+	if domain == "" {
+		return "", nil
+	}
+
+	domain = domains.Normalize(domain)
+
+	ok, err := registry.Exists(ctx, domain)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errUnknownDomain
+	}
+
+	return domain, nil
+}
+
+var (
+	errExpirationConflict = errors.New("only one of expires_at and ttl can be set")
+	errInvalidTTL         = errors.New("ttl must be a positive duration, e.g. 24h")
+	errExpiresInPast      = errors.New("expires_at must be in the future")
+)
+
+// expiration returns the moment the link expires, or zero time if it never does.
+func expiration(req Request, now time.Time) (time.Time, error) {
+	switch {
+	case req.ExpiresAt != nil && req.TTL != "":
+		return time.Time{}, errExpirationConflict
+	case req.TTL != "":
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			return time.Time{}, errInvalidTTL
+		}
+
+		return now.Add(ttl), nil
+	case req.ExpiresAt != nil:
+		if !req.ExpiresAt.After(now) {
+			return time.Time{}, errExpiresInPast
+		}
+
+		return *req.ExpiresAt, nil
+	default:
+		return time.Time{}, nil
+	}
+}
+
 func responseOK(w http.ResponseWriter, r *http.Request, alias string) {
 	render.JSON(w, r, Response{
 		Response: resp.OK(),
@@ -7,22 +7,37 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"url-shortener/internal/acl"
+	"url-shortener/internal/domains"
 	"url-shortener/internal/http-server/handlers/url/save"
 	"url-shortener/internal/http-server/handlers/url/save/mocks"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/shortener"
+	"url-shortener/internal/storage"
 )
 
+var registry = domains.NewRegistry([]string{"go.example"}, nil)
+
 func TestSaveHandler(t *testing.T) {
 	cases := []struct {
 		name      string
 		alias     string
 		url       string
+		ttl       string
+		domain    string
+		geo       string
+		devices   string
+		split     string
+		query     string
+		tags      string
 		respError string
+		respCode  int
 		mockError error
 	}{
 		{
@@ -48,12 +63,138 @@ func TestSaveHandler(t *testing.T) {
 			respError: "field URL is not a valid URL",
 		},
 		{
-			name:      "SaveURL Error",
+			name:      "Rejected",
+			url:       "https://google.com",
+			respError: "url is flagged as malicious: blocklist",
+			mockError: fmt.Errorf("%w: url is flagged as malicious: blocklist", shortener.ErrRejected),
+		},
+		{
+			name:      "Forbidden",
+			url:       "https://google.com",
+			respError: "forbidden",
+			respCode:  http.StatusForbidden,
+			mockError: shortener.ErrForbidden,
+		},
+		{
+			name:      "Create Error",
 			alias:     "test_alias",
 			url:       "https://google.com",
 			respError: "failed to add url",
 			mockError: errors.New("unexpected error"),
 		},
+		{
+			name:      "Alias exists",
+			alias:     "test_alias",
+			url:       "https://google.com",
+			respError: "url already exists",
+			respCode:  http.StatusConflict,
+			mockError: storage.ErrURLExists,
+		},
+		{
+			name:  "With TTL",
+			alias: "ttl_alias",
+			url:   "https://google.com",
+			ttl:   "24h",
+		},
+		{
+			name:      "Invalid TTL",
+			alias:     "ttl_alias",
+			url:       "https://google.com",
+			ttl:       "-1h",
+			respError: "ttl must be a positive duration, e.g. 24h",
+		},
+		{
+			name:   "Registered domain",
+			alias:  "domain_alias",
+			url:    "https://google.com",
+			domain: "Go.Example",
+		},
+		{
+			name:      "Unknown domain",
+			alias:     "domain_alias",
+			url:       "https://google.com",
+			domain:    "other.example",
+			respError: "domain is not registered",
+		},
+		{
+			name:  "Geo targets",
+			alias: "geo_alias",
+			url:   "https://google.com",
+			geo:   `{"us": "https://google.com/us", "EU": "https://google.com/eu"}`,
+		},
+		{
+			name:      "Invalid geo code",
+			alias:     "geo_alias",
+			url:       "https://google.com",
+			geo:       `{"Europe": "https://google.com/eu"}`,
+			respError: `invalid geo_targets: unknown location code "EUROPE"`,
+		},
+		{
+			name:    "Device targets",
+			alias:   "device_alias",
+			url:     "https://google.com",
+			devices: `{"iOS": "https://apps.apple.com/app/id1"}`,
+		},
+		{
+			name:      "Unknown platform",
+			alias:     "device_alias",
+			url:       "https://google.com",
+			devices:   `{"windows phone": "https://google.com/wp"}`,
+			respError: `invalid device_targets: unknown platform "windows phone"`,
+		},
+		{
+			name:  "Split",
+			alias: "split_alias",
+			url:   "https://google.com",
+			split: `{"variants": [{"url": "https://google.com/a"}, {"url": "https://google.com/b", "weight": 3}], "sticky": "cookie"}`,
+		},
+		{
+			name:      "Split with one variant",
+			alias:     "split_alias",
+			url:       "https://google.com",
+			split:     `{"variants": [{"url": "https://google.com/a"}]}`,
+			respError: "invalid split: from 2 to 10 variants are allowed",
+		},
+		{
+			name:      "Split with duplicate names",
+			alias:     "split_alias",
+			url:       "https://google.com",
+			split:     `{"variants": [{"name": "x", "url": "https://google.com/a"}, {"name": "x", "url": "https://google.com/b"}]}`,
+			respError: `invalid split: duplicate variant name "x"`,
+		},
+		{
+			name:      "Split with unknown sticky mode",
+			alias:     "split_alias",
+			url:       "https://google.com",
+			split:     `{"variants": [{"url": "https://google.com/a"}, {"url": "https://google.com/b"}], "sticky": "session"}`,
+			respError: `invalid split: unknown sticky mode "session"`,
+		},
+		{
+			name:  "Query params",
+			alias: "utm_alias",
+			url:   "https://google.com",
+			query: `{"utm_source": "shortener", "utm_content": "{variant}"}`,
+		},
+		{
+			name:      "Unknown query placeholder",
+			alias:     "utm_alias",
+			url:       "https://google.com",
+			query:     `{"utm_campaign": "{country}"}`,
+			respError: `invalid query_params: "utm_campaign": unknown placeholder {country}`,
+		},
+		{
+			name:  "Tags",
+			alias: "tagged_alias",
+			url:   "https://google.com",
+			tags:  `["Campaign-X", "docs", " campaign-x"]`,
+		},
+		{
+			name:      "Invalid tag",
+			alias:     "tagged_alias",
+			url:       "https://google.com",
+			tags:      `["campaign x"]`,
+			respError: `invalid tag "campaign x": must be 1 to 50 letters, digits, '-' or '_'`,
+		},
 	}
 
 	for _, tc := range cases {
@@ -62,27 +203,72 @@ func TestSaveHandler(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			urlSaverMock := mocks.NewURLSaver(t)
+			creatorMock := mocks.NewCreator(t)
 
 			if tc.respError == "" || tc.mockError != nil {
-				urlSaverMock.On("SaveURL", tc.url, mock.AnythingOfType("string")).
-					Return(int64(1), tc.mockError).
+				creatorMock.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(link shortener.Link) bool {
+					u := link.URL
+
+					return link.Alias == tc.alias && u.URL == tc.url && u.ExpiresAt.IsZero() == (tc.ttl == "") &&
+						(tc.domain == "" || u.Domain == "go.example") &&
+						(tc.geo == "" || u.GeoTargets["US"] == "https://google.com/us") &&
+						(tc.devices == "" || u.DeviceTargets["ios"] == "https://apps.apple.com/app/id1") &&
+						(tc.split == "" || u.Split.Sticky == storage.StickyCookie && len(u.Split.Variants) == 2 &&
+							u.Split.Variants[0] == storage.Variant{Name: "A", URL: "https://google.com/a", Weight: 1} &&
+							u.Split.Variants[1] == storage.Variant{Name: "B", URL: "https://google.com/b", Weight: 3}) &&
+						(tc.query == "" || u.QueryParams["utm_source"] == "shortener") &&
+						(tc.tags == "" || slices.Equal(u.Tags, storage.Tags{"campaign-x", "docs"}))
+				}), "example.com").
+					Return(tc.alias, tc.mockError).
 					Once()
 			}
 
-			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock)
+			handler := save.New(slogdiscard.NewDiscardLogger(), creatorMock, registry)
+
+			geo := tc.geo
+			if geo == "" {
+				geo = "null"
+			}
+
+			devices := tc.devices
+			if devices == "" {
+				devices = "null"
+			}
+
+			split := tc.split
+			if split == "" {
+				split = "null"
+			}
+
+			query := tc.query
+			if query == "" {
+				query = "null"
+			}
+
+			tags := tc.tags
+			if tags == "" {
+				tags = "null"
+			}
 
-			input := fmt.Sprintf(`{"url": "%s", "alias": "%s"}`, tc.url, tc.alias)
+			input := fmt.Sprintf(
+				`{"url": "%s", "alias": "%s", "ttl": "%s", "domain": "%s", "geo_targets": %s, "device_targets": %s, "split": %s, `+
+					`"query_params": %s, "tags": %s}`,
+				tc.url, tc.alias, tc.ttl, tc.domain, geo, devices, split, query, tags)
 
-			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
+			req, err := http.NewRequest(http.MethodPost, "http://example.com/save", bytes.NewReader([]byte(input)))
 			// NoError проверяет, что функция не вернула ошибку.
 			require.NoError(t, err)
 
 			rr := httptest.NewRecorder()
 			handler.ServeHTTP(rr, req)
 
+			respCode := tc.respCode
+			if respCode == 0 {
+				respCode = http.StatusOK
+			}
+
 			//Equal производит сравнение двух значений
-			require.Equal(t, rr.Code, http.StatusOK)
+			require.Equal(t, respCode, rr.Code)
 
 			body := rr.Body.String()
 
@@ -90,9 +276,78 @@ func TestSaveHandler(t *testing.T) {
 			// NoError проверяет, что функция не вернула ошибку.
 			require.NoError(t, json.Unmarshal([]byte(body), &resp))
 			//Equal производит сравнение двух значений
-			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.respError, resp.Error.Error())
 
 			// TODO: add more checks
 		})
 	}
 }
+
+func TestSaveHandler_Dedupe(t *testing.T) {
+	creatorMock := mocks.NewCreator(t)
+	creatorMock.On("Create", mock.Anything, shortener.Account{Role: acl.RoleEditor}, mock.MatchedBy(func(link shortener.Link) bool {
+		return link.Dedupe && link.URL.URL == "https://google.com"
+	}), mock.Anything).
+		Return("existing", nil).
+		Once()
+
+	handler := save.New(slogdiscard.NewDiscardLogger(), creatorMock, registry)
+
+	input := `{"url": "https://google.com", "dedupe": true}`
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/url", bytes.NewReader([]byte(input))))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp save.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Nil(t, resp.Error)
+	require.Equal(t, "existing", resp.Alias)
+}
+
+func TestSaveHandler_BurnAfterReading(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     string
+		respError string
+	}{
+		{
+			name:  "One-time link",
+			input: `{"url": "https://google.com", "burn_after_reading": true}`,
+		},
+		{
+			name:      "With click limit",
+			input:     `{"url": "https://google.com", "burn_after_reading": true, "max_clicks": 3}`,
+			respError: "field BurnAfterReading is not valid",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			creatorMock := mocks.NewCreator(t)
+			if tc.respError == "" {
+				creatorMock.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(link shortener.Link) bool {
+					return link.URL.BurnAfterReading
+				}), mock.Anything).
+					Return("once", nil).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), creatorMock, registry)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/url", bytes.NewReader([]byte(tc.input))))
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+type Count struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+type Response struct {
+	resp.Response
+	Alias string `json:"alias,omitempty"`
+	Total int64  `json:"total"`
+	// Uniques estimates distinct visitors, by IP address and user agent, within about 2%.
+	Uniques int64   `json:"uniques"`
+	ByDay   []Count `json:"by_day"`
+	// UniquesByDay estimates distinct visitors of every day in ByDay.
+	UniquesByDay []Count `json:"uniques_by_day"`
+	ByReferrer   []Count `json:"by_referrer"`
+	ByBrowser    []Count `json:"by_browser"`
+	// ByVariant counts clicks of every A/B test variant, ordered by variant name.
+	ByVariant []Count `json:"by_variant"`
+	// ByReferrerHost counts clicks by referring site, "direct" for clicks without one.
+	ByReferrerHost []Count `json:"by_referrer_host"`
+	// BySource, ByMedium and ByCampaign count clicks by utm_source, utm_medium and
+	// utm_campaign of the short link, top first.
+	BySource   []Count `json:"by_source"`
+	ByMedium   []Count `json:"by_medium"`
+	ByCampaign []Count `json:"by_campaign"`
+	// ByCountry counts clicks by ISO country code and ByCity by "City, CC", top first;
+	// both are empty unless a GeoIP database is configured.
+	ByCountry []Count `json:"by_country"`
+	ByCity    []Count `json:"by_city"`
+}
+
+// ClickStatsGetter is an interface for getting aggregated clicks of url.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=ClickStatsGetter
+type ClickStatsGetter interface {
+	GetClickStats(ctx context.Context, alias string) (storage.ClickStats, error)
+}
+
+func New(log *slog.Logger, statsGetter ClickStatsGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.stats.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid request"))
+
+			return
+		}
+
+		// Ссылки хранятся в пространстве имен тенанта
+		alias = tenant.Key(tenant.FromContext(r.Context()), alias)
+
+		stats, err := statsGetter.GetClickStats(r.Context(), alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", "alias", alias)
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get click stats", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response:       resp.OK(),
+			Alias:          tenant.Alias(tenant.FromContext(r.Context()), alias),
+			Total:          stats.Total,
+			Uniques:        stats.Uniques,
+			ByDay:          toCounts(stats.ByDay),
+			UniquesByDay:   toCounts(stats.UniquesByDay),
+			ByReferrer:     toCounts(stats.ByReferrer),
+			ByBrowser:      toCounts(stats.ByBrowser),
+			ByVariant:      toCounts(stats.ByVariant),
+			ByReferrerHost: toCounts(stats.ByReferrerHost),
+			BySource:       toCounts(stats.BySource),
+			ByMedium:       toCounts(stats.ByMedium),
+			ByCampaign:     toCounts(stats.ByCampaign),
+			ByCountry:      toCounts(stats.ByCountry),
+			ByCity:         toCounts(stats.ByCity),
+		})
+	}
+}
+
+func toCounts(counts []storage.Count) []Count {
+	res := make([]Count, 0, len(counts))
+	for _, c := range counts {
+		res = append(res, Count{Key: c.Key, Count: c.Count})
+	}
+
+	return res
+}
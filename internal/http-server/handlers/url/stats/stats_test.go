@@ -0,0 +1,93 @@
+package stats_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/stats"
+	"url-shortener/internal/http-server/handlers/url/stats/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestStatsHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		alias     string
+		mockStats storage.ClickStats
+		mockError error
+		respCode  int
+		respError string
+	}{
+		{
+			name:  "Success",
+			alias: "test_alias",
+			mockStats: storage.ClickStats{
+				Total:          3,
+				Uniques:        2,
+				ByDay:          []storage.Count{{Key: "2024-01-01", Count: 3}},
+				UniquesByDay:   []storage.Count{{Key: "2024-01-01", Count: 2}},
+				ByReferrer:     []storage.Count{{Key: "direct", Count: 2}, {Key: "https://t.me", Count: 1}},
+				ByBrowser:      []storage.Count{{Key: "chrome", Count: 3}},
+				ByVariant:      []storage.Count{{Key: "A", Count: 2}, {Key: "B", Count: 1}},
+				ByReferrerHost: []storage.Count{{Key: "direct", Count: 2}, {Key: "t.me", Count: 1}},
+				BySource:       []storage.Count{{Key: "newsletter", Count: 1}},
+				ByMedium:       []storage.Count{{Key: "email", Count: 1}},
+				ByCampaign:     []storage.Count{{Key: "autumn", Count: 1}},
+				ByCountry:      []storage.Count{{Key: "DE", Count: 3}},
+				ByCity:         []storage.Count{{Key: "Berlin, DE", Count: 2}},
+			},
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Not found",
+			alias:     "missing_alias",
+			mockError: storage.ErrURLNotFound,
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			statsGetterMock := mocks.NewClickStatsGetter(t)
+
+			statsGetterMock.On("GetClickStats", mock.Anything, tc.alias).
+				Return(tc.mockStats, tc.mockError).
+				Once()
+
+			r := chi.NewRouter()
+			r.Get("/url/{alias}/stats", stats.New(slogdiscard.NewDiscardLogger(), statsGetterMock))
+
+			req, err := http.NewRequest(http.MethodGet, "/url/"+tc.alias+"/stats", nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp stats.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+			require.Equal(t, tc.mockStats.Total, resp.Total)
+			require.Equal(t, tc.mockStats.Uniques, resp.Uniques)
+			require.Len(t, resp.UniquesByDay, len(tc.mockStats.UniquesByDay))
+			require.Len(t, resp.ByReferrer, len(tc.mockStats.ByReferrer))
+			require.Len(t, resp.ByVariant, len(tc.mockStats.ByVariant))
+			require.Len(t, resp.ByReferrerHost, len(tc.mockStats.ByReferrerHost))
+			require.Len(t, resp.BySource, len(tc.mockStats.BySource))
+			require.Len(t, resp.ByCity, len(tc.mockStats.ByCity))
+		})
+	}
+}
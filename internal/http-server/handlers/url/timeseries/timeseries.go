@@ -0,0 +1,204 @@
+package timeseries
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+const (
+	IntervalHour = "hour"
+	IntervalDay  = "day"
+)
+
+// maxBuckets limits the length of the series, e.g. to 41 days by hour.
+const maxBuckets = 1000
+
+type Bucket struct {
+	// Time is the start of the bucket in UTC.
+	Time   time.Time `json:"time"`
+	Clicks int64     `json:"clicks"`
+}
+
+type Response struct {
+	resp.Response
+	Alias    string    `json:"alias,omitempty"`
+	Interval string    `json:"interval,omitempty"`
+	From     time.Time `json:"from,omitempty"`
+	To       time.Time `json:"to,omitempty"`
+	// Buckets cover [from, to) without gaps, buckets without clicks have zero.
+	Buckets []Bucket `json:"buckets"`
+}
+
+// HourlyClicksGetter is an interface for getting clicks of url per hour.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=HourlyClicksGetter
+type HourlyClicksGetter interface {
+	GetHourlyClicks(ctx context.Context, alias string, from, to time.Time) ([]storage.ClickBucket, error)
+}
+
+var (
+	errInvalidInterval = errors.New(`interval must be "hour" or "day"`)
+	errInvalidFrom     = errors.New("from must be an RFC 3339 time")
+	errInvalidTo       = errors.New("to must be an RFC 3339 time")
+	errInvalidRange    = errors.New("from must be before to")
+	errRangeTooLong    = errors.New("range must have at most " + strconv.Itoa(maxBuckets) + " buckets")
+)
+
+// New returns clicks of the link in hour or day buckets (UTC) between the "from" and
+// "to" query parameters. Bounds are aligned to the buckets; by default the series
+// ends now and covers the last 24 hours or 30 days.
+func New(log *slog.Logger, clicksGetter HourlyClicksGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.timeseries.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid request"))
+
+			return
+		}
+
+		interval, from, to, err := parseQuery(r, time.Now())
+		if err != nil {
+			log.Info("invalid query", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
+
+			return
+		}
+
+		// Ссылки хранятся в пространстве имен тенанта
+		alias = tenant.Key(tenant.FromContext(r.Context()), alias)
+
+		hours, err := clicksGetter.GetHourlyClicks(r.Context(), alias, from, to)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", "alias", alias)
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get hourly clicks", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Alias:    tenant.Alias(tenant.FromContext(r.Context()), alias),
+			Interval: interval,
+			From:     from,
+			To:       to,
+			Buckets:  fill(hours, interval, from, to),
+		})
+	}
+}
+
+func parseQuery(r *http.Request, now time.Time) (interval string, from, to time.Time, err error) {
+	q := r.URL.Query()
+
+	interval = q.Get("interval")
+	if interval == "" {
+		interval = IntervalHour
+	}
+
+	var (
+		step   time.Duration
+		period time.Duration
+	)
+	switch interval {
+	case IntervalHour:
+		step, period = time.Hour, 24*time.Hour
+	case IntervalDay:
+		step, period = 24*time.Hour, 30*24*time.Hour
+	default:
+		return "", time.Time{}, time.Time{}, errInvalidInterval
+	}
+
+	to = now
+	if v := q.Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			return "", time.Time{}, time.Time{}, errInvalidTo
+		}
+	}
+	// Конец включает неполный текущий интервал
+	to = ceil(to.UTC(), step)
+
+	from = to.Add(-period)
+	if v := q.Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			return "", time.Time{}, time.Time{}, errInvalidFrom
+		}
+	}
+	from = from.UTC().Truncate(step)
+
+	if !from.Before(to) {
+		return "", time.Time{}, time.Time{}, errInvalidRange
+	}
+	if to.Sub(from)/step > maxBuckets {
+		return "", time.Time{}, time.Time{}, errRangeTooLong
+	}
+
+	return interval, from, to, nil
+}
+
+// ceil rounds t up to a multiple of step.
+func ceil(t time.Time, step time.Duration) time.Time {
+	if down := t.Truncate(step); down.Before(t) {
+		return down.Add(step)
+	}
+
+	return t
+}
+
+// fill sums hours into buckets of the interval and adds empty buckets, so that the
+// series covers [from, to) without gaps.
+func fill(hours []storage.ClickBucket, interval string, from, to time.Time) []Bucket {
+	step := time.Hour
+	if interval == IntervalDay {
+		step = 24 * time.Hour
+	}
+
+	buckets := make([]Bucket, 0, to.Sub(from)/step)
+	for t := from; t.Before(to); t = t.Add(step) {
+		buckets = append(buckets, Bucket{Time: t})
+	}
+
+	for _, h := range hours {
+		i := int(h.Start.Sub(from) / step)
+		if h.Start.Before(from) || i >= len(buckets) {
+			continue
+		}
+
+		buckets[i].Clicks += h.Clicks
+	}
+
+	return buckets
+}
@@ -0,0 +1,140 @@
+package timeseries_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/timeseries"
+	"url-shortener/internal/http-server/handlers/url/timeseries/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func hour(h int) time.Time {
+	return time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC).Add(time.Duration(h) * time.Hour)
+}
+
+func TestTimeseriesHandler(t *testing.T) {
+	hours := []storage.ClickBucket{
+		{Start: hour(1), Clicks: 2},
+		{Start: hour(3), Clicks: 1},
+		{Start: hour(25), Clicks: 4},
+	}
+
+	cases := []struct {
+		name        string
+		query       string
+		mockFrom    time.Time
+		mockTo      time.Time
+		mockError   error
+		respCode    int
+		respError   string
+		respBuckets []timeseries.Bucket
+	}{
+		{
+			name:     "Hours",
+			query:    "?from=2026-10-15T01:30:00Z&to=2026-10-15T03:10:00Z",
+			mockFrom: hour(1),
+			mockTo:   hour(4),
+			respCode: http.StatusOK,
+			respBuckets: []timeseries.Bucket{
+				{Time: hour(1), Clicks: 2},
+				{Time: hour(2)},
+				{Time: hour(3), Clicks: 1},
+			},
+		},
+		{
+			name:     "Days",
+			query:    "?interval=day&from=2026-10-15T00:00:00Z&to=2026-10-17T00:00:00Z",
+			mockFrom: hour(0),
+			mockTo:   hour(48),
+			respCode: http.StatusOK,
+			respBuckets: []timeseries.Bucket{
+				{Time: hour(0), Clicks: 3},
+				{Time: hour(24), Clicks: 4},
+			},
+		},
+		{
+			name:      "Not found",
+			query:     "?from=2026-10-15T01:00:00Z&to=2026-10-15T04:00:00Z",
+			mockFrom:  hour(1),
+			mockTo:    hour(4),
+			mockError: storage.ErrURLNotFound,
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+		},
+		{
+			name:      "Invalid interval",
+			query:     "?interval=week",
+			respCode:  http.StatusBadRequest,
+			respError: `interval must be "hour" or "day"`,
+		},
+		{
+			name:      "Invalid from",
+			query:     "?from=yesterday",
+			respCode:  http.StatusBadRequest,
+			respError: "from must be an RFC 3339 time",
+		},
+		{
+			name:      "Empty range",
+			query:     "?from=2026-10-15T04:00:00Z&to=2026-10-15T01:00:00Z",
+			respCode:  http.StatusBadRequest,
+			respError: "from must be before to",
+		},
+		{
+			name:      "Range too long",
+			query:     "?from=2026-01-01T00:00:00Z&to=2026-10-15T00:00:00Z",
+			respCode:  http.StatusBadRequest,
+			respError: "range must have at most 1000 buckets",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			clicksGetterMock := mocks.NewHourlyClicksGetter(t)
+
+			if !tc.mockFrom.IsZero() {
+				clicksGetterMock.On("GetHourlyClicks", mock.Anything, "test_alias", tc.mockFrom, tc.mockTo).
+					Return(hours, tc.mockError).
+					Once()
+			}
+
+			r := chi.NewRouter()
+			r.Get("/url/{alias}/stats/timeseries", timeseries.New(slogdiscard.NewDiscardLogger(), clicksGetterMock))
+
+			req := httptest.NewRequest(http.MethodGet, "/url/test_alias/stats/timeseries"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var res timeseries.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+			require.Equal(t, tc.respError, res.Error.Error())
+
+			if tc.respError != "" {
+				return
+			}
+
+			require.Equal(t, "test_alias", res.Alias)
+			require.True(t, tc.mockFrom.Equal(res.From))
+			require.True(t, tc.mockTo.Equal(res.To))
+			require.Len(t, res.Buckets, len(tc.respBuckets))
+			for i, b := range tc.respBuckets {
+				require.True(t, b.Time.Equal(res.Buckets[i].Time))
+				require.Equal(t, b.Clicks, res.Buckets[i].Clicks)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/acl"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/jwt"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/loopcheck"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/lib/urlcheck"
+	"url-shortener/internal/screening"
+	"url-shortener/internal/storage"
+	linkTransfer "url-shortener/internal/transfer"
+)
+
+type InvalidRecord struct {
+	Line   int    `json:"line"`
+	Alias  string `json:"alias,omitempty"`
+	Reason string `json:"reason"`
+}
+
+type ImportResponse struct {
+	resp.Response
+	Imported    int               `json:"imported"`
+	Overwritten []string          `json:"overwritten,omitempty"`
+	Skipped     []string          `json:"skipped,omitempty"`
+	Renamed     map[string]string `json:"renamed,omitempty"`
+	Invalid     []InvalidRecord   `json:"invalid,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLExporter
+type URLExporter interface {
+	ListURLs(ctx context.Context, filter storage.ListFilter) ([]storage.URL, string, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLImporter
+type URLImporter interface {
+	SaveURL(ctx context.Context, u storage.URL) (int64, error)
+	SaveURLs(ctx context.Context, urls []storage.URL) ([]int64, error)
+	GetURLInfo(ctx context.Context, alias string) (storage.URL, error)
+	UpdateURL(ctx context.Context, alias string, update storage.URLUpdate, version int64) (storage.URL, error)
+}
+
+// NewExport returns handler of GET /url/export streaming links as ND-JSON or CSV.
+// The format is taken from the format query parameter or the extension (/url/export.csv).
+func NewExport(log *slog.Logger, urlExporter URLExporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.transfer.NewExport"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format, _ = r.Context().Value(middleware.URLFormatCtxKey).(string)
+		}
+		if format == "" {
+			format = linkTransfer.FormatNDJSON
+		}
+
+		enc, err := linkTransfer.NewWriter(w, format)
+		if err != nil {
+			log.Info("unknown format", slog.String("format", format))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
+
+			return
+		}
+
+		w.Header().Set("Content-Type", linkTransfer.ContentType(format))
+		w.Header().Set("Content-Disposition", `attachment; filename="links.`+format+`"`)
+
+		// Пользователи выгружают только свои ссылки; с API-ключом - все
+		n, err := linkTransfer.Export(r.Context(),
+			urlExporter, enc, storage.ListFilter{UserID: jwt.UserID(r.Context())}, tenant.FromContext(r.Context()),
+		)
+		if err != nil {
+			// Часть ответа уже отправлена, сообщить клиенту об ошибке можно только обрывом
+			log.Error("failed to export urls", slog.Int("exported", n), sl.Err(err))
+
+			panic(http.ErrAbortHandler)
+		}
+
+		log.Info("urls exported", slog.Int("count", n))
+	}
+}
+
+// NewImport returns handler of POST /url/import. The body is ND-JSON or CSV, the format
+// is taken from the format query parameter or text/csv Content-Type. The conflict query
+// parameter selects what to do with taken aliases: skip (default), overwrite or rename.
+// Destinations are checked by checker, screener and loopChecker like those of new links.
+func NewImport(
+	log *slog.Logger,
+	urlImporter URLImporter,
+	checker *urlcheck.Checker,
+	screener screening.Screener,
+	loopChecker *loopcheck.Checker,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.transfer.NewImport"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		q := r.URL.Query()
+
+		format := q.Get("format")
+		if format == "" && strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+			format = linkTransfer.FormatCSV
+		}
+
+		conflict := q.Get("conflict")
+		if !linkTransfer.ValidConflict(conflict) {
+			log.Info("unknown conflict strategy", slog.String("conflict", conflict))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, linkTransfer.ErrUnknownConflict.Error()))
+
+			return
+		}
+
+		dec, err := linkTransfer.NewReader(r.Body, format)
+		if err != nil {
+			log.Info("unknown format", slog.String("format", format))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
+
+			return
+		}
+
+		res, err := linkTransfer.Import(r.Context(), urlImporter, checker, screener, loopChecker, dec, linkTransfer.ImportOptions{
+			Conflict: conflict,
+			UserID:   jwt.UserID(r.Context()),
+			APIKeyID: apikey.KeyID(r.Context()),
+			Admin:    acl.IsAdmin(r.Context()),
+			Tenant:   tenant.FromContext(r.Context()),
+			Host:     r.Host,
+		})
+
+		var malformed *linkTransfer.MalformedError
+		if errors.As(err, &malformed) {
+			log.Info("malformed input", slog.Int("imported", res.Imported), sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, malformed.Error()))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to import urls", slog.Int("imported", res.Imported), sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "failed to import urls"))
+
+			return
+		}
+
+		log.Info("urls imported",
+			slog.Int("imported", res.Imported),
+			slog.Int("overwritten", len(res.Overwritten)),
+			slog.Int("renamed", len(res.Renamed)),
+			slog.Int("skipped", len(res.Skipped)),
+			slog.Int("invalid", len(res.Invalid)),
+		)
+
+		out := ImportResponse{
+			Response:    resp.OK(),
+			Imported:    res.Imported,
+			Overwritten: res.Overwritten,
+			Skipped:     res.Skipped,
+			Renamed:     res.Renamed,
+		}
+		for _, rec := range res.Invalid {
+			out.Invalid = append(out.Invalid, InvalidRecord{Line: rec.Line, Alias: rec.Alias, Reason: rec.Reason})
+		}
+
+		render.JSON(w, r, out)
+	}
+}
@@ -0,0 +1,218 @@
+package transfer_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/transfer"
+	"url-shortener/internal/http-server/handlers/url/transfer/mocks"
+	"url-shortener/internal/lib/jwt"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/loopcheck"
+	"url-shortener/internal/lib/urlcheck"
+	"url-shortener/internal/screening"
+	"url-shortener/internal/storage"
+)
+
+var (
+	checker     = urlcheck.New(urlcheck.Options{BlockPrivate: true})
+	screener    = screening.NewBlocklist([]string{"phish.example"})
+	loopChecker = loopcheck.New(nil, loopcheck.Options{Hosts: []string{"sho.rt"}})
+)
+
+func TestExportHandler(t *testing.T) {
+	cases := []struct {
+		name        string
+		query       string
+		userID      int64
+		filter      *storage.ListFilter
+		respCode    int
+		contentType string
+		body        string
+	}{
+		{
+			name:        "NDJSON by default",
+			filter:      &storage.ListFilter{Limit: 100},
+			respCode:    http.StatusOK,
+			contentType: "application/x-ndjson",
+			body:        `{"alias":"a1","url":"https://a.com"}` + "\n",
+		},
+		{
+			name:        "CSV of the user",
+			query:       "?format=csv",
+			userID:      7,
+			filter:      &storage.ListFilter{Limit: 100, UserID: 7},
+			respCode:    http.StatusOK,
+			contentType: "text/csv; charset=utf-8",
+			body:        "alias,url,expires_at,redirect_code\na1,https://a.com,,\n",
+		},
+		{
+			name:     "Unknown format",
+			query:    "?format=xml",
+			respCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlExporterMock := mocks.NewURLExporter(t)
+
+			if tc.filter != nil {
+				urlExporterMock.On("ListURLs", mock.Anything, *tc.filter).
+					Return([]storage.URL{{Alias: "a1", URL: "https://a.com"}}, "", nil).
+					Once()
+			}
+
+			handler := transfer.NewExport(slogdiscard.NewDiscardLogger(), urlExporterMock)
+
+			req, err := http.NewRequest(http.MethodGet, "/url/export"+tc.query, nil)
+			require.NoError(t, err)
+			if tc.userID != 0 {
+				req = req.WithContext(jwt.WithUserID(req.Context(), tc.userID))
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+			if tc.body != "" {
+				require.Equal(t, tc.contentType, rr.Header().Get("Content-Type"))
+				require.Equal(t, tc.body, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestImportHandler(t *testing.T) {
+	urlImporterMock := mocks.NewURLImporter(t)
+
+	urlImporterMock.On("SaveURLs", mock.Anything, mock.MatchedBy(func(urls []storage.URL) bool {
+		return len(urls) == 2 && urls[0].Alias == "taken" && urls[1].Alias == "free"
+	})).Return([]int64{0, 2}, nil).Once()
+	urlImporterMock.On("SaveURL", mock.Anything, mock.MatchedBy(func(u storage.URL) bool {
+		return strings.HasPrefix(u.Alias, "taken-")
+	})).Return(int64(3), nil).Once()
+
+	handler := transfer.NewImport(slogdiscard.NewDiscardLogger(), urlImporterMock, checker, screener, loopChecker)
+
+	body := "alias,url\ntaken,https://a.com\nfree,https://b.com\nx,https://c.com\n"
+
+	req, err := http.NewRequest(http.MethodPost, "/url/import?conflict=rename", strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "text/csv")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp transfer.ImportResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Nil(t, resp.Error)
+	require.Equal(t, 1, resp.Imported)
+	require.Contains(t, resp.Renamed, "taken")
+	require.Len(t, resp.Invalid, 1)
+	require.Equal(t, 3, resp.Invalid[0].Line)
+}
+
+func TestImportHandler_Loop(t *testing.T) {
+	urlImporterMock := mocks.NewURLImporter(t)
+
+	handler := transfer.NewImport(slogdiscard.NewDiscardLogger(), urlImporterMock, checker, screener, loopChecker)
+
+	body := `{"alias":"self","url":"https://api.example.com/x"}` + "\n"
+
+	req, err := http.NewRequest(http.MethodPost, "/url/import", strings.NewReader(body))
+	require.NoError(t, err)
+	req.Host = "api.example.com"
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp transfer.ImportResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Zero(t, resp.Imported)
+	require.Len(t, resp.Invalid, 1)
+	require.Equal(t, "url leads to a redirect loop: api.example.com is served by the shortener", resp.Invalid[0].Reason)
+}
+
+func TestImportHandler_Errors(t *testing.T) {
+	cases := []struct {
+		name      string
+		query     string
+		body      string
+		mockError error
+		respCode  int
+		respError string
+	}{
+		{
+			name:      "Unknown conflict strategy",
+			query:     "?conflict=merge",
+			respCode:  http.StatusBadRequest,
+			respError: "unknown conflict strategy, use skip, overwrite or rename",
+		},
+		{
+			name:      "Unknown format",
+			query:     "?format=xml",
+			respCode:  http.StatusBadRequest,
+			respError: "unknown format, use ndjson or csv",
+		},
+		{
+			name:      "Malformed input",
+			body:      `{"alias":`,
+			respCode:  http.StatusBadRequest,
+			respError: "record 1: unexpected EOF",
+		},
+		{
+			name:      "Storage error",
+			body:      `{"alias":"ok","url":"https://a.com"}`,
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "failed to import urls",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlImporterMock := mocks.NewURLImporter(t)
+
+			if tc.mockError != nil {
+				urlImporterMock.On("SaveURLs", mock.Anything, mock.Anything).
+					Return(nil, tc.mockError).
+					Once()
+			}
+
+			handler := transfer.NewImport(slogdiscard.NewDiscardLogger(), urlImporterMock, checker, screener, loopChecker)
+
+			req, err := http.NewRequest(http.MethodPost, "/url/import"+tc.query, strings.NewReader(tc.body))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp transfer.ImportResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+		})
+	}
+}
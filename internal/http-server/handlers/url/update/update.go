@@ -0,0 +1,306 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/acl"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/etag"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/loopcheck"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/lib/urlcheck"
+	"url-shortener/internal/screening"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	URL *string `json:"url,omitempty" validate:"omitempty,url"`
+	// ExpiresAt, TTL and NoExpiry are mutually exclusive ways to change link expiration.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	TTL       string     `json:"ttl,omitempty"`
+	NoExpiry  bool       `json:"no_expiry,omitempty"`
+	// RedirectCode 0 resets the link to the default redirect status.
+	RedirectCode *int `json:"redirect_code,omitempty" validate:"omitempty,oneof=0 301 302 307 308"`
+	// NoAnalytics turns click statistics of the link off or back on.
+	NoAnalytics *bool `json:"no_analytics,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	Alias        string     `json:"alias,omitempty"`
+	URL          string     `json:"url,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	RedirectCode int        `json:"redirect_code,omitempty"`
+	NoAnalytics  bool       `json:"no_analytics,omitempty"`
+}
+
+// URLUpdater is an interface for updating url by alias.
+// GetURLInfo is used to check the owner of the link, see acl.Principal.CanManage.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLUpdater
+type URLUpdater interface {
+	GetURLInfo(ctx context.Context, alias string) (storage.URL, error)
+	UpdateURL(ctx context.Context, alias string, update storage.URLUpdate, version int64) (storage.URL, error)
+}
+
+func New(
+	log *slog.Logger,
+	urlUpdater URLUpdater,
+	checker *urlcheck.Checker,
+	screener screening.Screener,
+	loopChecker *loopcheck.Checker,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.update.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid request"))
+
+			return
+		}
+
+		// Ссылки хранятся в пространстве имен тенанта
+		alias = tenant.Key(tenant.FromContext(r.Context()), alias)
+
+		// If-Match is optional: without it the last write wins.
+		var version int64
+		if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+			v, err := etag.Version(ifMatch)
+			if err != nil {
+				log.Info("invalid If-Match header", slog.String("if_match", ifMatch))
+
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid If-Match header"))
+
+				return
+			}
+
+			version = v
+		}
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "empty request"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to decode request"))
+
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(r, validateErr))
+
+			return
+		}
+
+		if req.URL != nil {
+			normalized, err := checker.Normalize(*req.URL)
+			if err != nil {
+				log.Info("url rejected", slog.String("url", *req.URL), sl.Err(err))
+
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
+
+				return
+			}
+			req.URL = &normalized
+
+			flagged, err := screener.Screen(r.Context(), []string{normalized})
+			if err != nil {
+				log.Error("failed to screen url", sl.Err(err))
+			}
+			if reason, ok := flagged[normalized]; ok {
+				log.Info("url is flagged", slog.String("url", normalized), slog.String("reason", reason))
+
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "url is flagged as malicious: "+reason))
+
+				return
+			}
+
+			err = loopChecker.Check(r.Context(), normalized, r.Host)
+			if errors.Is(err, loopcheck.ErrLoop) {
+				log.Info("url leads to a loop", sl.Err(err))
+
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
+
+				return
+			}
+			if err != nil {
+				log.Error("failed to check url for loops", sl.Err(err))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error(r, resp.CodeInternal, "failed to update url"))
+
+				return
+			}
+		}
+
+		upd, err := toUpdate(req, time.Now())
+		if err != nil {
+			log.Info("invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
+
+			return
+		}
+
+		// Чужие ссылки выглядят несуществующими, изменить любую может только администратор
+		existing, err := urlUpdater.GetURLInfo(r.Context(), alias)
+		if err == nil && !acl.FromContext(r.Context()).CanManage(existing) {
+			log.Info("url is owned by another client", slog.String("alias", alias))
+
+			err = storage.ErrURLNotFound
+		}
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", "alias", alias)
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url info", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "failed to update url"))
+
+			return
+		}
+
+		u, err := urlUpdater.UpdateURL(r.Context(), alias, upd, version)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", "alias", alias)
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if errors.Is(err, storage.ErrURLModified) {
+			log.Info("url was modified concurrently", "alias", alias)
+
+			render.Status(r, http.StatusPreconditionFailed)
+			render.JSON(w, r, resp.Error(r, resp.CodeConflict, "url was modified, fetch it again"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to update url", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "failed to update url"))
+
+			return
+		}
+
+		log.Info("url updated", slog.String("alias", alias), slog.Int64("version", u.Version))
+
+		var expiresAt *time.Time
+		if !u.ExpiresAt.IsZero() {
+			expiresAt = &u.ExpiresAt
+		}
+
+		w.Header().Set("ETag", etag.FromVersion(u.Version))
+
+		render.JSON(w, r, Response{
+			Response:     resp.OK(),
+			Alias:        tenant.Alias(tenant.FromContext(r.Context()), u.Alias),
+			URL:          u.URL,
+			ExpiresAt:    expiresAt,
+			RedirectCode: u.RedirectCode,
+			NoAnalytics:  u.NoAnalytics,
+		})
+	}
+}
+
+var (
+	errNothingToUpdate    = errors.New("nothing to update")
+	errExpirationConflict = errors.New("only one of expires_at, ttl and no_expiry can be set")
+	errInvalidTTL         = errors.New("ttl must be a positive duration, e.g. 24h")
+	errExpiresInPast      = errors.New("expires_at must be in the future")
+)
+
+func toUpdate(req Request, now time.Time) (storage.URLUpdate, error) {
+	upd := storage.URLUpdate{URL: req.URL, RedirectCode: req.RedirectCode, NoAnalytics: req.NoAnalytics}
+
+	set := 0
+	if req.ExpiresAt != nil {
+		set++
+	}
+	if req.TTL != "" {
+		set++
+	}
+	if req.NoExpiry {
+		set++
+	}
+	if set > 1 {
+		return storage.URLUpdate{}, errExpirationConflict
+	}
+
+	switch {
+	case req.TTL != "":
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			return storage.URLUpdate{}, errInvalidTTL
+		}
+
+		expiresAt := now.Add(ttl)
+		upd.ExpiresAt = &expiresAt
+	case req.ExpiresAt != nil:
+		if !req.ExpiresAt.After(now) {
+			return storage.URLUpdate{}, errExpiresInPast
+		}
+
+		upd.ExpiresAt = req.ExpiresAt
+	case req.NoExpiry:
+		upd.ExpiresAt = &time.Time{}
+	}
+
+	if upd.URL == nil && upd.ExpiresAt == nil && upd.RedirectCode == nil && upd.NoAnalytics == nil {
+		return storage.URLUpdate{}, errNothingToUpdate
+	}
+
+	return upd, nil
+}
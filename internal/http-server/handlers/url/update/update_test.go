@@ -0,0 +1,213 @@
+package update_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/update"
+	"url-shortener/internal/http-server/handlers/url/update/mocks"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/loopcheck"
+	"url-shortener/internal/lib/urlcheck"
+	"url-shortener/internal/screening"
+	"url-shortener/internal/storage"
+)
+
+func TestUpdateHandler(t *testing.T) {
+	cases := []struct {
+		name       string
+		alias      string
+		body       string
+		ifMatch    string
+		version    int64
+		mockCalled bool
+		mockError  error
+		foreign    bool
+		infoError  error
+		respCode   int
+		respError  string
+		respETag   string
+	}{
+		{
+			name:       "Success",
+			alias:      "test_alias",
+			body:       `{"url": "https://example.com"}`,
+			mockCalled: true,
+			respCode:   http.StatusOK,
+			respETag:   `"2"`,
+		},
+		{
+			name:       "Matching If-Match",
+			alias:      "test_alias",
+			body:       `{"ttl": "1h"}`,
+			ifMatch:    `"1"`,
+			version:    1,
+			mockCalled: true,
+			respCode:   http.StatusOK,
+			respETag:   `"2"`,
+		},
+		{
+			name:       "Modified",
+			alias:      "test_alias",
+			body:       `{"url": "https://example.com"}`,
+			ifMatch:    `"1"`,
+			version:    1,
+			mockCalled: true,
+			mockError:  storage.ErrURLModified,
+			respCode:   http.StatusPreconditionFailed,
+			respError:  "url was modified, fetch it again",
+		},
+		{
+			name:      "Not found",
+			alias:     "missing_alias",
+			body:      `{"url": "https://example.com"}`,
+			infoError: storage.ErrURLNotFound,
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+		},
+		{
+			name:      "Foreign link",
+			alias:     "test_alias",
+			body:      `{"url": "https://example.com"}`,
+			foreign:   true,
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+		},
+		{
+			name:       "Deleted concurrently",
+			alias:      "test_alias",
+			body:       `{"url": "https://example.com"}`,
+			mockCalled: true,
+			mockError:  storage.ErrURLNotFound,
+			respCode:   http.StatusNotFound,
+			respError:  "not found",
+		},
+		{
+			name:      "Nothing to update",
+			alias:     "test_alias",
+			body:      `{}`,
+			respCode:  http.StatusBadRequest,
+			respError: "nothing to update",
+		},
+		{
+			name:      "Invalid URL",
+			alias:     "test_alias",
+			body:      `{"url": "not a url"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field URL is not a valid URL",
+		},
+		{
+			name:      "Scheme not allowed",
+			alias:     "test_alias",
+			body:      `{"url": "ftp://example.com/file"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "url scheme is not allowed: ftp",
+		},
+		{
+			name:      "Flagged URL",
+			alias:     "test_alias",
+			body:      `{"url": "https://phish.example/login"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "url is flagged as malicious: blocklist",
+		},
+		{
+			name:      "Link to the service",
+			alias:     "test_alias",
+			body:      `{"url": "https://sho.rt/other"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "url leads to a redirect loop: sho.rt is served by the shortener",
+		},
+		{
+			name:       "Redirect code",
+			alias:      "test_alias",
+			body:       `{"redirect_code": 301}`,
+			mockCalled: true,
+			respCode:   http.StatusOK,
+			respETag:   `"2"`,
+		},
+		{
+			name:       "No analytics",
+			alias:      "test_alias",
+			body:       `{"no_analytics": true}`,
+			mockCalled: true,
+			respCode:   http.StatusOK,
+			respETag:   `"2"`,
+		},
+		{
+			name:      "Invalid redirect code",
+			alias:     "test_alias",
+			body:      `{"redirect_code": 200}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field RedirectCode is not valid",
+		},
+		{
+			name:      "Invalid If-Match",
+			alias:     "test_alias",
+			body:      `{"url": "https://example.com"}`,
+			ifMatch:   "abc",
+			respCode:  http.StatusBadRequest,
+			respError: "invalid If-Match header",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlUpdaterMock := mocks.NewURLUpdater(t)
+
+			if tc.mockCalled || tc.infoError != nil || tc.foreign {
+				owner := storage.URL{Alias: tc.alias, APIKeyID: 1}
+				if tc.foreign {
+					owner.APIKeyID = 2
+				}
+
+				urlUpdaterMock.On("GetURLInfo", mock.Anything, tc.alias).
+					Return(owner, tc.infoError).
+					Once()
+			}
+			if tc.mockCalled {
+				urlUpdaterMock.On("UpdateURL", mock.Anything, tc.alias, mock.AnythingOfType("storage.URLUpdate"), tc.version).
+					Return(storage.URL{Alias: tc.alias, URL: "https://example.com", Version: 2}, tc.mockError).
+					Once()
+			}
+
+			r := chi.NewRouter()
+			r.Patch("/url/{alias}", update.New(
+				slogdiscard.NewDiscardLogger(),
+				urlUpdaterMock,
+				urlcheck.New(urlcheck.Options{}),
+				screening.NewBlocklist([]string{"phish.example"}),
+				loopcheck.New(nil, loopcheck.Options{Hosts: []string{"sho.rt"}}),
+			))
+
+			req, err := http.NewRequest(http.MethodPatch, "/url/"+tc.alias, bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+			req = req.WithContext(apikey.WithKeyID(req.Context(), 1))
+
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+			require.Equal(t, tc.respETag, rr.Header().Get("ETag"))
+
+			var resp update.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package role
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	Email string `json:"email" validate:"required,email"`
+	// Role takes effect with the next token of the user.
+	Role string `json:"role" validate:"required,oneof=viewer editor admin"`
+}
+
+// UserRoleSetter is an interface for changing roles of users.
+// GetUser is used to check the tenant of the user.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=UserRoleSetter
+type UserRoleSetter interface {
+	GetUser(ctx context.Context, email string) (storage.User, error)
+	SetUserRole(ctx context.Context, email, role string) error
+}
+
+// New returns handler of PUT /users/role; it is available to admins only.
+func New(log *slog.Logger, roleSetter UserRoleSetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.user.role.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "empty request"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to decode request"))
+
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(r, validateErr))
+
+			return
+		}
+
+		// Пользователи другого тенанта для администратора выглядят несуществующими
+		user, err := roleSetter.GetUser(r.Context(), req.Email)
+		if err == nil && user.Tenant != tenant.FromContext(r.Context()) {
+			err = storage.ErrUserNotFound
+		}
+		if err == nil {
+			err = roleSetter.SetUserRole(r.Context(), req.Email, req.Role)
+		}
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found", slog.String("email", req.Email))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "user not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to set user role", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("user role changed", slog.Int64("id", user.ID), slog.String("role", req.Role))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,104 @@
+package role_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/user/role"
+	"url-shortener/internal/http-server/handlers/user/role/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestRoleHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		user      storage.User
+		getError  error
+		setCall   bool
+		setError  error
+		respCode  int
+		respError string
+	}{
+		{
+			name:     "Success",
+			body:     `{"email": "user@example.com", "role": "viewer"}`,
+			user:     storage.User{ID: 7},
+			setCall:  true,
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Unknown role",
+			body:      `{"email": "user@example.com", "role": "owner"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field Role is not valid",
+		},
+		{
+			name:      "User not found",
+			body:      `{"email": "user@example.com", "role": "viewer"}`,
+			getError:  storage.ErrUserNotFound,
+			respCode:  http.StatusNotFound,
+			respError: "user not found",
+		},
+		{
+			name:      "User of other tenant",
+			body:      `{"email": "user@example.com", "role": "admin"}`,
+			user:      storage.User{ID: 7, Tenant: "brand"},
+			respCode:  http.StatusNotFound,
+			respError: "user not found",
+		},
+		{
+			name:      "SetUserRole Error",
+			body:      `{"email": "user@example.com", "role": "viewer"}`,
+			user:      storage.User{ID: 7},
+			setCall:   true,
+			setError:  errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			roleSetterMock := mocks.NewUserRoleSetter(t)
+
+			if tc.respCode != http.StatusBadRequest {
+				roleSetterMock.On("GetUser", mock.Anything, "user@example.com").
+					Return(tc.user, tc.getError).
+					Once()
+			}
+			if tc.setCall {
+				roleSetterMock.On("SetUserRole", mock.Anything, "user@example.com", "viewer").
+					Return(tc.setError).
+					Once()
+			}
+
+			handler := role.New(slogdiscard.NewDiscardLogger(), roleSetterMock)
+
+			req, err := http.NewRequest(http.MethodPut, "/users/role", bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			require.Equal(t, tc.respError, body.Error.Error())
+		})
+	}
+}
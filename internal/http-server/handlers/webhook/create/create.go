@@ -0,0 +1,128 @@
+package create
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/signature"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/webhook"
+)
+
+type Request struct {
+	// URL receives the events as signed POST requests.
+	URL string `json:"url" validate:"required,url"`
+	// Events are the event types to deliver, e.g. "link.created", see package webhook.
+	Events []string `json:"events" validate:"required"`
+}
+
+type Response struct {
+	resp.Response
+	ID     int64    `json:"id,omitempty"`
+	URL    string   `json:"url,omitempty"`
+	Events []string `json:"events,omitempty"`
+	// Secret signs the deliveries, see webhook.Sign. It is shown only once.
+	Secret string `json:"secret,omitempty"`
+}
+
+// WebhookSaver is an interface for subscribing webhooks to link events.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=WebhookSaver
+type WebhookSaver interface {
+	SaveWebhook(ctx context.Context, webhook storage.Webhook) (int64, error)
+}
+
+func New(log *slog.Logger, webhookSaver WebhookSaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhook.create.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "empty request"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to decode request"))
+
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(r, validateErr))
+
+			return
+		}
+
+		if err := webhook.ValidateEventTypes(req.Events); err != nil {
+			log.Info("invalid events", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
+
+			return
+		}
+
+		secret, err := signature.GenerateSecret()
+		if err != nil {
+			log.Error("failed to generate webhook secret", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		id, err := webhookSaver.SaveWebhook(r.Context(), storage.Webhook{
+			URL:    req.URL,
+			Events: req.Events,
+			Secret: secret,
+		})
+		if err != nil {
+			log.Error("failed to save webhook", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("webhook created", slog.Int64("id", id), slog.Any("events", req.Events))
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			ID:       id,
+			URL:      req.URL,
+			Events:   req.Events,
+			Secret:   secret,
+		})
+	}
+}
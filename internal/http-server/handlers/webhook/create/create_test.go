@@ -0,0 +1,104 @@
+package create_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/webhook/create"
+	"url-shortener/internal/http-server/handlers/webhook/create/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestCreateHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		respCode  int
+		respError string
+		mockError error
+		mockCall  bool
+	}{
+		{
+			name:     "Success",
+			body:     `{"url": "https://hooks.example/links", "events": ["link.created", "link.expired"]}`,
+			respCode: http.StatusCreated,
+			mockCall: true,
+		},
+		{
+			name:      "Unknown event",
+			body:      `{"url": "https://hooks.example/links", "events": ["link.updated"]}`,
+			respCode:  http.StatusBadRequest,
+			respError: `unknown event "link.updated", expected one of: link.created, link.deleted, link.clicked, link.expired`,
+		},
+		{
+			name:      "No events",
+			body:      `{"url": "https://hooks.example/links", "events": []}`,
+			respCode:  http.StatusBadRequest,
+			respError: "events must not be empty",
+		},
+		{
+			name:      "Invalid url",
+			body:      `{"url": "hooks", "events": ["link.created"]}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field URL is not a valid URL",
+		},
+		{
+			name:      "SaveWebhook Error",
+			body:      `{"url": "https://hooks.example/links", "events": ["link.created", "link.expired"]}`,
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+			mockError: errors.New("unexpected error"),
+			mockCall:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			webhookSaverMock := mocks.NewWebhookSaver(t)
+
+			var savedSecret string
+			if tc.mockCall {
+				webhookSaverMock.On("SaveWebhook", mock.Anything, mock.MatchedBy(func(w storage.Webhook) bool {
+					savedSecret = w.Secret
+
+					return w.URL == "https://hooks.example/links" &&
+						len(w.Events) == 2 && w.Events[0] == "link.created" && w.Events[1] == "link.expired"
+				})).
+					Return(int64(1), tc.mockError).
+					Once()
+			}
+
+			handler := create.New(slogdiscard.NewDiscardLogger(), webhookSaverMock)
+
+			req, err := http.NewRequest(http.MethodPost, "/admin/webhooks", bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp create.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+
+			if tc.respCode == http.StatusCreated {
+				require.Equal(t, int64(1), resp.ID)
+				require.NotEmpty(t, resp.Secret)
+				require.Equal(t, savedSecret, resp.Secret)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package delete_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/webhook/delete"
+	"url-shortener/internal/http-server/handlers/webhook/delete/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestDeleteHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		id        string
+		respCode  int
+		respError string
+		mockError error
+		mockCall  bool
+	}{
+		{
+			name:     "Success",
+			id:       "1",
+			respCode: http.StatusOK,
+			mockCall: true,
+		},
+		{
+			name:      "Invalid id",
+			id:        "abc",
+			respCode:  http.StatusBadRequest,
+			respError: "invalid request",
+		},
+		{
+			name:      "Not found",
+			id:        "1",
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+			mockError: storage.ErrWebhookNotFound,
+			mockCall:  true,
+		},
+		{
+			name:      "DeleteWebhook Error",
+			id:        "1",
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+			mockError: errors.New("unexpected error"),
+			mockCall:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			webhookDeleterMock := mocks.NewWebhookDeleter(t)
+
+			if tc.mockCall {
+				webhookDeleterMock.On("DeleteWebhook", mock.Anything, int64(1)).
+					Return(tc.mockError).
+					Once()
+			}
+
+			r := chi.NewRouter()
+			r.Delete("/admin/webhooks/{id}", delete.New(slogdiscard.NewDiscardLogger(), webhookDeleterMock))
+
+			req, err := http.NewRequest(http.MethodDelete, "/admin/webhooks/"+tc.id, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			require.Equal(t, tc.respError, body.Error.Error())
+		})
+	}
+}
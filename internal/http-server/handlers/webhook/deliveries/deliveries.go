@@ -0,0 +1,128 @@
+package deliveries
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+const defaultLimit = 50
+
+type Delivery struct {
+	ID    int64  `json:"id"`
+	Event string `json:"event"`
+	// Alias is the key of the link with the tenant prefix.
+	Alias    string `json:"alias"`
+	Attempts int    `json:"attempts"`
+	// StatusCode is the status of the last response, absent if there was none.
+	StatusCode int `json:"status_code,omitempty"`
+	// Error is absent for delivered events.
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Response struct {
+	resp.Response
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+// DeliveryLister is an interface for reading the delivery log of webhooks.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=DeliveryLister
+type DeliveryLister interface {
+	GetWebhook(ctx context.Context, id int64) (storage.Webhook, error)
+	ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]storage.WebhookDelivery, error)
+}
+
+var errInvalidLimit = errors.New("limit must be between 1 and " + strconv.Itoa(storage.WebhookDeliveryLog))
+
+// New returns handler of GET /admin/webhooks/{id}/deliveries listing the latest
+// deliveries of the webhook, newest first. The number is set by the "limit" query
+// parameter, 50 by default; the storage keeps the last 100.
+func New(log *slog.Logger, deliveryLister DeliveryLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhook.deliveries.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			log.Info("invalid webhook id", slog.String("id", chi.URLParam(r, "id")))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid request"))
+
+			return
+		}
+
+		limit := defaultLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > storage.WebhookDeliveryLog {
+				log.Info("invalid query", sl.Err(errInvalidLimit))
+
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, errInvalidLimit.Error()))
+
+				return
+			}
+		}
+
+		// Пустой журнал и удаленный вебхук должны различаться
+		_, err = deliveryLister.GetWebhook(r.Context(), id)
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			log.Info("webhook not found", slog.Int64("id", id))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get webhook", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		deliveries, err := deliveryLister.ListWebhookDeliveries(r.Context(), id, limit)
+		if err != nil {
+			log.Error("failed to list webhook deliveries", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		res := Response{Response: resp.OK(), Deliveries: make([]Delivery, 0, len(deliveries))}
+		for _, d := range deliveries {
+			res.Deliveries = append(res.Deliveries, Delivery{
+				ID:         d.ID,
+				Event:      d.Event,
+				Alias:      d.Alias,
+				Attempts:   d.Attempts,
+				StatusCode: d.StatusCode,
+				Error:      d.Error,
+				CreatedAt:  d.CreatedAt,
+			})
+		}
+
+		render.JSON(w, r, res)
+	}
+}
@@ -0,0 +1,110 @@
+package deliveries_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/webhook/deliveries"
+	"url-shortener/internal/http-server/handlers/webhook/deliveries/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestDeliveriesHandler(t *testing.T) {
+	createdAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name      string
+		path      string
+		respCode  int
+		respError string
+		getError  error
+		limit     int
+		want      []deliveries.Delivery
+	}{
+		{
+			name:     "Success",
+			path:     "/admin/webhooks/3/deliveries",
+			respCode: http.StatusOK,
+			limit:    50,
+			want: []deliveries.Delivery{
+				{ID: 2, Event: "link.clicked", Alias: "promo", Attempts: 4, StatusCode: 503, Error: "unexpected status 503", CreatedAt: createdAt},
+				{ID: 1, Event: "link.created", Alias: "promo", Attempts: 1, StatusCode: 200, CreatedAt: createdAt},
+			},
+		},
+		{
+			name:     "Limit",
+			path:     "/admin/webhooks/3/deliveries?limit=1",
+			respCode: http.StatusOK,
+			limit:    1,
+			want: []deliveries.Delivery{
+				{ID: 2, Event: "link.clicked", Alias: "promo", Attempts: 4, StatusCode: 503, Error: "unexpected status 503", CreatedAt: createdAt},
+			},
+		},
+		{
+			name:      "Invalid limit",
+			path:      "/admin/webhooks/3/deliveries?limit=1000",
+			respCode:  http.StatusBadRequest,
+			respError: "limit must be between 1 and 100",
+		},
+		{
+			name:      "Not found",
+			path:      "/admin/webhooks/3/deliveries",
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+			getError:  storage.ErrWebhookNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			deliveryListerMock := mocks.NewDeliveryLister(t)
+
+			if tc.limit > 0 || tc.getError != nil {
+				deliveryListerMock.On("GetWebhook", mock.Anything, int64(3)).
+					Return(storage.Webhook{ID: 3}, tc.getError).
+					Once()
+			}
+			if tc.limit > 0 {
+				var stored []storage.WebhookDelivery
+				for _, d := range tc.want {
+					stored = append(stored, storage.WebhookDelivery{
+						ID: d.ID, WebhookID: 3, Event: d.Event, Alias: d.Alias, Attempts: d.Attempts,
+						StatusCode: d.StatusCode, Error: d.Error, CreatedAt: d.CreatedAt,
+					})
+				}
+
+				deliveryListerMock.On("ListWebhookDeliveries", mock.Anything, int64(3), tc.limit).
+					Return(stored, nil).
+					Once()
+			}
+
+			r := chi.NewRouter()
+			r.Get("/admin/webhooks/{id}/deliveries", deliveries.New(slogdiscard.NewDiscardLogger(), deliveryListerMock))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp deliveries.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+
+			if tc.respCode == http.StatusOK {
+				require.Equal(t, tc.want, resp.Deliveries)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package list
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// Webhook is a subscription without its secret, which is shown only on creation.
+type Webhook struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Response struct {
+	resp.Response
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// WebhookLister is an interface for listing webhooks.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=WebhookLister
+type WebhookLister interface {
+	ListWebhooks(ctx context.Context) ([]storage.Webhook, error)
+}
+
+// New lists webhooks ordered by id.
+func New(log *slog.Logger, webhookLister WebhookLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhook.list.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		webhooks, err := webhookLister.ListWebhooks(r.Context())
+		if err != nil {
+			log.Error("failed to list webhooks", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		res := Response{Response: resp.OK(), Webhooks: make([]Webhook, 0, len(webhooks))}
+		for _, wh := range webhooks {
+			res.Webhooks = append(res.Webhooks, Webhook{
+				ID:        wh.ID,
+				URL:       wh.URL,
+				Events:    wh.Events,
+				CreatedAt: wh.CreatedAt,
+			})
+		}
+
+		render.JSON(w, r, res)
+	}
+}
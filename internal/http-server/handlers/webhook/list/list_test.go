@@ -0,0 +1,60 @@
+package list_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/webhook/list"
+	"url-shortener/internal/http-server/handlers/webhook/list/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestListHandler(t *testing.T) {
+	createdAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	webhookListerMock := mocks.NewWebhookLister(t)
+	webhookListerMock.On("ListWebhooks", mock.Anything).
+		Return([]storage.Webhook{
+			{ID: 1, URL: "https://hooks.example/a", Events: storage.EventTypes{"link.created"}, Secret: "s1", CreatedAt: createdAt},
+			{ID: 2, URL: "https://hooks.example/b", Events: storage.EventTypes{"link.clicked"}, Secret: "s2", CreatedAt: createdAt},
+		}, nil).
+		Once()
+
+	handler := list.New(slogdiscard.NewDiscardLogger(), webhookListerMock)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	// Секрет показывается только при создании
+	require.NotContains(t, rr.Body.String(), "s1")
+
+	var resp list.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Equal(t, []list.Webhook{
+		{ID: 1, URL: "https://hooks.example/a", Events: []string{"link.created"}, CreatedAt: createdAt},
+		{ID: 2, URL: "https://hooks.example/b", Events: []string{"link.clicked"}, CreatedAt: createdAt},
+	}, resp.Webhooks)
+}
+
+func TestListHandler_Error(t *testing.T) {
+	webhookListerMock := mocks.NewWebhookLister(t)
+	webhookListerMock.On("ListWebhooks", mock.Anything).
+		Return(nil, errors.New("unexpected error")).
+		Once()
+
+	handler := list.New(slogdiscard.NewDiscardLogger(), webhookListerMock)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil))
+
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+}
@@ -0,0 +1,152 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/webhook"
+)
+
+type Request struct {
+	URL *string `json:"url,omitempty" validate:"omitempty,url"`
+	// Events replace the subscribed event types.
+	Events []string `json:"events,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	ID     int64    `json:"id,omitempty"`
+	URL    string   `json:"url,omitempty"`
+	Events []string `json:"events,omitempty"`
+}
+
+// WebhookUpdater is an interface for changing webhooks.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=WebhookUpdater
+type WebhookUpdater interface {
+	GetWebhook(ctx context.Context, id int64) (storage.Webhook, error)
+	UpdateWebhook(ctx context.Context, webhook storage.Webhook) error
+}
+
+// New returns handler of PATCH /admin/webhooks/{id} changing the URL and events of
+// the webhook. The secret stays the same.
+func New(log *slog.Logger, webhookUpdater WebhookUpdater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhook.update.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			log.Info("invalid webhook id", slog.String("id", chi.URLParam(r, "id")))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "invalid request"))
+
+			return
+		}
+
+		var req Request
+
+		err = render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "empty request"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to decode request"))
+
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(r, validateErr))
+
+			return
+		}
+
+		if req.URL == nil && req.Events == nil {
+			log.Info("nothing to update")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "nothing to update"))
+
+			return
+		}
+
+		if req.Events != nil {
+			if err := webhook.ValidateEventTypes(req.Events); err != nil {
+				log.Info("invalid events", sl.Err(err))
+
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, err.Error()))
+
+				return
+			}
+		}
+
+		wh, err := webhookUpdater.GetWebhook(r.Context(), id)
+		if err == nil {
+			if req.URL != nil {
+				wh.URL = *req.URL
+			}
+			if req.Events != nil {
+				wh.Events = req.Events
+			}
+
+			err = webhookUpdater.UpdateWebhook(r.Context(), wh)
+		}
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			log.Info("webhook not found", slog.Int64("id", id))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(r, resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to update webhook", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("webhook updated", slog.Int64("id", id), slog.Any("events", wh.Events))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			ID:       id,
+			URL:      wh.URL,
+			Events:   wh.Events,
+		})
+	}
+}
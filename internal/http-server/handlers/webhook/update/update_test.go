@@ -0,0 +1,139 @@
+package update_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/webhook/update"
+	"url-shortener/internal/http-server/handlers/webhook/update/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestUpdateHandler(t *testing.T) {
+	saved := storage.Webhook{
+		ID:     7,
+		URL:    "https://hooks.example/links",
+		Events: storage.EventTypes{"link.created"},
+		Secret: "secret",
+	}
+
+	cases := []struct {
+		name      string
+		id        string
+		body      string
+		respCode  int
+		respError string
+		getError  error
+		updated   *storage.Webhook
+		mockError error
+	}{
+		{
+			name:     "Events",
+			id:       "7",
+			body:     `{"events": ["link.clicked", "link.deleted"]}`,
+			respCode: http.StatusOK,
+			updated: &storage.Webhook{
+				ID: 7, URL: "https://hooks.example/links", Events: storage.EventTypes{"link.clicked", "link.deleted"}, Secret: "secret",
+			},
+		},
+		{
+			name:     "URL",
+			id:       "7",
+			body:     `{"url": "https://hooks.example/v2"}`,
+			respCode: http.StatusOK,
+			updated: &storage.Webhook{
+				ID: 7, URL: "https://hooks.example/v2", Events: storage.EventTypes{"link.created"}, Secret: "secret",
+			},
+		},
+		{
+			name:      "Not found",
+			id:        "7",
+			body:      `{"url": "https://hooks.example/v2"}`,
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+			getError:  storage.ErrWebhookNotFound,
+		},
+		{
+			name:      "Nothing to update",
+			id:        "7",
+			body:      `{}`,
+			respCode:  http.StatusBadRequest,
+			respError: "nothing to update",
+		},
+		{
+			name:      "Empty events",
+			id:        "7",
+			body:      `{"events": []}`,
+			respCode:  http.StatusBadRequest,
+			respError: "events must not be empty",
+		},
+		{
+			name:      "Invalid id",
+			id:        "abc",
+			body:      `{"url": "https://hooks.example/v2"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "invalid request",
+		},
+		{
+			name:      "UpdateWebhook Error",
+			id:        "7",
+			body:      `{"url": "https://hooks.example/v2"}`,
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+			updated: &storage.Webhook{
+				ID: 7, URL: "https://hooks.example/v2", Events: storage.EventTypes{"link.created"}, Secret: "secret",
+			},
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			webhookUpdaterMock := mocks.NewWebhookUpdater(t)
+
+			if tc.updated != nil || tc.getError != nil {
+				webhookUpdaterMock.On("GetWebhook", mock.Anything, int64(7)).
+					Return(saved, tc.getError).
+					Once()
+			}
+			if tc.updated != nil {
+				webhookUpdaterMock.On("UpdateWebhook", mock.Anything, *tc.updated).
+					Return(tc.mockError).
+					Once()
+			}
+
+			r := chi.NewRouter()
+			r.Patch("/admin/webhooks/{id}", update.New(slogdiscard.NewDiscardLogger(), webhookUpdaterMock))
+
+			req, err := http.NewRequest(http.MethodPatch, "/admin/webhooks/"+tc.id, bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp update.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error.Error())
+
+			if tc.respCode == http.StatusOK {
+				require.Equal(t, tc.updated.URL, resp.URL)
+				require.Equal(t, []string(tc.updated.Events), resp.Events)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/acl"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+// Header carries the API key.
+const Header = "X-API-Key"
+
+// APIKeyGetter is an interface for looking up API keys by hash.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=APIKeyGetter
+type APIKeyGetter interface {
+	GetAPIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error)
+}
+
+// QueryParam carries the API key where clients can't set headers, e.g. bookmarklets.
+const QueryParam = "token"
+
+// New returns middleware rejecting requests without a valid API key.
+// The id of the key is put into request context, see apikey.KeyID; keys bound
+// to a tenant replace the tenant of the request, see tenant.FromContext; the role
+// of the key is put into request context too, see acl.Role.
+func New(log *slog.Logger, keyGetter APIKeyGetter) func(next http.Handler) http.Handler {
+	return newMiddleware(log, keyGetter, false)
+}
+
+// NewWithQuery is New which also accepts the key in the QueryParam query parameter.
+// The access log leaves it out, but browsers keep it in the history, so it is meant
+// for endpoints of bookmarklets and browser extensions only.
+func NewWithQuery(log *slog.Logger, keyGetter APIKeyGetter) func(next http.Handler) http.Handler {
+	return newMiddleware(log, keyGetter, true)
+}
+
+func newMiddleware(log *slog.Logger, keyGetter APIKeyGetter, query bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/apikey"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			log := log.With(
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+			)
+
+			key := r.Header.Get(Header)
+			if key == "" && query {
+				key = r.URL.Query().Get(QueryParam)
+			}
+			if key == "" {
+				log.Info("api key is missing")
+
+				unauthorized(w, r)
+
+				return
+			}
+
+			apiKey, err := keyGetter.GetAPIKeyByHash(r.Context(), apikey.Hash(key))
+			if errors.Is(err, storage.ErrAPIKeyNotFound) {
+				log.Info("api key not found")
+
+				unauthorized(w, r)
+
+				return
+			}
+			if err != nil {
+				log.Error("failed to get api key", sl.Err(err))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+				return
+			}
+			if !apiKey.RevokedAt.IsZero() {
+				log.Info("api key is revoked", slog.Int64("api_key_id", apiKey.ID))
+
+				unauthorized(w, r)
+
+				return
+			}
+
+			ctx := apikey.WithKeyID(r.Context(), apiKey.ID)
+			// Ключи тенанта по умолчанию работают в тенанте запроса
+			if apiKey.Tenant != tenant.Default {
+				ctx = tenant.WithTenant(ctx, apiKey.Tenant)
+			}
+			ctx = acl.WithRole(ctx, apiKey.Role)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, http.StatusUnauthorized)
+	render.JSON(w, r, resp.Error(r, resp.CodeUnauthorized, "unauthorized"))
+}
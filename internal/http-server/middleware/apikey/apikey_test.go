@@ -0,0 +1,160 @@
+package apikey_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/acl"
+	mwAPIKey "url-shortener/internal/http-server/middleware/apikey"
+	"url-shortener/internal/http-server/middleware/apikey/mocks"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	cases := []struct {
+		name      string
+		key       string
+		apiKey    storage.APIKey
+		mockError error
+		respCode  int
+		// reqTenant is resolved from the request, wantTenant is passed to the handler
+		reqTenant  string
+		wantTenant string
+		wantRole   string
+	}{
+		{
+			name:     "Success",
+			key:      "valid_key",
+			apiKey:   storage.APIKey{ID: 7},
+			respCode: http.StatusOK,
+		},
+		{
+			name:       "Default tenant key",
+			key:        "valid_key",
+			apiKey:     storage.APIKey{ID: 7},
+			respCode:   http.StatusOK,
+			reqTenant:  "brand",
+			wantTenant: "brand",
+		},
+		{
+			name:       "Tenant key",
+			key:        "valid_key",
+			apiKey:     storage.APIKey{ID: 7, Tenant: "brand"},
+			respCode:   http.StatusOK,
+			reqTenant:  "other",
+			wantTenant: "brand",
+		},
+		{
+			name:     "Admin key",
+			key:      "valid_key",
+			apiKey:   storage.APIKey{ID: 7, Role: acl.RoleAdmin},
+			respCode: http.StatusOK,
+			wantRole: acl.RoleAdmin,
+		},
+		{
+			name:     "Missing key",
+			respCode: http.StatusUnauthorized,
+		},
+		{
+			name:      "Unknown key",
+			key:       "unknown_key",
+			mockError: storage.ErrAPIKeyNotFound,
+			respCode:  http.StatusUnauthorized,
+		},
+		{
+			name:     "Revoked key",
+			key:      "revoked_key",
+			apiKey:   storage.APIKey{ID: 7, RevokedAt: time.Now()},
+			respCode: http.StatusUnauthorized,
+		},
+		{
+			name:      "GetAPIKeyByHash Error",
+			key:       "valid_key",
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			keyGetterMock := mocks.NewAPIKeyGetter(t)
+
+			if tc.key != "" {
+				keyGetterMock.On("GetAPIKeyByHash", mock.Anything, apikey.Hash(tc.key)).
+					Return(tc.apiKey, tc.mockError).
+					Once()
+			}
+
+			var (
+				keyID     int64
+				keyTenant string
+				keyRole   string
+			)
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				keyID = apikey.KeyID(r.Context())
+				keyTenant = tenant.FromContext(r.Context())
+				keyRole = acl.Role(r.Context())
+			})
+
+			handler := mwAPIKey.New(slogdiscard.NewDiscardLogger(), keyGetterMock)(next)
+
+			req := httptest.NewRequest(http.MethodGet, "/url", nil)
+			req = req.WithContext(tenant.WithTenant(req.Context(), tc.reqTenant))
+			if tc.key != "" {
+				req.Header.Set(mwAPIKey.Header, tc.key)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			if tc.respCode == http.StatusOK {
+				require.Equal(t, tc.apiKey.ID, keyID)
+				require.Equal(t, tc.wantTenant, keyTenant)
+				wantRole := tc.wantRole
+				if wantRole == "" {
+					wantRole = acl.RoleEditor
+				}
+				require.Equal(t, wantRole, keyRole)
+			}
+		})
+	}
+}
+
+func TestAPIKeyMiddleware_Query(t *testing.T) {
+	keyGetterMock := mocks.NewAPIKeyGetter(t)
+	keyGetterMock.On("GetAPIKeyByHash", mock.Anything, apikey.Hash("valid_key")).
+		Return(storage.APIKey{ID: 7}, nil).
+		Once()
+
+	var keyID int64
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyID = apikey.KeyID(r.Context())
+	})
+
+	// Ключ в запросе принимается только там, где это явно разрешено
+	rr := httptest.NewRecorder()
+	mwAPIKey.New(slogdiscard.NewDiscardLogger(), keyGetterMock)(next).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url?token=valid_key", nil))
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	rr = httptest.NewRecorder()
+	mwAPIKey.NewWithQuery(slogdiscard.NewDiscardLogger(), keyGetterMock)(next).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/shorten?token=valid_key", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, int64(7), keyID)
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/acl"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/jwt"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tenant"
+)
+
+const bearerPrefix = "Bearer "
+
+// New returns middleware authenticating requests with a JWT from the
+// Authorization header. The id, the tenant and the role of the user are put into
+// request context, see jwt.UserID, tenant.FromContext and acl.Role. Requests without a bearer token are passed to fallback,
+// e.g. API key authentication.
+func New(
+	log *slog.Logger,
+	secret string,
+	fallback func(next http.Handler) http.Handler,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/auth"),
+		)
+
+		fallbackHandler := fallback(next)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if !strings.HasPrefix(header, bearerPrefix) {
+				fallbackHandler.ServeHTTP(w, r)
+
+				return
+			}
+
+			claims, err := jwt.ParseToken(strings.TrimPrefix(header, bearerPrefix), secret)
+			if err != nil {
+				log.Info("invalid token",
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+					sl.Err(err),
+				)
+
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error(r, resp.CodeUnauthorized, "unauthorized"))
+
+				return
+			}
+
+			// Пользователь работает только в своем тенанте, независимо от адреса запроса
+			ctx := jwt.WithUserID(r.Context(), claims.UserID)
+			ctx = tenant.WithTenant(ctx, claims.Tenant)
+			ctx = acl.WithRole(ctx, claims.Role)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,98 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/acl"
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/jwt"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	const secret = "test-secret"
+
+	validToken, err := jwt.NewToken(storage.User{ID: 7, Tenant: "brand", Role: acl.RoleViewer}, secret, time.Hour)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name          string
+		authorization string
+		respCode      int
+		userID        int64
+		tenant        string
+		role          string
+		fallback      bool
+	}{
+		{
+			name:          "Valid token",
+			authorization: "Bearer " + validToken,
+			respCode:      http.StatusOK,
+			userID:        7,
+			tenant:        "brand",
+			role:          acl.RoleViewer,
+		},
+		{
+			name:          "Invalid token",
+			authorization: "Bearer invalid",
+			respCode:      http.StatusUnauthorized,
+		},
+		{
+			name:     "No token",
+			respCode: http.StatusTeapot,
+			fallback: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				userID     int64
+				userTenant string
+				userRole   string
+			)
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userID = jwt.UserID(r.Context())
+				userTenant = tenant.FromContext(r.Context())
+				userRole = acl.Role(r.Context())
+			})
+
+			fallbackCalled := false
+			fallback := func(http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					fallbackCalled = true
+					w.WriteHeader(http.StatusTeapot)
+				})
+			}
+
+			handler := auth.New(slogdiscard.NewDiscardLogger(), secret, fallback)(next)
+
+			req := httptest.NewRequest(http.MethodGet, "/url", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+			require.Equal(t, tc.userID, userID)
+			require.Equal(t, tc.tenant, userTenant)
+			if tc.role != "" {
+				require.Equal(t, tc.role, userRole)
+			}
+			require.Equal(t, tc.fallback, fallbackCalled)
+		})
+	}
+}
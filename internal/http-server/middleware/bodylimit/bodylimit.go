@@ -0,0 +1,42 @@
+package bodylimit
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+)
+
+// New returns middleware limiting request bodies to maxBytes. Requests declaring
+// a larger Content-Length get 413 right away; bodies sent without it fail to read
+// past the limit, and handlers report request.ErrTooLarge.
+func New(log *slog.Logger, maxBytes int64) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/bodylimit"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				log.Info("request body is too large",
+					slog.Int64("content_length", r.ContentLength),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+				)
+
+				render.Status(r, http.StatusRequestEntityTooLarge)
+				render.JSON(w, r, resp.Error(r, resp.CodeTooLarge, "request body is too large"))
+
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,38 @@
+package bodylimit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestBodyLimit(t *testing.T) {
+	handler := New(slogdiscard.NewDiscardLogger(), 8)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := io.ReadAll(r.Body); err != nil {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+			}
+		}),
+	)
+
+	request := func(body string, contentLength int64) int {
+		req := httptest.NewRequest(http.MethodPost, "/url", strings.NewReader(body))
+		req.ContentLength = contentLength
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr.Code
+	}
+
+	require.Equal(t, http.StatusOK, request("12345678", 8))
+	require.Equal(t, http.StatusRequestEntityTooLarge, request("123456789", 9))
+	// Без Content-Length тело обрезается при чтении
+	require.Equal(t, http.StatusRequestEntityTooLarge, request("123456789", -1))
+}
@@ -0,0 +1,138 @@
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options configure cross-origin requests of browser apps.
+type Options struct {
+	// AllowedOrigins are origins like "https://dash.example"; "*" allows any origin.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// ExposedHeaders are response headers scripts may read, e.g. ETag.
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies and HTTP authentication. With it
+	// "*" echoes the request origin, as browsers reject the wildcard.
+	AllowCredentials bool
+	// MaxAge is how long browsers cache preflight responses.
+	MaxAge time.Duration
+	// SkipPaths are passed on untouched, e.g. endpoints with their own CORS policy.
+	SkipPaths []string
+}
+
+// New returns middleware answering preflight requests and adding CORS headers
+// to responses for allowed origins. Requests of other origins are passed on without
+// the headers, so browsers block them; their preflights get 403.
+func New(opts Options) func(next http.Handler) http.Handler {
+	anyOrigin := false
+	origins := make(map[string]struct{}, len(opts.AllowedOrigins))
+	for _, origin := range opts.AllowedOrigins {
+		if origin == "*" {
+			anyOrigin = true
+			continue
+		}
+		origins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = struct{}{}
+	}
+
+	methods := make(map[string]struct{}, len(opts.AllowedMethods))
+	for _, method := range opts.AllowedMethods {
+		methods[strings.ToUpper(method)] = struct{}{}
+	}
+
+	allowMethods := strings.Join(opts.AllowedMethods, ", ")
+	allowHeaders := strings.Join(opts.AllowedHeaders, ", ")
+	exposeHeaders := strings.Join(opts.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(opts.MaxAge.Seconds()))
+
+	allowed := func(origin string) bool {
+		if anyOrigin {
+			return true
+		}
+		_, ok := origins[strings.ToLower(origin)]
+
+		return ok
+	}
+
+	skip := make(map[string]struct{}, len(opts.SkipPaths))
+	for _, path := range opts.SkipPaths {
+		skip[path] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := skip[r.URL.Path]; ok {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			// Ответ зависит от Origin, общие кеши должны это учитывать
+			w.Header().Add("Vary", "Origin")
+
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			if !allowed(origin) {
+				if preflight {
+					w.WriteHeader(http.StatusForbidden)
+
+					return
+				}
+
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			if anyOrigin && !opts.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			if opts.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if exposeHeaders != "" {
+					w.Header().Set("Access-Control-Expose-Headers", exposeHeaders)
+				}
+
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+			if _, ok := methods[strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))]; !ok {
+				w.WriteHeader(http.StatusForbidden)
+
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+			if allowHeaders != "" {
+				w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+			}
+			if opts.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", maxAge)
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
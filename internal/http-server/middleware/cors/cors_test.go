@@ -0,0 +1,112 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORS(t *testing.T) {
+	opts := Options{
+		AllowedOrigins: []string{"https://dash.example"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		ExposedHeaders: []string{"ETag"},
+		MaxAge:         10 * time.Minute,
+	}
+
+	handler := New(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	request := func(method, origin, requestMethod string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/url", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if requestMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", requestMethod)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	// Preflight
+	rr := request(http.MethodOptions, "https://dash.example", "POST")
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Equal(t, "https://dash.example", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST", rr.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Authorization, Content-Type", rr.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", rr.Header().Get("Access-Control-Max-Age"))
+
+	require.Equal(t, http.StatusForbidden, request(http.MethodOptions, "https://dash.example", "DELETE").Code)
+	require.Equal(t, http.StatusForbidden, request(http.MethodOptions, "https://evil.example", "POST").Code)
+
+	// Actual request
+	rr = request(http.MethodGet, "https://dash.example", "")
+	require.Equal(t, http.StatusTeapot, rr.Code)
+	assert.Equal(t, "https://dash.example", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "ETag", rr.Header().Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, "Origin", rr.Header().Get("Vary"))
+
+	rr = request(http.MethodGet, "https://evil.example", "")
+	require.Equal(t, http.StatusTeapot, rr.Code)
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+
+	// Same-origin and non-browser requests are untouched
+	rr = request(http.MethodGet, "", "")
+	require.Equal(t, http.StatusTeapot, rr.Code)
+	assert.Empty(t, rr.Header().Get("Vary"))
+}
+
+func TestCORS_AnyOrigin(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, tc := range []struct {
+		credentials bool
+		allowOrigin string
+	}{
+		{credentials: false, allowOrigin: "*"},
+		{credentials: true, allowOrigin: "https://any.example"},
+	} {
+		handler := New(Options{AllowedOrigins: []string{"*"}, AllowCredentials: tc.credentials})(next)
+
+		req := httptest.NewRequest(http.MethodGet, "/url", nil)
+		req.Header.Set("Origin", "https://any.example")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, tc.allowOrigin, rr.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestCORS_SkipPaths(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	handler := New(Options{
+		AllowedOrigins: []string{"https://dash.example"},
+		AllowedMethods: []string{http.MethodGet},
+		SkipPaths:      []string{"/api/shorten"},
+	})(next)
+
+	// Preflight другого сайта доходит до маршрута со своей политикой
+	req := httptest.NewRequest(http.MethodOptions, "/api/shorten", nil)
+	req.Header.Set("Origin", "https://other.example")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusTeapot, rr.Code)
+	assert.Empty(t, rr.Header().Get("Vary"))
+}
@@ -0,0 +1,55 @@
+package ipfilter
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/clientip"
+)
+
+type Options struct {
+	// Allow are the only networks let in; empty lets in any not denied.
+	Allow []netip.Prefix
+	// Deny are networks rejected even if allowed.
+	Deny []netip.Prefix
+}
+
+// New returns middleware rejecting requests of clients outside the allowed networks
+// or inside the denied ones with 403. Behind proxies it must follow the realip middleware.
+func New(log *slog.Logger, opts Options) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/ipfilter"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ip := clientip.PeerIP(r)
+
+			denied := clientip.Contains(opts.Deny, ip)
+			if !denied && len(opts.Allow) > 0 {
+				denied = !clientip.Contains(opts.Allow, ip)
+			}
+
+			if denied {
+				log.Info("ip is not allowed",
+					slog.String("ip", ip.String()),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+				)
+
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.Error(r, resp.CodeForbidden, "forbidden"))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,54 @@
+package ipfilter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/clientip"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestIPFilter(t *testing.T) {
+	mustParse := func(ss ...string) []netip.Prefix {
+		prefixes, err := clientip.ParsePrefixes(ss)
+		require.NoError(t, err)
+
+		return prefixes
+	}
+
+	handler := New(slogdiscard.NewDiscardLogger(), Options{
+		Allow: mustParse("192.168.0.0/16", "2001:db8::/32"),
+		Deny:  mustParse("192.168.66.0/24"),
+	})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	request := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.RemoteAddr = remoteAddr
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr.Code
+	}
+
+	require.Equal(t, http.StatusOK, request("192.168.1.5:1234"))
+	require.Equal(t, http.StatusOK, request("[2001:db8::5]:1234"))
+	require.Equal(t, http.StatusForbidden, request("203.0.113.7:1234"))
+	require.Equal(t, http.StatusForbidden, request("192.168.66.5:1234"))
+	// Адрес, подставленный middleware realip, без порта
+	require.Equal(t, http.StatusOK, request("192.168.1.5"))
+	require.Equal(t, http.StatusForbidden, request("garbage"))
+
+	// Без списка разрешенных пускаем всех, кроме запрещенных
+	handler = New(slogdiscard.NewDiscardLogger(), Options{Deny: mustParse("203.0.113.0/24")})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+	require.Equal(t, http.StatusOK, request("198.51.100.1:1234"))
+	require.Equal(t, http.StatusForbidden, request("203.0.113.7:1234"))
+}
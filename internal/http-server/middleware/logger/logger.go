@@ -2,32 +2,77 @@ package logger
 
 import (
 	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"golang.org/x/exp/slog"
 )
 
-func New(log *slog.Logger) func(next http.Handler) http.Handler {
+// Options control what the access log contains.
+type Options struct {
+	// SampleRedirects logs one of every N successful redirects; 0 and 1 log all of them.
+	// Errors and other requests are always logged.
+	SampleRedirects int
+	SkipUserAgent   bool
+	// SkipQuery leaves the query string out; it may contain campaign tags or tokens.
+	SkipQuery bool
+	// SkipPaths are not logged at all, e.g. probes of load balancers.
+	SkipPaths []string
+}
+
+// redactedParams are query parameters carrying credentials, e.g. the API key of
+// GET /api/shorten; their values are never logged.
+var redactedParams = []string{"token"}
+
+func New(log *slog.Logger, opts Options) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		log := log.With(
 			slog.String("component", "middleware/logger"),
 		)
 
-		log.Info("logger middleware enabled")
+		log.Info("logger middleware enabled", slog.Int("sample_redirects", opts.SampleRedirects))
+
+		skip := make(map[string]struct{}, len(opts.SkipPaths))
+		for _, path := range opts.SkipPaths {
+			skip[path] = struct{}{}
+		}
+
+		var redirects atomic.Uint64
 
 		fn := func(w http.ResponseWriter, r *http.Request) {
-			entry := log.With(
+			if _, ok := skip[r.URL.Path]; ok {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			attrs := []any{
 				slog.String("method", r.Method),
 				slog.String("path", r.URL.Path),
-				slog.String("remote_addr", r.RemoteAddr),
-				slog.String("user_agent", r.UserAgent()),
-				slog.String("request_id", middleware.GetReqID(r.Context())),
-			)
+			}
+			if !opts.SkipQuery && r.URL.RawQuery != "" {
+				attrs = append(attrs, slog.String("query", redactQuery(r.URL.RawQuery)))
+			}
+			attrs = append(attrs, slog.String("remote_addr", r.RemoteAddr))
+			if !opts.SkipUserAgent {
+				attrs = append(attrs, slog.String("user_agent", r.UserAgent()))
+			}
+			attrs = append(attrs, slog.String("request_id", middleware.GetReqID(r.Context())))
+
+			entry := log.With(attrs...)
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
 			t1 := time.Now()
 			defer func() {
+				// Редиректов на порядки больше остальных запросов, в лог попадает каждый N-й
+				if opts.SampleRedirects > 1 && isRedirect(ww.Status()) &&
+					redirects.Add(1)%uint64(opts.SampleRedirects) != 1 {
+					return
+				}
+
 				entry.Info("request completed",
 					slog.Int("status", ww.Status()),
 					slog.Int("bytes", ww.BytesWritten()),
@@ -41,3 +86,35 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 		return http.HandlerFunc(fn)
 	}
 }
+
+// redactQuery replaces values of redactedParams in the raw query.
+func redactQuery(rawQuery string) string {
+	found := false
+	for _, param := range redactedParams {
+		if strings.Contains(rawQuery, param+"=") {
+			found = true
+		}
+	}
+	if !found {
+		return rawQuery
+	}
+
+	// Некорректные пары отбрасываются: в них тоже может оказаться токен
+	q, _ := url.ParseQuery(rawQuery)
+	for _, param := range redactedParams {
+		if _, ok := q[param]; ok {
+			q.Set(param, "REDACTED")
+		}
+	}
+
+	return q.Encode()
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
+}
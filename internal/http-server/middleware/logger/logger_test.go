@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	handler := New(log, Options{SampleRedirects: 3, SkipUserAgent: true, SkipQuery: true, SkipPaths: []string{"/health"}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/missing" {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+			http.Redirect(w, r, "https://example.com/", http.StatusFound)
+		}),
+	)
+
+	request := func(target string) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("User-Agent", "test-agent")
+
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	buf.Reset()
+	for i := 0; i < 6; i++ {
+		request("/alias?utm_source=secret")
+	}
+	request("/missing")
+	request("/health")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	// 2 из 6 редиректов и ошибка; проба не логируется
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[2], `"status":404`)
+
+	for _, line := range lines {
+		assert.NotContains(t, line, "test-agent")
+		assert.NotContains(t, line, "utm_source")
+	}
+}
+
+func TestLogger_Fields(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	handler := New(log, Options{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	buf.Reset()
+	req := httptest.NewRequest(http.MethodGet, "/url?limit=10", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Contains(t, buf.String(), `"query":"limit=10"`)
+	assert.Contains(t, buf.String(), `"user_agent":"test-agent"`)
+}
+
+func TestLogger_RedactsToken(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	handler := New(log, Options{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	buf.Reset()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/shorten?url=https%3A%2F%2Fexample.com&token=secret-key", nil))
+
+	assert.NotContains(t, buf.String(), "secret-key")
+	assert.Contains(t, buf.String(), `"query":"token=REDACTED&url=https%3A%2F%2Fexample.com"`)
+}
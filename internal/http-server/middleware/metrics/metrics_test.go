@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	r := chi.NewRouter()
+	r.Use(New(reg))
+	r.Get("/{alias}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusFound)
+	})
+
+	for _, alias := range []string{"a", "b"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/"+alias, nil))
+	}
+
+	count, err := testutil.GatherAndCount(reg, "http_requests_total")
+	require.NoError(t, err)
+	// Оба запроса попадают в одну серию с route="/{alias}"
+	require.Equal(t, 1, count)
+}
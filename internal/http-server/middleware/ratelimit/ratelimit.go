@@ -0,0 +1,163 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+	"golang.org/x/time/rate"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/jwt"
+)
+
+// idleTTL is how long a bucket of an idle client is kept.
+const idleTTL = 10 * time.Minute
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Limiter is a set of token buckets, one per client key.
+type Limiter struct {
+	rps   rate.Limit
+	burst int
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewLimiter returns limiter allowing rps requests per second per key
+// with bursts of up to burst requests.
+func NewLimiter(rps float64, burst int) *Limiter {
+	return &Limiter{
+		rps:       rate.Limit(rps),
+		burst:     burst,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// SetLimit changes the rate and the burst of all clients, including the ones already seen.
+func (l *Limiter) SetLimit(rps float64, burst int) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rps = rate.Limit(rps)
+	l.burst = burst
+
+	for _, b := range l.buckets {
+		b.limiter.SetLimitAt(now, l.rps)
+		b.limiter.SetBurstAt(now, l.burst)
+	}
+}
+
+// Allow takes a token from the bucket of key. If there is none,
+// it returns false and the time until the next token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(l.rps, l.burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	res := b.limiter.ReserveN(now, 1)
+	if delay := res.DelayFrom(now); delay > 0 {
+		// Токен не берем: отклоненный запрос не должен отодвигать следующие
+		res.CancelAt(now)
+
+		return false, delay
+	}
+
+	return true, 0
+}
+
+// sweep drops buckets of idle clients, so the map does not grow forever.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleTTL {
+		return
+	}
+
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > idleTTL {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.lastSweep = now
+}
+
+// New returns middleware limiting requests by the key returned by keyFunc.
+// Rejected requests get 429 with Retry-After header.
+func New(log *slog.Logger, limiter *Limiter, keyFunc func(r *http.Request) string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/ratelimit"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			key := keyFunc(r)
+
+			if ok, retryAfter := limiter.Allow(key); !ok {
+				log.Info("rate limit exceeded",
+					slog.String("key", key),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+				)
+
+				seconds := int(math.Ceil(retryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+
+				render.Status(r, http.StatusTooManyRequests)
+				render.JSON(w, r, resp.Error(r, resp.CodeRateLimited, "too many requests"))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// ByIP keys requests by client IP.
+func ByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "ip:" + r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// ByClient keys requests by API key or user, falling back to client IP.
+// It must be used after authentication middleware.
+func ByClient(r *http.Request) string {
+	if id := apikey.KeyID(r.Context()); id != 0 {
+		return "key:" + strconv.FormatInt(id, 10)
+	}
+	if id := jwt.UserID(r.Context()); id != 0 {
+		return "user:" + strconv.FormatInt(id, 10)
+	}
+
+	return ByIP(r)
+}
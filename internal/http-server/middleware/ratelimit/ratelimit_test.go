@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestRateLimit(t *testing.T) {
+	limiter := NewLimiter(1, 2)
+
+	handler := New(slogdiscard.NewDiscardLogger(), limiter, ByClient)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	request := func(remoteAddr string, keyID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/test_alias", nil)
+		req.RemoteAddr = remoteAddr
+		if keyID != 0 {
+			req = req.WithContext(apikey.WithKeyID(req.Context(), keyID))
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	// Burst
+	require.Equal(t, http.StatusOK, request("10.0.0.1:1000", 0).Code)
+	require.Equal(t, http.StatusOK, request("10.0.0.1:1001", 0).Code)
+
+	rr := request("10.0.0.1:1002", 0)
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	require.Equal(t, "1", rr.Header().Get("Retry-After"))
+
+	// Other clients have their own buckets
+	require.Equal(t, http.StatusOK, request("10.0.0.2:1000", 0).Code)
+	require.Equal(t, http.StatusOK, request("10.0.0.1:1003", 1).Code)
+
+	// New limits apply to new and known clients
+	limiter.SetLimit(1000, 3)
+	for i := 0; i < 3; i++ {
+		require.Equal(t, http.StatusOK, request("10.0.0.3:1000", 0).Code)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	require.Equal(t, http.StatusOK, request("10.0.0.1:1004", 0).Code)
+}
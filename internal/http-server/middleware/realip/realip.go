@@ -0,0 +1,26 @@
+package realip
+
+import (
+	"net/http"
+
+	"url-shortener/internal/lib/clientip"
+)
+
+// New returns middleware replacing the remote address of requests with the client
+// address found by resolver, so rate limits, analytics, geo targeting and logs see
+// the client instead of the load balancer. X-Forwarded-For and X-Real-IP of
+// untrusted peers are ignored, so clients can't spoof their address.
+func New(resolver *clientip.Resolver) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			// Адрес без порта: порт клиента за прокси неизвестен
+			if ip := resolver.ClientIP(r); ip.IsValid() && ip != clientip.PeerIP(r) {
+				r.RemoteAddr = ip.String()
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
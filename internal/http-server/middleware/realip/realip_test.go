@@ -0,0 +1,40 @@
+package realip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/clientip"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted, err := clientip.ParsePrefixes([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	resolver, err := clientip.New(trusted, clientip.HeaderForwardedFor)
+	require.NoError(t, err)
+
+	var remoteAddr string
+	handler := New(resolver)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteAddr = r.RemoteAddr
+	}))
+
+	request := func(peer, forwardedFor string) string {
+		req := httptest.NewRequest(http.MethodGet, "/alias", nil)
+		req.RemoteAddr = peer
+		req.Header.Set(clientip.HeaderForwardedFor, forwardedFor)
+
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		return remoteAddr
+	}
+
+	require.Equal(t, "203.0.113.7", request("10.0.0.1:1234", "203.0.113.7"))
+	require.Equal(t, "2001:db8::1", request("10.0.0.1:1234", "2001:db8::1"))
+	// Заголовок от клиента напрямую - подделка
+	require.Equal(t, "198.51.100.1:1234", request("198.51.100.1:1234", "203.0.113.7"))
+	require.Equal(t, "10.0.0.1:1234", request("10.0.0.1:1234", ""))
+}
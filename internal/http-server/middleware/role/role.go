@@ -0,0 +1,41 @@
+package role
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/acl"
+	resp "url-shortener/internal/lib/api/response"
+)
+
+// New returns middleware rejecting requests of clients without the permissions
+// of the required role, see acl.Allows. It must follow the authentication middleware.
+func New(log *slog.Logger, required string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/role"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if role := acl.Role(r.Context()); !acl.Allows(role, required) {
+				log.Info("role is not allowed",
+					slog.String("role", role),
+					slog.String("required", required),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+				)
+
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.Error(r, resp.CodeForbidden, "forbidden"))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,35 @@
+package role
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/acl"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestRole(t *testing.T) {
+	handler := New(slogdiscard.NewDiscardLogger(), acl.RoleEditor)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	request := func(role string) int {
+		req := httptest.NewRequest(http.MethodPost, "/url", nil)
+		req = req.WithContext(acl.WithRole(context.Background(), role))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr.Code
+	}
+
+	require.Equal(t, http.StatusForbidden, request(acl.RoleViewer))
+	require.Equal(t, http.StatusOK, request(acl.RoleEditor))
+	require.Equal(t, http.StatusOK, request(acl.RoleAdmin))
+	// Ключи без роли созданы до появления ролей и остаются редакторами
+	require.Equal(t, http.StatusOK, request(""))
+}
@@ -0,0 +1,50 @@
+package sentry
+
+import (
+	"net/http"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// New returns middleware reporting panics to Sentry with the request, its ID
+// and the route, then panicking again for middleware.Recoverer, which answers 500.
+// It must be used after Recoverer.
+func New(hub *sentry.Hub) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+
+				// ErrAbortHandler - штатный способ оборвать ответ, а не сбой
+				if rec != http.ErrAbortHandler {
+					report(hub, r, rec)
+				}
+
+				panic(rec)
+			}()
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func report(hub *sentry.Hub, r *http.Request, rec any) {
+	hub = hub.Clone()
+
+	scope := hub.Scope()
+	scope.SetRequest(r)
+	scope.SetTag("request_id", middleware.GetReqID(r.Context()))
+	// Шаблон маршрута к моменту паники уже известен
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		scope.SetTag("route", rctx.RoutePattern())
+	}
+
+	hub.RecoverWithContext(r.Context(), rec)
+}
@@ -0,0 +1,73 @@
+package sentry_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mwSentry "url-shortener/internal/http-server/middleware/sentry"
+)
+
+// transport keeps events instead of sending them.
+type transport struct {
+	mu     sync.Mutex
+	events []*sentry.Event
+}
+
+func (t *transport) Configure(sentry.ClientOptions) {}
+func (t *transport) Flush(time.Duration) bool       { return true }
+func (t *transport) SendEvent(event *sentry.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.events = append(t.events, event)
+}
+
+func TestSentry(t *testing.T) {
+	tr := &transport{}
+	client, err := sentry.NewClient(sentry.ClientOptions{Transport: tr, AttachStacktrace: true})
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Use(middleware.Recoverer)
+	router.Use(mwSentry.New(sentry.NewHub(client, sentry.NewScope())))
+	router.Get("/url/{alias}", func(http.ResponseWriter, *http.Request) {
+		panic(errors.New("boom"))
+	})
+	router.Get("/abort", func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+	router.Get("/ok", func(http.ResponseWriter, *http.Request) {})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/abc", nil))
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	assert.Panics(t, func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+	})
+
+	require.Len(t, tr.events, 1)
+
+	event := tr.events[0]
+	assert.Equal(t, "/url/{alias}", event.Tags["route"])
+	assert.NotEmpty(t, event.Tags["request_id"])
+	assert.Contains(t, event.Request.URL, "/url/abc")
+	require.Len(t, event.Exception, 1)
+	assert.Equal(t, "boom", event.Exception[0].Value)
+	assert.NotNil(t, event.Exception[0].Stacktrace)
+}
@@ -0,0 +1,236 @@
+package signature
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/acl"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/signature"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+// Allowed length of nonces.
+const (
+	minNonceLen = 16
+	maxNonceLen = 128
+)
+
+// APIKeyGetter is an interface for looking up API keys by id.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=APIKeyGetter
+type APIKeyGetter interface {
+	GetAPIKey(ctx context.Context, id int64) (storage.APIKey, error)
+}
+
+type Options struct {
+	// MaxSkew is how far the timestamp of a request may be from the server time.
+	MaxSkew time.Duration
+	// MaxBodySize limits bodies of signed requests, they are read into memory to check the signature.
+	MaxBodySize int64
+}
+
+// New returns middleware authenticating requests signed with the signing secret of
+// an API key, see package lib/signature. The request path is checked as received
+// by the service, so proxies must not rewrite it. The context is set up like by
+// the apikey middleware. Requests without a signature are passed to fallback.
+//
+// Nonces are remembered in memory until the timestamps of their requests leave
+// MaxSkew, so with several instances a request may be replayed once per instance
+// within MaxSkew.
+func New(
+	log *slog.Logger,
+	keyGetter APIKeyGetter,
+	opts Options,
+	fallback func(next http.Handler) http.Handler,
+) func(next http.Handler) http.Handler {
+	seen := newNonces()
+
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/signature"),
+		)
+
+		fallbackHandler := fallback(next)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			sig := r.Header.Get(signature.HeaderSignature)
+			if sig == "" {
+				fallbackHandler.ServeHTTP(w, r)
+
+				return
+			}
+
+			log := log.With(
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+			)
+
+			keyID, err := strconv.ParseInt(r.Header.Get(signature.HeaderKeyID), 10, 64)
+			if err != nil || keyID <= 0 {
+				log.Info("invalid key id")
+
+				unauthorized(w, r, "unauthorized")
+
+				return
+			}
+
+			timestamp := r.Header.Get(signature.HeaderTimestamp)
+			unix, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil {
+				log.Info("invalid timestamp", slog.Int64("api_key_id", keyID))
+
+				unauthorized(w, r, "unauthorized")
+
+				return
+			}
+
+			now := time.Now()
+			signedAt := time.Unix(unix, 0)
+			if signedAt.Before(now.Add(-opts.MaxSkew)) || signedAt.After(now.Add(opts.MaxSkew)) {
+				log.Info("timestamp out of range", slog.Int64("api_key_id", keyID), slog.Time("timestamp", signedAt))
+
+				unauthorized(w, r, "timestamp is too far from server time")
+
+				return
+			}
+
+			nonce := r.Header.Get(signature.HeaderNonce)
+			if len(nonce) < minNonceLen || len(nonce) > maxNonceLen {
+				log.Info("invalid nonce", slog.Int64("api_key_id", keyID))
+
+				unauthorized(w, r, "unauthorized")
+
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, opts.MaxBodySize+1))
+			if err != nil {
+				log.Error("failed to read request body", sl.Err(err))
+
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(r, resp.CodeBadRequest, "failed to read request"))
+
+				return
+			}
+			if int64(len(body)) > opts.MaxBodySize {
+				log.Info("request body is too large", slog.Int64("api_key_id", keyID))
+
+				render.Status(r, http.StatusRequestEntityTooLarge)
+				render.JSON(w, r, resp.Error(r, resp.CodeTooLarge, "request body is too large"))
+
+				return
+			}
+
+			apiKey, err := keyGetter.GetAPIKey(r.Context(), keyID)
+			if errors.Is(err, storage.ErrAPIKeyNotFound) {
+				log.Info("api key not found", slog.Int64("api_key_id", keyID))
+
+				unauthorized(w, r, "unauthorized")
+
+				return
+			}
+			if err != nil {
+				log.Error("failed to get api key", sl.Err(err))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error(r, resp.CodeInternal, "internal error"))
+
+				return
+			}
+			if !apiKey.RevokedAt.IsZero() || apiKey.SigningSecret == "" {
+				log.Info("api key is revoked or can't sign", slog.Int64("api_key_id", keyID))
+
+				unauthorized(w, r, "unauthorized")
+
+				return
+			}
+
+			stringToSign := signature.StringToSign(r.Method, r.RequestURI, timestamp, nonce, body)
+			if !signature.Valid(apiKey.SigningSecret, stringToSign, sig) {
+				log.Info("invalid signature", slog.Int64("api_key_id", keyID))
+
+				unauthorized(w, r, "unauthorized")
+
+				return
+			}
+
+			// Nonce запоминаем только после проверки подписи, чтобы чужие запросы не заполняли память
+			if !seen.add(strconv.FormatInt(keyID, 10)+":"+nonce, signedAt.Add(opts.MaxSkew), now) {
+				log.Warn("replayed request", slog.Int64("api_key_id", keyID))
+
+				unauthorized(w, r, "request is replayed")
+
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := apikey.WithKeyID(r.Context(), apiKey.ID)
+			// Ключи тенанта по умолчанию работают в тенанте запроса
+			if apiKey.Tenant != tenant.Default {
+				ctx = tenant.WithTenant(ctx, apiKey.Tenant)
+			}
+			ctx = acl.WithRole(ctx, apiKey.Role)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, msg string) {
+	render.Status(r, http.StatusUnauthorized)
+	render.JSON(w, r, resp.Error(r, resp.CodeUnauthorized, msg))
+}
+
+// sweepInterval is how often expired nonces are removed.
+const sweepInterval = time.Minute
+
+// nonces remembers nonces of accepted requests until they expire.
+type nonces struct {
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	sweepAt time.Time
+}
+
+func newNonces() *nonces {
+	return &nonces{seen: make(map[string]time.Time)}
+}
+
+// add remembers the nonce until expiresAt; false means it is already remembered.
+func (n *nonces) add(nonce string, expiresAt, now time.Time) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if now.After(n.sweepAt) {
+		for k, exp := range n.seen {
+			if !exp.After(now) {
+				delete(n.seen, k)
+			}
+		}
+
+		n.sweepAt = now.Add(sweepInterval)
+	}
+
+	if exp, ok := n.seen[nonce]; ok && exp.After(now) {
+		return false
+	}
+
+	n.seen[nonce] = expiresAt
+
+	return true
+}
@@ -0,0 +1,219 @@
+package signature_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/acl"
+	mwSignature "url-shortener/internal/http-server/middleware/signature"
+	"url-shortener/internal/http-server/middleware/signature/mocks"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/signature"
+	"url-shortener/internal/storage"
+)
+
+const secret = "signing-secret"
+
+var opts = mwSignature.Options{MaxSkew: 5 * time.Minute, MaxBodySize: 1024}
+
+func TestSignatureMiddleware(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		// signedAt is the time of the signature, zero is now
+		signedAt time.Time
+		// change breaks the signed request
+		change    func(r *http.Request)
+		getCall   bool
+		apiKey    storage.APIKey
+		mockError error
+		respCode  int
+	}{
+		{
+			name:     "Success",
+			body:     `{"url": "https://example.com"}`,
+			getCall:  true,
+			apiKey:   storage.APIKey{ID: 7, Role: acl.RoleAdmin, SigningSecret: secret},
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Unsigned request",
+			change:   func(r *http.Request) { r.Header.Del(signature.HeaderSignature) },
+			respCode: http.StatusTeapot,
+		},
+		{
+			name:     "Changed body",
+			body:     `{"url": "https://example.com"}`,
+			change:   func(r *http.Request) { r.Body = io.NopCloser(strings.NewReader(`{"url": "https://evil.com"}`)) },
+			getCall:  true,
+			apiKey:   storage.APIKey{ID: 7, SigningSecret: secret},
+			respCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Changed path",
+			change:   func(r *http.Request) { r.RequestURI = "/url/other" },
+			getCall:  true,
+			apiKey:   storage.APIKey{ID: 7, SigningSecret: secret},
+			respCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Other secret",
+			getCall:  true,
+			apiKey:   storage.APIKey{ID: 7, SigningSecret: "other-secret"},
+			respCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Old timestamp",
+			signedAt: time.Now().Add(-10 * time.Minute),
+			respCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Short nonce",
+			change:   func(r *http.Request) { r.Header.Set(signature.HeaderNonce, "short") },
+			respCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Invalid key id",
+			change:   func(r *http.Request) { r.Header.Set(signature.HeaderKeyID, "abc") },
+			respCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Too large body",
+			body:     strings.Repeat("a", 1025),
+			respCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:      "Unknown key",
+			getCall:   true,
+			mockError: storage.ErrAPIKeyNotFound,
+			respCode:  http.StatusUnauthorized,
+		},
+		{
+			name:     "Revoked key",
+			getCall:  true,
+			apiKey:   storage.APIKey{ID: 7, SigningSecret: secret, RevokedAt: time.Now()},
+			respCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Key without signing secret",
+			getCall:  true,
+			apiKey:   storage.APIKey{ID: 7},
+			respCode: http.StatusUnauthorized,
+		},
+		{
+			name:      "GetAPIKey Error",
+			getCall:   true,
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			keyGetterMock := mocks.NewAPIKeyGetter(t)
+
+			if tc.getCall {
+				keyGetterMock.On("GetAPIKey", mock.Anything, int64(7)).
+					Return(tc.apiKey, tc.mockError).
+					Once()
+			}
+
+			var (
+				body    string
+				keyID   int64
+				keyRole string
+			)
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				body = string(b)
+				keyID = apikey.KeyID(r.Context())
+				keyRole = acl.Role(r.Context())
+			})
+			fallback := func(http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusTeapot)
+				})
+			}
+
+			handler := mwSignature.New(slogdiscard.NewDiscardLogger(), keyGetterMock, opts, fallback)(next)
+
+			signedAt := tc.signedAt
+			if signedAt.IsZero() {
+				signedAt = time.Now()
+			}
+
+			req := signedRequest(t, tc.body, signedAt)
+			if tc.change != nil {
+				tc.change(req)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			if tc.respCode == http.StatusOK {
+				require.Equal(t, tc.body, body)
+				require.Equal(t, tc.apiKey.ID, keyID)
+				require.Equal(t, tc.apiKey.Role, keyRole)
+			}
+		})
+	}
+}
+
+func TestSignatureMiddleware_Replay(t *testing.T) {
+	keyGetterMock := mocks.NewAPIKeyGetter(t)
+	keyGetterMock.On("GetAPIKey", mock.Anything, int64(7)).
+		Return(storage.APIKey{ID: 7, SigningSecret: secret}, nil).
+		Times(3)
+
+	handler := mwSignature.New(slogdiscard.NewDiscardLogger(), keyGetterMock, opts, func(next http.Handler) http.Handler {
+		return next
+	})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	req := signedRequest(t, `{"alias": "go"}`, time.Now())
+	replay := req.Clone(req.Context())
+	replay.Body = io.NopCloser(strings.NewReader(`{"alias": "go"}`))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, replay)
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	// Тот же nonce другого запроса с новой подписью тоже отклоняется
+	other := signedRequest(t, "", time.Now())
+	other.Header.Set(signature.HeaderNonce, req.Header.Get(signature.HeaderNonce))
+	other.Header.Set(signature.HeaderSignature, signature.Compute(secret, signature.StringToSign(
+		other.Method, other.RequestURI, other.Header.Get(signature.HeaderTimestamp), req.Header.Get(signature.HeaderNonce), nil)))
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, other)
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func signedRequest(t *testing.T, body string, signedAt time.Time) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/url?tag=go", strings.NewReader(body))
+	require.NoError(t, signature.Sign(req, 7, secret, signedAt))
+	require.Equal(t, strconv.FormatInt(signedAt.Unix(), 10), req.Header.Get(signature.HeaderTimestamp))
+
+	return req
+}
@@ -0,0 +1,117 @@
+package tenant
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"url-shortener/internal/domains"
+	"url-shortener/internal/lib/tenant"
+)
+
+// Mapping assigns requests to a tenant by the Host header or the first path segment.
+type Mapping struct {
+	Tenant string
+	// Hosts are domains of the tenant, e.g. "go.brand.example".
+	Hosts []string
+	// PathPrefix is a single path segment, e.g. "/brand"; empty means none.
+	PathPrefix string
+}
+
+// Resolver finds the tenant of a request.
+type Resolver struct {
+	hosts    map[string]string
+	prefixes map[string]string
+}
+
+// NewResolver validates mappings and builds a resolver of them.
+func NewResolver(mappings []Mapping) (*Resolver, error) {
+	const op = "middleware.tenant.NewResolver"
+
+	rs := &Resolver{
+		hosts:    make(map[string]string),
+		prefixes: make(map[string]string),
+	}
+
+	for _, m := range mappings {
+		if err := tenant.Validate(m.Tenant); err != nil {
+			return nil, fmt.Errorf("%s: %w: %q", op, err, m.Tenant)
+		}
+
+		for _, host := range m.Hosts {
+			host = domains.Normalize(host)
+			if _, ok := rs.hosts[host]; ok {
+				return nil, fmt.Errorf("%s: host %q is mapped twice", op, host)
+			}
+
+			rs.hosts[host] = m.Tenant
+		}
+
+		if m.PathPrefix == "" {
+			continue
+		}
+
+		// Префикс - один сегмент пути, иначе его нельзя отличить от alias
+		prefix := strings.Trim(m.PathPrefix, "/")
+		if tenant.Validate(prefix) != nil {
+			return nil, fmt.Errorf("%s: invalid path prefix %q", op, m.PathPrefix)
+		}
+		if _, ok := rs.prefixes[prefix]; ok {
+			return nil, fmt.Errorf("%s: path prefix %q is mapped twice", op, m.PathPrefix)
+		}
+
+		rs.prefixes[prefix] = m.Tenant
+	}
+
+	return rs, nil
+}
+
+// Prefixes returns path prefixes of the tenants without slashes. They can't be
+// used as aliases of the default tenant.
+func (rs *Resolver) Prefixes() []string {
+	prefixes := make([]string, 0, len(rs.prefixes))
+	for prefix := range rs.prefixes {
+		prefixes = append(prefixes, prefix)
+	}
+
+	return prefixes
+}
+
+// Resolve returns the tenant of r and the path to route r by. The host mapping
+// takes precedence; a matched path prefix is cut from the path.
+func (rs *Resolver) Resolve(r *http.Request) (string, string) {
+	if name, ok := rs.hosts[domains.Normalize(r.Host)]; ok {
+		return name, r.URL.Path
+	}
+
+	segment, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if name, ok := rs.prefixes[segment]; ok {
+		return name, "/" + rest
+	}
+
+	return tenant.Default, r.URL.Path
+}
+
+// New returns middleware putting the tenant of the request into its context,
+// see tenant.FromContext. It must run before routing, so that requests under
+// a path prefix are routed as if the prefix was not there.
+func New(resolver *Resolver) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			name, path := resolver.Resolve(r)
+
+			r = r.WithContext(tenant.WithTenant(r.Context(), name))
+
+			if path != r.URL.Path {
+				u := *r.URL
+				u.Path = path
+				u.RawPath = ""
+				r.URL = &u
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
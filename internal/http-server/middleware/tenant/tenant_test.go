@@ -0,0 +1,97 @@
+package tenant_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mwTenant "url-shortener/internal/http-server/middleware/tenant"
+	"url-shortener/internal/lib/tenant"
+)
+
+func TestTenantMiddleware(t *testing.T) {
+	resolver, err := mwTenant.NewResolver([]mwTenant.Mapping{
+		{Tenant: "brand", Hosts: []string{"go.brand.example"}, PathPrefix: "/brand"},
+		{Tenant: "other", PathPrefix: "other/"},
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name   string
+		target string
+		tenant string
+		alias  string
+	}{
+		{
+			name:   "Default",
+			target: "http://sho.rt/alias",
+			alias:  "alias",
+		},
+		{
+			name:   "Host",
+			target: "http://go.brand.example/alias",
+			tenant: "brand",
+			alias:  "alias",
+		},
+		{
+			name:   "Host with port",
+			target: "http://GO.brand.example:8080/alias",
+			tenant: "brand",
+			alias:  "alias",
+		},
+		{
+			name:   "Path prefix",
+			target: "http://sho.rt/other/alias",
+			tenant: "other",
+			alias:  "alias",
+		},
+		{
+			name:   "Host takes precedence",
+			target: "http://go.brand.example/other",
+			tenant: "brand",
+			alias:  "other",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotTenant, gotAlias string
+
+			router := chi.NewRouter()
+			router.Use(mwTenant.New(resolver))
+			router.Get("/{alias}", func(w http.ResponseWriter, r *http.Request) {
+				gotTenant = tenant.FromContext(r.Context())
+				gotAlias = chi.URLParam(r, "alias")
+			})
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.target, nil))
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tc.tenant, gotTenant)
+			assert.Equal(t, tc.alias, gotAlias)
+		})
+	}
+}
+
+func TestNewResolver_Invalid(t *testing.T) {
+	_, err := mwTenant.NewResolver([]mwTenant.Mapping{{Tenant: "brand/x"}})
+	assert.Error(t, err)
+
+	_, err = mwTenant.NewResolver([]mwTenant.Mapping{{Tenant: "brand", PathPrefix: "/a/b"}})
+	assert.Error(t, err)
+
+	_, err = mwTenant.NewResolver([]mwTenant.Mapping{
+		{Tenant: "brand", Hosts: []string{"go.example"}},
+		{Tenant: "other", Hosts: []string{"GO.example"}},
+	})
+	assert.Error(t, err)
+}
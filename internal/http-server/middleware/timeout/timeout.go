@@ -0,0 +1,53 @@
+package timeout
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+)
+
+// New returns middleware canceling the request context after d, so storage calls
+// of slow requests are interrupted. If the handler gives up without writing
+// a response, the client gets 504. Zero d disables the limit.
+func New(log *slog.Logger, d time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+
+		log := log.With(
+			slog.String("component", "middleware/timeout"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) || ww.Status() != 0 {
+				return
+			}
+
+			log.Warn("request timed out",
+				slog.String("path", r.URL.Path),
+				slog.Duration("timeout", d),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+			)
+
+			render.Status(r, http.StatusGatewayTimeout)
+			render.JSON(w, r, resp.Error(r, resp.CodeTimeout, "request timed out"))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,73 @@
+package timeout_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/timeout"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestTimeout(t *testing.T) {
+	// Обработчик ждет отмены контекста, как запрос к хранилищу
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	// Обработчик сам ответил на отмену
+	answered := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	fast := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := r.Context().Deadline()
+		assert.True(t, ok)
+	})
+
+	cases := []struct {
+		name     string
+		timeout  time.Duration
+		handler  http.Handler
+		respCode int
+	}{
+		{name: "Timed out", timeout: 10 * time.Millisecond, handler: slow, respCode: http.StatusGatewayTimeout},
+		{name: "Handler answered", timeout: 10 * time.Millisecond, handler: answered, respCode: http.StatusServiceUnavailable},
+		{name: "In time", timeout: time.Second, handler: fast, respCode: http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := timeout.New(slogdiscard.NewDiscardLogger(), tc.timeout)(tc.handler)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url", nil))
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			if tc.respCode == http.StatusGatewayTimeout {
+				var body resp.Response
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+				assert.Equal(t, resp.CodeTimeout, body.Error.Code)
+			}
+		})
+	}
+}
+
+func TestTimeout_Disabled(t *testing.T) {
+	handler := timeout.New(slogdiscard.NewDiscardLogger(), 0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := r.Context().Deadline()
+		assert.False(t, ok)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/url", nil))
+}
@@ -0,0 +1,80 @@
+package janitor
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// URLPurger is an interface for removing expired and deleted urls and archiving inactive ones.
+type URLPurger interface {
+	DeleteExpiredURLs(ctx context.Context) ([]string, error)
+	PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (int64, error)
+	ArchiveURLs(ctx context.Context, inactiveBefore time.Time) (int64, error)
+	PurgeClickEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Run purges expired urls and urls deleted more than retention ago every interval until ctx is done.
+// With a positive archiveAfter it also archives urls inactive for that long, and with a positive
+// clickRetention it purges click events older than that.
+func Run(
+	ctx context.Context,
+	log *slog.Logger,
+	purger URLPurger,
+	interval, retention, archiveAfter, clickRetention time.Duration,
+) {
+	const op = "janitor.Run"
+
+	log = log.With(slog.String("op", op))
+
+	if interval <= 0 {
+		log.Info("janitor is disabled")
+
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := purger.DeleteExpiredURLs(ctx)
+			if err != nil {
+				log.Error("failed to delete expired urls", sl.Err(err))
+			} else if len(expired) > 0 {
+				log.Info("expired urls deleted", slog.Int("count", len(expired)))
+			}
+
+			purged, err := purger.PurgeDeletedURLs(ctx, time.Now().Add(-retention))
+			if err != nil {
+				log.Error("failed to purge deleted urls", sl.Err(err))
+			} else if purged > 0 {
+				log.Info("deleted urls purged", slog.Int64("count", purged))
+			}
+
+			if archiveAfter > 0 {
+				archived, err := purger.ArchiveURLs(ctx, time.Now().Add(-archiveAfter))
+				if err != nil {
+					log.Error("failed to archive urls", sl.Err(err))
+				} else if archived > 0 {
+					log.Info("inactive urls archived", slog.Int64("count", archived))
+				}
+			}
+
+			if clickRetention > 0 {
+				clicks, err := purger.PurgeClickEvents(ctx, time.Now().Add(-clickRetention))
+				if err != nil {
+					log.Error("failed to purge click events", sl.Err(err))
+				} else if clicks > 0 {
+					log.Info("old click events purged", slog.Int64("count", clicks))
+				}
+			}
+		}
+	}
+}
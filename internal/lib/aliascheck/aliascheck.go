@@ -0,0 +1,119 @@
+package aliascheck
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+const (
+	MinLength = 2
+	MaxLength = 64
+)
+
+var (
+	ErrInvalid  = errors.New("invalid alias")
+	ErrReserved = errors.New("alias is reserved")
+)
+
+// routes are aliases which collide with service routes.
+var routes = map[string]struct{}{
+	"url":     {},
+	"admin":   {},
+	"auth":    {},
+	"docs":    {},
+	"export":  {},
+	"favicon": {},
+	"health":  {},
+	"import":  {},
+	"metrics": {},
+	"openapi": {},
+	"preview": {},
+	"ready":   {},
+	"robots":  {},
+}
+
+var (
+	mu sync.RWMutex
+	// reserved contains aliases added by Reserve and SetReserved.
+	reserved = map[string]struct{}{}
+)
+
+// Reserve adds aliases to the reserved ones, so routes added later don't break existing links.
+func Reserve(aliases ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	add(reserved, aliases)
+}
+
+// SetReserved replaces aliases added by Reserve and earlier SetReserved calls,
+// e.g. when the config is reloaded. Aliases of service routes stay reserved.
+func SetReserved(aliases ...string) {
+	m := make(map[string]struct{}, len(aliases))
+	add(m, aliases)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	reserved = m
+}
+
+func add(m map[string]struct{}, aliases []string) {
+	for _, alias := range aliases {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			m[strings.ToLower(alias)] = struct{}{}
+		}
+	}
+}
+
+// Validate checks that alias has allowed length and charset (letters, digits, '-' and '_')
+// and is not reserved.
+func Validate(alias string) error {
+	if len(alias) < MinLength || len(alias) > MaxLength {
+		return ErrInvalid
+	}
+
+	if !HasValidCharset(alias) {
+		return ErrInvalid
+	}
+
+	if IsReserved(alias) {
+		return ErrReserved
+	}
+
+	return nil
+}
+
+// IsReserved reports whether alias is reserved. Comparison is case-insensitive.
+func IsReserved(alias string) bool {
+	alias = strings.ToLower(alias)
+	if _, ok := routes[alias]; ok {
+		return true
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	_, ok := reserved[alias]
+
+	return ok
+}
+
+// HasValidCharset reports whether s consists of letters, digits, '-' and '_' only.
+func HasValidCharset(s string) bool {
+	for _, c := range s {
+		if !isAllowed(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isAllowed(c rune) bool {
+	return (c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') ||
+		c == '-' || c == '_'
+}
@@ -0,0 +1,65 @@
+package aliascheck
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		alias string
+		err   error
+	}{
+		{
+			name:  "valid",
+			alias: "my-link_01",
+		},
+		{
+			name:  "too short",
+			alias: "a",
+			err:   ErrInvalid,
+		},
+		{
+			name:  "too long",
+			alias: strings.Repeat("a", MaxLength+1),
+			err:   ErrInvalid,
+		},
+		{
+			name:  "bad charset",
+			alias: "hello/world",
+			err:   ErrInvalid,
+		},
+		{
+			name:  "reserved",
+			alias: "URL",
+			err:   ErrReserved,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, Validate(tt.alias), tt.err)
+		})
+	}
+}
+
+func TestReserve(t *testing.T) {
+	assert.NoError(t, Validate("static"))
+
+	Reserve("Static", " ", "robots.txt")
+
+	assert.ErrorIs(t, Validate("static"), ErrReserved)
+	assert.True(t, IsReserved("ROBOTS.TXT"))
+	assert.False(t, IsReserved(""))
+
+	SetReserved("blog")
+
+	assert.True(t, IsReserved("blog"))
+	assert.False(t, IsReserved("static"))
+	assert.True(t, IsReserved("url"), "aliases of routes stay reserved")
+
+	SetReserved()
+	assert.False(t, IsReserved("blog"))
+}
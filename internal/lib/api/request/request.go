@@ -0,0 +1,32 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrTooLarge is returned when the body exceeds the limit of the bodylimit middleware.
+var ErrTooLarge = errors.New("request body is too large")
+
+// DecodeJSON decodes the request body into v. Unlike render.DecodeJSON it rejects
+// unknown fields, so typos in field names are reported instead of silently ignored.
+// An empty body gives io.EOF.
+func DecodeJSON(r io.Reader, v any) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(v)
+	if err == nil {
+		return nil
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return fmt.Errorf("%w: limit is %d bytes", ErrTooLarge, maxBytesErr.Limit)
+	}
+
+	return err
+}
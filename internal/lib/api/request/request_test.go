@@ -0,0 +1,34 @@
+package request_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/api/request"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	type body struct {
+		URL string `json:"url"`
+	}
+
+	var v body
+	require.NoError(t, request.DecodeJSON(strings.NewReader(`{"url": "https://google.com"}`), &v))
+	assert.Equal(t, "https://google.com", v.URL)
+
+	err := request.DecodeJSON(strings.NewReader(`{"urll": "https://google.com"}`), &v)
+	assert.EqualError(t, err, `json: unknown field "urll"`)
+
+	err = request.DecodeJSON(strings.NewReader(""), &v)
+	assert.ErrorIs(t, err, io.EOF)
+
+	limited := http.MaxBytesReader(httptest.NewRecorder(), io.NopCloser(strings.NewReader(`{"url": "https://google.com"}`)), 8)
+	err = request.DecodeJSON(limited, &v)
+	assert.ErrorIs(t, err, request.ErrTooLarge)
+}
@@ -2,14 +2,34 @@ package response
 
 import (
 	"fmt"
+	"net/http"
 	"strings"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-playground/validator/v10"
 )
 
 type Response struct {
-	Status string `json:"status"`
-	Error  string `json:"error,omitempty"`
+	Status string     `json:"status"`
+	Error  *ErrorBody `json:"error,omitempty"`
+}
+
+// ErrorBody is the envelope of every error response. RequestID is the id from the
+// RequestID middleware, users can quote it in bug reports to find the logs.
+type ErrorBody struct {
+	Code      string   `json:"code"`
+	Message   string   `json:"message"`
+	Details   []string `json:"details,omitempty"`
+	RequestID string   `json:"request_id,omitempty"`
+}
+
+// Error returns the message. It is nil-safe, so successful responses give "".
+func (e *ErrorBody) Error() string {
+	if e == nil {
+		return ""
+	}
+
+	return e.Message
 }
 
 const (
@@ -17,20 +37,42 @@ const (
 	StatusError = "Error"
 )
 
+// Machine-readable error codes, clients should rely on them instead of messages.
+const (
+	CodeBadRequest   = "bad_request"
+	CodeValidation   = "validation_failed"
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden    = "forbidden"
+	CodeNotFound     = "not_found"
+	CodeConflict     = "conflict"
+	CodeExpired      = "expired"
+	CodeExhausted    = "exhausted"
+	CodeTooLarge     = "too_large"
+	CodeRateLimited  = "rate_limited"
+	CodeInternal     = "internal_error"
+	CodeUnavailable  = "unavailable"
+	CodeTimeout      = "timeout"
+)
+
 func OK() Response {
 	return Response{
 		Status: StatusOK,
 	}
 }
 
-func Error(msg string) Response {
+func Error(r *http.Request, code, msg string) Response {
 	return Response{
 		Status: StatusError,
-		Error:  msg,
+		Error: &ErrorBody{
+			Code:      code,
+			Message:   msg,
+			RequestID: middleware.GetReqID(r.Context()),
+		},
 	}
 }
 
-func ValidationError(errs validator.ValidationErrors) Response {
+// ValidationError lists every invalid field in details, message joins them.
+func ValidationError(r *http.Request, errs validator.ValidationErrors) Response {
 	var errMsgs []string
 
 	for _, err := range errs {
@@ -44,8 +86,8 @@ func ValidationError(errs validator.ValidationErrors) Response {
 		}
 	}
 
-	return Response{
-		Status: StatusError,
-		Error:  strings.Join(errMsgs, ", "),
-	}
+	res := Error(r, CodeValidation, strings.Join(errMsgs, ", "))
+	res.Error.Details = errMsgs
+
+	return res
 }
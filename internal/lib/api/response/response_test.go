@@ -0,0 +1,30 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/require"
+
+	resp "url-shortener/internal/lib/api/response"
+)
+
+func TestError(t *testing.T) {
+	var res resp.Response
+
+	h := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res = resp.Error(r, resp.CodeNotFound, "not found")
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, resp.StatusError, res.Status)
+	require.Equal(t, resp.CodeNotFound, res.Error.Code)
+	require.Equal(t, "not found", res.Error.Error())
+	require.NotEmpty(t, res.Error.RequestID)
+}
+
+func TestOK(t *testing.T) {
+	require.Equal(t, "", resp.OK().Error.Error())
+}
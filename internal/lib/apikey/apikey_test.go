@@ -0,0 +1,31 @@
+package apikey
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	k1, err := Generate()
+	require.NoError(t, err)
+	k2, err := Generate()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(k1, keyPrefix))
+	assert.NotEqual(t, k1, k2)
+}
+
+func TestHash(t *testing.T) {
+	assert.Equal(t, Hash("key"), Hash("key"))
+	assert.NotEqual(t, Hash("key"), Hash("other"))
+	assert.Len(t, Hash("key"), 64)
+}
+
+func TestKeyID(t *testing.T) {
+	assert.Zero(t, KeyID(context.Background()))
+	assert.Equal(t, int64(42), KeyID(WithKeyID(context.Background(), 42)))
+}
@@ -0,0 +1,40 @@
+package captcha_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/captcha"
+)
+
+func TestVerify(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok := r.PostFormValue("secret") == "secret" &&
+			r.PostFormValue("response") == "solved" &&
+			r.PostFormValue("remoteip") == "203.0.113.7"
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": ok})
+	}))
+	defer srv.Close()
+
+	v := captcha.New(srv.URL, "secret", time.Second)
+
+	ok, err := v.Verify(context.Background(), "solved", "203.0.113.7")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = v.Verify(context.Background(), "forged", "203.0.113.7")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// Пустой токен не отправляется провайдеру
+	ok, err = captcha.New("http://127.0.0.1:0", "secret", time.Second).Verify(context.Background(), "", "")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
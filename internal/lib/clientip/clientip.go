@@ -0,0 +1,143 @@
+// Package clientip finds the address of the client of a request made through
+// trusted proxies, e.g. load balancers.
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Headers proxies put the client address in.
+const (
+	HeaderForwardedFor = "X-Forwarded-For"
+	HeaderRealIP       = "X-Real-IP"
+)
+
+// Resolver returns the client address of requests. Headers are believed only
+// when the peer is a trusted proxy, otherwise anyone could spoof the address.
+type Resolver struct {
+	trusted []netip.Prefix
+	header  string
+}
+
+// New returns the resolver trusting header set by the proxies; header is
+// HeaderForwardedFor, HeaderRealIP or empty to always use the peer address.
+func New(trustedProxies []netip.Prefix, header string) (*Resolver, error) {
+	switch {
+	case header == "":
+	case strings.EqualFold(header, HeaderForwardedFor):
+		header = HeaderForwardedFor
+	case strings.EqualFold(header, HeaderRealIP):
+		header = HeaderRealIP
+	default:
+		return nil, fmt.Errorf("unsupported client ip header %q", header)
+	}
+
+	return &Resolver{trusted: trustedProxies, header: header}, nil
+}
+
+// ClientIP returns the address of the client, invalid if the peer address is not an IP.
+func (res *Resolver) ClientIP(r *http.Request) netip.Addr {
+	peer := PeerIP(r)
+	if res == nil || res.header == "" || !res.isTrusted(peer) {
+		return peer
+	}
+
+	if res.header == HeaderRealIP {
+		if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get(HeaderRealIP))); err == nil {
+			return addr.Unmap()
+		}
+
+		return peer
+	}
+
+	// Каждый прокси дописывает адрес справа, поэтому идем справа налево до первого недоверенного
+	var hops []string
+	for _, v := range r.Header.Values(HeaderForwardedFor) {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+
+		client = addr.Unmap()
+		if !res.isTrusted(client) {
+			break
+		}
+	}
+
+	return client
+}
+
+func (res *Resolver) isTrusted(addr netip.Addr) bool {
+	return Contains(res.trusted, addr)
+}
+
+// PeerIP returns the address the request came from, invalid if it is not an IP.
+func PeerIP(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+
+	return addr.Unmap()
+}
+
+// ParsePrefixes parses CIDRs like "10.0.0.0/8"; a single address is a prefix of its full length.
+func ParsePrefixes(ss []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(ss))
+	for _, s := range ss {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q: %w", s, err)
+			}
+
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr %q: %w", s, err)
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+// Contains reports whether addr is in one of prefixes.
+func Contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,127 @@
+package clientip_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/clientip"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := clientip.ParsePrefixes([]string{"10.0.0.0/8", "192.168.1.1"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		header     string
+		remoteAddr string
+		xff        []string
+		realIP     string
+		want       string
+	}{
+		{
+			name:       "No header",
+			remoteAddr: "10.0.0.1:1234",
+			xff:        []string{"203.0.113.7"},
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "Forwarded by trusted proxy",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.1:1234",
+			xff:        []string{"203.0.113.7"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "Spoofed by client",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.1:1234",
+			xff:        []string{"1.2.3.4, 203.0.113.7, 10.0.0.2"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "Several headers",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.1:1234",
+			xff:        []string{"203.0.113.7", "192.168.1.1"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "Untrusted peer",
+			header:     "X-Forwarded-For",
+			remoteAddr: "203.0.113.9:1234",
+			xff:        []string{"1.2.3.4"},
+			want:       "203.0.113.9",
+		},
+		{
+			name:       "All hops trusted",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.1:1234",
+			xff:        []string{"10.0.0.3, 10.0.0.2"},
+			want:       "10.0.0.3",
+		},
+		{
+			name:       "Invalid hop",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.1:1234",
+			xff:        []string{"203.0.113.7, garbage"},
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "Real IP",
+			header:     "x-real-ip",
+			remoteAddr: "10.0.0.1:1234",
+			realIP:     "2001:db8::1",
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "IPv4-mapped peer",
+			remoteAddr: "[::ffff:203.0.113.7]:1234",
+			want:       "203.0.113.7",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := clientip.New(trusted, tc.header)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for _, v := range tc.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if tc.realIP != "" {
+				req.Header.Set("X-Real-IP", tc.realIP)
+			}
+
+			assert.Equal(t, tc.want, res.ClientIP(req).String())
+		})
+	}
+}
+
+func TestNew_UnsupportedHeader(t *testing.T) {
+	_, err := clientip.New(nil, "Forwarded")
+	require.Error(t, err)
+}
+
+func TestParsePrefixes(t *testing.T) {
+	prefixes, err := clientip.ParsePrefixes([]string{"10.1.2.3/8", " 2001:db8::/32 ", "203.0.113.7", ""})
+	require.NoError(t, err)
+	require.Len(t, prefixes, 3)
+	assert.Equal(t, "10.0.0.0/8", prefixes[0].String())
+	assert.Equal(t, "203.0.113.7/32", prefixes[2].String())
+
+	_, err = clientip.ParsePrefixes([]string{"10.0.0.0/33"})
+	require.Error(t, err)
+
+	_, err = clientip.ParsePrefixes([]string{"office"})
+	require.Error(t, err)
+}
@@ -0,0 +1,68 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@every 1h",
+	} {
+		_, err := Parse(spec)
+		require.ErrorIs(t, err, ErrInvalidSpec, spec)
+	}
+}
+
+func TestNext(t *testing.T) {
+	// Пятница, 16 октября 2026
+	from := time.Date(2026, 10, 16, 10, 30, 15, 0, time.UTC)
+
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 10, 16, 10, 31, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 10, 16, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2026, 10, 17, 3, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 10, 16, 10, 45, 0, 0, time.UTC)},
+		{"10/20 * * * *", time.Date(2026, 10, 16, 10, 50, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2026, 10, 16, 13, 0, 0, 0, time.UTC)},
+		{"30 10,22 * * *", time.Date(2026, 10, 16, 22, 30, 0, 0, time.UTC)},
+		// Воскресенье записывается и как 0, и как 7
+		{"0 0 * * 0", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 1-5", time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Ограничены оба дня: подходит любой из них
+		{"0 0 1 * 6", time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.spec, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := Parse(tc.spec)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, s.Next(from))
+		})
+	}
+}
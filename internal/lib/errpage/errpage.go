@@ -0,0 +1,74 @@
+// Package errpage renders HTML pages of missing and expired links for browsers.
+// Operators can replace the built-in pages with their own templates for branding.
+package errpage
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/http"
+)
+
+//go:embed notfound.html
+var notFoundHTML string
+
+//go:embed expired.html
+var expiredHTML string
+
+// Data is passed to the templates.
+type Data struct {
+	// Alias is the requested alias without the tenant namespace.
+	Alias string
+	// Host is the requested short domain.
+	Host string
+	// Exhausted is set on the expired page of a link which reached its click limit.
+	Exhausted bool
+}
+
+// Pages holds the templates of the "link not found" and "link expired" pages.
+type Pages struct {
+	notFound *template.Template
+	expired  *template.Template
+}
+
+// New parses the templates at notFoundPath and expiredPath; an empty path keeps the built-in page.
+func New(notFoundPath, expiredPath string) (*Pages, error) {
+	const op = "lib.errpage.New"
+
+	notFound, err := parse("notfound", notFoundHTML, notFoundPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	expired, err := parse("expired", expiredHTML, expiredPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Pages{notFound: notFound, expired: expired}, nil
+}
+
+func parse(name, builtin, path string) (*template.Template, error) {
+	if path == "" {
+		return template.New(name).Parse(builtin)
+	}
+
+	return template.ParseFiles(path)
+}
+
+// NotFound writes the "link not found" page with status 404.
+func (p *Pages) NotFound(w http.ResponseWriter, data Data) error {
+	return render(w, http.StatusNotFound, p.notFound, data)
+}
+
+// Expired writes the "link expired" page with status 410.
+func (p *Pages) Expired(w http.ResponseWriter, data Data) error {
+	return render(w, http.StatusGone, p.expired, data)
+}
+
+func render(w http.ResponseWriter, status int, tmpl *template.Template, data Data) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+
+	return tmpl.Execute(w, data)
+}
@@ -0,0 +1,48 @@
+package errpage_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/errpage"
+)
+
+func TestPages(t *testing.T) {
+	pages, err := errpage.New("", "")
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	require.NoError(t, pages.NotFound(rr, errpage.Data{Alias: "abc", Host: "sho.rt"}))
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Body.String(), "sho.rt/abc")
+
+	rr = httptest.NewRecorder()
+	require.NoError(t, pages.Expired(rr, errpage.Data{Alias: "abc", Host: "sho.rt", Exhausted: true}))
+
+	assert.Equal(t, http.StatusGone, rr.Code)
+	assert.Contains(t, rr.Body.String(), "click limit")
+}
+
+func TestPages_Custom(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "404.html")
+	require.NoError(t, os.WriteFile(path, []byte(`<h1>Brand: no {{.Alias}} here</h1>`), 0o600))
+
+	pages, err := errpage.New(path, "")
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	require.NoError(t, pages.NotFound(rr, errpage.Data{Alias: "<abc>"}))
+
+	assert.Equal(t, "<h1>Brand: no &lt;abc&gt; here</h1>", rr.Body.String())
+
+	_, err = errpage.New(filepath.Join(t.TempDir(), "missing.html"), "")
+	require.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package errpage
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// WantsJSON reports whether the client prefers JSON to HTML by the Accept header.
+// API clients ask for application/json; browsers and requests with */* or no Accept get HTML.
+func WantsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+
+	return quality(accept, "application/json") > quality(accept, "text/html")
+}
+
+// quality returns the q value of the most specific media range in accept matching
+// mediaType, or zero if none matches.
+func quality(accept, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+
+	best, q := -1, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, _ := strings.Cut(part, ";")
+
+		var specificity int
+		switch strings.ToLower(strings.TrimSpace(mediaRange)) {
+		case mediaType:
+			specificity = 2
+		case typ + "/*":
+			specificity = 1
+		case "*/*":
+			specificity = 0
+		default:
+			continue
+		}
+
+		if specificity > best {
+			best, q = specificity, qValue(params)
+		}
+	}
+
+	return q
+}
+
+// qValue returns the q parameter of a media range, 1 if it is missing or malformed.
+func qValue(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if !strings.EqualFold(name, "q") {
+			continue
+		}
+
+		if q, err := strconv.ParseFloat(value, 64); err == nil {
+			return q
+		}
+	}
+
+	return 1
+}
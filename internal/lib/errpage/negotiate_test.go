@@ -0,0 +1,44 @@
+package errpage_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/lib/errpage"
+)
+
+func TestWantsJSON(t *testing.T) {
+	cases := []struct {
+		name   string
+		accept string
+		want   bool
+	}{
+		{name: "No Accept", accept: "", want: false},
+		{name: "Any", accept: "*/*", want: false},
+		{name: "JSON", accept: "application/json", want: true},
+		{name: "HTML", accept: "text/html", want: false},
+		{name: "Browser", accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", want: false},
+		{name: "Any application", accept: "application/*", want: true},
+		{name: "JSON preferred", accept: "text/html;q=0.5, application/json", want: true},
+		{name: "HTML preferred", accept: "application/json;q=0.5, text/html", want: false},
+		{name: "HTML refused", accept: "text/html;q=0, */*;q=0.1", want: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/abc", nil)
+			if tc.accept != "" {
+				r.Header.Set("Accept", tc.accept)
+			}
+
+			assert.Equal(t, tc.want, errpage.WantsJSON(r))
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package etag
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalid = errors.New("invalid etag")
+
+// weakPrefix marks weak validators.
+const weakPrefix = "W/"
+
+// FromVersion formats link version as a strong ETag value.
+func FromVersion(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// Version parses version from ETag value, e.g. of If-Match header.
+// Weak validators (W/"...") are accepted as well, including the ones of Weak.
+func Version(value string) (int64, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), weakPrefix)
+
+	unquoted, err := strconv.Unquote(value)
+	if err != nil {
+		return 0, ErrInvalid
+	}
+
+	// У слабых тегов после версии идет хеш представления
+	unquoted, _, _ = strings.Cut(unquoted, "-")
+
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, ErrInvalid
+	}
+
+	return version, nil
+}
+
+// Weak returns a weak ETag of the representation body. A non-zero version of the
+// link goes first, so the tag can still be sent in If-Match of updates.
+func Weak(version int64, body []byte) string {
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:8])
+
+	if version > 0 {
+		hash = strconv.FormatInt(version, 10) + "-" + hash
+	}
+
+	return weakPrefix + strconv.Quote(hash)
+}
+
+// NoneMatch reports whether the If-None-Match header value lists tag or is "*".
+// Tags are compared weakly, as RFC 9110 requires for If-None-Match.
+func NoneMatch(header, tag string) bool {
+	tag = strings.TrimPrefix(tag, weakPrefix)
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, weakPrefix) == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// JSON writes v as JSON with a weak ETag of it, see Weak, or 304 Not Modified
+// without a body if the client already has it according to If-None-Match.
+func JSON(w http.ResponseWriter, r *http.Request, v any, version int64) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	// Как в render.JSON, чтобы тело не зависело от способа отправки
+	enc.SetEscapeHTML(true)
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	tag := Weak(version, buf.Bytes())
+	w.Header().Set("ETag", tag)
+
+	if NoneMatch(r.Header.Get("If-None-Match"), tag) {
+		w.WriteHeader(http.StatusNotModified)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
@@ -0,0 +1,114 @@
+package etag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		version int64
+		err     error
+	}{
+		{
+			name:    "strong",
+			value:   FromVersion(3),
+			version: 3,
+		},
+		{
+			name:    "weak",
+			value:   `W/"7"`,
+			version: 7,
+		},
+		{
+			name:    "weak with hash",
+			value:   Weak(5, []byte("{}")),
+			version: 5,
+		},
+		{
+			name:  "unquoted",
+			value: "7",
+			err:   ErrInvalid,
+		},
+		{
+			name:  "not a number",
+			value: `"abc"`,
+			err:   ErrInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := Version(tt.value)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.version, version)
+		})
+	}
+}
+
+func TestWeak(t *testing.T) {
+	tag := Weak(3, []byte(`{"a":1}`))
+
+	assert.Regexp(t, `^W/"3-[0-9a-f]{16}"$`, tag)
+	assert.Equal(t, tag, Weak(3, []byte(`{"a":1}`)))
+	assert.NotEqual(t, tag, Weak(3, []byte(`{"a":2}`)))
+	assert.NotEqual(t, tag, Weak(4, []byte(`{"a":1}`)))
+	assert.Regexp(t, `^W/"[0-9a-f]{16}"$`, Weak(0, []byte(`{"a":1}`)))
+}
+
+func TestNoneMatch(t *testing.T) {
+	tag := `W/"3-abcdef"`
+
+	tests := []struct {
+		name   string
+		header string
+		match  bool
+	}{
+		{name: "empty", header: "", match: false},
+		{name: "same", header: tag, match: true},
+		{name: "strong", header: `"3-abcdef"`, match: true},
+		{name: "list", header: `"1", W/"3-abcdef"`, match: true},
+		{name: "any", header: "*", match: true},
+		{name: "other", header: `W/"4-abcdef"`, match: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, NoneMatch(tt.header, tag))
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	v := map[string]string{"url": "https://example.com/?a=1&b=2"}
+
+	rr := httptest.NewRecorder()
+	JSON(rr, httptest.NewRequest(http.MethodGet, "/", nil), v, 2)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"url":"https://example.com/?a=1&b=2"}`, rr.Body.String())
+
+	tag := rr.Header().Get("ETag")
+	require.Regexp(t, `^W/"2-`, tag)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", tag)
+
+	rr = httptest.NewRecorder()
+	JSON(rr, req, v, 2)
+
+	require.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Equal(t, tag, rr.Header().Get("ETag"))
+	assert.Empty(t, rr.Body.String())
+}
@@ -0,0 +1,246 @@
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Типы полей формата MaxMind DB
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// maxDepth limits nesting of maps, arrays and pointers, so a corrupted
+// database can't send the decoder into an endless loop.
+const maxDepth = 32
+
+var errOutOfBounds = errors.New("unexpected end of data")
+
+// decoder decodes values of the data section into strings, uint64, int32,
+// float64, []byte, *big.Int, bool, []any and map[string]any.
+type decoder struct {
+	buf   []byte
+	depth int
+}
+
+// decode returns the value at offset and the offset right after it.
+func (d decoder) decode(offset uint) (any, uint, error) {
+	if d.depth > maxDepth {
+		return nil, 0, errors.New("data is nested too deep")
+	}
+
+	typ, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if typ == typePointer {
+		target, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		// Указатель ведет на значение, но разбор продолжается сразу за ним
+		value, _, err := decoder{buf: d.buf, depth: d.depth + 1}.decode(target)
+
+		return value, next, err
+	}
+
+	return d.value(typ, size, offset)
+}
+
+// control reads the control byte of the field at offset and returns its type,
+// size and the offset of its payload. For pointers size holds the raw control bits.
+func (d decoder) control(offset uint) (int, uint, uint, error) {
+	if offset >= uint(len(d.buf)) {
+		return 0, 0, 0, errOutOfBounds
+	}
+
+	ctrl := d.buf[offset]
+	offset++
+
+	typ := int(ctrl >> 5)
+	if typ == typePointer {
+		return typ, uint(ctrl & 0x1f), offset, nil
+	}
+
+	if typ == typeExtended {
+		if offset >= uint(len(d.buf)) {
+			return 0, 0, 0, errOutOfBounds
+		}
+
+		typ = 7 + int(d.buf[offset])
+		offset++
+	}
+
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(d.buf)) {
+			return 0, 0, 0, errOutOfBounds
+		}
+
+		ext := uint(uintBytes(d.buf[offset : offset+n]))
+		offset += n
+
+		switch n {
+		case 1:
+			size = 29 + ext
+		case 2:
+			size = 285 + ext
+		default:
+			size = 65821 + ext
+		}
+	}
+
+	return typ, size, offset, nil
+}
+
+// pointer returns the data section offset the pointer refers to and the offset after the pointer.
+func (d decoder) pointer(ctrl, offset uint) (uint, uint, error) {
+	n := ctrl>>3 + 1
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, errOutOfBounds
+	}
+
+	b := d.buf[offset : offset+n]
+
+	var target uint
+	switch n {
+	case 1:
+		target = (ctrl&7)<<8 | uint(uintBytes(b))
+	case 2:
+		target = ((ctrl&7)<<16 | uint(uintBytes(b))) + 2048
+	case 3:
+		target = ((ctrl&7)<<24 | uint(uintBytes(b))) + 526336
+	default:
+		target = uint(uintBytes(b))
+	}
+
+	return target, offset + n, nil
+}
+
+func (d decoder) value(typ int, size, offset uint) (any, uint, error) {
+	switch typ {
+	case typeMap:
+		return d.decodeMap(size, offset)
+	case typeArray:
+		return d.decodeArray(size, offset)
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(d.buf)) {
+		return nil, 0, errOutOfBounds
+	}
+
+	b := d.buf[offset : offset+size]
+	next := offset + size
+
+	switch typ {
+	case typeString:
+		return string(b), next, nil
+	case typeBytes:
+		return append([]byte(nil), b...), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("invalid double size %d", size)
+		}
+
+		return math.Float64frombits(uintBytes(b)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("invalid float size %d", size)
+		}
+
+		return float64(math.Float32frombits(uint32(uintBytes(b)))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, fmt.Errorf("invalid integer size %d", size)
+		}
+
+		return uintBytes(b), next, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, fmt.Errorf("invalid integer size %d", size)
+		}
+
+		return int32(uint32(uintBytes(b))), next, nil
+	case typeUint128:
+		return new(big.Int).SetBytes(b), next, nil
+	default:
+		return nil, 0, fmt.Errorf("unexpected field type %d", typ)
+	}
+}
+
+func (d decoder) decodeMap(size, offset uint) (any, uint, error) {
+	nested := decoder{buf: d.buf, depth: d.depth + 1}
+
+	m := make(map[string]any)
+	for i := uint(0); i < size; i++ {
+		key, next, err := nested.decode(offset)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		k, ok := key.(string)
+		if !ok {
+			return nil, 0, errors.New("map key is not a string")
+		}
+
+		m[k], offset, err = nested.decode(next)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return m, offset, nil
+}
+
+func (d decoder) decodeArray(size, offset uint) (any, uint, error) {
+	nested := decoder{buf: d.buf, depth: d.depth + 1}
+
+	var a []any
+	for i := uint(0); i < size; i++ {
+		var (
+			v   any
+			err error
+		)
+
+		v, offset, err = nested.decode(offset)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		a = append(a, v)
+	}
+
+	return a, offset, nil
+}
+
+// uintBytes decodes a big-endian unsigned integer of up to 8 bytes.
+func uintBytes(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+
+	return v
+}
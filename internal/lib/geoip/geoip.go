@@ -0,0 +1,211 @@
+// Package geoip looks up the location of IP addresses in MaxMind databases
+// (GeoLite2/GeoIP2 Country and City, MaxMind DB format).
+package geoip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// metadataStart marks the metadata section at the end of the database file.
+var metadataStart = []byte("\xab\xcd\xefMaxMind.com")
+
+// dataSectionSeparator is the 16 zero bytes between the search tree and the data section.
+const dataSectionSeparator = 16
+
+var (
+	ErrNotFound = errors.New("address not found")
+	ErrInvalid  = errors.New("invalid database")
+)
+
+// Location is where the address is registered. Codes are upper case,
+// fields missing in the database are empty.
+type Location struct {
+	// Country is the ISO 3166-1 alpha-2 code, e.g. "DE".
+	Country string
+	// Continent is the MaxMind continent code: AF, AN, AS, EU, NA, OC or SA.
+	Continent string
+	// City is the English name of the city, always empty with Country databases.
+	City string
+}
+
+// Reader looks up addresses in a database loaded into memory.
+// It is safe for concurrent use.
+type Reader struct {
+	tree       []byte
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	// ipv4Start is the node of ::/96 subtree holding IPv4 addresses in IPv6 databases.
+	ipv4Start uint
+}
+
+// Open loads the database at path.
+func Open(path string) (*Reader, error) {
+	const op = "geoip.Open"
+
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r, err := New(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, nil
+}
+
+// New parses the database contents.
+func New(buf []byte) (*Reader, error) {
+	start := bytes.LastIndex(buf, metadataStart)
+	if start == -1 {
+		return nil, fmt.Errorf("%w: metadata not found", ErrInvalid)
+	}
+
+	meta, _, err := decoder{buf: buf[start+len(metadataStart):]}.decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: metadata: %v", ErrInvalid, err)
+	}
+
+	fields, ok := meta.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata is not a map", ErrInvalid)
+	}
+
+	r := &Reader{
+		nodeCount:  uintField(fields, "node_count"),
+		recordSize: uintField(fields, "record_size"),
+		ipVersion:  uintField(fields, "ip_version"),
+	}
+
+	switch r.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("%w: unsupported record size %d", ErrInvalid, r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("%w: unsupported ip version %d", ErrInvalid, r.ipVersion)
+	}
+
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+dataSectionSeparator > uint(start) {
+		return nil, fmt.Errorf("%w: search tree is out of bounds", ErrInvalid)
+	}
+
+	r.tree = buf[:treeSize]
+	r.data = buf[treeSize+dataSectionSeparator : start]
+
+	if r.ipVersion == 6 {
+		for i := 0; i < 96 && r.ipv4Start < r.nodeCount; i++ {
+			r.ipv4Start = r.record(r.ipv4Start, 0)
+		}
+	}
+
+	return r, nil
+}
+
+// Lookup returns the location of ip, ErrNotFound if the database has no record of it.
+func (r *Reader) Lookup(ip net.IP) (Location, error) {
+	const op = "geoip.Reader.Lookup"
+
+	offset, err := r.find(ip)
+	if err != nil {
+		return Location{}, err
+	}
+
+	value, _, err := decoder{buf: r.data}.decode(offset)
+	if err != nil {
+		return Location{}, fmt.Errorf("%s: %w: %v", op, ErrInvalid, err)
+	}
+
+	record, _ := value.(map[string]any)
+
+	return Location{
+		Country:   strings.ToUpper(stringField(record, "country", "iso_code")),
+		Continent: strings.ToUpper(stringField(record, "continent", "code")),
+		City:      stringField(record, "city", "names", "en"),
+	}, nil
+}
+
+// find walks the search tree and returns the offset of the record in the data section.
+func (r *Reader) find(ip net.IP) (uint, error) {
+	node := uint(0)
+
+	addr := ip.To4()
+	if addr != nil {
+		node = r.ipv4Start
+	} else {
+		if r.ipVersion == 4 {
+			return 0, ErrNotFound
+		}
+
+		addr = ip.To16()
+		if addr == nil {
+			return 0, ErrNotFound
+		}
+	}
+
+	for i := 0; i < len(addr)*8 && node < r.nodeCount; i++ {
+		bit := uint(addr[i/8]>>(7-i%8)) & 1
+		node = r.record(node, bit)
+	}
+
+	// Запись равная числу узлов означает, что адреса нет в базе
+	if node <= r.nodeCount {
+		return 0, ErrNotFound
+	}
+
+	offset := node - r.nodeCount - dataSectionSeparator
+	if offset >= uint(len(r.data)) {
+		return 0, fmt.Errorf("%w: record is out of bounds", ErrInvalid)
+	}
+
+	return offset, nil
+}
+
+// record returns the left (bit 0) or the right (bit 1) record of the node.
+func (r *Reader) record(node, bit uint) uint {
+	b := r.tree[node*r.recordSize/4:]
+
+	switch r.recordSize {
+	case 24:
+		b = b[bit*3:]
+
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		// Средний байт узла хранит старшие биты обеих записей
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		b = b[bit*4:]
+
+		return uint(b[0])<<24 | uint(b[1])<<16 | uint(b[2])<<8 | uint(b[3])
+	}
+}
+
+func uintField(m map[string]any, key string) uint {
+	v, _ := m[key].(uint64)
+
+	return uint(v)
+}
+
+// stringField returns the string at the path of nested maps, empty if there is none.
+func stringField(m map[string]any, path ...string) string {
+	for _, key := range path[:len(path)-1] {
+		m, _ = m[key].(map[string]any)
+	}
+
+	s, _ := m[path[len(path)-1]].(string)
+
+	return s
+}
@@ -0,0 +1,265 @@
+package geoip_test
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/geoip"
+)
+
+// pointer is encoded as a pointer to the offset in the data section.
+type pointer int
+
+// encode writes v in MaxMind DB format. Only types used by the tests are supported.
+func encode(v any) []byte {
+	switch v := v.(type) {
+	case string:
+		return append([]byte{2<<5 | byte(len(v))}, v...)
+	case uint64:
+		return []byte{6<<5 | 4, byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+	case pointer:
+		return []byte{1<<5 | byte(v>>8), byte(v)}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		buf := []byte{7<<5 | byte(len(v))}
+		for _, k := range keys {
+			buf = append(buf, encode(k)...)
+			buf = append(buf, encode(v[k])...)
+		}
+
+		return buf
+	default:
+		panic("unsupported type")
+	}
+}
+
+type trieNode struct {
+	children [2]*trieNode
+	// records of leaves: offset of the data + 1, 0 if there is no data
+	data [2]int
+}
+
+// buildDB builds a database of networks pointing to offsets in data.
+func buildDB(t *testing.T, ipVersion, recordSize int, networks map[string]int, data []byte) []byte {
+	t.Helper()
+
+	root := &trieNode{}
+	for cidr, offset := range networks {
+		_, network, err := net.ParseCIDR(cidr)
+		require.NoError(t, err)
+
+		ones, _ := network.Mask.Size()
+		ip := network.IP
+		if ipVersion == 6 {
+			ip = ip.To16()
+			if network.IP.To4() != nil {
+				// В базах IPv6 адреса IPv4 лежат в поддереве ::/96
+				ip = append(make(net.IP, 12), network.IP.To4()...)
+				ones += 96
+			}
+		}
+
+		node := root
+		for i := 0; i < ones; i++ {
+			bit := ip[i/8] >> (7 - i%8) & 1
+			if i == ones-1 {
+				node.data[bit] = offset + 1
+				break
+			}
+
+			if node.children[bit] == nil {
+				node.children[bit] = &trieNode{}
+			}
+			node = node.children[bit]
+		}
+	}
+
+	var nodes []*trieNode
+	index := map[*trieNode]int{}
+	var walk func(n *trieNode)
+	walk = func(n *trieNode) {
+		index[n] = len(nodes)
+		nodes = append(nodes, n)
+		for _, c := range n.children {
+			if c != nil {
+				walk(c)
+			}
+		}
+	}
+	walk(root)
+
+	nodeCount := len(nodes)
+	record := func(n *trieNode, bit int) uint32 {
+		switch {
+		case n.children[bit] != nil:
+			return uint32(index[n.children[bit]])
+		case n.data[bit] != 0:
+			return uint32(nodeCount + 16 + n.data[bit] - 1)
+		default:
+			return uint32(nodeCount)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, n := range nodes {
+		left, right := record(n, 0), record(n, 1)
+
+		switch recordSize {
+		case 24:
+			buf.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left), byte(right >> 16), byte(right >> 8), byte(right)})
+		case 28:
+			buf.Write([]byte{
+				byte(left >> 16), byte(left >> 8), byte(left),
+				byte(left>>20)&0xf0 | byte(right>>24)&0x0f,
+				byte(right >> 16), byte(right >> 8), byte(right),
+			})
+		case 32:
+			buf.Write([]byte{
+				byte(left >> 24), byte(left >> 16), byte(left >> 8), byte(left),
+				byte(right >> 24), byte(right >> 16), byte(right >> 8), byte(right),
+			})
+		}
+	}
+
+	buf.Write(make([]byte, 16))
+	buf.Write(data)
+	buf.WriteString("\xab\xcd\xefMaxMind.com")
+	buf.Write(encode(map[string]any{
+		"node_count":    uint64(nodeCount),
+		"record_size":   uint64(recordSize),
+		"ip_version":    uint64(ipVersion),
+		"database_type": "GeoLite2-Country",
+	}))
+
+	return buf.Bytes()
+}
+
+// testData returns records of Berlin and the USA and their offsets. The continent
+// of the USA is a pointer to a string, as the MaxMind writer deduplicates values.
+func testData() ([]byte, int, int) {
+	de := encode(map[string]any{
+		"city":      map[string]any{"names": map[string]any{"de": "Berlin", "en": "Berlin"}},
+		"continent": map[string]any{"code": "EU"},
+		"country":   map[string]any{"iso_code": "DE"},
+	})
+	na := encode("NA")
+	us := encode(map[string]any{
+		"continent": map[string]any{"code": pointer(len(de))},
+		"country":   map[string]any{"iso_code": "US"},
+	})
+
+	data := append(append(de, na...), us...)
+
+	return data, 0, len(de) + len(na)
+}
+
+func TestReader_Lookup(t *testing.T) {
+	data, de, us := testData()
+
+	for _, tc := range []struct {
+		ipVersion  int
+		recordSize int
+	}{
+		{ipVersion: 6, recordSize: 28},
+		{ipVersion: 6, recordSize: 24},
+		{ipVersion: 6, recordSize: 32},
+		{ipVersion: 4, recordSize: 24},
+	} {
+		networks := map[string]int{"1.2.3.0/24": de, "8.8.0.0/16": us}
+		if tc.ipVersion == 6 {
+			networks["2001:db8::/32"] = us
+		}
+
+		r, err := geoip.New(buildDB(t, tc.ipVersion, tc.recordSize, networks, data))
+		require.NoError(t, err)
+
+		loc, err := r.Lookup(net.ParseIP("1.2.3.4"))
+		require.NoError(t, err)
+		assert.Equal(t, geoip.Location{Country: "DE", Continent: "EU", City: "Berlin"}, loc)
+
+		loc, err = r.Lookup(net.ParseIP("8.8.8.8"))
+		require.NoError(t, err)
+		assert.Equal(t, geoip.Location{Country: "US", Continent: "NA"}, loc)
+
+		_, err = r.Lookup(net.ParseIP("1.2.4.1"))
+		assert.ErrorIs(t, err, geoip.ErrNotFound)
+
+		loc, err = r.Lookup(net.ParseIP("2001:db8::1"))
+		if tc.ipVersion == 4 {
+			assert.ErrorIs(t, err, geoip.ErrNotFound)
+		} else {
+			require.NoError(t, err)
+			assert.Equal(t, "US", loc.Country)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	data, de, _ := testData()
+
+	path := filepath.Join(t.TempDir(), "GeoLite2-Country.mmdb")
+	require.NoError(t, os.WriteFile(path, buildDB(t, 6, 28, map[string]int{"1.2.3.0/24": de}, data), 0o600))
+
+	r, err := geoip.Open(path)
+	require.NoError(t, err)
+
+	loc, err := r.Lookup(net.ParseIP("1.2.3.4"))
+	require.NoError(t, err)
+	assert.Equal(t, "DE", loc.Country)
+
+	_, err = geoip.New([]byte("not a database"))
+	assert.ErrorIs(t, err, geoip.ErrInvalid)
+}
+
+func TestReloader(t *testing.T) {
+	data, de, us := testData()
+
+	path := filepath.Join(t.TempDir(), "GeoLite2-City.mmdb")
+	require.NoError(t, os.WriteFile(path, buildDB(t, 6, 28, map[string]int{"1.2.3.0/24": de}, data), 0o600))
+
+	r, err := geoip.OpenReloader(path)
+	require.NoError(t, err)
+
+	loc, err := r.Lookup(net.ParseIP("1.2.3.4"))
+	require.NoError(t, err)
+	assert.Equal(t, "DE", loc.Country)
+
+	reloaded, err := r.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+
+	// Битый файл не заменяет загруженную базу
+	require.NoError(t, os.WriteFile(path, []byte("not a database"), 0o600))
+	_, err = r.Reload()
+	assert.ErrorIs(t, err, geoip.ErrInvalid)
+
+	loc, err = r.Lookup(net.ParseIP("1.2.3.4"))
+	require.NoError(t, err)
+	assert.Equal(t, "DE", loc.Country)
+
+	require.NoError(t, os.WriteFile(path, buildDB(t, 6, 28, map[string]int{"1.2.3.0/24": us}, data), 0o600))
+	// Размер базы не изменился, обновление видно только по времени изменения
+	updated := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, updated, updated))
+
+	reloaded, err = r.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+
+	loc, err = r.Lookup(net.ParseIP("1.2.3.4"))
+	require.NoError(t, err)
+	assert.Equal(t, "US", loc.Country)
+}
@@ -0,0 +1,98 @@
+package geoip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// Reloader looks up addresses in the database file and loads the file again when it
+// changes, e.g. after geoipupdate, without restarting the service.
+// It is safe for concurrent use.
+type Reloader struct {
+	path   string
+	reader atomic.Pointer[Reader]
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+// OpenReloader loads the database at path.
+func OpenReloader(path string) (*Reloader, error) {
+	const op = "geoip.OpenReloader"
+
+	r := &Reloader{path: path}
+	if _, err := r.Reload(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, nil
+}
+
+// Lookup returns the location of ip in the last loaded database.
+func (r *Reloader) Lookup(ip net.IP) (Location, error) {
+	return r.reader.Load().Lookup(ip)
+}
+
+// Reload loads the database if the file has changed since the last load and reports
+// whether it was loaded. A file which fails to load leaves the previous database in use.
+func (r *Reloader) Reload() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false, err
+	}
+	if info.ModTime().Equal(r.modTime) && info.Size() == r.size {
+		return false, nil
+	}
+
+	reader, err := Open(r.path)
+	if err != nil {
+		return false, err
+	}
+
+	r.reader.Store(reader)
+	r.modTime, r.size = info.ModTime(), info.Size()
+
+	return true, nil
+}
+
+// Run checks the file for changes every interval until ctx is done.
+func (r *Reloader) Run(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	const op = "geoip.Reloader.Run"
+
+	log = log.With(slog.String("op", op))
+
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Битый файл не страшен: до следующей попытки работает прежняя база
+			reloaded, err := r.Reload()
+			if err != nil {
+				log.Error("failed to reload geoip database", sl.Err(err))
+			} else if reloaded {
+				log.Info("geoip database reloaded", slog.String("path", r.path))
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Package hll implements HyperLogLog sketches for estimating the number of distinct
+// values. A sketch keeps only the maximum bit patterns of hashes, not the values
+// themselves, and takes at most 4 KiB however many values are added.
+package hll
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+const (
+	// precision is the number of hash bits selecting a register: 2^12 registers give
+	// a standard error of about 1.6%.
+	precision = 12
+	registers = 1 << precision
+)
+
+const (
+	formatDense  byte = 1
+	formatSparse byte = 2
+)
+
+var ErrInvalidSketch = errors.New("invalid sketch")
+
+type Sketch struct {
+	registers [registers]uint8
+}
+
+func New() *Sketch {
+	return &Sketch{}
+}
+
+// Add adds a value by its 64-bit hash. Hashes must be uniformly distributed, see Hash.
+func (s *Sketch) Add(hash uint64) {
+	idx := hash >> (64 - precision)
+	// Единица в конце ограничивает длину серии нулей, если все биты нулевые
+	rank := uint8(bits.LeadingZeros64(hash<<precision|1<<(precision-1))) + 1
+
+	if rank > s.registers[idx] {
+		s.registers[idx] = rank
+	}
+}
+
+// Merge adds the values of other to s, as if they were added to s directly.
+func (s *Sketch) Merge(other *Sketch) {
+	for i, r := range other.registers {
+		if r > s.registers[i] {
+			s.registers[i] = r
+		}
+	}
+}
+
+// Count returns the estimated number of distinct values added.
+func (s *Sketch) Count() uint64 {
+	var (
+		sum   float64
+		zeros int
+	)
+
+	for _, r := range s.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	m := float64(registers)
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+
+	// На малых количествах оценка по пустым регистрам точнее
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(estimate + 0.5)
+}
+
+// MarshalBinary encodes the sketch. Sketches with few values are encoded as a list of
+// non-empty registers, which is much shorter than all of them.
+func (s *Sketch) MarshalBinary() ([]byte, error) {
+	var nonZero int
+	for _, r := range s.registers {
+		if r != 0 {
+			nonZero++
+		}
+	}
+
+	// Индекс регистра занимает 2 байта, значение 1
+	if nonZero*3 >= registers {
+		return append([]byte{formatDense}, s.registers[:]...), nil
+	}
+
+	data := make([]byte, 1, 1+nonZero*3)
+	data[0] = formatSparse
+	for i, r := range s.registers {
+		if r != 0 {
+			data = binary.BigEndian.AppendUint16(data, uint16(i))
+			data = append(data, r)
+		}
+	}
+
+	return data, nil
+}
+
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return ErrInvalidSketch
+	}
+
+	var regs [registers]uint8
+
+	switch data[0] {
+	case formatDense:
+		if len(data) != 1+registers {
+			return ErrInvalidSketch
+		}
+
+		copy(regs[:], data[1:])
+	case formatSparse:
+		if (len(data)-1)%3 != 0 {
+			return ErrInvalidSketch
+		}
+
+		for i := 1; i < len(data); i += 3 {
+			idx := binary.BigEndian.Uint16(data[i:])
+			if int(idx) >= registers {
+				return ErrInvalidSketch
+			}
+
+			regs[idx] = data[i+2]
+		}
+	default:
+		return ErrInvalidSketch
+	}
+
+	s.registers = regs
+
+	return nil
+}
+
+// Hash returns a 64-bit hash of the parts for Add.
+func Hash(parts ...string) uint64 {
+	h := fnv.New64a()
+	for _, p := range parts {
+		_, _ = h.Write([]byte(p))
+		// Разделитель, чтобы ("ab", "c") и ("a", "bc") различались
+		_, _ = h.Write([]byte{0})
+	}
+
+	// FNV плохо перемешивает старшие биты, по которым выбирается регистр
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
@@ -0,0 +1,73 @@
+package hll
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCount(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 1000, 100000} {
+		s := New()
+		for i := 0; i < n; i++ {
+			// Повторы не должны менять оценку
+			s.Add(Hash(strconv.Itoa(i)))
+			s.Add(Hash(strconv.Itoa(i)))
+		}
+
+		require.InDelta(t, n, s.Count(), float64(n)*0.05+1, "n = %d", n)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a, b := New(), New()
+	for i := 0; i < 6000; i++ {
+		a.Add(Hash(strconv.Itoa(i)))
+	}
+	for i := 4000; i < 10000; i++ {
+		b.Add(Hash(strconv.Itoa(i)))
+	}
+
+	a.Merge(b)
+
+	require.InDelta(t, 10000, a.Count(), 500)
+}
+
+func TestMarshalBinary(t *testing.T) {
+	for _, n := range []int{3, 10000} {
+		s := New()
+		for i := 0; i < n; i++ {
+			s.Add(Hash("visitor", strconv.Itoa(i)))
+		}
+
+		data, err := s.MarshalBinary()
+		require.NoError(t, err)
+
+		if n < 100 {
+			require.Len(t, data, 1+n*3)
+		}
+
+		var decoded Sketch
+		require.NoError(t, decoded.UnmarshalBinary(data))
+		require.Equal(t, s.Count(), decoded.Count())
+	}
+}
+
+func TestUnmarshalBinary_Invalid(t *testing.T) {
+	for _, data := range [][]byte{
+		nil,
+		{formatDense, 1, 2},
+		{formatSparse, 0, 1},
+		{formatSparse, 0xff, 0xff, 1},
+		{9},
+	} {
+		var s Sketch
+		require.ErrorIs(t, s.UnmarshalBinary(data), ErrInvalidSketch)
+	}
+}
+
+func TestHash(t *testing.T) {
+	require.Equal(t, Hash("a", "b"), Hash("a", "b"))
+	require.NotEqual(t, Hash("ab", "c"), Hash("a", "bc"))
+}
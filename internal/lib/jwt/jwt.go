@@ -0,0 +1,72 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"url-shortener/internal/storage"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// NewToken issues a token for user valid for ttl, signed with HS256.
+func NewToken(user storage.User, secret string, ttl time.Duration) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"uid":    user.ID,
+		"email":  user.Email,
+		"tenant": user.Tenant,
+		"role":   user.Role,
+		"exp":    time.Now().Add(ttl).Unix(),
+	})
+
+	return token.SignedString([]byte(secret))
+}
+
+// Claims identify the user a token was issued for. A changed role of the user
+// takes effect with the next token.
+type Claims struct {
+	UserID int64
+	Tenant string
+	Role   string
+}
+
+// ParseToken validates token signature and expiration and returns its claims.
+func ParseToken(tokenString, secret string) (Claims, error) {
+	var claims jwt.MapClaims
+
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(*jwt.Token) (any, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	// Числа в JSON декодируются как float64
+	uid, ok := claims["uid"].(float64)
+	if !ok || uid <= 0 {
+		return Claims{}, ErrInvalidToken
+	}
+
+	// Токены, выданные до появления тенантов, относятся к тенанту по умолчанию
+	tenant, _ := claims["tenant"].(string)
+	role, _ := claims["role"].(string)
+
+	return Claims{UserID: int64(uid), Tenant: tenant, Role: role}, nil
+}
+
+type ctxKey struct{}
+
+// WithUserID returns a copy of ctx carrying the id of the authenticated user.
+func WithUserID(ctx context.Context, id int64) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// UserID returns the id of the authenticated user, zero if there is none.
+func UserID(ctx context.Context) int64 {
+	id, _ := ctx.Value(ctxKey{}).(int64)
+
+	return id
+}
@@ -0,0 +1,42 @@
+package jwt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/storage"
+)
+
+func TestToken(t *testing.T) {
+	const secret = "test-secret"
+
+	user := storage.User{ID: 42, Email: "user@example.com", Tenant: "brand", Role: "viewer"}
+
+	token, err := NewToken(user, secret, time.Hour)
+	require.NoError(t, err)
+
+	claims, err := ParseToken(token, secret)
+	require.NoError(t, err)
+	assert.Equal(t, Claims{UserID: user.ID, Tenant: user.Tenant, Role: user.Role}, claims)
+
+	_, err = ParseToken(token, "other-secret")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	expired, err := NewToken(user, secret, -time.Minute)
+	require.NoError(t, err)
+
+	_, err = ParseToken(expired, secret)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = ParseToken("garbage", secret)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestUserID(t *testing.T) {
+	assert.Zero(t, UserID(context.Background()))
+	assert.Equal(t, int64(42), UserID(WithUserID(context.Background(), 42)))
+}
@@ -0,0 +1,105 @@
+// Package listener opens listeners for addresses of the config: TCP host:port,
+// unix sockets and sockets passed by systemd socket activation.
+package listener
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	// UnixPrefix marks a unix socket path: "unix:/run/url-shortener.sock".
+	UnixPrefix = "unix:"
+	// SystemdPrefix marks a socket passed by systemd, by its FileDescriptorName:
+	// "systemd:http".
+	SystemdPrefix = "systemd:"
+
+	// listenFDsStart is the first file descriptor passed by systemd.
+	listenFDsStart = 3
+)
+
+var ErrNotActivated = errors.New("socket is not passed by systemd")
+
+// Listen opens a listener on the address. Unix sockets get the mode, so that
+// a reverse proxy running as another user can connect; a socket file left
+// by a previous run is removed first.
+func Listen(address string, mode fs.FileMode) (net.Listener, error) {
+	const op = "lib.listener.Listen"
+
+	var (
+		ln  net.Listener
+		err error
+	)
+
+	switch {
+	case strings.HasPrefix(address, SystemdPrefix):
+		ln, err = systemd(strings.TrimPrefix(address, SystemdPrefix))
+	case strings.HasPrefix(address, UnixPrefix):
+		ln, err = unix(strings.TrimPrefix(address, UnixPrefix), mode)
+	default:
+		ln, err = net.Listen("tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ln, nil
+}
+
+func unix(path string, mode fs.FileMode) (net.Listener, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		_ = ln.Close()
+
+		return nil, err
+	}
+
+	return ln, nil
+}
+
+// systemd returns the socket named in FileDescriptorName of the socket unit, see sd_listen_fds(3).
+// Sockets stay open in systemd between restarts, so connections wait in the backlog
+// instead of being refused while the service starts.
+func systemd(name string) (net.Listener, error) {
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil, ErrNotActivated
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LISTEN_FDS: %w", err)
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	for i := 0; i < count && i < len(names); i++ {
+		if names[i] != name {
+			continue
+		}
+
+		fd := listenFDsStart + i
+		// Дочерним процессам сокет не нужен
+		syscall.CloseOnExec(fd)
+
+		f := os.NewFile(uintptr(fd), name)
+		// FileListener дублирует дескриптор, исходный больше не нужен
+		defer f.Close()
+
+		return net.FileListener(f)
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrNotActivated, name)
+}
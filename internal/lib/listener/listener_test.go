@@ -0,0 +1,56 @@
+package listener_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/listener"
+)
+
+func TestListen_TCP(t *testing.T) {
+	ln, err := listener.Listen("127.0.0.1:0", 0o660)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	assert.Equal(t, "tcp", ln.Addr().Network())
+}
+
+func TestListen_Unix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "url-shortener.sock")
+
+	// Файл, оставшийся после прошлого запуска, не мешает
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+	ln, err := listener.Listen(listener.UnixPrefix+path, 0o660)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeSocket, info.Mode().Type())
+	assert.Equal(t, os.FileMode(0o660), info.Mode().Perm())
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestListen_Systemd(t *testing.T) {
+	t.Setenv("LISTEN_PID", "")
+
+	_, err := listener.Listen(listener.SystemdPrefix+"http", 0)
+	assert.ErrorIs(t, err, listener.ErrNotActivated)
+
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "1")
+	t.Setenv("LISTEN_FDNAMES", "internal")
+
+	_, err = listener.Listen(listener.SystemdPrefix+"http", 0)
+	assert.ErrorIs(t, err, listener.ErrNotActivated)
+}
@@ -0,0 +1,119 @@
+// Package slogsentry reports error records of slog to Sentry.
+package slogsentry
+
+import (
+	"context"
+
+	"github.com/getsentry/sentry-go"
+	"golang.org/x/exp/slog"
+)
+
+// tagKeys are attributes which become searchable Sentry tags, the rest go to extra data.
+var tagKeys = map[string]bool{
+	"op":         true,
+	"request_id": true,
+	"component":  true,
+}
+
+// Handler passes records to the next handler and reports the ones of error
+// level to Sentry. The "error" attribute, set by sl.Err, becomes the exception.
+type Handler struct {
+	next  slog.Handler
+	hub   *sentry.Hub
+	attrs []slog.Attr
+	group string
+}
+
+func New(next slog.Handler, hub *sentry.Hub) *Handler {
+	return &Handler{next: next, hub: hub}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelError || h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelError {
+		h.report(r)
+	}
+
+	if !h.next.Enabled(ctx, r.Level) {
+		return nil
+	}
+
+	return h.next.Handle(ctx, r)
+}
+
+func (h *Handler) report(r slog.Record) {
+	event := sentry.NewEvent()
+	event.Level = sentry.LevelError
+	event.Message = r.Message
+	event.Timestamp = r.Time
+
+	errMsg := ""
+	add := func(key string, a slog.Attr) {
+		value := a.Value.Resolve().String()
+		switch {
+		case key == "error":
+			errMsg = value
+		case tagKeys[key]:
+			event.Tags[key] = value
+		default:
+			event.Extra[key] = value
+		}
+	}
+
+	for _, a := range h.attrs {
+		add(a.Key, a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		add(h.prefix(a.Key), a)
+
+		return true
+	})
+
+	if errMsg != "" {
+		// Стек места записи в лог: по нему видно, какой обработчик вернул ошибку
+		event.Exception = []sentry.Exception{{
+			Type:       r.Message,
+			Value:      errMsg,
+			Stacktrace: sentry.NewStacktrace(),
+		}}
+	}
+
+	h.hub.CaptureEvent(event)
+}
+
+// prefix qualifies key with the groups of the handler.
+func (h *Handler) prefix(key string) string {
+	if h.group == "" {
+		return key
+	}
+
+	return h.group + "." + key
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	// Группа запоминается в ключах сразу: следующие WithGroup ее уже не касаются
+	all := make([]slog.Attr, len(h.attrs), len(h.attrs)+len(attrs))
+	copy(all, h.attrs)
+	for _, a := range attrs {
+		all = append(all, slog.Attr{Key: h.prefix(a.Key), Value: a.Value})
+	}
+
+	return &Handler{
+		next:  h.next.WithAttrs(attrs),
+		hub:   h.hub,
+		attrs: all,
+		group: h.group,
+	}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{
+		next:  h.next.WithGroup(name),
+		hub:   h.hub,
+		attrs: h.attrs,
+		group: h.prefix(name),
+	}
+}
@@ -0,0 +1,74 @@
+package slogsentry_test
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/logger/handlers/slogsentry"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// transport keeps events instead of sending them.
+type transport struct {
+	mu     sync.Mutex
+	events []*sentry.Event
+}
+
+func (t *transport) Configure(sentry.ClientOptions) {}
+func (t *transport) Flush(time.Duration) bool       { return true }
+func (t *transport) SendEvent(event *sentry.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.events = append(t.events, event)
+}
+
+func TestHandler(t *testing.T) {
+	tr := &transport{}
+	client, err := sentry.NewClient(sentry.ClientOptions{Transport: tr})
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	next := slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelInfo})
+	log := slog.New(slogsentry.New(next, sentry.NewHub(client, sentry.NewScope())))
+
+	log = log.With(
+		slog.String("op", "handlers.url.save.New"),
+		slog.String("request_id", "req-1"),
+	)
+
+	log.Debug("debug message")
+	log.Info("url added", slog.String("alias", "abc"))
+	log.Error("failed to add url", sl.Err(errors.New("disk is full")), slog.String("alias", "abc"))
+	log.WithGroup("storage").Error("failed to close", slog.String("driver", "sqlite"))
+
+	// Все записи, кроме отключенных уровнем, доходят до основного обработчика
+	assert.NotContains(t, out.String(), "debug message")
+	assert.Contains(t, out.String(), "url added")
+	assert.Contains(t, out.String(), "failed to add url")
+
+	require.Len(t, tr.events, 2)
+
+	event := tr.events[0]
+	assert.Equal(t, "failed to add url", event.Message)
+	assert.Equal(t, sentry.LevelError, event.Level)
+	assert.Equal(t, "handlers.url.save.New", event.Tags["op"])
+	assert.Equal(t, "req-1", event.Tags["request_id"])
+	assert.Equal(t, "abc", event.Extra["alias"])
+	require.Len(t, event.Exception, 1)
+	assert.Equal(t, "disk is full", event.Exception[0].Value)
+	assert.NotNil(t, event.Exception[0].Stacktrace)
+
+	event = tr.events[1]
+	assert.Equal(t, "req-1", event.Tags["request_id"])
+	assert.Equal(t, "sqlite", event.Extra["storage.driver"])
+	assert.Empty(t, event.Exception)
+}
@@ -0,0 +1,61 @@
+// Package logfile opens log outputs: stdout or files rotated by size and age.
+package logfile
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+type Options struct {
+	// MaxSize is the size in megabytes at which the file is rotated.
+	MaxSize int
+	// MaxAge is how long rotated files are kept, rounded up to days; 0 keeps them.
+	MaxAge time.Duration
+	// MaxBackups is how many rotated files are kept, 0 keeps all.
+	MaxBackups int
+	// Compress gzips rotated files.
+	Compress bool
+}
+
+// Open returns stdout for the empty path, otherwise the file at path rotated by opts.
+// The file is checked to be writable right away, so a wrong path fails the start
+// instead of the first log record.
+func Open(path string, opts Options) (io.WriteCloser, error) {
+	const op = "lib.logger.logfile.Open"
+
+	if path == "" {
+		return nopCloser{os.Stdout}, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	_ = f.Close()
+
+	return &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    opts.MaxSize,
+		MaxAge:     int(math.Ceil(opts.MaxAge.Hours() / 24)),
+		MaxBackups: opts.MaxBackups,
+		LocalTime:  true,
+		Compress:   opts.Compress,
+	}, nil
+}
+
+// nopCloser keeps stdout open when the log is closed on shutdown.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
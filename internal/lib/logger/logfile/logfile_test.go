@@ -0,0 +1,54 @@
+package logfile_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/logfile"
+)
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+
+	w, err := logfile.Open(path, logfile.Options{MaxSize: 1, MaxAge: 36 * time.Hour})
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte("first\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	// Новые записи дописываются в конец файла
+	w, err = logfile.Open(path, logfile.Options{MaxSize: 1})
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte("second\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "first\nsecond\n", string(b))
+}
+
+func TestOpen_Stdout(t *testing.T) {
+	w, err := logfile.Open("", logfile.Options{})
+	require.NoError(t, err)
+
+	// Закрытие лога не закрывает stdout
+	require.NoError(t, w.Close())
+	_, err = os.Stdout.Stat()
+	assert.NoError(t, err)
+}
+
+func TestOpen_NotWritable(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file"), nil, 0o600))
+
+	_, err := logfile.Open(filepath.Join(dir, "file", "app.log"), logfile.Options{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,193 @@
+// Package loopcheck rejects link destinations which lead back to the service,
+// directly or via a chain of redirects, so links can't redirect in a loop.
+package loopcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"url-shortener/internal/domains"
+	"url-shortener/internal/lib/urlcheck"
+)
+
+var ErrLoop = errors.New("url leads to a redirect loop")
+
+// HostRegistry is an interface for checking short domains of the service, see domains.Registry.
+type HostRegistry interface {
+	Exists(ctx context.Context, host string) (bool, error)
+}
+
+type Options struct {
+	// Hosts are hosts of the service besides the short domains of the registry,
+	// e.g. the host of the API or of a CDN in front of the service.
+	Hosts []string
+	// MaxHops is how many redirects of a destination Check follows, 0 disables following.
+	MaxHops int
+	// Timeout limits following of the whole chain.
+	Timeout time.Duration
+	// AllowPrivate allows following redirects to loopback and private addresses.
+	AllowPrivate bool
+}
+
+type Checker struct {
+	hosts    map[string]struct{}
+	registry HostRegistry
+	client   *http.Client
+	opts     Options
+}
+
+func New(registry HostRegistry, opts Options) *Checker {
+	c := &Checker{
+		hosts:    make(map[string]struct{}, len(opts.Hosts)),
+		registry: registry,
+		opts:     opts,
+	}
+
+	for _, host := range opts.Hosts {
+		if host = domains.Normalize(host); host != "" {
+			c.hosts[host] = struct{}{}
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivate {
+		dialer.Control = urlcheck.DenyPrivate
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// Через прокси проверка адреса в dialer не сработала бы
+	transport.Proxy = nil
+
+	c.client = &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: transport,
+		// Каждый переход проверяем сами
+		CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return c
+}
+
+// CheckHost returns ErrLoop if rawURL points to a host of the service; self is the
+// host the request came to, it is served by the service too.
+func (c *Checker) CheckHost(ctx context.Context, rawURL, self string) error {
+	const op = "loopcheck.CheckHost"
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+
+	own, err := c.own(ctx, u.Host, self)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if own {
+		return fmt.Errorf("%w: %s is served by the shortener", ErrLoop, domains.Normalize(u.Host))
+	}
+
+	return nil
+}
+
+// Check is CheckHost which also follows up to MaxHops redirects of rawURL and returns
+// ErrLoop if one of them leads to the service or to a URL seen before. Destinations
+// which can't be fetched are not errors, the chain just isn't followed further.
+func (c *Checker) Check(ctx context.Context, rawURL, self string) error {
+	const op = "loopcheck.Check"
+
+	if err := c.CheckHost(ctx, rawURL, self); err != nil {
+		return err
+	}
+
+	if c.opts.MaxHops <= 0 {
+		return nil
+	}
+
+	if c.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.Timeout)
+		defer cancel()
+	}
+
+	seen := map[string]struct{}{rawURL: {}}
+	current := rawURL
+
+	for hop := 0; hop < c.opts.MaxHops; hop++ {
+		next, ok := c.next(ctx, current)
+		if !ok {
+			return nil
+		}
+
+		if _, ok := seen[next.String()]; ok {
+			return fmt.Errorf("%w: %s redirects back to %s", ErrLoop, current, next)
+		}
+		seen[next.String()] = struct{}{}
+
+		own, err := c.own(ctx, next.Host, self)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if own {
+			return fmt.Errorf("%w: %s redirects to the shortener", ErrLoop, current)
+		}
+
+		current = next.String()
+	}
+
+	return nil
+}
+
+// next returns where rawURL redirects to; false if it doesn't redirect or can't be fetched.
+func (c *Checker) next(ctx context.Context, rawURL string) (*url.URL, bool) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, false
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, false
+	}
+	// Тело не нужно, важен только Location
+	_ = res.Body.Close()
+
+	if res.StatusCode < http.StatusMultipleChoices || res.StatusCode >= http.StatusBadRequest {
+		return nil, false
+	}
+
+	location, err := res.Location()
+	if err != nil || (location.Scheme != "http" && location.Scheme != "https") {
+		return nil, false
+	}
+
+	return location, true
+}
+
+func (c *Checker) own(ctx context.Context, host, self string) (bool, error) {
+	host = domains.Normalize(host)
+	if host == "" {
+		return false, nil
+	}
+
+	if self != "" && host == domains.Normalize(self) {
+		return true, nil
+	}
+
+	if _, ok := c.hosts[host]; ok {
+		return true, nil
+	}
+
+	if c.registry == nil {
+		return false, nil
+	}
+
+	return c.registry.Exists(ctx, host)
+}
@@ -0,0 +1,115 @@
+package loopcheck_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domains"
+	"url-shortener/internal/lib/loopcheck"
+)
+
+func TestCheckHost(t *testing.T) {
+	c := loopcheck.New(domains.NewRegistry([]string{"go.brand.example"}, nil), loopcheck.Options{Hosts: []string{"sho.rt"}})
+
+	cases := []struct {
+		name string
+		url  string
+		self string
+		loop bool
+	}{
+		{name: "Other host", url: "https://example.com/", self: "api.sho.rt"},
+		{name: "Service host", url: "https://Sho.rt/alias", loop: true},
+		{name: "Short domain", url: "http://go.brand.example:8080/alias", loop: true},
+		{name: "Request host", url: "https://api.sho.rt/url", self: "API.sho.rt:443", loop: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := c.CheckHost(context.Background(), tc.url, tc.self)
+			if tc.loop {
+				require.ErrorIs(t, err, loopcheck.ErrLoop)
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+		case "/b":
+			http.Redirect(w, r, "https://sho.rt/alias", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, srv.URL+"/loop2", http.StatusFound)
+		case "/loop2":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	// Подтесты параллельные и выполняются после возврата из TestCheck
+	t.Cleanup(srv.Close)
+
+	cases := []struct {
+		name    string
+		url     string
+		maxHops int
+		loop    bool
+	}{
+		{name: "No redirects", url: srv.URL + "/page", maxHops: 5},
+		{name: "Chain to the service", url: srv.URL + "/a", maxHops: 5, loop: true},
+		{name: "Chain longer than max hops", url: srv.URL + "/a", maxHops: 1},
+		{name: "Following disabled", url: srv.URL + "/a"},
+		{name: "Cycle", url: srv.URL + "/loop", maxHops: 5, loop: true},
+		{name: "Unreachable", url: "http://127.0.0.1:1/", maxHops: 5},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := loopcheck.New(nil, loopcheck.Options{
+				Hosts:        []string{"sho.rt"},
+				MaxHops:      tc.maxHops,
+				Timeout:      time.Second,
+				AllowPrivate: true,
+			})
+
+			err := c.Check(context.Background(), tc.url, "")
+			if tc.loop {
+				require.ErrorIs(t, err, loopcheck.ErrLoop)
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCheck_Private(t *testing.T) {
+	srv := httptest.NewServer(http.RedirectHandler("https://sho.rt/alias", http.StatusFound))
+	defer srv.Close()
+
+	// Адрес сервера закрытый, запрос к нему не отправляется
+	c := loopcheck.New(nil, loopcheck.Options{Hosts: []string{"sho.rt"}, MaxHops: 5, Timeout: time.Second})
+
+	require.NoError(t, c.Check(context.Background(), srv.URL, ""))
+}
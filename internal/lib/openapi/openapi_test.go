@@ -0,0 +1,56 @@
+package openapi_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/openapi"
+)
+
+type base struct {
+	Status string `json:"status"`
+}
+
+type item struct {
+	base
+	Name    string     `json:"name" validate:"required"`
+	Link    string     `json:"link,omitempty" validate:"omitempty,url"`
+	Code    *int       `json:"code,omitempty" validate:"omitempty,oneof=301 302"`
+	At      *time.Time `json:"at,omitempty"`
+	Tags    []string   `json:"tags"`
+	Next    *item      `json:"next,omitempty"`
+	Ignored string     `json:"-"`
+}
+
+func TestSchema(t *testing.T) {
+	doc := openapi.New("test", "1")
+
+	ref := doc.Schema(item{})
+	assert.Equal(t, "#/components/schemas/openapi_test.item", ref.Ref)
+
+	s := doc.Components.Schemas["openapi_test.item"]
+	require.NotNil(t, s)
+
+	assert.Equal(t, "object", s.Type)
+	assert.ElementsMatch(t, []string{"status", "name", "tags"}, s.Required)
+	assert.NotContains(t, s.Properties, "Ignored")
+	assert.Equal(t, "uri", s.Properties["link"].Format)
+	assert.Equal(t, []any{int64(301), int64(302)}, s.Properties["code"].Enum)
+	assert.True(t, s.Properties["code"].Nullable)
+	assert.Equal(t, "date-time", s.Properties["at"].Format)
+	assert.Equal(t, "string", s.Properties["tags"].Items.Type)
+	assert.Equal(t, ref.Ref, s.Properties["next"].Ref)
+}
+
+func TestAdd(t *testing.T) {
+	doc := openapi.New("test", "1")
+
+	doc.Add("GET", "/a", openapi.Operation{Summary: "get"})
+	doc.Add("POST", "/a", openapi.Operation{Summary: "post"})
+
+	require.Len(t, doc.Paths["/a"], 2)
+	assert.Equal(t, "post", doc.Paths["/a"]["post"].Summary)
+}
@@ -0,0 +1,275 @@
+// Package pagetitle fetches titles and OpenGraph metadata of web pages for link previews.
+package pagetitle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+
+	"url-shortener/internal/lib/urlcheck"
+)
+
+const (
+	// maxBody is how much of the page is read looking for the title.
+	maxBody = 64 << 10
+	// maxLength limits the length of returned titles in runes.
+	maxLength = 200
+	// maxDescriptionLength limits the length of returned descriptions in runes.
+	maxDescriptionLength = 300
+	// maxImageLength limits image urls, longer ones are dropped.
+	maxImageLength = 2048
+	maxRedirects   = 3
+)
+
+var (
+	ErrNoTitle = errors.New("page has no title")
+	// ErrNoMetadata means the page has neither a title nor OpenGraph metadata.
+	ErrNoMetadata = errors.New("page has no metadata")
+)
+
+// Metadata is what the page tells about itself in <head>.
+type Metadata struct {
+	// Title is the <title> of the page, or og:title if it has none.
+	Title string
+	// Description is og:description, or the description meta tag.
+	Description string
+	// Image is the absolute http(s) url of og:image.
+	Image string
+	// SiteName is og:site_name.
+	SiteName string
+}
+
+type Options struct {
+	Timeout time.Duration
+	// AllowPrivate allows fetching pages from loopback and private addresses.
+	// Keep it off in production: the service would fetch any URL a user saved.
+	AllowPrivate bool
+}
+
+type Fetcher struct {
+	client *http.Client
+}
+
+func New(opts Options) *Fetcher {
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivate {
+		dialer.Control = urlcheck.DenyPrivate
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// Через прокси проверка адреса в dialer не сработала бы
+	transport.Proxy = nil
+
+	return &Fetcher{
+		client: &http.Client{
+			Timeout:   opts.Timeout,
+			Transport: transport,
+			CheckRedirect: func(_ *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return http.ErrUseLastResponse
+				}
+
+				return nil
+			},
+		},
+	}
+}
+
+// Title returns the title of the HTML page at rawURL, unescaped and with collapsed spaces.
+func (f *Fetcher) Title(ctx context.Context, rawURL string) (string, error) {
+	const op = "lib.pagetitle.Title"
+
+	md, err := f.Fetch(ctx, rawURL)
+	if errors.Is(err, ErrNoMetadata) || (err == nil && md.Title == "") {
+		return "", fmt.Errorf("%s: %w", op, ErrNoTitle)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return md.Title, nil
+}
+
+// Fetch returns the title and OpenGraph metadata of the HTML page at rawURL.
+// Only the first 64 KiB of the page are read, <head> is expected there.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Metadata, error) {
+	const op = "lib.pagetitle.Fetch"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("Accept", "text/html")
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return Metadata{}, fmt.Errorf("%s: unexpected status %s", op, res.Status)
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); mediaType != "text/html" {
+		return Metadata{}, fmt.Errorf("%s: %w", op, ErrNoMetadata)
+	}
+
+	md, err := extract(io.LimitReader(res.Body, maxBody), res.Request.URL)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if md == (Metadata{}) {
+		return Metadata{}, fmt.Errorf("%s: %w", op, ErrNoMetadata)
+	}
+
+	return md, nil
+}
+
+// extract reads <title> and meta tags of the page up to <body>; base resolves
+// relative image urls.
+func extract(r io.Reader, base *url.URL) (Metadata, error) {
+	var (
+		md                  Metadata
+		ogTitle, metaDesc   string
+		inTitle, titleFound bool
+		title               strings.Builder
+	)
+
+	z := html.NewTokenizer(r)
+
+loop:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			// Страница может обрываться на лимите чтения
+			if err := z.Err(); !errors.Is(err, io.EOF) {
+				return Metadata{}, err
+			}
+
+			break loop
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+
+			switch string(name) {
+			case "title":
+				inTitle = !titleFound
+			case "body":
+				break loop
+			case "meta":
+				if !hasAttr {
+					continue
+				}
+
+				key, content := metaAttrs(z)
+				switch key {
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					md.Description = content
+				case "description":
+					metaDesc = content
+				case "og:image":
+					md.Image = content
+				case "og:site_name":
+					md.SiteName = content
+				}
+			}
+		case html.TextToken:
+			if inTitle {
+				title.Write(z.Text())
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+
+			switch string(name) {
+			case "title":
+				if inTitle {
+					inTitle, titleFound = false, true
+				}
+			case "head":
+				break loop
+			}
+		}
+	}
+
+	md.Title = clean(title.String(), maxLength)
+	if md.Title == "" {
+		md.Title = clean(ogTitle, maxLength)
+	}
+
+	if md.Description == "" {
+		md.Description = metaDesc
+	}
+	md.Description = clean(md.Description, maxDescriptionLength)
+	md.SiteName = clean(md.SiteName, maxLength)
+	md.Image = resolveImage(base, md.Image)
+
+	return md, nil
+}
+
+// metaAttrs returns the property or name of the meta tag, lowercased, and its content.
+func metaAttrs(z *html.Tokenizer) (key, content string) {
+	for {
+		name, value, more := z.TagAttr()
+
+		switch string(name) {
+		case "property", "name":
+			if key == "" {
+				key = strings.ToLower(string(value))
+			}
+		case "content":
+			content = string(value)
+		}
+
+		if !more {
+			return key, content
+		}
+	}
+}
+
+// clean collapses spaces of s and cuts it to max runes; invalid UTF-8 gives "".
+func clean(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if !utf8.ValidString(s) {
+		return ""
+	}
+
+	if utf8.RuneCountInString(s) > max {
+		s = string([]rune(s)[:max-1]) + "…"
+	}
+
+	return s
+}
+
+// resolveImage returns the absolute http(s) url of the image, or "" if it isn't one.
+func resolveImage(base *url.URL, image string) string {
+	image = strings.TrimSpace(image)
+	if image == "" || len(image) > maxImageLength {
+		return ""
+	}
+
+	ref, err := url.Parse(image)
+	if err != nil {
+		return ""
+	}
+
+	abs := base.ResolveReference(ref)
+	if abs.Scheme != "http" && abs.Scheme != "https" {
+		return ""
+	}
+
+	return abs.String()
+}
@@ -0,0 +1,155 @@
+package pagetitle_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/pagetitle"
+	"url-shortener/internal/lib/urlcheck"
+)
+
+func TestTitle(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+		err         error
+	}{
+		{
+			name:        "Simple",
+			contentType: "text/html; charset=utf-8",
+			body:        "<html><head><title>Example Domain</title></head></html>",
+			want:        "Example Domain",
+		},
+		{
+			name:        "Attributes, entities and spaces",
+			contentType: "text/html",
+			body:        "<HTML><TITLE data-x=\"1\">\n  Tom &amp; Jerry\n\t</TITLE>",
+			want:        "Tom & Jerry",
+		},
+		{
+			name:        "Long",
+			contentType: "text/html",
+			body:        "<title>" + strings.Repeat("a", 300) + "</title>",
+			want:        strings.Repeat("a", 199) + "…",
+		},
+		{
+			name:        "No title",
+			contentType: "text/html",
+			body:        "<html><body>hi</body></html>",
+			err:         pagetitle.ErrNoTitle,
+		},
+		{
+			name:        "Not HTML",
+			contentType: "application/json",
+			body:        `{"title": "x"}`,
+			err:         pagetitle.ErrNoTitle,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+
+			f := pagetitle.New(pagetitle.Options{Timeout: time.Second, AllowPrivate: true})
+
+			title, err := f.Title(context.Background(), srv.URL)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, title)
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		want pagetitle.Metadata
+		err  error
+	}{
+		{
+			name: "OpenGraph",
+			body: `<html><head><title>Example</title>` +
+				`<meta property="og:title" content="Example OG">` +
+				`<meta property="og:description" content="  An example &amp; more  ">` +
+				`<meta name="description" content="Plain description">` +
+				`<meta property="og:image" content="/img/cover.png">` +
+				`<meta property="og:site_name" content="Example Site">` +
+				`</head><body><title>Not this</title></body></html>`,
+			want: pagetitle.Metadata{
+				Title:       "Example",
+				Description: "An example & more",
+				Image:       "/img/cover.png",
+				SiteName:    "Example Site",
+			},
+		},
+		{
+			name: "Fallbacks",
+			body: `<head><meta property="og:title" content="Only OG"><meta name="Description" content="Plain">` +
+				`<meta property="og:image" content="javascript:alert(1)"></head>`,
+			want: pagetitle.Metadata{Title: "Only OG", Description: "Plain"},
+		},
+		{
+			name: "Nothing",
+			body: `<html><head></head><body><p>hi</p></body></html>`,
+			err:  pagetitle.ErrNoMetadata,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+
+			f := pagetitle.New(pagetitle.Options{Timeout: time.Second, AllowPrivate: true})
+
+			md, err := f.Fetch(context.Background(), srv.URL)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+
+				return
+			}
+
+			// Относительные адреса картинок разрешаются от адреса страницы
+			if tc.want.Image != "" {
+				tc.want.Image = srv.URL + tc.want.Image
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, md)
+		})
+	}
+}
+
+func TestTitle_PrivateAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<title>internal</title>"))
+	}))
+	defer srv.Close()
+
+	f := pagetitle.New(pagetitle.Options{Timeout: time.Second})
+
+	_, err := f.Title(context.Background(), srv.URL)
+	require.ErrorIs(t, err, urlcheck.ErrPrivateAddress)
+}
@@ -0,0 +1,85 @@
+// Package querytpl appends query parameter templates of links to their destinations,
+// so campaign tags like utm_source don't have to be baked into every long URL.
+package querytpl
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	// MaxParams limits the number of parameters of a template.
+	MaxParams = 20
+	// MaxLength limits the length of parameter names and values.
+	MaxLength = 256
+)
+
+// Placeholders are replaced in parameter values on every redirect.
+const (
+	// Alias is the alias of the link the visitor opened.
+	Alias = "{alias}"
+	// Variant is the name of the A/B test variant served, empty for links without a split.
+	Variant = "{variant}"
+)
+
+var placeholderRe = regexp.MustCompile(`\{[^{}]*\}`)
+
+// Vars are values of placeholders for a redirect.
+type Vars struct {
+	Alias   string
+	Variant string
+}
+
+// Validate checks that params fit the limits and use only known placeholders.
+func Validate(params map[string]string) error {
+	if len(params) > MaxParams {
+		return fmt.Errorf("at most %d parameters are allowed", MaxParams)
+	}
+
+	for name, value := range params {
+		if name == "" || len(name) > MaxLength || len(value) > MaxLength {
+			return fmt.Errorf("%q: name and value must be from 1 to %d characters", name, MaxLength)
+		}
+
+		for _, placeholder := range placeholderRe.FindAllString(value, -1) {
+			if placeholder != Alias && placeholder != Variant {
+				return fmt.Errorf("%q: unknown placeholder %s", name, placeholder)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Apply returns target with params and, if passthrough is set, incoming query
+// parameters. Later sources win: parameters of target are replaced by params of
+// the same name, and those by incoming ones.
+func Apply(target string, params map[string]string, incoming url.Values, passthrough bool, vars Vars) (string, error) {
+	if len(params) == 0 && (!passthrough || len(incoming) == 0) {
+		return target, nil
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+
+	replacer := strings.NewReplacer(Alias, vars.Alias, Variant, vars.Variant)
+	for name, value := range params {
+		query.Set(name, replacer.Replace(value))
+	}
+
+	if passthrough {
+		for name, values := range incoming {
+			query[name] = values
+		}
+	}
+
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
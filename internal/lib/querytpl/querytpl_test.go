@@ -0,0 +1,81 @@
+package querytpl_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/querytpl"
+)
+
+func TestApply(t *testing.T) {
+	cases := []struct {
+		name        string
+		target      string
+		params      map[string]string
+		incoming    string
+		passthrough bool
+		want        string
+	}{
+		{
+			name:   "No params",
+			target: "https://example.com/page?b=2&a=1",
+			want:   "https://example.com/page?b=2&a=1",
+		},
+		{
+			name:   "Params",
+			target: "https://example.com/page",
+			params: map[string]string{"utm_source": "shortener", "utm_campaign": "{alias}-{variant}"},
+			want:   "https://example.com/page?utm_campaign=promo-B&utm_source=shortener",
+		},
+		{
+			name:   "Params replace target ones",
+			target: "https://example.com/page?utm_source=old&id=1#top",
+			params: map[string]string{"utm_source": "new"},
+			want:   "https://example.com/page?id=1&utm_source=new#top",
+		},
+		{
+			name:        "Passthrough",
+			target:      "https://example.com/page",
+			params:      map[string]string{"utm_source": "shortener", "utm_medium": "link"},
+			incoming:    "utm_source=newsletter&ref=42",
+			passthrough: true,
+			want:        "https://example.com/page?ref=42&utm_medium=link&utm_source=newsletter",
+		},
+		{
+			name:     "Incoming ignored without passthrough",
+			target:   "https://example.com/page",
+			incoming: "ref=42",
+			want:     "https://example.com/page",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			incoming, err := url.ParseQuery(tc.incoming)
+			require.NoError(t, err)
+
+			got, err := querytpl.Apply(tc.target, tc.params, incoming, tc.passthrough,
+				querytpl.Vars{Alias: "promo", Variant: "B"})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, querytpl.Validate(map[string]string{"utm_campaign": "{alias}", "utm_content": "{variant}"}))
+
+	err := querytpl.Validate(map[string]string{"utm_campaign": "{country}"})
+	assert.EqualError(t, err, `"utm_campaign": unknown placeholder {country}`)
+
+	assert.Error(t, querytpl.Validate(map[string]string{"": "empty"}))
+	assert.Error(t, querytpl.Validate(map[string]string{"long": strings.Repeat("a", querytpl.MaxLength+1)}))
+}
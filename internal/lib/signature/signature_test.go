@@ -0,0 +1,60 @@
+package signature
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStringToSign(t *testing.T) {
+	got := StringToSign("post", "/url?tag=go", "1700000000", "nonce", []byte(`{"url":"https://example.com"}`))
+
+	lines := strings.Split(got, "\n")
+	require.Len(t, lines, 5)
+	assert.Equal(t, []string{"POST", "/url?tag=go", "1700000000", "nonce"}, lines[:4])
+	assert.Len(t, lines[4], 64)
+
+	// Пустое тело подписывается хешем пустой строки
+	assert.True(t, strings.HasSuffix(StringToSign("GET", "/url", "1", "n", nil),
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"))
+}
+
+func TestSign(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	r := httptest.NewRequest(http.MethodPost, "https://sho.rt/url?tag=go", strings.NewReader(`{"url":"https://example.com"}`))
+	require.NoError(t, Sign(r, 7, "secret", now))
+
+	assert.Equal(t, "7", r.Header.Get(HeaderKeyID))
+	assert.Equal(t, "1700000000", r.Header.Get(HeaderTimestamp))
+	assert.GreaterOrEqual(t, len(r.Header.Get(HeaderNonce)), 16)
+
+	// Тело остается доступным для отправки
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"url":"https://example.com"}`, string(body))
+
+	stringToSign := StringToSign(http.MethodPost, "/url?tag=go", "1700000000", r.Header.Get(HeaderNonce), body)
+
+	assert.True(t, Valid("secret", stringToSign, r.Header.Get(HeaderSignature)))
+	assert.True(t, Valid("secret", stringToSign, strings.ToUpper(r.Header.Get(HeaderSignature))))
+	assert.False(t, Valid("other", stringToSign, r.Header.Get(HeaderSignature)))
+	assert.False(t, Valid("secret", stringToSign+"x", r.Header.Get(HeaderSignature)))
+	assert.False(t, Valid("secret", stringToSign, "not hex"))
+}
+
+func TestGenerateSecret(t *testing.T) {
+	s1, err := GenerateSecret()
+	require.NoError(t, err)
+	s2, err := GenerateSecret()
+	require.NoError(t, err)
+
+	assert.NotEqual(t, s1, s2)
+	assert.Len(t, s1, 43)
+}
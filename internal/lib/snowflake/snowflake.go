@@ -0,0 +1,93 @@
+// Package snowflake generates unique time-ordered IDs without coordination between
+// instances: every instance has its own node ID, and IDs of one node never repeat.
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	nodeBits     = 10
+	sequenceBits = 12
+
+	// MaxNode is the largest node ID.
+	MaxNode = 1<<nodeBits - 1
+
+	maxSequence = 1<<sequenceBits - 1
+)
+
+// Epoch is the start of ID timestamps; a recent epoch keeps encoded IDs short.
+var Epoch = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var ErrInvalidNode = errors.New("invalid node id")
+
+// Generator returns IDs made of milliseconds since Epoch, the node ID and a sequence
+// number within the millisecond, 4096 IDs per millisecond per node.
+type Generator struct {
+	mu   sync.Mutex
+	node int64
+	// last is the millisecond of the previous ID; it never goes back, even if the clock does.
+	last int64
+	seq  int64
+	now  func() time.Time
+}
+
+func New(node int) (*Generator, error) {
+	const op = "lib.snowflake.New"
+
+	if node < 0 || node > MaxNode {
+		return nil, fmt.Errorf("%s: %w: %d, must be from 0 to %d", op, ErrInvalidNode, node, MaxNode)
+	}
+
+	return &Generator{node: int64(node), now: time.Now}, nil
+}
+
+// Next returns a new ID.
+func (g *Generator) Next() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := g.now().Sub(Epoch).Milliseconds()
+
+	switch {
+	case ms > g.last:
+		g.last = ms
+		g.seq = 0
+	case g.seq < maxSequence:
+		// Та же миллисекунда или часы ушли назад
+		g.seq++
+	default:
+		// Номера миллисекунды исчерпаны: занимаем следующую вместо ожидания
+		g.last++
+		g.seq = 0
+	}
+
+	return g.last<<(nodeBits+sequenceBits) | g.node<<sequenceBits | g.seq
+}
+
+// NextString returns a new ID encoded in base62.
+func (g *Generator) NextString() string {
+	return Encode(g.Next())
+}
+
+const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// Encode returns id in base62; id must not be negative.
+func Encode(id int64) string {
+	if id == 0 {
+		return alphabet[:1]
+	}
+
+	var b [11]byte
+	i := len(b)
+	for id > 0 {
+		i--
+		b[i] = alphabet[id%62]
+		id /= 62
+	}
+
+	return string(b[i:])
+}
@@ -0,0 +1,102 @@
+package snowflake
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New(MaxNode)
+	require.NoError(t, err)
+
+	_, err = New(MaxNode + 1)
+	require.ErrorIs(t, err, ErrInvalidNode)
+
+	_, err = New(-1)
+	require.ErrorIs(t, err, ErrInvalidNode)
+}
+
+func TestNext_Unique(t *testing.T) {
+	const perNode = 10000
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[int64]struct{})
+		wg   sync.WaitGroup
+	)
+
+	// Два экземпляра с разными node генерируют одновременно
+	for node := 0; node < 2; node++ {
+		g, err := New(node)
+		require.NoError(t, err)
+
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				ids := make([]int64, perNode)
+				for i := range ids {
+					ids[i] = g.Next()
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				for _, id := range ids {
+					seen[id] = struct{}{}
+				}
+			}()
+		}
+	}
+
+	wg.Wait()
+
+	assert.Len(t, seen, 2*4*perNode)
+}
+
+func TestNext_Clock(t *testing.T) {
+	now := Epoch.Add(time.Hour)
+
+	g, err := New(1)
+	require.NoError(t, err)
+	g.now = func() time.Time { return now }
+
+	prev := g.Next()
+
+	// Часы ушли назад: ID все равно растут
+	now = now.Add(-time.Second)
+	for i := 0; i < 2*maxSequence; i++ {
+		id := g.Next()
+		require.Greater(t, id, prev)
+
+		prev = id
+	}
+}
+
+func TestEncode(t *testing.T) {
+	cases := []struct {
+		id   int64
+		want string
+	}{
+		{id: 0, want: "0"},
+		{id: 61, want: "z"},
+		{id: 62, want: "10"},
+		{id: 1<<63 - 1, want: "AzL8n0Y58m7"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.want, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, Encode(tc.id))
+		})
+	}
+}
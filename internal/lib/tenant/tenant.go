@@ -0,0 +1,71 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"url-shortener/internal/lib/aliascheck"
+)
+
+// Default is the tenant of deployments without multi-tenancy. Its aliases are stored as is.
+const Default = ""
+
+// separator joins the tenant and the alias in storage keys. Aliases can't contain it.
+const separator = "/"
+
+const maxLength = 32
+
+var ErrInvalid = errors.New("invalid tenant")
+
+// Validate checks that name has allowed length and charset (letters, digits, '-' and '_').
+func Validate(name string) error {
+	if name == "" || len(name) > maxLength || !aliascheck.HasValidCharset(name) {
+		return ErrInvalid
+	}
+
+	return nil
+}
+
+// Key returns the storage key of alias in the tenant namespace, so equal aliases
+// of different tenants don't collide.
+func Key(tenant, alias string) string {
+	if tenant == Default {
+		return alias
+	}
+
+	return tenant + separator + alias
+}
+
+// Alias returns the alias of the storage key as seen by tenant. The default tenant
+// sees keys of other tenants as is.
+func Alias(tenant, key string) string {
+	if tenant == Default {
+		return key
+	}
+
+	return strings.TrimPrefix(key, tenant+separator)
+}
+
+// Owns reports whether the storage key belongs to the tenant namespace.
+func Owns(tenant, key string) bool {
+	if tenant == Default {
+		return !strings.Contains(key, separator)
+	}
+
+	return strings.HasPrefix(key, tenant+separator)
+}
+
+type ctxKey struct{}
+
+// WithTenant returns a copy of ctx carrying the tenant of the request.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, tenant)
+}
+
+// FromContext returns the tenant of the request, Default if there is none.
+func FromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(ctxKey{}).(string)
+
+	return tenant
+}
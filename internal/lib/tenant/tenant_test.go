@@ -0,0 +1,35 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate("brand-1"))
+	assert.ErrorIs(t, Validate(""), ErrInvalid)
+	assert.ErrorIs(t, Validate("a/b"), ErrInvalid)
+	assert.ErrorIs(t, Validate("brand.example"), ErrInvalid)
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "alias", Key(Default, "alias"))
+	assert.Equal(t, "brand/alias", Key("brand", "alias"))
+
+	assert.Equal(t, "alias", Alias("brand", "brand/alias"))
+	assert.Equal(t, "brand/alias", Alias(Default, "brand/alias"))
+	assert.Equal(t, "alias", Alias(Default, "alias"))
+
+	assert.True(t, Owns("brand", "brand/alias"))
+	assert.False(t, Owns("brand", "alias"))
+	assert.False(t, Owns("brand", "brand-2/alias"))
+	assert.True(t, Owns(Default, "alias"))
+	assert.False(t, Owns(Default, "brand/alias"))
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, Default, FromContext(context.Background()))
+	assert.Equal(t, "brand", FromContext(WithTenant(context.Background(), "brand")))
+}
@@ -0,0 +1,65 @@
+// Package uadetect tells the platform of a visitor from the User-Agent header.
+// It knows only what redirect rules need and is not a general purpose parser.
+package uadetect
+
+import "strings"
+
+type Platform string
+
+const (
+	IOS     Platform = "ios"
+	Android Platform = "android"
+	Desktop Platform = "desktop"
+	// Unknown covers bots, command line clients and empty User-Agent.
+	Unknown Platform = ""
+)
+
+// Platforms are the platforms links can have destinations for.
+var Platforms = []Platform{IOS, Android, Desktop}
+
+// Valid reports whether p is one of Platforms.
+func Valid(p Platform) bool {
+	for _, known := range Platforms {
+		if p == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Detect returns the platform of the user agent.
+func Detect(userAgent string) Platform {
+	ua := strings.ToLower(userAgent)
+
+	switch {
+	case ua == "":
+		return Unknown
+	// Android раньше Linux: в User-Agent Android тоже есть "Linux"
+	case strings.Contains(ua, "android"):
+		return Android
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return IOS
+	case isBot(ua):
+		return Unknown
+	case strings.Contains(ua, "windows"), strings.Contains(ua, "macintosh"), strings.Contains(ua, "mac os x"),
+		strings.Contains(ua, "x11"), strings.Contains(ua, "linux"), strings.Contains(ua, "cros"):
+		return Desktop
+	default:
+		return Unknown
+	}
+}
+
+// botMarkers are substrings of crawler and library user agents. Crawlers often
+// pretend to be desktop browsers, but should see the default destination.
+var botMarkers = []string{"bot", "crawler", "spider", "slurp", "facebookexternalhit", "curl/", "wget/"}
+
+func isBot(ua string) bool {
+	for _, marker := range botMarkers {
+		if strings.Contains(ua, marker) {
+			return true
+		}
+	}
+
+	return false
+}
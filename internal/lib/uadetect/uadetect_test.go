@@ -0,0 +1,72 @@
+package uadetect_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/lib/uadetect"
+)
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		name      string
+		userAgent string
+		platform  uadetect.Platform
+	}{
+		{
+			name:      "iPhone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			platform:  uadetect.IOS,
+		},
+		{
+			name:      "iPad",
+			userAgent: "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0.6099.119 Mobile/15E148 Safari/604.1",
+			platform:  uadetect.IOS,
+		},
+		{
+			name:      "Android",
+			userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+			platform:  uadetect.Android,
+		},
+		{
+			name:      "Windows",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			platform:  uadetect.Desktop,
+		},
+		{
+			name:      "macOS",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15",
+			platform:  uadetect.Desktop,
+		},
+		{
+			name:      "Linux",
+			userAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+			platform:  uadetect.Desktop,
+		},
+		{
+			name:      "Crawler",
+			userAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			platform:  uadetect.Unknown,
+		},
+		{
+			name:      "curl",
+			userAgent: "curl/8.5.0",
+			platform:  uadetect.Unknown,
+		},
+		{
+			name:     "Empty",
+			platform: uadetect.Unknown,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.platform, uadetect.Detect(tc.userAgent))
+		})
+	}
+}
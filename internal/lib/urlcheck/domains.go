@@ -0,0 +1,102 @@
+package urlcheck
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+// DomainPolicy limits the domains URLs can point to. Patterns are host names where
+// * matches any characters, dots included: "*.example.com" matches all subdomains
+// of example.com, but not example.com itself. Blocked domains are rejected even if
+// they are allowed; with an empty allow list any domain which is not blocked passes.
+type DomainPolicy struct {
+	mu    sync.RWMutex
+	block []string
+	allow []string
+}
+
+// NewDomainPolicy checks the patterns and builds the policy.
+func NewDomainPolicy(block, allow []string) (*DomainPolicy, error) {
+	const op = "urlcheck.NewDomainPolicy"
+
+	p := &DomainPolicy{}
+
+	var err error
+	if p.block, err = patterns(block); err != nil {
+		return nil, fmt.Errorf("%s: block: %w", op, err)
+	}
+	if p.allow, err = patterns(allow); err != nil {
+		return nil, fmt.Errorf("%s: allow: %w", op, err)
+	}
+
+	return p, nil
+}
+
+func patterns(entries []string) ([]string, error) {
+	res := make([]string, 0, len(entries))
+
+	for _, e := range entries {
+		e = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(e)), ".")
+		if e == "" {
+			continue
+		}
+
+		// Шаблон проверяем сразу, чтобы ошибка в конфиге не обнаружилась на первой ссылке
+		if _, err := path.Match(e, ""); err != nil || strings.ContainsAny(e, "/:") {
+			return nil, fmt.Errorf("invalid pattern %q", e)
+		}
+
+		res = append(res, e)
+	}
+
+	return res, nil
+}
+
+// Replace swaps the lists of p for the lists of other, e.g. a policy built from the reloaded config.
+func (p *DomainPolicy) Replace(other *DomainPolicy) {
+	other.mu.RLock()
+	block, allow := other.block, other.allow
+	other.mu.RUnlock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.block, p.allow = block, allow
+}
+
+// AllowedHost reports whether URLs can point to host.
+func (p *DomainPolicy) AllowedHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if matchAny(p.block, host) {
+		return false
+	}
+
+	return len(p.allow) == 0 || matchAny(p.allow, host)
+}
+
+// Allowed reports whether the host of rawURL is allowed; URLs without a host are.
+func (p *DomainPolicy) Allowed(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return true
+	}
+
+	return p.AllowedHost(u.Hostname())
+}
+
+func matchAny(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,135 @@
+// Package urlcheck validates and normalizes URLs before they are shortened.
+package urlcheck
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+var (
+	ErrInvalid        = errors.New("url is not valid")
+	ErrScheme         = errors.New("url scheme is not allowed")
+	ErrTooLong        = errors.New("url is too long")
+	ErrPrivateAddress = errors.New("url points to a private address")
+	ErrDomain         = errors.New("url domain is not allowed")
+)
+
+type Options struct {
+	// Schemes are allowed URL schemes, http and https if empty.
+	Schemes []string
+	// MaxLength limits the length of the normalized URL, zero means no limit.
+	MaxLength int
+	// BlockPrivate rejects loopback, private and link-local IP hosts and localhost,
+	// so short links can't be used to reach internal services. Host names are not resolved.
+	BlockPrivate bool
+	// StripFragment removes #fragment, it is never sent to the server anyway.
+	StripFragment bool
+	// Domains limit hosts of URLs, nil allows any.
+	Domains *DomainPolicy
+}
+
+type Checker struct {
+	schemes map[string]struct{}
+	opts    Options
+}
+
+func New(opts Options) *Checker {
+	schemes := opts.Schemes
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+
+	c := &Checker{schemes: make(map[string]struct{}, len(schemes)), opts: opts}
+	for _, s := range schemes {
+		c.schemes[strings.ToLower(strings.TrimSpace(s))] = struct{}{}
+	}
+
+	return c
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Normalize checks rawURL and returns it with lower-case scheme and host,
+// without default port and, if configured, without fragment.
+func (c *Checker) Normalize(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return "", ErrInvalid
+	}
+
+	// url.Parse уже приводит схему к нижнему регистру
+	if _, ok := c.schemes[u.Scheme]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrScheme, u.Scheme)
+	}
+
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+
+	if c.opts.BlockPrivate && isPrivate(host) {
+		return "", ErrPrivateAddress
+	}
+
+	if c.opts.Domains != nil && !c.opts.Domains.AllowedHost(host) {
+		return "", fmt.Errorf("%w: %s", ErrDomain, host)
+	}
+
+	u.Host = host
+	if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	}
+	if port != "" {
+		u.Host += ":" + port
+	}
+
+	if c.opts.StripFragment {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+
+	normalized := u.String()
+	if c.opts.MaxLength > 0 && len(normalized) > c.opts.MaxLength {
+		return "", fmt.Errorf("%w, max %d characters", ErrTooLong, c.opts.MaxLength)
+	}
+
+	return normalized, nil
+}
+
+func isPrivate(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && PrivateIP(ip)
+}
+
+// PrivateIP reports whether ip is a loopback, private, link-local or unspecified address.
+func PrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()
+}
+
+// DenyPrivate is a net.Dialer Control refusing connections to private addresses.
+// The address is checked after name resolution, so DNS can't be used to get around it.
+func DenyPrivate(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || PrivateIP(ip) {
+		return ErrPrivateAddress
+	}
+
+	return nil
+}
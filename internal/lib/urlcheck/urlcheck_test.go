@@ -0,0 +1,113 @@
+package urlcheck_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/urlcheck"
+)
+
+func TestNormalize(t *testing.T) {
+	checker := urlcheck.New(urlcheck.Options{
+		MaxLength:     40,
+		BlockPrivate:  true,
+		StripFragment: true,
+	})
+
+	cases := []struct {
+		name string
+		url  string
+		want string
+		err  error
+	}{
+		{name: "Unchanged", url: "https://example.com/Path?q=1", want: "https://example.com/Path?q=1"},
+		{name: "Case of scheme and host", url: "HTTPS://Example.COM/Path", want: "https://example.com/Path"},
+		{name: "Default port", url: "http://example.com:80/a", want: "http://example.com/a"},
+		{name: "Other port", url: "http://example.com:8080/a", want: "http://example.com:8080/a"},
+		{name: "Fragment", url: "https://example.com/a#top", want: "https://example.com/a"},
+		{name: "IPv6", url: "https://[2001:DB8::1]:443/", want: "https://[2001:db8::1]/"},
+		{name: "Scheme", url: "ftp://example.com/file", err: urlcheck.ErrScheme},
+		{name: "Javascript", url: "javascript:alert(1)", err: urlcheck.ErrInvalid},
+		{name: "No host", url: "/relative", err: urlcheck.ErrInvalid},
+		{name: "Too long", url: "https://example.com/" + strings.Repeat("a", 30), err: urlcheck.ErrTooLong},
+		{name: "Loopback", url: "http://127.0.0.1/admin", err: urlcheck.ErrPrivateAddress},
+		{name: "Private", url: "http://10.0.0.5/", err: urlcheck.ErrPrivateAddress},
+		{name: "Link-local", url: "http://169.254.169.254/latest/meta-data", err: urlcheck.ErrPrivateAddress},
+		{name: "IPv6 loopback", url: "http://[::1]/", err: urlcheck.ErrPrivateAddress},
+		{name: "Localhost", url: "http://LOCALHOST:8080/", err: urlcheck.ErrPrivateAddress},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := checker.Normalize(tc.url)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestNormalize_Options(t *testing.T) {
+	checker := urlcheck.New(urlcheck.Options{Schemes: []string{"HTTPS"}})
+
+	got, err := checker.Normalize("https://127.0.0.1/#frag")
+	require.NoError(t, err)
+	require.Equal(t, "https://127.0.0.1/#frag", got)
+
+	_, err = checker.Normalize("http://example.com")
+	require.ErrorIs(t, err, urlcheck.ErrScheme)
+}
+
+func TestDomainPolicy(t *testing.T) {
+	policy, err := urlcheck.NewDomainPolicy(
+		[]string{"*.evil.example", "bad-*.com"},
+		[]string{"example.com", "*.example.com", "*.evil.example", "bad-site.com", "Other.org."},
+	)
+	require.NoError(t, err)
+
+	cases := []struct {
+		host string
+		want bool
+	}{
+		{host: "example.com", want: true},
+		{host: "a.b.example.com", want: true},
+		{host: "other.org", want: true},
+		{host: "EXAMPLE.com.", want: true},
+		{host: "notexample.com", want: false},
+		{host: "x.evil.example", want: false},
+		{host: "bad-site.com", want: false},
+		{host: "example.net", want: false},
+	}
+
+	for _, tc := range cases {
+		require.Equal(t, tc.want, policy.AllowedHost(tc.host), tc.host)
+	}
+
+	require.True(t, policy.Allowed("https://www.example.com/path"))
+	require.False(t, policy.Allowed("https://x.evil.example/login"))
+
+	checker := urlcheck.New(urlcheck.Options{Domains: policy})
+
+	_, err = checker.Normalize("https://example.net/")
+	require.ErrorIs(t, err, urlcheck.ErrDomain)
+
+	// Пустые списки пропускают любой домен
+	empty, err := urlcheck.NewDomainPolicy(nil, nil)
+	require.NoError(t, err)
+
+	policy.Replace(empty)
+	require.True(t, policy.AllowedHost("example.net"))
+
+	_, err = urlcheck.NewDomainPolicy([]string{"[a-"}, nil)
+	require.Error(t, err)
+
+	_, err = urlcheck.NewDomainPolicy(nil, []string{"https://example.com"})
+	require.Error(t, err)
+}
@@ -0,0 +1,146 @@
+// Package mailer sends emails to users, e.g. links to confirm the email or reset the password.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails. Send is expected to limit its own duration.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type SMTPOptions struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// From is the sender address, e.g. "url-shortener@example.com".
+	From    string
+	Timeout time.Duration
+}
+
+// SMTP sends emails through an SMTP server, upgrading the connection with STARTTLS
+// when the server supports it.
+type SMTP struct {
+	opts SMTPOptions
+}
+
+func NewSMTP(opts SMTPOptions) *SMTP {
+	return &SMTP{opts: opts}
+}
+
+func (m *SMTP) Send(ctx context.Context, msg Message) error {
+	const op = "mailer.SMTP.Send"
+
+	ctx, cancel := context.WithTimeout(ctx, m.opts.Timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(m.opts.Host, strconv.Itoa(m.opts.Port))
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("%s: dial: %w", op, err)
+	}
+
+	// net/smtp не принимает контекст, поэтому время сессии ограничиваем дедлайном соединения
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		_ = conn.Close()
+
+		return fmt.Errorf("%s: set deadline: %w", op, err)
+	}
+
+	c, err := smtp.NewClient(conn, m.opts.Host)
+	if err != nil {
+		_ = conn.Close()
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.opts.Host}); err != nil {
+			return fmt.Errorf("%s: starttls: %w", op, err)
+		}
+	}
+
+	if m.opts.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.opts.Username, m.opts.Password, m.opts.Host)); err != nil {
+			return fmt.Errorf("%s: auth: %w", op, err)
+		}
+	}
+
+	if err := c.Mail(m.opts.From); err != nil {
+		return fmt.Errorf("%s: mail from: %w", op, err)
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("%s: rcpt to: %w", op, err)
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("%s: data: %w", op, err)
+	}
+	if _, err := w.Write(build(m.opts.From, msg, time.Now())); err != nil {
+		return fmt.Errorf("%s: write: %w", op, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("%s: data: %w", op, err)
+	}
+
+	return c.Quit()
+}
+
+// build returns msg with headers, the subject is encoded to allow non-ASCII characters.
+func build(from string, msg Message, now time.Time) []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+
+	return b.Bytes()
+}
+
+// Log writes emails to the log instead of sending them. It is used when SMTP is not
+// configured, e.g. in development; the log then contains tokens of users.
+type Log struct {
+	log *slog.Logger
+}
+
+func NewLog(log *slog.Logger) *Log {
+	return &Log{log: log.With(slog.String("component", "mailer"))}
+}
+
+func (m *Log) Send(_ context.Context, msg Message) error {
+	m.log.Info("email is not sent, SMTP is not configured",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("body", msg.Body),
+	)
+
+	return nil
+}
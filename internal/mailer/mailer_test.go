@@ -0,0 +1,100 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuild(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	got := string(build("noreply@example.com", Message{
+		To:      "user@example.com",
+		Subject: "Подтвердите email",
+		Body:    "Hello",
+	}, now))
+
+	assert.Contains(t, got, "From: noreply@example.com\r\n")
+	assert.Contains(t, got, "To: user@example.com\r\n")
+	assert.Contains(t, got, "Subject: =?utf-8?q?")
+	assert.Contains(t, got, "Date: Wed, 01 May 2024 12:00:00 +0000\r\n")
+	assert.True(t, strings.HasSuffix(got, "\r\n\r\nHello"))
+}
+
+func TestSMTP_Send(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	received := make(chan []string, 1)
+	go serveSMTP(ln, received)
+
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+	portNum, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	m := NewSMTP(SMTPOptions{Host: host, Port: portNum, From: "noreply@example.com", Timeout: 5 * time.Second})
+
+	err = m.Send(context.Background(), Message{To: "user@example.com", Subject: "Hi", Body: "token"})
+	require.NoError(t, err)
+
+	commands := <-received
+	assert.Contains(t, commands, "MAIL FROM:<noreply@example.com> BODY=8BITMIME")
+	assert.Contains(t, commands, "RCPT TO:<user@example.com>")
+	assert.Contains(t, commands, "token")
+}
+
+// serveSMTP accepts one session of a minimal SMTP server and sends the lines received from the client.
+func serveSMTP(ln net.Listener, received chan<- []string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var lines []string
+	defer func() { received <- lines }()
+
+	r := bufio.NewReader(conn)
+	reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+
+	data := false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		lines = append(lines, line)
+
+		switch {
+		case data && line == ".":
+			data = false
+			reply("250 OK")
+		case data:
+		case strings.HasPrefix(line, "EHLO"):
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case strings.HasPrefix(line, "DATA"):
+			data = true
+			reply("354 Go ahead")
+		case strings.HasPrefix(line, "QUIT"):
+			reply("221 Bye")
+
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
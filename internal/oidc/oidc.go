@@ -0,0 +1,132 @@
+// Package oidc delegates authentication of users to OAuth2 providers: OpenID Connect
+// ones, e.g. Google or Keycloak, and GitHub, which doesn't support OpenID Connect.
+// Providers are used with the authorization code flow and only tell the verified
+// email of the user; accounts are managed by the caller.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrEmailNotVerified means the provider doesn't confirm that the email belongs to the user.
+	ErrEmailNotVerified = errors.New("email is not verified")
+	ErrInvalidToken     = errors.New("invalid id token")
+)
+
+// httpTimeout limits requests to providers, so a stuck provider doesn't hold a login forever.
+const httpTimeout = 10 * time.Second
+
+// Identity is the user authenticated by a provider.
+type Identity struct {
+	Email string
+}
+
+// Provider sends users to log in and identifies them when they come back with the code.
+type Provider interface {
+	// AuthCodeURL returns the login page of the provider. state is returned to the
+	// callback as is, nonce is put into the id token of OpenID Connect providers.
+	AuthCodeURL(ctx context.Context, state, nonce string) (string, error)
+	// Exchange trades the authorization code for the identity of the user;
+	// ErrEmailNotVerified is returned if the email is not verified by the provider.
+	Exchange(ctx context.Context, code, nonce string) (Identity, error)
+}
+
+// Config is the client registered at the provider.
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback the provider sends users back to.
+	RedirectURL string
+	// Scopes are requested in addition to the ones needed to get the email.
+	Scopes []string
+}
+
+// NewState returns a random value for the state and nonce parameters.
+func NewState() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchange requests tokens for the authorization code at the token endpoint.
+func exchange(ctx context.Context, client *http.Client, tokenURL string, cfg Config, code string) (tokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {cfg.RedirectURL},
+		"client_id":     {cfg.ClientID},
+		"client_secret": {cfg.ClientSecret},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// GitHub отвечает формой, если не попросить JSON
+	req.Header.Set("Accept", "application/json")
+
+	var token tokenResponse
+	if err := doJSON(client, req, &token); err != nil {
+		return tokenResponse{}, fmt.Errorf("token endpoint: %w", err)
+	}
+
+	// Ошибки OAuth2 приходят и с кодом 200, например у GitHub
+	if token.Error != "" {
+		return tokenResponse{}, fmt.Errorf("token endpoint: %s: %s", token.Error, token.ErrorDescription)
+	}
+	if token.AccessToken == "" {
+		return tokenResponse{}, errors.New("token endpoint: no access token")
+	}
+
+	return token, nil
+}
+
+// maxResponseSize limits responses of providers read into memory.
+const maxResponseSize = 1 << 20
+
+// doJSON sends req and decodes the JSON response into v. Error responses of OAuth2
+// endpoints are JSON too, so they are decoded for 400 as well.
+func doJSON(client *http.Client, req *http.Request, v any) error {
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	return nil
+}
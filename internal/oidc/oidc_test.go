@@ -0,0 +1,198 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testConfig = Config{ClientID: "client", ClientSecret: "secret", RedirectURL: "https://sho.rt/auth/sso/test/callback"}
+
+// newIssuer starts an OpenID Connect provider which issues idToken for the code "code".
+func newIssuer(t *testing.T, idToken func(issuer string) string) *httptest.Server {
+	t.Helper()
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_ = json.NewEncoder(w).Encode(metadata{
+				Issuer:                srv.URL,
+				AuthorizationEndpoint: srv.URL + "/authorize",
+				TokenEndpoint:         srv.URL + "/token",
+			})
+		case "/token":
+			if r.PostFormValue("code") != "code" || r.PostFormValue("client_secret") != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error": "invalid_grant"}`))
+
+				return
+			}
+
+			_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "id_token": idToken(srv.URL)})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+
+	// Подпись не проверяется, поэтому подходит любой ключ
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("key"))
+	require.NoError(t, err)
+
+	return token
+}
+
+func TestOIDC(t *testing.T) {
+	srv := newIssuer(t, func(issuer string) string {
+		return sign(t, jwt.MapClaims{
+			"iss":            issuer,
+			"aud":            "client",
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"nonce":          "nonce",
+			"email":          "user@example.com",
+			"email_verified": true,
+		})
+	})
+
+	p := NewOIDC(srv.URL+"/", testConfig)
+
+	authURL, err := p.AuthCodeURL(context.Background(), "state", "nonce")
+	require.NoError(t, err)
+
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	assert.Equal(t, "client", u.Query().Get("client_id"))
+	assert.Equal(t, testConfig.RedirectURL, u.Query().Get("redirect_uri"))
+	assert.Equal(t, "openid email", u.Query().Get("scope"))
+	assert.Equal(t, "state", u.Query().Get("state"))
+	assert.Equal(t, "nonce", u.Query().Get("nonce"))
+
+	identity, err := p.Exchange(context.Background(), "code", "nonce")
+	require.NoError(t, err)
+	assert.Equal(t, Identity{Email: "user@example.com"}, identity)
+
+	_, err = p.Exchange(context.Background(), "wrong", "nonce")
+	require.ErrorContains(t, err, "invalid_grant")
+}
+
+func TestOIDC_Verify(t *testing.T) {
+	const issuer = "https://issuer.example"
+
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":            issuer,
+			"aud":            []string{"other", "client"},
+			"exp":            now.Add(time.Minute).Unix(),
+			"nonce":          "nonce",
+			"email":          "user@example.com",
+			"email_verified": "true",
+		}
+	}
+
+	cases := []struct {
+		name    string
+		change  func(jwt.MapClaims)
+		wantErr error
+	}{
+		{name: "Valid", change: func(jwt.MapClaims) {}},
+		{name: "Other issuer", change: func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }, wantErr: ErrInvalidToken},
+		{name: "Other audience", change: func(c jwt.MapClaims) { c["aud"] = "other" }, wantErr: ErrInvalidToken},
+		{name: "Expired", change: func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() }, wantErr: ErrInvalidToken},
+		{name: "No expiration", change: func(c jwt.MapClaims) { delete(c, "exp") }, wantErr: ErrInvalidToken},
+		{name: "Other nonce", change: func(c jwt.MapClaims) { c["nonce"] = "replayed" }, wantErr: ErrInvalidToken},
+		{name: "Unverified email", change: func(c jwt.MapClaims) { c["email_verified"] = false }, wantErr: ErrEmailNotVerified},
+		{name: "No email", change: func(c jwt.MapClaims) { delete(c, "email") }, wantErr: ErrEmailNotVerified},
+	}
+
+	p := NewOIDC(issuer, testConfig)
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			claims := valid()
+			tc.change(claims)
+
+			identity, err := p.verify(sign(t, claims), issuer, "nonce", now)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "user@example.com", identity.Email)
+		})
+	}
+}
+
+func TestGitHub(t *testing.T) {
+	emails := `[{"email": "old@example.com", "primary": false, "verified": true},
+		{"email": "user@example.com", "primary": true, "verified": true}]`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login/oauth/access_token":
+			// GitHub сообщает об ошибках OAuth2 с кодом 200
+			if r.PostFormValue("code") != "code" {
+				_, _ = w.Write([]byte(`{"error": "bad_verification_code"}`))
+
+				return
+			}
+
+			_, _ = w.Write([]byte(`{"access_token": "access"}`))
+		case "/user/emails":
+			if r.Header.Get("Authorization") != "Bearer access" {
+				w.WriteHeader(http.StatusUnauthorized)
+
+				return
+			}
+
+			_, _ = w.Write([]byte(emails))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewGitHub(testConfig)
+	p.tokenURL = srv.URL + "/login/oauth/access_token"
+	p.apiURL = srv.URL
+
+	authURL, err := p.AuthCodeURL(context.Background(), "state", "nonce")
+	require.NoError(t, err)
+	assert.Contains(t, authURL, "https://github.com/login/oauth/authorize?")
+	assert.Contains(t, authURL, "scope=user%3Aemail")
+	assert.Contains(t, authURL, "state=state")
+
+	identity, err := p.Exchange(context.Background(), "code", "")
+	require.NoError(t, err)
+	assert.Equal(t, Identity{Email: "user@example.com"}, identity)
+
+	_, err = p.Exchange(context.Background(), "wrong", "")
+	require.ErrorContains(t, err, "bad_verification_code")
+
+	emails = `[{"email": "user@example.com", "primary": true, "verified": false}]`
+
+	_, err = p.Exchange(context.Background(), "code", "")
+	require.ErrorIs(t, err, ErrEmailNotVerified)
+}
@@ -0,0 +1,151 @@
+// Package pagemeta collects titles and OpenGraph metadata of destination pages of
+// new links in the background and stores them for lists, previews and the admin UI.
+package pagemeta
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	linkEvents "url-shortener/internal/events"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/pagetitle"
+	"url-shortener/internal/storage"
+)
+
+// Fetcher is an interface for fetching metadata of web pages, see package pagetitle.
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (pagetitle.Metadata, error)
+}
+
+// Store is an interface for storing metadata of destination pages.
+type Store interface {
+	SetPageMetadata(ctx context.Context, alias string, page storage.PageMetadata) error
+}
+
+type Options struct {
+	// Workers is the number of concurrent fetches.
+	Workers int
+	// BufferSize is the number of links which may wait for a fetch; extra ones are skipped.
+	BufferSize int
+	// Timeout limits fetching and storing metadata of one link.
+	Timeout time.Duration
+}
+
+type job struct {
+	alias string
+	url   string
+}
+
+// Collector fetches metadata of new links from background workers, so saving a link
+// doesn't wait for its destination. It is a link event publisher: links are queued on
+// link_created.
+type Collector struct {
+	log     *slog.Logger
+	fetcher Fetcher
+	store   Store
+	timeout time.Duration
+
+	jobs chan job
+	done chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+func New(log *slog.Logger, fetcher Fetcher, store Store, opts Options) *Collector {
+	c := &Collector{
+		log:     log.With(slog.String("component", "pagemeta")),
+		fetcher: fetcher,
+		store:   store,
+		timeout: opts.Timeout,
+		jobs:    make(chan job, opts.BufferSize),
+		done:    make(chan struct{}),
+	}
+
+	for i := 0; i < opts.Workers; i++ {
+		c.wg.Add(1)
+		go c.run()
+	}
+
+	return c
+}
+
+// Publish queues the link of link_created for a fetch. It never blocks:
+// if the buffer is full, the link is skipped and keeps no metadata.
+func (c *Collector) Publish(e linkEvents.Event) {
+	if e.Type != linkEvents.TypeLinkCreated || e.URL == "" {
+		return
+	}
+
+	select {
+	case c.jobs <- job{alias: e.Alias, url: e.URL}:
+	default:
+		c.log.Warn("page metadata buffer is full, link skipped", slog.String("alias", e.Alias))
+	}
+}
+
+// Close stops the workers after they process the queued links.
+func (c *Collector) Close() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+
+	c.wg.Wait()
+}
+
+func (c *Collector) run() {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case j := <-c.jobs:
+			c.collect(j)
+		case <-c.done:
+			for {
+				select {
+				case j := <-c.jobs:
+					c.collect(j)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *Collector) collect(j job) {
+	log := c.log.With(slog.String("alias", j.alias))
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	md, err := c.fetcher.Fetch(ctx, j.url)
+	if errors.Is(err, pagetitle.ErrNoMetadata) {
+		log.Debug("destination page has no metadata")
+
+		return
+	}
+	if err != nil {
+		// Недоступные страницы - обычное дело, метаданные просто не появятся
+		log.Debug("failed to fetch page metadata", sl.Err(err))
+
+		return
+	}
+
+	page := storage.PageMetadata{Title: md.Title, Description: md.Description, Image: md.Image}
+	if page == (storage.PageMetadata{}) {
+		return
+	}
+
+	err = c.store.SetPageMetadata(ctx, j.alias, page)
+	if errors.Is(err, storage.ErrURLNotFound) {
+		// Ссылку успели удалить
+		return
+	}
+	if err != nil {
+		log.Error("failed to store page metadata", sl.Err(err))
+	}
+}
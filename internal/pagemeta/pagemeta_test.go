@@ -0,0 +1,72 @@
+package pagemeta_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	linkEvents "url-shortener/internal/events"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/pagetitle"
+	"url-shortener/internal/pagemeta"
+	"url-shortener/internal/storage"
+)
+
+type fakeFetcher struct {
+	pages map[string]pagetitle.Metadata
+}
+
+func (f fakeFetcher) Fetch(_ context.Context, rawURL string) (pagetitle.Metadata, error) {
+	md, ok := f.pages[rawURL]
+	if !ok {
+		return pagetitle.Metadata{}, errors.New("unreachable")
+	}
+
+	return md, nil
+}
+
+type fakeStore struct {
+	mu    sync.Mutex
+	pages map[string]storage.PageMetadata
+}
+
+func (s *fakeStore) SetPageMetadata(_ context.Context, alias string, page storage.PageMetadata) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pages[alias] = page
+
+	return nil
+}
+
+func TestCollector(t *testing.T) {
+	fetcher := fakeFetcher{pages: map[string]pagetitle.Metadata{
+		"https://example.com":   {Title: "Example", Description: "About", Image: "https://example.com/og.png", SiteName: "Ex"},
+		"https://empty.example": {},
+	}}
+	store := &fakeStore{pages: map[string]storage.PageMetadata{}}
+
+	c := pagemeta.New(slogdiscard.NewDiscardLogger(), fetcher, store, pagemeta.Options{
+		Workers:    2,
+		BufferSize: 10,
+		Timeout:    time.Second,
+	})
+
+	now := time.Now()
+	c.Publish(linkEvents.Event{Type: linkEvents.TypeLinkCreated, Alias: "ok", URL: "https://example.com", Time: now})
+	c.Publish(linkEvents.Event{Type: linkEvents.TypeLinkCreated, Alias: "down", URL: "https://down.example", Time: now})
+	c.Publish(linkEvents.Event{Type: linkEvents.TypeLinkCreated, Alias: "empty", URL: "https://empty.example", Time: now})
+	// Другие события страницу не запрашивают
+	c.Publish(linkEvents.Event{Type: linkEvents.TypeLinkDeleted, Alias: "deleted", URL: "https://example.com", Time: now})
+
+	// Close дожидается обработки очереди
+	c.Close()
+
+	require.Equal(t, map[string]storage.PageMetadata{
+		"ok": {Title: "Example", Description: "About", Image: "https://example.com/og.png"},
+	}, store.pages)
+}
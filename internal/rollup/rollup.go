@@ -0,0 +1,47 @@
+package rollup
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// ClickRoller is an interface for rolling up click events into hourly counts.
+type ClickRoller interface {
+	RollupClicks(ctx context.Context, until time.Time) (int64, error)
+}
+
+// Run rolls up clicks of every hour which ended more than delay ago, every interval
+// until ctx is done. The delay leaves time for buffered clicks to be saved: clicks
+// saved after their hour was rolled up are not counted in time series.
+func Run(ctx context.Context, log *slog.Logger, roller ClickRoller, interval, delay time.Duration) {
+	const op = "rollup.Run"
+
+	log = log.With(slog.String("op", op))
+
+	if interval <= 0 {
+		log.Info("click rollup is disabled")
+
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rows, err := roller.RollupClicks(ctx, time.Now().Add(-delay))
+			if err != nil {
+				log.Error("failed to roll up clicks", sl.Err(err))
+			} else if rows > 0 {
+				log.Debug("clicks rolled up", slog.Int64("rows", rows))
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package screening
+
+import (
+	"context"
+	"fmt"
+
+	"url-shortener/internal/storage"
+)
+
+// ReasonBanned is the reason of urls flagged by Banned.
+const ReasonBanned = "banned"
+
+// BannedHostLister is an interface for listing destination hosts banned by administrators.
+type BannedHostLister interface {
+	ListBannedHosts(ctx context.Context) ([]storage.BannedHost, error)
+}
+
+// Banned flags urls of hosts banned via the admin API, including their subdomains.
+// The list is read on every check, so bans apply at once; it is expected to be short.
+type Banned struct {
+	store BannedHostLister
+}
+
+func NewBanned(store BannedHostLister) *Banned {
+	return &Banned{store: store}
+}
+
+func (b *Banned) Screen(ctx context.Context, urls []string) (map[string]string, error) {
+	const op = "screening.Banned.Screen"
+
+	flagged := make(map[string]string)
+	if len(urls) == 0 {
+		return flagged, nil
+	}
+
+	hosts, err := b.store.ListBannedHosts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(hosts) == 0 {
+		return flagged, nil
+	}
+
+	entries := make([]string, len(hosts))
+	for i, h := range hosts {
+		entries[i] = h.Host
+	}
+
+	list := NewBlocklist(entries)
+	for _, rawURL := range urls {
+		if list.blocked(rawURL) {
+			flagged[rawURL] = ReasonBanned
+		}
+	}
+
+	return flagged, nil
+}
@@ -0,0 +1,109 @@
+package screening
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// recheckPageSize is the number of links screened at once.
+const recheckPageSize = 500
+
+// URLQuarantiner is an interface for listing links and changing their quarantine state.
+type URLQuarantiner interface {
+	ListURLs(ctx context.Context, filter storage.ListFilter) ([]storage.URL, string, error)
+	QuarantineURL(ctx context.Context, alias, reason string) error
+}
+
+// Run rechecks all links every interval until ctx is done: destinations flagged since
+// they were saved are quarantined, and links which are no longer flagged are released.
+func Run(ctx context.Context, log *slog.Logger, store URLQuarantiner, screener Screener, interval time.Duration) {
+	const op = "screening.Run"
+
+	log = log.With(slog.String("op", op))
+
+	if interval <= 0 {
+		log.Info("url recheck is disabled")
+
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			quarantined, released, err := Recheck(ctx, store, screener)
+			if err != nil {
+				log.Error("failed to recheck urls", sl.Err(err))
+			}
+			if quarantined > 0 || released > 0 {
+				log.Info("urls rechecked", slog.Int("quarantined", quarantined), slog.Int("released", released))
+			}
+		}
+	}
+}
+
+// Recheck screens all links once and returns the numbers of quarantined and released ones.
+// A failed screening stops the pass, so links are never released because a source is down.
+func Recheck(ctx context.Context, store URLQuarantiner, screener Screener) (quarantined, released int, err error) {
+	filter := storage.ListFilter{Limit: recheckPageSize}
+
+	for {
+		urls, next, err := store.ListURLs(ctx, filter)
+		if err != nil {
+			return quarantined, released, err
+		}
+
+		var targets []string
+		for _, u := range urls {
+			targets = append(targets, u.Destinations()...)
+		}
+
+		flagged, err := screener.Screen(ctx, targets)
+		if err != nil {
+			return quarantined, released, err
+		}
+
+		for _, u := range urls {
+			reason := flaggedReason(u, flagged)
+			if reason == u.QuarantineReason {
+				continue
+			}
+
+			if err := store.QuarantineURL(ctx, u.Alias, reason); err != nil {
+				return quarantined, released, err
+			}
+
+			if reason != "" {
+				quarantined++
+			} else {
+				released++
+			}
+		}
+
+		if next == "" {
+			return quarantined, released, nil
+		}
+
+		filter.Cursor = next
+	}
+}
+
+// flaggedReason returns the reason the first flagged destination of the link was flagged for.
+func flaggedReason(u storage.URL, flagged map[string]string) string {
+	for _, destination := range u.Destinations() {
+		if reason, ok := flagged[destination]; ok {
+			return reason
+		}
+	}
+
+	return ""
+}
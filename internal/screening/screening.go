@@ -0,0 +1,150 @@
+// Package screening checks link destinations against malicious URL sources:
+// a local blocklist and Google Safe Browsing.
+package screening
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Screener checks destinations against a source of malicious URLs.
+type Screener interface {
+	// Screen returns flagged urls with the reason, urls missing in the result are clean.
+	Screen(ctx context.Context, urls []string) (map[string]string, error)
+}
+
+// Chain combines screeners; the reason of the first screener flagging a url wins.
+// An empty chain flags nothing, so screening can be always on in handlers.
+type Chain []Screener
+
+func (c Chain) Screen(ctx context.Context, urls []string) (map[string]string, error) {
+	flagged := make(map[string]string)
+
+	var errs []error
+	for _, s := range c {
+		res, err := s.Screen(ctx, urls)
+		if err != nil {
+			// Остальные источники все равно проверяем
+			errs = append(errs, err)
+
+			continue
+		}
+
+		for u, reason := range res {
+			if _, ok := flagged[u]; !ok {
+				flagged[u] = reason
+			}
+		}
+	}
+
+	return flagged, errors.Join(errs...)
+}
+
+// ReasonBlocklist is the reason of urls flagged by Blocklist.
+const ReasonBlocklist = "blocklist"
+
+// Blocklist flags urls of listed hosts, including their subdomains, and listed urls.
+type Blocklist struct {
+	mu    sync.RWMutex
+	hosts map[string]struct{}
+	urls  map[string]struct{}
+}
+
+// NewBlocklist builds a blocklist of entries: host names or full urls with scheme.
+func NewBlocklist(entries []string) *Blocklist {
+	b := &Blocklist{hosts: make(map[string]struct{}), urls: make(map[string]struct{})}
+
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		switch {
+		case e == "":
+		case strings.Contains(e, "://"):
+			b.urls[e] = struct{}{}
+		default:
+			b.hosts[strings.ToLower(strings.TrimSuffix(e, "."))] = struct{}{}
+		}
+	}
+
+	return b
+}
+
+// LoadBlocklist reads a blocklist file with one entry per line; lines starting with # are comments.
+func LoadBlocklist(path string) (*Blocklist, error) {
+	const op = "screening.LoadBlocklist"
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer f.Close()
+
+	var entries []string
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			entries = append(entries, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return NewBlocklist(entries), nil
+}
+
+// Replace swaps entries of b for entries of other, e.g. a blocklist loaded again from the file.
+func (b *Blocklist) Replace(other *Blocklist) {
+	other.mu.RLock()
+	hosts, urls := other.hosts, other.urls
+	other.mu.RUnlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.hosts, b.urls = hosts, urls
+}
+
+func (b *Blocklist) Screen(_ context.Context, urls []string) (map[string]string, error) {
+	flagged := make(map[string]string)
+
+	for _, rawURL := range urls {
+		if b.blocked(rawURL) {
+			flagged[rawURL] = ReasonBlocklist
+		}
+	}
+
+	return flagged, nil
+}
+
+func (b *Blocklist) blocked(rawURL string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if _, ok := b.urls[rawURL]; ok {
+		return true
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	// evil.example.com блокируется записью example.com
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for host != "" {
+		if _, ok := b.hosts[host]; ok {
+			return true
+		}
+
+		_, host, _ = strings.Cut(host, ".")
+	}
+
+	return false
+}
@@ -0,0 +1,162 @@
+package screening_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/screening"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/sqlite"
+)
+
+func TestBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# phishing\nevil.example\n\nhttps://good.example/login.php\n"), 0o600))
+
+	b, err := screening.LoadBlocklist(path)
+	require.NoError(t, err)
+
+	flagged, err := b.Screen(context.Background(), []string{
+		"https://evil.example/",
+		"http://login.EVIL.example/path",
+		"https://notevil.example/",
+		"https://good.example/login.php",
+		"https://good.example/",
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"https://evil.example/":          screening.ReasonBlocklist,
+		"http://login.EVIL.example/path": screening.ReasonBlocklist,
+		"https://good.example/login.php": screening.ReasonBlocklist,
+	}, flagged)
+
+	b.Replace(screening.NewBlocklist([]string{"good.example"}))
+
+	flagged, err = b.Screen(context.Background(), []string{"https://evil.example/", "https://good.example/"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"https://good.example/": screening.ReasonBlocklist}, flagged)
+}
+
+type failingScreener struct{}
+
+func (failingScreener) Screen(context.Context, []string) (map[string]string, error) {
+	return nil, errors.New("unavailable")
+}
+
+func TestChain(t *testing.T) {
+	chain := screening.Chain{
+		failingScreener{},
+		screening.NewBlocklist([]string{"evil.example"}),
+	}
+
+	flagged, err := chain.Screen(context.Background(), []string{"https://evil.example/", "https://ok.example/"})
+	require.Error(t, err)
+	require.Equal(t, map[string]string{"https://evil.example/": screening.ReasonBlocklist}, flagged)
+
+	flagged, err = screening.Chain{}.Screen(context.Background(), []string{"https://evil.example/"})
+	require.NoError(t, err)
+	require.Empty(t, flagged)
+}
+
+func TestSafeBrowsing(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "secret", r.URL.Query().Get("key"))
+
+		var req struct {
+			ThreatInfo struct {
+				ThreatEntries []struct {
+					URL string `json:"url"`
+				} `json:"threatEntries"`
+			} `json:"threatInfo"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Len(t, req.ThreatInfo.ThreatEntries, 2)
+
+		_, _ = w.Write([]byte(`{"matches": [{"threatType": "SOCIAL_ENGINEERING", "threat": {"url": "https://phish.example/"}}]}`))
+	}))
+	defer srv.Close()
+
+	sb := screening.NewSafeBrowsing("secret", srv.URL, time.Second)
+
+	flagged, err := sb.Screen(context.Background(), []string{"https://phish.example/", "https://ok.example/"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"https://phish.example/": "social_engineering"}, flagged)
+}
+
+func TestSafeBrowsing_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	_, err := screening.NewSafeBrowsing("bad", srv.URL, time.Second).Screen(context.Background(), []string{"https://ok.example/"})
+	require.Error(t, err)
+}
+
+func TestRecheck(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	for _, u := range []storage.URL{
+		{Alias: "evil", URL: "https://evil.example/"},
+		{Alias: "ok", URL: "https://ok.example/"},
+		{Alias: "fixed", URL: "https://fixed.example/"},
+	} {
+		_, err := s.SaveURL(context.Background(), u)
+		require.NoError(t, err)
+	}
+	require.NoError(t, s.QuarantineURL(context.Background(), "fixed", screening.ReasonBlocklist))
+
+	quarantined, released, err := screening.Recheck(context.Background(), s, screening.NewBlocklist([]string{"evil.example"}))
+	require.NoError(t, err)
+	require.Equal(t, 1, quarantined)
+	require.Equal(t, 1, released)
+
+	for alias, reason := range map[string]string{"evil": screening.ReasonBlocklist, "ok": "", "fixed": ""} {
+		u, err := s.GetURL(context.Background(), alias)
+		require.NoError(t, err)
+		require.Equal(t, reason, u.QuarantineReason, alias)
+	}
+
+	// Недоступный источник не снимает карантин
+	_, released, err = screening.Recheck(context.Background(), s, screening.Chain{failingScreener{}})
+	require.Error(t, err)
+	require.Zero(t, released)
+}
+
+func TestBanned(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	b := screening.NewBanned(s)
+
+	flagged, err := b.Screen(context.Background(), []string{"https://evil.example/"})
+	require.NoError(t, err)
+	require.Empty(t, flagged)
+
+	require.NoError(t, s.BanHost(context.Background(), "evil.example"))
+
+	flagged, err = b.Screen(context.Background(), []string{"https://evil.example/", "https://cdn.evil.example/x", "https://good.example/"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"https://evil.example/":      screening.ReasonBanned,
+		"https://cdn.evil.example/x": screening.ReasonBanned,
+	}, flagged)
+
+	require.NoError(t, s.UnbanHost(context.Background(), "evil.example"))
+
+	flagged, err = b.Screen(context.Background(), []string{"https://evil.example/"})
+	require.NoError(t, err)
+	require.Empty(t, flagged)
+}
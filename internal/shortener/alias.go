@@ -0,0 +1,73 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"url-shortener/internal/lib/aliascheck"
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/lib/snowflake"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+// AliasOptions configure generation of aliases.
+type AliasOptions struct {
+	// Length is the initial length of generated random aliases.
+	Length int
+	// Attempts limits the number of tries to find a free alias.
+	Attempts int
+	// Sequence, if set, generates sequential aliases instead of random ones. They are
+	// unique across instances with different node IDs, so collisions are possible
+	// only with custom aliases.
+	Sequence *snowflake.Generator
+}
+
+// lengthStep is the number of collisions after which generated aliases get one character longer.
+const lengthStep = 2
+
+var errNoFreeAlias = errors.New("no free alias found")
+
+// URLSaver is an interface for saving a single link.
+type URLSaver interface {
+	SaveURL(ctx context.Context, u storage.URL) (int64, error)
+}
+
+// SaveWithGeneratedAlias saves u under generated aliases in the tenant namespace until
+// a free one is found and returns the alias.
+func SaveWithGeneratedAlias(ctx context.Context, urlSaver URLSaver, u storage.URL, opts AliasOptions, tenantName string) (string, int64, error) {
+	for attempt := 0; attempt < opts.Attempts; attempt++ {
+		alias := generateAlias(opts, attempt)
+		u.Alias = tenant.Key(tenantName, alias)
+
+		id, err := urlSaver.SaveURL(ctx, u)
+		if !errors.Is(err, storage.ErrURLExists) {
+			return alias, id, err
+		}
+	}
+
+	return "", 0, fmt.Errorf("%w after %d attempts", errNoFreeAlias, opts.Attempts)
+}
+
+// aliasLength grows the alias after every lengthStep collisions, so a crowded
+// alias space doesn't make every save retry many times.
+func aliasLength(opts AliasOptions, attempt int) int {
+	return opts.Length + attempt/lengthStep
+}
+
+// generateAlias returns an alias for the attempt which is not reserved.
+func generateAlias(opts AliasOptions, attempt int) string {
+	for {
+		var alias string
+		if opts.Sequence != nil {
+			alias = opts.Sequence.NextString()
+		} else {
+			alias = random.NewRandomString(aliasLength(opts, attempt))
+		}
+
+		if !aliascheck.IsReserved(alias) {
+			return alias
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/acl"
+	"url-shortener/internal/lib/aliascheck"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/loopcheck"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/storage"
+)
+
+// Link is a link to create on behalf of an account.
+type Link struct {
+	// URL holds the destinations and settings of the link. The account owns the link
+	// and Create sets its alias, so Alias, UserID and APIKeyID are ignored.
+	URL storage.URL
+	// Alias is a custom alias without the tenant namespace, empty to generate one.
+	Alias string
+	// Dedupe returns the alias of an active link of the account to the same url
+	// instead of creating a new one. It is ignored with a custom alias.
+	Dedupe bool
+}
+
+// Result is the outcome of a single link of CreateBatch.
+type Result struct {
+	Alias string
+	Err   error
+}
+
+// Create checks the destinations of the link, saves it in the tenant of the account and
+// returns its alias, without the tenant namespace. host is the host the request came
+// to, links to it are loops too; it may be empty. A taken custom alias is reported as
+// storage.ErrURLExists.
+func (s *Service) Create(ctx context.Context, account Account, link Link, host string) (string, error) {
+	const op = "shortener.Create"
+
+	if !acl.Allows(account.Role, acl.RoleEditor) {
+		return "", ErrForbidden
+	}
+
+	u, err := s.prepare(account, link)
+	if err != nil {
+		return "", err
+	}
+
+	flagged, err := s.screener.Screen(ctx, u.Destinations())
+	if err != nil {
+		// Источник недоступен: ссылку проверит периодическая перепроверка
+		s.log.Error("failed to screen url", slog.String("op", op), sl.Err(err))
+	}
+	if reason, ok := flaggedReason(u, flagged); ok {
+		return "", rejected(flaggedMessage(reason))
+	}
+
+	err = checkLoops(ctx, s.loopChecker.Check, u, host)
+	if errors.Is(err, ErrRejected) {
+		return "", err
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if link.Dedupe && link.Alias == "" {
+		alias, ok, err := s.find(ctx, account, u)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		if ok {
+			return alias, nil
+		}
+	}
+
+	alias := link.Alias
+	if alias != "" {
+		u.Alias = tenant.Key(account.Tenant, alias)
+		_, err = s.store.SaveURL(ctx, u)
+	} else {
+		alias, _, err = SaveWithGeneratedAlias(ctx, s.store, u, s.aliasOpts, account.Tenant)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return alias, nil
+}
+
+// CreateBatch creates the links like Create, the results are in the order of links.
+// Rejected links and taken aliases are reported per link and don't prevent the rest
+// from being saved. Redirects of destinations are not followed looking for loops, it
+// would take too long for many links; only links to hosts of the service are rejected.
+func (s *Service) CreateBatch(ctx context.Context, account Account, links []Link, host string) ([]Result, error) {
+	const op = "shortener.CreateBatch"
+
+	if !acl.Allows(account.Role, acl.RoleEditor) {
+		return nil, ErrForbidden
+	}
+
+	results := make([]Result, len(links))
+	urls := make([]storage.URL, 0, len(links))
+	// positions[i] - индекс в results ссылки urls[i]
+	positions := make([]int, 0, len(links))
+
+	for i, link := range links {
+		u, err := s.prepare(account, link)
+		if err != nil {
+			results[i].Err = err
+
+			continue
+		}
+
+		err = checkLoops(ctx, s.loopChecker.CheckHost, u, host)
+		if errors.Is(err, ErrRejected) {
+			results[i].Err = err
+
+			continue
+		}
+		if err != nil {
+			results[i].Err = fmt.Errorf("%s: %w", op, err)
+
+			continue
+		}
+
+		if link.Dedupe && link.Alias == "" {
+			alias, ok, err := s.find(ctx, account, u)
+			if err != nil {
+				results[i].Err = fmt.Errorf("%s: %w", op, err)
+
+				continue
+			}
+			if ok {
+				results[i].Alias = alias
+
+				continue
+			}
+		}
+
+		alias := link.Alias
+		if alias == "" {
+			alias = generateAlias(s.aliasOpts, 0)
+		}
+
+		u.Alias = tenant.Key(account.Tenant, alias)
+		results[i].Alias = alias
+
+		urls = append(urls, u)
+		positions = append(positions, i)
+	}
+
+	// Все ссылки проверяем одним запросом к источникам
+	if len(urls) > 0 {
+		var destinations []string
+		for _, u := range urls {
+			destinations = append(destinations, u.Destinations()...)
+		}
+
+		flagged, err := s.screener.Screen(ctx, destinations)
+		if err != nil {
+			s.log.Error("failed to screen urls", slog.String("op", op), sl.Err(err))
+		}
+
+		kept, keptPositions := urls[:0], positions[:0]
+		for i, u := range urls {
+			if reason, ok := flaggedReason(u, flagged); ok {
+				results[positions[i]] = Result{Err: rejected(flaggedMessage(reason))}
+
+				continue
+			}
+
+			kept = append(kept, u)
+			keptPositions = append(keptPositions, positions[i])
+		}
+		urls, positions = kept, keptPositions
+	}
+
+	// Занятые сгенерированные псевдонимы генерируем заново и сохраняем повторно
+	for attempt := 1; len(urls) > 0; attempt++ {
+		ids, err := s.store.SaveURLs(ctx, urls)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		var retryURLs []storage.URL
+		var retryPositions []int
+
+		for i, id := range ids {
+			pos := positions[i]
+
+			switch {
+			case id != 0:
+			case links[pos].Alias != "":
+				results[pos] = Result{Err: storage.ErrURLExists}
+			case attempt < s.aliasOpts.Attempts:
+				alias := generateAlias(s.aliasOpts, attempt)
+				urls[i].Alias = tenant.Key(account.Tenant, alias)
+				results[pos].Alias = alias
+
+				retryURLs = append(retryURLs, urls[i])
+				retryPositions = append(retryPositions, pos)
+			default:
+				results[pos] = Result{Err: fmt.Errorf("%s: %w after %d attempts", op, errNoFreeAlias, attempt)}
+			}
+		}
+
+		urls, positions = retryURLs, retryPositions
+	}
+
+	return results, nil
+}
+
+// prepare returns the link to save: destinations normalized by the checker, a valid
+// custom alias and the account as the owner.
+func (s *Service) prepare(account Account, link Link) (storage.URL, error) {
+	u := link.URL
+
+	normalized, err := s.checker.Normalize(u.URL)
+	if err != nil {
+		return storage.URL{}, rejected(err.Error())
+	}
+	u.URL = normalized
+
+	// Адрес вебхука проверяется по тем же правилам, чтобы он не вел в приватную сеть
+	if u.WebhookURL != "" {
+		u.WebhookURL, err = s.checker.Normalize(u.WebhookURL)
+		if err != nil {
+			return storage.URL{}, rejected("invalid webhook_url: " + err.Error())
+		}
+	}
+
+	if len(u.GeoTargets) > 0 {
+		geoTargets := make(storage.GeoTargets, len(u.GeoTargets))
+		for code, target := range u.GeoTargets {
+			if geoTargets[code], err = s.checker.Normalize(target); err != nil {
+				return storage.URL{}, rejected(fmt.Sprintf("invalid geo_targets: %s: %s", code, err))
+			}
+		}
+		u.GeoTargets = geoTargets
+	}
+
+	if len(u.DeviceTargets) > 0 {
+		deviceTargets := make(storage.DeviceTargets, len(u.DeviceTargets))
+		for platform, target := range u.DeviceTargets {
+			if deviceTargets[platform], err = s.checker.Normalize(target); err != nil {
+				return storage.URL{}, rejected(fmt.Sprintf("invalid device_targets: %s: %s", platform, err))
+			}
+		}
+		u.DeviceTargets = deviceTargets
+	}
+
+	if len(u.Split.Variants) > 0 {
+		variants := make([]storage.Variant, len(u.Split.Variants))
+		for i, v := range u.Split.Variants {
+			if v.URL, err = s.checker.Normalize(v.URL); err != nil {
+				return storage.URL{}, rejected(fmt.Sprintf("invalid split: %s: %s", v.Name, err))
+			}
+			variants[i] = v
+		}
+		u.Split.Variants = variants
+	}
+
+	if link.Alias != "" {
+		if err := aliascheck.Validate(link.Alias); err != nil {
+			return storage.URL{}, rejected(err.Error())
+		}
+	}
+
+	u.Alias = ""
+	u.UserID = account.UserID
+	u.APIKeyID = account.APIKeyID
+
+	return u, nil
+}
+
+// find returns the alias of an active link of the account to the same url.
+func (s *Service) find(ctx context.Context, account Account, u storage.URL) (string, bool, error) {
+	existing, err := s.store.FindURL(ctx, u.URL, account.UserID, account.APIKeyID)
+	if errors.Is(err, storage.ErrURLNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	// Ссылка другого тенанта не подходит: по его alias клиент перейти не сможет
+	if !tenant.Owns(account.Tenant, existing.Alias) {
+		return "", false, nil
+	}
+
+	return tenant.Alias(account.Tenant, existing.Alias), true, nil
+}
+
+// rejected returns ErrRejected with the message for the user.
+func rejected(message string) error {
+	return fmt.Errorf("%w: %s", ErrRejected, message)
+}
+
+// checkLoops checks every destination of u with check, see loopcheck.Checker; self is
+// the host the request came to. Loops are reported as ErrRejected.
+func checkLoops(ctx context.Context, check func(ctx context.Context, rawURL, self string) error, u storage.URL, self string) error {
+	for _, destination := range u.Destinations() {
+		err := check(ctx, destination, self)
+		if errors.Is(err, loopcheck.ErrLoop) {
+			return rejected(err.Error())
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// flaggedReason returns the reason the first destination of u flagged by screening was flagged.
+func flaggedReason(u storage.URL, flagged map[string]string) (string, bool) {
+	for _, destination := range u.Destinations() {
+		if reason, ok := flagged[destination]; ok {
+			return reason, true
+		}
+	}
+
+	return "", false
+}
+
+func flaggedMessage(reason string) string {
+	return "url is flagged as malicious: " + reason
+}
@@ -0,0 +1,156 @@
+// Package shortener creates, inspects and deletes links on behalf of a user or an API
+// key of the service. It checks destinations of new links and applies the permissions
+// of the acl package for POST /url and clients other than the HTTP API, e.g. chat bots,
+// so they can't bypass the checks or drift apart.
+package shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/acl"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/jwt"
+	"url-shortener/internal/lib/loopcheck"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/lib/urlcheck"
+	"url-shortener/internal/screening"
+	"url-shortener/internal/storage"
+)
+
+var (
+	// ErrRejected wraps the reason the url can't be shortened, e.g. it is malformed or
+	// flagged as malicious. The message is meant for the user.
+	ErrRejected = errors.New("url rejected")
+	// ErrForbidden is returned when the role of the account doesn't allow the action or
+	// the link belongs to someone else.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Account is the client the service acts for: a user or an API key.
+type Account struct {
+	UserID   int64
+	APIKeyID int64
+	// Tenant is the namespace of the links, empty for the default tenant.
+	Tenant string
+	// Role is one of the roles of the acl package, empty for editors.
+	Role string
+}
+
+// UserAccount returns the account of the user.
+func UserAccount(u storage.User) Account {
+	return Account{UserID: u.ID, Tenant: u.Tenant, Role: u.Role}
+}
+
+// APIKeyAccount returns the account of the API key.
+func APIKeyAccount(k storage.APIKey) Account {
+	return Account{APIKeyID: k.ID, Tenant: k.Tenant, Role: k.Role}
+}
+
+// FromContext returns the account of the client authenticated by the middleware,
+// like acl.FromContext.
+func FromContext(ctx context.Context) Account {
+	return Account{
+		UserID:   jwt.UserID(ctx),
+		APIKeyID: apikey.KeyID(ctx),
+		Tenant:   tenant.FromContext(ctx),
+		Role:     acl.Role(ctx),
+	}
+}
+
+// principal returns the account as a client of the acl package.
+func (a Account) principal() acl.Principal {
+	return acl.Principal{UserID: a.UserID, APIKeyID: a.APIKeyID, Admin: acl.Allows(a.Role, acl.RoleAdmin)}
+}
+
+// Store is an interface for the links and their clicks.
+type Store interface {
+	SaveURL(ctx context.Context, u storage.URL) (int64, error)
+	SaveURLs(ctx context.Context, urls []storage.URL) ([]int64, error)
+	FindURL(ctx context.Context, target string, userID, apiKeyID int64) (storage.URL, error)
+	GetURLInfo(ctx context.Context, alias string) (storage.URL, error)
+	DeleteURL(ctx context.Context, alias string) error
+	GetClickStats(ctx context.Context, alias string) (storage.ClickStats, error)
+}
+
+type Service struct {
+	log         *slog.Logger
+	store       Store
+	aliasOpts   AliasOptions
+	checker     *urlcheck.Checker
+	screener    screening.Screener
+	loopChecker *loopcheck.Checker
+}
+
+func New(
+	log *slog.Logger,
+	store Store,
+	aliasOpts AliasOptions,
+	checker *urlcheck.Checker,
+	screener screening.Screener,
+	loopChecker *loopcheck.Checker,
+) *Service {
+	return &Service{
+		log:         log,
+		store:       store,
+		aliasOpts:   aliasOpts,
+		checker:     checker,
+		screener:    screener,
+		loopChecker: loopChecker,
+	}
+}
+
+// Shorten saves rawURL under a generated alias owned by the account and returns the
+// alias, without the tenant namespace.
+func (s *Service) Shorten(ctx context.Context, account Account, rawURL string) (string, error) {
+	return s.Create(ctx, account, Link{URL: storage.URL{URL: rawURL}}, "")
+}
+
+// Stats returns the link of the account's tenant and its clicks. Like
+// GET /url/{alias}/stats, it is allowed for links of others.
+func (s *Service) Stats(ctx context.Context, account Account, alias string) (storage.URL, storage.ClickStats, error) {
+	const op = "shortener.Stats"
+
+	key := tenant.Key(account.Tenant, alias)
+
+	u, err := s.store.GetURLInfo(ctx, key)
+	if err != nil {
+		return storage.URL{}, storage.ClickStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	stats, err := s.store.GetClickStats(ctx, key)
+	if err != nil {
+		return storage.URL{}, storage.ClickStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return u, stats, nil
+}
+
+// Delete deletes the link of the account's tenant if the account may manage it.
+func (s *Service) Delete(ctx context.Context, account Account, alias string) error {
+	const op = "shortener.Delete"
+
+	if !acl.Allows(account.Role, acl.RoleEditor) {
+		return ErrForbidden
+	}
+
+	key := tenant.Key(account.Tenant, alias)
+
+	u, err := s.store.GetURLInfo(ctx, key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !account.principal().CanManage(u) {
+		return ErrForbidden
+	}
+
+	if err := s.store.DeleteURL(ctx, key); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
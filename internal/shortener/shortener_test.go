@@ -0,0 +1,500 @@
+package shortener_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/loopcheck"
+	"url-shortener/internal/lib/snowflake"
+	"url-shortener/internal/lib/urlcheck"
+	"url-shortener/internal/shortener"
+	"url-shortener/internal/storage"
+)
+
+type fakeStore struct {
+	urls    map[string]storage.URL
+	deleted []string
+	// collisions is the number of saves reported as taken aliases before the real check.
+	collisions int
+	// attempts are aliases the links were saved under, including taken ones.
+	attempts []string
+}
+
+func (s *fakeStore) save(u storage.URL) int64 {
+	s.attempts = append(s.attempts, u.Alias)
+
+	if s.collisions > 0 {
+		s.collisions--
+
+		return 0
+	}
+	if _, ok := s.urls[u.Alias]; ok {
+		return 0
+	}
+
+	s.urls[u.Alias] = u
+
+	return int64(len(s.urls))
+}
+
+func (s *fakeStore) SaveURL(_ context.Context, u storage.URL) (int64, error) {
+	id := s.save(u)
+	if id == 0 {
+		return 0, storage.ErrURLExists
+	}
+
+	return id, nil
+}
+
+func (s *fakeStore) SaveURLs(_ context.Context, urls []storage.URL) ([]int64, error) {
+	ids := make([]int64, len(urls))
+	for i, u := range urls {
+		ids[i] = s.save(u)
+	}
+
+	return ids, nil
+}
+
+func (s *fakeStore) FindURL(_ context.Context, target string, userID, apiKeyID int64) (storage.URL, error) {
+	for _, u := range s.urls {
+		if u.URL == target && u.UserID == userID && u.APIKeyID == apiKeyID {
+			return u, nil
+		}
+	}
+
+	return storage.URL{}, storage.ErrURLNotFound
+}
+
+func (s *fakeStore) GetURLInfo(_ context.Context, alias string) (storage.URL, error) {
+	u, ok := s.urls[alias]
+	if !ok {
+		return storage.URL{}, storage.ErrURLNotFound
+	}
+
+	return u, nil
+}
+
+func (s *fakeStore) DeleteURL(_ context.Context, alias string) error {
+	s.deleted = append(s.deleted, alias)
+
+	return nil
+}
+
+func (s *fakeStore) GetClickStats(context.Context, string) (storage.ClickStats, error) {
+	return storage.ClickStats{Total: 3}, nil
+}
+
+type fakeScreener map[string]string
+
+func (s fakeScreener) Screen(context.Context, []string) (map[string]string, error) {
+	return s, nil
+}
+
+type fakeRegistry struct{}
+
+func (fakeRegistry) Exists(context.Context, string) (bool, error) {
+	return false, nil
+}
+
+var aliasOpts = shortener.AliasOptions{Length: 6, Attempts: 3}
+
+func newService(store *fakeStore) *shortener.Service {
+	return newServiceWithOptions(store, aliasOpts)
+}
+
+func newServiceWithOptions(store *fakeStore, opts shortener.AliasOptions) *shortener.Service {
+	return shortener.New(
+		slogdiscard.NewDiscardLogger(),
+		store,
+		opts,
+		urlcheck.New(urlcheck.Options{BlockPrivate: true, StripFragment: true}),
+		fakeScreener{"https://evil.example": "phishing"},
+		loopcheck.New(fakeRegistry{}, loopcheck.Options{Hosts: []string{"sho.rt"}}),
+	)
+}
+
+func TestShorten(t *testing.T) {
+	cases := []struct {
+		name    string
+		account shortener.Account
+		url     string
+		wantErr error
+		message string
+	}{
+		{name: "Success", account: shortener.Account{UserID: 7, Tenant: "brand"}, url: "https://example.com"},
+		{name: "Viewer", account: shortener.Account{UserID: 7, Role: "viewer"}, url: "https://example.com", wantErr: shortener.ErrForbidden},
+		{name: "Private", account: shortener.Account{UserID: 7}, url: "http://127.0.0.1/admin", wantErr: shortener.ErrRejected},
+		{name: "Flagged", account: shortener.Account{UserID: 7}, url: "https://evil.example", wantErr: shortener.ErrRejected, message: "phishing"},
+		{name: "Loop", account: shortener.Account{UserID: 7}, url: "https://sho.rt/abc", wantErr: shortener.ErrRejected},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := &fakeStore{urls: map[string]storage.URL{}}
+
+			alias, err := newService(store).Shorten(context.Background(), tc.account, tc.url)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				require.Contains(t, err.Error(), tc.message)
+				require.Empty(t, store.urls)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, alias, 6)
+
+			// Ссылка сохраняется в пространстве тенанта пользователя и принадлежит ему
+			u, ok := store.urls["brand/"+alias]
+			require.True(t, ok)
+			require.Equal(t, tc.url, u.URL)
+			require.Equal(t, tc.account.UserID, u.UserID)
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	cases := []struct {
+		name    string
+		account shortener.Account
+		alias   string
+		wantErr error
+	}{
+		{name: "Own link", account: shortener.Account{UserID: 7}, alias: "own"},
+		{name: "Link of other user", account: shortener.Account{UserID: 7}, alias: "other", wantErr: shortener.ErrForbidden},
+		{name: "Admin", account: shortener.Account{UserID: 7, Role: "admin"}, alias: "other"},
+		{name: "Viewer", account: shortener.Account{UserID: 7, Role: "viewer"}, alias: "own", wantErr: shortener.ErrForbidden},
+		{name: "Link of api key", account: shortener.Account{APIKeyID: 3}, alias: "key"},
+		{name: "Not found", account: shortener.Account{UserID: 7}, alias: "missing", wantErr: storage.ErrURLNotFound},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := &fakeStore{urls: map[string]storage.URL{
+				"own":   {Alias: "own", UserID: 7},
+				"other": {Alias: "other", UserID: 8},
+				"key":   {Alias: "key", APIKeyID: 3},
+			}}
+
+			err := newService(store).Delete(context.Background(), tc.account, tc.alias)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				require.Empty(t, store.deleted)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, []string{tc.alias}, store.deleted)
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	store := &fakeStore{urls: map[string]storage.URL{"brand/promo": {Alias: "brand/promo", URL: "https://example.com"}}}
+
+	u, stats, err := newService(store).Stats(context.Background(), shortener.Account{UserID: 8, Tenant: "brand", Role: "viewer"}, "promo")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com", u.URL)
+	require.Equal(t, int64(3), stats.Total)
+}
+
+func TestCreate(t *testing.T) {
+	cases := []struct {
+		name    string
+		account shortener.Account
+		link    shortener.Link
+		host    string
+		// want is the saved link, compared without its alias
+		want    storage.URL
+		wantErr error
+		message string
+	}{
+		{
+			name:    "Custom alias",
+			account: shortener.Account{APIKeyID: 3, Tenant: "brand"},
+			link:    shortener.Link{URL: storage.URL{URL: "https://example.com", UserID: 9}, Alias: "promo"},
+			want:    storage.URL{URL: "https://example.com", APIKeyID: 3},
+		},
+		{
+			name:    "Normalized destinations",
+			account: shortener.Account{UserID: 7},
+			link: shortener.Link{URL: storage.URL{
+				URL:           "HTTPS://Example.COM:443/Path#frag",
+				WebhookURL:    "https://Hooks.Example/click",
+				GeoTargets:    storage.GeoTargets{"US": "https://EXAMPLE.com/us"},
+				DeviceTargets: storage.DeviceTargets{"ios": "https://Apps.Apple.com/app"},
+				Split: storage.Split{Variants: []storage.Variant{
+					{Name: "A", URL: "https://Example.com/a", Weight: 1},
+					{Name: "B", URL: "https://Example.com/b", Weight: 3},
+				}},
+				BurnAfterReading: true,
+			}},
+			want: storage.URL{
+				URL:           "https://example.com/Path",
+				UserID:        7,
+				WebhookURL:    "https://hooks.example/click",
+				GeoTargets:    storage.GeoTargets{"US": "https://example.com/us"},
+				DeviceTargets: storage.DeviceTargets{"ios": "https://apps.apple.com/app"},
+				Split: storage.Split{Variants: []storage.Variant{
+					{Name: "A", URL: "https://example.com/a", Weight: 1},
+					{Name: "B", URL: "https://example.com/b", Weight: 3},
+				}},
+				BurnAfterReading: true,
+			},
+		},
+		{
+			name:    "Scheme not allowed",
+			link:    shortener.Link{URL: storage.URL{URL: "ftp://example.com/file"}},
+			wantErr: shortener.ErrRejected,
+			message: "url scheme is not allowed: ftp",
+		},
+		{
+			name:    "Private webhook",
+			link:    shortener.Link{URL: storage.URL{URL: "https://example.com", WebhookURL: "http://10.0.0.1/hook"}},
+			wantErr: shortener.ErrRejected,
+			message: "invalid webhook_url: url points to a private address",
+		},
+		{
+			name:    "Private geo target",
+			link:    shortener.Link{URL: storage.URL{URL: "https://example.com", GeoTargets: storage.GeoTargets{"DE": "http://127.0.0.1"}}},
+			wantErr: shortener.ErrRejected,
+			message: "invalid geo_targets: DE: url points to a private address",
+		},
+		{
+			name: "Flagged split variant",
+			link: shortener.Link{URL: storage.URL{URL: "https://example.com", Split: storage.Split{Variants: []storage.Variant{
+				{Name: "A", URL: "https://example.com/a", Weight: 1},
+				{Name: "B", URL: "https://evil.example", Weight: 1},
+			}}}},
+			wantErr: shortener.ErrRejected,
+			message: "url is flagged as malicious: phishing",
+		},
+		{
+			name:    "Flagged device target",
+			link:    shortener.Link{URL: storage.URL{URL: "https://example.com", DeviceTargets: storage.DeviceTargets{"android": "https://evil.example"}}},
+			wantErr: shortener.ErrRejected,
+			message: "url is flagged as malicious: phishing",
+		},
+		{
+			name:    "Link to the request host",
+			link:    shortener.Link{URL: storage.URL{URL: "https://go.brand.example/abc"}},
+			host:    "go.brand.example",
+			wantErr: shortener.ErrRejected,
+			message: "url leads to a redirect loop",
+		},
+		{
+			name:    "Invalid alias",
+			link:    shortener.Link{URL: storage.URL{URL: "https://example.com"}, Alias: "bad alias!"},
+			wantErr: shortener.ErrRejected,
+			message: "invalid alias",
+		},
+		{
+			name:    "Reserved alias",
+			link:    shortener.Link{URL: storage.URL{URL: "https://example.com"}, Alias: "health"},
+			wantErr: shortener.ErrRejected,
+			message: "alias is reserved",
+		},
+		{
+			name:    "Taken alias",
+			link:    shortener.Link{URL: storage.URL{URL: "https://example.com"}, Alias: "taken"},
+			wantErr: storage.ErrURLExists,
+		},
+		{
+			name:    "Viewer",
+			account: shortener.Account{Role: "viewer"},
+			link:    shortener.Link{URL: storage.URL{URL: "https://example.com"}},
+			wantErr: shortener.ErrForbidden,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := &fakeStore{urls: map[string]storage.URL{"taken": {Alias: "taken", URL: "https://other.com"}}}
+
+			alias, err := newService(store).Create(context.Background(), tc.account, tc.link, tc.host)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				require.Contains(t, err.Error(), tc.message)
+				require.Len(t, store.urls, 1)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			key := alias
+			if tc.account.Tenant != "" {
+				key = tc.account.Tenant + "/" + alias
+			}
+
+			u, ok := store.urls[key]
+			require.True(t, ok)
+			u.Alias = ""
+			require.Equal(t, tc.want, u)
+
+			if tc.link.Alias != "" {
+				require.Equal(t, tc.link.Alias, alias)
+			}
+		})
+	}
+}
+
+func TestCreate_GeneratedAliasCollision(t *testing.T) {
+	cases := []struct {
+		name       string
+		collisions int
+		wantErr    bool
+	}{
+		{name: "Retried", collisions: 2},
+		{name: "Attempts exhausted", collisions: 3, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := &fakeStore{urls: map[string]storage.URL{}, collisions: tc.collisions}
+
+			_, err := newService(store).Create(context.Background(), shortener.Account{}, shortener.Link{URL: storage.URL{URL: "https://example.com"}}, "")
+			if tc.wantErr {
+				require.Error(t, err)
+				require.NotErrorIs(t, err, shortener.ErrRejected)
+			} else {
+				require.NoError(t, err)
+			}
+
+			// Псевдоним удлиняется после каждых двух коллизий
+			lengths := make([]int, 0, len(store.attempts))
+			for _, alias := range store.attempts {
+				lengths = append(lengths, len(alias))
+			}
+			require.Equal(t, []int{6, 6, 7}, lengths)
+		})
+	}
+}
+
+func TestCreate_SequentialAlias(t *testing.T) {
+	g, err := snowflake.New(1)
+	require.NoError(t, err)
+
+	opts := aliasOpts
+	opts.Sequence = g
+	service := newServiceWithOptions(&fakeStore{urls: map[string]storage.URL{}}, opts)
+
+	var aliases []string
+	for i := 0; i < 2; i++ {
+		alias, err := service.Create(context.Background(), shortener.Account{}, shortener.Link{URL: storage.URL{URL: "https://example.com"}}, "")
+		require.NoError(t, err)
+
+		aliases = append(aliases, alias)
+	}
+
+	// Алиасы последовательные, а не случайной длины aliasOpts.Length
+	require.NotEqual(t, aliases[0], aliases[1])
+	require.Len(t, aliases[1], len(snowflake.Encode(g.Next())))
+}
+
+func TestCreate_Dedupe(t *testing.T) {
+	cases := []struct {
+		name    string
+		account shortener.Account
+		alias   string
+		want    string
+	}{
+		{name: "Existing link", account: shortener.Account{UserID: 7, Tenant: "brand"}, want: "promo"},
+		{name: "Link of other user", account: shortener.Account{UserID: 8, Tenant: "brand"}},
+		{name: "Link of other tenant", account: shortener.Account{UserID: 7}},
+		{name: "Custom alias", account: shortener.Account{UserID: 7, Tenant: "brand"}, alias: "custom", want: "custom"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := &fakeStore{urls: map[string]storage.URL{
+				"brand/promo": {Alias: "brand/promo", URL: "https://example.com", UserID: 7},
+			}}
+
+			link := shortener.Link{URL: storage.URL{URL: "https://example.com"}, Alias: tc.alias, Dedupe: true}
+
+			alias, err := newService(store).Create(context.Background(), tc.account, link, "")
+			require.NoError(t, err)
+
+			if tc.want != "" {
+				require.Equal(t, tc.want, alias)
+			} else {
+				require.NotEqual(t, "promo", alias)
+			}
+			require.Equal(t, tc.want == "promo", len(store.urls) == 1)
+		})
+	}
+}
+
+func TestCreateBatch(t *testing.T) {
+	store := &fakeStore{urls: map[string]storage.URL{"taken": {Alias: "taken", URL: "https://other.com"}}}
+
+	links := []shortener.Link{
+		{URL: storage.URL{URL: "https://example.com"}, Alias: "first"},
+		{URL: storage.URL{URL: "ftp://example.com"}},
+		{URL: storage.URL{URL: "https://example.com"}, Alias: "taken"},
+		{URL: storage.URL{URL: "https://example.com/generated"}},
+		{URL: storage.URL{URL: "https://example.com"}, Alias: "health"},
+		{URL: storage.URL{URL: "https://evil.example"}, Alias: "flagged"},
+		{URL: storage.URL{URL: "https://sho.rt/first"}, Alias: "loop"},
+	}
+
+	results, err := newService(store).CreateBatch(context.Background(), shortener.Account{UserID: 7}, links, "")
+	require.NoError(t, err)
+	require.Len(t, results, len(links))
+
+	require.Equal(t, shortener.Result{Alias: "first"}, results[0])
+	require.ErrorIs(t, results[1].Err, shortener.ErrRejected)
+	require.ErrorIs(t, results[2].Err, storage.ErrURLExists)
+	require.NoError(t, results[3].Err)
+	require.Len(t, results[3].Alias, 6)
+	require.ErrorContains(t, results[4].Err, "alias is reserved")
+	require.Equal(t, "url rejected: url is flagged as malicious: phishing", results[5].Err.Error())
+	require.Empty(t, results[5].Alias)
+	require.ErrorContains(t, results[6].Err, "url leads to a redirect loop")
+
+	require.Equal(t, int64(7), store.urls[results[3].Alias].UserID)
+	require.Len(t, store.urls, 3)
+}
+
+func TestCreateBatch_GeneratedAliasCollision(t *testing.T) {
+	store := &fakeStore{urls: map[string]storage.URL{"taken": {Alias: "taken"}}, collisions: 1}
+
+	links := []shortener.Link{
+		{URL: storage.URL{URL: "https://a.com"}},
+		{URL: storage.URL{URL: "https://b.com"}, Alias: "taken"},
+	}
+
+	results, err := newService(store).CreateBatch(context.Background(), shortener.Account{}, links, "")
+	require.NoError(t, err)
+
+	// Повторно сохраняется только сгенерированный псевдоним
+	require.Len(t, store.attempts, 3)
+	require.NoError(t, results[0].Err)
+	require.Equal(t, store.attempts[2], results[0].Alias)
+	require.ErrorIs(t, results[1].Err, storage.ErrURLExists)
+}
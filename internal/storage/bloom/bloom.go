@@ -0,0 +1,189 @@
+// Package bloom answers lookups of unknown aliases from an in-memory bloom filter,
+// so crawlers and scanners requesting random paths don't reach the storage.
+package bloom
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// pageSize is the number of links read at once while the filter is rebuilt.
+const pageSize = 1000
+
+// Storage decorates storage.Storage with a bloom filter of existing aliases: GetURL of
+// an alias the filter has never seen returns storage.ErrURLNotFound without a lookup.
+// The filter is filled by Rebuild, until then every lookup goes to the storage. Links
+// saved by other instances sharing the storage are unknown until the next rebuild.
+// Methods which are not overridden are passed to the embedded storage as is.
+type Storage struct {
+	storage.Storage
+
+	capacity int
+	fpRate   float64
+
+	mu     sync.RWMutex
+	filter *Filter
+	// pending collects aliases saved while the filter is rebuilt.
+	pending    []string
+	rebuilding bool
+}
+
+// New wraps s; the filter is sized for at least capacity links with the false positive rate fpRate.
+func New(s storage.Storage, capacity int, fpRate float64) *Storage {
+	return &Storage{
+		Storage:  s,
+		capacity: capacity,
+		fpRate:   fpRate,
+	}
+}
+
+func (s *Storage) GetURL(ctx context.Context, alias string) (storage.URL, error) {
+	s.mu.RLock()
+	known := s.filter == nil || s.filter.Test(alias)
+	s.mu.RUnlock()
+
+	if !known {
+		return storage.URL{}, storage.ErrURLNotFound
+	}
+
+	return s.Storage.GetURL(ctx, alias)
+}
+
+func (s *Storage) SaveURL(ctx context.Context, u storage.URL) (int64, error) {
+	// Добавляем до сохранения, иначе переход по только что созданной ссылке
+	// мог бы успеть получить 404. Лишний алиас после ошибки ничему не вредит.
+	s.add(u.Alias)
+
+	return s.Storage.SaveURL(ctx, u)
+}
+
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.URL) ([]int64, error) {
+	aliases := make([]string, len(urls))
+	for i, u := range urls {
+		aliases[i] = u.Alias
+	}
+	s.add(aliases...)
+
+	return s.Storage.SaveURLs(ctx, urls)
+}
+
+// RestoreURL adds the alias, as links deleted before the last rebuild are not in the filter.
+func (s *Storage) RestoreURL(ctx context.Context, alias string) error {
+	s.add(alias)
+
+	return s.Storage.RestoreURL(ctx, alias)
+}
+
+func (s *Storage) add(aliases ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, alias := range aliases {
+		if s.filter != nil {
+			s.filter.Add(alias)
+		}
+		if s.rebuilding {
+			s.pending = append(s.pending, alias)
+		}
+	}
+}
+
+// Rebuild fills a new filter with aliases of all links and replaces the current one.
+// It returns the number of links read.
+func (s *Storage) Rebuild(ctx context.Context) (int, error) {
+	const op = "storage.bloom.Rebuild"
+
+	s.mu.Lock()
+	s.rebuilding = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.rebuilding = false
+		s.pending = nil
+		s.mu.Unlock()
+	}()
+
+	var aliases []string
+
+	// Архивные ссылки тоже существуют: переход по ним возвращает их из архива.
+	// Архив читается вторым, чтобы не потерять ссылки, перенесенные во время чтения.
+	for _, archived := range []bool{false, true} {
+		filter := storage.ListFilter{Limit: pageSize, Archived: archived}
+		for {
+			urls, next, err := s.Storage.ListURLs(ctx, filter)
+			if err != nil {
+				return 0, fmt.Errorf("%s: %w", op, err)
+			}
+
+			for _, u := range urls {
+				aliases = append(aliases, u.Alias)
+			}
+
+			if next == "" {
+				break
+			}
+
+			filter.Cursor = next
+		}
+	}
+
+	// Запас на ссылки, созданные до следующей пересборки
+	f := NewFilter(max(s.capacity, 2*len(aliases)), s.fpRate)
+	for _, alias := range aliases {
+		f.Add(alias)
+	}
+
+	s.mu.Lock()
+	for _, alias := range s.pending {
+		f.Add(alias)
+	}
+	s.filter = f
+	s.mu.Unlock()
+
+	return len(aliases), nil
+}
+
+// Run rebuilds the filter at once and then every interval until ctx is done;
+// a zero interval rebuilds it only once.
+func (s *Storage) Run(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	const op = "storage.bloom.Run"
+
+	log = log.With(slog.String("op", op))
+
+	rebuild := func() {
+		n, err := s.Rebuild(ctx)
+		if err != nil {
+			log.Error("failed to rebuild alias filter", sl.Err(err))
+
+			return
+		}
+
+		log.Info("alias filter rebuilt", slog.Int("aliases", n))
+	}
+
+	rebuild()
+
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rebuild()
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/storage"
+)
+
+// fakeStorage counts GetURL calls and lists saved aliases page by page.
+type fakeStorage struct {
+	storage.Storage
+
+	aliases []string
+	// archived aliases are listed with ListFilter.Archived only.
+	archived []string
+	calls    int
+	// onList is called on every ListURLs call, e.g. to save a link during a rebuild.
+	onList  func()
+	listErr error
+}
+
+func (f *fakeStorage) GetURL(_ context.Context, alias string) (storage.URL, error) {
+	f.calls++
+
+	for _, a := range append(f.aliases, f.archived...) {
+		if a == alias {
+			return storage.URL{Alias: alias, URL: "https://example.com"}, nil
+		}
+	}
+
+	return storage.URL{}, storage.ErrURLNotFound
+}
+
+func (f *fakeStorage) SaveURL(_ context.Context, u storage.URL) (int64, error) {
+	f.aliases = append(f.aliases, u.Alias)
+
+	return int64(len(f.aliases)), nil
+}
+
+func (f *fakeStorage) RestoreURL(_ context.Context, alias string) error {
+	f.aliases = append(f.aliases, alias)
+
+	return nil
+}
+
+func (f *fakeStorage) ListURLs(_ context.Context, filter storage.ListFilter) ([]storage.URL, string, error) {
+	if f.onList != nil {
+		f.onList()
+	}
+	if f.listErr != nil {
+		return nil, "", f.listErr
+	}
+
+	aliases := f.aliases
+	if filter.Archived {
+		aliases = f.archived
+	}
+
+	from := 0
+	if filter.Cursor != "" {
+		from, _ = strconv.Atoi(filter.Cursor)
+	}
+	to := min(from+filter.Limit, len(aliases))
+
+	var urls []storage.URL
+	for _, alias := range aliases[from:to] {
+		urls = append(urls, storage.URL{Alias: alias})
+	}
+
+	var next string
+	if to < len(aliases) {
+		next = strconv.Itoa(to)
+	}
+
+	return urls, next, nil
+}
+
+func TestGetURL(t *testing.T) {
+	f := &fakeStorage{}
+	for i := 0; i < 2*pageSize+1; i++ {
+		f.aliases = append(f.aliases, fmt.Sprintf("alias%d", i))
+	}
+
+	s := New(f, 100, 0.01)
+
+	// Пока фильтр не построен, все запросы идут в хранилище
+	_, err := s.GetURL(context.Background(), "unknown")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	assert.Equal(t, 1, f.calls)
+
+	n, err := s.Rebuild(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, len(f.aliases), n)
+
+	f.calls = 0
+	for _, alias := range f.aliases {
+		_, err := s.GetURL(context.Background(), alias)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, len(f.aliases), f.calls)
+
+	f.calls = 0
+	for i := 0; i < 1000; i++ {
+		_, err := s.GetURL(context.Background(), fmt.Sprintf("unknown%d", i))
+		require.ErrorIs(t, err, storage.ErrURLNotFound)
+	}
+	assert.Less(t, f.calls, 50)
+}
+
+func TestSaveURL(t *testing.T) {
+	f := &fakeStorage{aliases: []string{"a"}}
+	s := New(f, 100, 0.01)
+
+	_, err := s.Rebuild(context.Background())
+	require.NoError(t, err)
+
+	_, err = s.SaveURL(context.Background(), storage.URL{Alias: "b", URL: "https://example.com"})
+	require.NoError(t, err)
+
+	_, err = s.GetURL(context.Background(), "b")
+	require.NoError(t, err)
+
+	// Ссылка удалена до построения фильтра
+	require.NoError(t, s.RestoreURL(context.Background(), "c"))
+
+	_, err = s.GetURL(context.Background(), "c")
+	require.NoError(t, err)
+}
+
+func TestRebuild_SavedDuringRebuild(t *testing.T) {
+	f := &fakeStorage{aliases: []string{"a"}}
+	s := New(f, 100, 0.01)
+
+	// Ссылка сохраняется после того, как хранилище уже прочитано
+	f.onList = func() {
+		f.onList = nil
+		s.add("late")
+	}
+
+	_, err := s.Rebuild(context.Background())
+	require.NoError(t, err)
+
+	assert.True(t, s.filter.Test("late"))
+	assert.Empty(t, s.pending)
+}
+
+func TestRebuild_Archived(t *testing.T) {
+	f := &fakeStorage{aliases: []string{"a"}, archived: []string{"old"}}
+	s := New(f, 100, 0.01)
+
+	n, err := s.Rebuild(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// Переход по архивной ссылке доходит до хранилища, которое ее вернет
+	_, err = s.GetURL(context.Background(), "old")
+	require.NoError(t, err)
+}
+
+func TestRebuild_Error(t *testing.T) {
+	f := &fakeStorage{aliases: []string{"a"}, listErr: errors.New("storage is down")}
+	s := New(f, 100, 0.01)
+
+	_, err := s.Rebuild(context.Background())
+	require.Error(t, err)
+
+	// Без фильтра запросы по-прежнему идут в хранилище
+	_, err = s.GetURL(context.Background(), "a")
+	require.NoError(t, err)
+}
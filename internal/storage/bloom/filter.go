@@ -0,0 +1,67 @@
+package bloom
+
+import (
+	"hash/maphash"
+	"math"
+)
+
+// Filter is a bloom filter of strings. It is not safe for concurrent use.
+type Filter struct {
+	bits []uint64
+	// m is the number of bits, k is the number of hash functions.
+	m, k uint64
+	seed maphash.Seed
+}
+
+// NewFilter creates a filter for n items with the false positive rate p.
+func NewFilter(n int, p float64) *Filter {
+	if n < 1 {
+		n = 1
+	}
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	return &Filter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+		seed: maphash.MakeSeed(),
+	}
+}
+
+// Add adds s to the filter.
+func (f *Filter) Add(s string) {
+	h1, h2 := f.hash(s)
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Test reports whether s may be in the filter; false means it was never added.
+func (f *Filter) Test(s string) bool {
+	h1, h2 := f.hash(s)
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hash returns two halves of one 64-bit hash, the rest of the k hashes are
+// their combinations (Kirsch and Mitzenmacher).
+func (f *Filter) hash(s string) (uint64, uint64) {
+	h := maphash.String(f.seed, s)
+
+	return h & math.MaxUint32, h>>32 | 1
+}
@@ -0,0 +1,51 @@
+package bloom
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	cases := []struct {
+		name string
+		n    int
+		p    float64
+	}{
+		{name: "1%", n: 10000, p: 0.01},
+		{name: "0.1%", n: 10000, p: 0.001},
+		{name: "Invalid rate", n: 1000, p: 0},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f := NewFilter(tc.n, tc.p)
+			for i := 0; i < tc.n; i++ {
+				f.Add(fmt.Sprintf("added%d", i))
+			}
+
+			for i := 0; i < tc.n; i++ {
+				assert.True(t, f.Test(fmt.Sprintf("added%d", i)))
+			}
+
+			p := tc.p
+			if p == 0 {
+				p = 0.01
+			}
+
+			var positives int
+			for i := 0; i < tc.n; i++ {
+				if f.Test(fmt.Sprintf("missing%d", i)) {
+					positives++
+				}
+			}
+			// Доля ложных срабатываний случайна и может превышать заданную
+			assert.LessOrEqual(t, float64(positives)/float64(tc.n), 3*p)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"url-shortener/internal/storage"
+)
+
+// Storage decorates storage.Storage with an in-memory LRU cache of GetURL results.
+// Entries live for ttl and are dropped on update, delete and quarantine of the link, so
+// only other instances sharing the storage may see a changed link for up to ttl.
+// Missing links are cached for negativeTTL and dropped when the alias is saved; links
+// saved by other instances may be reported missing for up to negativeTTL.
+// Methods which are not overridden are passed to the embedded storage as is.
+type Storage struct {
+	storage.Storage
+
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order holds entries from most to least recently used.
+	order *list.List
+}
+
+type entry struct {
+	alias    string
+	u        storage.URL
+	cachedAt time.Time
+	// missing marks a cached storage.ErrURLNotFound.
+	missing bool
+}
+
+// New wraps s with a cache of up to size links; missing links are not cached if negativeTTL is zero.
+func New(s storage.Storage, size int, ttl, negativeTTL time.Duration) *Storage {
+	return &Storage{
+		Storage:     s,
+		size:        size,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		entries:     make(map[string]*list.Element, size),
+		order:       list.New(),
+	}
+}
+
+// GetURL returns the cached link if it is there and not stale. Found links and, with
+// negativeTTL, missing ones are cached; expiration of a cached link is checked on every call.
+func (s *Storage) GetURL(ctx context.Context, alias string) (storage.URL, error) {
+	now := time.Now()
+
+	if e, ok := s.get(alias, now); ok {
+		if e.missing {
+			return storage.URL{}, storage.ErrURLNotFound
+		}
+
+		if !e.u.ExpiresAt.IsZero() && !now.Before(e.u.ExpiresAt) {
+			s.remove(alias)
+
+			return storage.URL{}, storage.ErrURLExpired
+		}
+
+		return e.u, nil
+	}
+
+	u, err := s.Storage.GetURL(ctx, alias)
+	if errors.Is(err, storage.ErrURLNotFound) && s.negativeTTL > 0 {
+		s.add(&entry{alias: alias, cachedAt: now, missing: true})
+	}
+	if err != nil {
+		return storage.URL{}, err
+	}
+
+	s.add(&entry{alias: alias, u: u, cachedAt: now})
+
+	return u, nil
+}
+
+func (s *Storage) SaveURL(ctx context.Context, u storage.URL) (int64, error) {
+	defer s.remove(u.Alias)
+
+	return s.Storage.SaveURL(ctx, u)
+}
+
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.URL) ([]int64, error) {
+	defer func() {
+		for _, u := range urls {
+			s.remove(u.Alias)
+		}
+	}()
+
+	return s.Storage.SaveURLs(ctx, urls)
+}
+
+func (s *Storage) RestoreURL(ctx context.Context, alias string) error {
+	defer s.remove(alias)
+
+	return s.Storage.RestoreURL(ctx, alias)
+}
+
+func (s *Storage) UpdateURL(ctx context.Context, alias string, update storage.URLUpdate, version int64) (storage.URL, error) {
+	defer s.remove(alias)
+
+	return s.Storage.UpdateURL(ctx, alias, update, version)
+}
+
+func (s *Storage) DeleteURL(ctx context.Context, alias string) error {
+	defer s.remove(alias)
+
+	return s.Storage.DeleteURL(ctx, alias)
+}
+
+// ConsumeClick drops the link from the cache once it is exhausted, so GetURL reports it.
+func (s *Storage) ConsumeClick(ctx context.Context, alias string) error {
+	err := s.Storage.ConsumeClick(ctx, alias)
+	if errors.Is(err, storage.ErrURLExhausted) {
+		s.remove(alias)
+	}
+
+	return err
+}
+
+func (s *Storage) BurnURL(ctx context.Context, alias string) error {
+	defer s.remove(alias)
+
+	return s.Storage.BurnURL(ctx, alias)
+}
+
+func (s *Storage) QuarantineURL(ctx context.Context, alias, reason string) error {
+	defer s.remove(alias)
+
+	return s.Storage.QuarantineURL(ctx, alias, reason)
+}
+
+func (s *Storage) get(alias string, now time.Time) (*entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[alias]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*entry)
+
+	ttl := s.ttl
+	if e.missing {
+		ttl = s.negativeTTL
+	}
+
+	if now.Sub(e.cachedAt) >= ttl {
+		s.order.Remove(el)
+		delete(s.entries, alias)
+
+		return nil, false
+	}
+
+	s.order.MoveToFront(el)
+
+	return e, true
+}
+
+func (s *Storage) add(e *entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[e.alias]; ok {
+		el.Value = e
+		s.order.MoveToFront(el)
+
+		return
+	}
+
+	s.entries[e.alias] = s.order.PushFront(e)
+
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*entry).alias)
+	}
+}
+
+func (s *Storage) remove(alias string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[alias]; ok {
+		s.order.Remove(el)
+		delete(s.entries, alias)
+	}
+}
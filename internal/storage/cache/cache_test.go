@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/storage"
+)
+
+// fakeStorage counts GetURL calls; other methods are not used by the tests.
+type fakeStorage struct {
+	storage.Storage
+
+	urls  map[string]storage.URL
+	calls int
+}
+
+func (f *fakeStorage) GetURL(_ context.Context, alias string) (storage.URL, error) {
+	f.calls++
+
+	u, ok := f.urls[alias]
+	if !ok {
+		return storage.URL{}, storage.ErrURLNotFound
+	}
+
+	return u, nil
+}
+
+func (f *fakeStorage) SaveURL(_ context.Context, u storage.URL) (int64, error) {
+	f.urls[u.Alias] = u
+
+	return int64(len(f.urls)), nil
+}
+
+func (f *fakeStorage) UpdateURL(_ context.Context, alias string, update storage.URLUpdate, _ int64) (storage.URL, error) {
+	u := f.urls[alias]
+	u.URL = *update.URL
+	f.urls[alias] = u
+
+	return u, nil
+}
+
+func (f *fakeStorage) DeleteURL(_ context.Context, alias string) error {
+	delete(f.urls, alias)
+
+	return nil
+}
+
+func newFake(aliases ...string) *fakeStorage {
+	f := &fakeStorage{urls: make(map[string]storage.URL)}
+	for _, alias := range aliases {
+		f.urls[alias] = storage.URL{Alias: alias, URL: "https://" + alias + ".com"}
+	}
+
+	return f
+}
+
+func TestGetURL_Cached(t *testing.T) {
+	f := newFake("a")
+	s := New(f, 10, time.Minute, 0)
+
+	for i := 0; i < 3; i++ {
+		u, err := s.GetURL(context.Background(), "a")
+		require.NoError(t, err)
+		assert.Equal(t, "https://a.com", u.URL)
+	}
+
+	assert.Equal(t, 1, f.calls)
+}
+
+func TestGetURL_MissNotCached(t *testing.T) {
+	f := newFake()
+	s := New(f, 10, time.Minute, 0)
+
+	_, err := s.GetURL(context.Background(), "a")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	f.urls["a"] = storage.URL{Alias: "a", URL: "https://a.com"}
+
+	_, err = s.GetURL(context.Background(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, 2, f.calls)
+}
+
+func TestGetURL_MissCached(t *testing.T) {
+	f := newFake()
+	s := New(f, 10, time.Minute, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		_, err := s.GetURL(context.Background(), "a")
+		require.ErrorIs(t, err, storage.ErrURLNotFound)
+	}
+	assert.Equal(t, 1, f.calls)
+
+	// Сохранение ссылки сбрасывает закешированный промах
+	_, err := s.SaveURL(context.Background(), storage.URL{Alias: "a", URL: "https://a.com"})
+	require.NoError(t, err)
+
+	u, err := s.GetURL(context.Background(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, "https://a.com", u.URL)
+	assert.Equal(t, 2, f.calls)
+}
+
+func TestGetURL_MissTTL(t *testing.T) {
+	f := newFake()
+	s := New(f, 10, time.Minute, time.Nanosecond)
+
+	_, _ = s.GetURL(context.Background(), "a")
+	time.Sleep(time.Millisecond)
+
+	// Ссылку создал другой экземпляр: промах устарел и не мешает ее найти
+	f.urls["a"] = storage.URL{Alias: "a", URL: "https://a.com"}
+
+	_, err := s.GetURL(context.Background(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, 2, f.calls)
+}
+
+func TestGetURL_Evicted(t *testing.T) {
+	f := newFake("a", "b", "c")
+	s := New(f, 2, time.Minute, 0)
+
+	for _, alias := range []string{"a", "b", "a", "c", "a", "b"} {
+		_, err := s.GetURL(context.Background(), alias)
+		require.NoError(t, err)
+	}
+
+	// "b" вытеснена при добавлении "c", "a" использовалась чаще и осталась
+	assert.Equal(t, 4, f.calls)
+}
+
+func TestGetURL_TTL(t *testing.T) {
+	f := newFake("a")
+	s := New(f, 10, time.Nanosecond, 0)
+
+	_, _ = s.GetURL(context.Background(), "a")
+	time.Sleep(time.Millisecond)
+	_, _ = s.GetURL(context.Background(), "a")
+
+	assert.Equal(t, 2, f.calls)
+}
+
+func TestGetURL_Expired(t *testing.T) {
+	f := newFake()
+	f.urls["a"] = storage.URL{Alias: "a", URL: "https://a.com", ExpiresAt: time.Now().Add(20 * time.Millisecond)}
+	s := New(f, 10, time.Minute, 0)
+
+	_, err := s.GetURL(context.Background(), "a")
+	require.NoError(t, err)
+
+	time.Sleep(30 * time.Millisecond)
+
+	_, err = s.GetURL(context.Background(), "a")
+	require.ErrorIs(t, err, storage.ErrURLExpired)
+}
+
+func TestInvalidation(t *testing.T) {
+	f := newFake("a")
+	s := New(f, 10, time.Minute, 0)
+
+	_, _ = s.GetURL(context.Background(), "a")
+
+	newURL := "https://new.com"
+	_, err := s.UpdateURL(context.Background(), "a", storage.URLUpdate{URL: &newURL}, 0)
+	require.NoError(t, err)
+
+	u, err := s.GetURL(context.Background(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, newURL, u.URL)
+
+	require.NoError(t, s.DeleteURL(context.Background(), "a"))
+
+	_, err = s.GetURL(context.Background(), "a")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+}
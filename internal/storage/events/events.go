@@ -0,0 +1,113 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	linkEvents "url-shortener/internal/events"
+	"url-shortener/internal/storage"
+)
+
+// Publisher is an interface for publishing link events. Publish must not block.
+type Publisher interface {
+	Publish(e linkEvents.Event)
+}
+
+// Multi publishes events to all publishers.
+type Multi []Publisher
+
+func (m Multi) Publish(e linkEvents.Event) {
+	for _, p := range m {
+		p.Publish(e)
+	}
+}
+
+// Storage decorates storage.Storage with link_created, link_deleted and link_expired
+// events published after successful saves, deletes and burns of one-time links.
+type Storage struct {
+	storage.Storage
+
+	publisher Publisher
+}
+
+func New(s storage.Storage, publisher Publisher) *Storage {
+	return &Storage{Storage: s, publisher: publisher}
+}
+
+func (s *Storage) SaveURL(ctx context.Context, u storage.URL) (int64, error) {
+	id, err := s.Storage.SaveURL(ctx, u)
+	if err == nil {
+		s.created(u)
+	}
+
+	return id, err
+}
+
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.URL) ([]int64, error) {
+	ids, err := s.Storage.SaveURLs(ctx, urls)
+	if err != nil {
+		return ids, err
+	}
+
+	// Нулевой id - alias занят, ссылка не сохранена
+	for i, id := range ids {
+		if id != 0 {
+			s.created(urls[i])
+		}
+	}
+
+	return ids, nil
+}
+
+func (s *Storage) DeleteURL(ctx context.Context, alias string) error {
+	err := s.Storage.DeleteURL(ctx, alias)
+	if err == nil {
+		s.publisher.Publish(linkEvents.Event{
+			Type:  linkEvents.TypeLinkDeleted,
+			Alias: alias,
+			Time:  time.Now(),
+		})
+	}
+
+	return err
+}
+
+// BurnURL publishes link_deleted: a burnt link is deleted like by its owner.
+func (s *Storage) BurnURL(ctx context.Context, alias string) error {
+	err := s.Storage.BurnURL(ctx, alias)
+	if err == nil {
+		s.publisher.Publish(linkEvents.Event{
+			Type:  linkEvents.TypeLinkDeleted,
+			Alias: alias,
+			Time:  time.Now(),
+		})
+	}
+
+	return err
+}
+
+// DeleteExpiredURLs publishes link_expired for every removed link, also when
+// removing the rest of them failed.
+func (s *Storage) DeleteExpiredURLs(ctx context.Context) ([]string, error) {
+	aliases, err := s.Storage.DeleteExpiredURLs(ctx)
+
+	now := time.Now()
+	for _, alias := range aliases {
+		s.publisher.Publish(linkEvents.Event{
+			Type:  linkEvents.TypeLinkExpired,
+			Alias: alias,
+			Time:  now,
+		})
+	}
+
+	return aliases, err
+}
+
+func (s *Storage) created(u storage.URL) {
+	s.publisher.Publish(linkEvents.Event{
+		Type:  linkEvents.TypeLinkCreated,
+		Alias: u.Alias,
+		URL:   u.URL,
+		Time:  time.Now(),
+	})
+}
@@ -0,0 +1,81 @@
+package factory
+
+import (
+	"errors"
+	"fmt"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+	"url-shortener/internal/storage/redis"
+	"url-shortener/internal/storage/sqlite"
+)
+
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverRedis    = "redis"
+)
+
+var (
+	ErrUnknownDriver = errors.New("unknown storage driver")
+)
+
+// New creates storage selected by cfg.Storage.Driver.
+func New(cfg *config.Config) (storage.Storage, error) {
+	const op = "storage.factory.New"
+
+	switch cfg.Storage.Driver {
+	case DriverSQLite, "":
+		if cfg.StoragePath == "" {
+			return nil, fmt.Errorf("%s: storage_path is not set", op)
+		}
+
+		sqliteCfg := cfg.Storage.SQLite
+
+		s, err := sqlite.New(cfg.StoragePath, sqlite.Options{
+			JournalMode:  sqliteCfg.JournalMode,
+			BusyTimeout:  sqliteCfg.BusyTimeout,
+			ForeignKeys:  sqliteCfg.ForeignKeys,
+			MaxReadConns: sqliteCfg.MaxReadConns,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return s, nil
+	case DriverPostgres:
+		pgCfg := cfg.Storage.Postgres
+		if pgCfg.DSN == "" {
+			return nil, fmt.Errorf("%s: storage.postgres.dsn is not set", op)
+		}
+
+		s, err := postgres.New(pgCfg.DSN, postgres.Options{
+			MaxOpenConns:    pgCfg.MaxOpenConns,
+			MaxIdleConns:    pgCfg.MaxIdleConns,
+			ConnMaxLifetime: pgCfg.ConnMaxLifetime,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return s, nil
+	case DriverRedis:
+		redisCfg := cfg.Storage.Redis
+
+		s, err := redis.New(redis.Options{
+			Addr:      redisCfg.Addr,
+			Password:  redisCfg.Password,
+			DB:        redisCfg.DB,
+			KeyPrefix: redisCfg.KeyPrefix,
+			TTL:       redisCfg.TTL,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return s, nil
+	default:
+		return nil, fmt.Errorf("%s: %w: %q", op, ErrUnknownDriver, cfg.Storage.Driver)
+	}
+}
@@ -0,0 +1,355 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"url-shortener/internal/storage"
+)
+
+// Storage decorates storage.Storage with operation duration histograms
+// and redirect lookup hit/miss counters. Methods which are not overridden
+// are passed to the embedded storage as is.
+type Storage struct {
+	storage.Storage
+
+	duration *prometheus.HistogramVec
+	lookups  *prometheus.CounterVec
+}
+
+func New(s storage.Storage, reg prometheus.Registerer) *Storage {
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "storage_operation_duration_seconds",
+		Help:    "Storage operation latency by operation.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation"})
+
+	lookups := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "redirect_lookups_total",
+		Help: "Alias lookups by result: hit, miss, expired, exhausted or error.",
+	}, []string{"result"})
+
+	reg.MustRegister(duration, lookups)
+
+	return &Storage{
+		Storage:  s,
+		duration: duration,
+		lookups:  lookups,
+	}
+}
+
+func (s *Storage) observe(operation string, t1 time.Time) {
+	s.duration.WithLabelValues(operation).Observe(time.Since(t1).Seconds())
+}
+
+func (s *Storage) SaveURL(ctx context.Context, u storage.URL) (int64, error) {
+	defer s.observe("save_url", time.Now())
+
+	return s.Storage.SaveURL(ctx, u)
+}
+
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.URL) ([]int64, error) {
+	defer s.observe("save_urls", time.Now())
+
+	return s.Storage.SaveURLs(ctx, urls)
+}
+
+func (s *Storage) GetURL(ctx context.Context, alias string) (storage.URL, error) {
+	defer s.observe("get_url", time.Now())
+
+	u, err := s.Storage.GetURL(ctx, alias)
+
+	switch {
+	case err == nil:
+		s.lookups.WithLabelValues("hit").Inc()
+	case errors.Is(err, storage.ErrURLNotFound):
+		s.lookups.WithLabelValues("miss").Inc()
+	case errors.Is(err, storage.ErrURLExpired):
+		s.lookups.WithLabelValues("expired").Inc()
+	case errors.Is(err, storage.ErrURLExhausted):
+		s.lookups.WithLabelValues("exhausted").Inc()
+	default:
+		s.lookups.WithLabelValues("error").Inc()
+	}
+
+	return u, err
+}
+
+func (s *Storage) FindURL(ctx context.Context, target string, userID, apiKeyID int64) (storage.URL, error) {
+	defer s.observe("find_url", time.Now())
+
+	return s.Storage.FindURL(ctx, target, userID, apiKeyID)
+}
+
+func (s *Storage) GetURLInfo(ctx context.Context, alias string) (storage.URL, error) {
+	defer s.observe("get_url_info", time.Now())
+
+	return s.Storage.GetURLInfo(ctx, alias)
+}
+
+func (s *Storage) UpdateURL(ctx context.Context, alias string, update storage.URLUpdate, version int64) (storage.URL, error) {
+	defer s.observe("update_url", time.Now())
+
+	return s.Storage.UpdateURL(ctx, alias, update, version)
+}
+
+func (s *Storage) DeleteURL(ctx context.Context, alias string) error {
+	defer s.observe("delete_url", time.Now())
+
+	return s.Storage.DeleteURL(ctx, alias)
+}
+
+func (s *Storage) RestoreURL(ctx context.Context, alias string) error {
+	defer s.observe("restore_url", time.Now())
+
+	return s.Storage.RestoreURL(ctx, alias)
+}
+
+func (s *Storage) ConsumeClick(ctx context.Context, alias string) error {
+	defer s.observe("consume_click", time.Now())
+
+	return s.Storage.ConsumeClick(ctx, alias)
+}
+
+func (s *Storage) BurnURL(ctx context.Context, alias string) error {
+	defer s.observe("burn_url", time.Now())
+
+	return s.Storage.BurnURL(ctx, alias)
+}
+
+func (s *Storage) QuarantineURL(ctx context.Context, alias, reason string) error {
+	defer s.observe("quarantine_url", time.Now())
+
+	return s.Storage.QuarantineURL(ctx, alias, reason)
+}
+
+func (s *Storage) MarkURLDead(ctx context.Context, alias, reason string) error {
+	defer s.observe("mark_url_dead", time.Now())
+
+	return s.Storage.MarkURLDead(ctx, alias, reason)
+}
+
+func (s *Storage) SetPageMetadata(ctx context.Context, alias string, page storage.PageMetadata) error {
+	defer s.observe("set_page_metadata", time.Now())
+
+	return s.Storage.SetPageMetadata(ctx, alias, page)
+}
+
+func (s *Storage) ListURLs(ctx context.Context, filter storage.ListFilter) ([]storage.URL, string, error) {
+	defer s.observe("list_urls", time.Now())
+
+	return s.Storage.ListURLs(ctx, filter)
+}
+
+func (s *Storage) IncrementHits(ctx context.Context, hits map[string]int64) error {
+	defer s.observe("increment_hits", time.Now())
+
+	return s.Storage.IncrementHits(ctx, hits)
+}
+
+func (s *Storage) SaveClickEvents(ctx context.Context, events []storage.ClickEvent) error {
+	defer s.observe("save_click_events", time.Now())
+
+	return s.Storage.SaveClickEvents(ctx, events)
+}
+
+func (s *Storage) GetClickStats(ctx context.Context, alias string) (storage.ClickStats, error) {
+	defer s.observe("get_click_stats", time.Now())
+
+	return s.Storage.GetClickStats(ctx, alias)
+}
+
+func (s *Storage) GetHourlyClicks(ctx context.Context, alias string, from, to time.Time) ([]storage.ClickBucket, error) {
+	defer s.observe("get_hourly_clicks", time.Now())
+
+	return s.Storage.GetHourlyClicks(ctx, alias, from, to)
+}
+
+func (s *Storage) ExportClickEvents(ctx context.Context, filter storage.ClickFilter, fn func(storage.ClickEvent) error) error {
+	defer s.observe("export_click_events", time.Now())
+
+	return s.Storage.ExportClickEvents(ctx, filter, fn)
+}
+
+func (s *Storage) GetDailyClicks(ctx context.Context, from, to time.Time) ([]storage.DailyClicks, error) {
+	defer s.observe("get_daily_clicks", time.Now())
+
+	return s.Storage.GetDailyClicks(ctx, from, to)
+}
+
+func (s *Storage) GetServiceStats(ctx context.Context, now time.Time) (storage.ServiceStats, error) {
+	defer s.observe("get_service_stats", time.Now())
+
+	return s.Storage.GetServiceStats(ctx, now)
+}
+
+func (s *Storage) SaveAPIKey(ctx context.Context, key storage.APIKey) (int64, error) {
+	defer s.observe("save_api_key", time.Now())
+
+	return s.Storage.SaveAPIKey(ctx, key)
+}
+
+func (s *Storage) GetAPIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error) {
+	defer s.observe("get_api_key_by_hash", time.Now())
+
+	return s.Storage.GetAPIKeyByHash(ctx, hash)
+}
+
+func (s *Storage) GetAPIKey(ctx context.Context, id int64) (storage.APIKey, error) {
+	defer s.observe("get_api_key", time.Now())
+
+	return s.Storage.GetAPIKey(ctx, id)
+}
+
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64) error {
+	defer s.observe("revoke_api_key", time.Now())
+
+	return s.Storage.RevokeAPIKey(ctx, id)
+}
+
+func (s *Storage) SaveUser(ctx context.Context, user storage.User) (int64, error) {
+	defer s.observe("save_user", time.Now())
+
+	return s.Storage.SaveUser(ctx, user)
+}
+
+func (s *Storage) GetUser(ctx context.Context, email string) (storage.User, error) {
+	defer s.observe("get_user", time.Now())
+
+	return s.Storage.GetUser(ctx, email)
+}
+
+func (s *Storage) SetUserRole(ctx context.Context, email, role string) error {
+	defer s.observe("set_user_role", time.Now())
+
+	return s.Storage.SetUserRole(ctx, email, role)
+}
+
+func (s *Storage) VerifyUser(ctx context.Context, email string) error {
+	defer s.observe("verify_user", time.Now())
+
+	return s.Storage.VerifyUser(ctx, email)
+}
+
+func (s *Storage) SetUserPassword(ctx context.Context, email string, passHash []byte) error {
+	defer s.observe("set_user_password", time.Now())
+
+	return s.Storage.SetUserPassword(ctx, email, passHash)
+}
+
+func (s *Storage) SaveUserToken(ctx context.Context, token storage.UserToken) error {
+	defer s.observe("save_user_token", time.Now())
+
+	return s.Storage.SaveUserToken(ctx, token)
+}
+
+func (s *Storage) ConsumeUserToken(ctx context.Context, hash, purpose string) (storage.UserToken, error) {
+	defer s.observe("consume_user_token", time.Now())
+
+	return s.Storage.ConsumeUserToken(ctx, hash, purpose)
+}
+
+func (s *Storage) SaveDomain(ctx context.Context, domain storage.Domain) error {
+	defer s.observe("save_domain", time.Now())
+
+	return s.Storage.SaveDomain(ctx, domain)
+}
+
+func (s *Storage) GetDomain(ctx context.Context, host string) (storage.Domain, error) {
+	defer s.observe("get_domain", time.Now())
+
+	return s.Storage.GetDomain(ctx, host)
+}
+
+func (s *Storage) ListDomains(ctx context.Context) ([]storage.Domain, error) {
+	defer s.observe("list_domains", time.Now())
+
+	return s.Storage.ListDomains(ctx)
+}
+
+func (s *Storage) DeleteDomain(ctx context.Context, host string) error {
+	defer s.observe("delete_domain", time.Now())
+
+	return s.Storage.DeleteDomain(ctx, host)
+}
+
+func (s *Storage) SaveReport(ctx context.Context, report storage.Report) (int64, error) {
+	defer s.observe("save_report", time.Now())
+
+	return s.Storage.SaveReport(ctx, report)
+}
+
+func (s *Storage) ListReports(ctx context.Context, status string, limit int) ([]storage.Report, error) {
+	defer s.observe("list_reports", time.Now())
+
+	return s.Storage.ListReports(ctx, status, limit)
+}
+
+func (s *Storage) ResolveReports(ctx context.Context, alias, status string) (int64, error) {
+	defer s.observe("resolve_reports", time.Now())
+
+	return s.Storage.ResolveReports(ctx, alias, status)
+}
+
+func (s *Storage) BanHost(ctx context.Context, host string) error {
+	defer s.observe("ban_host", time.Now())
+
+	return s.Storage.BanHost(ctx, host)
+}
+
+func (s *Storage) UnbanHost(ctx context.Context, host string) error {
+	defer s.observe("unban_host", time.Now())
+
+	return s.Storage.UnbanHost(ctx, host)
+}
+
+func (s *Storage) ListBannedHosts(ctx context.Context) ([]storage.BannedHost, error) {
+	defer s.observe("list_banned_hosts", time.Now())
+
+	return s.Storage.ListBannedHosts(ctx)
+}
+
+func (s *Storage) SaveWebhook(ctx context.Context, webhook storage.Webhook) (int64, error) {
+	defer s.observe("save_webhook", time.Now())
+
+	return s.Storage.SaveWebhook(ctx, webhook)
+}
+
+func (s *Storage) GetWebhook(ctx context.Context, id int64) (storage.Webhook, error) {
+	defer s.observe("get_webhook", time.Now())
+
+	return s.Storage.GetWebhook(ctx, id)
+}
+
+func (s *Storage) ListWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	defer s.observe("list_webhooks", time.Now())
+
+	return s.Storage.ListWebhooks(ctx)
+}
+
+func (s *Storage) UpdateWebhook(ctx context.Context, webhook storage.Webhook) error {
+	defer s.observe("update_webhook", time.Now())
+
+	return s.Storage.UpdateWebhook(ctx, webhook)
+}
+
+func (s *Storage) DeleteWebhook(ctx context.Context, id int64) error {
+	defer s.observe("delete_webhook", time.Now())
+
+	return s.Storage.DeleteWebhook(ctx, id)
+}
+
+func (s *Storage) SaveWebhookDelivery(ctx context.Context, delivery storage.WebhookDelivery) error {
+	defer s.observe("save_webhook_delivery", time.Now())
+
+	return s.Storage.SaveWebhookDelivery(ctx, delivery)
+}
+
+func (s *Storage) ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]storage.WebhookDelivery, error) {
+	defer s.observe("list_webhook_deliveries", time.Now())
+
+	return s.Storage.ListWebhookDeliveries(ctx, webhookID, limit)
+}
@@ -0,0 +1,162 @@
+// Package migrations applies versioned SQL migrations embedded into the binary.
+// Files live in a directory per dialect and are named <version>_<name>.sql;
+// applied versions are recorded in the schema_migrations table.
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialects of the SQL storages.
+const (
+	DialectSQLite   = "sqlite"
+	DialectPostgres = "postgres"
+)
+
+// lockID is the key of the postgres advisory lock, so concurrently starting
+// instances don't apply the same migrations twice.
+const lockID = 7265237
+
+var ErrUnknownDialect = errors.New("unknown dialect")
+
+//go:embed sqlite/*.sql postgres/*.sql
+var files embed.FS
+
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// List returns migrations of dialect ordered by version.
+func List(dialect string) ([]Migration, error) {
+	const op = "storage.migrations.List"
+
+	if dialect != DialectSQLite && dialect != DialectPostgres {
+		return nil, fmt.Errorf("%s: %w: %s", op, ErrUnknownDialect, dialect)
+	}
+
+	entries, err := fs.ReadDir(files, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+	for _, e := range entries {
+		version, name, ok := strings.Cut(strings.TrimSuffix(e.Name(), ".sql"), "_")
+		v, err := strconv.Atoi(version)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("%s: invalid migration file name %s", op, e.Name())
+		}
+
+		body, err := files.ReadFile(path.Join(dialect, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		migrations = append(migrations, Migration{Version: v, Name: name, SQL: string(body)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up applies pending migrations in one transaction and returns them.
+// Nothing is applied if any of them fails.
+func Up(db *sql.DB, dialect string) ([]Migration, error) {
+	const op = "storage.migrations.Up"
+
+	migrations, err := List(dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if dialect == DialectPostgres {
+		if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", lockID); err != nil {
+			return nil, fmt.Errorf("%s: lock: %w", op, err)
+		}
+	}
+
+	if _, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations(
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL)`,
+	); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	current, err := version(tx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	insert := "INSERT INTO schema_migrations(version, name, applied_at) VALUES(?, ?, ?)"
+	if dialect == DialectPostgres {
+		insert = "INSERT INTO schema_migrations(version, name, applied_at) VALUES($1, $2, $3)"
+	}
+
+	var applied []Migration
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+
+		if _, err := tx.Exec(m.SQL); err != nil {
+			return nil, fmt.Errorf("%s: migration %d_%s: %w", op, m.Version, m.Name, err)
+		}
+
+		if _, err := tx.Exec(insert, m.Version, m.Name, time.Now().UTC()); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		applied = append(applied, m)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	return applied, nil
+}
+
+// Version returns the latest applied version, zero if there are none.
+func Version(db *sql.DB) (int, error) {
+	const op = "storage.migrations.Version"
+
+	v, err := version(db)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return v, nil
+}
+
+type querier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func version(q querier) (int, error) {
+	var v sql.NullInt64
+	if err := q.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&v); err != nil {
+		return 0, err
+	}
+
+	return int(v.Int64), nil
+}
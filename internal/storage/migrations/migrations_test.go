@@ -0,0 +1,87 @@
+package migrations_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/migrations"
+	"url-shortener/internal/storage/sqlite"
+)
+
+func TestList(t *testing.T) {
+	for _, dialect := range []string{migrations.DialectSQLite, migrations.DialectPostgres} {
+		list, err := migrations.List(dialect)
+		require.NoError(t, err)
+		require.NotEmpty(t, list)
+
+		for i, m := range list {
+			require.Equal(t, i+1, m.Version, "%s migrations must be numbered without gaps", dialect)
+			require.NotEmpty(t, m.SQL)
+		}
+	}
+
+	_, err := migrations.List("mysql")
+	require.ErrorIs(t, err, migrations.ErrUnknownDialect)
+}
+
+func TestUp(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	all, err := migrations.List(migrations.DialectSQLite)
+	require.NoError(t, err)
+
+	applied, err := migrations.Up(db, migrations.DialectSQLite)
+	require.NoError(t, err)
+	require.Equal(t, all, applied)
+
+	applied, err = migrations.Up(db, migrations.DialectSQLite)
+	require.NoError(t, err)
+	require.Empty(t, applied)
+
+	version, err := migrations.Version(db)
+	require.NoError(t, err)
+	require.Equal(t, all[len(all)-1].Version, version)
+}
+
+func TestUp_LegacySQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	// Схема самой первой версии сервиса, до expires_at и остальных колонок
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+	CREATE TABLE url(
+		id INTEGER PRIMARY KEY,
+		alias TEXT NOT NULL UNIQUE,
+		url TEXT NOT NULL);
+	INSERT INTO url(alias, url) VALUES('old', 'https://example.com');
+	`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	s, err := sqlite.New(path, sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	u, err := s.GetURL(context.Background(), "old")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com", u.URL)
+
+	_, err = s.SaveURL(context.Background(), storage.URL{Alias: "new", URL: "https://example.org"})
+	require.NoError(t, err)
+
+	all, err := migrations.List(migrations.DialectSQLite)
+	require.NoError(t, err)
+
+	version, err := s.SchemaVersion()
+	require.NoError(t, err)
+	require.Equal(t, all[len(all)-1].Version, version)
+}
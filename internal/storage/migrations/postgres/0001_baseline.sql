@@ -0,0 +1,42 @@
+-- Схема на момент перехода на версионные миграции. Все операторы идемпотентны,
+-- поэтому файл применяется и к базам, созданным до появления миграций.
+CREATE TABLE IF NOT EXISTS url(
+	id BIGSERIAL PRIMARY KEY,
+	alias TEXT NOT NULL UNIQUE,
+	url TEXT NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_alias ON url(alias);
+ALTER TABLE url ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+ALTER TABLE url ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE url ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+ALTER TABLE url ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE url ADD COLUMN IF NOT EXISTS hits BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_created_at ON url(created_at);
+CREATE INDEX IF NOT EXISTS idx_alias_pattern ON url(alias text_pattern_ops);
+CREATE TABLE IF NOT EXISTS click_event(
+	id BIGSERIAL PRIMARY KEY,
+	alias TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	referrer TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	browser TEXT NOT NULL DEFAULT '',
+	ip TEXT NOT NULL DEFAULT '');
+CREATE INDEX IF NOT EXISTS idx_click_event_alias_created_at ON click_event(alias, created_at);
+CREATE INDEX IF NOT EXISTS idx_expires_at ON url(expires_at);
+CREATE TABLE IF NOT EXISTS api_key(
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	hash TEXT NOT NULL UNIQUE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	revoked_at TIMESTAMPTZ);
+ALTER TABLE url ADD COLUMN IF NOT EXISTS api_key_id BIGINT REFERENCES api_key(id);
+CREATE TABLE IF NOT EXISTS users(
+	id BIGSERIAL PRIMARY KEY,
+	email TEXT NOT NULL UNIQUE,
+	pass_hash BYTEA NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now());
+ALTER TABLE url ADD COLUMN IF NOT EXISTS user_id BIGINT REFERENCES users(id);
+CREATE INDEX IF NOT EXISTS idx_user_id ON url(user_id);
+ALTER TABLE url ADD COLUMN IF NOT EXISTS redirect_code INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE url ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_deleted_at ON url(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_url ON url USING hash (url);
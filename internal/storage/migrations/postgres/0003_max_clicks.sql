@@ -0,0 +1,3 @@
+-- Ограничение числа переходов по ссылке, 0 - без ограничения.
+ALTER TABLE url ADD COLUMN IF NOT EXISTS max_clicks BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE url ADD COLUMN IF NOT EXISTS exhausted_at TIMESTAMPTZ;
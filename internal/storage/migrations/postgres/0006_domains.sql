@@ -0,0 +1,5 @@
+-- Короткие домены, зарегистрированные через API, и привязка к ним ссылок.
+CREATE TABLE IF NOT EXISTS domain(
+	host TEXT PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now());
+ALTER TABLE url ADD COLUMN IF NOT EXISTS domain TEXT NOT NULL DEFAULT '';
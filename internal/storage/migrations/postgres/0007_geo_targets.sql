@@ -0,0 +1,2 @@
+-- Адреса перехода по странам и континентам посетителей, JSON-объект.
+ALTER TABLE url ADD COLUMN IF NOT EXISTS geo_targets TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Адреса перехода для iOS, Android и десктопов, JSON-объект.
+ALTER TABLE url ADD COLUMN IF NOT EXISTS device_targets TEXT NOT NULL DEFAULT '';
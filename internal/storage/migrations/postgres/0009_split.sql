@@ -0,0 +1,3 @@
+-- Распределение переходов между вариантами A/B-теста, JSON-объект.
+ALTER TABLE url ADD COLUMN IF NOT EXISTS split TEXT NOT NULL DEFAULT '';
+ALTER TABLE click_event ADD COLUMN IF NOT EXISTS variant TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Шаблоны параметров запроса, добавляемых к адресу перехода.
+ALTER TABLE url ADD COLUMN IF NOT EXISTS query_params TEXT NOT NULL DEFAULT '';
+ALTER TABLE url ADD COLUMN IF NOT EXISTS pass_query BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,5 @@
+-- Теги и описание ссылок для поиска. Теги хранятся JSON-массивом,
+-- поиск по тегу использует GIN-индекс по jsonb.
+ALTER TABLE url ADD COLUMN IF NOT EXISTS tags TEXT NOT NULL DEFAULT '';
+ALTER TABLE url ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_tags ON url USING GIN ((NULLIF(tags, '')::jsonb));
@@ -0,0 +1,2 @@
+-- Роль пользователя, пустая строка - editor.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+-- Подтверждение email: пользователи, зарегистрированные раньше, считаются подтвержденными.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verified_at TIMESTAMPTZ;
+UPDATE users SET verified_at = created_at WHERE verified_at IS NULL;
+CREATE TABLE IF NOT EXISTS user_token(
+	hash TEXT PRIMARY KEY,
+	email TEXT NOT NULL,
+	purpose TEXT NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_user_token_expires_at ON user_token(expires_at);
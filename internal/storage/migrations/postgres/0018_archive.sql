@@ -0,0 +1,33 @@
+-- Архив ссылок, по которым давно не переходили: горячая таблица url остается
+-- небольшой, а ссылка возвращается из архива при первом обращении к ней.
+-- Идентификатор ссылки при возврате из архива меняется.
+ALTER TABLE url ADD COLUMN IF NOT EXISTS last_hit_at TIMESTAMPTZ;
+CREATE TABLE IF NOT EXISTS url_archive(
+	id BIGSERIAL PRIMARY KEY,
+	alias TEXT NOT NULL UNIQUE,
+	url TEXT NOT NULL,
+	expires_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ,
+	updated_at TIMESTAMPTZ,
+	version BIGINT NOT NULL,
+	hits BIGINT NOT NULL,
+	api_key_id BIGINT,
+	user_id BIGINT,
+	redirect_code INTEGER NOT NULL,
+	quarantine_reason TEXT NOT NULL,
+	max_clicks BIGINT NOT NULL,
+	exhausted_at TIMESTAMPTZ,
+	webhook_url TEXT NOT NULL,
+	domain TEXT NOT NULL,
+	geo_targets TEXT NOT NULL,
+	device_targets TEXT NOT NULL,
+	split TEXT NOT NULL,
+	query_params TEXT NOT NULL,
+	pass_query BOOLEAN NOT NULL,
+	tags TEXT NOT NULL,
+	description TEXT NOT NULL,
+	dead_reason TEXT NOT NULL,
+	dead_since TIMESTAMPTZ,
+	last_hit_at TIMESTAMPTZ,
+	archived_at TIMESTAMPTZ NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_url_archive_expires_at ON url_archive(expires_at);
@@ -0,0 +1,2 @@
+-- Сводная статистика считает переходы за последние дни по всем ссылкам.
+CREATE INDEX IF NOT EXISTS idx_click_event_created_at ON click_event(created_at);
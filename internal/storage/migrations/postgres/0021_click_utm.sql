@@ -0,0 +1,7 @@
+-- Источники переходов для маркетинга: хост Referer и utm_*-параметры короткой
+-- ссылки. У переходов, сохраненных раньше, хост не заполнен, в разбивку по
+-- сайтам они не попадают.
+ALTER TABLE click_event ADD COLUMN IF NOT EXISTS referrer_host TEXT NOT NULL DEFAULT '';
+ALTER TABLE click_event ADD COLUMN IF NOT EXISTS utm_source TEXT NOT NULL DEFAULT '';
+ALTER TABLE click_event ADD COLUMN IF NOT EXISTS utm_medium TEXT NOT NULL DEFAULT '';
+ALTER TABLE click_event ADD COLUMN IF NOT EXISTS utm_campaign TEXT NOT NULL DEFAULT '';
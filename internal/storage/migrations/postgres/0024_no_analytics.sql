@@ -0,0 +1,3 @@
+-- Ссылки, переходы по которым не попадают в статистику: считается только hits.
+ALTER TABLE url ADD COLUMN IF NOT EXISTS no_analytics BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE url_archive ADD COLUMN IF NOT EXISTS no_analytics BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+-- Одноразовые ссылки: первый переход удаляет ссылку.
+ALTER TABLE url ADD COLUMN IF NOT EXISTS burn_after_reading BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE url_archive ADD COLUMN IF NOT EXISTS burn_after_reading BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,7 @@
+-- Заголовок и OpenGraph-метаданные страницы назначения, загружаются после сохранения.
+ALTER TABLE url ADD COLUMN IF NOT EXISTS page_title TEXT NOT NULL DEFAULT '';
+ALTER TABLE url ADD COLUMN IF NOT EXISTS page_description TEXT NOT NULL DEFAULT '';
+ALTER TABLE url ADD COLUMN IF NOT EXISTS page_image TEXT NOT NULL DEFAULT '';
+ALTER TABLE url_archive ADD COLUMN IF NOT EXISTS page_title TEXT NOT NULL DEFAULT '';
+ALTER TABLE url_archive ADD COLUMN IF NOT EXISTS page_description TEXT NOT NULL DEFAULT '';
+ALTER TABLE url_archive ADD COLUMN IF NOT EXISTS page_image TEXT NOT NULL DEFAULT '';
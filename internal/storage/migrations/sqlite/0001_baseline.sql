@@ -0,0 +1,41 @@
+-- Схема на момент перехода на версионные миграции. Все операторы идемпотентны,
+-- поэтому файл применяется и к базам, созданным до появления миграций.
+CREATE TABLE IF NOT EXISTS url(
+	id INTEGER PRIMARY KEY,
+	alias TEXT NOT NULL UNIQUE,
+	url TEXT NOT NULL,
+	expires_at TIMESTAMP,
+	created_at TIMESTAMP,
+	updated_at TIMESTAMP,
+	version INTEGER NOT NULL DEFAULT 1,
+	hits INTEGER NOT NULL DEFAULT 0,
+	api_key_id INTEGER,
+	user_id INTEGER,
+	redirect_code INTEGER NOT NULL DEFAULT 0,
+	deleted_at TIMESTAMP);
+CREATE INDEX IF NOT EXISTS idx_alias ON url(alias);
+CREATE INDEX IF NOT EXISTS idx_expires_at ON url(expires_at);
+CREATE INDEX IF NOT EXISTS idx_created_at ON url(created_at);
+CREATE TABLE IF NOT EXISTS click_event(
+	id INTEGER PRIMARY KEY,
+	alias TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	referrer TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	browser TEXT NOT NULL DEFAULT '',
+	ip TEXT NOT NULL DEFAULT '');
+CREATE INDEX IF NOT EXISTS idx_click_event_alias_created_at ON click_event(alias, created_at);
+CREATE TABLE IF NOT EXISTS api_key(
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	hash TEXT NOT NULL UNIQUE,
+	created_at TIMESTAMP NOT NULL,
+	revoked_at TIMESTAMP);
+CREATE TABLE IF NOT EXISTS users(
+	id INTEGER PRIMARY KEY,
+	email TEXT NOT NULL UNIQUE,
+	pass_hash BLOB NOT NULL,
+	created_at TIMESTAMP NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_user_id ON url(user_id);
+CREATE INDEX IF NOT EXISTS idx_deleted_at ON url(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_url ON url(url);
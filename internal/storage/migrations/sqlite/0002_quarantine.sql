@@ -0,0 +1,2 @@
+-- Причина карантина ссылки, пустая строка - ссылка не в карантине.
+ALTER TABLE url ADD COLUMN quarantine_reason TEXT NOT NULL DEFAULT '';
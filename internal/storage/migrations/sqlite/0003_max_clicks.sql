@@ -0,0 +1,3 @@
+-- Ограничение числа переходов по ссылке, 0 - без ограничения.
+ALTER TABLE url ADD COLUMN max_clicks INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE url ADD COLUMN exhausted_at TIMESTAMP;
@@ -0,0 +1,2 @@
+-- Адрес для уведомлений о переходах по ссылке, пустая строка - без уведомлений.
+ALTER TABLE url ADD COLUMN webhook_url TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Тенант, к которому привязаны ключ API и пользователь, пустая строка - тенант по умолчанию.
+ALTER TABLE api_key ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+-- Короткие домены, зарегистрированные через API, и привязка к ним ссылок.
+CREATE TABLE IF NOT EXISTS domain(
+	host TEXT PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL);
+ALTER TABLE url ADD COLUMN domain TEXT NOT NULL DEFAULT '';
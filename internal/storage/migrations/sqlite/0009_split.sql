@@ -0,0 +1,3 @@
+-- Распределение переходов между вариантами A/B-теста, JSON-объект.
+ALTER TABLE url ADD COLUMN split TEXT NOT NULL DEFAULT '';
+ALTER TABLE click_event ADD COLUMN variant TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Шаблоны параметров запроса, добавляемых к адресу перехода.
+ALTER TABLE url ADD COLUMN query_params TEXT NOT NULL DEFAULT '';
+ALTER TABLE url ADD COLUMN pass_query INTEGER NOT NULL DEFAULT 0;
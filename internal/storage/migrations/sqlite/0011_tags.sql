@@ -0,0 +1,19 @@
+-- Теги и описание ссылок для поиска. Теги хранятся JSON-массивом в url.tags,
+-- а для поиска по тегу триггеры поддерживают индексную таблицу url_tag.
+ALTER TABLE url ADD COLUMN tags TEXT NOT NULL DEFAULT '';
+ALTER TABLE url ADD COLUMN description TEXT NOT NULL DEFAULT '';
+CREATE TABLE IF NOT EXISTS url_tag(
+	tag TEXT NOT NULL,
+	url_id INTEGER NOT NULL,
+	PRIMARY KEY(tag, url_id)) WITHOUT ROWID;
+CREATE INDEX IF NOT EXISTS idx_url_tag_url_id ON url_tag(url_id);
+CREATE TRIGGER IF NOT EXISTS url_tag_insert AFTER INSERT ON url BEGIN
+	INSERT OR IGNORE INTO url_tag(tag, url_id) SELECT value, NEW.id FROM json_each(NULLIF(NEW.tags, ''));
+END;
+CREATE TRIGGER IF NOT EXISTS url_tag_update AFTER UPDATE OF tags ON url BEGIN
+	DELETE FROM url_tag WHERE url_id = OLD.id;
+	INSERT OR IGNORE INTO url_tag(tag, url_id) SELECT value, NEW.id FROM json_each(NULLIF(NEW.tags, ''));
+END;
+CREATE TRIGGER IF NOT EXISTS url_tag_delete AFTER DELETE ON url BEGIN
+	DELETE FROM url_tag WHERE url_id = OLD.id;
+END;
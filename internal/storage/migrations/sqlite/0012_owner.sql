@@ -0,0 +1,3 @@
+-- Роль ключа API: 'admin' управляет всеми ссылками, пустая строка - только своими.
+ALTER TABLE api_key ADD COLUMN role TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_api_key_id ON url(api_key_id);
@@ -0,0 +1,9 @@
+-- Подтверждение email: пользователи, зарегистрированные раньше, считаются подтвержденными.
+ALTER TABLE users ADD COLUMN verified_at TIMESTAMP;
+UPDATE users SET verified_at = created_at;
+CREATE TABLE IF NOT EXISTS user_token(
+	hash TEXT PRIMARY KEY,
+	email TEXT NOT NULL,
+	purpose TEXT NOT NULL,
+	expires_at TIMESTAMP NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_user_token_expires_at ON user_token(expires_at);
@@ -0,0 +1,2 @@
+-- Секрет для подписи запросов ключом API (HMAC), пустая строка - подпись не используется.
+ALTER TABLE api_key ADD COLUMN signing_secret TEXT NOT NULL DEFAULT '';
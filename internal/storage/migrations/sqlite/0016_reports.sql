@@ -0,0 +1,15 @@
+-- Жалобы на ссылки и запрещенные хосты назначения.
+CREATE TABLE IF NOT EXISTS report(
+	id INTEGER PRIMARY KEY,
+	alias TEXT NOT NULL,
+	category TEXT NOT NULL,
+	details TEXT NOT NULL DEFAULT '',
+	contact TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'open',
+	created_at TIMESTAMP NOT NULL,
+	resolved_at TIMESTAMP);
+CREATE INDEX IF NOT EXISTS idx_report_status ON report(status, id);
+CREATE INDEX IF NOT EXISTS idx_report_alias ON report(alias, status);
+CREATE TABLE IF NOT EXISTS banned_host(
+	host TEXT PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL);
@@ -0,0 +1,4 @@
+-- Результат проверки назначения ссылки: причина, пустая строка - ссылка жива,
+-- и время, когда назначение впервые оказалось недоступным.
+ALTER TABLE url ADD COLUMN dead_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE url ADD COLUMN dead_since TIMESTAMP;
@@ -0,0 +1,33 @@
+-- Архив ссылок, по которым давно не переходили: горячая таблица url остается
+-- небольшой, а ссылка возвращается из архива при первом обращении к ней.
+-- Идентификатор ссылки при возврате из архива меняется.
+ALTER TABLE url ADD COLUMN last_hit_at TIMESTAMP;
+CREATE TABLE IF NOT EXISTS url_archive(
+	id INTEGER PRIMARY KEY,
+	alias TEXT NOT NULL UNIQUE,
+	url TEXT NOT NULL,
+	expires_at TIMESTAMP,
+	created_at TIMESTAMP,
+	updated_at TIMESTAMP,
+	version INTEGER NOT NULL,
+	hits INTEGER NOT NULL,
+	api_key_id INTEGER,
+	user_id INTEGER,
+	redirect_code INTEGER NOT NULL,
+	quarantine_reason TEXT NOT NULL,
+	max_clicks INTEGER NOT NULL,
+	exhausted_at TIMESTAMP,
+	webhook_url TEXT NOT NULL,
+	domain TEXT NOT NULL,
+	geo_targets TEXT NOT NULL,
+	device_targets TEXT NOT NULL,
+	split TEXT NOT NULL,
+	query_params TEXT NOT NULL,
+	pass_query INTEGER NOT NULL,
+	tags TEXT NOT NULL,
+	description TEXT NOT NULL,
+	dead_reason TEXT NOT NULL,
+	dead_since TIMESTAMP,
+	last_hit_at TIMESTAMP,
+	archived_at TIMESTAMP NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_url_archive_expires_at ON url_archive(expires_at);
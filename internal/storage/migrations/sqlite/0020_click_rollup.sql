@@ -0,0 +1,11 @@
+-- Переходы по часам (bucket - 'YYYY-MM-DD HH' в UTC) для графиков за любой период.
+-- rolled_until - начало первого часа, который еще не свернут.
+CREATE TABLE IF NOT EXISTS click_rollup(
+	alias TEXT NOT NULL,
+	bucket TEXT NOT NULL,
+	clicks INTEGER NOT NULL,
+	PRIMARY KEY(alias, bucket));
+CREATE TABLE IF NOT EXISTS click_rollup_state(
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	rolled_until TIMESTAMP);
+INSERT OR IGNORE INTO click_rollup_state(id, rolled_until) VALUES (1, NULL);
@@ -0,0 +1,7 @@
+-- Уникальные посетители ссылки за день (day - 'YYYY-MM-DD' в UTC): HyperLogLog
+-- по хешам адреса и user agent. Сами адреса и хеши не хранятся.
+CREATE TABLE IF NOT EXISTS click_unique(
+	alias TEXT NOT NULL,
+	day TEXT NOT NULL,
+	sketch BLOB NOT NULL,
+	PRIMARY KEY(alias, day));
@@ -0,0 +1,4 @@
+-- Место посетителя по базе GeoIP: код страны и город. Определяется до
+-- анонимизации адреса, сам адрес для этого не хранится.
+ALTER TABLE click_event ADD COLUMN country TEXT NOT NULL DEFAULT '';
+ALTER TABLE click_event ADD COLUMN city TEXT NOT NULL DEFAULT '';
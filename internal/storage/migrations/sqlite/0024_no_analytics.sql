@@ -0,0 +1,3 @@
+-- Ссылки, переходы по которым не попадают в статистику: считается только hits.
+ALTER TABLE url ADD COLUMN no_analytics INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE url_archive ADD COLUMN no_analytics INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,17 @@
+-- Вебхуки, подписанные на события ссылок, и журнал доставок.
+CREATE TABLE IF NOT EXISTS webhook(
+	id INTEGER PRIMARY KEY,
+	url TEXT NOT NULL,
+	events TEXT NOT NULL DEFAULT '',
+	secret TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL);
+CREATE TABLE IF NOT EXISTS webhook_delivery(
+	id INTEGER PRIMARY KEY,
+	webhook_id INTEGER NOT NULL,
+	event TEXT NOT NULL,
+	alias TEXT NOT NULL DEFAULT '',
+	attempts INTEGER NOT NULL,
+	status_code INTEGER NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_webhook ON webhook_delivery(webhook_id, id);
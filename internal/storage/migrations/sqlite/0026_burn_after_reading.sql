@@ -0,0 +1,3 @@
+-- Одноразовые ссылки: первый переход удаляет ссылку.
+ALTER TABLE url ADD COLUMN burn_after_reading INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE url_archive ADD COLUMN burn_after_reading INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,1982 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib" // registers "pgx" driver for database/sql
+
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/migrations"
+)
+
+// uniqueViolation is the PostgreSQL error code for unique constraint violations.
+const uniqueViolation = "23505"
+
+type Storage struct {
+	db *sql.DB
+}
+
+// Options holds connection pool settings.
+type Options struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+func New(dsn string, opts Options) (*Storage, error) {
+	const op = "storage.postgres.New"
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := migrations.Up(db, migrations.DialectPostgres); err != nil {
+		_ = db.Close()
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Storage{db: db}, nil
+}
+
+func (s *Storage) SaveURL(ctx context.Context, u storage.URL) (int64, error) {
+	const op = "storage.postgres.SaveURL"
+
+	var expiresAt sql.NullTime
+	if !u.ExpiresAt.IsZero() {
+		expiresAt = sql.NullTime{Time: u.ExpiresAt, Valid: true}
+	}
+
+	var id int64
+
+	err := s.db.QueryRowContext(ctx,
+		"INSERT INTO url(url, alias, expires_at, api_key_id, user_id, redirect_code, max_clicks, webhook_url, domain, "+
+			"geo_targets, device_targets, split, query_params, pass_query, no_analytics, burn_after_reading, tags, description) "+
+			// Алиас архивной ссылки занят, хотя ее нет в url
+			"SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18 "+
+			"WHERE NOT EXISTS (SELECT 1 FROM url_archive WHERE alias = $2) RETURNING id",
+		u.URL, u.Alias, expiresAt, nullID(u.APIKeyID), nullID(u.UserID), u.RedirectCode, u.MaxClicks, u.WebhookURL, u.Domain,
+		u.GeoTargets, u.DeviceTargets, u.Split, u.QueryParams, u.PassQuery, u.NoAnalytics, u.BurnAfterReading, u.Tags, u.Description,
+	).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation || errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrURLExists)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.URL) ([]int64, error) {
+	const op = "storage.postgres.SaveURLs"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx,
+		"INSERT INTO url(url, alias, expires_at, api_key_id, user_id, redirect_code, max_clicks, webhook_url, domain, "+
+			"geo_targets, device_targets, split, query_params, pass_query, no_analytics, burn_after_reading, tags, description) "+
+			"SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18 "+
+			"WHERE NOT EXISTS (SELECT 1 FROM url_archive WHERE alias = $2) ON CONFLICT (alias) DO NOTHING RETURNING id",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: prepare statement: %w", op, err)
+	}
+	defer stmt.Close()
+
+	ids := make([]int64, len(urls))
+
+	for i, u := range urls {
+		var expiresAt sql.NullTime
+		if !u.ExpiresAt.IsZero() {
+			expiresAt = sql.NullTime{Time: u.ExpiresAt, Valid: true}
+		}
+
+		err := stmt.QueryRowContext(ctx,
+			u.URL, u.Alias, expiresAt, nullID(u.APIKeyID), nullID(u.UserID), u.RedirectCode, u.MaxClicks, u.WebhookURL, u.Domain,
+			u.GeoTargets, u.DeviceTargets, u.Split, u.QueryParams, u.PassQuery, u.NoAnalytics, u.BurnAfterReading, u.Tags, u.Description,
+		).Scan(&ids[i])
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return ids, nil
+}
+
+func (s *Storage) GetURL(ctx context.Context, alias string) (storage.URL, error) {
+	const op = "storage.postgres.GetURL"
+
+	var (
+		u           = storage.URL{Alias: alias}
+		expiresAt   sql.NullTime
+		exhaustedAt sql.NullTime
+	)
+
+	scan := func() error {
+		return s.db.QueryRowContext(ctx,
+			"SELECT url, expires_at, redirect_code, quarantine_reason, max_clicks, exhausted_at, webhook_url, domain, geo_targets, "+
+				"device_targets, split, query_params, pass_query, no_analytics, burn_after_reading "+
+				"FROM url WHERE alias = $1 AND deleted_at IS NULL", alias,
+		).Scan(
+			&u.URL, &expiresAt, &u.RedirectCode, &u.QuarantineReason, &u.MaxClicks, &exhaustedAt, &u.WebhookURL, &u.Domain,
+			&u.GeoTargets, &u.DeviceTargets, &u.Split, &u.QueryParams, &u.PassQuery, &u.NoAnalytics, &u.BurnAfterReading,
+		)
+	}
+
+	err := scan()
+	if errors.Is(err, sql.ErrNoRows) {
+		// Ссылки из архива возвращаются в url, это медленнее обычного редиректа
+		restored, uerr := s.unarchive(ctx, alias)
+		if uerr != nil {
+			return storage.URL{}, fmt.Errorf("%s: %w", op, uerr)
+		}
+		if restored {
+			err = scan()
+		}
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.URL{}, storage.ErrURLNotFound
+		}
+
+		return storage.URL{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	if expiresAt.Valid && !time.Now().Before(expiresAt.Time) {
+		return storage.URL{}, storage.ErrURLExpired
+	}
+	if exhaustedAt.Valid {
+		return storage.URL{}, storage.ErrURLExhausted
+	}
+
+	u.ExpiresAt = expiresAt.Time
+
+	return u, nil
+}
+
+func (s *Storage) FindURL(ctx context.Context, target string, userID, apiKeyID int64) (storage.URL, error) {
+	const op = "storage.postgres.FindURL"
+
+	var alias string
+
+	err := s.db.QueryRowContext(ctx, `SELECT alias FROM url
+		WHERE url = $1 AND user_id IS NOT DISTINCT FROM $2 AND api_key_id IS NOT DISTINCT FROM $3
+		AND deleted_at IS NULL AND exhausted_at IS NULL AND (expires_at IS NULL OR expires_at > now())
+		ORDER BY id DESC LIMIT 1`,
+		target, nullID(userID), nullID(apiKeyID),
+	).Scan(&alias)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.URL{}, storage.ErrURLNotFound
+		}
+
+		return storage.URL{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return s.GetURLInfo(ctx, alias)
+}
+
+func (s *Storage) GetURLInfo(ctx context.Context, alias string) (storage.URL, error) {
+	const op = "storage.postgres.GetURLInfo"
+
+	u, err := s.getURLInfo(ctx, alias)
+	if !errors.Is(err, storage.ErrURLNotFound) {
+		return u, err
+	}
+
+	restored, err := s.unarchive(ctx, alias)
+	if err != nil {
+		return storage.URL{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if !restored {
+		return storage.URL{}, storage.ErrURLNotFound
+	}
+
+	return s.getURLInfo(ctx, alias)
+}
+
+func (s *Storage) getURLInfo(ctx context.Context, alias string) (storage.URL, error) {
+	const op = "storage.postgres.GetURLInfo"
+
+	var (
+		u           storage.URL
+		updatedAt   sql.NullTime
+		expiresAt   sql.NullTime
+		apiKeyID    sql.NullInt64
+		userID      sql.NullInt64
+		exhaustedAt sql.NullTime
+		deadSince   sql.NullTime
+	)
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, alias, url, created_at, updated_at, expires_at, version, hits, api_key_id, user_id, redirect_code,
+		quarantine_reason, max_clicks, exhausted_at, webhook_url, domain, geo_targets, device_targets, split,
+		query_params, pass_query, no_analytics, burn_after_reading, tags, description, dead_reason, dead_since,
+		page_title, page_description, page_image
+		FROM url WHERE alias = $1 AND deleted_at IS NULL`,
+		alias,
+	).Scan(
+		&u.ID, &u.Alias, &u.URL, &u.CreatedAt, &updatedAt, &expiresAt, &u.Version, &u.Hits, &apiKeyID, &userID, &u.RedirectCode,
+		&u.QuarantineReason, &u.MaxClicks, &exhaustedAt, &u.WebhookURL, &u.Domain,
+		&u.GeoTargets, &u.DeviceTargets, &u.Split, &u.QueryParams, &u.PassQuery, &u.NoAnalytics, &u.BurnAfterReading, &u.Tags, &u.Description,
+		&u.DeadReason, &deadSince, &u.Page.Title, &u.Page.Description, &u.Page.Image,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.URL{}, storage.ErrURLNotFound
+		}
+
+		return storage.URL{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	u.UpdatedAt = updatedAt.Time
+	u.ExpiresAt = expiresAt.Time
+	u.APIKeyID = apiKeyID.Int64
+	u.UserID = userID.Int64
+	u.ExhaustedAt = exhaustedAt.Time
+	u.DeadSince = deadSince.Time
+
+	return u, nil
+}
+
+func (s *Storage) UpdateURL(ctx context.Context, alias string, update storage.URLUpdate, version int64) (storage.URL, error) {
+	const op = "storage.postgres.UpdateURL"
+
+	// Архивная ссылка сначала возвращается в url
+	if _, err := s.unarchive(ctx, alias); err != nil {
+		return storage.URL{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := "UPDATE url SET updated_at = now(), version = version + 1"
+	args := []any{}
+
+	if update.URL != nil {
+		args = append(args, *update.URL)
+		// Новое назначение еще не проверялось
+		query += fmt.Sprintf(", url = $%d, dead_reason = '', dead_since = NULL, "+
+			"page_title = '', page_description = '', page_image = ''", len(args))
+	}
+	if update.ExpiresAt != nil {
+		var expiresAt sql.NullTime
+		if !update.ExpiresAt.IsZero() {
+			expiresAt = sql.NullTime{Time: *update.ExpiresAt, Valid: true}
+		}
+
+		args = append(args, expiresAt)
+		query += fmt.Sprintf(", expires_at = $%d", len(args))
+	}
+	if update.RedirectCode != nil {
+		args = append(args, *update.RedirectCode)
+		query += fmt.Sprintf(", redirect_code = $%d", len(args))
+	}
+	if update.NoAnalytics != nil {
+		args = append(args, *update.NoAnalytics)
+		query += fmt.Sprintf(", no_analytics = $%d", len(args))
+	}
+
+	args = append(args, alias)
+	query += fmt.Sprintf(" WHERE alias = $%d AND deleted_at IS NULL", len(args))
+
+	if version != 0 {
+		args = append(args, version)
+		query += fmt.Sprintf(" AND version = $%d", len(args))
+	}
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return storage.URL{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return storage.URL{}, fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	u, err := s.GetURLInfo(ctx, alias)
+	if err != nil {
+		return storage.URL{}, err
+	}
+
+	if affected == 0 {
+		return storage.URL{}, fmt.Errorf("%s: %w", op, storage.ErrURLModified)
+	}
+
+	return u, nil
+}
+
+func (s *Storage) DeleteURL(ctx context.Context, alias string) error {
+	const op = "storage.postgres.DeleteURL"
+
+	if _, err := s.unarchive(ctx, alias); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := s.db.ExecContext(ctx, "UPDATE url SET deleted_at = now() WHERE alias = $1 AND deleted_at IS NULL", alias)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) RestoreURL(ctx context.Context, alias string) error {
+	const op = "storage.postgres.RestoreURL"
+
+	res, err := s.db.ExecContext(ctx, "UPDATE url SET deleted_at = NULL WHERE alias = $1 AND deleted_at IS NOT NULL", alias)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) ConsumeClick(ctx context.Context, alias string) error {
+	const op = "storage.postgres.ConsumeClick"
+
+	// В SET используются значения до обновления, поэтому hits + 1 - новое значение
+	res, err := s.db.ExecContext(ctx, `UPDATE url SET hits = hits + 1,
+		exhausted_at = CASE WHEN hits + 1 >= max_clicks THEN now() ELSE exhausted_at END
+		WHERE alias = $1 AND deleted_at IS NULL AND max_clicks > 0 AND hits < max_clicks`,
+		alias,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrURLExhausted
+	}
+
+	return nil
+}
+
+func (s *Storage) BurnURL(ctx context.Context, alias string) error {
+	const op = "storage.postgres.BurnURL"
+
+	// Удаление с условием в одном запросе: из одновременных переходов ссылку сжигает только один
+	res, err := s.db.ExecContext(ctx, `UPDATE url SET hits = hits + 1, last_hit_at = now(), deleted_at = now()
+		WHERE alias = $1 AND deleted_at IS NULL AND burn_after_reading`,
+		alias,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) QuarantineURL(ctx context.Context, alias, reason string) error {
+	const op = "storage.postgres.QuarantineURL"
+
+	res, err := s.db.ExecContext(ctx, "UPDATE url SET quarantine_reason = $1 WHERE alias = $2 AND deleted_at IS NULL", reason, alias)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) MarkURLDead(ctx context.Context, alias, reason string) error {
+	const op = "storage.postgres.MarkURLDead"
+
+	// dead_since сохраняется, пока ссылка остается мертвой
+	res, err := s.db.ExecContext(ctx, `UPDATE url SET dead_reason = $1,
+		dead_since = CASE WHEN $1 = '' THEN NULL ELSE COALESCE(dead_since, now()) END
+		WHERE alias = $2 AND deleted_at IS NULL`,
+		reason, alias,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) SetPageMetadata(ctx context.Context, alias string, page storage.PageMetadata) error {
+	const op = "storage.postgres.SetPageMetadata"
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE url SET page_title = $1, page_description = $2, page_image = $3 WHERE alias = $4 AND deleted_at IS NULL",
+		page.Title, page.Description, page.Image, alias,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	const op = "storage.postgres.PurgeDeletedURLs"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM click_event WHERE alias IN (SELECT alias FROM url WHERE deleted_at < $1)",
+		deletedBefore,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: delete click events: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM click_rollup WHERE alias IN (SELECT alias FROM url WHERE deleted_at < $1)",
+		deletedBefore,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: delete click rollup: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM click_unique WHERE alias IN (SELECT alias FROM url WHERE deleted_at < $1)",
+		deletedBefore,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: delete unique visitors: %w", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM url WHERE deleted_at < $1", deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return affected, nil
+}
+
+func (s *Storage) PurgeClickEvents(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.postgres.PurgeClickEvents"
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM click_event WHERE created_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	return affected, nil
+}
+
+// archiveColumns are copied between url and url_archive. id is not copied, so
+// a restored link gets a new one, like with SQLite.
+const archiveColumns = "alias, url, expires_at, created_at, updated_at, version, hits, api_key_id, user_id, " +
+	"redirect_code, quarantine_reason, max_clicks, exhausted_at, webhook_url, domain, geo_targets, device_targets, " +
+	"split, query_params, pass_query, no_analytics, burn_after_reading, tags, description, dead_reason, dead_since, " +
+	"page_title, page_description, page_image, last_hit_at"
+
+// archiveBatchSize is the number of links moved to the archive by one statement.
+const archiveBatchSize = 500
+
+func (s *Storage) ArchiveURLs(ctx context.Context, inactiveBefore time.Time) (int64, error) {
+	const op = "storage.postgres.ArchiveURLs"
+
+	var (
+		archived int64
+		afterID  int64
+	)
+
+	for {
+		var n, lastID int64
+
+		// Перенос одним запросом: ссылка либо в url, либо в архиве
+		err := s.db.QueryRowContext(ctx, `WITH moved AS (
+			DELETE FROM url WHERE id IN (
+				SELECT id FROM url
+				WHERE id > $1 AND deleted_at IS NULL AND COALESCE(last_hit_at, created_at) < $2
+				AND (updated_at IS NULL OR updated_at < $2)
+				ORDER BY id LIMIT $3 FOR UPDATE SKIP LOCKED)
+			RETURNING id, `+archiveColumns+`
+		), archived AS (
+			INSERT INTO url_archive(`+archiveColumns+`, archived_at) SELECT `+archiveColumns+`, now() FROM moved
+		)
+		SELECT count(*), COALESCE(max(id), 0) FROM moved`,
+			afterID, inactiveBefore, archiveBatchSize,
+		).Scan(&n, &lastID)
+		if err != nil {
+			return archived, fmt.Errorf("%s: execute statement: %w", op, err)
+		}
+
+		archived += n
+		if lastID == 0 {
+			return archived, nil
+		}
+
+		afterID = lastID
+	}
+}
+
+// unarchive moves the link from the archive back to url and reports whether it was archived.
+func (s *Storage) unarchive(ctx context.Context, alias string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `WITH restored AS (
+		DELETE FROM url_archive WHERE alias = $1 RETURNING `+archiveColumns+`
+	)
+	INSERT INTO url(`+archiveColumns+`) SELECT `+archiveColumns+` FROM restored`,
+		alias,
+	)
+	if err != nil {
+		return false, fmt.Errorf("restore archived url: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+func (s *Storage) ListURLs(ctx context.Context, filter storage.ListFilter) ([]storage.URL, string, error) {
+	const op = "storage.postgres.ListURLs"
+
+	query := "SELECT id, alias, url, created_at, updated_at, expires_at, version, hits, api_key_id, user_id, redirect_code, " +
+		"quarantine_reason, max_clicks, exhausted_at, webhook_url, domain, geo_targets, device_targets, split, " +
+		"query_params, pass_query, no_analytics, burn_after_reading, tags, description, dead_reason, dead_since, " +
+		"page_title, page_description, page_image "
+	if filter.Archived {
+		query += "FROM url_archive WHERE true"
+	} else {
+		query += "FROM url WHERE deleted_at IS NULL"
+	}
+	var args []any
+
+	if filter.Cursor != "" {
+		afterID, err := strconv.ParseInt(filter.Cursor, 10, 64)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", op, storage.ErrInvalidCursor)
+		}
+
+		args = append(args, afterID)
+		query += fmt.Sprintf(" AND id > $%d", len(args))
+	}
+	if filter.AliasPrefix != "" {
+		args = append(args, filter.AliasPrefix)
+		query += fmt.Sprintf(" AND alias >= $%d", len(args))
+
+		if bound := storage.PrefixUpperBound(filter.AliasPrefix); bound != "" {
+			args = append(args, bound)
+			query += fmt.Sprintf(" AND alias < $%d", len(args))
+		}
+	}
+	if !filter.CreatedFrom.IsZero() {
+		args = append(args, filter.CreatedFrom)
+		query += fmt.Sprintf(" AND created_at >= $%d", len(args))
+	}
+	if !filter.CreatedTo.IsZero() {
+		args = append(args, filter.CreatedTo)
+		query += fmt.Sprintf(" AND created_at < $%d", len(args))
+	}
+	if filter.UserID != 0 {
+		args = append(args, filter.UserID)
+		query += fmt.Sprintf(" AND user_id = $%d", len(args))
+	}
+	if filter.APIKeyID != 0 {
+		args = append(args, filter.APIKeyID)
+		query += fmt.Sprintf(" AND api_key_id = $%d", len(args))
+	}
+	if filter.Tag != "" {
+		// Выражение совпадает с выражением индекса idx_tags
+		args = append(args, filter.Tag)
+		query += fmt.Sprintf(" AND NULLIF(tags, '')::jsonb @> jsonb_build_array($%d::text)", len(args))
+	}
+	if filter.Query != "" {
+		args = append(args, storage.LikePattern(filter.Query))
+		query += fmt.Sprintf(" AND (alias ILIKE $%[1]d OR url ILIKE $%[1]d OR description ILIKE $%[1]d)", len(args))
+	}
+	if filter.Dead {
+		query += " AND dead_reason <> ''"
+	}
+
+	args = append(args, filter.Limit+1)
+	query += fmt.Sprintf(" ORDER BY id LIMIT $%d", len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	var urls []storage.URL
+
+	for rows.Next() {
+		var (
+			u           storage.URL
+			updatedAt   sql.NullTime
+			expiresAt   sql.NullTime
+			apiKeyID    sql.NullInt64
+			userID      sql.NullInt64
+			exhaustedAt sql.NullTime
+			deadSince   sql.NullTime
+		)
+
+		err := rows.Scan(
+			&u.ID, &u.Alias, &u.URL, &u.CreatedAt, &updatedAt, &expiresAt, &u.Version, &u.Hits, &apiKeyID, &userID, &u.RedirectCode,
+			&u.QuarantineReason, &u.MaxClicks, &exhaustedAt, &u.WebhookURL, &u.Domain,
+			&u.GeoTargets, &u.DeviceTargets, &u.Split, &u.QueryParams, &u.PassQuery, &u.NoAnalytics, &u.BurnAfterReading, &u.Tags, &u.Description,
+			&u.DeadReason, &deadSince, &u.Page.Title, &u.Page.Description, &u.Page.Image,
+		)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: scan row: %w", op, err)
+		}
+
+		u.UpdatedAt = updatedAt.Time
+		u.ExpiresAt = expiresAt.Time
+		u.APIKeyID = apiKeyID.Int64
+		u.UserID = userID.Int64
+		u.ExhaustedAt = exhaustedAt.Time
+		u.DeadSince = deadSince.Time
+
+		urls = append(urls, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var next string
+	if len(urls) > filter.Limit {
+		urls = urls[:filter.Limit]
+		next = strconv.FormatInt(urls[len(urls)-1].ID, 10)
+	}
+
+	return urls, next, nil
+}
+
+func (s *Storage) IncrementHits(ctx context.Context, hits map[string]int64) error {
+	const op = "storage.postgres.IncrementHits"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx, "UPDATE url SET hits = hits + $1, last_hit_at = now() WHERE alias = $2")
+	if err != nil {
+		return fmt.Errorf("%s: prepare statement: %w", op, err)
+	}
+	defer stmt.Close()
+
+	for alias, n := range hits {
+		if _, err := stmt.ExecContext(ctx, n, alias); err != nil {
+			return fmt.Errorf("%s: execute statement: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveClickEvents(ctx context.Context, events []storage.ClickEvent) error {
+	const op = "storage.postgres.SaveClickEvents"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx,
+		"INSERT INTO click_event(alias, created_at, referrer, user_agent, browser, ip, variant, "+
+			"referrer_host, utm_source, utm_medium, utm_campaign, country, city) "+
+			"VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)",
+	)
+	if err != nil {
+		return fmt.Errorf("%s: prepare statement: %w", op, err)
+	}
+	defer stmt.Close()
+
+	for _, e := range events {
+		if _, err := stmt.ExecContext(ctx, e.Alias, e.Time, e.Referrer, e.UserAgent, e.Browser, e.IP, e.Variant,
+			e.ReferrerHost, e.UTMSource, e.UTMMedium, e.UTMCampaign, e.Country, e.City); err != nil {
+			return fmt.Errorf("%s: execute statement: %w", op, err)
+		}
+	}
+
+	for _, v := range storage.VisitorSketches(events) {
+		if err := mergeVisitors(ctx, tx, v); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+// mergeVisitors adds visitors to the sketch of the day. The row is locked, so
+// concurrent instances don't lose each other's visitors.
+func mergeVisitors(ctx context.Context, tx *sql.Tx, v storage.VisitorSketch) error {
+	sketch, err := v.Sketch.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("encode visitors: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO click_unique(alias, day, sketch) VALUES($1, $2, $3)
+		ON CONFLICT (alias, day) DO NOTHING`, v.Alias, v.Day, sketch)
+	if err != nil {
+		return fmt.Errorf("save visitors: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("get rows affected: %w", err)
+	}
+	// Первые посетители дня уже сохранены
+	if n > 0 {
+		return nil
+	}
+
+	err = tx.QueryRowContext(ctx, "SELECT sketch FROM click_unique WHERE alias = $1 AND day = $2 FOR UPDATE", v.Alias, v.Day).
+		Scan(&sketch)
+	if err != nil {
+		return fmt.Errorf("get visitors: %w", err)
+	}
+
+	sketch, err = storage.MergeSketch(sketch, v.Sketch)
+	if err != nil {
+		return fmt.Errorf("merge visitors: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE click_unique SET sketch = $1 WHERE alias = $2 AND day = $3", sketch, v.Alias, v.Day)
+	if err != nil {
+		return fmt.Errorf("save visitors: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetClickStats(ctx context.Context, alias string) (storage.ClickStats, error) {
+	const op = "storage.postgres.GetClickStats"
+
+	var exists bool
+
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM url WHERE alias = $1 AND deleted_at IS NULL)", alias).Scan(&exists)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	if !exists {
+		return storage.ClickStats{}, storage.ErrURLNotFound
+	}
+
+	var stats storage.ClickStats
+
+	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM click_event WHERE alias = $1", alias).Scan(&stats.Total)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks: %w", op, err)
+	}
+
+	stats.ByDay, err = s.countClicks(ctx, `SELECT to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS k, COUNT(*)
+		FROM click_event WHERE alias = $1 GROUP BY k ORDER BY k`, alias)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by day: %w", op, err)
+	}
+
+	stats.ByReferrer, err = s.countClicks(ctx, `SELECT CASE referrer WHEN '' THEN $1 ELSE referrer END AS k, COUNT(*) AS c
+		FROM click_event WHERE alias = $2 GROUP BY k ORDER BY c DESC, k LIMIT $3`,
+		storage.DirectReferrer, alias, storage.StatsTopLimit)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by referrer: %w", op, err)
+	}
+
+	stats.ByBrowser, err = s.countClicks(ctx, `SELECT browser AS k, COUNT(*) AS c FROM click_event
+		WHERE alias = $1 GROUP BY k ORDER BY c DESC, k LIMIT $2`, alias, storage.StatsTopLimit)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by browser: %w", op, err)
+	}
+
+	stats.ByVariant, err = s.countClicks(ctx, `SELECT variant AS k, COUNT(*) FROM click_event
+		WHERE alias = $1 AND variant <> '' GROUP BY k ORDER BY k`, alias)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by variant: %w", op, err)
+	}
+
+	// Старые переходы с Referer, но без хоста, не попадают ни в один сайт
+	stats.ByReferrerHost, err = s.countClicks(ctx, `SELECT CASE referrer WHEN '' THEN $1 ELSE referrer_host END AS k, COUNT(*) AS c
+		FROM click_event WHERE alias = $2 AND (referrer = '' OR referrer_host <> '') GROUP BY k ORDER BY c DESC, k LIMIT $3`,
+		storage.DirectReferrer, alias, storage.StatsTopLimit)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by referrer host: %w", op, err)
+	}
+
+	stats.BySource, err = s.countClicks(ctx, `SELECT utm_source AS k, COUNT(*) AS c FROM click_event
+		WHERE alias = $1 AND utm_source <> '' GROUP BY k ORDER BY c DESC, k LIMIT $2`, alias, storage.StatsTopLimit)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by source: %w", op, err)
+	}
+
+	stats.ByMedium, err = s.countClicks(ctx, `SELECT utm_medium AS k, COUNT(*) AS c FROM click_event
+		WHERE alias = $1 AND utm_medium <> '' GROUP BY k ORDER BY c DESC, k LIMIT $2`, alias, storage.StatsTopLimit)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by medium: %w", op, err)
+	}
+
+	stats.ByCampaign, err = s.countClicks(ctx, `SELECT utm_campaign AS k, COUNT(*) AS c FROM click_event
+		WHERE alias = $1 AND utm_campaign <> '' GROUP BY k ORDER BY c DESC, k LIMIT $2`, alias, storage.StatsTopLimit)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by campaign: %w", op, err)
+	}
+
+	stats.ByCountry, err = s.countClicks(ctx, `SELECT country AS k, COUNT(*) AS c FROM click_event
+		WHERE alias = $1 AND country <> '' GROUP BY k ORDER BY c DESC, k LIMIT $2`, alias, storage.StatsTopLimit)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by country: %w", op, err)
+	}
+
+	// Ключ совпадает с storage.CityKey
+	stats.ByCity, err = s.countClicks(ctx, `SELECT city || ', ' || country AS k, COUNT(*) AS c FROM click_event
+		WHERE alias = $1 AND city <> '' GROUP BY k ORDER BY c DESC, k LIMIT $2`, alias, storage.StatsTopLimit)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by city: %w", op, err)
+	}
+
+	stats.Uniques, stats.UniquesByDay, err = s.countUniques(ctx, alias)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count uniques: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+// countUniques estimates visitors of the link from the sketches of its days.
+func (s *Storage) countUniques(ctx context.Context, alias string) (int64, []storage.Count, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT day, sketch FROM click_unique WHERE alias = $1", alias)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var days []storage.DaySketch
+
+	for rows.Next() {
+		var d storage.DaySketch
+		if err := rows.Scan(&d.Day, &d.Sketch); err != nil {
+			return 0, nil, err
+		}
+
+		days = append(days, d)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	return storage.CountUniques(days)
+}
+
+// countClicks runs query returning (key, count) rows.
+func (s *Storage) countClicks(ctx context.Context, query string, args ...any) ([]storage.Count, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []storage.Count
+
+	for rows.Next() {
+		var c storage.Count
+		if err := rows.Scan(&c.Key, &c.Count); err != nil {
+			return nil, err
+		}
+
+		counts = append(counts, c)
+	}
+
+	return counts, rows.Err()
+}
+
+// GetHourlyClicks reads the rolled up hours from click_rollup and counts the rest of
+// the range from click_event. from and to are truncated to the hour.
+func (s *Storage) GetHourlyClicks(ctx context.Context, alias string, from, to time.Time) ([]storage.ClickBucket, error) {
+	const op = "storage.postgres.GetHourlyClicks"
+
+	var exists bool
+
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM url WHERE alias = $1 AND deleted_at IS NULL)", alias).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	if !exists {
+		return nil, storage.ErrURLNotFound
+	}
+
+	var rolledUntil sql.NullTime
+
+	err = s.db.QueryRowContext(ctx, "SELECT rolled_until FROM click_rollup_state WHERE id = 1").Scan(&rolledUntil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: get rollup state: %w", op, err)
+	}
+
+	from, to = from.UTC().Truncate(time.Hour), to.UTC().Truncate(time.Hour)
+	split := rolledUntil.Time.UTC()
+
+	byHour := make(map[time.Time]int64)
+
+	if rollupTo := minTime(to, split); from.Before(rollupTo) {
+		err := s.countBuckets(ctx, byHour, "SELECT bucket, clicks FROM click_rollup WHERE alias = $1 AND bucket >= $2 AND bucket < $3",
+			alias, from, rollupTo)
+		if err != nil {
+			return nil, fmt.Errorf("%s: read rollup: %w", op, err)
+		}
+	}
+
+	// Часы, которые еще не свернуты, считаются по самим событиям
+	if rawFrom := maxTime(from, split); rawFrom.Before(to) {
+		err := s.countBuckets(ctx, byHour, `SELECT date_trunc('hour', created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS b, COUNT(*)
+			FROM click_event WHERE alias = $1 AND created_at >= $2 AND created_at < $3 GROUP BY b`, alias, rawFrom, to)
+		if err != nil {
+			return nil, fmt.Errorf("%s: count clicks: %w", op, err)
+		}
+	}
+
+	return storage.SortedBuckets(byHour), nil
+}
+
+func (s *Storage) ExportClickEvents(ctx context.Context, filter storage.ClickFilter, fn func(storage.ClickEvent) error) error {
+	const op = "storage.postgres.ExportClickEvents"
+
+	query := `SELECT alias, created_at, referrer, referrer_host, user_agent, browser, ip, variant,
+		utm_source, utm_medium, utm_campaign, country, city FROM click_event WHERE created_at >= $1 AND created_at < $2`
+	args := []any{filter.From, filter.To}
+
+	if filter.Alias != "" {
+		var exists bool
+
+		err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM url WHERE alias = $1 AND deleted_at IS NULL)",
+			filter.Alias).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("%s: execute statement: %w", op, err)
+		}
+		if !exists {
+			return storage.ErrURLNotFound
+		}
+
+		query += " AND alias = $3"
+		args = append(args, filter.Alias)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query+" ORDER BY created_at, id", args...)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e storage.ClickEvent
+		if err := rows.Scan(&e.Alias, &e.Time, &e.Referrer, &e.ReferrerHost, &e.UserAgent, &e.Browser, &e.IP, &e.Variant,
+			&e.UTMSource, &e.UTMMedium, &e.UTMCampaign, &e.Country, &e.City); err != nil {
+			return fmt.Errorf("%s: scan row: %w", op, err)
+		}
+
+		e.Time = e.Time.UTC()
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetDailyClicks, like GetHourlyClicks, reads the rolled up hours from click_rollup
+// and counts the rest of the range from click_event.
+func (s *Storage) GetDailyClicks(ctx context.Context, from, to time.Time) ([]storage.DailyClicks, error) {
+	const op = "storage.postgres.GetDailyClicks"
+
+	var rolledUntil sql.NullTime
+
+	err := s.db.QueryRowContext(ctx, "SELECT rolled_until FROM click_rollup_state WHERE id = 1").Scan(&rolledUntil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: get rollup state: %w", op, err)
+	}
+
+	from, to = from.UTC().Truncate(24*time.Hour), to.UTC().Truncate(24*time.Hour)
+	split := rolledUntil.Time.UTC()
+
+	var days []storage.DailyClicks
+
+	if rollupTo := minTime(to, split); from.Before(rollupTo) {
+		rolled, err := s.countDays(ctx, `SELECT alias, to_char(bucket AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS d, SUM(clicks)
+			FROM click_rollup WHERE bucket >= $1 AND bucket < $2 GROUP BY alias, d`, from, rollupTo)
+		if err != nil {
+			return nil, fmt.Errorf("%s: read rollup: %w", op, err)
+		}
+
+		days = append(days, rolled...)
+	}
+
+	if rawFrom := maxTime(from, split); rawFrom.Before(to) {
+		raw, err := s.countDays(ctx, `SELECT alias, to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS d, COUNT(*)
+			FROM click_event WHERE created_at >= $1 AND created_at < $2 GROUP BY alias, d`, rawFrom, to)
+		if err != nil {
+			return nil, fmt.Errorf("%s: count clicks: %w", op, err)
+		}
+
+		days = append(days, raw...)
+	}
+
+	return storage.MergeDailyClicks(days), nil
+}
+
+// countDays runs query returning (alias, day, count) rows.
+func (s *Storage) countDays(ctx context.Context, query string, args ...any) ([]storage.DailyClicks, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []storage.DailyClicks
+
+	for rows.Next() {
+		var d storage.DailyClicks
+		if err := rows.Scan(&d.Alias, &d.Day, &d.Clicks); err != nil {
+			return nil, err
+		}
+
+		days = append(days, d)
+	}
+
+	return days, rows.Err()
+}
+
+// countBuckets runs query returning (hour, count) rows and adds them to byHour.
+func (s *Storage) countBuckets(ctx context.Context, byHour map[time.Time]int64, query string, args ...any) error {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			start  time.Time
+			clicks int64
+		)
+		if err := rows.Scan(&start, &clicks); err != nil {
+			return err
+		}
+
+		byHour[start.UTC()] += clicks
+	}
+
+	return rows.Err()
+}
+
+// rollupChunk is the longest period rolled up in one transaction.
+const rollupChunk = 24 * time.Hour
+
+func (s *Storage) RollupClicks(ctx context.Context, until time.Time) (int64, error) {
+	const op = "storage.postgres.RollupClicks"
+
+	until = until.UTC().Truncate(time.Hour)
+
+	var total int64
+
+	for {
+		n, done, err := s.rollupChunk(ctx, until)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("%s: %w", op, err)
+		}
+		if done {
+			return total, nil
+		}
+	}
+}
+
+// rollupChunk rolls up clicks of at most rollupChunk after the last rolled up hour.
+// done is true once everything before until is rolled up.
+func (s *Storage) rollupChunk(ctx context.Context, until time.Time) (n int64, done bool, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var rolledUntil sql.NullTime
+
+	// Блокировка строки не дает двум экземплярам свернуть одни и те же часы
+	err = tx.QueryRowContext(ctx, "SELECT rolled_until FROM click_rollup_state WHERE id = 1 FOR UPDATE").Scan(&rolledUntil)
+	if err != nil {
+		return 0, false, fmt.Errorf("get rollup state: %w", err)
+	}
+
+	// Периоды без переходов пропускаем сразу до следующего перехода
+	var next time.Time
+
+	err = tx.QueryRowContext(ctx, "SELECT created_at FROM click_event WHERE created_at >= $1 ORDER BY created_at LIMIT 1",
+		rolledUntil.Time.UTC()).Scan(&next)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, false, fmt.Errorf("find next click: %w", err)
+	}
+
+	from := next.UTC().Truncate(time.Hour)
+	to := minTime(from.Add(rollupChunk), until)
+
+	if errors.Is(err, sql.ErrNoRows) || !from.Before(until) {
+		to = until
+	} else {
+		res, err := tx.ExecContext(ctx, `INSERT INTO click_rollup(alias, bucket, clicks)
+			SELECT alias, date_trunc('hour', created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS b, COUNT(*)
+			FROM click_event WHERE created_at >= $1 AND created_at < $2 GROUP BY alias, b
+			ON CONFLICT (alias, bucket) DO UPDATE SET clicks = click_rollup.clicks + EXCLUDED.clicks`, from, to)
+		if err != nil {
+			return 0, false, fmt.Errorf("roll up clicks: %w", err)
+		}
+
+		if n, err = res.RowsAffected(); err != nil {
+			return 0, false, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+	}
+
+	// Свернутое не откатываем, даже если часы сервера ушли назад
+	if rolledUntil.Valid && !to.After(rolledUntil.Time) {
+		return n, true, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE click_rollup_state SET rolled_until = $1 WHERE id = 1", to); err != nil {
+		return 0, false, fmt.Errorf("update rollup state: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, false, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return n, !to.Before(until), nil
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
+
+// GetServiceStats counts clicks of the windows in one pass over the last 30 days of
+// click_event, which is read by the created_at index.
+func (s *Storage) GetServiceStats(ctx context.Context, now time.Time) (storage.ServiceStats, error) {
+	const op = "storage.postgres.GetServiceStats"
+
+	day, week, month := storage.StatsWindows(now)
+
+	var stats storage.ServiceStats
+
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM url WHERE deleted_at IS NULL").Scan(&stats.Links)
+	if err != nil {
+		return storage.ServiceStats{}, fmt.Errorf("%s: count links: %w", op, err)
+	}
+
+	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) FILTER (WHERE created_at >= $1), COUNT(*) FILTER (WHERE created_at >= $2), COUNT(*)
+		FROM click_event WHERE created_at >= $3`, day, week, month).
+		Scan(&stats.ClicksToday, &stats.Clicks7d, &stats.Clicks30d)
+	if err != nil {
+		return storage.ServiceStats{}, fmt.Errorf("%s: count clicks: %w", op, err)
+	}
+
+	stats.TopLinks, err = s.countClicks(ctx, `SELECT alias, hits FROM url
+		WHERE deleted_at IS NULL AND hits > 0 ORDER BY hits DESC, alias LIMIT $1`, storage.StatsTopLimit)
+	if err != nil {
+		return storage.ServiceStats{}, fmt.Errorf("%s: top links: %w", op, err)
+	}
+
+	stats.CreatedByDay, err = s.countClicks(ctx, `SELECT to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS k, COUNT(*)
+		FROM url WHERE deleted_at IS NULL AND created_at >= $1 GROUP BY k ORDER BY k`, month.Truncate(24*time.Hour))
+	if err != nil {
+		return storage.ServiceStats{}, fmt.Errorf("%s: count links by day: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+func (s *Storage) DeleteExpiredURLs(ctx context.Context) ([]string, error) {
+	const op = "storage.postgres.DeleteExpiredURLs"
+
+	var aliases []string
+
+	// Архивные ссылки истекают так же, как активные
+	for _, table := range []string{"url", "url_archive"} {
+		rows, err := s.db.QueryContext(ctx, "DELETE FROM "+table+" WHERE expires_at <= now() RETURNING alias")
+		if err != nil {
+			return aliases, fmt.Errorf("%s: execute statement: %w", op, err)
+		}
+
+		for rows.Next() {
+			var alias string
+			if err := rows.Scan(&alias); err != nil {
+				rows.Close()
+
+				return aliases, fmt.Errorf("%s: scan row: %w", op, err)
+			}
+
+			aliases = append(aliases, alias)
+		}
+
+		err = rows.Err()
+		rows.Close()
+
+		if err != nil {
+			return aliases, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return aliases, nil
+}
+
+func (s *Storage) SaveAPIKey(ctx context.Context, key storage.APIKey) (int64, error) {
+	const op = "storage.postgres.SaveAPIKey"
+
+	var id int64
+
+	err := s.db.QueryRowContext(ctx,
+		"INSERT INTO api_key(name, hash, tenant, role, signing_secret) VALUES($1, $2, $3, $4, $5) RETURNING id",
+		key.Name, key.Hash, key.Tenant, key.Role, key.SigningSecret,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetAPIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error) {
+	const op = "storage.postgres.GetAPIKeyByHash"
+
+	return s.getAPIKey(ctx, op, "hash = $1", hash)
+}
+
+func (s *Storage) GetAPIKey(ctx context.Context, id int64) (storage.APIKey, error) {
+	const op = "storage.postgres.GetAPIKey"
+
+	return s.getAPIKey(ctx, op, "id = $1", id)
+}
+
+// getAPIKey returns the key matching the condition with one argument.
+func (s *Storage) getAPIKey(ctx context.Context, op, cond string, arg any) (storage.APIKey, error) {
+	var (
+		key       storage.APIKey
+		revokedAt sql.NullTime
+	)
+
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id, name, hash, tenant, role, signing_secret, created_at, revoked_at FROM api_key WHERE "+cond, arg,
+	).Scan(&key.ID, &key.Name, &key.Hash, &key.Tenant, &key.Role, &key.SigningSecret, &key.CreatedAt, &revokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.APIKey{}, storage.ErrAPIKeyNotFound
+		}
+
+		return storage.APIKey{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	key.RevokedAt = revokedAt.Time
+
+	return key, nil
+}
+
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64) error {
+	const op = "storage.postgres.RevokeAPIKey"
+
+	res, err := s.db.ExecContext(ctx, "UPDATE api_key SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL", id)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveUser(ctx context.Context, user storage.User) (int64, error) {
+	const op = "storage.postgres.SaveUser"
+
+	var (
+		id         int64
+		verifiedAt sql.NullTime
+	)
+	if !user.VerifiedAt.IsZero() {
+		verifiedAt = sql.NullTime{Time: user.VerifiedAt, Valid: true}
+	}
+
+	err := s.db.QueryRowContext(ctx,
+		"INSERT INTO users(email, pass_hash, tenant, role, verified_at) VALUES($1, $2, $3, $4, $5) RETURNING id",
+		user.Email, user.PassHash, user.Tenant, user.Role, verifiedAt,
+	).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetUser(ctx context.Context, email string) (storage.User, error) {
+	const op = "storage.postgres.GetUser"
+
+	var (
+		user       storage.User
+		verifiedAt sql.NullTime
+	)
+
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id, email, pass_hash, tenant, role, created_at, verified_at FROM users WHERE email = $1", email,
+	).Scan(&user.ID, &user.Email, &user.PassHash, &user.Tenant, &user.Role, &user.CreatedAt, &verifiedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.User{}, storage.ErrUserNotFound
+		}
+
+		return storage.User{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	user.VerifiedAt = verifiedAt.Time
+
+	return user, nil
+}
+
+func (s *Storage) SetUserRole(ctx context.Context, email, role string) error {
+	const op = "storage.postgres.SetUserRole"
+
+	res, err := s.db.ExecContext(ctx, "UPDATE users SET role = $1 WHERE email = $2", role, email)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) VerifyUser(ctx context.Context, email string) error {
+	const op = "storage.postgres.VerifyUser"
+
+	res, err := s.db.ExecContext(ctx, "UPDATE users SET verified_at = COALESCE(verified_at, now()) WHERE email = $1", email)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) SetUserPassword(ctx context.Context, email string, passHash []byte) error {
+	const op = "storage.postgres.SetUserPassword"
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE users SET pass_hash = $1, verified_at = COALESCE(verified_at, now()) WHERE email = $2", passHash, email,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SaveUserToken saves the token and removes expired ones, so unused tokens don't pile up.
+func (s *Storage) SaveUserToken(ctx context.Context, token storage.UserToken) error {
+	const op = "storage.postgres.SaveUserToken"
+
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM user_token WHERE expires_at <= now()"); err != nil {
+		return fmt.Errorf("%s: failed to delete expired tokens: %w", op, err)
+	}
+
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO user_token(hash, email, purpose, expires_at) VALUES($1, $2, $3, $4)",
+		token.Hash, token.Email, token.Purpose, token.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ConsumeUserToken(ctx context.Context, hash, purpose string) (storage.UserToken, error) {
+	const op = "storage.postgres.ConsumeUserToken"
+
+	token := storage.UserToken{Hash: hash, Purpose: purpose}
+
+	// Токен удаляется в том же запросе, которым читается, поэтому использовать его можно один раз
+	err := s.db.QueryRowContext(ctx,
+		"DELETE FROM user_token WHERE hash = $1 AND purpose = $2 RETURNING email, expires_at", hash, purpose,
+	).Scan(&token.Email, &token.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.UserToken{}, storage.ErrTokenNotFound
+		}
+
+		return storage.UserToken{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	if !token.ExpiresAt.After(time.Now()) {
+		return storage.UserToken{}, storage.ErrTokenNotFound
+	}
+
+	return token, nil
+}
+
+func (s *Storage) SaveDomain(ctx context.Context, domain storage.Domain) error {
+	const op = "storage.postgres.SaveDomain"
+
+	_, err := s.db.ExecContext(ctx, "INSERT INTO domain(host) VALUES($1)", domain.Host)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return fmt.Errorf("%s: %w", op, storage.ErrDomainExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetDomain(ctx context.Context, host string) (storage.Domain, error) {
+	const op = "storage.postgres.GetDomain"
+
+	var domain storage.Domain
+
+	err := s.db.QueryRowContext(ctx, "SELECT host, created_at FROM domain WHERE host = $1", host).Scan(&domain.Host, &domain.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.Domain{}, storage.ErrDomainNotFound
+		}
+
+		return storage.Domain{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return domain, nil
+}
+
+func (s *Storage) ListDomains(ctx context.Context) ([]storage.Domain, error) {
+	const op = "storage.postgres.ListDomains"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT host, created_at FROM domain ORDER BY host")
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	var domains []storage.Domain
+	for rows.Next() {
+		var domain storage.Domain
+		if err := rows.Scan(&domain.Host, &domain.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+
+		domains = append(domains, domain)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return domains, nil
+}
+
+func (s *Storage) DeleteDomain(ctx context.Context, host string) error {
+	const op = "storage.postgres.DeleteDomain"
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM domain WHERE host = $1", host)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrDomainNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveReport(ctx context.Context, report storage.Report) (int64, error) {
+	const op = "storage.postgres.SaveReport"
+
+	var id int64
+
+	err := s.db.QueryRowContext(ctx,
+		"INSERT INTO report(alias, category, details, contact, status) VALUES($1, $2, $3, $4, $5) RETURNING id",
+		report.Alias, report.Category, report.Details, report.Contact, storage.ReportOpen,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) ListReports(ctx context.Context, status string, limit int) ([]storage.Report, error) {
+	const op = "storage.postgres.ListReports"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, alias, category, details, contact, status, created_at, resolved_at
+		FROM report
+		WHERE $1 = '' OR status = $1
+		ORDER BY id
+		LIMIT $2`,
+		status, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	var reports []storage.Report
+	for rows.Next() {
+		var (
+			report     storage.Report
+			resolvedAt sql.NullTime
+		)
+		err := rows.Scan(&report.ID, &report.Alias, &report.Category, &report.Details, &report.Contact,
+			&report.Status, &report.CreatedAt, &resolvedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+
+		report.ResolvedAt = resolvedAt.Time
+		reports = append(reports, report)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return reports, nil
+}
+
+func (s *Storage) ResolveReports(ctx context.Context, alias, status string) (int64, error) {
+	const op = "storage.postgres.ResolveReports"
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE report SET status = $1, resolved_at = now() WHERE alias = $2 AND status = $3",
+		status, alias, storage.ReportOpen,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	return affected, nil
+}
+
+func (s *Storage) BanHost(ctx context.Context, host string) error {
+	const op = "storage.postgres.BanHost"
+
+	_, err := s.db.ExecContext(ctx, "INSERT INTO banned_host(host) VALUES($1) ON CONFLICT(host) DO NOTHING", host)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) UnbanHost(ctx context.Context, host string) error {
+	const op = "storage.postgres.UnbanHost"
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM banned_host WHERE host = $1", host)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrHostNotBanned
+	}
+
+	return nil
+}
+
+func (s *Storage) ListBannedHosts(ctx context.Context) ([]storage.BannedHost, error) {
+	const op = "storage.postgres.ListBannedHosts"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT host, created_at FROM banned_host ORDER BY host")
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	var hosts []storage.BannedHost
+	for rows.Next() {
+		var host storage.BannedHost
+		if err := rows.Scan(&host.Host, &host.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+
+		hosts = append(hosts, host)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return hosts, nil
+}
+
+func (s *Storage) SaveWebhook(ctx context.Context, webhook storage.Webhook) (int64, error) {
+	const op = "storage.postgres.SaveWebhook"
+
+	var id int64
+
+	err := s.db.QueryRowContext(ctx,
+		"INSERT INTO webhook(url, events, secret) VALUES($1, $2, $3) RETURNING id",
+		webhook.URL, webhook.Events, webhook.Secret,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetWebhook(ctx context.Context, id int64) (storage.Webhook, error) {
+	const op = "storage.postgres.GetWebhook"
+
+	var webhook storage.Webhook
+
+	err := s.db.QueryRowContext(ctx, "SELECT id, url, events, secret, created_at FROM webhook WHERE id = $1", id).
+		Scan(&webhook.ID, &webhook.URL, &webhook.Events, &webhook.Secret, &webhook.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.Webhook{}, storage.ErrWebhookNotFound
+		}
+
+		return storage.Webhook{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return webhook, nil
+}
+
+func (s *Storage) ListWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	const op = "storage.postgres.ListWebhooks"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id, url, events, secret, created_at FROM webhook ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	var webhooks []storage.Webhook
+	for rows.Next() {
+		var webhook storage.Webhook
+		if err := rows.Scan(&webhook.ID, &webhook.URL, &webhook.Events, &webhook.Secret, &webhook.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhooks, nil
+}
+
+func (s *Storage) UpdateWebhook(ctx context.Context, webhook storage.Webhook) error {
+	const op = "storage.postgres.UpdateWebhook"
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE webhook SET url = $1, events = $2 WHERE id = $3",
+		webhook.URL, webhook.Events, webhook.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteWebhook(ctx context.Context, id int64) error {
+	const op = "storage.postgres.DeleteWebhook"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM webhook WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrWebhookNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM webhook_delivery WHERE webhook_id = $1", id); err != nil {
+		return fmt.Errorf("%s: delete deliveries: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveWebhookDelivery(ctx context.Context, delivery storage.WebhookDelivery) error {
+	const op = "storage.postgres.SaveWebhookDelivery"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO webhook_delivery(webhook_id, event, alias, attempts, status_code, error)
+		VALUES($1, $2, $3, $4, $5, $6)`,
+		delivery.WebhookID, delivery.Event, delivery.Alias, delivery.Attempts, delivery.StatusCode, delivery.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	// Журнал ограничен последними доставками, старые удаляются при записи новых
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM webhook_delivery
+		WHERE webhook_id = $1 AND id <= (
+			SELECT id FROM webhook_delivery WHERE webhook_id = $1 ORDER BY id DESC LIMIT 1 OFFSET $2)`,
+		delivery.WebhookID, storage.WebhookDeliveryLog,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: trim deliveries: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]storage.WebhookDelivery, error) {
+	const op = "storage.postgres.ListWebhookDeliveries"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, webhook_id, event, alias, attempts, status_code, error, created_at
+		FROM webhook_delivery
+		WHERE webhook_id = $1
+		ORDER BY id DESC
+		LIMIT $2`,
+		webhookID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	var deliveries []storage.WebhookDelivery
+	for rows.Next() {
+		var d storage.WebhookDelivery
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Alias, &d.Attempts, &d.StatusCode, &d.Error, &d.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// SchemaVersion returns the version of the latest applied migration.
+func (s *Storage) SchemaVersion() (int, error) {
+	return migrations.Version(s.db)
+}
+
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+// nullID converts zero id to NULL.
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
@@ -0,0 +1,17 @@
+package storage
+
+// PrefixUpperBound returns the smallest string greater than every string with the given prefix,
+// so that prefix search can be done with an index-friendly range: prefix <= s < bound.
+// Empty bound means there is no upper limit.
+func PrefixUpperBound(prefix string) string {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+
+			return string(b[:i+1])
+		}
+	}
+
+	return ""
+}
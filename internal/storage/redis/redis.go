@@ -0,0 +1,2104 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"url-shortener/internal/storage"
+)
+
+type Storage struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// Options holds connection and key settings.
+type Options struct {
+	Addr     string
+	Password string
+	DB       int
+	// KeyPrefix is prepended to every key, so several services can share one Redis.
+	KeyPrefix string
+	// TTL is the default lifetime of saved aliases. Zero means no expiration.
+	TTL time.Duration
+}
+
+func New(opts Options) (*Storage, error) {
+	const op = "storage.redis.New"
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     opts.Addr,
+		Password: opts.Password,
+		DB:       opts.DB,
+	})
+
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		_ = client.Close()
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Storage{
+		client: client,
+		prefix: opts.KeyPrefix,
+		ttl:    opts.TTL,
+	}, nil
+}
+
+func (s *Storage) urlKey(alias string) string {
+	return s.prefix + "url:" + alias
+}
+
+func (s *Storage) clicksKey(alias string) string {
+	return s.prefix + "clicks:" + alias
+}
+
+// uniquesKey is a HyperLogLog of visitors of the link on the day. The day goes first:
+// it has a fixed length, while aliases may contain colons.
+func (s *Storage) uniquesKey(day, alias string) string {
+	return s.prefix + "uniques:" + day + ":" + alias
+}
+
+// uniqueDaysKey is a set of days with uniquesKey of the link.
+func (s *Storage) uniqueDaysKey(alias string) string {
+	return s.prefix + "unique_days:" + alias
+}
+
+// deletedKey is a sorted set of soft-deleted aliases scored by deletion time in milliseconds.
+func (s *Storage) deletedKey() string {
+	return s.prefix + "deleted_urls"
+}
+
+// targetKey is a set of aliases saved for the target URL, used by FindURL.
+// It may contain stale aliases, they are removed on lookup.
+func (s *Storage) targetKey(target string) string {
+	sum := sha256.Sum256([]byte(target))
+
+	return s.prefix + "target:" + hex.EncodeToString(sum[:])
+}
+
+func (s *Storage) idKey() string {
+	return s.prefix + "url_id"
+}
+
+func (s *Storage) apiKeyKey(hash string) string {
+	return s.prefix + "api_key:" + hash
+}
+
+// apiKeyHashKey maps api key id to its hash, so keys can be revoked by id.
+func (s *Storage) apiKeyHashKey(id int64) string {
+	return s.prefix + "api_key_hash:" + strconv.FormatInt(id, 10)
+}
+
+func (s *Storage) apiKeyIDKey() string {
+	return s.prefix + "api_key_id"
+}
+
+func (s *Storage) userKey(email string) string {
+	return s.prefix + "user:" + email
+}
+
+// domainsKey is a hash of registered domains: host -> created_at.
+func (s *Storage) domainsKey() string {
+	return s.prefix + "domains"
+}
+
+// userTokenKey is a hash of the user token fields, removed by Redis when the token expires.
+func (s *Storage) userTokenKey(hash string) string {
+	return s.prefix + "user_token:" + hash
+}
+
+func (s *Storage) userIDKey() string {
+	return s.prefix + "user_id"
+}
+
+// reportKey is a hash of the abuse report fields.
+func (s *Storage) reportKey(id int64) string {
+	return s.prefix + "report:" + strconv.FormatInt(id, 10)
+}
+
+// reportsKey is a sorted set of report ids with the status, all reports if it is empty, scored by id.
+func (s *Storage) reportsKey(status string) string {
+	if status == "" {
+		return s.prefix + "reports"
+	}
+
+	return s.prefix + "reports:" + status
+}
+
+// openReportsKey is a set of ids of open reports of the link.
+func (s *Storage) openReportsKey(alias string) string {
+	return s.prefix + "open_reports:" + alias
+}
+
+func (s *Storage) reportIDKey() string {
+	return s.prefix + "report_id"
+}
+
+// bannedHostsKey is a hash of banned destination hosts: host -> created_at.
+func (s *Storage) bannedHostsKey() string {
+	return s.prefix + "banned_hosts"
+}
+
+// webhookKey is a hash of the webhook fields.
+func (s *Storage) webhookKey(id int64) string {
+	return s.prefix + "webhook:" + strconv.FormatInt(id, 10)
+}
+
+// webhooksKey is a sorted set of webhook ids scored by id.
+func (s *Storage) webhooksKey() string {
+	return s.prefix + "webhooks"
+}
+
+func (s *Storage) webhookIDKey() string {
+	return s.prefix + "webhook_id"
+}
+
+// webhookDeliveriesKey is a stream of the latest deliveries of the webhook.
+func (s *Storage) webhookDeliveriesKey(id int64) string {
+	return s.prefix + "webhook_deliveries:" + strconv.FormatInt(id, 10)
+}
+
+func (s *Storage) webhookDeliveryIDKey() string {
+	return s.prefix + "webhook_delivery_id"
+}
+
+// saveScript stores link fields in a hash unless the alias is taken, adds the alias
+// to the target set and sets the key TTL in milliseconds when it is positive.
+var saveScript = redis.NewScript(`
+if redis.call("HSETNX", KEYS[1], "url", ARGV[1]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], "id", ARGV[2], "created_at", ARGV[3], "expires_at", ARGV[4], "version", 1,
+	"api_key_id", ARGV[6], "user_id", ARGV[7], "redirect_code", ARGV[8], "max_clicks", ARGV[10], "webhook_url", ARGV[11],
+	"domain", ARGV[12], "geo_targets", ARGV[13],
+	"device_targets", ARGV[14], "split", ARGV[15],
+	"query_params", ARGV[16], "pass_query", ARGV[17],
+	"tags", ARGV[18], "description", ARGV[19], "no_analytics", ARGV[20],
+	"burn_after_reading", ARGV[21])
+redis.call("SADD", KEYS[2], ARGV[9])
+if tonumber(ARGV[5]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[5])
+end
+return 1
+`)
+
+// SaveURL saves url under alias. Links with ExpiresAt set are given Redis TTL,
+// so Redis removes them itself; the rest use the default TTL from Options.
+func (s *Storage) SaveURL(ctx context.Context, u storage.URL) (int64, error) {
+	const op = "storage.redis.SaveURL"
+
+	ttl := s.ttl
+	expiresAt := u.ExpiresAt
+	if !expiresAt.IsZero() {
+		ttl = time.Until(expiresAt)
+		if ttl <= 0 {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrURLExpired)
+		}
+	} else if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	geoTargets, err := u.GeoTargets.Value()
+	if err != nil {
+		return 0, fmt.Errorf("%s: encode geo targets: %w", op, err)
+	}
+
+	deviceTargets, err := u.DeviceTargets.Value()
+	if err != nil {
+		return 0, fmt.Errorf("%s: encode device targets: %w", op, err)
+	}
+
+	split, err := u.Split.Value()
+	if err != nil {
+		return 0, fmt.Errorf("%s: encode split: %w", op, err)
+	}
+
+	queryParams, err := u.QueryParams.Value()
+	if err != nil {
+		return 0, fmt.Errorf("%s: encode query params: %w", op, err)
+	}
+
+	tags, err := u.Tags.Value()
+	if err != nil {
+		return 0, fmt.Errorf("%s: encode tags: %w", op, err)
+	}
+
+	id, err := s.client.Incr(ctx, s.idKey()).Result()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get id: %w", op, err)
+	}
+
+	saved, err := saveScript.Run(ctx, s.client, []string{s.urlKey(u.Alias), s.targetKey(u.URL)},
+		u.URL, id, formatTime(time.Now()), formatTime(expiresAt), ttl.Milliseconds(), u.APIKeyID, u.UserID, u.RedirectCode, u.Alias,
+		u.MaxClicks, u.WebhookURL, u.Domain, geoTargets, deviceTargets, split, queryParams, u.PassQuery, tags, u.Description,
+		u.NoAnalytics, u.BurnAfterReading,
+	).Int()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if saved == 0 {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrURLExists)
+	}
+
+	return id, nil
+}
+
+// SaveURLs saves links in one MULTI/EXEC transaction.
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.URL) ([]int64, error) {
+	const op = "storage.redis.SaveURLs"
+
+	if len(urls) == 0 {
+		return nil, nil
+	}
+
+	now := formatTime(time.Now())
+
+	// Резервируем сразу диапазон id на всю пачку
+	lastID, err := s.client.IncrBy(ctx, s.idKey(), int64(len(urls))).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get id: %w", op, err)
+	}
+	firstID := lastID - int64(len(urls)) + 1
+
+	cmds := make([]*redis.Cmd, len(urls))
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, u := range urls {
+			ttl := s.ttl
+			expiresAt := u.ExpiresAt
+			if !expiresAt.IsZero() {
+				ttl = time.Until(expiresAt)
+				if ttl <= 0 {
+					return storage.ErrURLExpired
+				}
+			} else if ttl > 0 {
+				expiresAt = time.Now().Add(ttl)
+			}
+
+			geoTargets, err := u.GeoTargets.Value()
+			if err != nil {
+				return err
+			}
+
+			deviceTargets, err := u.DeviceTargets.Value()
+			if err != nil {
+				return err
+			}
+
+			split, err := u.Split.Value()
+			if err != nil {
+				return err
+			}
+
+			queryParams, err := u.QueryParams.Value()
+			if err != nil {
+				return err
+			}
+
+			tags, err := u.Tags.Value()
+			if err != nil {
+				return err
+			}
+
+			cmds[i] = saveScript.Eval(ctx, pipe, []string{s.urlKey(u.Alias), s.targetKey(u.URL)},
+				u.URL, firstID+int64(i), now, formatTime(expiresAt), ttl.Milliseconds(), u.APIKeyID, u.UserID, u.RedirectCode, u.Alias,
+				u.MaxClicks, u.WebhookURL, u.Domain, geoTargets, deviceTargets, split, queryParams, u.PassQuery, tags, u.Description,
+				u.NoAnalytics, u.BurnAfterReading,
+			)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids := make([]int64, len(urls))
+
+	for i, cmd := range cmds {
+		saved, err := cmd.Int()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if saved == 1 {
+			ids[i] = firstID + int64(i)
+		}
+	}
+
+	return ids, nil
+}
+
+func (s *Storage) GetURL(ctx context.Context, alias string) (storage.URL, error) {
+	const op = "storage.redis.GetURL"
+
+	values, err := s.client.HMGet(ctx, s.urlKey(alias),
+		"url", "expires_at", "redirect_code", "deleted_at", "quarantine_reason", "max_clicks", "exhausted_at", "webhook_url",
+		"domain", "geo_targets", "device_targets", "split", "query_params", "pass_query", "no_analytics",
+		"burn_after_reading",
+	).Result()
+	if err != nil {
+		return storage.URL{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resURL, ok := values[0].(string)
+	if !ok || values[3] != nil {
+		return storage.URL{}, storage.ErrURLNotFound
+	}
+
+	expiresAt, _ := values[1].(string)
+	code, _ := values[2].(string)
+	redirectCode, _ := strconv.Atoi(code)
+	reason, _ := values[4].(string)
+	clicks, _ := values[5].(string)
+	maxClicks, _ := strconv.ParseInt(clicks, 10, 64)
+	webhookURL, _ := values[7].(string)
+	domain, _ := values[8].(string)
+
+	var geoTargets storage.GeoTargets
+	if err := geoTargets.Scan(values[9]); err != nil {
+		return storage.URL{}, fmt.Errorf("%s: decode geo targets: %w", op, err)
+	}
+
+	var deviceTargets storage.DeviceTargets
+	if err := deviceTargets.Scan(values[10]); err != nil {
+		return storage.URL{}, fmt.Errorf("%s: decode device targets: %w", op, err)
+	}
+
+	var split storage.Split
+	if err := split.Scan(values[11]); err != nil {
+		return storage.URL{}, fmt.Errorf("%s: decode split: %w", op, err)
+	}
+
+	var queryParams storage.QueryParams
+	if err := queryParams.Scan(values[12]); err != nil {
+		return storage.URL{}, fmt.Errorf("%s: decode query params: %w", op, err)
+	}
+
+	passQuery, _ := values[13].(string)
+	noAnalytics, _ := values[14].(string)
+	burn, _ := values[15].(string)
+
+	if values[6] != nil {
+		return storage.URL{}, storage.ErrURLExhausted
+	}
+
+	return storage.URL{
+		Alias:            alias,
+		URL:              resURL,
+		ExpiresAt:        parseTime(expiresAt),
+		RedirectCode:     redirectCode,
+		QuarantineReason: reason,
+		MaxClicks:        maxClicks,
+		WebhookURL:       webhookURL,
+		Domain:           domain,
+		GeoTargets:       geoTargets,
+		DeviceTargets:    deviceTargets,
+		Split:            split,
+		QueryParams:      queryParams,
+		PassQuery:        passQuery == "1",
+		NoAnalytics:      noAnalytics == "1",
+		BurnAfterReading: burn == "1",
+	}, nil
+}
+
+func (s *Storage) FindURL(ctx context.Context, target string, userID, apiKeyID int64) (storage.URL, error) {
+	const op = "storage.redis.FindURL"
+
+	key := s.targetKey(target)
+
+	aliases, err := s.client.SMembers(ctx, key).Result()
+	if err != nil {
+		return storage.URL{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var found storage.URL
+
+	for _, alias := range aliases {
+		u, err := s.GetURLInfo(ctx, alias)
+		if errors.Is(err, storage.ErrURLNotFound) || err == nil && u.URL != target {
+			// Ссылка удалена, истекла или изменена
+			if err := s.client.SRem(ctx, key, alias).Err(); err != nil {
+				return storage.URL{}, fmt.Errorf("%s: %w", op, err)
+			}
+
+			continue
+		}
+		if err != nil {
+			return storage.URL{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if u.UserID != userID || u.APIKeyID != apiKeyID {
+			continue
+		}
+		if !u.ExpiresAt.IsZero() && !time.Now().Before(u.ExpiresAt) || !u.ExhaustedAt.IsZero() {
+			continue
+		}
+
+		if u.ID > found.ID {
+			found = u
+		}
+	}
+
+	if found.ID == 0 {
+		return storage.URL{}, storage.ErrURLNotFound
+	}
+
+	return found, nil
+}
+
+func (s *Storage) GetURLInfo(ctx context.Context, alias string) (storage.URL, error) {
+	const op = "storage.redis.GetURLInfo"
+
+	fields, err := s.client.HGetAll(ctx, s.urlKey(alias)).Result()
+	if err != nil {
+		return storage.URL{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(fields) == 0 || fields["deleted_at"] != "" {
+		return storage.URL{}, storage.ErrURLNotFound
+	}
+
+	id, _ := strconv.ParseInt(fields["id"], 10, 64)
+	version, _ := strconv.ParseInt(fields["version"], 10, 64)
+	hits, _ := strconv.ParseInt(fields["hits"], 10, 64)
+	apiKeyID, _ := strconv.ParseInt(fields["api_key_id"], 10, 64)
+	userID, _ := strconv.ParseInt(fields["user_id"], 10, 64)
+	redirectCode, _ := strconv.Atoi(fields["redirect_code"])
+	maxClicks, _ := strconv.ParseInt(fields["max_clicks"], 10, 64)
+
+	var geoTargets storage.GeoTargets
+	if err := geoTargets.Scan(fields["geo_targets"]); err != nil {
+		return storage.URL{}, fmt.Errorf("%s: decode geo targets: %w", op, err)
+	}
+
+	var deviceTargets storage.DeviceTargets
+	if err := deviceTargets.Scan(fields["device_targets"]); err != nil {
+		return storage.URL{}, fmt.Errorf("%s: decode device targets: %w", op, err)
+	}
+
+	var split storage.Split
+	if err := split.Scan(fields["split"]); err != nil {
+		return storage.URL{}, fmt.Errorf("%s: decode split: %w", op, err)
+	}
+
+	var queryParams storage.QueryParams
+	if err := queryParams.Scan(fields["query_params"]); err != nil {
+		return storage.URL{}, fmt.Errorf("%s: decode query params: %w", op, err)
+	}
+
+	var tags storage.Tags
+	if err := tags.Scan(fields["tags"]); err != nil {
+		return storage.URL{}, fmt.Errorf("%s: decode tags: %w", op, err)
+	}
+
+	return storage.URL{
+		ID:               id,
+		Alias:            alias,
+		URL:              fields["url"],
+		CreatedAt:        parseTime(fields["created_at"]),
+		UpdatedAt:        parseTime(fields["updated_at"]),
+		ExpiresAt:        parseTime(fields["expires_at"]),
+		Version:          version,
+		Hits:             hits,
+		APIKeyID:         apiKeyID,
+		UserID:           userID,
+		RedirectCode:     redirectCode,
+		QuarantineReason: fields["quarantine_reason"],
+		MaxClicks:        maxClicks,
+		ExhaustedAt:      parseTime(fields["exhausted_at"]),
+		WebhookURL:       fields["webhook_url"],
+		Domain:           fields["domain"],
+		GeoTargets:       geoTargets,
+		DeviceTargets:    deviceTargets,
+		Split:            split,
+		QueryParams:      queryParams,
+		PassQuery:        fields["pass_query"] == "1",
+		NoAnalytics:      fields["no_analytics"] == "1",
+		BurnAfterReading: fields["burn_after_reading"] == "1",
+		Tags:             tags,
+		Description:      fields["description"],
+		DeadReason:       fields["dead_reason"],
+		DeadSince:        parseTime(fields["dead_since"]),
+		Page: storage.PageMetadata{
+			Title:       fields["page_title"],
+			Description: fields["page_description"],
+			Image:       fields["page_image"],
+		},
+	}, nil
+}
+
+// updateScript applies changes to the link hash if its version matches ARGV[1] (0 means any).
+// Returns -1 if the link does not exist, -2 on version mismatch and the new version otherwise.
+var updateScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 or redis.call("HEXISTS", KEYS[1], "deleted_at") == 1 then
+	return -1
+end
+local version = tonumber(redis.call("HGET", KEYS[1], "version") or "1")
+if tonumber(ARGV[1]) > 0 and version ~= tonumber(ARGV[1]) then
+	return -2
+end
+if ARGV[2] == "1" then
+	redis.call("HSET", KEYS[1], "url", ARGV[3])
+	redis.call("HDEL", KEYS[1], "dead_reason", "dead_since", "page_title", "page_description", "page_image")
+	redis.call("SADD", KEYS[2], ARGV[10])
+end
+if ARGV[4] == "1" then
+	redis.call("HSET", KEYS[1], "expires_at", ARGV[5])
+	if tonumber(ARGV[6]) > 0 then
+		redis.call("PEXPIRE", KEYS[1], ARGV[6])
+	else
+		redis.call("PERSIST", KEYS[1])
+	end
+end
+if ARGV[8] == "1" then
+	redis.call("HSET", KEYS[1], "redirect_code", ARGV[9])
+end
+if ARGV[11] == "1" then
+	redis.call("HSET", KEYS[1], "no_analytics", ARGV[12])
+end
+redis.call("HSET", KEYS[1], "version", version + 1, "updated_at", ARGV[7])
+return version + 1
+`)
+
+func (s *Storage) UpdateURL(ctx context.Context, alias string, update storage.URLUpdate, version int64) (storage.URL, error) {
+	const op = "storage.redis.UpdateURL"
+
+	var (
+		setURL, setExpiry, setCode, setAnalytics string = "0", "0", "0", "0"
+		newURL, expiresAt                        string
+		ttl                                      time.Duration
+		redirectCode                             int
+		noAnalytics                              bool
+	)
+
+	if update.URL != nil {
+		setURL, newURL = "1", *update.URL
+	}
+	if update.RedirectCode != nil {
+		setCode, redirectCode = "1", *update.RedirectCode
+	}
+	if update.NoAnalytics != nil {
+		setAnalytics, noAnalytics = "1", *update.NoAnalytics
+	}
+	if update.ExpiresAt != nil {
+		setExpiry, expiresAt = "1", formatTime(*update.ExpiresAt)
+
+		if !update.ExpiresAt.IsZero() {
+			ttl = time.Until(*update.ExpiresAt)
+			if ttl <= 0 {
+				return storage.URL{}, fmt.Errorf("%s: %w", op, storage.ErrURLExpired)
+			}
+		}
+	}
+
+	res, err := updateScript.Run(ctx, s.client, []string{s.urlKey(alias), s.targetKey(newURL)},
+		version, setURL, newURL, setExpiry, expiresAt, ttl.Milliseconds(), formatTime(time.Now()),
+		setCode, redirectCode, alias, setAnalytics, noAnalytics,
+	).Int64()
+	if err != nil {
+		return storage.URL{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch res {
+	case -1:
+		return storage.URL{}, storage.ErrURLNotFound
+	case -2:
+		return storage.URL{}, fmt.Errorf("%s: %w", op, storage.ErrURLModified)
+	}
+
+	return s.GetURLInfo(ctx, alias)
+}
+
+// deleteScript marks an active link as deleted and remembers it for purging.
+var deleteScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 or redis.call("HEXISTS", KEYS[1], "deleted_at") == 1 then
+	return 0
+end
+redis.call("HSET", KEYS[1], "deleted_at", ARGV[1])
+redis.call("ZADD", KEYS[2], ARGV[2], ARGV[3])
+return 1
+`)
+
+func (s *Storage) DeleteURL(ctx context.Context, alias string) error {
+	const op = "storage.redis.DeleteURL"
+
+	now := time.Now()
+
+	deleted, err := deleteScript.Run(ctx, s.client, []string{s.urlKey(alias), s.deletedKey()},
+		formatTime(now), now.UnixMilli(), alias,
+	).Int()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if deleted == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+var restoreScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], "deleted_at") == 0 then
+	return 0
+end
+redis.call("HDEL", KEYS[1], "deleted_at")
+redis.call("ZREM", KEYS[2], ARGV[1])
+return 1
+`)
+
+func (s *Storage) RestoreURL(ctx context.Context, alias string) error {
+	const op = "storage.redis.RestoreURL"
+
+	restored, err := restoreScript.Run(ctx, s.client, []string{s.urlKey(alias), s.deletedKey()},
+		alias,
+	).Int()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if restored == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// consumeClickScript counts a click of a link with max_clicks and sets exhausted_at
+// (ARGV[1]) on the last allowed one. Returns 0 if no clicks are left.
+var consumeClickScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], "deleted_at") == 1 then
+	return 0
+end
+local max = tonumber(redis.call("HGET", KEYS[1], "max_clicks") or "0")
+local hits = tonumber(redis.call("HGET", KEYS[1], "hits") or "0")
+if max <= 0 or hits >= max then
+	return 0
+end
+redis.call("HINCRBY", KEYS[1], "hits", 1)
+if hits + 1 >= max then
+	redis.call("HSET", KEYS[1], "exhausted_at", ARGV[1])
+end
+return 1
+`)
+
+func (s *Storage) ConsumeClick(ctx context.Context, alias string) error {
+	const op = "storage.redis.ConsumeClick"
+
+	consumed, err := consumeClickScript.Run(ctx, s.client, []string{s.urlKey(alias)},
+		formatTime(time.Now()),
+	).Int()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if consumed == 0 {
+		return storage.ErrURLExhausted
+	}
+
+	return nil
+}
+
+// burnScript counts the click of a link with burn_after_reading and deletes it like
+// deleteScript. Returns 0 if there is no such active link.
+var burnScript = redis.NewScript(`
+if redis.call("HGET", KEYS[1], "burn_after_reading") ~= "1" or redis.call("HEXISTS", KEYS[1], "deleted_at") == 1 then
+	return 0
+end
+redis.call("HINCRBY", KEYS[1], "hits", 1)
+redis.call("HSET", KEYS[1], "deleted_at", ARGV[1])
+redis.call("ZADD", KEYS[2], ARGV[2], ARGV[3])
+return 1
+`)
+
+func (s *Storage) BurnURL(ctx context.Context, alias string) error {
+	const op = "storage.redis.BurnURL"
+
+	now := time.Now()
+
+	burned, err := burnScript.Run(ctx, s.client, []string{s.urlKey(alias), s.deletedKey()},
+		formatTime(now), now.UnixMilli(), alias,
+	).Int()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if burned == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// quarantineScript sets the quarantine reason of an existing link, an empty ARGV[1] removes it.
+var quarantineScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 or redis.call("HEXISTS", KEYS[1], "deleted_at") == 1 then
+	return 0
+end
+if ARGV[1] == "" then
+	redis.call("HDEL", KEYS[1], "quarantine_reason")
+else
+	redis.call("HSET", KEYS[1], "quarantine_reason", ARGV[1])
+end
+return 1
+`)
+
+func (s *Storage) QuarantineURL(ctx context.Context, alias, reason string) error {
+	const op = "storage.redis.QuarantineURL"
+
+	found, err := quarantineScript.Run(ctx, s.client, []string{s.urlKey(alias)}, reason).Int()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if found == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// markDeadScript sets the dead reason of an existing link and dead_since (ARGV[2])
+// unless it is already set; an empty ARGV[1] removes both.
+var markDeadScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 or redis.call("HEXISTS", KEYS[1], "deleted_at") == 1 then
+	return 0
+end
+if ARGV[1] == "" then
+	redis.call("HDEL", KEYS[1], "dead_reason", "dead_since")
+else
+	redis.call("HSET", KEYS[1], "dead_reason", ARGV[1])
+	redis.call("HSETNX", KEYS[1], "dead_since", ARGV[2])
+end
+return 1
+`)
+
+func (s *Storage) MarkURLDead(ctx context.Context, alias, reason string) error {
+	const op = "storage.redis.MarkURLDead"
+
+	found, err := markDeadScript.Run(ctx, s.client, []string{s.urlKey(alias)},
+		reason, formatTime(time.Now()),
+	).Int()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if found == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// pageMetadataScript sets the page metadata of an existing link.
+var pageMetadataScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 or redis.call("HEXISTS", KEYS[1], "deleted_at") == 1 then
+	return 0
+end
+redis.call("HSET", KEYS[1], "page_title", ARGV[1], "page_description", ARGV[2], "page_image", ARGV[3])
+return 1
+`)
+
+func (s *Storage) SetPageMetadata(ctx context.Context, alias string, page storage.PageMetadata) error {
+	const op = "storage.redis.SetPageMetadata"
+
+	found, err := pageMetadataScript.Run(ctx, s.client, []string{s.urlKey(alias)},
+		page.Title, page.Description, page.Image,
+	).Int()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if found == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// purgeScript removes the link, its clicks and visitors if it is still deleted: the key may have
+// expired and the alias may have been taken again since.
+var purgeScript = redis.NewScript(`
+redis.call("ZREM", KEYS[3], ARGV[1])
+if redis.call("HEXISTS", KEYS[1], "deleted_at") == 0 then
+	return 0
+end
+for _, day in ipairs(redis.call("SMEMBERS", KEYS[4])) do
+	redis.call("DEL", ARGV[2] .. day .. ":" .. ARGV[1])
+end
+redis.call("DEL", KEYS[1], KEYS[2], KEYS[4])
+return 1
+`)
+
+func (s *Storage) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	const op = "storage.redis.PurgeDeletedURLs"
+
+	aliases, err := s.client.ZRangeByScore(ctx, s.deletedKey(), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: "(" + strconv.FormatInt(deletedBefore.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var purged int64
+
+	for _, alias := range aliases {
+		n, err := purgeScript.Run(ctx, s.client,
+			[]string{s.urlKey(alias), s.clicksKey(alias), s.deletedKey(), s.uniqueDaysKey(alias)},
+			alias, s.prefix+"uniques:",
+		).Int64()
+		if err != nil {
+			return purged, fmt.Errorf("%s: %w", op, err)
+		}
+
+		purged += n
+	}
+
+	return purged, nil
+}
+
+// incrHitsScript increments hits of existing links only, so hits of deleted
+// or expired links do not resurrect their keys.
+var incrHitsScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	redis.call("HINCRBY", KEYS[1], "hits", ARGV[1])
+end
+return 0
+`)
+
+func (s *Storage) IncrementHits(ctx context.Context, hits map[string]int64) error {
+	const op = "storage.redis.IncrementHits"
+
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for alias, n := range hits {
+			incrHitsScript.Eval(ctx, pipe, []string{s.urlKey(alias)}, n)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// maxClickEvents limits the length of per-link click streams.
+const maxClickEvents = 100000
+
+func (s *Storage) SaveClickEvents(ctx context.Context, events []storage.ClickEvent) error {
+	const op = "storage.redis.SaveClickEvents"
+
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, e := range events {
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: s.clicksKey(e.Alias),
+				MaxLen: maxClickEvents,
+				Approx: true,
+				Values: map[string]any{
+					"time":          formatTime(e.Time),
+					"referrer":      e.Referrer,
+					"referrer_host": e.ReferrerHost,
+					"user_agent":    e.UserAgent,
+					"browser":       e.Browser,
+					"ip":            e.IP,
+					"variant":       e.Variant,
+					"utm_source":    e.UTMSource,
+					"utm_medium":    e.UTMMedium,
+					"utm_campaign":  e.UTMCampaign,
+					"country":       e.Country,
+					"city":          e.City,
+				},
+			})
+
+			if e.Visitor != 0 {
+				day := e.Time.UTC().Format("2006-01-02")
+				pipe.PFAdd(ctx, s.uniquesKey(day, e.Alias), strconv.FormatUint(e.Visitor, 16))
+				pipe.SAdd(ctx, s.uniqueDaysKey(e.Alias), day)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetClickStats(ctx context.Context, alias string) (storage.ClickStats, error) {
+	const op = "storage.redis.GetClickStats"
+
+	values, err := s.client.HMGet(ctx, s.urlKey(alias), "url", "deleted_at").Result()
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if values[0] == nil || values[1] != nil {
+		return storage.ClickStats{}, storage.ErrURLNotFound
+	}
+
+	msgs, err := s.client.XRange(ctx, s.clicksKey(alias), "-", "+").Result()
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	events := make([]storage.ClickEvent, 0, len(msgs))
+	for _, msg := range msgs {
+		events = append(events, clickEvent(alias, msg))
+	}
+
+	stats := storage.AggregateClicks(events)
+
+	stats.Uniques, stats.UniquesByDay, err = s.countUniques(ctx, alias)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+// countUniques counts visitors of every day of the link and of all days together.
+func (s *Storage) countUniques(ctx context.Context, alias string) (int64, []storage.Count, error) {
+	days, err := s.client.SMembers(ctx, s.uniqueDaysKey(alias)).Result()
+	if err != nil || len(days) == 0 {
+		return 0, nil, err
+	}
+
+	sort.Strings(days)
+
+	keys := make([]string, 0, len(days))
+	for _, day := range days {
+		keys = append(keys, s.uniquesKey(day, alias))
+	}
+
+	cmds, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.PFCount(ctx, key)
+		}
+		// PFCOUNT нескольких ключей считает их объединение
+		pipe.PFCount(ctx, keys...)
+
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	byDay := make([]storage.Count, 0, len(days))
+	for i, day := range days {
+		byDay = append(byDay, storage.Count{Key: day, Count: cmds[i].(*redis.IntCmd).Val()})
+	}
+
+	return cmds[len(days)].(*redis.IntCmd).Val(), byDay, nil
+}
+
+// clickEvent decodes an entry of the click stream of the link.
+func clickEvent(alias string, msg redis.XMessage) storage.ClickEvent {
+	str := func(k string) string {
+		v, _ := msg.Values[k].(string)
+
+		return v
+	}
+
+	return storage.ClickEvent{
+		Alias:        alias,
+		Time:         parseTime(str("time")),
+		Referrer:     str("referrer"),
+		ReferrerHost: str("referrer_host"),
+		UserAgent:    str("user_agent"),
+		Browser:      str("browser"),
+		IP:           str("ip"),
+		Variant:      str("variant"),
+		UTMSource:    str("utm_source"),
+		UTMMedium:    str("utm_medium"),
+		UTMCampaign:  str("utm_campaign"),
+		Country:      str("country"),
+		City:         str("city"),
+	}
+}
+
+// exportPageSize is the number of stream entries read by one XRANGE of an export.
+const exportPageSize = 1000
+
+// ExportClickEvents reads click streams page by page. Events of all links are
+// ordered by time within each link only: streams are read one after another.
+func (s *Storage) ExportClickEvents(ctx context.Context, filter storage.ClickFilter, fn func(storage.ClickEvent) error) error {
+	const op = "storage.redis.ExportClickEvents"
+
+	if filter.Alias != "" {
+		values, err := s.client.HMGet(ctx, s.urlKey(filter.Alias), "url", "deleted_at").Result()
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if values[0] == nil || values[1] != nil {
+			return storage.ErrURLNotFound
+		}
+
+		return s.exportStream(ctx, filter.Alias, filter, fn)
+	}
+
+	keyPrefixLen := len(s.clicksKey(""))
+
+	return s.eachClickStream(ctx, func(key string) error {
+		return s.exportStream(ctx, key[keyPrefixLen:], filter, fn)
+	})
+}
+
+// exportStream calls fn for events of the link in the range of the filter.
+func (s *Storage) exportStream(ctx context.Context, alias string, filter storage.ClickFilter, fn func(storage.ClickEvent) error) error {
+	const op = "storage.redis.exportStream"
+
+	// Как и в GetHourlyClicks, конец диапазона идентификаторов берется с запасом
+	start := strconv.FormatInt(filter.From.UnixMilli(), 10)
+	end := strconv.FormatInt(filter.To.Add(time.Minute).UnixMilli(), 10)
+
+	for {
+		msgs, err := s.client.XRangeN(ctx, s.clicksKey(alias), start, end, exportPageSize).Result()
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, msg := range msgs {
+			e := clickEvent(alias, msg)
+			if e.Time.Before(filter.From) || !e.Time.Before(filter.To) {
+				continue
+			}
+
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+
+		if len(msgs) < exportPageSize {
+			return nil
+		}
+
+		// Следующая страница начинается после последней прочитанной записи
+		start = "(" + msgs[len(msgs)-1].ID
+	}
+}
+
+// eachClickStream calls fn with the key of every click stream.
+func (s *Storage) eachClickStream(ctx context.Context, fn func(key string) error) error {
+	var cursor uint64
+
+	for {
+		keys, next, err := s.client.Scan(ctx, cursor, s.clicksKey("*"), 1000).Result()
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if err := fn(key); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+
+		cursor = next
+	}
+}
+
+// GetDailyClicks counts click streams of all links, Redis keeps no rollup.
+func (s *Storage) GetDailyClicks(ctx context.Context, from, to time.Time) ([]storage.DailyClicks, error) {
+	const op = "storage.redis.GetDailyClicks"
+
+	from, to = from.UTC().Truncate(24*time.Hour), to.UTC().Truncate(24*time.Hour)
+
+	keyPrefixLen := len(s.clicksKey(""))
+
+	var days []storage.DailyClicks
+
+	err := s.eachClickStream(ctx, func(key string) error {
+		byDay := make(map[string]int64)
+
+		err := s.exportStream(ctx, key[keyPrefixLen:], storage.ClickFilter{From: from, To: to}, func(e storage.ClickEvent) error {
+			byDay[e.Time.UTC().Format("2006-01-02")]++
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for day, clicks := range byDay {
+			days = append(days, storage.DailyClicks{Alias: key[keyPrefixLen:], Day: day, Clicks: clicks})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return storage.MergeDailyClicks(days), nil
+}
+
+// PurgeClickEvents trims click streams of all links by entry ids, which are the times
+// events were saved.
+func (s *Storage) PurgeClickEvents(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.redis.PurgeClickEvents"
+
+	minID := strconv.FormatInt(before.UnixMilli(), 10)
+
+	var purged int64
+
+	err := s.eachClickStream(ctx, func(key string) error {
+		n, err := s.client.XTrimMinID(ctx, key, minID).Result()
+		purged += n
+
+		return err
+	})
+	if err != nil {
+		return purged, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return purged, nil
+}
+
+// ArchiveURLs archives nothing: links are kept in memory, an archive in the same
+// Redis wouldn't free any. Inactive links can be given an expiration instead.
+func (s *Storage) ArchiveURLs(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+// ListURLs iterates keys with SCAN, so the cursor is a Redis SCAN cursor
+// and pages may be slightly shorter or longer than the limit.
+func (s *Storage) ListURLs(ctx context.Context, filter storage.ListFilter) ([]storage.URL, string, error) {
+	const op = "storage.redis.ListURLs"
+
+	// Ссылки в Redis не архивируются
+	if filter.Archived {
+		return nil, "", nil
+	}
+
+	var cursor uint64
+	if filter.Cursor != "" {
+		c, err := strconv.ParseUint(filter.Cursor, 10, 64)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", op, storage.ErrInvalidCursor)
+		}
+
+		cursor = c
+	}
+
+	match := s.urlKey(filter.AliasPrefix) + "*"
+	keyPrefixLen := len(s.urlKey(""))
+
+	var urls []storage.URL
+
+	for {
+		keys, next, err := s.client.Scan(ctx, cursor, match, int64(filter.Limit)).Result()
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, key := range keys {
+			u, err := s.GetURLInfo(ctx, key[keyPrefixLen:])
+			if errors.Is(err, storage.ErrURLNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, "", fmt.Errorf("%s: %w", op, err)
+			}
+
+			if !filter.CreatedFrom.IsZero() && u.CreatedAt.Before(filter.CreatedFrom) {
+				continue
+			}
+			if !filter.CreatedTo.IsZero() && !u.CreatedAt.Before(filter.CreatedTo) {
+				continue
+			}
+			if filter.UserID != 0 && u.UserID != filter.UserID {
+				continue
+			}
+			if filter.APIKeyID != 0 && u.APIKeyID != filter.APIKeyID {
+				continue
+			}
+			if !filter.Matches(u) {
+				continue
+			}
+
+			urls = append(urls, u)
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return urls, "", nil
+		}
+		if len(urls) >= filter.Limit {
+			return urls, strconv.FormatUint(cursor, 10), nil
+		}
+	}
+}
+
+// GetHourlyClicks counts the events of the click stream in the range. The stream
+// keeps the latest events only, so older hours may be missing.
+func (s *Storage) GetHourlyClicks(ctx context.Context, alias string, from, to time.Time) ([]storage.ClickBucket, error) {
+	const op = "storage.redis.GetHourlyClicks"
+
+	values, err := s.client.HMGet(ctx, s.urlKey(alias), "url", "deleted_at").Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if values[0] == nil || values[1] != nil {
+		return nil, storage.ErrURLNotFound
+	}
+
+	from, to = from.UTC().Truncate(time.Hour), to.UTC().Truncate(time.Hour)
+
+	// Идентификаторы записей потока - время добавления в миллисекундах. Событие попадает
+	// в поток чуть позже перехода, поэтому конец диапазона берется с запасом
+	msgs, err := s.client.XRange(ctx, s.clicksKey(alias),
+		strconv.FormatInt(from.UnixMilli(), 10), strconv.FormatInt(to.Add(time.Minute).UnixMilli(), 10)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	byHour := make(map[time.Time]int64)
+	for _, msg := range msgs {
+		v, _ := msg.Values["time"].(string)
+		if t := parseTime(v); !t.Before(from) && t.Before(to) {
+			byHour[t.UTC().Truncate(time.Hour)]++
+		}
+	}
+
+	return storage.SortedBuckets(byHour), nil
+}
+
+// RollupClicks rolls up nothing: GetHourlyClicks reads the capped click stream directly.
+func (s *Storage) RollupClicks(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+// GetServiceStats reads every link and its clicks of the last 30 days: Redis can't
+// aggregate them, so the result is better cached by the caller.
+func (s *Storage) GetServiceStats(ctx context.Context, now time.Time) (storage.ServiceStats, error) {
+	const op = "storage.redis.GetServiceStats"
+
+	day, week, month := storage.StatsWindows(now)
+	createdFrom := month.Truncate(24 * time.Hour)
+
+	var stats storage.ServiceStats
+
+	hits := make(map[string]int64)
+	created := make(map[string]int64)
+
+	filter := storage.ListFilter{Limit: 500}
+	for {
+		urls, next, err := s.ListURLs(ctx, filter)
+		if err != nil {
+			return storage.ServiceStats{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, u := range urls {
+			stats.Links++
+
+			if u.Hits > 0 {
+				hits[u.Alias] = u.Hits
+			}
+			if !u.CreatedAt.Before(createdFrom) {
+				created[u.CreatedAt.UTC().Format("2006-01-02")]++
+			}
+
+			// Идентификаторы записей потока - время добавления в миллисекундах
+			msgs, err := s.client.XRange(ctx, s.clicksKey(u.Alias), strconv.FormatInt(month.UnixMilli(), 10), "+").Result()
+			if err != nil {
+				return storage.ServiceStats{}, fmt.Errorf("%s: %w", op, err)
+			}
+
+			for _, msg := range msgs {
+				v, _ := msg.Values["time"].(string)
+				t := parseTime(v)
+
+				if t.Before(month) {
+					continue
+				}
+				stats.Clicks30d++
+				if !t.Before(week) {
+					stats.Clicks7d++
+				}
+				if !t.Before(day) {
+					stats.ClicksToday++
+				}
+			}
+		}
+
+		if next == "" {
+			break
+		}
+		filter.Cursor = next
+	}
+
+	stats.TopLinks = storage.TopCounts(hits, storage.StatsTopLimit)
+	stats.CreatedByDay = storage.SortedCounts(created)
+
+	return stats, nil
+}
+
+// DeleteExpiredURLs is a no-op: Redis evicts expired keys itself, so no aliases of
+// expired links are returned.
+func (s *Storage) DeleteExpiredURLs(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (s *Storage) SaveAPIKey(ctx context.Context, key storage.APIKey) (int64, error) {
+	const op = "storage.redis.SaveAPIKey"
+
+	id, err := s.client.Incr(ctx, s.apiKeyIDKey()).Result()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get id: %w", op, err)
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, s.apiKeyKey(key.Hash),
+			"id", id,
+			"name", key.Name,
+			"tenant", key.Tenant,
+			"role", key.Role,
+			"signing_secret", key.SigningSecret,
+			"created_at", formatTime(time.Now()),
+		)
+		pipe.Set(ctx, s.apiKeyHashKey(id), key.Hash, 0)
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetAPIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error) {
+	const op = "storage.redis.GetAPIKeyByHash"
+
+	fields, err := s.client.HGetAll(ctx, s.apiKeyKey(hash)).Result()
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(fields) == 0 {
+		return storage.APIKey{}, storage.ErrAPIKeyNotFound
+	}
+
+	id, _ := strconv.ParseInt(fields["id"], 10, 64)
+
+	return storage.APIKey{
+		ID:            id,
+		Name:          fields["name"],
+		Hash:          hash,
+		CreatedAt:     parseTime(fields["created_at"]),
+		RevokedAt:     parseTime(fields["revoked_at"]),
+		Tenant:        fields["tenant"],
+		Role:          fields["role"],
+		SigningSecret: fields["signing_secret"],
+	}, nil
+}
+
+func (s *Storage) GetAPIKey(ctx context.Context, id int64) (storage.APIKey, error) {
+	const op = "storage.redis.GetAPIKey"
+
+	hash, err := s.client.Get(ctx, s.apiKeyHashKey(id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return storage.APIKey{}, storage.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s.GetAPIKeyByHash(ctx, hash)
+}
+
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64) error {
+	const op = "storage.redis.RevokeAPIKey"
+
+	hash, err := s.client.Get(ctx, s.apiKeyHashKey(id)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return storage.ErrAPIKeyNotFound
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// revoked_at is set only once, so revoking a revoked key reports it as not found
+	revoked, err := s.client.HSetNX(ctx, s.apiKeyKey(hash), "revoked_at", formatTime(time.Now())).Result()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !revoked {
+		return storage.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// saveUserScript creates the user hash unless the email is taken.
+var saveUserScript = redis.NewScript(`
+if redis.call("HSETNX", KEYS[1], "id", ARGV[1]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], "pass_hash", ARGV[2], "created_at", ARGV[3], "tenant", ARGV[4], "role", ARGV[5],
+	"verified_at", ARGV[6])
+return 1
+`)
+
+// setUserRoleScript changes the role of an existing user.
+var setUserRoleScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], "role", ARGV[1])
+return 1
+`)
+
+// verifyUserScript sets verified_at of an existing user unless it is set.
+var verifyUserScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+local verified = redis.call("HGET", KEYS[1], "verified_at")
+if not verified or verified == "" then
+	redis.call("HSET", KEYS[1], "verified_at", ARGV[1])
+end
+return 1
+`)
+
+// setUserPasswordScript changes the password of an existing user and verifies it
+// like verifyUserScript.
+var setUserPasswordScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], "pass_hash", ARGV[1])
+local verified = redis.call("HGET", KEYS[1], "verified_at")
+if not verified or verified == "" then
+	redis.call("HSET", KEYS[1], "verified_at", ARGV[2])
+end
+return 1
+`)
+
+// consumeUserTokenScript removes the token hash if it has the purpose ARGV[1]
+// and returns its email and expires_at.
+var consumeUserTokenScript = redis.NewScript(`
+local fields = redis.call("HMGET", KEYS[1], "purpose", "email", "expires_at")
+if fields[1] ~= ARGV[1] then
+	return false
+end
+redis.call("DEL", KEYS[1])
+return {fields[2], fields[3]}
+`)
+
+func (s *Storage) SaveUser(ctx context.Context, user storage.User) (int64, error) {
+	const op = "storage.redis.SaveUser"
+
+	id, err := s.client.Incr(ctx, s.userIDKey()).Result()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get id: %w", op, err)
+	}
+
+	saved, err := saveUserScript.Run(ctx, s.client, []string{s.userKey(user.Email)},
+		id, user.PassHash, formatTime(time.Now()), user.Tenant, user.Role, formatTime(user.VerifiedAt),
+	).Int()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if saved == 0 {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetUser(ctx context.Context, email string) (storage.User, error) {
+	const op = "storage.redis.GetUser"
+
+	fields, err := s.client.HGetAll(ctx, s.userKey(email)).Result()
+	if err != nil {
+		return storage.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(fields) == 0 {
+		return storage.User{}, storage.ErrUserNotFound
+	}
+
+	id, _ := strconv.ParseInt(fields["id"], 10, 64)
+
+	// Пользователи, сохраненные до подтверждения email, не имеют поля verified_at и считаются подтвержденными
+	verifiedAt, ok := fields["verified_at"]
+	if !ok {
+		verifiedAt = fields["created_at"]
+	}
+
+	return storage.User{
+		ID:         id,
+		Email:      email,
+		PassHash:   []byte(fields["pass_hash"]),
+		CreatedAt:  parseTime(fields["created_at"]),
+		Tenant:     fields["tenant"],
+		Role:       fields["role"],
+		VerifiedAt: parseTime(verifiedAt),
+	}, nil
+}
+
+func (s *Storage) SetUserRole(ctx context.Context, email, role string) error {
+	const op = "storage.redis.SetUserRole"
+
+	updated, err := setUserRoleScript.Run(ctx, s.client, []string{s.userKey(email)}, role).Int()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if updated == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) VerifyUser(ctx context.Context, email string) error {
+	const op = "storage.redis.VerifyUser"
+
+	updated, err := verifyUserScript.Run(ctx, s.client, []string{s.userKey(email)}, formatTime(time.Now())).Int()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if updated == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) SetUserPassword(ctx context.Context, email string, passHash []byte) error {
+	const op = "storage.redis.SetUserPassword"
+
+	updated, err := setUserPasswordScript.Run(ctx, s.client, []string{s.userKey(email)},
+		passHash, formatTime(time.Now()),
+	).Int()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if updated == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveUserToken(ctx context.Context, token storage.UserToken) error {
+	const op = "storage.redis.SaveUserToken"
+
+	key := s.userTokenKey(token.Hash)
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key,
+			"email", token.Email,
+			"purpose", token.Purpose,
+			"expires_at", formatTime(token.ExpiresAt),
+		)
+		pipe.PExpireAt(ctx, key, token.ExpiresAt)
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ConsumeUserToken(ctx context.Context, hash, purpose string) (storage.UserToken, error) {
+	const op = "storage.redis.ConsumeUserToken"
+
+	fields, err := consumeUserTokenScript.Run(ctx, s.client, []string{s.userTokenKey(hash)}, purpose).StringSlice()
+	if errors.Is(err, redis.Nil) {
+		return storage.UserToken{}, storage.ErrTokenNotFound
+	}
+	if err != nil {
+		return storage.UserToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	token := storage.UserToken{
+		Hash:      hash,
+		Email:     fields[0],
+		Purpose:   purpose,
+		ExpiresAt: parseTime(fields[1]),
+	}
+	if !token.ExpiresAt.After(time.Now()) {
+		return storage.UserToken{}, storage.ErrTokenNotFound
+	}
+
+	return token, nil
+}
+
+func (s *Storage) SaveDomain(ctx context.Context, domain storage.Domain) error {
+	const op = "storage.redis.SaveDomain"
+
+	saved, err := s.client.HSetNX(ctx, s.domainsKey(), domain.Host, formatTime(time.Now())).Result()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !saved {
+		return fmt.Errorf("%s: %w", op, storage.ErrDomainExists)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetDomain(ctx context.Context, host string) (storage.Domain, error) {
+	const op = "storage.redis.GetDomain"
+
+	createdAt, err := s.client.HGet(ctx, s.domainsKey(), host).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return storage.Domain{}, storage.ErrDomainNotFound
+		}
+
+		return storage.Domain{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return storage.Domain{Host: host, CreatedAt: parseTime(createdAt)}, nil
+}
+
+func (s *Storage) ListDomains(ctx context.Context) ([]storage.Domain, error) {
+	const op = "storage.redis.ListDomains"
+
+	fields, err := s.client.HGetAll(ctx, s.domainsKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	domains := make([]storage.Domain, 0, len(fields))
+	for host, createdAt := range fields {
+		domains = append(domains, storage.Domain{Host: host, CreatedAt: parseTime(createdAt)})
+	}
+
+	sort.Slice(domains, func(i, j int) bool { return domains[i].Host < domains[j].Host })
+
+	return domains, nil
+}
+
+func (s *Storage) DeleteDomain(ctx context.Context, host string) error {
+	const op = "storage.redis.DeleteDomain"
+
+	deleted, err := s.client.HDel(ctx, s.domainsKey(), host).Result()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if deleted == 0 {
+		return storage.ErrDomainNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveReport(ctx context.Context, report storage.Report) (int64, error) {
+	const op = "storage.redis.SaveReport"
+
+	id, err := s.client.Incr(ctx, s.reportIDKey()).Result()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, s.reportKey(id),
+			"alias", report.Alias,
+			"category", report.Category,
+			"details", report.Details,
+			"contact", report.Contact,
+			"status", storage.ReportOpen,
+			"created_at", formatTime(time.Now()),
+		)
+		pipe.ZAdd(ctx, s.reportsKey(""), redis.Z{Score: float64(id), Member: id})
+		pipe.ZAdd(ctx, s.reportsKey(storage.ReportOpen), redis.Z{Score: float64(id), Member: id})
+		pipe.SAdd(ctx, s.openReportsKey(report.Alias), id)
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) ListReports(ctx context.Context, status string, limit int) ([]storage.Report, error) {
+	const op = "storage.redis.ListReports"
+
+	members, err := s.client.ZRange(ctx, s.reportsKey(status), 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids := make([]int64, len(members))
+	for i, m := range members {
+		ids[i], _ = strconv.ParseInt(m, 10, 64)
+	}
+
+	cmds := make([]*redis.MapStringStringCmd, len(ids))
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.HGetAll(ctx, s.reportKey(id))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	reports := make([]storage.Report, 0, len(ids))
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			continue
+		}
+
+		reports = append(reports, storage.Report{
+			ID:         ids[i],
+			Alias:      fields["alias"],
+			Category:   fields["category"],
+			Details:    fields["details"],
+			Contact:    fields["contact"],
+			Status:     fields["status"],
+			CreatedAt:  parseTime(fields["created_at"]),
+			ResolvedAt: parseTime(fields["resolved_at"]),
+		})
+	}
+
+	return reports, nil
+}
+
+// resolveReportsScript moves the open reports of the link (KEYS[1]) to the status
+// ARGV[1]; KEYS[2] and KEYS[3] are sets of open reports and reports with the status,
+// ARGV[3] is the prefix of report keys.
+var resolveReportsScript = redis.NewScript(`
+local ids = redis.call("SMEMBERS", KEYS[1])
+for _, id in ipairs(ids) do
+	redis.call("HSET", ARGV[3] .. id, "status", ARGV[1], "resolved_at", ARGV[2])
+	redis.call("ZREM", KEYS[2], id)
+	redis.call("ZADD", KEYS[3], id, id)
+end
+redis.call("DEL", KEYS[1])
+return #ids
+`)
+
+func (s *Storage) ResolveReports(ctx context.Context, alias, status string) (int64, error) {
+	const op = "storage.redis.ResolveReports"
+
+	n, err := resolveReportsScript.Run(ctx, s.client,
+		[]string{s.openReportsKey(alias), s.reportsKey(storage.ReportOpen), s.reportsKey(status)},
+		status, formatTime(time.Now()), s.prefix+"report:",
+	).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+func (s *Storage) BanHost(ctx context.Context, host string) error {
+	const op = "storage.redis.BanHost"
+
+	if err := s.client.HSetNX(ctx, s.bannedHostsKey(), host, formatTime(time.Now())).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) UnbanHost(ctx context.Context, host string) error {
+	const op = "storage.redis.UnbanHost"
+
+	deleted, err := s.client.HDel(ctx, s.bannedHostsKey(), host).Result()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if deleted == 0 {
+		return storage.ErrHostNotBanned
+	}
+
+	return nil
+}
+
+func (s *Storage) ListBannedHosts(ctx context.Context) ([]storage.BannedHost, error) {
+	const op = "storage.redis.ListBannedHosts"
+
+	fields, err := s.client.HGetAll(ctx, s.bannedHostsKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	hosts := make([]storage.BannedHost, 0, len(fields))
+	for host, createdAt := range fields {
+		hosts = append(hosts, storage.BannedHost{Host: host, CreatedAt: parseTime(createdAt)})
+	}
+
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Host < hosts[j].Host })
+
+	return hosts, nil
+}
+
+func (s *Storage) SaveWebhook(ctx context.Context, webhook storage.Webhook) (int64, error) {
+	const op = "storage.redis.SaveWebhook"
+
+	events, err := webhook.Events.Value()
+	if err != nil {
+		return 0, fmt.Errorf("%s: encode events: %w", op, err)
+	}
+
+	id, err := s.client.Incr(ctx, s.webhookIDKey()).Result()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, s.webhookKey(id),
+			"url", webhook.URL,
+			"events", events,
+			"secret", webhook.Secret,
+			"created_at", formatTime(time.Now()),
+		)
+		pipe.ZAdd(ctx, s.webhooksKey(), redis.Z{Score: float64(id), Member: id})
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetWebhook(ctx context.Context, id int64) (storage.Webhook, error) {
+	const op = "storage.redis.GetWebhook"
+
+	fields, err := s.client.HGetAll(ctx, s.webhookKey(id)).Result()
+	if err != nil {
+		return storage.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(fields) == 0 {
+		return storage.Webhook{}, storage.ErrWebhookNotFound
+	}
+
+	webhook, err := webhookFromFields(id, fields)
+	if err != nil {
+		return storage.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhook, nil
+}
+
+func (s *Storage) ListWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	const op = "storage.redis.ListWebhooks"
+
+	members, err := s.client.ZRange(ctx, s.webhooksKey(), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids := make([]int64, len(members))
+	for i, m := range members {
+		ids[i], _ = strconv.ParseInt(m, 10, 64)
+	}
+
+	cmds := make([]*redis.MapStringStringCmd, len(ids))
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.HGetAll(ctx, s.webhookKey(id))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	webhooks := make([]storage.Webhook, 0, len(ids))
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			continue
+		}
+
+		webhook, err := webhookFromFields(ids[i], fields)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, nil
+}
+
+func webhookFromFields(id int64, fields map[string]string) (storage.Webhook, error) {
+	var events storage.EventTypes
+	if err := events.Scan(fields["events"]); err != nil {
+		return storage.Webhook{}, fmt.Errorf("decode events: %w", err)
+	}
+
+	return storage.Webhook{
+		ID:        id,
+		URL:       fields["url"],
+		Events:    events,
+		Secret:    fields["secret"],
+		CreatedAt: parseTime(fields["created_at"]),
+	}, nil
+}
+
+// updateWebhookScript sets the url (ARGV[1]) and events (ARGV[2]) of the webhook
+// KEYS[1] if it exists.
+var updateWebhookScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], "url", ARGV[1], "events", ARGV[2])
+return 1
+`)
+
+func (s *Storage) UpdateWebhook(ctx context.Context, webhook storage.Webhook) error {
+	const op = "storage.redis.UpdateWebhook"
+
+	events, err := webhook.Events.Value()
+	if err != nil {
+		return fmt.Errorf("%s: encode events: %w", op, err)
+	}
+
+	updated, err := updateWebhookScript.Run(ctx, s.client, []string{s.webhookKey(webhook.ID)},
+		webhook.URL, events,
+	).Int()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if updated == 0 {
+		return storage.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteWebhook(ctx context.Context, id int64) error {
+	const op = "storage.redis.DeleteWebhook"
+
+	var del *redis.IntCmd
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		del = pipe.Del(ctx, s.webhookKey(id))
+		pipe.Del(ctx, s.webhookDeliveriesKey(id))
+		pipe.ZRem(ctx, s.webhooksKey(), id)
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if del.Val() == 0 {
+		return storage.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveWebhookDelivery(ctx context.Context, delivery storage.WebhookDelivery) error {
+	const op = "storage.redis.SaveWebhookDelivery"
+
+	id, err := s.client.Incr(ctx, s.webhookDeliveryIDKey()).Result()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Поток обрезается точно, журнал хранит ровно последние доставки
+	err = s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.webhookDeliveriesKey(delivery.WebhookID),
+		MaxLen: storage.WebhookDeliveryLog,
+		Values: map[string]any{
+			"id":          id,
+			"event":       delivery.Event,
+			"alias":       delivery.Alias,
+			"attempts":    delivery.Attempts,
+			"status_code": delivery.StatusCode,
+			"error":       delivery.Error,
+			"created_at":  formatTime(time.Now()),
+		},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]storage.WebhookDelivery, error) {
+	const op = "storage.redis.ListWebhookDeliveries"
+
+	msgs, err := s.client.XRevRangeN(ctx, s.webhookDeliveriesKey(webhookID), "+", "-", int64(limit)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	deliveries := make([]storage.WebhookDelivery, 0, len(msgs))
+	for _, msg := range msgs {
+		str := func(k string) string {
+			v, _ := msg.Values[k].(string)
+
+			return v
+		}
+
+		id, _ := strconv.ParseInt(str("id"), 10, 64)
+		attempts, _ := strconv.Atoi(str("attempts"))
+		statusCode, _ := strconv.Atoi(str("status_code"))
+
+		deliveries = append(deliveries, storage.WebhookDelivery{
+			ID:         id,
+			WebhookID:  webhookID,
+			Event:      str("event"),
+			Alias:      str("alias"),
+			Attempts:   attempts,
+			StatusCode: statusCode,
+			Error:      str("error"),
+			CreatedAt:  parseTime(str("created_at")),
+		})
+	}
+
+	return deliveries, nil
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+func (s *Storage) Close() error {
+	return s.client.Close()
+}
+
+// formatTime returns empty string for zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// parseTime returns zero time for empty or malformed values.
+func parseTime(v string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, v)
+
+	return t
+}
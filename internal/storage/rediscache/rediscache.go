@@ -0,0 +1,230 @@
+// Package rediscache caches links for redirects in Redis in front of SQL storage,
+// so all instances of the service share one warm cache.
+package rediscache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// Storage decorates storage.Storage with a read-through Redis cache of GetURL results.
+// Saved links are written to the cache at once; updated, deleted, quarantined and
+// exhausted ones are removed from it. Redis errors are logged and the storage is used
+// instead, so the cache being down only makes redirects slower.
+// Methods which are not overridden are passed to the embedded storage as is.
+type Storage struct {
+	storage.Storage
+
+	log    *slog.Logger
+	client client
+	prefix string
+	ttl    time.Duration
+}
+
+// client is the part of *redis.Client the cache uses.
+type client interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Set(ctx context.Context, key string, value any, expiration time.Duration) *redis.StatusCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+	Close() error
+}
+
+// Options holds connection and key settings.
+type Options struct {
+	Addr     string
+	Password string
+	DB       int
+	// KeyPrefix is prepended to every key; it must differ from the one of Redis storage.
+	KeyPrefix string
+	// TTL is the lifetime of cached links.
+	TTL time.Duration
+}
+
+func New(s storage.Storage, log *slog.Logger, opts Options) (*Storage, error) {
+	const op = "storage.rediscache.New"
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     opts.Addr,
+		Password: opts.Password,
+		DB:       opts.DB,
+	})
+
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		_ = client.Close()
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Storage{
+		Storage: s,
+		log:     log.With(slog.String("component", "rediscache")),
+		client:  client,
+		prefix:  opts.KeyPrefix,
+		ttl:     opts.TTL,
+	}, nil
+}
+
+func (s *Storage) key(alias string) string {
+	return s.prefix + "url:" + alias
+}
+
+// GetURL returns the cached link or reads it from the storage and caches it.
+// Expiration of a cached link is checked on every call.
+func (s *Storage) GetURL(ctx context.Context, alias string) (storage.URL, error) {
+	data, err := s.client.Get(ctx, s.key(alias)).Bytes()
+	switch {
+	case err == nil:
+		var u storage.URL
+		if err := json.Unmarshal(data, &u); err != nil {
+			s.log.Error("malformed cached link", slog.String("alias", alias), sl.Err(err))
+
+			break
+		}
+
+		if !u.ExpiresAt.IsZero() && !time.Now().Before(u.ExpiresAt) {
+			return storage.URL{}, storage.ErrURLExpired
+		}
+
+		return u, nil
+	case !errors.Is(err, redis.Nil):
+		s.log.Error("failed to get cached link", slog.String("alias", alias), sl.Err(err))
+	}
+
+	u, err := s.Storage.GetURL(ctx, alias)
+	if err != nil {
+		return storage.URL{}, err
+	}
+
+	s.set(ctx, u)
+
+	return u, nil
+}
+
+func (s *Storage) SaveURL(ctx context.Context, u storage.URL) (int64, error) {
+	id, err := s.Storage.SaveURL(ctx, u)
+	if err == nil {
+		s.set(ctx, redirectFields(u))
+	}
+
+	return id, err
+}
+
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.URL) ([]int64, error) {
+	ids, err := s.Storage.SaveURLs(ctx, urls)
+	if err != nil {
+		return ids, err
+	}
+
+	for i, id := range ids {
+		// Ноль - алиас уже занят, в кеше должна остаться существующая ссылка
+		if id != 0 {
+			s.set(ctx, redirectFields(urls[i]))
+		}
+	}
+
+	return ids, nil
+}
+
+func (s *Storage) UpdateURL(ctx context.Context, alias string, update storage.URLUpdate, version int64) (storage.URL, error) {
+	defer s.remove(ctx, alias)
+
+	return s.Storage.UpdateURL(ctx, alias, update, version)
+}
+
+func (s *Storage) DeleteURL(ctx context.Context, alias string) error {
+	defer s.remove(ctx, alias)
+
+	return s.Storage.DeleteURL(ctx, alias)
+}
+
+func (s *Storage) RestoreURL(ctx context.Context, alias string) error {
+	defer s.remove(ctx, alias)
+
+	return s.Storage.RestoreURL(ctx, alias)
+}
+
+// ConsumeClick drops the link from the cache once it is exhausted, so GetURL reports it.
+func (s *Storage) ConsumeClick(ctx context.Context, alias string) error {
+	err := s.Storage.ConsumeClick(ctx, alias)
+	if errors.Is(err, storage.ErrURLExhausted) {
+		s.remove(ctx, alias)
+	}
+
+	return err
+}
+
+func (s *Storage) BurnURL(ctx context.Context, alias string) error {
+	defer s.remove(ctx, alias)
+
+	return s.Storage.BurnURL(ctx, alias)
+}
+
+func (s *Storage) QuarantineURL(ctx context.Context, alias, reason string) error {
+	defer s.remove(ctx, alias)
+
+	return s.Storage.QuarantineURL(ctx, alias, reason)
+}
+
+func (s *Storage) Close() error {
+	return errors.Join(s.client.Close(), s.Storage.Close())
+}
+
+// set caches u for ttl, but not longer than until the link expires.
+func (s *Storage) set(ctx context.Context, u storage.URL) {
+	ttl := s.ttl
+	if !u.ExpiresAt.IsZero() {
+		untilExpired := time.Until(u.ExpiresAt)
+		if untilExpired <= 0 {
+			return
+		}
+
+		ttl = min(ttl, untilExpired)
+	}
+
+	data, err := json.Marshal(u)
+	if err != nil {
+		s.log.Error("failed to encode link", slog.String("alias", u.Alias), sl.Err(err))
+
+		return
+	}
+
+	if err := s.client.Set(ctx, s.key(u.Alias), data, ttl).Err(); err != nil {
+		s.log.Error("failed to cache link", slog.String("alias", u.Alias), sl.Err(err))
+	}
+}
+
+func (s *Storage) remove(ctx context.Context, alias string) {
+	// Ссылка уже изменена в хранилище: удаляем ее из кеша, даже если запрос отменен
+	if err := s.client.Del(context.WithoutCancel(ctx), s.key(alias)).Err(); err != nil {
+		s.log.Error("failed to remove cached link", slog.String("alias", alias), sl.Err(err))
+	}
+}
+
+// redirectFields returns only the fields of u which GetURL of the storage sets.
+func redirectFields(u storage.URL) storage.URL {
+	return storage.URL{
+		Alias:            u.Alias,
+		URL:              u.URL,
+		ExpiresAt:        u.ExpiresAt,
+		RedirectCode:     u.RedirectCode,
+		MaxClicks:        u.MaxClicks,
+		WebhookURL:       u.WebhookURL,
+		Domain:           u.Domain,
+		GeoTargets:       u.GeoTargets,
+		DeviceTargets:    u.DeviceTargets,
+		Split:            u.Split,
+		QueryParams:      u.QueryParams,
+		PassQuery:        u.PassQuery,
+		NoAnalytics:      u.NoAnalytics,
+		BurnAfterReading: u.BurnAfterReading,
+	}
+}
@@ -0,0 +1,404 @@
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+// fakeClient keeps keys in memory; down makes every command fail like an unreachable Redis.
+type fakeClient struct {
+	values map[string]string
+	ttls   map[string]time.Duration
+	down   bool
+}
+
+var errDown = errors.New("connection refused")
+
+func (c *fakeClient) Get(_ context.Context, key string) *redis.StringCmd {
+	if c.down {
+		return redis.NewStringResult("", errDown)
+	}
+
+	value, ok := c.values[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+
+	return redis.NewStringResult(value, nil)
+}
+
+func (c *fakeClient) Set(_ context.Context, key string, value any, expiration time.Duration) *redis.StatusCmd {
+	if c.down {
+		return redis.NewStatusResult("", errDown)
+	}
+
+	c.values[key] = string(value.([]byte))
+	c.ttls[key] = expiration
+
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (c *fakeClient) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	if c.down {
+		return redis.NewIntResult(0, errDown)
+	}
+	// Настоящий клиент не выполняет команды с отмененным контекстом
+	if err := ctx.Err(); err != nil {
+		return redis.NewIntResult(0, err)
+	}
+
+	var n int64
+	for _, key := range keys {
+		if _, ok := c.values[key]; ok {
+			delete(c.values, key)
+			n++
+		}
+	}
+
+	return redis.NewIntResult(n, nil)
+}
+
+func (c *fakeClient) Close() error {
+	return nil
+}
+
+// fakeStorage keeps links in memory and counts GetURL calls.
+type fakeStorage struct {
+	storage.Storage
+
+	urls  map[string]storage.URL
+	calls int
+	// exhausted are aliases ConsumeClick reports as exhausted.
+	exhausted map[string]bool
+	// err is returned by the methods changing links.
+	err error
+}
+
+func (f *fakeStorage) GetURL(_ context.Context, alias string) (storage.URL, error) {
+	f.calls++
+
+	u, ok := f.urls[alias]
+	if !ok {
+		return storage.URL{}, storage.ErrURLNotFound
+	}
+
+	return u, nil
+}
+
+func (f *fakeStorage) SaveURL(_ context.Context, u storage.URL) (int64, error) {
+	f.urls[u.Alias] = u
+
+	return int64(len(f.urls)), nil
+}
+
+func (f *fakeStorage) SaveURLs(_ context.Context, urls []storage.URL) ([]int64, error) {
+	ids := make([]int64, len(urls))
+	for i, u := range urls {
+		if _, ok := f.urls[u.Alias]; ok {
+			continue
+		}
+
+		f.urls[u.Alias] = u
+		ids[i] = int64(len(f.urls))
+	}
+
+	return ids, nil
+}
+
+func (f *fakeStorage) UpdateURL(_ context.Context, alias string, update storage.URLUpdate, _ int64) (storage.URL, error) {
+	if f.err != nil {
+		return storage.URL{}, f.err
+	}
+
+	u := f.urls[alias]
+	u.URL = *update.URL
+	f.urls[alias] = u
+
+	return u, nil
+}
+
+func (f *fakeStorage) DeleteURL(_ context.Context, alias string) error {
+	delete(f.urls, alias)
+
+	return f.err
+}
+
+func (f *fakeStorage) RestoreURL(_ context.Context, alias string) error {
+	u := f.urls[alias]
+	u.URL = "https://restored.example"
+	f.urls[alias] = u
+
+	return f.err
+}
+
+func (f *fakeStorage) ConsumeClick(_ context.Context, alias string) error {
+	if f.exhausted[alias] {
+		delete(f.urls, alias)
+
+		return storage.ErrURLExhausted
+	}
+
+	return f.err
+}
+
+func (f *fakeStorage) BurnURL(_ context.Context, alias string) error {
+	delete(f.urls, alias)
+
+	return f.err
+}
+
+func (f *fakeStorage) QuarantineURL(_ context.Context, alias, reason string) error {
+	u := f.urls[alias]
+	u.QuarantineReason = reason
+	f.urls[alias] = u
+
+	return f.err
+}
+
+func newTestStorage(aliases ...string) (*Storage, *fakeStorage, *fakeClient) {
+	f := &fakeStorage{urls: make(map[string]storage.URL), exhausted: make(map[string]bool)}
+	for _, alias := range aliases {
+		f.urls[alias] = storage.URL{Alias: alias, URL: "https://" + alias + ".com"}
+	}
+
+	c := &fakeClient{values: make(map[string]string), ttls: make(map[string]time.Duration)}
+
+	return &Storage{
+		Storage: f,
+		log:     slogdiscard.NewDiscardLogger(),
+		client:  c,
+		prefix:  "cache:",
+		ttl:     time.Hour,
+	}, f, c
+}
+
+func TestGetURL_ReadThrough(t *testing.T) {
+	s, f, c := newTestStorage()
+	f.urls["a"] = storage.URL{
+		Alias:         "a",
+		URL:           "https://a.com",
+		RedirectCode:  301,
+		DeviceTargets: map[string]string{"ios": "https://apps.apple.com/a"},
+	}
+
+	for i := 0; i < 3; i++ {
+		u, err := s.GetURL(context.Background(), "a")
+		require.NoError(t, err)
+		assert.Equal(t, f.urls["a"], u)
+	}
+
+	assert.Equal(t, 1, f.calls)
+	assert.Contains(t, c.values, "cache:url:a")
+	assert.Equal(t, time.Hour, c.ttls["cache:url:a"])
+}
+
+func TestGetURL_TTLUntilExpiry(t *testing.T) {
+	s, f, c := newTestStorage()
+	f.urls["a"] = storage.URL{Alias: "a", URL: "https://a.com", ExpiresAt: time.Now().Add(time.Minute)}
+
+	_, err := s.GetURL(context.Background(), "a")
+	require.NoError(t, err)
+
+	// Ссылка не должна жить в кеше дольше, чем в хранилище
+	assert.LessOrEqual(t, c.ttls["cache:url:a"], time.Minute)
+}
+
+func TestGetURL_CachedExpired(t *testing.T) {
+	s, f, c := newTestStorage()
+	c.values["cache:url:a"] = `{"Alias":"a","URL":"https://a.com","ExpiresAt":"2000-01-01T00:00:00Z"}`
+
+	_, err := s.GetURL(context.Background(), "a")
+	require.ErrorIs(t, err, storage.ErrURLExpired)
+	assert.Zero(t, f.calls)
+}
+
+func TestGetURL_MissNotCached(t *testing.T) {
+	s, f, c := newTestStorage()
+
+	_, err := s.GetURL(context.Background(), "a")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	assert.Empty(t, c.values)
+
+	// Промах не кешируется: ссылка, созданная в хранилище напрямую, видна сразу
+	f.urls["a"] = storage.URL{Alias: "a", URL: "https://a.com"}
+
+	u, err := s.GetURL(context.Background(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, "https://a.com", u.URL)
+}
+
+func TestGetURL_RedisDown(t *testing.T) {
+	s, f, c := newTestStorage("a")
+	c.down = true
+
+	for i := 0; i < 2; i++ {
+		u, err := s.GetURL(context.Background(), "a")
+		require.NoError(t, err)
+		assert.Equal(t, "https://a.com", u.URL)
+	}
+
+	assert.Equal(t, 2, f.calls)
+}
+
+func TestSaveURL_Cached(t *testing.T) {
+	s, f, c := newTestStorage()
+
+	_, err := s.SaveURL(context.Background(), storage.URL{
+		Alias:       "a",
+		URL:         "https://a.com",
+		Description: "not needed for redirects",
+	})
+	require.NoError(t, err)
+
+	u, err := s.GetURL(context.Background(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, "https://a.com", u.URL)
+	assert.Empty(t, u.Description)
+	assert.Zero(t, f.calls)
+	assert.Contains(t, c.values, "cache:url:a")
+}
+
+func TestSaveURLs_TakenAliasKeepsCache(t *testing.T) {
+	s, f, _ := newTestStorage("taken")
+
+	_, err := s.GetURL(context.Background(), "taken")
+	require.NoError(t, err)
+
+	ids, err := s.SaveURLs(context.Background(), []storage.URL{
+		{Alias: "taken", URL: "https://other.com"},
+		{Alias: "new", URL: "https://new.com"},
+	})
+	require.NoError(t, err)
+	require.Zero(t, ids[0])
+
+	u, err := s.GetURL(context.Background(), "taken")
+	require.NoError(t, err)
+	assert.Equal(t, "https://taken.com", u.URL)
+
+	u, err = s.GetURL(context.Background(), "new")
+	require.NoError(t, err)
+	assert.Equal(t, "https://new.com", u.URL)
+
+	assert.Equal(t, 1, f.calls)
+}
+
+func TestInvalidation(t *testing.T) {
+	newURL := "https://b.com"
+
+	cases := []struct {
+		name string
+		// exhausted makes ConsumeClick report the link as exhausted.
+		exhausted bool
+		change    func(s *Storage) error
+		// kept is true when the cached link must stay.
+		kept bool
+	}{
+		{
+			name: "UpdateURL",
+			change: func(s *Storage) error {
+				_, err := s.UpdateURL(context.Background(), "a", storage.URLUpdate{URL: &newURL}, 0)
+
+				return err
+			},
+		},
+		{
+			name:   "DeleteURL",
+			change: func(s *Storage) error { return s.DeleteURL(context.Background(), "a") },
+		},
+		{
+			name:   "RestoreURL",
+			change: func(s *Storage) error { return s.RestoreURL(context.Background(), "a") },
+		},
+		{
+			name:   "BurnURL",
+			change: func(s *Storage) error { return s.BurnURL(context.Background(), "a") },
+		},
+		{
+			name:   "QuarantineURL",
+			change: func(s *Storage) error { return s.QuarantineURL(context.Background(), "a", "malware") },
+		},
+		{
+			name:      "ConsumeClick exhausted",
+			exhausted: true,
+			change:    func(s *Storage) error { return s.ConsumeClick(context.Background(), "a") },
+		},
+		{
+			name:   "ConsumeClick",
+			change: func(s *Storage) error { return s.ConsumeClick(context.Background(), "a") },
+			kept:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, f, c := newTestStorage("a")
+			f.exhausted["a"] = tc.exhausted
+
+			cached, err := s.GetURL(context.Background(), "a")
+			require.NoError(t, err)
+			require.Contains(t, c.values, "cache:url:a")
+
+			_ = tc.change(s)
+
+			if tc.kept {
+				assert.Contains(t, c.values, "cache:url:a")
+
+				return
+			}
+
+			assert.NotContains(t, c.values, "cache:url:a")
+
+			// Следующий редирект видит состояние хранилища, а не старую копию
+			u, err := s.GetURL(context.Background(), "a")
+			if err == nil {
+				assert.NotEqual(t, cached, u)
+			} else {
+				require.ErrorIs(t, err, storage.ErrURLNotFound)
+			}
+			assert.Equal(t, 2, f.calls)
+		})
+	}
+}
+
+func TestInvalidation_StorageError(t *testing.T) {
+	s, f, c := newTestStorage("a")
+
+	_, err := s.GetURL(context.Background(), "a")
+	require.NoError(t, err)
+
+	// Ошибка могла случиться уже после изменения, поэтому кеш сбрасывается в любом случае
+	f.err = storage.ErrURLModified
+	newURL := "https://b.com"
+	_, err = s.UpdateURL(context.Background(), "a", storage.URLUpdate{URL: &newURL}, 1)
+	require.ErrorIs(t, err, storage.ErrURLModified)
+
+	assert.NotContains(t, c.values, "cache:url:a")
+}
+
+func TestInvalidation_CanceledContext(t *testing.T) {
+	s, _, c := newTestStorage("a")
+
+	_, err := s.GetURL(context.Background(), "a")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Хранилище уже удалило ссылку, клиент ушел - копия в кеше все равно удаляется
+	_ = s.DeleteURL(ctx, "a")
+
+	assert.NotContains(t, c.values, "cache:url:a")
+}
@@ -0,0 +1,131 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"url-shortener/internal/storage"
+)
+
+// Сравнение подготовленных в New запросов с подготовкой на каждый вызов,
+// как было раньше: go test -bench . -cpu 1,8 ./internal/storage/sqlite
+
+const benchAliases = 1000
+
+func newBenchStorage(b *testing.B) *Storage {
+	b.Helper()
+
+	s, err := New(filepath.Join(b.TempDir(), "storage.db"), Options{
+		JournalMode:  "WAL",
+		BusyTimeout:  5 * time.Second,
+		MaxReadConns: 8,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { _ = s.Close() })
+
+	urls := make([]storage.URL, benchAliases)
+	for i := range urls {
+		urls[i] = storage.URL{Alias: fmt.Sprintf("alias%d", i), URL: "https://example.com"}
+	}
+	if _, err := s.SaveURLs(context.Background(), urls); err != nil {
+		b.Fatal(err)
+	}
+
+	return s
+}
+
+func BenchmarkGetURL(b *testing.B) {
+	s := newBenchStorage(b)
+
+	b.Run("Prepared", func(b *testing.B) {
+		var n atomic.Int64
+
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				alias := fmt.Sprintf("alias%d", n.Add(1)%benchAliases)
+				if _, err := s.GetURL(context.Background(), alias); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	})
+
+	b.Run("Unprepared", func(b *testing.B) {
+		var n atomic.Int64
+
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				alias := fmt.Sprintf("alias%d", n.Add(1)%benchAliases)
+
+				stmt, err := s.db.PrepareContext(context.Background(), getURLQuery)
+				if err != nil {
+					b.Error(err)
+
+					continue
+				}
+
+				var u storage.URL
+				err = stmt.QueryRowContext(context.Background(), alias).Scan(
+					&u.URL, new(any), &u.RedirectCode, &u.QuarantineReason, &u.MaxClicks, new(any), &u.WebhookURL,
+					&u.Domain, &u.GeoTargets, &u.DeviceTargets, &u.Split, &u.QueryParams, &u.PassQuery,
+					&u.NoAnalytics, &u.BurnAfterReading,
+				)
+				if err != nil {
+					b.Error(err)
+				}
+
+				_ = stmt.Close()
+			}
+		})
+	})
+}
+
+func BenchmarkSaveURL(b *testing.B) {
+	s := newBenchStorage(b)
+
+	// Подтесты запускаются несколько раз, счетчик общий, чтобы алиасы не повторялись
+	var n atomic.Int64
+
+	b.Run("Prepared", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				u := storage.URL{Alias: fmt.Sprintf("prepared%d", n.Add(1)), URL: "https://example.com"}
+				if _, err := s.SaveURL(context.Background(), u); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	})
+
+	b.Run("Unprepared", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				u := storage.URL{Alias: fmt.Sprintf("unprepared%d", n.Add(1)), URL: "https://example.com"}
+
+				stmt, err := s.wdb.PrepareContext(context.Background(), saveURLQuery)
+				if err != nil {
+					b.Error(err)
+
+					continue
+				}
+
+				_, err = stmt.ExecContext(context.Background(),
+					u.URL, u.Alias, time.Now().UTC(), nullTime(u.ExpiresAt), nullID(u.APIKeyID), nullID(u.UserID),
+					u.RedirectCode, u.MaxClicks, u.WebhookURL, u.Domain, u.GeoTargets, u.DeviceTargets, u.Split,
+					u.QueryParams, u.PassQuery, u.NoAnalytics, u.BurnAfterReading, u.Tags, u.Description,
+				)
+				if err != nil {
+					b.Error(err)
+				}
+
+				_ = stmt.Close()
+			}
+		})
+	})
+}
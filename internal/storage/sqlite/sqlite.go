@@ -1,57 +1,212 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mattn/go-sqlite3"
 
 	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/migrations"
 )
 
 type Storage struct {
+	// db is the pool of read connections.
 	db *sql.DB
+	// wdb is the single write connection: SQLite allows one writer at a time, and
+	// writers queueing in database/sql don't fail with "database is locked".
+	wdb *sql.DB
+
+	stmts statements
+}
+
+// Options configure SQLite connections. Zero values keep SQLite defaults.
+type Options struct {
+	// JournalMode is the journal_mode pragma. With WAL readers don't block the writer.
+	JournalMode string
+	// BusyTimeout is how long a connection waits for a lock before "database is locked".
+	BusyTimeout time.Duration
+	ForeignKeys bool
+	// MaxReadConns limits the pool of read connections.
+	MaxReadConns int
 }
 
-func New(storagePath string) (*Storage, error) {
+func New(storagePath string, opts Options) (*Storage, error) {
 	const op = "storage.sqlite.New" // Имя текущей функции для логов и ошибок
 
-	// 1. Подключаемся к БД
-	db, err := sql.Open("sqlite3", storagePath)
+	// 1. Подключаемся к БД: одно соединение на запись и пул на чтение
+	wdb, err := sql.Open("sqlite3", dsn(storagePath, opts, true))
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	wdb.SetMaxOpenConns(1)
+
+	// У каждого соединения с базой в памяти своя база, поэтому пул не нужен
+	db := wdb
+	if !inMemory(storagePath) {
+		db, err = sql.Open("sqlite3", dsn(storagePath, opts, false))
+		if err != nil {
+			_ = wdb.Close()
+
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		db.SetMaxOpenConns(opts.MaxReadConns)
+	}
+
+	s := &Storage{db: db, wdb: wdb}
+
+	// 2. Базы, созданные до версионных миграций, доводим до исходной схемы
+	if err := upgradeLegacy(wdb); err != nil {
+		_ = s.Close()
 
-	// 2. Создаем таблицу, если ее еще нет
-	stmt, err := db.Prepare(`
-	CREATE TABLE IF NOT EXISTS url(
-		id INTEGER PRIMARY KEY,
-		alias TEXT NOT NULL UNIQUE,
-		url TEXT NOT NULL);
-	CREATE INDEX IF NOT EXISTS idx_alias ON url(alias);
-	`)
-	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	_, err = stmt.Exec()
-	if err != nil {
+	// 3. Применяем недостающие миграции
+	if _, err := migrations.Up(wdb, migrations.DialectSQLite); err != nil {
+		_ = s.Close()
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// 4. Готовим запросы горячих путей один раз, а не на каждый вызов
+	if err := s.prepare(); err != nil {
+		_ = s.Close()
+
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &Storage{db: db}, nil
+	return s, nil
 }
 
-func (s *Storage) SaveURL(urlToSave string, alias string) (int64, error) {
-	const op = "storage.sqlite.SaveURL"
+// dsn adds pragmas of opts to path. journal_mode is persistent, so it is set by the writer only;
+// the writer also takes the lock at BEGIN, which avoids failing lock upgrades inside transactions.
+func dsn(path string, opts Options, writer bool) string {
+	params := url.Values{}
+
+	if opts.BusyTimeout > 0 {
+		params.Set("_busy_timeout", strconv.FormatInt(opts.BusyTimeout.Milliseconds(), 10))
+	}
+	if opts.ForeignKeys {
+		params.Set("_foreign_keys", "on")
+	}
+	if writer {
+		if opts.JournalMode != "" {
+			params.Set("_journal_mode", opts.JournalMode)
+		}
+		params.Set("_txlock", "immediate")
+	}
+
+	if len(params) == 0 {
+		return path
+	}
+
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+
+	return path + sep + params.Encode()
+}
+
+func inMemory(path string) bool {
+	return path == ":memory:" || strings.HasPrefix(path, "file::memory:") || strings.Contains(path, "mode=memory")
+}
+
+// legacyColumns were added to url table one by one before versioned migrations.
+var legacyColumns = []struct {
+	name       string
+	definition string
+}{
+	{"expires_at", "TIMESTAMP"},
+	{"created_at", "TIMESTAMP"},
+	{"updated_at", "TIMESTAMP"},
+	{"version", "INTEGER NOT NULL DEFAULT 1"},
+	{"hits", "INTEGER NOT NULL DEFAULT 0"},
+	{"api_key_id", "INTEGER"},
+	{"user_id", "INTEGER"},
+	{"redirect_code", "INTEGER NOT NULL DEFAULT 0"},
+	{"deleted_at", "TIMESTAMP"},
+}
 
-	stmt, err := s.db.Prepare("INSERT INTO url(url, alias) VALUES(?, ?)")
+// upgradeLegacy adds the columns of the baseline migration to url table of a database
+// created before versioned migrations; the rest of the baseline is idempotent.
+func upgradeLegacy(db *sql.DB) error {
+	legacy, err := tableExists(db, "url")
+	if err != nil || !legacy {
+		return err
+	}
+
+	migrated, err := tableExists(db, "schema_migrations")
+	if err != nil || migrated {
+		return err
+	}
+
+	for _, c := range legacyColumns {
+		if err := addColumnIfNotExists(db, "url", c.name, c.definition); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func tableExists(db *sql.DB, table string) (bool, error) {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&n)
+
+	return n > 0, err
+}
+
+// addColumnIfNotExists adds column to table unless it is already there.
+func addColumnIfNotExists(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return err
+		}
+
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
 	}
 
-	res, err := stmt.Exec(urlToSave, alias)
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+
+	return err
+}
+
+func (s *Storage) SaveURL(ctx context.Context, u storage.URL) (int64, error) {
+	const op = "storage.sqlite.SaveURL"
+
+	res, err := s.stmts.saveURL.ExecContext(ctx,
+		u.URL, u.Alias, time.Now().UTC(), nullTime(u.ExpiresAt), nullID(u.APIKeyID), nullID(u.UserID), u.RedirectCode, u.MaxClicks,
+		u.WebhookURL, u.Domain, u.GeoTargets, u.DeviceTargets, u.Split, u.QueryParams, u.PassQuery, u.NoAnalytics, u.BurnAfterReading, u.Tags, u.Description,
+		u.Alias,
+	)
 	if err != nil {
 		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
 			return 0, fmt.Errorf("%s: %w", op, storage.ErrURLExists)
@@ -60,6 +215,11 @@ func (s *Storage) SaveURL(urlToSave string, alias string) (int64, error) {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
+	// Ни одной строки: алиас занят ссылкой в архиве
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrURLExists)
+	}
+
 	id, err := res.LastInsertId()
 	if err != nil {
 		return 0, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
@@ -68,28 +228,1972 @@ func (s *Storage) SaveURL(urlToSave string, alias string) (int64, error) {
 	return id, nil
 }
 
-func (s *Storage) GetURL(alias string) (string, error) {
-	const op = "storage.sqlite.GetURL"
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.URL) ([]int64, error) {
+	const op = "storage.sqlite.SaveURLs"
 
-	stmt, err := s.db.Prepare("SELECT url FROM url WHERE alias = ?")
+	tx, err := s.wdb.BeginTx(ctx, nil)
 	if err != nil {
-		return "", fmt.Errorf("%s: prepare statement: %w", op, err)
+		return nil, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx, saveURLQuery+" ON CONFLICT(alias) DO NOTHING")
+	if err != nil {
+		return nil, fmt.Errorf("%s: prepare statement: %w", op, err)
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+	ids := make([]int64, len(urls))
+
+	for i, u := range urls {
+		res, err := stmt.ExecContext(ctx,
+			u.URL, u.Alias, now, nullTime(u.ExpiresAt), nullID(u.APIKeyID), nullID(u.UserID), u.RedirectCode, u.MaxClicks,
+			u.WebhookURL, u.Domain, u.GeoTargets, u.DeviceTargets, u.Split, u.QueryParams, u.PassQuery, u.NoAnalytics, u.BurnAfterReading, u.Tags, u.Description,
+			u.Alias,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+		}
+
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			continue
+		}
+
+		if ids[i], err = res.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: commit transaction: %w", op, err)
 	}
 
-	var resURL string
+	return ids, nil
+}
+
+func (s *Storage) GetURL(ctx context.Context, alias string) (storage.URL, error) {
+	const op = "storage.sqlite.GetURL"
+
+	var (
+		u           = storage.URL{Alias: alias}
+		expiresAt   sql.NullTime
+		exhaustedAt sql.NullTime
+	)
 
 	// 3. Scan() "переводит" полученные данные в GO-типы
-	err = stmt.QueryRow(alias).Scan(&resURL)
+	scan := func() error {
+		return s.stmts.getURL.QueryRowContext(ctx, alias).Scan(
+			&u.URL, &expiresAt, &u.RedirectCode, &u.QuarantineReason, &u.MaxClicks, &exhaustedAt, &u.WebhookURL, &u.Domain,
+			&u.GeoTargets, &u.DeviceTargets, &u.Split, &u.QueryParams, &u.PassQuery, &u.NoAnalytics, &u.BurnAfterReading,
+		)
+	}
+
+	err := scan()
+	if errors.Is(err, sql.ErrNoRows) {
+		// Ссылки из архива возвращаются в url, это медленнее обычного редиректа
+		restored, uerr := s.unarchive(ctx, alias)
+		if uerr != nil {
+			return storage.URL{}, fmt.Errorf("%s: %w", op, uerr)
+		}
+		if restored {
+			err = scan()
+		}
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.URL{}, storage.ErrURLNotFound
+		}
+
+		return storage.URL{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	if expiresAt.Valid && !time.Now().Before(expiresAt.Time) {
+		return storage.URL{}, storage.ErrURLExpired
+	}
+	if exhaustedAt.Valid {
+		return storage.URL{}, storage.ErrURLExhausted
+	}
+
+	u.ExpiresAt = expiresAt.Time
+
+	return u, nil
+}
+
+func (s *Storage) FindURL(ctx context.Context, target string, userID, apiKeyID int64) (storage.URL, error) {
+	const op = "storage.sqlite.FindURL"
+
+	var alias string
+
+	// IS сравнивает и с NULL, которым хранятся нулевые id
+	err := s.db.QueryRowContext(ctx, `SELECT alias FROM url
+		WHERE url = ? AND user_id IS ? AND api_key_id IS ? AND deleted_at IS NULL AND exhausted_at IS NULL
+		AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY id DESC LIMIT 1`,
+		target, nullID(userID), nullID(apiKeyID), time.Now().UTC(),
+	).Scan(&alias)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return "", storage.ErrURLNotFound
+			return storage.URL{}, storage.ErrURLNotFound
 		}
 
-		return "", fmt.Errorf("%s: execute statement: %w", op, err)
+		return storage.URL{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return s.GetURLInfo(ctx, alias)
+}
+
+func (s *Storage) GetURLInfo(ctx context.Context, alias string) (storage.URL, error) {
+	const op = "storage.sqlite.GetURLInfo"
+
+	u, err := s.getURLInfo(ctx, alias)
+	if !errors.Is(err, storage.ErrURLNotFound) {
+		return u, err
+	}
+
+	restored, err := s.unarchive(ctx, alias)
+	if err != nil {
+		return storage.URL{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if !restored {
+		return storage.URL{}, storage.ErrURLNotFound
+	}
+
+	return s.getURLInfo(ctx, alias)
+}
+
+func (s *Storage) getURLInfo(ctx context.Context, alias string) (storage.URL, error) {
+	const op = "storage.sqlite.GetURLInfo"
+
+	var (
+		u           storage.URL
+		createdAt   sql.NullTime
+		updatedAt   sql.NullTime
+		expiresAt   sql.NullTime
+		apiKeyID    sql.NullInt64
+		userID      sql.NullInt64
+		exhaustedAt sql.NullTime
+		deadSince   sql.NullTime
+	)
+
+	err := s.stmts.getURLInfo.QueryRowContext(ctx, alias).Scan(
+		&u.ID, &u.Alias, &u.URL, &createdAt, &updatedAt, &expiresAt, &u.Version, &u.Hits, &apiKeyID, &userID, &u.RedirectCode,
+		&u.QuarantineReason, &u.MaxClicks, &exhaustedAt, &u.WebhookURL, &u.Domain,
+		&u.GeoTargets, &u.DeviceTargets, &u.Split, &u.QueryParams, &u.PassQuery, &u.NoAnalytics, &u.BurnAfterReading, &u.Tags, &u.Description,
+		&u.DeadReason, &deadSince, &u.Page.Title, &u.Page.Description, &u.Page.Image,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.URL{}, storage.ErrURLNotFound
+		}
+
+		return storage.URL{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	u.CreatedAt = createdAt.Time
+	u.UpdatedAt = updatedAt.Time
+	u.ExpiresAt = expiresAt.Time
+	u.APIKeyID = apiKeyID.Int64
+	u.UserID = userID.Int64
+	u.ExhaustedAt = exhaustedAt.Time
+	u.DeadSince = deadSince.Time
+
+	return u, nil
+}
+
+func (s *Storage) UpdateURL(ctx context.Context, alias string, update storage.URLUpdate, version int64) (storage.URL, error) {
+	const op = "storage.sqlite.UpdateURL"
+
+	// Архивная ссылка сначала возвращается в url
+	if _, err := s.unarchive(ctx, alias); err != nil {
+		return storage.URL{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := "UPDATE url SET updated_at = ?, version = version + 1"
+	args := []any{time.Now().UTC()}
+
+	if update.URL != nil {
+		// Новое назначение еще не проверялось
+		query += ", url = ?, dead_reason = '', dead_since = NULL, " +
+			"page_title = '', page_description = '', page_image = ''"
+		args = append(args, *update.URL)
+	}
+	if update.ExpiresAt != nil {
+		query += ", expires_at = ?"
+		args = append(args, nullTime(*update.ExpiresAt))
+	}
+	if update.RedirectCode != nil {
+		query += ", redirect_code = ?"
+		args = append(args, *update.RedirectCode)
+	}
+	if update.NoAnalytics != nil {
+		query += ", no_analytics = ?"
+		args = append(args, *update.NoAnalytics)
+	}
+
+	query += " WHERE alias = ? AND deleted_at IS NULL"
+	args = append(args, alias)
+
+	if version != 0 {
+		query += " AND version = ?"
+		args = append(args, version)
+	}
+
+	res, err := s.wdb.ExecContext(ctx, query, args...)
+	if err != nil {
+		return storage.URL{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return storage.URL{}, fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	u, err := s.GetURLInfo(ctx, alias)
+	if err != nil {
+		return storage.URL{}, err
+	}
+
+	if affected == 0 {
+		return storage.URL{}, fmt.Errorf("%s: %w", op, storage.ErrURLModified)
+	}
+
+	return u, nil
+}
+
+func (s *Storage) DeleteURL(ctx context.Context, alias string) error {
+	const op = "storage.sqlite.DeleteURL"
+
+	if _, err := s.unarchive(ctx, alias); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt, err := s.wdb.PrepareContext(ctx, "UPDATE url SET deleted_at = ? WHERE alias = ? AND deleted_at IS NULL")
+	if err != nil {
+		return fmt.Errorf("%s: prepare statement: %w", op, err)
+	}
+
+	res, err := stmt.ExecContext(ctx, time.Now().UTC(), alias)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) RestoreURL(ctx context.Context, alias string) error {
+	const op = "storage.sqlite.RestoreURL"
+
+	res, err := s.wdb.ExecContext(ctx, "UPDATE url SET deleted_at = NULL WHERE alias = ? AND deleted_at IS NOT NULL", alias)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) ConsumeClick(ctx context.Context, alias string) error {
+	const op = "storage.sqlite.ConsumeClick"
+
+	res, err := s.stmts.consumeClick.ExecContext(ctx, time.Now().UTC(), alias)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrURLExhausted
+	}
+
+	return nil
+}
+
+func (s *Storage) BurnURL(ctx context.Context, alias string) error {
+	const op = "storage.sqlite.BurnURL"
+
+	now := time.Now().UTC()
+
+	res, err := s.stmts.burnURL.ExecContext(ctx, now, now, alias)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) QuarantineURL(ctx context.Context, alias, reason string) error {
+	const op = "storage.sqlite.QuarantineURL"
+
+	res, err := s.wdb.ExecContext(ctx, "UPDATE url SET quarantine_reason = ? WHERE alias = ? AND deleted_at IS NULL", reason, alias)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) MarkURLDead(ctx context.Context, alias, reason string) error {
+	const op = "storage.sqlite.MarkURLDead"
+
+	// dead_since сохраняется, пока ссылка остается мертвой
+	res, err := s.wdb.ExecContext(ctx, `UPDATE url SET dead_reason = ?,
+		dead_since = CASE WHEN ? = '' THEN NULL WHEN dead_since IS NULL THEN ? ELSE dead_since END
+		WHERE alias = ? AND deleted_at IS NULL`,
+		reason, reason, time.Now().UTC(), alias,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) SetPageMetadata(ctx context.Context, alias string, page storage.PageMetadata) error {
+	const op = "storage.sqlite.SetPageMetadata"
+
+	res, err := s.wdb.ExecContext(ctx,
+		"UPDATE url SET page_title = ?, page_description = ?, page_image = ? WHERE alias = ? AND deleted_at IS NULL",
+		page.Title, page.Description, page.Image, alias,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	const op = "storage.sqlite.PurgeDeletedURLs"
+
+	tx, err := s.wdb.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM click_event WHERE alias IN (SELECT alias FROM url WHERE deleted_at < ?)",
+		deletedBefore.UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: delete click events: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM click_rollup WHERE alias IN (SELECT alias FROM url WHERE deleted_at < ?)",
+		deletedBefore.UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: delete click rollup: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM click_unique WHERE alias IN (SELECT alias FROM url WHERE deleted_at < ?)",
+		deletedBefore.UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: delete unique visitors: %w", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM url WHERE deleted_at < ?", deletedBefore.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return affected, nil
+}
+
+func (s *Storage) PurgeClickEvents(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.sqlite.PurgeClickEvents"
+
+	res, err := s.wdb.ExecContext(ctx, "DELETE FROM click_event WHERE created_at < ?", before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get rows affected: %w", op, err)
 	}
 
-	return resURL, nil
+	return affected, nil
 }
 
-// TODO: implement method
-// func (s *Storage) DeleteURL(alias string) error
+// archiveColumns are copied between url and url_archive. id is not copied: SQLite may
+// give the id of an archived link to a new one.
+const archiveColumns = "alias, url, expires_at, created_at, updated_at, version, hits, api_key_id, user_id, " +
+	"redirect_code, quarantine_reason, max_clicks, exhausted_at, webhook_url, domain, geo_targets, device_targets, " +
+	"split, query_params, pass_query, no_analytics, burn_after_reading, tags, description, dead_reason, dead_since, " +
+	"page_title, page_description, page_image, last_hit_at"
+
+// archiveBatchSize is the number of links moved to the archive in one transaction,
+// so redirects of other links don't wait for the writer long.
+const archiveBatchSize = 500
+
+func (s *Storage) ArchiveURLs(ctx context.Context, inactiveBefore time.Time) (int64, error) {
+	const op = "storage.sqlite.ArchiveURLs"
+
+	var (
+		archived int64
+		afterID  int64
+	)
+
+	for {
+		n, lastID, err := s.archiveBatch(ctx, inactiveBefore.UTC(), afterID)
+		if err != nil {
+			return archived, fmt.Errorf("%s: %w", op, err)
+		}
+
+		archived += n
+		if lastID == 0 {
+			return archived, nil
+		}
+
+		afterID = lastID
+	}
+}
+
+// archiveBatch moves up to archiveBatchSize inactive links with ids after afterID and
+// returns their number and the last id checked, zero if there are no more links.
+func (s *Storage) archiveBatch(ctx context.Context, inactiveBefore time.Time, afterID int64) (int64, int64, error) {
+	tx, err := s.wdb.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM url
+		WHERE id > ? AND deleted_at IS NULL AND COALESCE(last_hit_at, created_at) < ?
+		AND (updated_at IS NULL OR updated_at < ?)
+		ORDER BY id LIMIT ?`,
+		afterID, inactiveBefore, inactiveBefore, archiveBatchSize,
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("select urls: %w", err)
+	}
+
+	var ids []any
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+
+			return 0, 0, fmt.Errorf("scan row: %w", err)
+		}
+
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("select urls: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return 0, 0, nil
+	}
+
+	in := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO url_archive("+archiveColumns+", archived_at) SELECT "+archiveColumns+", ? FROM url WHERE id IN ("+in+")",
+		append([]any{time.Now().UTC()}, ids...)...,
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("copy urls: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM url WHERE id IN ("+in+")", ids...)
+	if err != nil {
+		return 0, 0, fmt.Errorf("delete urls: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return affected, ids[len(ids)-1].(int64), nil
+}
+
+// unarchive moves the link from the archive back to url and reports whether it was archived.
+func (s *Storage) unarchive(ctx context.Context, alias string) (bool, error) {
+	var found int
+	err := s.stmts.archived.QueryRowContext(ctx, alias).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("find archived url: %w", err)
+	}
+
+	tx, err := s.wdb.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO url("+archiveColumns+") SELECT "+archiveColumns+" FROM url_archive WHERE alias = ?", alias,
+	)
+	if err != nil {
+		return false, fmt.Errorf("restore archived url: %w", err)
+	}
+
+	// Ссылку мог уже вернуть параллельный запрос
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM url_archive WHERE alias = ?", alias); err != nil {
+		return false, fmt.Errorf("delete archived url: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+func (s *Storage) ListURLs(ctx context.Context, filter storage.ListFilter) ([]storage.URL, string, error) {
+	const op = "storage.sqlite.ListURLs"
+
+	query := "SELECT id, alias, url, created_at, updated_at, expires_at, version, hits, api_key_id, user_id, redirect_code, " +
+		"quarantine_reason, max_clicks, exhausted_at, webhook_url, domain, geo_targets, device_targets, split, " +
+		"query_params, pass_query, no_analytics, burn_after_reading, tags, description, dead_reason, dead_since, " +
+		"page_title, page_description, page_image "
+	if filter.Archived {
+		query += "FROM url_archive WHERE true"
+	} else {
+		query += "FROM url WHERE deleted_at IS NULL"
+	}
+	var args []any
+
+	if filter.Cursor != "" {
+		afterID, err := strconv.ParseInt(filter.Cursor, 10, 64)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", op, storage.ErrInvalidCursor)
+		}
+
+		query += " AND id > ?"
+		args = append(args, afterID)
+	}
+	if filter.AliasPrefix != "" {
+		query += " AND alias >= ?"
+		args = append(args, filter.AliasPrefix)
+
+		if bound := storage.PrefixUpperBound(filter.AliasPrefix); bound != "" {
+			query += " AND alias < ?"
+			args = append(args, bound)
+		}
+	}
+	if !filter.CreatedFrom.IsZero() {
+		query += " AND created_at >= ?"
+		args = append(args, filter.CreatedFrom.UTC())
+	}
+	if !filter.CreatedTo.IsZero() {
+		query += " AND created_at < ?"
+		args = append(args, filter.CreatedTo.UTC())
+	}
+	if filter.UserID != 0 {
+		query += " AND user_id = ?"
+		args = append(args, filter.UserID)
+	}
+	if filter.APIKeyID != 0 {
+		query += " AND api_key_id = ?"
+		args = append(args, filter.APIKeyID)
+	}
+	if filter.Tag != "" {
+		// Архив не индексируется по тегам
+		if filter.Archived {
+			query += " AND EXISTS (SELECT 1 FROM json_each(NULLIF(tags, '')) WHERE value = ?)"
+		} else {
+			query += " AND id IN (SELECT url_id FROM url_tag WHERE tag = ?)"
+		}
+		args = append(args, filter.Tag)
+	}
+	if filter.Query != "" {
+		// LIKE в SQLite не учитывает регистр латиницы
+		pattern := storage.LikePattern(filter.Query)
+		query += ` AND (alias LIKE ? ESCAPE '\' OR url LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\')`
+		args = append(args, pattern, pattern, pattern)
+	}
+	if filter.Dead {
+		query += " AND dead_reason <> ''"
+	}
+
+	// Запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	query += " ORDER BY id LIMIT ?"
+	args = append(args, filter.Limit+1)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	var urls []storage.URL
+
+	for rows.Next() {
+		var (
+			u           storage.URL
+			createdAt   sql.NullTime
+			updatedAt   sql.NullTime
+			expiresAt   sql.NullTime
+			apiKeyID    sql.NullInt64
+			userID      sql.NullInt64
+			exhaustedAt sql.NullTime
+			deadSince   sql.NullTime
+		)
+
+		err := rows.Scan(
+			&u.ID, &u.Alias, &u.URL, &createdAt, &updatedAt, &expiresAt, &u.Version, &u.Hits, &apiKeyID, &userID, &u.RedirectCode,
+			&u.QuarantineReason, &u.MaxClicks, &exhaustedAt, &u.WebhookURL, &u.Domain,
+			&u.GeoTargets, &u.DeviceTargets, &u.Split, &u.QueryParams, &u.PassQuery, &u.NoAnalytics, &u.BurnAfterReading, &u.Tags, &u.Description,
+			&u.DeadReason, &deadSince, &u.Page.Title, &u.Page.Description, &u.Page.Image,
+		)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: scan row: %w", op, err)
+		}
+
+		u.CreatedAt = createdAt.Time
+		u.UpdatedAt = updatedAt.Time
+		u.ExpiresAt = expiresAt.Time
+		u.APIKeyID = apiKeyID.Int64
+		u.UserID = userID.Int64
+		u.ExhaustedAt = exhaustedAt.Time
+		u.DeadSince = deadSince.Time
+
+		urls = append(urls, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var next string
+	if len(urls) > filter.Limit {
+		urls = urls[:filter.Limit]
+		next = strconv.FormatInt(urls[len(urls)-1].ID, 10)
+	}
+
+	return urls, next, nil
+}
+
+func (s *Storage) IncrementHits(ctx context.Context, hits map[string]int64) error {
+	const op = "storage.sqlite.IncrementHits"
+
+	tx, err := s.wdb.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt := tx.StmtContext(ctx, s.stmts.incrementHits)
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+
+	for alias, n := range hits {
+		if _, err := stmt.ExecContext(ctx, n, now, alias); err != nil {
+			return fmt.Errorf("%s: execute statement: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveClickEvents(ctx context.Context, events []storage.ClickEvent) error {
+	const op = "storage.sqlite.SaveClickEvents"
+
+	tx, err := s.wdb.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt := tx.StmtContext(ctx, s.stmts.saveClickEvent)
+	defer stmt.Close()
+
+	for _, e := range events {
+		if _, err := stmt.ExecContext(ctx, e.Alias, e.Time.UTC(), e.Referrer, e.UserAgent, e.Browser, e.IP, e.Variant,
+			e.ReferrerHost, e.UTMSource, e.UTMMedium, e.UTMCampaign, e.Country, e.City); err != nil {
+			return fmt.Errorf("%s: execute statement: %w", op, err)
+		}
+	}
+
+	for _, v := range storage.VisitorSketches(events) {
+		var sketch []byte
+
+		err := tx.QueryRowContext(ctx, "SELECT sketch FROM click_unique WHERE alias = ? AND day = ?", v.Alias, v.Day).
+			Scan(&sketch)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: get visitors: %w", op, err)
+		}
+
+		sketch, err = storage.MergeSketch(sketch, v.Sketch)
+		if err != nil {
+			return fmt.Errorf("%s: merge visitors: %w", op, err)
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO click_unique(alias, day, sketch) VALUES(?, ?, ?)
+			ON CONFLICT(alias, day) DO UPDATE SET sketch = excluded.sketch`, v.Alias, v.Day, sketch)
+		if err != nil {
+			return fmt.Errorf("%s: save visitors: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetClickStats(ctx context.Context, alias string) (storage.ClickStats, error) {
+	const op = "storage.sqlite.GetClickStats"
+
+	var exists bool
+
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM url WHERE alias = ? AND deleted_at IS NULL)", alias).Scan(&exists)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	if !exists {
+		return storage.ClickStats{}, storage.ErrURLNotFound
+	}
+
+	var stats storage.ClickStats
+
+	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM click_event WHERE alias = ?", alias).Scan(&stats.Total)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks: %w", op, err)
+	}
+
+	// created_at хранится в UTC в виде строки, поэтому первые 10 символов - это дата
+	stats.ByDay, err = s.countClicks(ctx, `SELECT substr(created_at, 1, 10) AS k, COUNT(*) FROM click_event
+		WHERE alias = ? GROUP BY k ORDER BY k`, alias)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by day: %w", op, err)
+	}
+
+	stats.ByReferrer, err = s.countClicks(ctx, `SELECT CASE referrer WHEN '' THEN ? ELSE referrer END AS k, COUNT(*) AS c
+		FROM click_event WHERE alias = ? GROUP BY k ORDER BY c DESC, k LIMIT ?`,
+		storage.DirectReferrer, alias, storage.StatsTopLimit)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by referrer: %w", op, err)
+	}
+
+	stats.ByBrowser, err = s.countClicks(ctx, `SELECT browser AS k, COUNT(*) AS c FROM click_event
+		WHERE alias = ? GROUP BY k ORDER BY c DESC, k LIMIT ?`, alias, storage.StatsTopLimit)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by browser: %w", op, err)
+	}
+
+	stats.ByVariant, err = s.countClicks(ctx, `SELECT variant AS k, COUNT(*) FROM click_event
+		WHERE alias = ? AND variant <> '' GROUP BY k ORDER BY k`, alias)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by variant: %w", op, err)
+	}
+
+	// Старые переходы с Referer, но без хоста, не попадают ни в один сайт
+	stats.ByReferrerHost, err = s.countClicks(ctx, `SELECT CASE referrer WHEN '' THEN ? ELSE referrer_host END AS k, COUNT(*) AS c
+		FROM click_event WHERE alias = ? AND (referrer = '' OR referrer_host <> '') GROUP BY k ORDER BY c DESC, k LIMIT ?`,
+		storage.DirectReferrer, alias, storage.StatsTopLimit)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by referrer host: %w", op, err)
+	}
+
+	stats.BySource, err = s.countClicks(ctx, `SELECT utm_source AS k, COUNT(*) AS c FROM click_event
+		WHERE alias = ? AND utm_source <> '' GROUP BY k ORDER BY c DESC, k LIMIT ?`, alias, storage.StatsTopLimit)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by source: %w", op, err)
+	}
+
+	stats.ByMedium, err = s.countClicks(ctx, `SELECT utm_medium AS k, COUNT(*) AS c FROM click_event
+		WHERE alias = ? AND utm_medium <> '' GROUP BY k ORDER BY c DESC, k LIMIT ?`, alias, storage.StatsTopLimit)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by medium: %w", op, err)
+	}
+
+	stats.ByCampaign, err = s.countClicks(ctx, `SELECT utm_campaign AS k, COUNT(*) AS c FROM click_event
+		WHERE alias = ? AND utm_campaign <> '' GROUP BY k ORDER BY c DESC, k LIMIT ?`, alias, storage.StatsTopLimit)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by campaign: %w", op, err)
+	}
+
+	stats.ByCountry, err = s.countClicks(ctx, `SELECT country AS k, COUNT(*) AS c FROM click_event
+		WHERE alias = ? AND country <> '' GROUP BY k ORDER BY c DESC, k LIMIT ?`, alias, storage.StatsTopLimit)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by country: %w", op, err)
+	}
+
+	// Ключ совпадает с storage.CityKey
+	stats.ByCity, err = s.countClicks(ctx, `SELECT city || ', ' || country AS k, COUNT(*) AS c FROM click_event
+		WHERE alias = ? AND city <> '' GROUP BY k ORDER BY c DESC, k LIMIT ?`, alias, storage.StatsTopLimit)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks by city: %w", op, err)
+	}
+
+	stats.Uniques, stats.UniquesByDay, err = s.countUniques(ctx, alias)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count uniques: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+// countUniques estimates visitors of the link from the sketches of its days.
+func (s *Storage) countUniques(ctx context.Context, alias string) (int64, []storage.Count, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT day, sketch FROM click_unique WHERE alias = ?", alias)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var days []storage.DaySketch
+
+	for rows.Next() {
+		var d storage.DaySketch
+		if err := rows.Scan(&d.Day, &d.Sketch); err != nil {
+			return 0, nil, err
+		}
+
+		days = append(days, d)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	return storage.CountUniques(days)
+}
+
+// countClicks runs query returning (key, count) rows.
+func (s *Storage) countClicks(ctx context.Context, query string, args ...any) ([]storage.Count, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []storage.Count
+
+	for rows.Next() {
+		var c storage.Count
+		if err := rows.Scan(&c.Key, &c.Count); err != nil {
+			return nil, err
+		}
+
+		counts = append(counts, c)
+	}
+
+	return counts, rows.Err()
+}
+
+// hourLayout is the format of click_rollup.bucket, the prefix of click_event.created_at.
+const hourLayout = "2006-01-02 15"
+
+// GetHourlyClicks reads the rolled up hours from click_rollup and counts the rest of
+// the range from click_event. from and to are truncated to the hour.
+func (s *Storage) GetHourlyClicks(ctx context.Context, alias string, from, to time.Time) ([]storage.ClickBucket, error) {
+	const op = "storage.sqlite.GetHourlyClicks"
+
+	var exists bool
+
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM url WHERE alias = ? AND deleted_at IS NULL)", alias).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	if !exists {
+		return nil, storage.ErrURLNotFound
+	}
+
+	var rolledUntil sql.NullTime
+
+	err = s.db.QueryRowContext(ctx, "SELECT rolled_until FROM click_rollup_state WHERE id = 1").Scan(&rolledUntil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: get rollup state: %w", op, err)
+	}
+
+	from, to = from.UTC().Truncate(time.Hour), to.UTC().Truncate(time.Hour)
+	split := rolledUntil.Time.UTC()
+
+	var counts []storage.Count
+
+	if rollupTo := minTime(to, split); from.Before(rollupTo) {
+		rolled, err := s.countClicks(ctx, "SELECT bucket, clicks FROM click_rollup WHERE alias = ? AND bucket >= ? AND bucket < ?",
+			alias, from.Format(hourLayout), rollupTo.Format(hourLayout))
+		if err != nil {
+			return nil, fmt.Errorf("%s: read rollup: %w", op, err)
+		}
+
+		counts = append(counts, rolled...)
+	}
+
+	// Часы, которые еще не свернуты, считаются по самим событиям
+	if rawFrom := maxTime(from, split); rawFrom.Before(to) {
+		raw, err := s.countClicks(ctx, `SELECT substr(created_at, 1, 13) AS k, COUNT(*) FROM click_event
+			WHERE alias = ? AND created_at >= ? AND created_at < ? GROUP BY k`, alias, rawFrom, to)
+		if err != nil {
+			return nil, fmt.Errorf("%s: count clicks: %w", op, err)
+		}
+
+		counts = append(counts, raw...)
+	}
+
+	byHour := make(map[time.Time]int64, len(counts))
+	for _, c := range counts {
+		start, err := time.Parse(hourLayout, c.Key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid hour %q: %w", op, c.Key, err)
+		}
+
+		byHour[start] += c.Count
+	}
+
+	return storage.SortedBuckets(byHour), nil
+}
+
+func (s *Storage) ExportClickEvents(ctx context.Context, filter storage.ClickFilter, fn func(storage.ClickEvent) error) error {
+	const op = "storage.sqlite.ExportClickEvents"
+
+	query := `SELECT alias, created_at, referrer, referrer_host, user_agent, browser, ip, variant,
+		utm_source, utm_medium, utm_campaign, country, city FROM click_event WHERE created_at >= ? AND created_at < ?`
+	args := []any{filter.From.UTC(), filter.To.UTC()}
+
+	if filter.Alias != "" {
+		var exists bool
+
+		err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM url WHERE alias = ? AND deleted_at IS NULL)",
+			filter.Alias).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("%s: execute statement: %w", op, err)
+		}
+		if !exists {
+			return storage.ErrURLNotFound
+		}
+
+		query += " AND alias = ?"
+		args = append(args, filter.Alias)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query+" ORDER BY created_at, id", args...)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e storage.ClickEvent
+		if err := rows.Scan(&e.Alias, &e.Time, &e.Referrer, &e.ReferrerHost, &e.UserAgent, &e.Browser, &e.IP, &e.Variant,
+			&e.UTMSource, &e.UTMMedium, &e.UTMCampaign, &e.Country, &e.City); err != nil {
+			return fmt.Errorf("%s: scan row: %w", op, err)
+		}
+
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetDailyClicks, like GetHourlyClicks, reads the rolled up hours from click_rollup
+// and counts the rest of the range from click_event.
+func (s *Storage) GetDailyClicks(ctx context.Context, from, to time.Time) ([]storage.DailyClicks, error) {
+	const op = "storage.sqlite.GetDailyClicks"
+
+	var rolledUntil sql.NullTime
+
+	err := s.db.QueryRowContext(ctx, "SELECT rolled_until FROM click_rollup_state WHERE id = 1").Scan(&rolledUntil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: get rollup state: %w", op, err)
+	}
+
+	from, to = from.UTC().Truncate(24*time.Hour), to.UTC().Truncate(24*time.Hour)
+	split := rolledUntil.Time.UTC()
+
+	var days []storage.DailyClicks
+
+	if rollupTo := minTime(to, split); from.Before(rollupTo) {
+		rolled, err := s.countDays(ctx, `SELECT alias, substr(bucket, 1, 10) AS d, SUM(clicks) FROM click_rollup
+			WHERE bucket >= ? AND bucket < ? GROUP BY alias, d`, from.Format(hourLayout), rollupTo.Format(hourLayout))
+		if err != nil {
+			return nil, fmt.Errorf("%s: read rollup: %w", op, err)
+		}
+
+		days = append(days, rolled...)
+	}
+
+	if rawFrom := maxTime(from, split); rawFrom.Before(to) {
+		raw, err := s.countDays(ctx, `SELECT alias, substr(created_at, 1, 10) AS d, COUNT(*) FROM click_event
+			WHERE created_at >= ? AND created_at < ? GROUP BY alias, d`, rawFrom, to)
+		if err != nil {
+			return nil, fmt.Errorf("%s: count clicks: %w", op, err)
+		}
+
+		days = append(days, raw...)
+	}
+
+	return storage.MergeDailyClicks(days), nil
+}
+
+// countDays runs query returning (alias, day, count) rows.
+func (s *Storage) countDays(ctx context.Context, query string, args ...any) ([]storage.DailyClicks, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []storage.DailyClicks
+
+	for rows.Next() {
+		var d storage.DailyClicks
+		if err := rows.Scan(&d.Alias, &d.Day, &d.Clicks); err != nil {
+			return nil, err
+		}
+
+		days = append(days, d)
+	}
+
+	return days, rows.Err()
+}
+
+// rollupChunk is the longest period rolled up in one transaction, so redirects don't
+// wait for the writer long.
+const rollupChunk = 24 * time.Hour
+
+func (s *Storage) RollupClicks(ctx context.Context, until time.Time) (int64, error) {
+	const op = "storage.sqlite.RollupClicks"
+
+	until = until.UTC().Truncate(time.Hour)
+
+	var total int64
+
+	for {
+		n, done, err := s.rollupChunk(ctx, until)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("%s: %w", op, err)
+		}
+		if done {
+			return total, nil
+		}
+	}
+}
+
+// rollupChunk rolls up clicks of at most rollupChunk after the last rolled up hour.
+// done is true once everything before until is rolled up.
+func (s *Storage) rollupChunk(ctx context.Context, until time.Time) (n int64, done bool, err error) {
+	tx, err := s.wdb.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var rolledUntil sql.NullTime
+
+	if err := tx.QueryRowContext(ctx, "SELECT rolled_until FROM click_rollup_state WHERE id = 1").Scan(&rolledUntil); err != nil {
+		return 0, false, fmt.Errorf("get rollup state: %w", err)
+	}
+
+	// Периоды без переходов пропускаем сразу до следующего перехода
+	var next time.Time
+
+	err = tx.QueryRowContext(ctx, "SELECT created_at FROM click_event WHERE created_at >= ? ORDER BY created_at LIMIT 1",
+		rolledUntil.Time.UTC()).Scan(&next)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, false, fmt.Errorf("find next click: %w", err)
+	}
+
+	from := next.UTC().Truncate(time.Hour)
+	to := minTime(from.Add(rollupChunk), until)
+
+	if errors.Is(err, sql.ErrNoRows) || !from.Before(until) {
+		to = until
+	} else {
+		res, err := tx.ExecContext(ctx, `INSERT INTO click_rollup(alias, bucket, clicks)
+			SELECT alias, substr(created_at, 1, 13), COUNT(*) FROM click_event
+			WHERE created_at >= ? AND created_at < ? GROUP BY alias, substr(created_at, 1, 13)
+			ON CONFLICT(alias, bucket) DO UPDATE SET clicks = clicks + excluded.clicks`, from, to)
+		if err != nil {
+			return 0, false, fmt.Errorf("roll up clicks: %w", err)
+		}
+
+		if n, err = res.RowsAffected(); err != nil {
+			return 0, false, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+	}
+
+	// Свернутое не откатываем, даже если часы сервера ушли назад
+	if rolledUntil.Valid && !to.After(rolledUntil.Time) {
+		return n, true, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE click_rollup_state SET rolled_until = ? WHERE id = 1", to); err != nil {
+		return 0, false, fmt.Errorf("update rollup state: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, false, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return n, !to.Before(until), nil
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
+
+// GetServiceStats counts clicks of the windows in one pass over the last 30 days of
+// click_event, which is read by the created_at index.
+func (s *Storage) GetServiceStats(ctx context.Context, now time.Time) (storage.ServiceStats, error) {
+	const op = "storage.sqlite.GetServiceStats"
+
+	day, week, month := storage.StatsWindows(now)
+
+	var stats storage.ServiceStats
+
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM url WHERE deleted_at IS NULL").Scan(&stats.Links)
+	if err != nil {
+		return storage.ServiceStats{}, fmt.Errorf("%s: count links: %w", op, err)
+	}
+
+	err = s.db.QueryRowContext(ctx, `SELECT COALESCE(SUM(created_at >= ?), 0), COALESCE(SUM(created_at >= ?), 0), COUNT(*)
+		FROM click_event WHERE created_at >= ?`, day, week, month).
+		Scan(&stats.ClicksToday, &stats.Clicks7d, &stats.Clicks30d)
+	if err != nil {
+		return storage.ServiceStats{}, fmt.Errorf("%s: count clicks: %w", op, err)
+	}
+
+	stats.TopLinks, err = s.countClicks(ctx, `SELECT alias, hits FROM url
+		WHERE deleted_at IS NULL AND hits > 0 ORDER BY hits DESC, alias LIMIT ?`, storage.StatsTopLimit)
+	if err != nil {
+		return storage.ServiceStats{}, fmt.Errorf("%s: top links: %w", op, err)
+	}
+
+	stats.CreatedByDay, err = s.countClicks(ctx, `SELECT substr(created_at, 1, 10) AS k, COUNT(*) FROM url
+		WHERE deleted_at IS NULL AND created_at >= ? GROUP BY k ORDER BY k`, month.Truncate(24*time.Hour))
+	if err != nil {
+		return storage.ServiceStats{}, fmt.Errorf("%s: count links by day: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+func (s *Storage) DeleteExpiredURLs(ctx context.Context) ([]string, error) {
+	const op = "storage.sqlite.DeleteExpiredURLs"
+
+	var aliases []string
+	now := time.Now().UTC()
+
+	// Архивные ссылки истекают так же, как активные
+	for _, table := range []string{"url", "url_archive"} {
+		rows, err := s.wdb.QueryContext(ctx,
+			"DELETE FROM "+table+" WHERE expires_at IS NOT NULL AND expires_at <= ? RETURNING alias", now)
+		if err != nil {
+			return aliases, fmt.Errorf("%s: execute statement: %w", op, err)
+		}
+
+		for rows.Next() {
+			var alias string
+			if err := rows.Scan(&alias); err != nil {
+				rows.Close()
+
+				return aliases, fmt.Errorf("%s: scan row: %w", op, err)
+			}
+
+			aliases = append(aliases, alias)
+		}
+
+		err = rows.Err()
+		rows.Close()
+
+		if err != nil {
+			return aliases, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return aliases, nil
+}
+
+func (s *Storage) SaveAPIKey(ctx context.Context, key storage.APIKey) (int64, error) {
+	const op = "storage.sqlite.SaveAPIKey"
+
+	res, err := s.wdb.ExecContext(ctx,
+		"INSERT INTO api_key(name, hash, tenant, role, signing_secret, created_at) VALUES(?, ?, ?, ?, ?, ?)",
+		key.Name, key.Hash, key.Tenant, key.Role, key.SigningSecret, time.Now().UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetAPIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error) {
+	const op = "storage.sqlite.GetAPIKeyByHash"
+
+	return s.getAPIKey(ctx, op, "hash = ?", hash)
+}
+
+func (s *Storage) GetAPIKey(ctx context.Context, id int64) (storage.APIKey, error) {
+	const op = "storage.sqlite.GetAPIKey"
+
+	return s.getAPIKey(ctx, op, "id = ?", id)
+}
+
+// getAPIKey returns the key matching the condition with one argument.
+func (s *Storage) getAPIKey(ctx context.Context, op, cond string, arg any) (storage.APIKey, error) {
+	var (
+		key       storage.APIKey
+		revokedAt sql.NullTime
+	)
+
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id, name, hash, tenant, role, signing_secret, created_at, revoked_at FROM api_key WHERE "+cond, arg,
+	).Scan(&key.ID, &key.Name, &key.Hash, &key.Tenant, &key.Role, &key.SigningSecret, &key.CreatedAt, &revokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.APIKey{}, storage.ErrAPIKeyNotFound
+		}
+
+		return storage.APIKey{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	key.RevokedAt = revokedAt.Time
+
+	return key, nil
+}
+
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64) error {
+	const op = "storage.sqlite.RevokeAPIKey"
+
+	res, err := s.wdb.ExecContext(ctx,
+		"UPDATE api_key SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", time.Now().UTC(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveUser(ctx context.Context, user storage.User) (int64, error) {
+	const op = "storage.sqlite.SaveUser"
+
+	res, err := s.wdb.ExecContext(ctx,
+		"INSERT INTO users(email, pass_hash, tenant, role, created_at, verified_at) VALUES(?, ?, ?, ?, ?, ?)",
+		user.Email, user.PassHash, user.Tenant, user.Role, time.Now().UTC(), nullTime(user.VerifiedAt),
+	)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+		}
+
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetUser(ctx context.Context, email string) (storage.User, error) {
+	const op = "storage.sqlite.GetUser"
+
+	var (
+		user       storage.User
+		verifiedAt sql.NullTime
+	)
+
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id, email, pass_hash, tenant, role, created_at, verified_at FROM users WHERE email = ?", email,
+	).Scan(&user.ID, &user.Email, &user.PassHash, &user.Tenant, &user.Role, &user.CreatedAt, &verifiedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.User{}, storage.ErrUserNotFound
+		}
+
+		return storage.User{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	user.VerifiedAt = verifiedAt.Time
+
+	return user, nil
+}
+
+func (s *Storage) SetUserRole(ctx context.Context, email, role string) error {
+	const op = "storage.sqlite.SetUserRole"
+
+	res, err := s.wdb.ExecContext(ctx, "UPDATE users SET role = ? WHERE email = ?", role, email)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) VerifyUser(ctx context.Context, email string) error {
+	const op = "storage.sqlite.VerifyUser"
+
+	res, err := s.wdb.ExecContext(ctx,
+		"UPDATE users SET verified_at = COALESCE(verified_at, ?) WHERE email = ?", time.Now().UTC(), email,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) SetUserPassword(ctx context.Context, email string, passHash []byte) error {
+	const op = "storage.sqlite.SetUserPassword"
+
+	res, err := s.wdb.ExecContext(ctx,
+		"UPDATE users SET pass_hash = ?, verified_at = COALESCE(verified_at, ?) WHERE email = ?",
+		passHash, time.Now().UTC(), email,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SaveUserToken saves the token and removes expired ones, so unused tokens don't pile up.
+func (s *Storage) SaveUserToken(ctx context.Context, token storage.UserToken) error {
+	const op = "storage.sqlite.SaveUserToken"
+
+	now := time.Now().UTC()
+
+	if _, err := s.wdb.ExecContext(ctx, "DELETE FROM user_token WHERE expires_at <= ?", now); err != nil {
+		return fmt.Errorf("%s: failed to delete expired tokens: %w", op, err)
+	}
+
+	_, err := s.wdb.ExecContext(ctx,
+		"INSERT INTO user_token(hash, email, purpose, expires_at) VALUES(?, ?, ?, ?)",
+		token.Hash, token.Email, token.Purpose, token.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ConsumeUserToken(ctx context.Context, hash, purpose string) (storage.UserToken, error) {
+	const op = "storage.sqlite.ConsumeUserToken"
+
+	token := storage.UserToken{Hash: hash, Purpose: purpose}
+
+	// Токен удаляется в том же запросе, которым читается, поэтому использовать его можно один раз
+	err := s.wdb.QueryRowContext(ctx,
+		"DELETE FROM user_token WHERE hash = ? AND purpose = ? RETURNING email, expires_at", hash, purpose,
+	).Scan(&token.Email, &token.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.UserToken{}, storage.ErrTokenNotFound
+		}
+
+		return storage.UserToken{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	if !token.ExpiresAt.After(time.Now()) {
+		return storage.UserToken{}, storage.ErrTokenNotFound
+	}
+
+	return token, nil
+}
+
+func (s *Storage) SaveDomain(ctx context.Context, domain storage.Domain) error {
+	const op = "storage.sqlite.SaveDomain"
+
+	_, err := s.wdb.ExecContext(ctx, "INSERT INTO domain(host, created_at) VALUES(?, ?)", domain.Host, time.Now().UTC())
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.Code == sqlite3.ErrConstraint {
+			return fmt.Errorf("%s: %w", op, storage.ErrDomainExists)
+		}
+
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetDomain(ctx context.Context, host string) (storage.Domain, error) {
+	const op = "storage.sqlite.GetDomain"
+
+	var domain storage.Domain
+
+	err := s.db.QueryRowContext(ctx, "SELECT host, created_at FROM domain WHERE host = ?", host).Scan(&domain.Host, &domain.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.Domain{}, storage.ErrDomainNotFound
+		}
+
+		return storage.Domain{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return domain, nil
+}
+
+func (s *Storage) ListDomains(ctx context.Context) ([]storage.Domain, error) {
+	const op = "storage.sqlite.ListDomains"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT host, created_at FROM domain ORDER BY host")
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	var domains []storage.Domain
+	for rows.Next() {
+		var domain storage.Domain
+		if err := rows.Scan(&domain.Host, &domain.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+
+		domains = append(domains, domain)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return domains, nil
+}
+
+func (s *Storage) DeleteDomain(ctx context.Context, host string) error {
+	const op = "storage.sqlite.DeleteDomain"
+
+	res, err := s.wdb.ExecContext(ctx, "DELETE FROM domain WHERE host = ?", host)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrDomainNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveReport(ctx context.Context, report storage.Report) (int64, error) {
+	const op = "storage.sqlite.SaveReport"
+
+	res, err := s.wdb.ExecContext(ctx,
+		"INSERT INTO report(alias, category, details, contact, status, created_at) VALUES(?, ?, ?, ?, ?, ?)",
+		report.Alias, report.Category, report.Details, report.Contact, storage.ReportOpen, time.Now().UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) ListReports(ctx context.Context, status string, limit int) ([]storage.Report, error) {
+	const op = "storage.sqlite.ListReports"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, alias, category, details, contact, status, created_at, resolved_at
+		FROM report
+		WHERE ? = '' OR status = ?
+		ORDER BY id
+		LIMIT ?`,
+		status, status, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	var reports []storage.Report
+	for rows.Next() {
+		var (
+			report     storage.Report
+			resolvedAt sql.NullTime
+		)
+		err := rows.Scan(&report.ID, &report.Alias, &report.Category, &report.Details, &report.Contact,
+			&report.Status, &report.CreatedAt, &resolvedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+
+		report.ResolvedAt = resolvedAt.Time
+		reports = append(reports, report)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return reports, nil
+}
+
+func (s *Storage) ResolveReports(ctx context.Context, alias, status string) (int64, error) {
+	const op = "storage.sqlite.ResolveReports"
+
+	res, err := s.wdb.ExecContext(ctx,
+		"UPDATE report SET status = ?, resolved_at = ? WHERE alias = ? AND status = ?",
+		status, time.Now().UTC(), alias, storage.ReportOpen,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	return affected, nil
+}
+
+func (s *Storage) BanHost(ctx context.Context, host string) error {
+	const op = "storage.sqlite.BanHost"
+
+	_, err := s.wdb.ExecContext(ctx,
+		"INSERT INTO banned_host(host, created_at) VALUES(?, ?) ON CONFLICT(host) DO NOTHING",
+		host, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) UnbanHost(ctx context.Context, host string) error {
+	const op = "storage.sqlite.UnbanHost"
+
+	res, err := s.wdb.ExecContext(ctx, "DELETE FROM banned_host WHERE host = ?", host)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrHostNotBanned
+	}
+
+	return nil
+}
+
+func (s *Storage) ListBannedHosts(ctx context.Context) ([]storage.BannedHost, error) {
+	const op = "storage.sqlite.ListBannedHosts"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT host, created_at FROM banned_host ORDER BY host")
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	var hosts []storage.BannedHost
+	for rows.Next() {
+		var host storage.BannedHost
+		if err := rows.Scan(&host.Host, &host.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+
+		hosts = append(hosts, host)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return hosts, nil
+}
+
+func (s *Storage) SaveWebhook(ctx context.Context, webhook storage.Webhook) (int64, error) {
+	const op = "storage.sqlite.SaveWebhook"
+
+	res, err := s.wdb.ExecContext(ctx,
+		"INSERT INTO webhook(url, events, secret, created_at) VALUES(?, ?, ?, ?)",
+		webhook.URL, webhook.Events, webhook.Secret, time.Now().UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetWebhook(ctx context.Context, id int64) (storage.Webhook, error) {
+	const op = "storage.sqlite.GetWebhook"
+
+	var webhook storage.Webhook
+
+	err := s.db.QueryRowContext(ctx, "SELECT id, url, events, secret, created_at FROM webhook WHERE id = ?", id).
+		Scan(&webhook.ID, &webhook.URL, &webhook.Events, &webhook.Secret, &webhook.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.Webhook{}, storage.ErrWebhookNotFound
+		}
+
+		return storage.Webhook{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return webhook, nil
+}
+
+func (s *Storage) ListWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	const op = "storage.sqlite.ListWebhooks"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id, url, events, secret, created_at FROM webhook ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	var webhooks []storage.Webhook
+	for rows.Next() {
+		var webhook storage.Webhook
+		if err := rows.Scan(&webhook.ID, &webhook.URL, &webhook.Events, &webhook.Secret, &webhook.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhooks, nil
+}
+
+func (s *Storage) UpdateWebhook(ctx context.Context, webhook storage.Webhook) error {
+	const op = "storage.sqlite.UpdateWebhook"
+
+	res, err := s.wdb.ExecContext(ctx,
+		"UPDATE webhook SET url = ?, events = ? WHERE id = ?",
+		webhook.URL, webhook.Events, webhook.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteWebhook(ctx context.Context, id int64) error {
+	const op = "storage.sqlite.DeleteWebhook"
+
+	tx, err := s.wdb.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM webhook WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if affected == 0 {
+		return storage.ErrWebhookNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM webhook_delivery WHERE webhook_id = ?", id); err != nil {
+		return fmt.Errorf("%s: delete deliveries: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveWebhookDelivery(ctx context.Context, delivery storage.WebhookDelivery) error {
+	const op = "storage.sqlite.SaveWebhookDelivery"
+
+	tx, err := s.wdb.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO webhook_delivery(webhook_id, event, alias, attempts, status_code, error, created_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?)`,
+		delivery.WebhookID, delivery.Event, delivery.Alias, delivery.Attempts, delivery.StatusCode, delivery.Error,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	// Журнал ограничен последними доставками, старые удаляются при записи новых
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM webhook_delivery
+		WHERE webhook_id = ? AND id <= (
+			SELECT id FROM webhook_delivery WHERE webhook_id = ? ORDER BY id DESC LIMIT 1 OFFSET ?)`,
+		delivery.WebhookID, delivery.WebhookID, storage.WebhookDeliveryLog,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: trim deliveries: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]storage.WebhookDelivery, error) {
+	const op = "storage.sqlite.ListWebhookDeliveries"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, webhook_id, event, alias, attempts, status_code, error, created_at
+		FROM webhook_delivery
+		WHERE webhook_id = ?
+		ORDER BY id DESC
+		LIMIT ?`,
+		webhookID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	var deliveries []storage.WebhookDelivery
+	for rows.Next() {
+		var d storage.WebhookDelivery
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Alias, &d.Attempts, &d.StatusCode, &d.Error, &d.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	if err := s.wdb.PingContext(ctx); err != nil {
+		return err
+	}
+
+	return s.db.PingContext(ctx)
+}
+
+// SchemaVersion returns the version of the latest applied migration.
+func (s *Storage) SchemaVersion() (int, error) {
+	return migrations.Version(s.db)
+}
+
+// Backup writes a consistent copy of the database to path with VACUUM INTO. It runs on
+// a read connection, so the server keeps serving and writing while it is copied.
+// path must not exist.
+func (s *Storage) Backup(ctx context.Context, path string) error {
+	const op = "storage.sqlite.Backup"
+
+	if _, err := s.db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Close() error {
+	err := s.stmts.close()
+
+	if s.db == s.wdb {
+		return errors.Join(err, s.db.Close())
+	}
+
+	return errors.Join(err, s.db.Close(), s.wdb.Close())
+}
+
+// nullTime converts zero time to NULL and stores the rest in UTC,
+// so that string comparison in SQLite matches time order.
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// nullID converts zero id to NULL.
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
@@ -0,0 +1,1124 @@
+package sqlite_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/hll"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/sqlite"
+)
+
+func TestConcurrentWrites(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{
+		JournalMode:  "WAL",
+		BusyTimeout:  time.Second,
+		ForeignKeys:  true,
+		MaxReadConns: 4,
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	const workers, saves = 8, 25
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*saves)
+
+	for w := 0; w < workers; w++ {
+		w := w
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < saves; i++ {
+				alias := fmt.Sprintf("w%d-%d", w, i)
+
+				if _, err := s.SaveURL(context.Background(), storage.URL{Alias: alias, URL: "https://example.com"}); err != nil {
+					errs <- err
+				}
+				if err := s.IncrementHits(context.Background(), map[string]int64{alias: 1}); err != nil {
+					errs <- err
+				}
+				if _, err := s.GetURL(context.Background(), alias); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
+
+func TestInMemory(t *testing.T) {
+	s, err := sqlite.New(":memory:", sqlite.Options{MaxReadConns: 4})
+	require.NoError(t, err)
+	defer s.Close()
+
+	_, err = s.SaveURL(context.Background(), storage.URL{Alias: "mem", URL: "https://example.com"})
+	require.NoError(t, err)
+
+	// Чтение должно идти в ту же базу, что и запись
+	_, err = s.GetURL(context.Background(), "mem")
+	require.NoError(t, err)
+}
+
+func TestCanceledContext(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	_, err = s.SaveURL(context.Background(), storage.URL{Alias: "canceled", URL: "https://example.com"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Отмененный запрос не должен доходить до базы
+	_, err = s.GetURL(ctx, "canceled")
+	require.ErrorIs(t, err, context.Canceled)
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "late", URL: "https://example.com"})
+	require.ErrorIs(t, err, context.Canceled)
+
+	_, err = s.GetURLInfo(context.Background(), "late")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestConsumeClick(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{
+		JournalMode:  "WAL",
+		BusyTimeout:  time.Second,
+		MaxReadConns: 4,
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	const maxClicks, clicks = 3, 10
+
+	_, err = s.SaveURL(context.Background(), storage.URL{Alias: "limited", URL: "https://example.com", MaxClicks: maxClicks})
+	require.NoError(t, err)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		consumed int
+	)
+
+	// Лишние переходы должны получить ErrURLExhausted даже при гонке
+	for i := 0; i < clicks; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := s.ConsumeClick(context.Background(), "limited")
+			if err == nil {
+				mu.Lock()
+				consumed++
+				mu.Unlock()
+
+				return
+			}
+
+			require.ErrorIs(t, err, storage.ErrURLExhausted)
+		}()
+	}
+
+	wg.Wait()
+
+	require.Equal(t, maxClicks, consumed)
+
+	_, err = s.GetURL(context.Background(), "limited")
+	require.ErrorIs(t, err, storage.ErrURLExhausted)
+
+	u, err := s.GetURLInfo(context.Background(), "limited")
+	require.NoError(t, err)
+	require.EqualValues(t, maxClicks, u.Hits)
+	require.False(t, u.ExhaustedAt.IsZero())
+}
+
+func TestBurnURL(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{
+		JournalMode:  "WAL",
+		BusyTimeout:  time.Second,
+		MaxReadConns: 4,
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	const clicks = 10
+
+	_, err = s.SaveURL(context.Background(), storage.URL{Alias: "secret", URL: "https://example.com", BurnAfterReading: true})
+	require.NoError(t, err)
+	_, err = s.SaveURL(context.Background(), storage.URL{Alias: "regular", URL: "https://example.com"})
+	require.NoError(t, err)
+
+	u, err := s.GetURL(context.Background(), "secret")
+	require.NoError(t, err)
+	require.True(t, u.BurnAfterReading)
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		burned int
+	)
+
+	// Из одновременных переходов ссылку сжигает только один
+	for i := 0; i < clicks; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := s.BurnURL(context.Background(), "secret")
+			if err == nil {
+				mu.Lock()
+				burned++
+				mu.Unlock()
+
+				return
+			}
+
+			require.ErrorIs(t, err, storage.ErrURLNotFound)
+		}()
+	}
+
+	wg.Wait()
+
+	require.Equal(t, 1, burned)
+
+	_, err = s.GetURL(context.Background(), "secret")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	// Обычные ссылки так не удаляются
+	require.ErrorIs(t, s.BurnURL(context.Background(), "regular"), storage.ErrURLNotFound)
+
+	_, err = s.GetURL(context.Background(), "regular")
+	require.NoError(t, err)
+}
+
+func TestTenant(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	_, err = s.SaveUser(context.Background(), storage.User{Email: "user@example.com", PassHash: []byte("hash"), Tenant: "brand"})
+	require.NoError(t, err)
+
+	user, err := s.GetUser(context.Background(), "user@example.com")
+	require.NoError(t, err)
+	require.Equal(t, "brand", user.Tenant)
+
+	_, err = s.SaveAPIKey(context.Background(), storage.APIKey{Name: "ci", Hash: "hash", Tenant: "brand"})
+	require.NoError(t, err)
+
+	key, err := s.GetAPIKeyByHash(context.Background(), "hash")
+	require.NoError(t, err)
+	require.Equal(t, "brand", key.Tenant)
+
+	// Одинаковые alias в разных тенантах - разные ссылки
+	for _, alias := range []string{"alias", "brand/alias"} {
+		_, err := s.SaveURL(context.Background(), storage.URL{Alias: alias, URL: "https://example.com/" + alias})
+		require.NoError(t, err)
+	}
+
+	u, err := s.GetURL(context.Background(), "brand/alias")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/brand/alias", u.URL)
+}
+
+func TestAPIKeySigningSecret(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	id, err := s.SaveAPIKey(context.Background(), storage.APIKey{Name: "ci", Hash: "hash", SigningSecret: "secret"})
+	require.NoError(t, err)
+
+	key, err := s.GetAPIKey(context.Background(), id)
+	require.NoError(t, err)
+	require.Equal(t, "hash", key.Hash)
+	require.Equal(t, "secret", key.SigningSecret)
+
+	_, err = s.GetAPIKey(context.Background(), id+1)
+	require.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+}
+
+func TestDomains(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.SaveDomain(context.Background(), storage.Domain{Host: "go.brand.example", CreatedAt: time.Now()}))
+	require.ErrorIs(t, s.SaveDomain(context.Background(), storage.Domain{Host: "go.brand.example", CreatedAt: time.Now()}), storage.ErrDomainExists)
+
+	d, err := s.GetDomain(context.Background(), "go.brand.example")
+	require.NoError(t, err)
+	require.Equal(t, "go.brand.example", d.Host)
+
+	list, err := s.ListDomains(context.Background())
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+
+	_, err = s.SaveURL(context.Background(), storage.URL{Alias: "alias", URL: "https://example.com/", Domain: "go.brand.example"})
+	require.NoError(t, err)
+
+	u, err := s.GetURL(context.Background(), "alias")
+	require.NoError(t, err)
+	require.Equal(t, "go.brand.example", u.Domain)
+
+	info, err := s.GetURLInfo(context.Background(), "alias")
+	require.NoError(t, err)
+	require.Equal(t, "go.brand.example", info.Domain)
+
+	require.NoError(t, s.DeleteDomain(context.Background(), "go.brand.example"))
+	require.ErrorIs(t, s.DeleteDomain(context.Background(), "go.brand.example"), storage.ErrDomainNotFound)
+
+	_, err = s.GetDomain(context.Background(), "go.brand.example")
+	require.ErrorIs(t, err, storage.ErrDomainNotFound)
+}
+
+func TestReports(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	for _, r := range []storage.Report{
+		{Alias: "alias", Category: "spam", Details: "ads", CreatedAt: time.Now()},
+		{Alias: "alias", Category: "phishing", Contact: "user@example.com", CreatedAt: time.Now()},
+		{Alias: "other", Category: "malware", CreatedAt: time.Now()},
+	} {
+		_, err := s.SaveReport(ctx, r)
+		require.NoError(t, err)
+	}
+
+	open, err := s.ListReports(ctx, storage.ReportOpen, 10)
+	require.NoError(t, err)
+	require.Len(t, open, 3)
+	require.Equal(t, "spam", open[0].Category)
+	require.Equal(t, "user@example.com", open[1].Contact)
+	require.True(t, open[0].ResolvedAt.IsZero())
+
+	n, err := s.ResolveReports(ctx, "alias", storage.ReportBanned)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+
+	// Закрытые жалобы повторно не разрешаются
+	n, err = s.ResolveReports(ctx, "alias", storage.ReportDismissed)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	banned, err := s.ListReports(ctx, storage.ReportBanned, 10)
+	require.NoError(t, err)
+	require.Len(t, banned, 2)
+	require.False(t, banned[0].ResolvedAt.IsZero())
+
+	all, err := s.ListReports(ctx, "", 2)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+}
+
+func TestBannedHosts(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	require.NoError(t, s.BanHost(ctx, "phish.example"))
+	require.NoError(t, s.BanHost(ctx, "evil.example"))
+	require.NoError(t, s.BanHost(ctx, "evil.example"))
+
+	hosts, err := s.ListBannedHosts(ctx)
+	require.NoError(t, err)
+	require.Len(t, hosts, 2)
+	require.Equal(t, "evil.example", hosts[0].Host)
+
+	require.NoError(t, s.UnbanHost(ctx, "evil.example"))
+	require.ErrorIs(t, s.UnbanHost(ctx, "evil.example"), storage.ErrHostNotBanned)
+}
+
+func TestTargets(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	targets := storage.GeoTargets{"US": "https://example.com/us", "EU": "https://example.com/eu"}
+	devices := storage.DeviceTargets{"ios": "https://apps.apple.com/app/id1"}
+	split := storage.Split{
+		Variants: []storage.Variant{
+			{Name: "A", URL: "https://example.com/a", Weight: 1},
+			{Name: "B", URL: "https://example.com/b", Weight: 2},
+		},
+		Sticky: storage.StickyIP,
+	}
+
+	params := storage.QueryParams{"utm_source": "shortener"}
+
+	_, err = s.SaveURL(context.Background(), storage.URL{
+		Alias: "geo", URL: "https://example.com/", GeoTargets: targets, DeviceTargets: devices, Split: split,
+		QueryParams: params, PassQuery: true,
+	})
+	require.NoError(t, err)
+	_, err = s.SaveURL(context.Background(), storage.URL{Alias: "plain", URL: "https://example.com/"})
+	require.NoError(t, err)
+
+	u, err := s.GetURL(context.Background(), "geo")
+	require.NoError(t, err)
+	require.Equal(t, targets, u.GeoTargets)
+	require.Equal(t, devices, u.DeviceTargets)
+	require.Equal(t, split, u.Split)
+	require.Equal(t, params, u.QueryParams)
+	require.True(t, u.PassQuery)
+
+	info, err := s.GetURLInfo(context.Background(), "geo")
+	require.NoError(t, err)
+	require.Equal(t, targets, info.GeoTargets)
+	require.Equal(t, devices, info.DeviceTargets)
+	require.Equal(t, split, info.Split)
+	require.Equal(t, params, info.QueryParams)
+	require.True(t, info.PassQuery)
+
+	u, err = s.GetURL(context.Background(), "plain")
+	require.NoError(t, err)
+	require.Nil(t, u.GeoTargets)
+	require.Empty(t, u.Split.Variants)
+	require.False(t, u.PassQuery)
+
+	now := time.Now()
+	require.NoError(t, s.SaveClickEvents(context.Background(), []storage.ClickEvent{
+		{Alias: "geo", Time: now, Variant: "B"},
+		{Alias: "geo", Time: now, Variant: "A"},
+		{Alias: "geo", Time: now, Variant: "B"},
+		{Alias: "geo", Time: now},
+	}))
+
+	stats, err := s.GetClickStats(context.Background(), "geo")
+	require.NoError(t, err)
+	require.Equal(t, []storage.Count{{Key: "A", Count: 1}, {Key: "B", Count: 2}}, stats.ByVariant)
+}
+
+func TestGetClickStats_Sources(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "promo", URL: "https://example.com/promo"})
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.NoError(t, s.SaveClickEvents(ctx, []storage.ClickEvent{
+		{Alias: "promo", Time: now, Referrer: "https://t.me/a", ReferrerHost: "t.me", UTMSource: "telegram", UTMMedium: "social"},
+		{Alias: "promo", Time: now, Referrer: "https://t.me/b", ReferrerHost: "t.me", UTMSource: "telegram", UTMMedium: "social"},
+		{Alias: "promo", Time: now, UTMSource: "newsletter", UTMMedium: "email", UTMCampaign: "autumn"},
+		{Alias: "promo", Time: now},
+		// Переход, сохраненный до появления хоста
+		{Alias: "promo", Time: now, Referrer: "https://old.example"},
+	}))
+
+	stats, err := s.GetClickStats(ctx, "promo")
+	require.NoError(t, err)
+	require.Equal(t, []storage.Count{{Key: "direct", Count: 2}, {Key: "t.me", Count: 2}}, stats.ByReferrerHost)
+	require.Equal(t, []storage.Count{{Key: "telegram", Count: 2}, {Key: "newsletter", Count: 1}}, stats.BySource)
+	require.Equal(t, []storage.Count{{Key: "social", Count: 2}, {Key: "email", Count: 1}}, stats.ByMedium)
+	require.Equal(t, []storage.Count{{Key: "autumn", Count: 1}}, stats.ByCampaign)
+}
+
+func TestGetClickStats_Location(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "promo", URL: "https://example.com/promo"})
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.NoError(t, s.SaveClickEvents(ctx, []storage.ClickEvent{
+		{Alias: "promo", Time: now, Country: "DE", City: "Berlin"},
+		{Alias: "promo", Time: now, Country: "DE", City: "Berlin"},
+		{Alias: "promo", Time: now, Country: "DE"},
+		{Alias: "promo", Time: now, Country: "US", City: "Berlin"},
+		{Alias: "promo", Time: now},
+	}))
+
+	stats, err := s.GetClickStats(ctx, "promo")
+	require.NoError(t, err)
+	require.Equal(t, []storage.Count{{Key: "DE", Count: 3}, {Key: "US", Count: 1}}, stats.ByCountry)
+	require.Equal(t, []storage.Count{{Key: "Berlin, DE", Count: 2}, {Key: "Berlin, US", Count: 1}}, stats.ByCity)
+}
+
+func TestGetClickStats_Uniques(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "promo", URL: "https://example.com/promo"})
+	require.NoError(t, err)
+
+	today := time.Now().UTC()
+	yesterday := today.AddDate(0, 0, -1)
+
+	// Посетители второй пачки дописываются в уже сохраненный день
+	for _, batch := range [][]storage.ClickEvent{
+		{
+			{Alias: "promo", Time: yesterday, Visitor: hll.Hash("1")},
+			{Alias: "promo", Time: today, Visitor: hll.Hash("1")},
+			{Alias: "promo", Time: today, Visitor: hll.Hash("2")},
+		},
+		{
+			{Alias: "promo", Time: today, Visitor: hll.Hash("2")},
+			{Alias: "promo", Time: today, Visitor: hll.Hash("3")},
+			{Alias: "promo", Time: today},
+		},
+	} {
+		require.NoError(t, s.SaveClickEvents(ctx, batch))
+	}
+
+	stats, err := s.GetClickStats(ctx, "promo")
+	require.NoError(t, err)
+	require.Equal(t, int64(6), stats.Total)
+	require.Equal(t, int64(3), stats.Uniques)
+	require.Equal(t, []storage.Count{
+		{Key: yesterday.Format("2006-01-02"), Count: 1},
+		{Key: today.Format("2006-01-02"), Count: 3},
+	}, stats.UniquesByDay)
+}
+
+func TestPurgeClickEvents(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "promo", URL: "https://example.com/promo"})
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+	old := now.AddDate(0, 0, -100)
+
+	require.NoError(t, s.SaveClickEvents(ctx, []storage.ClickEvent{
+		{Alias: "promo", Time: old, Visitor: hll.Hash("1")},
+		{Alias: "promo", Time: old, Visitor: hll.Hash("2")},
+		{Alias: "promo", Time: now, Visitor: hll.Hash("3")},
+	}))
+
+	purged, err := s.PurgeClickEvents(ctx, now.AddDate(0, 0, -90))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), purged)
+
+	// Посетители старых переходов остаются в оценке уникальных
+	stats, err := s.GetClickStats(ctx, "promo")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), stats.Total)
+	require.Equal(t, int64(3), stats.Uniques)
+}
+
+func TestNoAnalytics(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "private", URL: "https://example.com/", NoAnalytics: true})
+	require.NoError(t, err)
+
+	u, err := s.GetURL(ctx, "private")
+	require.NoError(t, err)
+	require.True(t, u.NoAnalytics)
+
+	off := false
+	_, err = s.UpdateURL(ctx, "private", storage.URLUpdate{NoAnalytics: &off}, u.Version)
+	require.NoError(t, err)
+
+	u, err = s.GetURLInfo(ctx, "private")
+	require.NoError(t, err)
+	require.False(t, u.NoAnalytics)
+}
+
+func TestSearch(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	_, err = s.SaveURL(ctx, storage.URL{
+		Alias: "docs", URL: "https://example.com/docs", Tags: storage.Tags{"campaign-x", "docs"}, Description: "API Reference",
+	})
+	require.NoError(t, err)
+	_, err = s.SaveURLs(ctx, []storage.URL{
+		{Alias: "promo", URL: "https://example.com/promo", Tags: storage.Tags{"campaign-x"}},
+		{Alias: "plain", URL: "https://example.com/100%_off"},
+	})
+	require.NoError(t, err)
+
+	info, err := s.GetURLInfo(ctx, "docs")
+	require.NoError(t, err)
+	require.Equal(t, storage.Tags{"campaign-x", "docs"}, info.Tags)
+	require.Equal(t, "API Reference", info.Description)
+
+	cases := []struct {
+		name    string
+		filter  storage.ListFilter
+		aliases []string
+	}{
+		{name: "Tag", filter: storage.ListFilter{Tag: "campaign-x"}, aliases: []string{"docs", "promo"}},
+		{name: "Tag and query", filter: storage.ListFilter{Tag: "campaign-x", Query: "reference"}, aliases: []string{"docs"}},
+		{name: "Query in URL", filter: storage.ListFilter{Query: "PROMO"}, aliases: []string{"promo"}},
+		{name: "Wildcards", filter: storage.ListFilter{Query: "%_"}, aliases: []string{"plain"}},
+		{name: "Unknown tag", filter: storage.ListFilter{Tag: "other"}},
+	}
+
+	for _, tc := range cases {
+		tc.filter.Limit = 10
+
+		urls, _, err := s.ListURLs(ctx, tc.filter)
+		require.NoError(t, err, tc.name)
+
+		var aliases []string
+		for _, u := range urls {
+			aliases = append(aliases, u.Alias)
+		}
+		require.Equal(t, tc.aliases, aliases, tc.name)
+	}
+
+	// Удаленная ссылка пропадает и из индекса тегов
+	require.NoError(t, s.DeleteURL(ctx, "promo"))
+	_, err = s.PurgeDeletedURLs(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+
+	urls, _, err := s.ListURLs(ctx, storage.ListFilter{Tag: "campaign-x", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+}
+
+func TestOwner(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	_, err = s.SaveAPIKey(ctx, storage.APIKey{Name: "ops", Hash: "hash", Role: "admin"})
+	require.NoError(t, err)
+
+	key, err := s.GetAPIKeyByHash(ctx, "hash")
+	require.NoError(t, err)
+	require.Equal(t, "admin", key.Role)
+
+	_, err = s.SaveURLs(ctx, []storage.URL{
+		{Alias: "by-key", URL: "https://example.com/1", APIKeyID: key.ID},
+		{Alias: "by-other-key", URL: "https://example.com/2", APIKeyID: key.ID + 1},
+		{Alias: "by-user", URL: "https://example.com/3", UserID: 7},
+	})
+	require.NoError(t, err)
+
+	urls, _, err := s.ListURLs(ctx, storage.ListFilter{APIKeyID: key.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	require.Equal(t, "by-key", urls[0].Alias)
+}
+
+func TestUserRole(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	_, err = s.SaveUser(ctx, storage.User{Email: "user@example.com", PassHash: []byte("hash")})
+	require.NoError(t, err)
+
+	require.NoError(t, s.SetUserRole(ctx, "user@example.com", "viewer"))
+
+	user, err := s.GetUser(ctx, "user@example.com")
+	require.NoError(t, err)
+	require.Equal(t, "viewer", user.Role)
+
+	require.ErrorIs(t, s.SetUserRole(ctx, "other@example.com", "viewer"), storage.ErrUserNotFound)
+}
+
+func TestUserVerification(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	_, err = s.SaveUser(ctx, storage.User{Email: "user@example.com", PassHash: []byte("hash")})
+	require.NoError(t, err)
+
+	user, err := s.GetUser(ctx, "user@example.com")
+	require.NoError(t, err)
+	require.True(t, user.VerifiedAt.IsZero())
+
+	require.NoError(t, s.VerifyUser(ctx, "user@example.com"))
+	require.ErrorIs(t, s.VerifyUser(ctx, "other@example.com"), storage.ErrUserNotFound)
+
+	user, err = s.GetUser(ctx, "user@example.com")
+	require.NoError(t, err)
+	require.False(t, user.VerifiedAt.IsZero())
+
+	require.NoError(t, s.SetUserPassword(ctx, "user@example.com", []byte("new-hash")))
+	require.ErrorIs(t, s.SetUserPassword(ctx, "other@example.com", []byte("new-hash")), storage.ErrUserNotFound)
+
+	user, err = s.GetUser(ctx, "user@example.com")
+	require.NoError(t, err)
+	require.Equal(t, []byte("new-hash"), user.PassHash)
+}
+
+func TestUserToken(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	token := storage.UserToken{
+		Hash:      "hash",
+		Email:     "user@example.com",
+		Purpose:   storage.TokenVerifyEmail,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	require.NoError(t, s.SaveUserToken(ctx, token))
+	require.NoError(t, s.SaveUserToken(ctx, storage.UserToken{
+		Hash:      "expired",
+		Email:     "user@example.com",
+		Purpose:   storage.TokenResetPassword,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}))
+
+	// Токен другого назначения не подходит и не расходуется
+	_, err = s.ConsumeUserToken(ctx, "hash", storage.TokenResetPassword)
+	require.ErrorIs(t, err, storage.ErrTokenNotFound)
+
+	got, err := s.ConsumeUserToken(ctx, "hash", storage.TokenVerifyEmail)
+	require.NoError(t, err)
+	require.Equal(t, token.Email, got.Email)
+	require.WithinDuration(t, token.ExpiresAt, got.ExpiresAt, time.Second)
+
+	_, err = s.ConsumeUserToken(ctx, "hash", storage.TokenVerifyEmail)
+	require.ErrorIs(t, err, storage.ErrTokenNotFound, "token must be usable once")
+
+	_, err = s.ConsumeUserToken(ctx, "expired", storage.TokenResetPassword)
+	require.ErrorIs(t, err, storage.ErrTokenNotFound)
+}
+
+func TestMarkURLDead(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "dead", URL: "https://gone.example/page"})
+	require.NoError(t, err)
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "alive", URL: "https://alive.example"})
+	require.NoError(t, err)
+
+	require.NoError(t, s.MarkURLDead(ctx, "dead", "404"))
+
+	u, err := s.GetURLInfo(ctx, "dead")
+	require.NoError(t, err)
+	require.Equal(t, "404", u.DeadReason)
+	require.False(t, u.DeadSince.IsZero())
+
+	// Время первого обнаружения сохраняется при смене причины
+	since := u.DeadSince
+	require.NoError(t, s.MarkURLDead(ctx, "dead", "410"))
+	u, err = s.GetURLInfo(ctx, "dead")
+	require.NoError(t, err)
+	require.Equal(t, "410", u.DeadReason)
+	require.True(t, since.Equal(u.DeadSince))
+
+	urls, _, err := s.ListURLs(ctx, storage.ListFilter{Dead: true, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	require.Equal(t, "dead", urls[0].Alias)
+
+	// Смена адреса сбрасывает результат проверки
+	newURL := "https://moved.example/page"
+	u, err = s.UpdateURL(ctx, "dead", storage.URLUpdate{URL: &newURL}, 0)
+	require.NoError(t, err)
+	require.Empty(t, u.DeadReason)
+	require.True(t, u.DeadSince.IsZero())
+
+	require.NoError(t, s.MarkURLDead(ctx, "alive", "dns"))
+	require.NoError(t, s.MarkURLDead(ctx, "alive", ""))
+	u, err = s.GetURLInfo(ctx, "alive")
+	require.NoError(t, err)
+	require.Empty(t, u.DeadReason)
+	require.True(t, u.DeadSince.IsZero())
+
+	require.ErrorIs(t, s.MarkURLDead(ctx, "missing", "404"), storage.ErrURLNotFound)
+}
+
+func TestSetPageMetadata(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "page", URL: "https://example.com/page"})
+	require.NoError(t, err)
+
+	page := storage.PageMetadata{
+		Title:       "Example",
+		Description: "An example page",
+		Image:       "https://example.com/og.png",
+	}
+	require.NoError(t, s.SetPageMetadata(ctx, "page", page))
+
+	u, err := s.GetURLInfo(ctx, "page")
+	require.NoError(t, err)
+	require.Equal(t, page, u.Page)
+
+	urls, _, err := s.ListURLs(ctx, storage.ListFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	require.Equal(t, page, urls[0].Page)
+
+	// Метаданные относятся к старому адресу, поэтому смена адреса их сбрасывает
+	newURL := "https://moved.example/page"
+	u, err = s.UpdateURL(ctx, "page", storage.URLUpdate{URL: &newURL}, 0)
+	require.NoError(t, err)
+	require.Empty(t, u.Page)
+
+	require.ErrorIs(t, s.SetPageMetadata(ctx, "missing", page), storage.ErrURLNotFound)
+}
+
+func TestArchiveURLs(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "old", URL: "https://old.example", Tags: storage.Tags{"promo"}})
+	require.NoError(t, err)
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "clicked", URL: "https://clicked.example"})
+	require.NoError(t, err)
+
+	inactiveBefore := time.Now()
+	require.NoError(t, s.IncrementHits(ctx, map[string]int64{"clicked": 1}))
+
+	n, err := s.ArchiveURLs(ctx, inactiveBefore)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	active, _, err := s.ListURLs(ctx, storage.ListFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	require.Equal(t, "clicked", active[0].Alias)
+
+	archived, _, err := s.ListURLs(ctx, storage.ListFilter{Archived: true, Tag: "promo", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, archived, 1)
+	require.Equal(t, "old", archived[0].Alias)
+
+	// Алиас архивной ссылки остается занятым
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "old", URL: "https://new.example"})
+	require.ErrorIs(t, err, storage.ErrURLExists)
+
+	ids, err := s.SaveURLs(ctx, []storage.URL{{Alias: "old", URL: "https://new.example"}})
+	require.NoError(t, err)
+	require.Equal(t, []int64{0}, ids)
+
+	// Переход возвращает ссылку из архива вместе с тегами
+	u, err := s.GetURL(ctx, "old")
+	require.NoError(t, err)
+	require.Equal(t, "https://old.example", u.URL)
+
+	tagged, _, err := s.ListURLs(ctx, storage.ListFilter{Tag: "promo", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, tagged, 1)
+
+	archived, _, err = s.ListURLs(ctx, storage.ListFilter{Archived: true, Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, archived)
+
+	_, err = s.GetURL(ctx, "missing")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	// Изменение тоже возвращает ссылку из архива
+	n, err = s.ArchiveURLs(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+
+	newURL := "https://clicked.example/new"
+	u, err = s.UpdateURL(ctx, "clicked", storage.URLUpdate{URL: &newURL}, 0)
+	require.NoError(t, err)
+	require.Equal(t, newURL, u.URL)
+	require.Equal(t, int64(1), u.Hits)
+}
+
+func TestGetServiceStats(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	for _, alias := range []string{"a", "b", "deleted"} {
+		_, err := s.SaveURL(ctx, storage.URL{Alias: alias, URL: "https://" + alias + ".example"})
+		require.NoError(t, err)
+	}
+	require.NoError(t, s.IncrementHits(ctx, map[string]int64{"a": 2, "b": 5, "deleted": 9}))
+	require.NoError(t, s.DeleteURL(ctx, "deleted"))
+
+	now := time.Now()
+	require.NoError(t, s.SaveClickEvents(ctx, []storage.ClickEvent{
+		{Alias: "a", Time: now},
+		{Alias: "a", Time: now.AddDate(0, 0, -3)},
+		{Alias: "b", Time: now.AddDate(0, 0, -20)},
+		{Alias: "b", Time: now.AddDate(0, 0, -40)},
+	}))
+
+	stats, err := s.GetServiceStats(ctx, now)
+	require.NoError(t, err)
+
+	require.Equal(t, int64(2), stats.Links)
+	require.Equal(t, int64(1), stats.ClicksToday)
+	require.Equal(t, int64(2), stats.Clicks7d)
+	require.Equal(t, int64(3), stats.Clicks30d)
+	require.Equal(t, []storage.Count{{Key: "b", Count: 5}, {Key: "a", Count: 2}}, stats.TopLinks)
+	require.Equal(t, []storage.Count{{Key: now.UTC().Format("2006-01-02"), Count: 2}}, stats.CreatedByDay)
+}
+
+func TestGetHourlyClicks(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "a", URL: "https://a.example"})
+	require.NoError(t, err)
+
+	day := time.Now().UTC().Truncate(time.Hour).Add(-48 * time.Hour)
+	require.NoError(t, s.SaveClickEvents(ctx, []storage.ClickEvent{
+		{Alias: "a", Time: day.Add(10 * time.Minute)},
+		{Alias: "a", Time: day.Add(50 * time.Minute)},
+		{Alias: "a", Time: day.Add(2*time.Hour + time.Minute)},
+		{Alias: "a", Time: day.Add(30 * time.Hour)},
+	}))
+
+	want := []storage.ClickBucket{
+		{Start: day, Clicks: 2},
+		{Start: day.Add(2 * time.Hour), Clicks: 1},
+		{Start: day.Add(30 * time.Hour), Clicks: 1},
+	}
+
+	buckets, err := s.GetHourlyClicks(ctx, "a", day, day.Add(48*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, want, buckets)
+
+	// Первые сутки уже свернуты, остальные часы читаются из событий
+	rows, err := s.RollupClicks(ctx, day.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), rows)
+
+	require.NoError(t, s.SaveClickEvents(ctx, []storage.ClickEvent{
+		{Alias: "a", Time: day.Add(30*time.Hour + time.Minute)},
+	}))
+	want[2].Clicks = 2
+
+	buckets, err = s.GetHourlyClicks(ctx, "a", day, day.Add(48*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, want, buckets)
+
+	// Повторная свертка не считает переходы дважды
+	_, err = s.RollupClicks(ctx, day.Add(24*time.Hour))
+	require.NoError(t, err)
+	_, err = s.RollupClicks(ctx, day.Add(47*time.Hour))
+	require.NoError(t, err)
+
+	buckets, err = s.GetHourlyClicks(ctx, "a", day, day.Add(48*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, want, buckets)
+
+	buckets, err = s.GetHourlyClicks(ctx, "a", day.Add(time.Hour), day.Add(3*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, want[1:2], buckets)
+
+	_, err = s.GetHourlyClicks(ctx, "missing", day, day.Add(time.Hour))
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestExportClickEvents(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	for _, alias := range []string{"a", "b"} {
+		_, err = s.SaveURL(ctx, storage.URL{Alias: alias, URL: "https://" + alias + ".example"})
+		require.NoError(t, err)
+	}
+
+	start := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+	require.NoError(t, s.SaveClickEvents(ctx, []storage.ClickEvent{
+		{Alias: "b", Time: start.Add(2 * time.Minute), Referrer: "https://news.example/", ReferrerHost: "news.example", Country: "DE"},
+		{Alias: "a", Time: start.Add(time.Minute), UTMSource: "mail"},
+		{Alias: "a", Time: start.Add(-time.Minute)},
+	}))
+
+	var events []storage.ClickEvent
+	collect := func(e storage.ClickEvent) error {
+		events = append(events, e)
+
+		return nil
+	}
+
+	require.NoError(t, s.ExportClickEvents(ctx, storage.ClickFilter{From: start, To: start.Add(time.Hour)}, collect))
+	require.Len(t, events, 2)
+	require.Equal(t, "a", events[0].Alias)
+	require.Equal(t, "mail", events[0].UTMSource)
+	require.True(t, events[0].Time.Equal(start.Add(time.Minute)))
+	require.Equal(t, "b", events[1].Alias)
+	require.Equal(t, "news.example", events[1].ReferrerHost)
+	require.Equal(t, "DE", events[1].Country)
+
+	events = nil
+	require.NoError(t, s.ExportClickEvents(ctx, storage.ClickFilter{Alias: "a", From: start.Add(-time.Hour), To: start.Add(time.Hour)}, collect))
+	require.Len(t, events, 2)
+
+	err = s.ExportClickEvents(ctx, storage.ClickFilter{Alias: "missing", From: start, To: start.Add(time.Hour)}, collect)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestGetDailyClicks(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -2)
+	require.NoError(t, s.SaveClickEvents(ctx, []storage.ClickEvent{
+		{Alias: "b", Time: day.Add(time.Hour)},
+		{Alias: "a", Time: day.Add(2 * time.Hour)},
+		{Alias: "a", Time: day.Add(20 * time.Hour)},
+		{Alias: "a", Time: day.Add(26 * time.Hour)},
+	}))
+
+	// Часть первого дня уже свернута, остальное считается по событиям
+	_, err = s.RollupClicks(ctx, day.Add(12*time.Hour))
+	require.NoError(t, err)
+
+	days, err := s.GetDailyClicks(ctx, day, day.Add(48*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []storage.DailyClicks{
+		{Alias: "a", Day: day.Format("2006-01-02"), Clicks: 2},
+		{Alias: "b", Day: day.Format("2006-01-02"), Clicks: 1},
+		{Alias: "a", Day: day.AddDate(0, 0, 1).Format("2006-01-02"), Clicks: 1},
+	}, days)
+}
+
+func TestDeleteExpiredURLs(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	for _, u := range []storage.URL{
+		{Alias: "expired", URL: "https://example.com/a", ExpiresAt: time.Now().Add(-time.Minute)},
+		{Alias: "active", URL: "https://example.com/b", ExpiresAt: time.Now().Add(time.Hour)},
+		{Alias: "forever", URL: "https://example.com/c"},
+	} {
+		_, err := s.SaveURL(ctx, u)
+		require.NoError(t, err)
+	}
+
+	expired, err := s.DeleteExpiredURLs(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"expired"}, expired)
+
+	_, err = s.GetURL(ctx, "expired")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	expired, err = s.DeleteExpiredURLs(ctx)
+	require.NoError(t, err)
+	require.Empty(t, expired)
+}
+
+func TestWebhooks(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	id, err := s.SaveWebhook(ctx, storage.Webhook{
+		URL:    "https://hooks.example/links",
+		Events: storage.EventTypes{"link.created", "link.expired"},
+		Secret: "secret",
+	})
+	require.NoError(t, err)
+
+	wh, err := s.GetWebhook(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, storage.EventTypes{"link.created", "link.expired"}, wh.Events)
+	require.Equal(t, "secret", wh.Secret)
+	require.False(t, wh.CreatedAt.IsZero())
+
+	wh.URL = "https://hooks.example/v2"
+	wh.Events = storage.EventTypes{"link.clicked"}
+	require.NoError(t, s.UpdateWebhook(ctx, wh))
+
+	list, err := s.ListWebhooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, "https://hooks.example/v2", list[0].URL)
+	require.Equal(t, storage.EventTypes{"link.clicked"}, list[0].Events)
+
+	// Журнал хранит только последние доставки
+	for i := 0; i < storage.WebhookDeliveryLog+5; i++ {
+		require.NoError(t, s.SaveWebhookDelivery(ctx, storage.WebhookDelivery{
+			WebhookID: id,
+			Event:     "link.clicked",
+			Alias:     "promo",
+			Attempts:  i + 1,
+		}))
+	}
+
+	deliveries, err := s.ListWebhookDeliveries(ctx, id, 1000)
+	require.NoError(t, err)
+	require.Len(t, deliveries, storage.WebhookDeliveryLog)
+	require.Equal(t, storage.WebhookDeliveryLog+5, deliveries[0].Attempts)
+	require.Equal(t, 6, deliveries[len(deliveries)-1].Attempts)
+
+	require.NoError(t, s.DeleteWebhook(ctx, id))
+	require.ErrorIs(t, s.DeleteWebhook(ctx, id), storage.ErrWebhookNotFound)
+	require.ErrorIs(t, s.UpdateWebhook(ctx, wh), storage.ErrWebhookNotFound)
+
+	_, err = s.GetWebhook(ctx, id)
+	require.ErrorIs(t, err, storage.ErrWebhookNotFound)
+
+	deliveries, err = s.ListWebhookDeliveries(ctx, id, 10)
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+}
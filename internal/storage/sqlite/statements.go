@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Запросы горячих путей: редирект, сохранение ссылки и учет переходов.
+const (
+	// Алиас архивной ссылки занят, хотя ее нет в url; последний параметр - снова алиас
+	saveURLQuery = "INSERT INTO url(url, alias, created_at, expires_at, api_key_id, user_id, redirect_code, max_clicks, " +
+		"webhook_url, domain, geo_targets, device_targets, split, query_params, pass_query, no_analytics, burn_after_reading, tags, description) " +
+		"SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ? " +
+		"WHERE NOT EXISTS (SELECT 1 FROM url_archive WHERE alias = ?)"
+
+	getURLQuery = "SELECT url, expires_at, redirect_code, quarantine_reason, max_clicks, exhausted_at, webhook_url, domain, " +
+		"geo_targets, device_targets, split, query_params, pass_query, no_analytics, burn_after_reading " +
+		"FROM url WHERE alias = ? AND deleted_at IS NULL"
+
+	getURLInfoQuery = "SELECT id, alias, url, created_at, updated_at, expires_at, version, hits, api_key_id, user_id, " +
+		"redirect_code, quarantine_reason, max_clicks, exhausted_at, webhook_url, domain, geo_targets, device_targets, " +
+		"split, query_params, pass_query, no_analytics, burn_after_reading, tags, description, dead_reason, dead_since, " +
+		"page_title, page_description, page_image " +
+		"FROM url WHERE alias = ? AND deleted_at IS NULL"
+
+	// Проверка архива при промахе: неизвестные алиасы не берут блокировку записи
+	archivedQuery = "SELECT 1 FROM url_archive WHERE alias = ?"
+
+	// В SET используются значения до обновления, поэтому hits + 1 - новое значение
+	consumeClickQuery = `UPDATE url SET hits = hits + 1,
+		exhausted_at = CASE WHEN hits + 1 >= max_clicks THEN ? ELSE exhausted_at END
+		WHERE alias = ? AND deleted_at IS NULL AND max_clicks > 0 AND hits < max_clicks`
+
+	// Удаление с условием в одном запросе: из одновременных переходов ссылку сжигает только один
+	burnURLQuery = "UPDATE url SET hits = hits + 1, last_hit_at = ?, deleted_at = ? " +
+		"WHERE alias = ? AND deleted_at IS NULL AND burn_after_reading = 1"
+
+	incrementHitsQuery = "UPDATE url SET hits = hits + ?, last_hit_at = ? WHERE alias = ?"
+
+	saveClickEventQuery = "INSERT INTO click_event(alias, created_at, referrer, user_agent, browser, ip, variant, " +
+		"referrer_host, utm_source, utm_medium, utm_campaign, country, city) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+)
+
+// statements are prepared once in New instead of on every call. Reads are prepared
+// on the read pool, database/sql prepares them again on every new connection.
+type statements struct {
+	saveURL        *sql.Stmt
+	getURL         *sql.Stmt
+	getURLInfo     *sql.Stmt
+	archived       *sql.Stmt
+	consumeClick   *sql.Stmt
+	burnURL        *sql.Stmt
+	incrementHits  *sql.Stmt
+	saveClickEvent *sql.Stmt
+}
+
+func (s *Storage) prepare() error {
+	for _, p := range []struct {
+		stmt  **sql.Stmt
+		db    *sql.DB
+		name  string
+		query string
+	}{
+		{&s.stmts.saveURL, s.wdb, "save url", saveURLQuery},
+		{&s.stmts.getURL, s.db, "get url", getURLQuery},
+		{&s.stmts.getURLInfo, s.db, "get url info", getURLInfoQuery},
+		{&s.stmts.archived, s.db, "archived", archivedQuery},
+		{&s.stmts.consumeClick, s.wdb, "consume click", consumeClickQuery},
+		{&s.stmts.burnURL, s.wdb, "burn url", burnURLQuery},
+		{&s.stmts.incrementHits, s.wdb, "increment hits", incrementHitsQuery},
+		{&s.stmts.saveClickEvent, s.wdb, "save click event", saveClickEventQuery},
+	} {
+		stmt, err := p.db.Prepare(p.query)
+		if err != nil {
+			return fmt.Errorf("prepare %s statement: %w", p.name, err)
+		}
+
+		*p.stmt = stmt
+	}
+
+	return nil
+}
+
+// close closes the prepared statements; the ones New failed to prepare are nil.
+func (st *statements) close() error {
+	var errs []error
+	for _, stmt := range []*sql.Stmt{
+		st.saveURL, st.getURL, st.getURLInfo, st.archived, st.consumeClick, st.burnURL, st.incrementHits,
+		st.saveClickEvent,
+	} {
+		if stmt != nil {
+			errs = append(errs, stmt.Close())
+		}
+	}
+
+	return errors.Join(errs...)
+}
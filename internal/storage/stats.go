@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"url-shortener/internal/lib/hll"
+)
+
+// StatsTopLimit is the number of entries kept in top lists of ClickStats, like ByReferrer.
+const StatsTopLimit = 10
+
+// DirectReferrer is used as referrer key of clicks without Referer header.
+const DirectReferrer = "direct"
+
+// StatsWindows returns the starts of the click windows of ServiceStats as of now: the
+// UTC day, 7 and 30 days ago. ServiceStats.CreatedByDay starts at the UTC day of month.
+func StatsWindows(now time.Time) (day, week, month time.Time) {
+	now = now.UTC()
+
+	return now.Truncate(24 * time.Hour), now.AddDate(0, 0, -7), now.AddDate(0, 0, -30)
+}
+
+// AggregateClicks builds ClickStats from raw events, for backends without query aggregation.
+func AggregateClicks(events []ClickEvent) ClickStats {
+	byDay := make(map[string]int64)
+	byReferrer := make(map[string]int64)
+	byBrowser := make(map[string]int64)
+	byVariant := make(map[string]int64)
+	byReferrerHost := make(map[string]int64)
+	bySource := make(map[string]int64)
+	byMedium := make(map[string]int64)
+	byCampaign := make(map[string]int64)
+	byCountry := make(map[string]int64)
+	byCity := make(map[string]int64)
+
+	for _, e := range events {
+		byDay[e.Time.UTC().Format("2006-01-02")]++
+
+		referrer := e.Referrer
+		if referrer == "" {
+			referrer = DirectReferrer
+		}
+		byReferrer[referrer]++
+		byBrowser[e.Browser]++
+
+		if e.Variant != "" {
+			byVariant[e.Variant]++
+		}
+
+		// Старые переходы с Referer, но без хоста, не попадают ни в один сайт
+		switch {
+		case e.Referrer == "":
+			byReferrerHost[DirectReferrer]++
+		case e.ReferrerHost != "":
+			byReferrerHost[e.ReferrerHost]++
+		}
+
+		if e.UTMSource != "" {
+			bySource[e.UTMSource]++
+		}
+		if e.UTMMedium != "" {
+			byMedium[e.UTMMedium]++
+		}
+		if e.UTMCampaign != "" {
+			byCampaign[e.UTMCampaign]++
+		}
+		if e.Country != "" {
+			byCountry[e.Country]++
+		}
+		if e.City != "" {
+			byCity[CityKey(e.City, e.Country)]++
+		}
+	}
+
+	return ClickStats{
+		Total:          int64(len(events)),
+		ByDay:          SortedCounts(byDay),
+		ByReferrer:     TopCounts(byReferrer, StatsTopLimit),
+		ByBrowser:      TopCounts(byBrowser, StatsTopLimit),
+		ByVariant:      SortedCounts(byVariant),
+		ByReferrerHost: TopCounts(byReferrerHost, StatsTopLimit),
+		BySource:       TopCounts(bySource, StatsTopLimit),
+		ByMedium:       TopCounts(byMedium, StatsTopLimit),
+		ByCampaign:     TopCounts(byCampaign, StatsTopLimit),
+		ByCountry:      TopCounts(byCountry, StatsTopLimit),
+		ByCity:         TopCounts(byCity, StatsTopLimit),
+	}
+}
+
+// CityKey is the key of the city in ClickStats.ByCity: names of cities in different
+// countries may be the same.
+func CityKey(city, country string) string {
+	return city + ", " + country
+}
+
+// SortedCounts returns counts of m ordered by key.
+func SortedCounts(m map[string]int64) []Count {
+	counts := toCounts(m)
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Key < counts[j].Key })
+
+	return counts
+}
+
+// TopCounts returns up to n counts of m with the largest values, top first.
+func TopCounts(m map[string]int64, n int) []Count {
+	return top(toCounts(m), n)
+}
+
+func toCounts(m map[string]int64) []Count {
+	counts := make([]Count, 0, len(m))
+	for k, v := range m {
+		counts = append(counts, Count{Key: k, Count: v})
+	}
+
+	return counts
+}
+
+func top(counts []Count, n int) []Count {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+
+		return counts[i].Key < counts[j].Key
+	})
+
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+
+	return counts
+}
+
+// SortedBuckets returns clicks per hour (the start of the hour -> count) ordered by hour.
+func SortedBuckets(m map[time.Time]int64) []ClickBucket {
+	buckets := make([]ClickBucket, 0, len(m))
+	for start, clicks := range m {
+		buckets = append(buckets, ClickBucket{Start: start, Clicks: clicks})
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Start.Before(buckets[j].Start) })
+
+	return buckets
+}
+
+// MergeDailyClicks sums clicks of the same link and day, e.g. counted from the rollup
+// and from raw events, and orders them by day and alias.
+func MergeDailyClicks(days []DailyClicks) []DailyClicks {
+	type key struct{ alias, day string }
+
+	idx := make(map[key]int, len(days))
+	merged := make([]DailyClicks, 0, len(days))
+
+	for _, d := range days {
+		k := key{d.Alias, d.Day}
+		if i, ok := idx[k]; ok {
+			merged[i].Clicks += d.Clicks
+
+			continue
+		}
+
+		idx[k] = len(merged)
+		merged = append(merged, d)
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].Day != merged[j].Day {
+			return merged[i].Day < merged[j].Day
+		}
+
+		return merged[i].Alias < merged[j].Alias
+	})
+
+	return merged
+}
+
+// DaySketch is an encoded HLL sketch of visitors of a day.
+type DaySketch struct {
+	Day    string
+	Sketch []byte
+}
+
+// VisitorSketch is a sketch of visitors of the link on a UTC day (YYYY-MM-DD).
+type VisitorSketch struct {
+	Alias  string
+	Day    string
+	Sketch *hll.Sketch
+}
+
+// VisitorSketches groups visitors of events by link and day. Sketches are ordered by
+// alias and day, so that concurrent writers lock rows in the same order.
+func VisitorSketches(events []ClickEvent) []VisitorSketch {
+	type linkDay struct{ alias, day string }
+
+	sketches := make(map[linkDay]*hll.Sketch)
+
+	for _, e := range events {
+		if e.Visitor == 0 {
+			continue
+		}
+
+		key := linkDay{alias: e.Alias, day: e.Time.UTC().Format("2006-01-02")}
+		if sketches[key] == nil {
+			sketches[key] = hll.New()
+		}
+
+		sketches[key].Add(e.Visitor)
+	}
+
+	res := make([]VisitorSketch, 0, len(sketches))
+	for key, sketch := range sketches {
+		res = append(res, VisitorSketch{Alias: key.alias, Day: key.day, Sketch: sketch})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Alias != res[j].Alias {
+			return res[i].Alias < res[j].Alias
+		}
+
+		return res[i].Day < res[j].Day
+	})
+
+	return res
+}
+
+// MergeSketch merges s into the encoded sketch, which may be nil, and encodes the result.
+func MergeSketch(data []byte, s *hll.Sketch) ([]byte, error) {
+	merged := hll.New()
+	if data != nil {
+		if err := merged.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+	}
+
+	merged.Merge(s)
+
+	return merged.MarshalBinary()
+}
+
+// CountUniques estimates visitors of every day and of all days together.
+func CountUniques(days []DaySketch) (int64, []Count, error) {
+	total := hll.New()
+	byDay := make([]Count, 0, len(days))
+
+	for _, d := range days {
+		s := hll.New()
+		if err := s.UnmarshalBinary(d.Sketch); err != nil {
+			return 0, nil, err
+		}
+
+		total.Merge(s)
+		byDay = append(byDay, Count{Key: d.Day, Count: int64(s.Count())})
+	}
+
+	sort.Slice(byDay, func(i, j int) bool { return byDay[i].Key < byDay[j].Key })
+
+	return int64(total.Count()), byDay, nil
+}
@@ -1,8 +1,469 @@
 package storage
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
 var (
 	ErrURLNotFound = errors.New("url not found")
 	ErrURLExists   = errors.New("url exists")
+	ErrURLExpired  = errors.New("url expired")
+	// ErrURLExhausted means the link reached its click limit.
+	ErrURLExhausted = errors.New("url exhausted")
+	// ErrURLModified means the link version differs from the expected one.
+	ErrURLModified    = errors.New("url modified")
+	ErrInvalidCursor  = errors.New("invalid cursor")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrUserExists     = errors.New("user exists")
+	ErrUserNotFound   = errors.New("user not found")
+	// ErrTokenNotFound means the user token is unknown, used or expired.
+	ErrTokenNotFound   = errors.New("token not found")
+	ErrDomainExists    = errors.New("domain exists")
+	ErrDomainNotFound  = errors.New("domain not found")
+	ErrHostNotBanned   = errors.New("host not banned")
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+// URL is a saved short link.
+type URL struct {
+	ID        int64
+	Alias     string
+	URL       string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// ExpiresAt is zero for links that never expire.
+	ExpiresAt time.Time
+	// Version is incremented on every update and is used for optimistic concurrency.
+	Version int64
+	// Hits is the number of redirects made by the link.
+	Hits int64
+	// APIKeyID is the key the link was saved with, zero if it was saved without one.
+	APIKeyID int64
+	// UserID is the owner of the link, zero if it was saved with an API key.
+	UserID int64
+	// RedirectCode is the HTTP status of the redirect, zero means the configured default.
+	RedirectCode int
+	// QuarantineReason is set when the destination was flagged as malicious;
+	// such links show a warning instead of redirecting.
+	QuarantineReason string
+	// MaxClicks limits the number of redirects, zero means no limit.
+	MaxClicks int64
+	// ExhaustedAt is set when the link reached MaxClicks.
+	ExhaustedAt time.Time
+	// BurnAfterReading makes the link one-time: the first redirect deletes it, see BurnURL.
+	BurnAfterReading bool
+	// WebhookURL is notified of every click of the link, empty means no notifications.
+	WebhookURL string
+	// Domain binds the link to a short domain: it redirects only on that host.
+	// Empty means the link works on every domain of the service.
+	Domain string
+	// GeoTargets send visitors from some countries or continents to other
+	// destinations; the rest go to URL.
+	GeoTargets GeoTargets
+	// DeviceTargets send visitors on some platforms to other destinations;
+	// they take precedence over GeoTargets.
+	DeviceTargets DeviceTargets
+	// Split divides the rest of visitors between weighted destinations for
+	// A/B tests; empty means all of them go to URL.
+	Split Split
+	// QueryParams are added to the query of the destination on every redirect.
+	QueryParams QueryParams
+	// PassQuery adds query parameters of the short link request to the destination.
+	PassQuery bool
+	// NoAnalytics turns off click events of the link, e.g. for privacy-sensitive
+	// links: only the hit counter is updated.
+	NoAnalytics bool
+	// Tags and Description help to find the link among others, see ListFilter.
+	Tags        Tags
+	Description string
+	// DeadReason is set by the dead-link checker when the destination is gone,
+	// e.g. "404" or "dns"; empty means the destination is alive or not checked yet.
+	DeadReason string
+	// DeadSince is the time the destination was first found dead.
+	DeadSince time.Time
+	// Page is the metadata of the destination page, empty until it is fetched.
+	Page PageMetadata
+}
+
+// PageMetadata is the title and OpenGraph metadata of the destination page,
+// fetched in the background after the link is saved.
+type PageMetadata struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Image is the absolute url of the og:image of the page.
+	Image string `json:"image,omitempty"`
+}
+
+// Domain is a short domain registered via the admin API.
+type Domain struct {
+	Host      string
+	CreatedAt time.Time
+}
+
+// Statuses of abuse reports.
+const (
+	ReportOpen      = "open"
+	ReportDismissed = "dismissed"
+	// ReportDisabled means the link was taken down.
+	ReportDisabled = "disabled"
+	// ReportBanned means the link was taken down and its destination host banned.
+	ReportBanned = "banned"
+)
+
+// Report is an abuse report of a link sent by a visitor.
+type Report struct {
+	ID int64
+	// Alias is the key of the link in the storage, with the tenant prefix.
+	Alias    string
+	Category string
+	Details  string
+	// Contact is the optional email of the reporter.
+	Contact   string
+	CreatedAt time.Time
+	Status    string
+	// ResolvedAt is zero for open reports.
+	ResolvedAt time.Time
+}
+
+// BannedHost is a destination host links can't point to, including its subdomains.
+type BannedHost struct {
+	Host      string
+	CreatedAt time.Time
+}
+
+// Webhook is an endpoint subscribed to link events via the admin API.
+type Webhook struct {
+	ID  int64
+	URL string
+	// Events are the subscribed event types, see package webhook.
+	Events EventTypes
+	// Secret signs the payloads delivered to the endpoint.
+	Secret    string
+	CreatedAt time.Time
+}
+
+// WebhookDeliveryLog is the number of the latest deliveries kept per webhook.
+const WebhookDeliveryLog = 100
+
+// WebhookDelivery records an event delivered to a webhook, successfully or not.
+type WebhookDelivery struct {
+	ID        int64
+	WebhookID int64
+	Event     string
+	// Alias is the key of the link in the storage, with the tenant prefix.
+	Alias string
+	// Attempts is the number of requests made, including retries.
+	Attempts int
+	// StatusCode is the status of the last response, zero if there was none.
+	StatusCode int
+	// Error is empty for delivered events.
+	Error     string
+	CreatedAt time.Time
+}
+
+// User is an account which owns links.
+type User struct {
+	ID        int64
+	Email     string
+	PassHash  []byte
+	CreatedAt time.Time
+	// Tenant is the namespace of the user's links, empty for the default tenant.
+	Tenant string
+	// Role is one of the roles of the acl package, empty for editors.
+	Role string
+	// VerifiedAt is zero until the user confirms the email.
+	VerifiedAt time.Time
+}
+
+// Purposes of user tokens.
+const (
+	TokenVerifyEmail   = "verify_email"
+	TokenResetPassword = "reset_password"
 )
+
+// UserToken is a one-time token emailed to the user. Only the hash of the token is stored.
+type UserToken struct {
+	Hash      string
+	Email     string
+	Purpose   string
+	ExpiresAt time.Time
+}
+
+// APIKey is a credential for the API. Only the hash of the key is stored.
+type APIKey struct {
+	ID        int64
+	Name      string
+	Hash      string
+	CreatedAt time.Time
+	// RevokedAt is zero for active keys.
+	RevokedAt time.Time
+	// Tenant binds the key to a namespace of links. Keys of the default tenant
+	// act in the tenant resolved from the request.
+	Tenant string
+	// Role is one of the roles of the acl package, empty for editors.
+	Role string
+	// SigningSecret signs requests of clients which don't send the key itself, see
+	// package signature. Unlike the key it is stored as is; empty disables signing.
+	SigningSecret string
+}
+
+// URLUpdate describes changes of a saved link. Nil fields are left untouched.
+type URLUpdate struct {
+	URL *string
+	// ExpiresAt pointing to zero time removes expiration.
+	ExpiresAt *time.Time
+	// RedirectCode pointing to zero resets the code to the configured default.
+	RedirectCode *int
+	// NoAnalytics turns click events of the link off or on.
+	NoAnalytics *bool
+}
+
+// ClickEvent is a single redirect made by a link.
+type ClickEvent struct {
+	Alias    string
+	Time     time.Time
+	Referrer string
+	// ReferrerHost is the host of Referrer without "www.", empty for direct clicks.
+	ReferrerHost string
+	UserAgent    string
+	Browser      string
+	// IP is anonymized before the event is saved.
+	IP string
+	// Variant is the name of the Split variant served, empty for links without a split.
+	Variant string
+	// UTMSource, UTMMedium and UTMCampaign are the utm_* query parameters of the
+	// short link, empty if the click had none.
+	UTMSource   string
+	UTMMedium   string
+	UTMCampaign string
+	// Country (ISO 3166-1 alpha-2) and City are looked up by IP before anonymization,
+	// empty if unknown or GeoIP is not configured.
+	Country string
+	City    string
+	// Visitor is a hash of the IP address and user agent made before anonymization.
+	// Only unique visitor estimates are saved, not the hash itself; zero is not counted.
+	Visitor uint64
+}
+
+// Count is a number of clicks grouped by Key.
+type Count struct {
+	Key   string
+	Count int64
+}
+
+// ClickStats is an aggregate of link click events.
+type ClickStats struct {
+	Total int64
+	// ByDay is ordered by day (YYYY-MM-DD, UTC); ByReferrer and ByBrowser by count, top first.
+	ByDay      []Count
+	ByReferrer []Count
+	ByBrowser  []Count
+	// ByVariant is ordered by variant name; clicks without a variant are not counted.
+	ByVariant []Count
+	// ByReferrerHost groups ByReferrer by site, direct clicks have DirectReferrer key.
+	ByReferrerHost []Count
+	// BySource, ByMedium and ByCampaign count clicks by UTM parameters, top first;
+	// clicks without the parameter are not counted.
+	BySource   []Count
+	ByMedium   []Count
+	ByCampaign []Count
+	// ByCountry and ByCity count clicks by visitor location, top first; City keys are
+	// "City, CC". Clicks from unknown locations are not counted.
+	ByCountry []Count
+	ByCity    []Count
+	// Uniques estimates distinct visitors of the link, UniquesByDay of every day with
+	// clicks, ordered by day. Estimates are off by about 2%.
+	Uniques      int64
+	UniquesByDay []Count
+}
+
+// ClickBucket is the number of clicks in the hour starting at Start (UTC).
+type ClickBucket struct {
+	Start  time.Time
+	Clicks int64
+}
+
+// ClickFilter selects click events recorded in [From, To).
+type ClickFilter struct {
+	// Alias selects clicks of one link, empty selects clicks of all links.
+	Alias string
+	From  time.Time
+	To    time.Time
+}
+
+// DailyClicks is the number of clicks of the link on the day (YYYY-MM-DD, UTC).
+type DailyClicks struct {
+	Alias  string
+	Day    string
+	Clicks int64
+}
+
+// ServiceStats is an aggregate of all links and clicks of the service.
+type ServiceStats struct {
+	// Links is the number of links, without deleted and archived ones.
+	Links int64
+	// ClicksToday counts clicks since the start of the UTC day, Clicks7d and Clicks30d
+	// in the last 7 and 30 days. Clicks are counted only if analytics is enabled.
+	ClicksToday int64
+	Clicks7d    int64
+	Clicks30d   int64
+	// TopLinks are up to StatsTopLimit links with the most hits, top first.
+	TopLinks []Count
+	// CreatedByDay is the number of links created on every day (YYYY-MM-DD, UTC) of
+	// the last 30 days, ordered by day; days without new links are omitted.
+	CreatedByDay []Count
+}
+
+// ListFilter selects links for ListURLs. Zero fields are not applied.
+type ListFilter struct {
+	AliasPrefix string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// Cursor is an opaque value returned by the previous ListURLs call.
+	Cursor string
+	Limit  int
+	// UserID limits links to the ones owned by the user.
+	UserID int64
+	// APIKeyID limits links to the ones saved with the API key.
+	APIKeyID int64
+	// Tag limits links to the ones with the tag.
+	Tag string
+	// Query limits links to the ones containing it in alias, URL or description, ignoring case.
+	Query string
+	// Dead limits links to the ones with a dead destination.
+	Dead bool
+	// Archived lists archived links instead of active ones, see Storage.ArchiveURLs.
+	Archived bool
+}
+
+// Storage is the set of operations every storage backend must implement.
+type Storage interface {
+	SaveURL(ctx context.Context, u URL) (int64, error)
+	// SaveURLs saves links in one transaction and returns their ids in the same order.
+	// The id is zero if the alias is already taken, the rest of the links are saved anyway.
+	SaveURLs(ctx context.Context, urls []URL) ([]int64, error)
+	// GetURL returns an active link for redirect; only Alias, URL, ExpiresAt, RedirectCode,
+	// QuarantineReason, MaxClicks, BurnAfterReading, WebhookURL, Domain, GeoTargets,
+	// DeviceTargets, Split, QueryParams, PassQuery and NoAnalytics are set.
+	// ErrURLExhausted is returned for exhausted links.
+	GetURL(ctx context.Context, alias string) (URL, error)
+	// FindURL returns the latest active link to target saved by the same user and API key.
+	FindURL(ctx context.Context, target string, userID, apiKeyID int64) (URL, error)
+	// GetURLInfo returns the stored link, including expired ones.
+	GetURLInfo(ctx context.Context, alias string) (URL, error)
+	// UpdateURL applies update to the link. If version is not zero, the link is updated
+	// only if its current version matches, otherwise ErrURLModified is returned.
+	UpdateURL(ctx context.Context, alias string, update URLUpdate, version int64) (URL, error)
+	// DeleteURL soft-deletes the link: it is hidden from every read until restored or purged.
+	DeleteURL(ctx context.Context, alias string) error
+	// RestoreURL undeletes the link; ErrURLNotFound is returned if there is no deleted link.
+	RestoreURL(ctx context.Context, alias string) error
+	// ConsumeClick counts a redirect of a link with MaxClicks and marks the link exhausted
+	// on the last allowed one. ErrURLExhausted is returned if no clicks are left.
+	ConsumeClick(ctx context.Context, alias string) error
+	// BurnURL counts the redirect of a link with BurnAfterReading and soft-deletes it in
+	// one statement. Of concurrent clicks only one burns the link, the rest get ErrURLNotFound.
+	BurnURL(ctx context.Context, alias string) error
+	// QuarantineURL sets the quarantine reason of the link, an empty reason releases it.
+	QuarantineURL(ctx context.Context, alias, reason string) error
+	// MarkURLDead sets the dead reason of the link, an empty reason marks it alive.
+	// DeadSince is kept from the first time the link was marked dead.
+	MarkURLDead(ctx context.Context, alias, reason string) error
+	// SetPageMetadata stores the metadata of the destination page of the link. It is
+	// cleared when the destination is changed.
+	SetPageMetadata(ctx context.Context, alias string, page PageMetadata) error
+	// PurgeDeletedURLs permanently removes links deleted before deletedBefore with their clicks.
+	PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (int64, error)
+	// ArchiveURLs moves links neither clicked nor updated since inactiveBefore to the
+	// archive and returns their number. Archived links are listed only with
+	// ListFilter.Archived; reading or changing one by alias restores it first.
+	ArchiveURLs(ctx context.Context, inactiveBefore time.Time) (int64, error)
+	// ListURLs returns a page of links and a cursor of the next page, empty if there are no more.
+	ListURLs(ctx context.Context, filter ListFilter) ([]URL, string, error)
+	// IncrementHits adds hits (alias -> count) to the links' counters in one batch.
+	IncrementHits(ctx context.Context, hits map[string]int64) error
+	SaveClickEvents(ctx context.Context, events []ClickEvent) error
+	// PurgeClickEvents removes click events recorded before before and returns their
+	// number. Hourly rollups and unique visitor sketches are kept, so do totals.
+	PurgeClickEvents(ctx context.Context, before time.Time) (int64, error)
+	// GetClickStats aggregates click events of the link.
+	GetClickStats(ctx context.Context, alias string) (ClickStats, error)
+	// GetHourlyClicks returns clicks of the link per hour in [from, to), ordered by hour;
+	// hours without clicks are omitted.
+	GetHourlyClicks(ctx context.Context, alias string, from, to time.Time) ([]ClickBucket, error)
+	// ExportClickEvents calls fn for every click event matching the filter, ordered by time,
+	// and returns the first error of fn. ErrURLNotFound is returned if the filter selects
+	// a link which doesn't exist.
+	ExportClickEvents(ctx context.Context, filter ClickFilter, fn func(ClickEvent) error) error
+	// GetDailyClicks returns clicks of all links per day in [from, to), ordered by day and
+	// alias; days without clicks are omitted. from and to are truncated to the day.
+	GetDailyClicks(ctx context.Context, from, to time.Time) ([]DailyClicks, error)
+	// RollupClicks adds clicks of the hours ending before until to the hourly rollup
+	// which keeps GetHourlyClicks fast, and returns the number of rollup rows written.
+	// Clicks saved after their hour was rolled up are not counted by GetHourlyClicks.
+	RollupClicks(ctx context.Context, until time.Time) (int64, error)
+	// GetServiceStats aggregates all links and clicks as of now.
+	GetServiceStats(ctx context.Context, now time.Time) (ServiceStats, error)
+	// DeleteExpiredURLs removes expired links and returns their aliases.
+	DeleteExpiredURLs(ctx context.Context) ([]string, error)
+	SaveAPIKey(ctx context.Context, key APIKey) (int64, error)
+	// GetAPIKeyByHash returns the key with the given hash, including revoked ones.
+	GetAPIKeyByHash(ctx context.Context, hash string) (APIKey, error)
+	// GetAPIKey returns the key with the given id, including revoked ones.
+	GetAPIKey(ctx context.Context, id int64) (APIKey, error)
+	// RevokeAPIKey revokes an active key; ErrAPIKeyNotFound is returned if there is none.
+	RevokeAPIKey(ctx context.Context, id int64) error
+	// SaveUser saves the user; ErrUserExists is returned if the email is taken.
+	SaveUser(ctx context.Context, user User) (int64, error)
+	GetUser(ctx context.Context, email string) (User, error)
+	// SetUserRole changes the role of the user; ErrUserNotFound is returned if there is none.
+	SetUserRole(ctx context.Context, email, role string) error
+	// VerifyUser marks the email of the user as confirmed; ErrUserNotFound is returned if there is none.
+	VerifyUser(ctx context.Context, email string) error
+	// SetUserPassword changes the password of the user after a reset by email, so it
+	// confirms the email as well; ErrUserNotFound is returned if there is none.
+	SetUserPassword(ctx context.Context, email string, passHash []byte) error
+	SaveUserToken(ctx context.Context, token UserToken) error
+	// ConsumeUserToken removes the token with the given hash and purpose and returns it;
+	// ErrTokenNotFound is returned if there is none or it has expired.
+	ConsumeUserToken(ctx context.Context, hash, purpose string) (UserToken, error)
+	// SaveDomain registers a short domain; ErrDomainExists is returned if it is registered.
+	SaveDomain(ctx context.Context, domain Domain) error
+	GetDomain(ctx context.Context, host string) (Domain, error)
+	// ListDomains returns registered domains ordered by host.
+	ListDomains(ctx context.Context) ([]Domain, error)
+	// DeleteDomain unregisters the domain; links bound to it keep their domain.
+	DeleteDomain(ctx context.Context, host string) error
+	SaveReport(ctx context.Context, report Report) (int64, error)
+	// ListReports returns up to limit reports with the status, all if it is empty, oldest first.
+	ListReports(ctx context.Context, status string, limit int) ([]Report, error)
+	// ResolveReports sets the status of the open reports of the link and returns their number.
+	ResolveReports(ctx context.Context, alias, status string) (int64, error)
+	// BanHost bans a destination host; banning it again is not an error.
+	BanHost(ctx context.Context, host string) error
+	// UnbanHost lifts the ban; ErrHostNotBanned is returned if there is none.
+	UnbanHost(ctx context.Context, host string) error
+	// ListBannedHosts returns banned hosts ordered by host.
+	ListBannedHosts(ctx context.Context) ([]BannedHost, error)
+	SaveWebhook(ctx context.Context, webhook Webhook) (int64, error)
+	// GetWebhook returns the webhook; ErrWebhookNotFound is returned if there is none.
+	GetWebhook(ctx context.Context, id int64) (Webhook, error)
+	// ListWebhooks returns all webhooks ordered by id.
+	ListWebhooks(ctx context.Context) ([]Webhook, error)
+	// UpdateWebhook changes the URL and events of the webhook; ErrWebhookNotFound is
+	// returned if there is none.
+	UpdateWebhook(ctx context.Context, webhook Webhook) error
+	// DeleteWebhook removes the webhook with its deliveries; ErrWebhookNotFound is
+	// returned if there is none.
+	DeleteWebhook(ctx context.Context, id int64) error
+	// SaveWebhookDelivery records the delivery, keeping WebhookDeliveryLog latest ones
+	// per webhook.
+	SaveWebhookDelivery(ctx context.Context, delivery WebhookDelivery) error
+	// ListWebhookDeliveries returns up to limit latest deliveries of the webhook, newest first.
+	ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]WebhookDelivery, error)
+	// Ping checks the connection to the storage.
+	Ping(ctx context.Context) error
+	Close() error
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"slices"
+	"strings"
+)
+
+// Tags label a link for search, e.g. "campaign-x". It is stored as a JSON array,
+// empty string for no tags; SQL storages index every tag for ListFilter.Tag.
+type Tags []string
+
+// Value implements driver.Valuer.
+func (t Tags) Value() (driver.Value, error) {
+	return marshalJSON(t, len(t) == 0)
+}
+
+// Scan implements sql.Scanner.
+func (t *Tags) Scan(src any) error {
+	return unmarshalJSON(src, t)
+}
+
+// EventTypes are the events a webhook is subscribed to. It is stored like Tags.
+type EventTypes []string
+
+// Value implements driver.Valuer.
+func (e EventTypes) Value() (driver.Value, error) {
+	return marshalJSON(e, len(e) == 0)
+}
+
+// Scan implements sql.Scanner.
+func (e *EventTypes) Scan(src any) error {
+	return unmarshalJSON(src, e)
+}
+
+// Matches reports whether u passes the Tag, Query and Dead conditions of the filter.
+// It is used by storages which filter links in memory.
+func (f ListFilter) Matches(u URL) bool {
+	if f.Tag != "" && !slices.Contains(u.Tags, f.Tag) {
+		return false
+	}
+
+	if f.Dead && u.DeadReason == "" {
+		return false
+	}
+
+	if f.Query != "" {
+		q := strings.ToLower(f.Query)
+		if !strings.Contains(strings.ToLower(u.Alias), q) &&
+			!strings.Contains(strings.ToLower(u.URL), q) &&
+			!strings.Contains(strings.ToLower(u.Description), q) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// LikePattern returns a LIKE pattern matching strings which contain s;
+// wildcards in s are escaped with a backslash.
+func LikePattern(s string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s) + "%"
+}
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Destinations returns every URL the link can redirect to, so all of them can be checked.
+// The order is stable: URL goes first, then device and geo targets sorted by key,
+// then split variants in their order.
+func (u URL) Destinations() []string {
+	destinations := []string{u.URL}
+	destinations = appendSorted(destinations, u.DeviceTargets)
+	destinations = appendSorted(destinations, u.GeoTargets)
+	for _, v := range u.Split.Variants {
+		destinations = append(destinations, v.URL)
+	}
+
+	return destinations
+}
+
+func appendSorted(dst []string, targets map[string]string) []string {
+	keys := make([]string, 0, len(targets))
+	for key := range targets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		dst = append(dst, targets[key])
+	}
+
+	return dst
+}
+
+// GeoTargets maps locations of visitors to destinations of the link. Keys are
+// ISO 3166-1 country codes ("DE") or MaxMind continent codes ("EU").
+// It is stored as a JSON object, empty string for links without targets.
+type GeoTargets map[string]string
+
+// Match returns the destination for the visitor location; the country takes
+// precedence over the continent.
+func (g GeoTargets) Match(country, continent string) (string, bool) {
+	for _, code := range []string{country, continent} {
+		if code == "" {
+			continue
+		}
+
+		if target, ok := g[code]; ok {
+			return target, true
+		}
+	}
+
+	return "", false
+}
+
+// Value implements driver.Valuer.
+func (g GeoTargets) Value() (driver.Value, error) {
+	return marshalJSON(g, len(g) == 0)
+}
+
+// Scan implements sql.Scanner.
+func (g *GeoTargets) Scan(src any) error {
+	return unmarshalJSON(src, g)
+}
+
+// DeviceTargets maps platforms of visitors ("ios", "android", "desktop") to
+// destinations of the link, e.g. app store pages. It is stored like GeoTargets.
+type DeviceTargets map[string]string
+
+// Value implements driver.Valuer.
+func (d DeviceTargets) Value() (driver.Value, error) {
+	return marshalJSON(d, len(d) == 0)
+}
+
+// Scan implements sql.Scanner.
+func (d *DeviceTargets) Scan(src any) error {
+	return unmarshalJSON(src, d)
+}
+
+// QueryParams maps query parameters to value templates, see package querytpl.
+// It is stored like GeoTargets.
+type QueryParams map[string]string
+
+// Value implements driver.Valuer.
+func (q QueryParams) Value() (driver.Value, error) {
+	return marshalJSON(q, len(q) == 0)
+}
+
+// Scan implements sql.Scanner.
+func (q *QueryParams) Scan(src any) error {
+	return unmarshalJSON(src, q)
+}
+
+// Sticky modes of Split.
+const (
+	// StickyCookie remembers the variant of a visitor in a cookie.
+	StickyCookie = "cookie"
+	// StickyIP derives the variant from the visitor IP address.
+	StickyIP = "ip"
+)
+
+// Variant is a destination of an A/B test.
+type Variant struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Weight is the share of visitors relative to other variants, at least 1.
+	Weight int `json:"weight"`
+}
+
+// Split divides visitors of a link between weighted variants.
+// It is stored like GeoTargets, empty string for links without variants.
+type Split struct {
+	Variants []Variant `json:"variants"`
+	// Sticky is StickyCookie or StickyIP to keep a visitor on the same variant,
+	// empty to pick a variant on every redirect.
+	Sticky string `json:"sticky,omitempty"`
+}
+
+// Pick returns the variant for n, a random or hashed number of the visitor.
+// Every variant gets a share of numbers proportional to its weight.
+func (s Split) Pick(n uint64) (Variant, bool) {
+	var total uint64
+	for _, v := range s.Variants {
+		total += uint64(v.Weight)
+	}
+	if total == 0 {
+		return Variant{}, false
+	}
+
+	n %= total
+	for _, v := range s.Variants {
+		if n < uint64(v.Weight) {
+			return v, true
+		}
+		n -= uint64(v.Weight)
+	}
+
+	return Variant{}, false
+}
+
+// Variant returns the variant with the name.
+func (s Split) Variant(name string) (Variant, bool) {
+	for _, v := range s.Variants {
+		if v.Name == name {
+			return v, true
+		}
+	}
+
+	return Variant{}, false
+}
+
+// Value implements driver.Valuer.
+func (s Split) Value() (driver.Value, error) {
+	return marshalJSON(s, len(s.Variants) == 0)
+}
+
+// Scan implements sql.Scanner.
+func (s *Split) Scan(src any) error {
+	return unmarshalJSON(src, s)
+}
+
+func marshalJSON(v any, empty bool) (driver.Value, error) {
+	if empty {
+		return "", nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+// unmarshalJSON decodes a column written by marshalJSON; NULL and empty string leave dst as is.
+func unmarshalJSON(src any, dst any) error {
+	var b []byte
+
+	switch src := src.(type) {
+	case nil:
+		return nil
+	case string:
+		b = []byte(src)
+	case []byte:
+		b = src
+	default:
+		return fmt.Errorf("unsupported type %T", src)
+	}
+
+	if len(b) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(b, dst)
+}
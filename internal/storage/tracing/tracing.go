@@ -0,0 +1,516 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"url-shortener/internal/storage"
+)
+
+const tracerName = "url-shortener/internal/storage"
+
+// Storage decorates storage.Storage with a span for every operation.
+// Methods which are not overridden are passed to the embedded storage as is.
+type Storage struct {
+	storage.Storage
+
+	tracer trace.Tracer
+	system string
+}
+
+// New wraps s; system is the storage driver name reported as db.system.
+func New(s storage.Storage, system string) *Storage {
+	return &Storage{
+		Storage: s,
+		tracer:  otel.Tracer(tracerName),
+		system:  system,
+	}
+}
+
+// start starts a span of the operation as a child of the span in ctx.
+func (s *Storage) start(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs,
+		attribute.String("db.system", s.system),
+		attribute.String("db.operation", operation),
+	)
+
+	return s.tracer.Start(ctx, "storage."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// end records err on span unless it is an expected "not found"-like result.
+func end(span trace.Span, err error) {
+	if err != nil &&
+		!errors.Is(err, storage.ErrURLNotFound) &&
+		!errors.Is(err, storage.ErrURLExists) &&
+		!errors.Is(err, storage.ErrURLExpired) &&
+		!errors.Is(err, storage.ErrURLExhausted) &&
+		!errors.Is(err, storage.ErrURLModified) &&
+		!errors.Is(err, storage.ErrAPIKeyNotFound) &&
+		!errors.Is(err, storage.ErrUserExists) &&
+		!errors.Is(err, storage.ErrUserNotFound) &&
+		!errors.Is(err, storage.ErrTokenNotFound) &&
+		!errors.Is(err, storage.ErrHostNotBanned) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+func aliasAttr(alias string) attribute.KeyValue {
+	return attribute.String("url.alias", alias)
+}
+
+func webhookAttr(id int64) attribute.KeyValue {
+	return attribute.Int64("webhook.id", id)
+}
+
+func (s *Storage) SaveURL(ctx context.Context, u storage.URL) (int64, error) {
+	ctx, span := s.start(ctx, "save_url", aliasAttr(u.Alias))
+
+	id, err := s.Storage.SaveURL(ctx, u)
+	end(span, err)
+
+	return id, err
+}
+
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.URL) ([]int64, error) {
+	ctx, span := s.start(ctx, "save_urls", attribute.Int("url.count", len(urls)))
+
+	ids, err := s.Storage.SaveURLs(ctx, urls)
+	end(span, err)
+
+	return ids, err
+}
+
+func (s *Storage) GetURL(ctx context.Context, alias string) (storage.URL, error) {
+	ctx, span := s.start(ctx, "get_url", aliasAttr(alias))
+
+	u, err := s.Storage.GetURL(ctx, alias)
+	end(span, err)
+
+	return u, err
+}
+
+func (s *Storage) FindURL(ctx context.Context, target string, userID, apiKeyID int64) (storage.URL, error) {
+	ctx, span := s.start(ctx, "find_url")
+
+	u, err := s.Storage.FindURL(ctx, target, userID, apiKeyID)
+	end(span, err)
+
+	return u, err
+}
+
+func (s *Storage) GetURLInfo(ctx context.Context, alias string) (storage.URL, error) {
+	ctx, span := s.start(ctx, "get_url_info", aliasAttr(alias))
+
+	u, err := s.Storage.GetURLInfo(ctx, alias)
+	end(span, err)
+
+	return u, err
+}
+
+func (s *Storage) UpdateURL(ctx context.Context, alias string, update storage.URLUpdate, version int64) (storage.URL, error) {
+	ctx, span := s.start(ctx, "update_url", aliasAttr(alias))
+
+	u, err := s.Storage.UpdateURL(ctx, alias, update, version)
+	end(span, err)
+
+	return u, err
+}
+
+func (s *Storage) DeleteURL(ctx context.Context, alias string) error {
+	ctx, span := s.start(ctx, "delete_url", aliasAttr(alias))
+
+	err := s.Storage.DeleteURL(ctx, alias)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) RestoreURL(ctx context.Context, alias string) error {
+	ctx, span := s.start(ctx, "restore_url", aliasAttr(alias))
+
+	err := s.Storage.RestoreURL(ctx, alias)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) ConsumeClick(ctx context.Context, alias string) error {
+	ctx, span := s.start(ctx, "consume_click", aliasAttr(alias))
+
+	err := s.Storage.ConsumeClick(ctx, alias)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) BurnURL(ctx context.Context, alias string) error {
+	ctx, span := s.start(ctx, "burn_url", aliasAttr(alias))
+
+	err := s.Storage.BurnURL(ctx, alias)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) QuarantineURL(ctx context.Context, alias, reason string) error {
+	ctx, span := s.start(ctx, "quarantine_url", aliasAttr(alias))
+
+	err := s.Storage.QuarantineURL(ctx, alias, reason)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) MarkURLDead(ctx context.Context, alias, reason string) error {
+	ctx, span := s.start(ctx, "mark_url_dead", aliasAttr(alias))
+
+	err := s.Storage.MarkURLDead(ctx, alias, reason)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) SetPageMetadata(ctx context.Context, alias string, page storage.PageMetadata) error {
+	ctx, span := s.start(ctx, "set_page_metadata", aliasAttr(alias))
+
+	err := s.Storage.SetPageMetadata(ctx, alias, page)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) ListURLs(ctx context.Context, filter storage.ListFilter) ([]storage.URL, string, error) {
+	ctx, span := s.start(ctx, "list_urls")
+
+	urls, next, err := s.Storage.ListURLs(ctx, filter)
+	end(span, err)
+
+	return urls, next, err
+}
+
+func (s *Storage) IncrementHits(ctx context.Context, hits map[string]int64) error {
+	ctx, span := s.start(ctx, "increment_hits", attribute.Int("hits.aliases", len(hits)))
+
+	err := s.Storage.IncrementHits(ctx, hits)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) SaveClickEvents(ctx context.Context, events []storage.ClickEvent) error {
+	ctx, span := s.start(ctx, "save_click_events", attribute.Int("click_events.count", len(events)))
+
+	err := s.Storage.SaveClickEvents(ctx, events)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) GetClickStats(ctx context.Context, alias string) (storage.ClickStats, error) {
+	ctx, span := s.start(ctx, "get_click_stats", aliasAttr(alias))
+
+	stats, err := s.Storage.GetClickStats(ctx, alias)
+	end(span, err)
+
+	return stats, err
+}
+
+func (s *Storage) GetHourlyClicks(ctx context.Context, alias string, from, to time.Time) ([]storage.ClickBucket, error) {
+	ctx, span := s.start(ctx, "get_hourly_clicks", aliasAttr(alias))
+
+	buckets, err := s.Storage.GetHourlyClicks(ctx, alias, from, to)
+	end(span, err)
+
+	return buckets, err
+}
+
+func (s *Storage) ExportClickEvents(ctx context.Context, filter storage.ClickFilter, fn func(storage.ClickEvent) error) error {
+	ctx, span := s.start(ctx, "export_click_events", aliasAttr(filter.Alias))
+
+	err := s.Storage.ExportClickEvents(ctx, filter, fn)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) GetDailyClicks(ctx context.Context, from, to time.Time) ([]storage.DailyClicks, error) {
+	ctx, span := s.start(ctx, "get_daily_clicks")
+
+	days, err := s.Storage.GetDailyClicks(ctx, from, to)
+	end(span, err)
+
+	return days, err
+}
+
+func (s *Storage) GetServiceStats(ctx context.Context, now time.Time) (storage.ServiceStats, error) {
+	ctx, span := s.start(ctx, "get_service_stats")
+
+	stats, err := s.Storage.GetServiceStats(ctx, now)
+	end(span, err)
+
+	return stats, err
+}
+
+func (s *Storage) SaveAPIKey(ctx context.Context, key storage.APIKey) (int64, error) {
+	ctx, span := s.start(ctx, "save_api_key")
+
+	id, err := s.Storage.SaveAPIKey(ctx, key)
+	end(span, err)
+
+	return id, err
+}
+
+func (s *Storage) GetAPIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error) {
+	ctx, span := s.start(ctx, "get_api_key_by_hash")
+
+	key, err := s.Storage.GetAPIKeyByHash(ctx, hash)
+	end(span, err)
+
+	return key, err
+}
+
+func (s *Storage) GetAPIKey(ctx context.Context, id int64) (storage.APIKey, error) {
+	ctx, span := s.start(ctx, "get_api_key")
+
+	key, err := s.Storage.GetAPIKey(ctx, id)
+	end(span, err)
+
+	return key, err
+}
+
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64) error {
+	ctx, span := s.start(ctx, "revoke_api_key", attribute.Int64("api_key.id", id))
+
+	err := s.Storage.RevokeAPIKey(ctx, id)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) SaveUser(ctx context.Context, user storage.User) (int64, error) {
+	ctx, span := s.start(ctx, "save_user")
+
+	id, err := s.Storage.SaveUser(ctx, user)
+	end(span, err)
+
+	return id, err
+}
+
+func (s *Storage) GetUser(ctx context.Context, email string) (storage.User, error) {
+	ctx, span := s.start(ctx, "get_user")
+
+	user, err := s.Storage.GetUser(ctx, email)
+	end(span, err)
+
+	return user, err
+}
+
+func (s *Storage) SetUserRole(ctx context.Context, email, role string) error {
+	ctx, span := s.start(ctx, "set_user_role")
+
+	err := s.Storage.SetUserRole(ctx, email, role)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) VerifyUser(ctx context.Context, email string) error {
+	ctx, span := s.start(ctx, "verify_user")
+
+	err := s.Storage.VerifyUser(ctx, email)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) SetUserPassword(ctx context.Context, email string, passHash []byte) error {
+	ctx, span := s.start(ctx, "set_user_password")
+
+	err := s.Storage.SetUserPassword(ctx, email, passHash)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) SaveUserToken(ctx context.Context, token storage.UserToken) error {
+	ctx, span := s.start(ctx, "save_user_token")
+
+	err := s.Storage.SaveUserToken(ctx, token)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) ConsumeUserToken(ctx context.Context, hash, purpose string) (storage.UserToken, error) {
+	ctx, span := s.start(ctx, "consume_user_token")
+
+	token, err := s.Storage.ConsumeUserToken(ctx, hash, purpose)
+	end(span, err)
+
+	return token, err
+}
+
+func (s *Storage) SaveDomain(ctx context.Context, domain storage.Domain) error {
+	ctx, span := s.start(ctx, "save_domain")
+
+	err := s.Storage.SaveDomain(ctx, domain)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) GetDomain(ctx context.Context, host string) (storage.Domain, error) {
+	ctx, span := s.start(ctx, "get_domain")
+
+	domain, err := s.Storage.GetDomain(ctx, host)
+	end(span, err)
+
+	return domain, err
+}
+
+func (s *Storage) ListDomains(ctx context.Context) ([]storage.Domain, error) {
+	ctx, span := s.start(ctx, "list_domains")
+
+	domains, err := s.Storage.ListDomains(ctx)
+	end(span, err)
+
+	return domains, err
+}
+
+func (s *Storage) DeleteDomain(ctx context.Context, host string) error {
+	ctx, span := s.start(ctx, "delete_domain")
+
+	err := s.Storage.DeleteDomain(ctx, host)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) SaveReport(ctx context.Context, report storage.Report) (int64, error) {
+	ctx, span := s.start(ctx, "save_report", aliasAttr(report.Alias))
+
+	id, err := s.Storage.SaveReport(ctx, report)
+	end(span, err)
+
+	return id, err
+}
+
+func (s *Storage) ListReports(ctx context.Context, status string, limit int) ([]storage.Report, error) {
+	ctx, span := s.start(ctx, "list_reports")
+
+	reports, err := s.Storage.ListReports(ctx, status, limit)
+	end(span, err)
+
+	return reports, err
+}
+
+func (s *Storage) ResolveReports(ctx context.Context, alias, status string) (int64, error) {
+	ctx, span := s.start(ctx, "resolve_reports", aliasAttr(alias))
+
+	n, err := s.Storage.ResolveReports(ctx, alias, status)
+	end(span, err)
+
+	return n, err
+}
+
+func (s *Storage) BanHost(ctx context.Context, host string) error {
+	ctx, span := s.start(ctx, "ban_host")
+
+	err := s.Storage.BanHost(ctx, host)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) UnbanHost(ctx context.Context, host string) error {
+	ctx, span := s.start(ctx, "unban_host")
+
+	err := s.Storage.UnbanHost(ctx, host)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) ListBannedHosts(ctx context.Context) ([]storage.BannedHost, error) {
+	ctx, span := s.start(ctx, "list_banned_hosts")
+
+	hosts, err := s.Storage.ListBannedHosts(ctx)
+	end(span, err)
+
+	return hosts, err
+}
+
+func (s *Storage) SaveWebhook(ctx context.Context, webhook storage.Webhook) (int64, error) {
+	ctx, span := s.start(ctx, "save_webhook")
+
+	id, err := s.Storage.SaveWebhook(ctx, webhook)
+	end(span, err)
+
+	return id, err
+}
+
+func (s *Storage) GetWebhook(ctx context.Context, id int64) (storage.Webhook, error) {
+	ctx, span := s.start(ctx, "get_webhook", webhookAttr(id))
+
+	webhook, err := s.Storage.GetWebhook(ctx, id)
+	end(span, err)
+
+	return webhook, err
+}
+
+func (s *Storage) ListWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	ctx, span := s.start(ctx, "list_webhooks")
+
+	webhooks, err := s.Storage.ListWebhooks(ctx)
+	end(span, err)
+
+	return webhooks, err
+}
+
+func (s *Storage) UpdateWebhook(ctx context.Context, webhook storage.Webhook) error {
+	ctx, span := s.start(ctx, "update_webhook", webhookAttr(webhook.ID))
+
+	err := s.Storage.UpdateWebhook(ctx, webhook)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) DeleteWebhook(ctx context.Context, id int64) error {
+	ctx, span := s.start(ctx, "delete_webhook", webhookAttr(id))
+
+	err := s.Storage.DeleteWebhook(ctx, id)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) SaveWebhookDelivery(ctx context.Context, delivery storage.WebhookDelivery) error {
+	ctx, span := s.start(ctx, "save_webhook_delivery", webhookAttr(delivery.WebhookID))
+
+	err := s.Storage.SaveWebhookDelivery(ctx, delivery)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]storage.WebhookDelivery, error) {
+	ctx, span := s.start(ctx, "list_webhook_deliveries", webhookAttr(webhookID))
+
+	deliveries, err := s.Storage.ListWebhookDeliveries(ctx, webhookID, limit)
+	end(span, err)
+
+	return deliveries, err
+}
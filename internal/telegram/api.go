@@ -0,0 +1,94 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// DefaultAPIURL is the endpoint of the Telegram Bot API.
+const DefaultAPIURL = "https://api.telegram.org"
+
+type update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *message `json:"message,omitempty"`
+}
+
+type message struct {
+	MessageID int64  `json:"message_id"`
+	From      *user  `json:"from,omitempty"`
+	Chat      chat   `json:"chat"`
+	Text      string `json:"text,omitempty"`
+}
+
+type user struct {
+	ID int64 `json:"id"`
+}
+
+type chat struct {
+	ID int64 `json:"id"`
+}
+
+type getUpdatesRequest struct {
+	Offset         int64    `json:"offset,omitempty"`
+	Timeout        int      `json:"timeout"`
+	AllowedUpdates []string `json:"allowed_updates"`
+}
+
+type sendMessageRequest struct {
+	ChatID           int64  `json:"chat_id"`
+	Text             string `json:"text"`
+	ReplyToMessageID int64  `json:"reply_to_message_id,omitempty"`
+	// Превью коротких ссылок засчитывались бы как переходы
+	DisableWebPagePreview bool `json:"disable_web_page_preview"`
+}
+
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Description string          `json:"description,omitempty"`
+	Result      json.RawMessage `json:"result,omitempty"`
+}
+
+// call POSTs the method with the JSON params and decodes the result into result, if set.
+func (b *Bot) call(ctx context.Context, method string, params, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.apiURL+"/bot"+b.token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		// Ошибка клиента содержит адрес запроса, а в нем токен бота
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer res.Body.Close()
+
+	var apiRes apiResponse
+	if err := json.NewDecoder(res.Body).Decode(&apiRes); err != nil {
+		return fmt.Errorf("%s: unexpected response status %d", method, res.StatusCode)
+	}
+	if !apiRes.OK {
+		return fmt.Errorf("%s: %s", method, apiRes.Description)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(apiRes.Result, result)
+}
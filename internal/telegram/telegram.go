@@ -0,0 +1,289 @@
+// Package telegram runs a Telegram bot which lets users of the service shorten urls,
+// look up stats and delete their links from a chat. Telegram users are mapped to
+// accounts of the service in the config, and the bot acts with their roles.
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/shortener"
+	"url-shortener/internal/storage"
+)
+
+const (
+	// retryDelay is the pause after a failed poll, so an outage of the API or a revoked
+	// token doesn't spin the loop.
+	retryDelay = 5 * time.Second
+	// commandTimeout limits handling of one message.
+	commandTimeout = 10 * time.Second
+	// topReferrers is the number of referrers in /stats replies.
+	topReferrers = 3
+)
+
+const helpText = `Send me a link to shorten it.
+
+/shorten <url> - shorten the url
+/stats <alias> - clicks of the link
+/delete <alias> - delete your link`
+
+// Service is an interface for managing links on behalf of a user, see package shortener.
+type Service interface {
+	Shorten(ctx context.Context, account shortener.Account, rawURL string) (string, error)
+	Stats(ctx context.Context, account shortener.Account, alias string) (storage.URL, storage.ClickStats, error)
+	Delete(ctx context.Context, account shortener.Account, alias string) error
+}
+
+// UserGetter is an interface for reading accounts the Telegram users are mapped to.
+type UserGetter interface {
+	GetUser(ctx context.Context, email string) (storage.User, error)
+}
+
+type Options struct {
+	Token string
+	// APIURL is DefaultAPIURL if empty.
+	APIURL string
+	// Users maps ids of Telegram users allowed to use the bot to emails of their accounts.
+	Users map[int64]string
+	// BaseURL prefixes aliases in replies, e.g. "https://sho.rt"; replies have bare
+	// aliases if it is empty.
+	BaseURL string
+	// PollTimeout is how long a request for updates waits for new messages.
+	PollTimeout time.Duration
+}
+
+type Bot struct {
+	log         *slog.Logger
+	service     Service
+	users       UserGetter
+	allowed     map[int64]string
+	token       string
+	apiURL      string
+	baseURL     string
+	pollTimeout time.Duration
+	client      *http.Client
+}
+
+func New(log *slog.Logger, service Service, users UserGetter, opts Options) *Bot {
+	apiURL := opts.APIURL
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+
+	return &Bot{
+		log:         log.With(slog.String("component", "telegram")),
+		service:     service,
+		users:       users,
+		allowed:     opts.Users,
+		token:       opts.Token,
+		apiURL:      strings.TrimSuffix(apiURL, "/"),
+		baseURL:     strings.TrimSuffix(opts.BaseURL, "/"),
+		pollTimeout: opts.PollTimeout,
+		// Запрос обновлений висит до PollTimeout, клиенту нужен запас сверху
+		client: &http.Client{Timeout: opts.PollTimeout + commandTimeout},
+	}
+}
+
+// Run polls for messages and answers them until ctx is done.
+func (b *Bot) Run(ctx context.Context) {
+	const op = "telegram.Bot.Run"
+
+	log := b.log.With(slog.String("op", op))
+
+	var offset int64
+
+	for {
+		var updates []update
+
+		err := b.call(ctx, "getUpdates", getUpdatesRequest{
+			Offset:         offset,
+			Timeout:        int(b.pollTimeout.Seconds()),
+			AllowedUpdates: []string{"message"},
+		}, &updates)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Error("failed to get updates", sl.Err(err))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryDelay):
+			}
+
+			continue
+		}
+
+		for _, u := range updates {
+			// Telegram считает обновление доставленным, когда следующий запрос
+			// передает offset больше его номера
+			offset = u.UpdateID + 1
+
+			if u.Message != nil && u.Message.From != nil && u.Message.Text != "" {
+				b.handle(ctx, *u.Message)
+			}
+		}
+	}
+}
+
+// handle answers the message.
+func (b *Bot) handle(ctx context.Context, m message) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	reply := b.answer(ctx, m)
+
+	err := b.call(ctx, "sendMessage", sendMessageRequest{
+		ChatID:                m.Chat.ID,
+		Text:                  reply,
+		ReplyToMessageID:      m.MessageID,
+		DisableWebPagePreview: true,
+	}, nil)
+	if err != nil {
+		b.log.Error("failed to send message", slog.Int64("chat_id", m.Chat.ID), sl.Err(err))
+	}
+}
+
+// answer runs the command of the message and returns the reply.
+func (b *Bot) answer(ctx context.Context, m message) string {
+	log := b.log.With(slog.Int64("telegram_user_id", m.From.ID))
+
+	command, arg := parseCommand(m.Text)
+
+	if command == "start" || command == "help" {
+		return helpText
+	}
+
+	email, ok := b.allowed[m.From.ID]
+	if !ok {
+		log.Info("telegram user is not allowed")
+
+		// Идентификатор нужен администратору, чтобы добавить пользователя в конфиг
+		return fmt.Sprintf("You are not allowed to use this bot. Your Telegram user id is %d.", m.From.ID)
+	}
+
+	u, err := b.users.GetUser(ctx, email)
+	if err != nil {
+		log.Error("failed to get user", slog.String("email", email), sl.Err(err))
+
+		return "Your account is not available, contact the administrator."
+	}
+
+	account := shortener.UserAccount(u)
+
+	switch command {
+	case "shorten":
+		return b.shorten(ctx, log, account, arg)
+	case "stats":
+		return b.stats(ctx, log, account, arg)
+	case "delete":
+		return b.delete(ctx, log, account, arg)
+	default:
+		return "Unknown command.\n\n" + helpText
+	}
+}
+
+func (b *Bot) shorten(ctx context.Context, log *slog.Logger, account shortener.Account, rawURL string) string {
+	if rawURL == "" {
+		return "Usage: /shorten <url>"
+	}
+
+	alias, err := b.service.Shorten(ctx, account, rawURL)
+	if err != nil {
+		return b.failure(log, "shorten url", err)
+	}
+
+	log.Info("url shortened", slog.String("alias", alias))
+
+	return b.shortURL(alias)
+}
+
+func (b *Bot) stats(ctx context.Context, log *slog.Logger, account shortener.Account, alias string) string {
+	if alias == "" {
+		return "Usage: /stats <alias>"
+	}
+
+	u, stats, err := b.service.Stats(ctx, account, alias)
+	if err != nil {
+		return b.failure(log, "get stats", err)
+	}
+
+	var reply strings.Builder
+
+	fmt.Fprintf(&reply, "%s -> %s\nClicks: %d\nUnique visitors: %d", b.shortURL(alias), u.URL, stats.Total, stats.Uniques)
+
+	if n := min(len(stats.ByReferrerHost), topReferrers); n > 0 {
+		referrers := make([]string, n)
+		for i, c := range stats.ByReferrerHost[:n] {
+			referrers[i] = fmt.Sprintf("%s (%d)", c.Key, c.Count)
+		}
+
+		reply.WriteString("\nTop referrers: " + strings.Join(referrers, ", "))
+	}
+
+	return reply.String()
+}
+
+func (b *Bot) delete(ctx context.Context, log *slog.Logger, account shortener.Account, alias string) string {
+	if alias == "" {
+		return "Usage: /delete <alias>"
+	}
+
+	if err := b.service.Delete(ctx, account, alias); err != nil {
+		return b.failure(log, "delete url", err)
+	}
+
+	log.Info("url deleted", slog.String("alias", alias))
+
+	return "Deleted " + alias + "."
+}
+
+// failure logs the error of the action and returns the reply explaining it.
+func (b *Bot) failure(log *slog.Logger, action string, err error) string {
+	switch {
+	case errors.Is(err, shortener.ErrRejected):
+		log.Info("url rejected", sl.Err(err))
+
+		return "Can't shorten it: " + strings.TrimPrefix(err.Error(), shortener.ErrRejected.Error()+": ")
+	case errors.Is(err, shortener.ErrForbidden):
+		log.Info("forbidden", slog.String("action", action))
+
+		return "You are not allowed to do that."
+	case errors.Is(err, storage.ErrURLNotFound):
+		return "Link not found."
+	default:
+		log.Error("failed to "+action, sl.Err(err))
+
+		return "Something went wrong, try again later."
+	}
+}
+
+func (b *Bot) shortURL(alias string) string {
+	if b.baseURL == "" {
+		return alias
+	}
+
+	return b.baseURL + "/" + alias
+}
+
+// parseCommand splits "/command@bot argument" into the command and the argument.
+// A message without a command is a url to shorten.
+func parseCommand(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return "shorten", text
+	}
+
+	command, arg, _ := strings.Cut(text[1:], " ")
+	command, _, _ = strings.Cut(command, "@")
+
+	return strings.ToLower(command), strings.TrimSpace(arg)
+}
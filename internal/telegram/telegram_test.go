@@ -0,0 +1,140 @@
+package telegram_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/shortener"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/telegram"
+)
+
+type fakeService struct{}
+
+func (fakeService) Shorten(_ context.Context, account shortener.Account, rawURL string) (string, error) {
+	if rawURL == "https://evil.example" {
+		return "", fmt.Errorf("%w: url is flagged as malicious: phishing", shortener.ErrRejected)
+	}
+
+	return fmt.Sprintf("u%d", account.UserID), nil
+}
+
+func (fakeService) Stats(_ context.Context, _ shortener.Account, alias string) (storage.URL, storage.ClickStats, error) {
+	if alias != "promo" {
+		return storage.URL{}, storage.ClickStats{}, storage.ErrURLNotFound
+	}
+
+	return storage.URL{URL: "https://example.com"}, storage.ClickStats{
+		Total:   5,
+		Uniques: 4,
+		ByReferrerHost: []storage.Count{
+			{Key: "google.com", Count: 3}, {Key: storage.DirectReferrer, Count: 1},
+			{Key: "t.me", Count: 1}, {Key: "x.com", Count: 1},
+		},
+	}, nil
+}
+
+func (fakeService) Delete(context.Context, shortener.Account, string) error {
+	return shortener.ErrForbidden
+}
+
+type fakeUsers struct{}
+
+func (fakeUsers) GetUser(_ context.Context, email string) (storage.User, error) {
+	return storage.User{ID: 7, Email: email}, nil
+}
+
+func TestBot(t *testing.T) {
+	cases := []struct {
+		name   string
+		from   int64
+		text   string
+		answer string
+	}{
+		{name: "Shorten", from: 1, text: "/shorten https://example.com", answer: "https://sho.rt/u7"},
+		{name: "Plain url", from: 1, text: "https://example.com", answer: "https://sho.rt/u7"},
+		{name: "Rejected", from: 1, text: "/shorten@url_bot https://evil.example", answer: "Can't shorten it: url is flagged as malicious: phishing"},
+		{
+			name:   "Stats",
+			from:   1,
+			text:   "/stats promo",
+			answer: "https://sho.rt/promo -> https://example.com\nClicks: 5\nUnique visitors: 4\nTop referrers: google.com (3), direct (1), t.me (1)",
+		},
+		{name: "Stats of missing link", from: 1, text: "/stats missing", answer: "Link not found."},
+		{name: "Delete forbidden", from: 1, text: "/delete promo", answer: "You are not allowed to do that."},
+		{name: "Usage", from: 1, text: "/delete", answer: "Usage: /delete <alias>"},
+		{name: "Unknown user", from: 2, text: "/shorten https://example.com", answer: "You are not allowed to use this bot. Your Telegram user id is 2."},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sent := make(chan map[string]any, 1)
+			polled := false
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/bottoken/getUpdates":
+					// Сообщение отдается один раз, дальше запрос ждет, как long polling
+					if polled {
+						<-r.Context().Done()
+
+						return
+					}
+					polled = true
+
+					_, _ = fmt.Fprintf(w, `{"ok":true,"result":[{"update_id":10,"message":{"message_id":3,"from":{"id":%d},"chat":{"id":42},"text":%q}}]}`, tc.from, tc.text)
+				case "/bottoken/sendMessage":
+					var body map[string]any
+					require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+					sent <- body
+
+					_, _ = w.Write([]byte(`{"ok":true,"result":{}}`))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer srv.Close()
+
+			bot := telegram.New(slogdiscard.NewDiscardLogger(), fakeService{}, fakeUsers{}, telegram.Options{
+				Token:   "token",
+				APIURL:  srv.URL,
+				Users:   map[int64]string{1: "alice@example.com"},
+				BaseURL: "https://sho.rt/",
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+
+			go func() {
+				defer close(done)
+
+				bot.Run(ctx)
+			}()
+
+			select {
+			case body := <-sent:
+				require.Equal(t, float64(42), body["chat_id"])
+				require.Equal(t, float64(3), body["reply_to_message_id"])
+				require.Equal(t, tc.answer, body["text"])
+			case <-time.After(5 * time.Second):
+				t.Fatal("no reply sent")
+			}
+
+			cancel()
+			<-done
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Formats of exported links.
+const (
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown format, use ndjson or csv")
+	ErrInvalidHeader = errors.New("csv header must contain alias and url columns")
+)
+
+// Record is an exported link. Owners, hits and timestamps are not transferred.
+type Record struct {
+	Alias        string     `json:"alias"`
+	URL          string     `json:"url"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	RedirectCode int        `json:"redirect_code,omitempty"`
+}
+
+var csvHeader = []string{"alias", "url", "expires_at", "redirect_code"}
+
+// ContentType returns the MIME type of the format.
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+
+	return "application/x-ndjson"
+}
+
+// Writer writes records in one of the formats.
+type Writer interface {
+	Write(rec Record) error
+	// Flush writes buffered records to the underlying writer.
+	Flush() error
+}
+
+// NewWriter returns a writer of format to w.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatNDJSON, "":
+		bw := bufio.NewWriter(w)
+
+		return &ndjsonWriter{w: bw, enc: json.NewEncoder(bw)}, nil
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// Reader reads records in one of the formats. Read returns io.EOF after the last record.
+type Reader interface {
+	Read() (Record, error)
+}
+
+// NewReader returns a reader of format from r.
+func NewReader(r io.Reader, format string) (Reader, error) {
+	switch format {
+	case FormatNDJSON, "":
+		return &ndjsonReader{dec: json.NewDecoder(r)}, nil
+	case FormatCSV:
+		return &csvReader{r: csv.NewReader(r)}, nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+type ndjsonWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(rec Record) error {
+	return w.enc.Encode(rec)
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return w.w.Flush()
+}
+
+type ndjsonReader struct {
+	dec *json.Decoder
+}
+
+func (r *ndjsonReader) Read() (Record, error) {
+	var rec Record
+	err := r.dec.Decode(&rec)
+
+	return rec, err
+}
+
+type csvWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (w *csvWriter) Write(rec Record) error {
+	if !w.headerWritten {
+		if err := w.w.Write(csvHeader); err != nil {
+			return err
+		}
+
+		w.headerWritten = true
+	}
+
+	var expiresAt, code string
+	if rec.ExpiresAt != nil {
+		expiresAt = rec.ExpiresAt.Format(time.RFC3339)
+	}
+	if rec.RedirectCode != 0 {
+		code = strconv.Itoa(rec.RedirectCode)
+	}
+
+	return w.w.Write([]string{rec.Alias, rec.URL, expiresAt, code})
+}
+
+func (w *csvWriter) Flush() error {
+	// Пустой экспорт все равно получает заголовок, чтобы файл можно было импортировать
+	if !w.headerWritten {
+		if err := w.w.Write(csvHeader); err != nil {
+			return err
+		}
+
+		w.headerWritten = true
+	}
+
+	w.w.Flush()
+
+	return w.w.Error()
+}
+
+type csvReader struct {
+	r *csv.Reader
+	// columns maps column names of the header to their positions.
+	columns map[string]int
+}
+
+func (r *csvReader) Read() (Record, error) {
+	if r.columns == nil {
+		if err := r.readHeader(); err != nil {
+			return Record{}, err
+		}
+	}
+
+	row, err := r.r.Read()
+	if err != nil {
+		return Record{}, err
+	}
+
+	rec := Record{Alias: r.field(row, "alias"), URL: r.field(row, "url")}
+
+	if v := r.field(row, "expires_at"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return Record{}, fmt.Errorf("invalid expires_at %q", v)
+		}
+
+		rec.ExpiresAt = &t
+	}
+
+	if v := r.field(row, "redirect_code"); v != "" {
+		code, err := strconv.Atoi(v)
+		if err != nil {
+			return Record{}, fmt.Errorf("invalid redirect_code %q", v)
+		}
+
+		rec.RedirectCode = code
+	}
+
+	return rec, nil
+}
+
+func (r *csvReader) readHeader() error {
+	header, err := r.r.Read()
+	if err != nil {
+		return err
+	}
+
+	r.columns = make(map[string]int, len(header))
+	for i, name := range header {
+		r.columns[name] = i
+	}
+
+	_, hasAlias := r.columns["alias"]
+	_, hasURL := r.columns["url"]
+	if !hasAlias || !hasURL {
+		return ErrInvalidHeader
+	}
+
+	// Строки могут быть короче заголовка, если последние колонки пустые
+	r.r.FieldsPerRecord = -1
+
+	return nil
+}
+
+func (r *csvReader) field(row []string, name string) string {
+	i, ok := r.columns[name]
+	if !ok || i >= len(row) {
+		return ""
+	}
+
+	return row[i]
+}
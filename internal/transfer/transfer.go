@@ -0,0 +1,410 @@
+// Package transfer exports links to CSV or ND-JSON and imports them back.
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/go-playground/validator/v10"
+
+	"url-shortener/internal/acl"
+	"url-shortener/internal/lib/aliascheck"
+	"url-shortener/internal/lib/loopcheck"
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/lib/urlcheck"
+	"url-shortener/internal/screening"
+	"url-shortener/internal/storage"
+)
+
+// Strategies of handling imported links whose aliases are taken.
+const (
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictRename    = "rename"
+)
+
+const (
+	// batchSize is the number of links read or saved per storage call.
+	batchSize = 100
+	// renameSuffixLength is the length of the random suffix added to taken aliases by rename.
+	renameSuffixLength = 4
+	renameAttempts     = 5
+)
+
+var ErrUnknownConflict = errors.New("unknown conflict strategy, use skip, overwrite or rename")
+
+// MalformedError means the input can't be read further, e.g. broken JSON or CSV.
+type MalformedError struct {
+	Line int
+	Err  error
+}
+
+func (e *MalformedError) Error() string {
+	return fmt.Sprintf("record %d: %s", e.Line, e.Err)
+}
+
+func (e *MalformedError) Unwrap() error {
+	return e.Err
+}
+
+type URLLister interface {
+	ListURLs(ctx context.Context, filter storage.ListFilter) ([]storage.URL, string, error)
+}
+
+// URLImporter saves imported links; GetURLInfo and UpdateURL are used by ConflictOverwrite.
+type URLImporter interface {
+	SaveURL(ctx context.Context, u storage.URL) (int64, error)
+	SaveURLs(ctx context.Context, urls []storage.URL) ([]int64, error)
+	GetURLInfo(ctx context.Context, alias string) (storage.URL, error)
+	UpdateURL(ctx context.Context, alias string, update storage.URLUpdate, version int64) (storage.URL, error)
+}
+
+// Export writes every link of the tenant matching filter to w and returns their number.
+func Export(ctx context.Context, lister URLLister, w Writer, filter storage.ListFilter, tenantName string) (int, error) {
+	const op = "transfer.Export"
+
+	filter.AliasPrefix = tenant.Key(tenantName, filter.AliasPrefix)
+	filter.Limit = batchSize
+
+	var n int
+	for {
+		urls, next, err := lister.ListURLs(ctx, filter)
+		if err != nil {
+			return n, fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, u := range urls {
+			rec := Record{Alias: tenant.Alias(tenantName, u.Alias), URL: u.URL, RedirectCode: u.RedirectCode}
+			if !u.ExpiresAt.IsZero() {
+				expiresAt := u.ExpiresAt
+				rec.ExpiresAt = &expiresAt
+			}
+
+			if err := w.Write(rec); err != nil {
+				return n, fmt.Errorf("%s: %w", op, err)
+			}
+
+			n++
+		}
+
+		if next == "" {
+			break
+		}
+
+		filter.Cursor = next
+	}
+
+	if err := w.Flush(); err != nil {
+		return n, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// ImportOptions configure Import.
+type ImportOptions struct {
+	// Conflict is the strategy for taken aliases, ConflictSkip if empty.
+	Conflict string
+	// UserID and APIKeyID become the owner of imported links. Only links they may
+	// manage are overwritten, see acl.Principal.CanManage; the rest are skipped.
+	UserID   int64
+	APIKeyID int64
+	// Admin overwrites links of everyone.
+	Admin bool
+	// Tenant is the namespace links are imported into.
+	Tenant string
+	// Host is the host the request came to, links to it are loops too; it may be empty.
+	Host string
+}
+
+// InvalidRecord is a record rejected by validation; Line counts records from 1.
+type InvalidRecord struct {
+	Line   int
+	Alias  string
+	Reason string
+}
+
+// Result reports what Import has done with every record.
+type Result struct {
+	Imported    int
+	Overwritten []string
+	Skipped     []string
+	// Renamed maps taken aliases to the ones the links were saved with.
+	Renamed map[string]string
+	Invalid []InvalidRecord
+}
+
+// ValidConflict reports whether conflict is a known strategy.
+func ValidConflict(conflict string) bool {
+	switch conflict {
+	case ConflictSkip, ConflictOverwrite, ConflictRename, "":
+		return true
+	default:
+		return false
+	}
+}
+
+// Import validates records read from r and saves them in batches. Destinations are
+// normalized by checker, screened by screener and checked for loops by loopChecker
+// like those of POST /url/batch; flagged records and loops are reported as invalid.
+// Invalid records are reported in the result and don't stop the import; malformed
+// input does.
+func Import(
+	ctx context.Context,
+	importer URLImporter,
+	checker *urlcheck.Checker,
+	screener screening.Screener,
+	loopChecker *loopcheck.Checker,
+	r Reader,
+	opts ImportOptions,
+) (Result, error) {
+	const op = "transfer.Import"
+
+	if !ValidConflict(opts.Conflict) {
+		return Result{}, ErrUnknownConflict
+	}
+
+	var (
+		res      = Result{Renamed: make(map[string]string)}
+		validate = validator.New()
+		batch    = make([]storage.URL, 0, batchSize)
+		// lines[i] - номер записи ссылки batch[i]
+		lines = make([]int, 0, batchSize)
+	)
+
+	for line := 1; ; line++ {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return res, fmt.Errorf("%s: %w", op, &MalformedError{Line: line, Err: err})
+		}
+
+		if reason := check(validate, checker, &rec); reason != "" {
+			res.Invalid = append(res.Invalid, InvalidRecord{Line: line, Alias: rec.Alias, Reason: reason})
+
+			continue
+		}
+
+		// Переходы по редиректам не проверяем, как и в POST /url/batch: для многих ссылок это слишком долго
+		err = loopChecker.CheckHost(ctx, rec.URL, opts.Host)
+		if errors.Is(err, loopcheck.ErrLoop) {
+			res.Invalid = append(res.Invalid, InvalidRecord{Line: line, Alias: rec.Alias, Reason: err.Error()})
+
+			continue
+		}
+		if err != nil {
+			return res, fmt.Errorf("%s: %w", op, err)
+		}
+
+		u := storage.URL{
+			Alias:        tenant.Key(opts.Tenant, rec.Alias),
+			URL:          rec.URL,
+			RedirectCode: rec.RedirectCode,
+			APIKeyID:     opts.APIKeyID,
+			UserID:       opts.UserID,
+		}
+		if rec.ExpiresAt != nil {
+			u.ExpiresAt = *rec.ExpiresAt
+		}
+
+		batch = append(batch, u)
+		lines = append(lines, line)
+		if len(batch) == batchSize {
+			if err := saveBatch(ctx, importer, screener, batch, lines, opts, &res); err != nil {
+				return res, fmt.Errorf("%s: %w", op, err)
+			}
+
+			batch, lines = batch[:0], lines[:0]
+		}
+	}
+
+	if err := saveBatch(ctx, importer, screener, batch, lines, opts, &res); err != nil {
+		return res, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Отмеченные при проверке ссылки добавляются после пачки, порядок восстанавливаем
+	slices.SortStableFunc(res.Invalid, func(a, b InvalidRecord) int { return a.Line - b.Line })
+
+	return res, nil
+}
+
+// check returns the reason the record can't be imported, empty if it can. The url of
+// rec is replaced with the normalized one.
+func check(validate *validator.Validate, checker *urlcheck.Checker, rec *Record) string {
+	if err := aliascheck.Validate(rec.Alias); err != nil {
+		return err.Error()
+	}
+
+	if err := validate.Var(rec.URL, "required,url"); err != nil {
+		return "url is not a valid URL"
+	}
+
+	// Импорт тоже сохраняет ссылки: схемы, длина и приватные адреса проверяются как в POST /url
+	normalized, err := checker.Normalize(rec.URL)
+	if err != nil {
+		return err.Error()
+	}
+	rec.URL = normalized
+
+	switch rec.RedirectCode {
+	case 0, 301, 302, 307, 308:
+		return ""
+	default:
+		return "redirect_code must be one of 301, 302, 307, 308"
+	}
+}
+
+// saveBatch screens urls read from lines, saves those not flagged and resolves taken
+// aliases by the conflict strategy.
+func saveBatch(
+	ctx context.Context,
+	importer URLImporter,
+	screener screening.Screener,
+	urls []storage.URL,
+	lines []int,
+	opts ImportOptions,
+	res *Result,
+) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	// Недоступный источник не мешает импорту, как и в POST /url:
+	// ссылки проверит периодическая перепроверка
+	destinations := make([]string, len(urls))
+	for i, u := range urls {
+		destinations[i] = u.URL
+	}
+	flagged, _ := screener.Screen(ctx, destinations)
+
+	kept := make([]storage.URL, 0, len(urls))
+	for i, u := range urls {
+		if reason, ok := flagged[u.URL]; ok {
+			res.Invalid = append(res.Invalid, InvalidRecord{
+				Line:   lines[i],
+				Alias:  tenant.Alias(opts.Tenant, u.Alias),
+				Reason: "url is flagged as malicious: " + reason,
+			})
+
+			continue
+		}
+
+		kept = append(kept, u)
+	}
+	urls = kept
+
+	if len(urls) == 0 {
+		return nil
+	}
+
+	ids, err := importer.SaveURLs(ctx, urls)
+	if err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		if id != 0 {
+			res.Imported++
+
+			continue
+		}
+
+		u := urls[i]
+		// В результате alias без тенанта, как в импортированном файле
+		alias := tenant.Alias(opts.Tenant, u.Alias)
+
+		switch opts.Conflict {
+		case ConflictOverwrite:
+			ok, err := overwrite(ctx, importer, u, acl.Principal{UserID: opts.UserID, APIKeyID: opts.APIKeyID, Admin: opts.Admin})
+			if err != nil {
+				return err
+			}
+			if !ok {
+				res.Skipped = append(res.Skipped, alias)
+
+				continue
+			}
+
+			res.Overwritten = append(res.Overwritten, alias)
+		case ConflictRename:
+			renamed, err := rename(ctx, importer, u, opts.Tenant)
+			if err != nil {
+				return err
+			}
+			if renamed == "" {
+				res.Skipped = append(res.Skipped, alias)
+
+				continue
+			}
+
+			res.Renamed[alias] = renamed
+		default:
+			res.Skipped = append(res.Skipped, alias)
+		}
+	}
+
+	return nil
+}
+
+// overwrite replaces the link under u.Alias. It returns false if the link is deleted
+// or belongs to another user.
+func overwrite(ctx context.Context, importer URLImporter, u storage.URL, owner acl.Principal) (bool, error) {
+	existing, err := importer.GetURLInfo(ctx, u.Alias)
+	if errors.Is(err, storage.ErrURLNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !owner.CanManage(existing) {
+		return false, nil
+	}
+
+	_, err = importer.UpdateURL(ctx, u.Alias, storage.URLUpdate{
+		URL:          &u.URL,
+		ExpiresAt:    &u.ExpiresAt,
+		RedirectCode: &u.RedirectCode,
+	}, 0)
+	// Удаленная ссылка занимает alias, но обновить ее нельзя
+	if errors.Is(err, storage.ErrURLNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// rename saves u under its alias with a random suffix in the tenant namespace and
+// returns the new alias. It returns "" if no free alias was found.
+func rename(ctx context.Context, importer URLImporter, u storage.URL, tenantName string) (string, error) {
+	base := tenant.Alias(tenantName, u.Alias)
+	if maxBase := aliascheck.MaxLength - renameSuffixLength - 1; len(base) > maxBase {
+		base = base[:maxBase]
+	}
+
+	for attempt := 0; attempt < renameAttempts; attempt++ {
+		alias := base + "-" + random.NewRandomString(renameSuffixLength)
+		u.Alias = tenant.Key(tenantName, alias)
+
+		_, err := importer.SaveURL(ctx, u)
+		if errors.Is(err, storage.ErrURLExists) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		return alias, nil
+	}
+
+	return "", nil
+}
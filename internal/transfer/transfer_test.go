@@ -0,0 +1,414 @@
+package transfer_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domains"
+	"url-shortener/internal/lib/loopcheck"
+	"url-shortener/internal/lib/tenant"
+	"url-shortener/internal/lib/urlcheck"
+	"url-shortener/internal/screening"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/sqlite"
+	"url-shortener/internal/transfer"
+)
+
+var (
+	checker     = urlcheck.New(urlcheck.Options{MaxLength: 100, BlockPrivate: true})
+	screener    = screening.NewBlocklist([]string{"phish.example"})
+	loopChecker = loopcheck.New(nil, loopcheck.Options{Hosts: []string{"sho.rt"}})
+)
+
+func newSQLite(t *testing.T) *sqlite.Storage {
+	t.Helper()
+
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+
+	return s
+}
+
+func TestExportImport(t *testing.T) {
+	for _, format := range []string{transfer.FormatNDJSON, transfer.FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			src := newSQLite(t)
+
+			expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+			for _, u := range []storage.URL{
+				{Alias: "one", URL: "https://example.com/1"},
+				{Alias: "two", URL: "https://example.com/2", ExpiresAt: expiresAt, RedirectCode: 301},
+			} {
+				_, err := src.SaveURL(context.Background(), u)
+				require.NoError(t, err)
+			}
+
+			var buf bytes.Buffer
+			w, err := transfer.NewWriter(&buf, format)
+			require.NoError(t, err)
+
+			n, err := transfer.Export(context.Background(), src, w, storage.ListFilter{}, tenant.Default)
+			require.NoError(t, err)
+			require.Equal(t, 2, n)
+
+			dst := newSQLite(t)
+			r, err := transfer.NewReader(&buf, format)
+			require.NoError(t, err)
+
+			res, err := transfer.Import(context.Background(), dst, checker, screener, loopChecker, r, transfer.ImportOptions{})
+			require.NoError(t, err)
+			require.Equal(t, 2, res.Imported)
+
+			u, err := dst.GetURLInfo(context.Background(), "two")
+			require.NoError(t, err)
+			require.Equal(t, "https://example.com/2", u.URL)
+			require.Equal(t, 301, u.RedirectCode)
+			require.True(t, expiresAt.Equal(u.ExpiresAt))
+		})
+	}
+}
+
+func TestImport_Conflict(t *testing.T) {
+	const input = `{"alias":"taken","url":"https://example.com/new"}
+{"alias":"free","url":"https://example.com/free"}
+{"alias":"x","url":"https://example.com/short-alias"}
+{"alias":"bad-url","url":"not a url"}
+`
+
+	cases := []struct {
+		conflict string
+		check    func(t *testing.T, s *sqlite.Storage, res transfer.Result)
+	}{
+		{
+			conflict: transfer.ConflictSkip,
+			check: func(t *testing.T, s *sqlite.Storage, res transfer.Result) {
+				require.Equal(t, []string{"taken"}, res.Skipped)
+
+				u, err := s.GetURLInfo(context.Background(), "taken")
+				require.NoError(t, err)
+				require.Equal(t, "https://example.com/old", u.URL)
+			},
+		},
+		{
+			conflict: transfer.ConflictOverwrite,
+			check: func(t *testing.T, s *sqlite.Storage, res transfer.Result) {
+				require.Equal(t, []string{"taken"}, res.Overwritten)
+
+				u, err := s.GetURLInfo(context.Background(), "taken")
+				require.NoError(t, err)
+				require.Equal(t, "https://example.com/new", u.URL)
+			},
+		},
+		{
+			conflict: transfer.ConflictRename,
+			check: func(t *testing.T, s *sqlite.Storage, res transfer.Result) {
+				alias := res.Renamed["taken"]
+				require.True(t, strings.HasPrefix(alias, "taken-"), alias)
+
+				u, err := s.GetURLInfo(context.Background(), alias)
+				require.NoError(t, err)
+				require.Equal(t, "https://example.com/new", u.URL)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.conflict, func(t *testing.T) {
+			s := newSQLite(t)
+
+			_, err := s.SaveURL(context.Background(), storage.URL{Alias: "taken", URL: "https://example.com/old"})
+			require.NoError(t, err)
+
+			r, err := transfer.NewReader(strings.NewReader(input), transfer.FormatNDJSON)
+			require.NoError(t, err)
+
+			res, err := transfer.Import(context.Background(), s, checker, screener, loopChecker, r, transfer.ImportOptions{Conflict: tc.conflict, Admin: true})
+			require.NoError(t, err)
+
+			require.Equal(t, 1, res.Imported)
+			require.Len(t, res.Invalid, 2)
+			require.Equal(t, 3, res.Invalid[0].Line)
+			require.Equal(t, 4, res.Invalid[1].Line)
+
+			tc.check(t, s, res)
+		})
+	}
+}
+
+func TestImport_CheckedDestinations(t *testing.T) {
+	const input = `{"alias":"private","url":"http://192.168.1.1/admin"}
+{"alias":"long","url":"https://example.com/` + "0123456789012345678901234567890123456789012345678901234567890123456789012345678901234567890123456789" + `"}
+{"alias":"scheme","url":"ftp://example.com/file"}
+{"alias":"taken","url":"http://10.0.0.1/"}
+{"alias":"ok","url":"HTTPS://Example.COM/page"}
+`
+
+	s := newSQLite(t)
+
+	_, err := s.SaveURL(context.Background(), storage.URL{Alias: "taken", URL: "https://example.com/old"})
+	require.NoError(t, err)
+
+	r, err := transfer.NewReader(strings.NewReader(input), transfer.FormatNDJSON)
+	require.NoError(t, err)
+
+	// Перезапись тоже не должна пропускать то, что отклоняет POST /url
+	res, err := transfer.Import(context.Background(), s, checker, screener, loopChecker, r, transfer.ImportOptions{Conflict: transfer.ConflictOverwrite, Admin: true})
+	require.NoError(t, err)
+	require.Equal(t, 1, res.Imported)
+	require.Empty(t, res.Overwritten)
+
+	require.Equal(t, []transfer.InvalidRecord{
+		{Line: 1, Alias: "private", Reason: "url points to a private address"},
+		{Line: 2, Alias: "long", Reason: "url is too long, max 100 characters"},
+		{Line: 3, Alias: "scheme", Reason: "url scheme is not allowed: ftp"},
+		{Line: 4, Alias: "taken", Reason: "url points to a private address"},
+	}, res.Invalid)
+
+	u, err := s.GetURLInfo(context.Background(), "ok")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/page", u.URL)
+
+	u, err = s.GetURLInfo(context.Background(), "taken")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/old", u.URL)
+}
+
+func TestImport_Screened(t *testing.T) {
+	const input = `{"alias":"ok","url":"https://example.com/page"}
+{"alias":"phish","url":"https://login.phish.example/"}
+{"alias":"taken","url":"https://phish.example/new"}
+`
+
+	s := newSQLite(t)
+
+	_, err := s.SaveURL(context.Background(), storage.URL{Alias: "taken", URL: "https://example.com/old"})
+	require.NoError(t, err)
+
+	r, err := transfer.NewReader(strings.NewReader(input), transfer.FormatNDJSON)
+	require.NoError(t, err)
+
+	res, err := transfer.Import(context.Background(), s, checker, screener, loopChecker, r, transfer.ImportOptions{Conflict: transfer.ConflictOverwrite, Admin: true})
+	require.NoError(t, err)
+	require.Equal(t, 1, res.Imported)
+	require.Empty(t, res.Overwritten)
+
+	require.Equal(t, []transfer.InvalidRecord{
+		{Line: 2, Alias: "phish", Reason: "url is flagged as malicious: blocklist"},
+		{Line: 3, Alias: "taken", Reason: "url is flagged as malicious: blocklist"},
+	}, res.Invalid)
+
+	_, err = s.GetURLInfo(context.Background(), "phish")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	// Перезапись не заменяет ссылку адресом из списка
+	u, err := s.GetURLInfo(context.Background(), "taken")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/old", u.URL)
+}
+
+func TestImport_BannedHost(t *testing.T) {
+	const input = `{"alias":"new","url":"https://cdn.banned.example/file"}
+{"alias":"taken","url":"https://banned.example/"}
+`
+
+	s := newSQLite(t)
+
+	_, err := s.SaveURL(context.Background(), storage.URL{Alias: "taken", URL: "https://example.com/old"})
+	require.NoError(t, err)
+	require.NoError(t, s.BanHost(context.Background(), "banned.example"))
+
+	r, err := transfer.NewReader(strings.NewReader(input), transfer.FormatNDJSON)
+	require.NoError(t, err)
+
+	res, err := transfer.Import(context.Background(), s, checker, screening.NewBanned(s), loopChecker, r, transfer.ImportOptions{Conflict: transfer.ConflictOverwrite, Admin: true})
+	require.NoError(t, err)
+	require.Zero(t, res.Imported)
+	require.Empty(t, res.Overwritten)
+
+	require.Equal(t, []transfer.InvalidRecord{
+		{Line: 1, Alias: "new", Reason: "url is flagged as malicious: banned"},
+		{Line: 2, Alias: "taken", Reason: "url is flagged as malicious: banned"},
+	}, res.Invalid)
+
+	// Импорт не отменяет бан: ссылка не заменяется адресом забаненного хоста
+	u, err := s.GetURLInfo(context.Background(), "taken")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/old", u.URL)
+}
+
+func TestImport_DomainPolicy(t *testing.T) {
+	const input = `{"alias":"new","url":"https://www.blocked.example/"}
+{"alias":"taken","url":"https://blocked.example/page"}
+{"alias":"other","url":"https://other.example/"}
+`
+
+	s := newSQLite(t)
+
+	_, err := s.SaveURL(context.Background(), storage.URL{Alias: "taken", URL: "https://example.com/old"})
+	require.NoError(t, err)
+
+	policy, err := urlcheck.NewDomainPolicy([]string{"blocked.example", "*.blocked.example"}, nil)
+	require.NoError(t, err)
+	domainChecker := urlcheck.New(urlcheck.Options{Domains: policy})
+
+	r, err := transfer.NewReader(strings.NewReader(input), transfer.FormatNDJSON)
+	require.NoError(t, err)
+
+	res, err := transfer.Import(context.Background(), s, domainChecker, screener, loopChecker, r, transfer.ImportOptions{Conflict: transfer.ConflictOverwrite, Admin: true})
+	require.NoError(t, err)
+	require.Equal(t, 1, res.Imported)
+	require.Empty(t, res.Overwritten)
+
+	require.Equal(t, []transfer.InvalidRecord{
+		{Line: 1, Alias: "new", Reason: "url domain is not allowed: www.blocked.example"},
+		{Line: 2, Alias: "taken", Reason: "url domain is not allowed: blocked.example"},
+	}, res.Invalid)
+
+	u, err := s.GetURLInfo(context.Background(), "taken")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/old", u.URL)
+
+	// Перезагруженные списки доменов действуют на следующий импорт
+	reloaded, err := urlcheck.NewDomainPolicy(nil, []string{"example.com"})
+	require.NoError(t, err)
+	policy.Replace(reloaded)
+
+	r, err = transfer.NewReader(strings.NewReader(`{"alias":"later","url":"https://other.example/"}`+"\n"), transfer.FormatNDJSON)
+	require.NoError(t, err)
+
+	res, err = transfer.Import(context.Background(), s, domainChecker, screener, loopChecker, r, transfer.ImportOptions{})
+	require.NoError(t, err)
+	require.Zero(t, res.Imported)
+	require.Equal(t, []transfer.InvalidRecord{
+		{Line: 1, Alias: "later", Reason: "url domain is not allowed: other.example"},
+	}, res.Invalid)
+}
+
+func TestImport_Loops(t *testing.T) {
+	const input = `{"alias":"self","url":"https://sho.rt/abc"}
+{"alias":"request","url":"https://api.example.com/url"}
+{"alias":"domain","url":"https://go.brand.example/x"}
+{"alias":"taken","url":"https://SHO.RT/other"}
+{"alias":"ok","url":"https://example.com/page"}
+`
+
+	s := newSQLite(t)
+
+	_, err := s.SaveURL(context.Background(), storage.URL{Alias: "taken", URL: "https://example.com/old"})
+	require.NoError(t, err)
+
+	r, err := transfer.NewReader(strings.NewReader(input), transfer.FormatNDJSON)
+	require.NoError(t, err)
+
+	loops := loopcheck.New(domains.NewRegistry([]string{"go.brand.example"}, nil), loopcheck.Options{Hosts: []string{"sho.rt"}})
+
+	res, err := transfer.Import(context.Background(), s, checker, screener, loops, r, transfer.ImportOptions{
+		Conflict: transfer.ConflictOverwrite,
+		Admin:    true,
+		Host:     "api.example.com",
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, res.Imported)
+	require.Empty(t, res.Overwritten)
+
+	require.Equal(t, []transfer.InvalidRecord{
+		{Line: 1, Alias: "self", Reason: "url leads to a redirect loop: sho.rt is served by the shortener"},
+		{Line: 2, Alias: "request", Reason: "url leads to a redirect loop: api.example.com is served by the shortener"},
+		{Line: 3, Alias: "domain", Reason: "url leads to a redirect loop: go.brand.example is served by the shortener"},
+		{Line: 4, Alias: "taken", Reason: "url leads to a redirect loop: sho.rt is served by the shortener"},
+	}, res.Invalid)
+
+	u, err := s.GetURLInfo(context.Background(), "taken")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/old", u.URL)
+}
+
+func TestImport_OverwriteOtherUser(t *testing.T) {
+	s := newSQLite(t)
+
+	_, err := s.SaveURL(context.Background(), storage.URL{Alias: "taken", URL: "https://example.com/old", UserID: 1})
+	require.NoError(t, err)
+
+	r, err := transfer.NewReader(strings.NewReader("alias,url\ntaken,https://example.com/new\n"), transfer.FormatCSV)
+	require.NoError(t, err)
+
+	res, err := transfer.Import(context.Background(), s, checker, screener, loopChecker, r, transfer.ImportOptions{Conflict: transfer.ConflictOverwrite, UserID: 2})
+	require.NoError(t, err)
+	require.Equal(t, []string{"taken"}, res.Skipped)
+}
+
+func TestImport_OverwriteOtherKey(t *testing.T) {
+	s := newSQLite(t)
+
+	_, err := s.SaveURL(context.Background(), storage.URL{Alias: "taken", URL: "https://example.com/old", APIKeyID: 1})
+	require.NoError(t, err)
+
+	r, err := transfer.NewReader(strings.NewReader("alias,url\ntaken,https://example.com/new\n"), transfer.FormatCSV)
+	require.NoError(t, err)
+
+	res, err := transfer.Import(context.Background(), s, checker, screener, loopChecker, r, transfer.ImportOptions{Conflict: transfer.ConflictOverwrite, APIKeyID: 2})
+	require.NoError(t, err)
+	require.Equal(t, []string{"taken"}, res.Skipped)
+}
+
+func TestImportExport_Tenant(t *testing.T) {
+	s := newSQLite(t)
+
+	// Одинаковые alias разных тенантов не конфликтуют
+	for _, alias := range []string{"taken", "brand/taken"} {
+		_, err := s.SaveURL(context.Background(), storage.URL{Alias: alias, URL: "https://example.com/old"})
+		require.NoError(t, err)
+	}
+
+	r, err := transfer.NewReader(strings.NewReader("alias,url\ntaken,https://example.com/new\nfree,https://example.com/free\n"), transfer.FormatCSV)
+	require.NoError(t, err)
+
+	res, err := transfer.Import(context.Background(), s, checker, screener, loopChecker, r, transfer.ImportOptions{Conflict: transfer.ConflictRename, Tenant: "brand"})
+	require.NoError(t, err)
+	require.Equal(t, 1, res.Imported)
+
+	renamed := res.Renamed["taken"]
+	require.True(t, strings.HasPrefix(renamed, "taken-"), renamed)
+
+	_, err = s.GetURLInfo(context.Background(), "brand/"+renamed)
+	require.NoError(t, err)
+	_, err = s.GetURLInfo(context.Background(), "brand/free")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := transfer.NewWriter(&buf, transfer.FormatCSV)
+	require.NoError(t, err)
+
+	n, err := transfer.Export(context.Background(), s, w, storage.ListFilter{}, "brand")
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.NotContains(t, buf.String(), "brand/")
+}
+
+func TestImport_Malformed(t *testing.T) {
+	s := newSQLite(t)
+
+	r, err := transfer.NewReader(strings.NewReader(`{"alias":"ok","url":"https://example.com"}
+{"alias":`), transfer.FormatNDJSON)
+	require.NoError(t, err)
+
+	_, err = transfer.Import(context.Background(), s, checker, screener, loopChecker, r, transfer.ImportOptions{})
+
+	var malformed *transfer.MalformedError
+	require.True(t, errors.As(err, &malformed))
+	require.Equal(t, 2, malformed.Line)
+
+	r, err = transfer.NewReader(strings.NewReader("name,target\n"), transfer.FormatCSV)
+	require.NoError(t, err)
+
+	_, err = transfer.Import(context.Background(), s, checker, screener, loopChecker, r, transfer.ImportOptions{})
+	require.ErrorIs(t, err, transfer.ErrInvalidHeader)
+}
@@ -0,0 +1,143 @@
+// Package usermail emails one-time tokens to users: links to confirm the email
+// and to reset the password. Only hashes of the tokens are stored.
+package usermail
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+
+	"url-shortener/internal/mailer"
+	"url-shortener/internal/storage"
+)
+
+// tokenBytes is the amount of randomness in a token.
+const tokenBytes = 32
+
+// Paths of the frontend pages the links lead to, relative to Options.AppURL.
+const (
+	verifyPath = "verify-email"
+	resetPath  = "reset-password"
+)
+
+// TokenSaver is an interface for saving user tokens.
+type TokenSaver interface {
+	SaveUserToken(ctx context.Context, token storage.UserToken) error
+}
+
+type Options struct {
+	// AppURL is the frontend, e.g. "https://sho.rt/app": links lead to its pages
+	// with the token in the "token" query parameter. Empty AppURL sends the token itself.
+	AppURL          string
+	VerificationTTL time.Duration
+	ResetTTL        time.Duration
+}
+
+// Sender creates user tokens and emails them.
+type Sender struct {
+	tokens TokenSaver
+	mailer mailer.Mailer
+	opts   Options
+}
+
+func New(tokens TokenSaver, m mailer.Mailer, opts Options) *Sender {
+	return &Sender{tokens: tokens, mailer: m, opts: opts}
+}
+
+// SendVerification emails the user a link to confirm the email.
+func (s *Sender) SendVerification(ctx context.Context, email string) error {
+	const op = "usermail.SendVerification"
+
+	link, err := s.issue(ctx, email, storage.TokenVerifyEmail, verifyPath, s.opts.VerificationTTL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf("To confirm your email, use %s\n\nThe link is valid for %s.\n",
+			link, s.opts.VerificationTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SendPasswordReset emails the user a link to set a new password.
+func (s *Sender) SendPasswordReset(ctx context.Context, email string) error {
+	const op = "usermail.SendPasswordReset"
+
+	link, err := s.issue(ctx, email, storage.TokenResetPassword, resetPath, s.opts.ResetTTL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("To set a new password, use %s\n\nThe link is valid for %s. "+
+			"If you didn't ask to reset the password, ignore this email.\n", link, s.opts.ResetTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// issue saves a new token and returns the link with it, or the token itself without AppURL.
+func (s *Sender) issue(ctx context.Context, email, purpose, path string, ttl time.Duration) (string, error) {
+	token, err := generate()
+	if err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+
+	err = s.tokens.SaveUserToken(ctx, storage.UserToken{
+		Hash:      HashToken(token),
+		Email:     email,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", fmt.Errorf("save token: %w", err)
+	}
+
+	if s.opts.AppURL == "" {
+		return "the code " + token, nil
+	}
+
+	u, err := url.Parse(s.opts.AppURL)
+	if err != nil {
+		return "", fmt.Errorf("parse app url: %w", err)
+	}
+
+	u = u.JoinPath(path)
+	u.RawQuery = url.Values{"token": {token}}.Encode()
+
+	return u.String(), nil
+}
+
+func generate() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the value stored instead of the token. Tokens are random and
+// long, so a plain SHA-256 is enough, like for API keys.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
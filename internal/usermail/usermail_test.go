@@ -0,0 +1,96 @@
+package usermail
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/mailer"
+	"url-shortener/internal/storage"
+)
+
+type fakeTokens struct {
+	saved []storage.UserToken
+	err   error
+}
+
+func (f *fakeTokens) SaveUserToken(_ context.Context, token storage.UserToken) error {
+	f.saved = append(f.saved, token)
+
+	return f.err
+}
+
+type fakeMailer struct {
+	sent []mailer.Message
+}
+
+func (f *fakeMailer) Send(_ context.Context, msg mailer.Message) error {
+	f.sent = append(f.sent, msg)
+
+	return nil
+}
+
+func TestSender(t *testing.T) {
+	tokens := &fakeTokens{}
+	m := &fakeMailer{}
+	s := New(tokens, m, Options{AppURL: "https://sho.rt/app", VerificationTTL: 24 * time.Hour, ResetTTL: time.Hour})
+
+	require.NoError(t, s.SendVerification(context.Background(), "user@example.com"))
+	require.NoError(t, s.SendPasswordReset(context.Background(), "user@example.com"))
+
+	require.Len(t, tokens.saved, 2)
+	require.Len(t, m.sent, 2)
+
+	for i, c := range []struct {
+		purpose string
+		path    string
+		ttl     time.Duration
+	}{
+		{purpose: storage.TokenVerifyEmail, path: "/app/verify-email", ttl: 24 * time.Hour},
+		{purpose: storage.TokenResetPassword, path: "/app/reset-password", ttl: time.Hour},
+	} {
+		saved, msg := tokens.saved[i], m.sent[i]
+
+		assert.Equal(t, "user@example.com", saved.Email)
+		assert.Equal(t, c.purpose, saved.Purpose)
+		assert.WithinDuration(t, time.Now().Add(c.ttl), saved.ExpiresAt, time.Minute)
+		assert.Equal(t, "user@example.com", msg.To)
+
+		// Ссылка в письме содержит токен, хеш которого сохранен
+		start := strings.Index(msg.Body, "https://")
+		require.NotEqual(t, -1, start)
+		link, err := url.Parse(strings.Fields(msg.Body[start:])[0])
+		require.NoError(t, err)
+
+		assert.Equal(t, c.path, link.Path)
+		assert.Equal(t, saved.Hash, HashToken(link.Query().Get("token")))
+	}
+
+	assert.NotEqual(t, tokens.saved[0].Hash, tokens.saved[1].Hash)
+}
+
+func TestSender_WithoutAppURL(t *testing.T) {
+	tokens := &fakeTokens{}
+	m := &fakeMailer{}
+	s := New(tokens, m, Options{VerificationTTL: time.Hour})
+
+	require.NoError(t, s.SendVerification(context.Background(), "user@example.com"))
+
+	require.Len(t, m.sent, 1)
+	assert.NotContains(t, m.sent[0].Body, "http")
+	assert.Contains(t, m.sent[0].Body, "the code ")
+}
+
+func TestSender_SaveError(t *testing.T) {
+	m := &fakeMailer{}
+	s := New(&fakeTokens{err: errors.New("unexpected error")}, m, Options{})
+
+	require.Error(t, s.SendPasswordReset(context.Background(), "user@example.com"))
+	assert.Empty(t, m.sent, "email must not be sent with a token which isn't saved")
+}
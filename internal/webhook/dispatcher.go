@@ -0,0 +1,227 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	linkEvents "url-shortener/internal/events"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// Event types webhooks subscribe to via the admin API.
+const (
+	EventLinkCreated = "link.created"
+	EventLinkDeleted = "link.deleted"
+	EventLinkClicked = "link.clicked"
+	// EventLinkExpired is sent when the janitor removes the expired link. Redis
+	// evicts expired links itself, so it is never sent with the redis storage.
+	EventLinkExpired = "link.expired"
+)
+
+// EventTypes lists the event types webhooks can subscribe to.
+var EventTypes = []string{EventLinkCreated, EventLinkDeleted, EventLinkClicked, EventLinkExpired}
+
+// ValidateEventTypes checks the event types of a subscription.
+func ValidateEventTypes(types []string) error {
+	if len(types) == 0 {
+		return errors.New("events must not be empty")
+	}
+
+	for _, t := range types {
+		if !slices.Contains(EventTypes, t) {
+			return fmt.Errorf("unknown event %q, expected one of: %s", t, strings.Join(EventTypes, ", "))
+		}
+	}
+
+	return nil
+}
+
+// brokerTypes maps types of broker events to event types of webhooks.
+var brokerTypes = map[string]string{
+	linkEvents.TypeLinkCreated: EventLinkCreated,
+	linkEvents.TypeLinkDeleted: EventLinkDeleted,
+	linkEvents.TypeLinkClicked: EventLinkClicked,
+	linkEvents.TypeLinkExpired: EventLinkExpired,
+}
+
+// saveTimeout limits recording of one delivery in the log.
+const saveTimeout = 5 * time.Second
+
+// Payload is the JSON body POSTed to webhooks subscribed via the admin API.
+type Payload struct {
+	Event string `json:"event"`
+	// Alias is the key of the link in the storage, with the tenant prefix.
+	Alias     string    `json:"alias"`
+	Timestamp time.Time `json:"timestamp"`
+	// URL is set for link.created.
+	URL string `json:"url,omitempty"`
+	// Referrer, UserAgent and Variant are set for link.clicked.
+	Referrer  string `json:"referrer,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Variant   string `json:"variant,omitempty"`
+}
+
+// Store is an interface for reading subscriptions and recording deliveries.
+type Store interface {
+	ListWebhooks(ctx context.Context) ([]storage.Webhook, error)
+	SaveWebhookDelivery(ctx context.Context, delivery storage.WebhookDelivery) error
+}
+
+// Dispatcher delivers link events to the webhooks subscribed to them, signing the
+// payloads with the webhook secrets, and records every delivery in the store.
+// Subscriptions are cached and reloaded by Run.
+type Dispatcher struct {
+	log      *slog.Logger
+	store    Store
+	sender   *sender
+	webhooks atomic.Pointer[[]storage.Webhook]
+}
+
+func NewDispatcher(log *slog.Logger, store Store, opts Options) *Dispatcher {
+	log = log.With(slog.String("component", "webhook_dispatcher"))
+
+	d := &Dispatcher{log: log, store: store, sender: newSender(log, opts)}
+	d.webhooks.Store(&[]storage.Webhook{})
+
+	return d
+}
+
+// Reload loads the subscriptions from the store.
+func (d *Dispatcher) Reload(ctx context.Context) error {
+	webhooks, err := d.store.ListWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	d.webhooks.Store(&webhooks)
+
+	return nil
+}
+
+// Run loads the subscriptions and reloads them every interval until ctx is done,
+// so webhooks changed via the admin API take effect within the interval.
+func (d *Dispatcher) Run(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	const op = "webhook.Dispatcher.Run"
+
+	log = log.With(slog.String("op", op))
+
+	if err := d.Reload(ctx); err != nil {
+		log.Error("failed to load webhooks", sl.Err(err))
+	}
+
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// При ошибке события рассылаются по прежним подпискам
+			if err := d.Reload(ctx); err != nil {
+				log.Error("failed to reload webhooks", sl.Err(err))
+			}
+		}
+	}
+}
+
+// Publish queues the link event for the subscribed webhooks, so Dispatcher can be
+// used as a publisher of storage events. It never blocks.
+func (d *Dispatcher) Publish(e linkEvents.Event) {
+	event, ok := brokerTypes[e.Type]
+	if !ok {
+		return
+	}
+
+	d.dispatch(Payload{
+		Event:     event,
+		Alias:     e.Alias,
+		Timestamp: e.Time.UTC(),
+		URL:       e.URL,
+		Referrer:  e.Referrer,
+		UserAgent: e.UserAgent,
+		Variant:   e.Variant,
+	})
+}
+
+// Record queues link.clicked, so Dispatcher can be used as a click recorder.
+func (d *Dispatcher) Record(e storage.ClickEvent) {
+	d.dispatch(Payload{
+		Event:     EventLinkClicked,
+		Alias:     e.Alias,
+		Timestamp: e.Time.UTC(),
+		Referrer:  e.Referrer,
+		UserAgent: e.UserAgent,
+		Variant:   e.Variant,
+	})
+}
+
+// Close stops the workers. Queued events are still delivered, but without retries.
+func (d *Dispatcher) Close() {
+	d.sender.close()
+}
+
+func (d *Dispatcher) dispatch(p Payload) {
+	var body []byte
+
+	for _, w := range *d.webhooks.Load() {
+		if !slices.Contains(w.Events, p.Event) {
+			continue
+		}
+
+		// Тело кодируется один раз и только если есть подписчики
+		if body == nil {
+			var err error
+			if body, err = json.Marshal(p); err != nil {
+				d.log.Error("failed to encode webhook payload", sl.Err(err))
+
+				return
+			}
+		}
+
+		d.sender.enqueue(delivery{
+			url:    w.URL,
+			event:  p.Event,
+			alias:  p.Alias,
+			body:   body,
+			secret: w.Secret,
+			report: d.reporter(w.ID, p),
+		})
+	}
+}
+
+// reporter returns the callback recording the outcome of the delivery in the store.
+func (d *Dispatcher) reporter(webhookID int64, p Payload) func(attempts, statusCode int, err error) {
+	return func(attempts, statusCode int, err error) {
+		record := storage.WebhookDelivery{
+			WebhookID:  webhookID,
+			Event:      p.Event,
+			Alias:      p.Alias,
+			Attempts:   attempts,
+			StatusCode: statusCode,
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), saveTimeout)
+		defer cancel()
+
+		if err := d.store.SaveWebhookDelivery(ctx, record); err != nil {
+			d.log.Error("failed to save webhook delivery", slog.Int64("webhook_id", webhookID), sl.Err(err))
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/signature"
+	"url-shortener/internal/storage"
+)
+
+// EventDead is the type of the event sent when the destination of the link is found dead.
+const EventDead = "dead"
+
+// Event is the JSON body POSTed to the link webhook on every click and on other
+// events of the link, see Type.
+type Event struct {
+	// Type is empty for clicks, so receivers written before other events keep working.
+	Type      string    `json:"type,omitempty"`
+	Alias     string    `json:"alias"`
+	Timestamp time.Time `json:"timestamp"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	// Variant is the A/B test variant the visitor was sent to.
+	Variant string `json:"variant,omitempty"`
+	// Reason is why the destination is considered dead, e.g. "404", for EventDead.
+	Reason string `json:"reason,omitempty"`
+}
+
+type Options struct {
+	// Workers is the number of concurrent deliveries.
+	Workers int
+	// BufferSize is the number of events which may wait for delivery; extra events are dropped.
+	BufferSize int
+	// Timeout limits one delivery attempt.
+	Timeout time.Duration
+	// MaxRetries is the number of retries after the first failed attempt.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, it doubles with every next one.
+	RetryBackoff time.Duration
+}
+
+// Headers of deliveries to webhooks with a secret, see Sign.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Sign returns the signature of the body sent at timestamp (unix seconds): the hex
+// HMAC-SHA256 of "<timestamp>.<body>" with the webhook secret. Receivers compute it
+// the same way and compare with the X-Webhook-Signature header.
+func Sign(secret, timestamp string, body []byte) string {
+	return signature.Compute(secret, timestamp+"."+string(body))
+}
+
+type delivery struct {
+	url string
+	// event and alias identify the delivery in logs.
+	event string
+	alias string
+	body  []byte
+	// secret signs every attempt when it is set.
+	secret string
+	// report is called with the outcome after the last attempt, if set.
+	report func(attempts, statusCode int, err error)
+}
+
+// sender posts deliveries from background workers, retrying failed attempts with
+// exponential backoff, so slow or failing receivers don't delay requests.
+type sender struct {
+	log    *slog.Logger
+	client *http.Client
+	opts   Options
+
+	deliveries chan delivery
+	done       chan struct{}
+	once       sync.Once
+	wg         sync.WaitGroup
+}
+
+func newSender(log *slog.Logger, opts Options) *sender {
+	s := &sender{
+		log:        log,
+		client:     &http.Client{Timeout: opts.Timeout},
+		opts:       opts,
+		deliveries: make(chan delivery, opts.BufferSize),
+		done:       make(chan struct{}),
+	}
+
+	for i := 0; i < opts.Workers; i++ {
+		s.wg.Add(1)
+		go s.run()
+	}
+
+	return s
+}
+
+// Notifier delivers click events to link webhooks from background workers,
+// so slow or failing receivers don't delay redirects.
+type Notifier struct {
+	log    *slog.Logger
+	sender *sender
+}
+
+func New(log *slog.Logger, opts Options) *Notifier {
+	log = log.With(slog.String("component", "webhook"))
+
+	return &Notifier{log: log, sender: newSender(log, opts)}
+}
+
+// Notify queues the click for delivery to webhookURL. It never blocks:
+// if the buffer is full, the event is dropped.
+func (n *Notifier) Notify(webhookURL string, e storage.ClickEvent) {
+	n.enqueue(webhookURL, Event{
+		Alias:     e.Alias,
+		Timestamp: e.Time.UTC(),
+		Referrer:  e.Referrer,
+		UserAgent: e.UserAgent,
+		Variant:   e.Variant,
+	})
+}
+
+// NotifyDead queues EventDead for delivery to webhookURL, like Notify.
+func (n *Notifier) NotifyDead(webhookURL, alias, reason string) {
+	n.enqueue(webhookURL, Event{
+		Type:      EventDead,
+		Alias:     alias,
+		Timestamp: time.Now().UTC(),
+		Reason:    reason,
+	})
+}
+
+func (n *Notifier) enqueue(webhookURL string, e Event) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		n.log.Error("failed to encode webhook event", sl.Err(err))
+
+		return
+	}
+
+	n.sender.enqueue(delivery{url: webhookURL, event: e.Type, alias: e.Alias, body: body})
+}
+
+// Close stops the workers. Queued events are still delivered, but without retries.
+func (n *Notifier) Close() {
+	n.sender.close()
+}
+
+func (s *sender) enqueue(d delivery) {
+	select {
+	case s.deliveries <- d:
+	default:
+		s.log.Warn("webhook buffer is full, event dropped", slog.String("event", d.event), slog.String("alias", d.alias))
+	}
+}
+
+// close stops the workers after they deliver the queued events without retries.
+func (s *sender) close() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+
+	s.wg.Wait()
+}
+
+func (s *sender) run() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case d := <-s.deliveries:
+			s.deliver(d)
+		case <-s.done:
+			for {
+				select {
+				case d := <-s.deliveries:
+					s.deliver(d)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver posts the body, retrying with exponential backoff until it succeeds,
+// the receiver rejects it or the sender is closed.
+func (s *sender) deliver(d delivery) {
+	backoff := s.opts.RetryBackoff
+
+	for attempt := 1; ; attempt++ {
+		statusCode, retry, err := s.post(d)
+		if err == nil {
+			s.report(d, attempt, statusCode, nil)
+
+			return
+		}
+
+		if !retry || attempt > s.opts.MaxRetries {
+			s.log.Warn("failed to deliver webhook",
+				slog.String("event", d.event),
+				slog.String("alias", d.alias),
+				slog.Int("attempts", attempt),
+				sl.Err(err),
+			)
+			s.report(d, attempt, statusCode, err)
+
+			return
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-s.done:
+			s.log.Warn("webhook retries stopped on shutdown",
+				slog.String("event", d.event),
+				slog.String("alias", d.alias),
+				sl.Err(err),
+			)
+			s.report(d, attempt, statusCode, err)
+
+			return
+		}
+	}
+}
+
+func (s *sender) report(d delivery, attempts, statusCode int, err error) {
+	if d.report != nil {
+		d.report(attempts, statusCode, err)
+	}
+}
+
+// post makes one delivery attempt. statusCode is zero if there was no response,
+// retry is false when repeating the request won't help.
+func (s *sender) post(d delivery) (statusCode int, retry bool, err error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, d.url, bytes.NewReader(d.body))
+	if err != nil {
+		return 0, false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "url-shortener-webhook")
+
+	if d.secret != "" {
+		// Подпись считается на каждую попытку: получатель может отвергать старые метки времени
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+		req.Header.Set(HeaderEvent, d.event)
+		req.Header.Set(HeaderTimestamp, timestamp)
+		req.Header.Set(HeaderSignature, Sign(d.secret, timestamp, d.body))
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, true, err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return res.StatusCode, false, nil
+	case res.StatusCode == http.StatusTooManyRequests, res.StatusCode >= 500:
+		return res.StatusCode, true, fmt.Errorf("unexpected status %d", res.StatusCode)
+	default:
+		// Остальные 4xx - ошибка получателя, повтор не поможет
+		return res.StatusCode, false, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+}
+
+// Nop discards click events. It is used when webhooks are disabled.
+type Nop struct{}
+
+func (Nop) Notify(string, storage.ClickEvent) {}
+
+func (Nop) NotifyDead(string, string, string) {}
+
+func (Nop) Close() {}
@@ -0,0 +1,211 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	linkEvents "url-shortener/internal/events"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/webhook"
+)
+
+func TestNotifier(t *testing.T) {
+	cases := []struct {
+		name string
+		// statuses are returned by the receiver on consecutive attempts, the last one repeats.
+		statuses     []int
+		wantAttempts int32
+	}{
+		{
+			name:         "Success",
+			statuses:     []int{http.StatusOK},
+			wantAttempts: 1,
+		},
+		{
+			name:         "Retried until success",
+			statuses:     []int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusNoContent},
+			wantAttempts: 3,
+		},
+		{
+			name:         "Retries exhausted",
+			statuses:     []int{http.StatusServiceUnavailable},
+			wantAttempts: 4,
+		},
+		{
+			name:         "Rejected without retries",
+			statuses:     []int{http.StatusBadRequest},
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var attempts atomic.Int32
+			events := make(chan webhook.Event, 10)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(attempts.Add(1))
+
+				var e webhook.Event
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				events <- e
+
+				if n > len(tc.statuses) {
+					n = len(tc.statuses)
+				}
+				w.WriteHeader(tc.statuses[n-1])
+			}))
+			defer srv.Close()
+
+			n := webhook.New(slogdiscard.NewDiscardLogger(), webhook.Options{
+				Workers:      1,
+				BufferSize:   10,
+				Timeout:      time.Second,
+				MaxRetries:   3,
+				RetryBackoff: time.Millisecond,
+			})
+
+			clickedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			n.Notify(srv.URL, storage.ClickEvent{
+				Alias:     "alias",
+				Time:      clickedAt,
+				Referrer:  "https://ref.example/",
+				UserAgent: "test-agent",
+				IP:        "203.0.113.42",
+			})
+
+			require.Eventually(t, func() bool {
+				return attempts.Load() == tc.wantAttempts
+			}, time.Second, time.Millisecond)
+
+			n.Close()
+
+			assert.Equal(t, tc.wantAttempts, attempts.Load())
+
+			e := <-events
+			assert.Equal(t, webhook.Event{
+				Alias:     "alias",
+				Timestamp: clickedAt,
+				Referrer:  "https://ref.example/",
+				UserAgent: "test-agent",
+			}, e)
+		})
+	}
+}
+
+type store struct {
+	webhooks   []storage.Webhook
+	deliveries chan storage.WebhookDelivery
+}
+
+func (s *store) ListWebhooks(context.Context) ([]storage.Webhook, error) {
+	return s.webhooks, nil
+}
+
+func (s *store) SaveWebhookDelivery(_ context.Context, d storage.WebhookDelivery) error {
+	s.deliveries <- d
+
+	return nil
+}
+
+func TestDispatcher(t *testing.T) {
+	type received struct {
+		header  http.Header
+		body    []byte
+		payload webhook.Payload
+	}
+
+	requests := make(chan received, 10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		var p webhook.Payload
+		assert.NoError(t, json.Unmarshal(body, &p))
+		requests <- received{header: r.Header, body: body, payload: p}
+
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer srv.Close()
+
+	st := &store{
+		webhooks: []storage.Webhook{
+			{ID: 1, URL: srv.URL + "/created", Events: storage.EventTypes{webhook.EventLinkCreated}, Secret: "secret-1"},
+			{ID: 2, URL: srv.URL + "/gone", Events: storage.EventTypes{webhook.EventLinkClicked, webhook.EventLinkExpired}, Secret: "secret-2"},
+		},
+		deliveries: make(chan storage.WebhookDelivery, 10),
+	}
+
+	d := webhook.NewDispatcher(slogdiscard.NewDiscardLogger(), st, webhook.Options{
+		Workers:      1,
+		BufferSize:   10,
+		Timeout:      time.Second,
+		MaxRetries:   3,
+		RetryBackoff: time.Millisecond,
+	})
+
+	// До загрузки подписок события никуда не отправляются
+	d.Publish(linkEvents.Event{Type: linkEvents.TypeLinkCreated, Alias: "early", Time: time.Now()})
+	require.NoError(t, d.Reload(context.Background()))
+
+	at := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	d.Publish(linkEvents.Event{Type: linkEvents.TypeLinkCreated, Alias: "promo", URL: "https://example.com", Time: at})
+	d.Publish(linkEvents.Event{Type: linkEvents.TypeLinkDeleted, Alias: "promo", Time: at})
+	d.Record(storage.ClickEvent{Alias: "promo", Time: at, Referrer: "https://ref.example/"})
+	d.Close()
+
+	close(requests)
+	close(st.deliveries)
+
+	byEvent := make(map[string]received)
+	for r := range requests {
+		byEvent[r.payload.Event] = r
+	}
+	require.Len(t, byEvent, 2)
+
+	created := byEvent[webhook.EventLinkCreated]
+	assert.Equal(t, webhook.Payload{
+		Event: webhook.EventLinkCreated, Alias: "promo", Timestamp: at, URL: "https://example.com",
+	}, created.payload)
+	assert.Equal(t, webhook.EventLinkCreated, created.header.Get(webhook.HeaderEvent))
+	assert.Equal(t,
+		webhook.Sign("secret-1", created.header.Get(webhook.HeaderTimestamp), created.body),
+		created.header.Get(webhook.HeaderSignature),
+	)
+
+	clicked := byEvent[webhook.EventLinkClicked]
+	assert.Equal(t, "https://ref.example/", clicked.payload.Referrer)
+	assert.Equal(t,
+		webhook.Sign("secret-2", clicked.header.Get(webhook.HeaderTimestamp), clicked.body),
+		clicked.header.Get(webhook.HeaderSignature),
+	)
+
+	var deliveries []storage.WebhookDelivery
+	for d := range st.deliveries {
+		deliveries = append(deliveries, d)
+	}
+	assert.ElementsMatch(t, []storage.WebhookDelivery{
+		{WebhookID: 1, Event: webhook.EventLinkCreated, Alias: "promo", Attempts: 1, StatusCode: http.StatusOK},
+		// 410 - ошибка получателя, повторов нет
+		{WebhookID: 2, Event: webhook.EventLinkClicked, Alias: "promo", Attempts: 1, StatusCode: http.StatusGone,
+			Error: "unexpected status 410"},
+	}, deliveries)
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/gavv/httpexpect/v2"
 	"github.com/stretchr/testify/require"
 
+	"url-shortener/internal/http-server/handlers/apikey/create"
 	"url-shortener/internal/http-server/handlers/url/save"
+	mwAPIKey "url-shortener/internal/http-server/middleware/apikey"
 	"url-shortener/internal/lib/api"
 	"url-shortener/internal/lib/random"
 )
@@ -30,7 +32,7 @@ func TestURLShortener_HappyPath(t *testing.T) {
 			URL:   gofakeit.URL(),
 			Alias: random.NewRandomString(10),
 		}).
-		WithBasicAuth("myuser", "mypass").
+		WithHeader(mwAPIKey.Header, createAPIKey(e)).
 		Expect().
 		Status(200).
 		JSON().Object().
@@ -80,14 +82,14 @@ func TestURLShortener_SaveRedirect(t *testing.T) {
 					URL:   tc.url,
 					Alias: tc.alias,
 				}).
-				WithBasicAuth("myuser", "mypass").
+				WithHeader(mwAPIKey.Header, createAPIKey(e)).
 				Expect().Status(http.StatusOK).
 				JSON().Object()
 
 			if tc.error != "" {
 				resp.NotContainsKey("alias")
 
-				resp.Value("error").String().IsEqual(tc.error)
+				resp.Value("error").Object().Value("message").String().IsEqual(tc.error)
 
 				return
 			}
@@ -109,6 +111,16 @@ func TestURLShortener_SaveRedirect(t *testing.T) {
 	}
 }
 
+// createAPIKey issues a new api key using admin credentials.
+func createAPIKey(e *httpexpect.Expect) string {
+	return e.POST("/admin/api-keys").
+		WithJSON(create.Request{Name: gofakeit.Word()}).
+		WithBasicAuth("myuser", "mypass").
+		Expect().Status(http.StatusCreated).
+		JSON().Object().
+		Value("key").String().Raw()
+}
+
 func testRedirect(t *testing.T, alias string, urlToRedirect string) {
 	u := url.URL{
 		Scheme: "http",